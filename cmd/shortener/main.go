@@ -87,5 +87,9 @@ func main() {
 		logger.Fatal("cannot configure app", zap.Error(err))
 	}
 
+	if len(cfg.Args) > 0 {
+		defer os.Exit(app.RunCommand(logger, cfg, os.Stdout))
+		return
+	}
 	defer os.Exit(app.Run(logger, cfg))
 }
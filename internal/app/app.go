@@ -4,20 +4,31 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/adwski/shorty/internal/config"
 	grpcserver "github.com/adwski/shorty/internal/grpc/server"
 	httpserver "github.com/adwski/shorty/internal/http/server"
 	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/normalizer"
 	"github.com/adwski/shorty/internal/profiler"
+	"github.com/adwski/shorty/internal/ratelimit"
+	"github.com/adwski/shorty/internal/services/account"
+	"github.com/adwski/shorty/internal/services/apikey"
+	"github.com/adwski/shorty/internal/services/backup"
+	"github.com/adwski/shorty/internal/services/oidc"
+	"github.com/adwski/shorty/internal/services/quota"
 	"github.com/adwski/shorty/internal/services/resolver"
 	"github.com/adwski/shorty/internal/services/shortener"
 	"github.com/adwski/shorty/internal/services/status"
+	"github.com/adwski/shorty/internal/services/transfer"
+	"github.com/adwski/shorty/internal/services/workspace"
 	"github.com/adwski/shorty/internal/storage/database"
 	"github.com/adwski/shorty/internal/storage/file"
 	"github.com/adwski/shorty/internal/storage/memory"
@@ -30,11 +41,47 @@ const (
 
 // Storage defines storage backend methods that is used by Shorty.
 type Storage interface {
-	Get(ctx context.Context, key string) (url string, err error)
+	Get(ctx context.Context, key string) (url *model.URL, err error)
 	Store(ctx context.Context, url *model.URL, overwrite bool) (string, error)
 	StoreBatch(ctx context.Context, urls []model.URL) error
-	ListUserURLs(ctx context.Context, userid string) ([]*model.URL, error)
+	ListUserURLs(ctx context.Context, userid, tag string) ([]*model.URL, error)
+	ListWorkspaceURLs(ctx context.Context, workspaceID, tag string) ([]*model.URL, error)
+	UpdateMeta(ctx context.Context, url *model.URL) error
 	DeleteUserURLs(ctx context.Context, urls []model.URL) (int64, error)
+	PurgeDeleted(ctx context.Context) (int64, error)
+	ScanURLs(ctx context.Context, after string, fn func(url *model.URL) error) error
+	LoadURLs(ctx context.Context, next func() (*model.URL, error), replace bool) error
+	ClaimUserURLs(ctx context.Context, fromUserID, toUserID string) (int64, error)
+	CreateAccount(ctx context.Context, acc *model.Account) error
+	GetAccount(ctx context.Context, login string) (*model.Account, error)
+	CreateIdentity(ctx context.Context, identity *model.Identity) error
+	GetIdentity(ctx context.Context, issuer, subject string) (*model.Identity, error)
+	CreateWorkspace(ctx context.Context, ws *model.Workspace, ownerID string) error
+	GetWorkspace(ctx context.Context, id string) (*model.Workspace, error)
+	ListWorkspaces(ctx context.Context, userID string) ([]*model.Workspace, error)
+	GetMember(ctx context.Context, workspaceID, userID string) (*model.Member, error)
+	ListMembers(ctx context.Context, workspaceID string) ([]*model.Member, error)
+	SetMember(ctx context.Context, member *model.Member) error
+	DeleteMember(ctx context.Context, workspaceID, userID string) error
+	CreateTransfer(ctx context.Context, transfer *model.Transfer) error
+	GetTransfer(ctx context.Context, id string) (*model.Transfer, error)
+	ListTransfers(ctx context.Context, userID string) ([]*model.Transfer, error)
+	DeleteTransfer(ctx context.Context, id string) error
+	TransferURLs(ctx context.Context, event *model.AuditEvent) error
+	ListAuditEvents(ctx context.Context, limit int) ([]*model.AuditEvent, error)
+	CountUserURLs(ctx context.Context, userID string) (int, error)
+	GetQuota(ctx context.Context, userID string) (*model.Quota, error)
+	SetQuota(ctx context.Context, quota *model.Quota) error
+	DeleteQuota(ctx context.Context, userID string) error
+	CreateAPIKey(ctx context.Context, key *model.APIKey) error
+	GetAPIKey(ctx context.Context, id string) (*model.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error)
+	UpdateAPIKey(ctx context.Context, key *model.APIKey) error
+	DeleteAPIKey(ctx context.Context, userID, id string) error
+	RevokeToken(ctx context.Context, id string, expires time.Time) error
+	IsTokenRevoked(ctx context.Context, id string) (bool, error)
+	AddVariantClicks(ctx context.Context, clicks []model.Click) error
+	GetVariantClicks(ctx context.Context, short string) ([]int64, error)
 	Ping(ctx context.Context) error
 	Stats(ctx context.Context) (*model.Stats, error)
 	Close()
@@ -48,6 +95,7 @@ type Shorty struct {
 	http         *httpserver.Server
 	grpc         *grpcserver.Server
 	shortenerSvc *shortener.Service
+	resolverSvc  *resolver.Service
 }
 
 // NewShorty creates Shorty instance from config.
@@ -55,32 +103,143 @@ func NewShorty(logger *zap.Logger, storage Storage, cfg *config.Config) (*Shorty
 	if storage == nil {
 		return nil, fmt.Errorf("nil storage")
 	}
-	shortenerSvc := shortener.New(&shortener.Config{
+	normalizerCfg := &normalizer.Config{}
+	if cfg.Normalize != nil {
+		normalizerCfg = &normalizer.Config{
+			TrackingParams: cfg.Normalize.GetTrackingParams(),
+			SortQuery:      cfg.Normalize.SortQuery,
+			RemoveFragment: cfg.Normalize.RemoveFragment,
+			StripTracking:  cfg.Normalize.StripTracking,
+		}
+	}
+	shortenerCfg := &shortener.Config{
 		Store:          storage,
+		Normalizer:     normalizer.New(normalizerCfg),
 		ServedScheme:   cfg.ServedScheme,
 		RedirectScheme: cfg.RedirectScheme,
 		Host:           cfg.ServedHost,
 		Logger:         logger,
 		PathLength:     defaultPathLength,
-	})
+	}
+	// quotas are enforced only if tiers are configured
+	var quotaSvc *quota.Service
+	if tiers := cfg.GetQuotaTiers(); len(tiers) > 0 {
+		quotaSvc = quota.New(&quota.Config{
+			Storage:     storage,
+			Logger:      logger,
+			DefaultTier: cfg.Quota.DefaultTier,
+			Tiers:       tiers,
+		})
+		shortenerCfg.Quotas = quotaSvc
+	}
+	shortenerSvc := shortener.New(shortenerCfg)
 	resolverSvc := resolver.New(&resolver.Config{
-		Store:  storage,
-		Logger: logger,
+		Store:           storage,
+		Logger:          logger,
+		DefaultRedirect: cfg.RedirectCode,
+		CacheMaxAge:     cfg.GetRedirectMaxAge(),
+		GeoIP:           cfg.GetGeoIP(),
+		Timezone:        cfg.GetScheduleTimezone(),
+		ServedScheme:    cfg.ServedScheme,
+		Host:            cfg.ServedHost,
 	})
 	statusSvc := status.New(&status.Config{
 		Storage: storage,
 		Logger:  logger,
 	})
 
+	// sessions are revoked in the same storage
+	cfg.GetAuthorizer().WithDenylist(storage)
+
+	accountCfg := &account.Config{
+		Storage:    storage,
+		Authorizer: cfg.GetAuthorizer(),
+		Logger:     logger,
+	}
+	if quotaSvc != nil {
+		accountCfg.Quotas = quotaSvc
+	}
+	accountSvc, err := account.New(accountCfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create account service: %w", err)
+	}
+
+	apikeySvc := apikey.New(&apikey.Config{
+		Storage: storage,
+		Logger:  logger,
+	})
+
+	workspaceSvc := workspace.New(&workspace.Config{
+		Storage: storage,
+		Logger:  logger,
+	})
+
+	transferCfg := &transfer.Config{
+		Storage: storage,
+		Logger:  logger,
+	}
+	if quotaSvc != nil {
+		transferCfg.Quotas = quotaSvc
+	}
+	transferSvc := transfer.New(transferCfg)
+
+	var oidcSvc *oidc.Service
+	if cfg.OIDC.Issuer != "" {
+		oidcCfg := &oidc.Config{
+			Storage:      storage,
+			Authorizer:   cfg.GetAuthorizer(),
+			Logger:       logger,
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			Scopes:       cfg.OIDC.GetScopes(),
+		}
+		if quotaSvc != nil {
+			oidcCfg.Quotas = quotaSvc
+		}
+		oidcSvc = oidc.New(oidcCfg)
+	}
+
+	var backupSvc *backup.Service
+	if cfg.BackupDir != "" {
+		if backupSvc, err = backup.New(&backup.Config{
+			Storage: storage,
+			Logger:  logger,
+			Dir:     cfg.BackupDir,
+		}); err != nil {
+			return nil, fmt.Errorf("cannot create backup service: %w", err)
+		}
+	}
+
+	var limiter *ratelimit.Limiter
+	if rules := cfg.GetRateLimits(); len(rules) > 0 {
+		var store ratelimit.Store
+		if cfg.RateLimit.Shared {
+			var ok bool
+			if store, ok = storage.(ratelimit.Store); !ok {
+				return nil, errors.New("shared rate limits require database storage")
+			}
+		}
+		limiter = ratelimit.New(&ratelimit.Config{
+			Store:  store,
+			Logger: logger,
+			Rules:  rules,
+		})
+	}
+
 	sh := &Shorty{
 		logger:       logger,
 		shortenerSvc: shortenerSvc,
+		resolverSvc:  resolverSvc,
 	}
 	if cfg.ListenAddr != "" {
-		sh.http = httpserver.NewServer(logger, cfg, resolverSvc, shortenerSvc, statusSvc)
+		sh.http = httpserver.NewServer(logger, cfg, resolverSvc, shortenerSvc, statusSvc,
+			backupSvc, accountSvc, apikeySvc, oidcSvc, workspaceSvc, transferSvc, quotaSvc, limiter)
 	}
 	if cfg.GRPCListenAddr != "" {
-		sh.grpc = grpcserver.NewServer(logger, cfg, resolverSvc, shortenerSvc, statusSvc)
+		sh.grpc = grpcserver.NewServer(logger, cfg, resolverSvc, shortenerSvc, statusSvc,
+			accountSvc, apikeySvc, workspaceSvc, transferSvc, quotaSvc, limiter)
 	}
 	return sh, nil
 }
@@ -125,9 +284,10 @@ func Run(logger *zap.Logger, cfg *config.Config) int {
 		go prof.Run(ctx, wg, errc)
 	}
 
-	// starting flusher
-	wg.Add(1)
+	// starting flushers
+	wg.Add(2)
 	go shorty.shortenerSvc.GetFlusher().Run(ctx, wg)
+	go shorty.resolverSvc.GetFlusher().Run(ctx, wg)
 
 	// starting http server
 	if shorty.http != nil {
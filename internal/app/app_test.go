@@ -4,16 +4,27 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/adwski/shorty/internal/app/mockapp"
+	"github.com/adwski/shorty/internal/auth"
 	"github.com/adwski/shorty/internal/config"
+	httpmodel "github.com/adwski/shorty/internal/http/model"
 	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/services/oidc/oidctest"
+	"github.com/adwski/shorty/internal/storage/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -64,7 +75,7 @@ func TestShorty(t *testing.T) {
 			st.On("Store", mock.Anything, mock.Anything, false).Return(
 				func(_ context.Context, url *model.URL, _ bool) (string, error) {
 					t.Log("registering mock get", url)
-					st.EXPECT().Get(mock.Anything, url.Short).Return(url.Orig, nil)
+					st.EXPECT().Get(mock.Anything, url.Short).Return(url, nil)
 					return "", nil
 				})
 
@@ -134,6 +145,1011 @@ func TestShorty(t *testing.T) {
 
 			// Check headers
 			assert.Equal(t, tt.args.url, res.Header.Get("Location"))
+			assert.Equal(t, "no-store", res.Header.Get("Cache-Control"))
 		})
 	}
 }
+
+func TestShorty_APIRoutes(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{method: http.MethodGet, path: "/api/user/urls", status: http.StatusUnauthorized},
+		{method: http.MethodGet, path: "/api/user/urls/qwe/variants", status: http.StatusUnauthorized},
+		{method: http.MethodPatch, path: "/api/user/urls/qwe", body: `{"title":"a"}`, status: http.StatusUnauthorized},
+		{method: http.MethodPost, path: "/api/shorten", body: `{"url":"ftp://"}`, status: http.StatusBadRequest},
+		{method: http.MethodPost, path: "/api/user/import", body: `{}`, status: http.StatusBadRequest},
+		{method: http.MethodGet, path: "/api/user/export", status: http.StatusUnauthorized},
+		{method: http.MethodGet, path: "/api/user/export?format=xml", status: http.StatusBadRequest},
+		{method: http.MethodPost, path: "/api/user/register", body: `{"login":"alice"}`, status: http.StatusBadRequest},
+		{method: http.MethodPost, path: "/api/user/login", body: `{"login":"a"}`, status: http.StatusUnauthorized},
+		{method: http.MethodGet, path: "/api/internal/stats", status: http.StatusForbidden},
+		{method: http.MethodPost, path: "/api/internal/backup", status: http.StatusForbidden},
+		{method: http.MethodPost, path: "/api/internal/restore", body: `{"name":"a"}`, status: http.StatusForbidden},
+		{method: http.MethodPost, path: "/api/internal/transfers", body: `{}`, status: http.StatusForbidden},
+		{method: http.MethodGet, path: "/api/internal/audit", status: http.StatusForbidden},
+		{method: http.MethodGet, path: "/api/user/transfers", status: http.StatusUnauthorized},
+		{method: http.MethodGet, path: "/qweasdzx/qr?format=gif", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			logger, err := zap.NewDevelopment()
+			require.NoError(t, err)
+
+			// short path routes must not be called, storage has no expectations
+			st := mockapp.NewStorage(t)
+
+			cfg, err := config.New(logger)
+			require.NoError(t, err)
+			cfg.RedirectScheme = "https"
+			cfg.BackupDir = t.TempDir()
+
+			shorty, err := NewShorty(logger, st, cfg)
+			require.NoError(t, err)
+
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+			shorty.http.Handler().ServeHTTP(w, r)
+			res := w.Result()
+			_ = res.Body.Close()
+
+			assert.Equal(t, tt.status, res.StatusCode)
+		})
+	}
+}
+
+func TestShorty_InternalRoutes(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		expect func(st *mockapp.Storage)
+	}{
+		{
+			method: http.MethodGet,
+			path:   "/api/internal/stats",
+			status: http.StatusOK,
+			expect: func(st *mockapp.Storage) {
+				st.EXPECT().Stats(mock.Anything).Return(&model.Stats{URLs: 1, Users: 1}, nil)
+			},
+		},
+		{
+			method: http.MethodPost,
+			path:   "/api/internal/backup",
+			status: http.StatusCreated,
+			expect: func(st *mockapp.Storage) {
+				st.EXPECT().ScanURLs(mock.Anything, "", mock.Anything).Return(nil)
+			},
+		},
+		{
+			method: http.MethodPost,
+			path:   "/api/internal/restore",
+			body:   `{"name":"snapshot-missing.ndjson.gz"}`,
+			status: http.StatusNotFound,
+			expect: func(*mockapp.Storage) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			logger, err := zap.NewDevelopment()
+			require.NoError(t, err)
+
+			// each route must reach its own handler, storage has only its expectations
+			st := mockapp.NewStorage(t)
+			tt.expect(st)
+
+			t.Setenv("TRUSTED_SUBNETS", "192.0.2.0/24")
+			cfg, err := config.New(logger)
+			require.NoError(t, err)
+			cfg.BackupDir = t.TempDir()
+
+			shorty, err := NewShorty(logger, st, cfg)
+			require.NoError(t, err)
+
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.RemoteAddr = "192.0.2.1:1234"
+			if tt.body != "" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+			shorty.http.Handler().ServeHTTP(w, r)
+			res := w.Result()
+			_ = res.Body.Close()
+
+			assert.Equal(t, tt.status, res.StatusCode)
+		})
+	}
+}
+
+func TestShorty_PasswordProtected(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	st := mockapp.NewStorage(t)
+	st.On("Store", mock.Anything, mock.Anything, false).Return(
+		func(_ context.Context, url *model.URL, _ bool) (string, error) {
+			st.EXPECT().Get(mock.Anything, url.Short).Return(url, nil)
+			return "", nil
+		})
+
+	cfg, err := config.New(logger)
+	require.NoError(t, err)
+
+	shorty, err := NewShorty(logger, st, cfg)
+	require.NoError(t, err)
+
+	serve := func(r *http.Request) (*http.Response, string) {
+		w := httptest.NewRecorder()
+		shorty.http.Handler().ServeHTTP(w, r)
+		res := w.Result()
+		body, errB := io.ReadAll(res.Body)
+		require.NoError(t, errB)
+		require.NoError(t, res.Body.Close())
+		return res, string(body)
+	}
+	postForm := func(path, password string) (*http.Response, string) {
+		r := httptest.NewRequest(http.MethodPost, path,
+			strings.NewReader(url.Values{"password": {password}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return serve(r)
+	}
+
+	// Store protected URL
+	r := httptest.NewRequest(http.MethodPost, "/api/shorten",
+		strings.NewReader(`{"url":"https://aaa.bbb/doc","password":"secret"}`))
+	r.Header.Set("Content-Type", "application/json")
+	res, body := serve(r)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	var shortenResp struct {
+		Result string `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &shortenResp))
+	u, err := url.Parse(shortenResp.Result)
+	require.NoError(t, err)
+
+	// Form is served instead of redirect
+	res, body = serve(httptest.NewRequest(http.MethodGet, u.Path, nil))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Header.Get("Location"))
+	assert.Equal(t, "no-store", res.Header.Get("Cache-Control"))
+	assert.Contains(t, res.Header.Get("Content-Type"), "text/html")
+	assert.Contains(t, body, `<form method="post">`)
+
+	// Wrong password
+	res, body = postForm(u.Path, "wrong")
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Empty(t, res.Header.Get("Location"))
+	assert.Contains(t, body, "Wrong password.")
+
+	// Correct password
+	res, _ = postForm(u.Path, "secret")
+	assert.Equal(t, http.StatusSeeOther, res.StatusCode)
+	assert.Equal(t, "https://aaa.bbb/doc", res.Header.Get("Location"))
+	assert.Equal(t, "no-store", res.Header.Get("Cache-Control"))
+}
+
+func TestShorty_Preview(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	st := mockapp.NewStorage(t)
+	st.On("Store", mock.Anything, mock.Anything, false).Return(
+		func(_ context.Context, url *model.URL, _ bool) (string, error) {
+			url.Created = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+			st.EXPECT().Get(mock.Anything, url.Short).Return(url, nil)
+			return "", nil
+		})
+
+	cfg, err := config.New(logger)
+	require.NoError(t, err)
+
+	shorty, err := NewShorty(logger, st, cfg)
+	require.NoError(t, err)
+
+	serve := func(method, path, body string) (*http.Response, string) {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		shorty.http.Handler().ServeHTTP(w, r)
+		res := w.Result()
+		b, errB := io.ReadAll(res.Body)
+		require.NoError(t, errB)
+		require.NoError(t, res.Body.Close())
+		return res, string(b)
+	}
+	shorten := func(body string) string {
+		res, b := serve(http.MethodPost, "/api/shorten", body)
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		var shortenResp struct {
+			Result string `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(b), &shortenResp))
+		u, errP := url.Parse(shortenResp.Result)
+		require.NoError(t, errP)
+		return u.Path
+	}
+
+	short := shorten(`{"url":"https://aaa.bbb/doc?a=1&b=2","title":"Docs <beta>","passthrough":true}`)
+	forced := shorten(`{"url":"https://ccc.ddd","preview":true}`)
+
+	tests := []struct {
+		name     string
+		path     string
+		preview  bool
+		location string
+		contains []string
+	}{
+		{
+			name:     "redirect",
+			path:     short,
+			location: "https://aaa.bbb/doc?a=1&b=2",
+		},
+		{
+			name:    "plus suffix",
+			path:    short + "+",
+			preview: true,
+			contains: []string{
+				"<h1>Docs &lt;beta&gt;</h1>",
+				`<a href="https://aaa.bbb/doc?a=1&amp;b=2" rel="noreferrer noopener">Continue</a>`,
+				`<time datetime="2024-05-01">May 1, 2024</time>`,
+			},
+		},
+		{
+			name:     "preview query param",
+			path:     short + "?preview=1&c=3",
+			preview:  true,
+			contains: []string{`<a href="https://aaa.bbb/doc?a=1&amp;b=2&amp;c=3"`},
+		},
+		{
+			name:     "preview query param disabled",
+			path:     short + "?preview=0",
+			location: "https://aaa.bbb/doc?a=1&b=2&preview=0",
+		},
+		{
+			name:     "forced by link",
+			path:     forced,
+			preview:  true,
+			contains: []string{"<title>Link preview</title>", `<a href="https://ccc.ddd"`},
+		},
+		{
+			name:     "plus suffix after passthrough path",
+			path:     short + "/x+",
+			location: "https://aaa.bbb/doc/x+?a=1&b=2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := serve(http.MethodGet, tt.path, "")
+			if !tt.preview {
+				assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+				assert.Equal(t, tt.location, res.Header.Get("Location"))
+				return
+			}
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Empty(t, res.Header.Get("Location"))
+			assert.Equal(t, "no-store", res.Header.Get("Cache-Control"))
+			assert.Contains(t, res.Header.Get("Content-Type"), "text/html")
+			for _, s := range tt.contains {
+				assert.Contains(t, body, s)
+			}
+		})
+	}
+}
+
+func TestShorty_RedirectCache(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	st := mockapp.NewStorage(t)
+	st.On("Store", mock.Anything, mock.Anything, false).Return(
+		func(_ context.Context, url *model.URL, _ bool) (string, error) {
+			st.EXPECT().Get(mock.Anything, url.Short).Return(url, nil)
+			return "", nil
+		})
+
+	t.Setenv("REDIRECT_MAX_AGE", "1h")
+	cfg, err := config.New(logger)
+	require.NoError(t, err)
+
+	shorty, err := NewShorty(logger, st, cfg)
+	require.NoError(t, err)
+
+	serve := func(method, path, body string) (*http.Response, string) {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		shorty.http.Handler().ServeHTTP(w, r)
+		res := w.Result()
+		b, errB := io.ReadAll(res.Body)
+		require.NoError(t, errB)
+		require.NoError(t, res.Body.Close())
+		return res, string(b)
+	}
+
+	tests := []struct {
+		name  string
+		body  string
+		code  int
+		cache string
+	}{
+		{
+			name:  "temporary",
+			body:  `{"url":"https://aaa.bbb","redirect":302}`,
+			code:  http.StatusFound,
+			cache: "no-store",
+		},
+		{
+			name:  "permanent",
+			body:  `{"url":"https://aaa.bbb","redirect":301}`,
+			code:  http.StatusMovedPermanently,
+			cache: "public, max-age=3600",
+		},
+		{
+			name:  "permanent with variants",
+			body:  `{"url":"https://aaa.bbb","redirect":308,"variants":[{"url":"https://ccc.ddd","weight":1}]}`,
+			code:  http.StatusPermanentRedirect,
+			cache: "no-store",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := serve(http.MethodPost, "/api/shorten", tt.body)
+			require.Equal(t, http.StatusCreated, res.StatusCode)
+			var shortenResp struct {
+				Result string `json:"result"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(body), &shortenResp))
+			u, err := url.Parse(shortenResp.Result)
+			require.NoError(t, err)
+
+			res, _ = serve(http.MethodGet, u.Path, "")
+			assert.Equal(t, tt.code, res.StatusCode)
+			assert.Equal(t, tt.cache, res.Header.Get("Cache-Control"))
+		})
+	}
+}
+
+func TestShorty_APIKeys(t *testing.T) {
+	logger := zap.NewNop()
+	cfg, err := config.New(logger)
+	require.NoError(t, err)
+	cfg.RedirectScheme = "https"
+
+	shorty, err := NewShorty(logger, memory.New(), cfg)
+	require.NoError(t, err)
+
+	do := func(method, path, body string, auth func(r *http.Request)) *http.Response {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		if auth != nil {
+			auth(r)
+		}
+		w := httptest.NewRecorder()
+		shorty.http.Handler().ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	// anonymous session
+	res := do(http.MethodPost, "/api/shorten", `{"url":"https://aaa.bbb/ccc"}`, nil)
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	require.Len(t, res.Cookies(), 1)
+	cookie := res.Cookies()[0]
+	withCookie := func(r *http.Request) { r.AddCookie(cookie) }
+
+	res = do(http.MethodPost, "/api/user/keys", `{"name":"ci","scopes":["read"]}`, withCookie)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	var key struct {
+		ID  string `json:"id"`
+		Key string `json:"key"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&key))
+	_ = res.Body.Close()
+	withKey := func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+key.Key) }
+
+	// key has read scope only
+	res = do(http.MethodGet, "/api/user/urls", "", withKey)
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Cookies())
+	assert.Contains(t, string(body), "https://aaa.bbb/ccc")
+
+	for _, tt := range []struct{ method, path, body string }{
+		{method: http.MethodPost, path: "/api/shorten", body: `{"url":"https://aaa.bbb/ddd"}`},
+		{method: http.MethodDelete, path: "/api/user/urls", body: `["aaa"]`},
+		{method: http.MethodPost, path: "/api/user/keys", body: `{"scopes":["delete"]}`},
+		{method: http.MethodGet, path: "/api/user/keys"},
+	} {
+		res = do(tt.method, tt.path, tt.body, withKey)
+		_ = res.Body.Close()
+		assert.Equal(t, http.StatusForbidden, res.StatusCode, tt.method+" "+tt.path)
+	}
+
+	// revoked key is rejected
+	res = do(http.MethodDelete, "/api/user/keys/"+key.ID, "", withCookie)
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+	res = do(http.MethodGet, "/api/user/urls", "", withKey)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestShorty_StrictAuth(t *testing.T) {
+	logger := zap.NewNop()
+	cfg, err := config.New(logger)
+	require.NoError(t, err)
+	cfg.StrictAuth = true
+
+	shorty, err := NewShorty(logger, memory.New(), cfg)
+	require.NoError(t, err)
+
+	do := func(method, path, body string, cookie *http.Cookie) *http.Response {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		shorty.http.Handler().ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	// user is not created implicitly
+	for _, path := range []string{"/api/shorten", "/"} {
+		res := do(http.MethodPost, path, `{"url":"https://aaa.bbb/ccc"}`, nil)
+		body, errB := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		require.NoError(t, errB)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, path)
+		assert.Contains(t, string(body), "session cookie is missing")
+		assert.Empty(t, res.Cookies())
+	}
+
+	// reads are served as in normal mode
+	res := do(http.MethodGet, "/api/user/urls", "", nil)
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "unauthenticated")
+	assert.Len(t, res.Cookies(), 1)
+
+	// explicit anonymous session
+	res = do(http.MethodPost, "/api/user/anonymous", "", nil)
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	require.Len(t, res.Cookies(), 1)
+	cookie := res.Cookies()[0]
+
+	res = do(http.MethodPost, "/api/shorten", `{"url":"https://aaa.bbb/ccc"}`, cookie)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Empty(t, res.Cookies())
+}
+
+func TestShorty_JWTKeys(t *testing.T) {
+	logger := zap.NewNop()
+	cfg, err := config.New(logger)
+	require.NoError(t, err)
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	key, err := auth.ParseKey("main", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	keys, err := auth.NewKeySet(key)
+	require.NoError(t, err)
+	cfg.GetAuthorizer().WithKeySet(keys, false)
+
+	shorty, err := NewShorty(logger, memory.New(), cfg)
+	require.NoError(t, err)
+
+	do := func(method, path, body string, cookie *http.Cookie) *http.Response {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		shorty.http.Handler().ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	res := do(http.MethodGet, "/.well-known/jwks.json", "", nil)
+	var jwks auth.JWKS
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&jwks))
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.NotEmpty(t, res.Header.Get("Cache-Control"))
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "main", jwks.Keys[0].Kid)
+	assert.Equal(t, "EdDSA", jwks.Keys[0].Alg)
+
+	// session issued with key pair is accepted
+	res = do(http.MethodPost, "/api/shorten", `{"url":"https://aaa.bbb/ccc"}`, nil)
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	require.Len(t, res.Cookies(), 1)
+	cookie := res.Cookies()[0]
+
+	res = do(http.MethodGet, "/api/user/urls", "", cookie)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Cookies())
+}
+
+func TestShorty_Logout(t *testing.T) {
+	logger := zap.NewNop()
+	cfg, err := config.New(logger)
+	require.NoError(t, err)
+	cfg.StrictAuth = true
+	// every token expires in less than refresh interval, so it's re-issued when used
+	cfg.GetAuthorizer().WithSessionTTL(time.Hour, time.Hour)
+
+	shorty, err := NewShorty(logger, memory.New(), cfg)
+	require.NoError(t, err)
+
+	do := func(method, path, body string, cookie *http.Cookie) *http.Response {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		shorty.http.Handler().ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	res := do(http.MethodPost, "/api/user/anonymous", "", nil)
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	require.Len(t, res.Cookies(), 1)
+	cookie := res.Cookies()[0]
+
+	res = do(http.MethodPost, "/api/shorten", `{"url":"https://aaa.bbb/ccc"}`, cookie)
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	require.Len(t, res.Cookies(), 1)
+	refreshed := res.Cookies()[0]
+
+	res = do(http.MethodPost, "/api/user/logout", "", refreshed)
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+	require.Len(t, res.Cookies(), 1)
+	assert.Empty(t, res.Cookies()[0].Value)
+	assert.Negative(t, res.Cookies()[0].MaxAge)
+
+	// all tokens of session are revoked
+	for _, c := range []*http.Cookie{cookie, refreshed} {
+		res = do(http.MethodPost, "/api/shorten", `{"url":"https://aaa.bbb/ddd"}`, c)
+		body, errB := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		require.NoError(t, errB)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		assert.Contains(t, string(body), "session is revoked")
+	}
+}
+
+func TestShorty_SSO(t *testing.T) {
+	idp, err := oidctest.NewProvider("shorty", "secret")
+	require.NoError(t, err)
+	defer idp.Close()
+
+	logger := zap.NewNop()
+	cfg, err := config.New(logger)
+	require.NoError(t, err)
+	cfg.StrictAuth = true
+	cfg.OIDC.Issuer = idp.Issuer()
+	cfg.OIDC.ClientID = "shorty"
+	cfg.OIDC.ClientSecret = "secret"
+	cfg.OIDC.RedirectURL = cfg.BaseURL + "/api/user/sso/callback"
+
+	shorty, err := NewShorty(logger, memory.New(), cfg)
+	require.NoError(t, err)
+
+	do := func(path string, cookies ...*http.Cookie) *http.Response {
+		r := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		shorty.http.Handler().ServeHTTP(w, r)
+		return w.Result()
+	}
+	idpClient := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	// login redirects to identity provider, which redirects back to callback
+	login := func(returnTo string) (*http.Cookie, string) {
+		res := do("/api/user/sso/login?return=" + url.QueryEscape(returnTo))
+		_ = res.Body.Close()
+		require.Equal(t, http.StatusFound, res.StatusCode)
+		var flow *http.Cookie
+		for _, c := range res.Cookies() {
+			if c.Name == "shortySSO" {
+				flow = c
+			}
+		}
+		require.NotNil(t, flow)
+		idpRes, errIdp := idpClient.Get(res.Header.Get("Location"))
+		require.NoError(t, errIdp)
+		_ = idpRes.Body.Close()
+		require.Equal(t, http.StatusFound, idpRes.StatusCode)
+		callback, errURL := url.Parse(idpRes.Header.Get("Location"))
+		require.NoError(t, errURL)
+		return flow, callback.RequestURI()
+	}
+
+	flow, callback := login("")
+	res := do(callback, flow)
+	var acc httpmodel.AccountResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&acc))
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "user@example.com", acc.Login)
+	var sess *http.Cookie
+	for _, c := range res.Cookies() {
+		if c.Name == "shortySessID" {
+			sess = c
+		}
+	}
+	require.NotNil(t, sess)
+
+	// session of signed in user is accepted in strict mode
+	res = do("/api/user/urls", sess)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// callback cannot be completed without flow cookie
+	res = do(callback)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	// user is redirected to local return path
+	flow, callback = login("/api/user/urls")
+	res = do(callback, flow)
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusFound, res.StatusCode)
+	assert.Equal(t, "/api/user/urls", res.Header.Get("Location"))
+
+	// identity provider denied login
+	res = do("/api/user/sso/callback?error=access_denied", flow)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestShorty_Workspaces(t *testing.T) {
+	logger := zap.NewNop()
+	cfg, err := config.New(logger)
+	require.NoError(t, err)
+	cfg.StrictAuth = true
+
+	shorty, err := NewShorty(logger, memory.New(), cfg)
+	require.NoError(t, err)
+
+	do := func(method, path, body string, cookie *http.Cookie) *http.Response {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		shorty.http.Handler().ServeHTTP(w, r)
+		return w.Result()
+	}
+	session := func(path, body string) (*http.Cookie, string) {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		shorty.http.Handler().ServeHTTP(w, r)
+		res := w.Result()
+		var acc httpmodel.AccountResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&acc))
+		_ = res.Body.Close()
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		require.Len(t, res.Cookies(), 1)
+		return res.Cookies()[0], acc.UserID
+	}
+	owner, ownerID := session("/api/user/anonymous", "")
+	member, _ := session("/api/user/register", `{"login":"bob","password":"correct-horse-battery"}`)
+
+	res := do(http.MethodPost, "/api/workspaces", `{"name":"Marketing"}`, owner)
+	var ws struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&ws))
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	wsPath := "/api/workspaces/" + ws.ID
+
+	res = do(http.MethodGet, wsPath+"/urls", "", member)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	res = do(http.MethodPut, wsPath+"/members", `{"login":"bob","role":"viewer"}`, owner)
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res = do(http.MethodPost, wsPath+"/shorten", `{"url":"https://aaa.bbb/ccc"}`, owner)
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	// viewer lists workspace links, but cannot create them
+	res = do(http.MethodGet, wsPath+"/urls", "", member)
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, string(body), "https://aaa.bbb/ccc")
+	res = do(http.MethodPost, wsPath+"/shorten", `{"url":"https://aaa.bbb/ddd"}`, member)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	// workspace links are not personal links of creator
+	res = do(http.MethodGet, "/api/user/urls", "", owner)
+	body, err = io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "https://aaa.bbb/ccc")
+
+	res = do(http.MethodDelete, wsPath+"/members/"+ownerID, "", owner)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+}
+
+func TestShorty_Transfers(t *testing.T) {
+	logger := zap.NewNop()
+	cfg, err := config.New(logger)
+	require.NoError(t, err)
+
+	shorty, err := NewShorty(logger, memory.New(), cfg)
+	require.NoError(t, err)
+
+	do := func(method, path, body string, cookie *http.Cookie) *http.Response {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		if cookie != nil {
+			r.AddCookie(cookie)
+		} else {
+			r.RemoteAddr = "127.0.0.1:12345"
+		}
+		w := httptest.NewRecorder()
+		shorty.http.Handler().ServeHTTP(w, r)
+		return w.Result()
+	}
+	session := func(path, body string) (*http.Cookie, string) {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		shorty.http.Handler().ServeHTTP(w, r)
+		res := w.Result()
+		var acc httpmodel.AccountResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&acc))
+		_ = res.Body.Close()
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		require.Len(t, res.Cookies(), 1)
+		return res.Cookies()[0], acc.UserID
+	}
+	decode := func(res *http.Response, v any) {
+		t.Helper()
+		require.NoError(t, json.NewDecoder(res.Body).Decode(v))
+		_ = res.Body.Close()
+	}
+	owner, ownerID := session("/api/user/anonymous", "")
+	recipient, _ := session("/api/user/register", `{"login":"bob","password":"correct-horse-battery"}`)
+
+	var short struct {
+		Result string `json:"result"`
+	}
+	res := do(http.MethodPost, "/api/shorten", `{"url":"https://aaa.bbb/ccc"}`, owner)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	decode(res, &short)
+	hash := short.Result[strings.LastIndex(short.Result, "/")+1:]
+
+	res = do(http.MethodPost, "/api/user/transfers", `{"to_login":"bob","shorts":["`+hash+`"]}`, owner)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	var offer struct {
+		ID string `json:"id"`
+	}
+	decode(res, &offer)
+
+	// only recipient accepts offer
+	res = do(http.MethodPost, "/api/user/transfers/"+offer.ID+"/accept", "", owner)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	res = do(http.MethodPost, "/api/user/transfers/"+offer.ID+"/accept", "", recipient)
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res = do(http.MethodGet, "/api/user/urls", "", recipient)
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "https://aaa.bbb/ccc")
+	res = do(http.MethodGet, "/api/user/transfers", "", owner)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	// administrator returns link to previous owner
+	res = do(http.MethodPost, "/api/internal/transfers", `{"from_user_id":"`+ownerID+`","to_login":"bob"}`, nil)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res = do(http.MethodGet, "/api/internal/audit?limit=1", "", nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var events []struct {
+		Action string `json:"action"`
+		From   string `json:"from_user_id"`
+		To     string `json:"to_user_id"`
+	}
+	decode(res, &events)
+	require.Len(t, events, 1)
+	assert.Equal(t, model.AuditTransferAccepted, events[0].Action)
+	assert.Equal(t, ownerID, events[0].From)
+
+	res = do(http.MethodPost, "/api/internal/transfers",
+		`{"from_user_id":"`+events[0].To+`","to_user_id":"`+ownerID+`"}`, nil)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res = do(http.MethodGet, "/api/user/urls", "", owner)
+	body, err = io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "https://aaa.bbb/ccc")
+}
+
+func TestShorty_RateLimits(t *testing.T) {
+	logger := zap.NewNop()
+	t.Setenv("RATE_LIMITS", "POST /api/shorten=1/1m")
+	cfg, err := config.New(logger)
+	require.NoError(t, err)
+
+	shorty, err := NewShorty(logger, memory.New(), cfg)
+	require.NoError(t, err)
+
+	do := func() *http.Response {
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://aaa.bbb/ccc"}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		shorty.http.Handler().ServeHTTP(w, r)
+		res := w.Result()
+		_ = res.Body.Close()
+		return res
+	}
+	assert.Equal(t, http.StatusCreated, do().StatusCode)
+	res := do()
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.Equal(t, "60", res.Header.Get("Retry-After"))
+
+	// shared buckets are kept only in database
+	cfg.RateLimit.Shared = true
+	_, err = NewShorty(logger, memory.New(), cfg)
+	assert.Error(t, err)
+}
+
+func TestShorty_Quotas(t *testing.T) {
+	logger := zap.NewNop()
+	t.Setenv("QUOTA_TIERS", "free=2/2,pro=0/10")
+	t.Setenv("QUOTA_DEFAULT_TIER", "free")
+	cfg, err := config.New(logger)
+	require.NoError(t, err)
+
+	shorty, err := NewShorty(logger, memory.New(), cfg)
+	require.NoError(t, err)
+
+	do := func(method, path, body string, cookie *http.Cookie) *http.Response {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		if cookie != nil {
+			r.AddCookie(cookie)
+		} else {
+			r.RemoteAddr = "127.0.0.1:12345"
+		}
+		w := httptest.NewRecorder()
+		shorty.http.Handler().ServeHTTP(w, r)
+		return w.Result()
+	}
+	decode := func(res *http.Response, v any) {
+		t.Helper()
+		require.NoError(t, json.NewDecoder(res.Body).Decode(v))
+		_ = res.Body.Close()
+	}
+	res := do(http.MethodPost, "/api/user/anonymous", "", nil)
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	require.Len(t, res.Cookies(), 1)
+	cookie := res.Cookies()[0]
+
+	res = do(http.MethodPost, "/api/shorten/batch", `[{"correlation_id":"1","original_url":"https://aaa.bbb/1"},`+
+		`{"correlation_id":"2","original_url":"https://aaa.bbb/2"},`+
+		`{"correlation_id":"3","original_url":"https://aaa.bbb/3"}]`, cookie)
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+	var exceeded httpmodel.QuotaExceededResponse
+	decode(res, &exceeded)
+	assert.Equal(t, httpmodel.QuotaExceededResponse{
+		Error:     exceeded.Error,
+		Quota:     "batch",
+		Limit:     2,
+		Requested: 3,
+		Remaining: 2,
+	}, exceeded)
+
+	res = do(http.MethodPost, "/api/shorten/batch", `[{"correlation_id":"1","original_url":"https://aaa.bbb/1"},`+
+		`{"correlation_id":"2","original_url":"https://aaa.bbb/2"}]`, cookie)
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	res = do(http.MethodPost, "/api/shorten", `{"url":"https://aaa.bbb/3"}`, cookie)
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+	decode(res, &exceeded)
+	assert.Equal(t, "urls", exceeded.Quota)
+	assert.Zero(t, exceeded.Remaining)
+
+	type quotaUsage struct {
+		UserID        string `json:"user_id"`
+		Tier          string `json:"tier"`
+		URLs          int    `json:"urls"`
+		RemainingURLs *int   `json:"remaining_urls"`
+	}
+	var usage quotaUsage
+	res = do(http.MethodGet, "/api/user/quota", "", cookie)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	decode(res, &usage)
+	assert.Equal(t, "free", usage.Tier)
+	assert.Equal(t, 2, usage.URLs)
+
+	// administrator moves user to another tier
+	res = do(http.MethodPut, "/api/internal/quotas/"+usage.UserID, `{"tier":"pro","max_urls":1}`, nil)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res = do(http.MethodPut, "/api/internal/quotas/"+usage.UserID, `{"tier":"pro"}`, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var pro quotaUsage
+	decode(res, &pro)
+	assert.Equal(t, "pro", pro.Tier)
+	assert.Nil(t, pro.RemainingURLs)
+	res = do(http.MethodPost, "/api/shorten", `{"url":"https://aaa.bbb/3"}`, cookie)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	res = do(http.MethodGet, "/api/internal/quotas", "", nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var tiers httpmodel.TiersResponse
+	decode(res, &tiers)
+	assert.Equal(t, "free", tiers.DefaultTier)
+	assert.Len(t, tiers.Tiers, 2)
+
+	res = do(http.MethodDelete, "/api/internal/quotas/"+usage.UserID, "", nil)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	res = do(http.MethodGet, "/api/internal/quotas/"+usage.UserID, "", nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	decode(res, &usage)
+	assert.Equal(t, "free", usage.Tier)
+	require.NotNil(t, usage.RemainingURLs)
+	assert.Zero(t, *usage.RemainingURLs)
+
+	// admin api is available only to trusted subnets
+	res = do(http.MethodGet, "/api/internal/quotas/"+usage.UserID, "", cookie)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+}
@@ -0,0 +1,343 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/adwski/shorty/internal/config"
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/storage/database"
+	"github.com/adwski/shorty/internal/storage/migration"
+	"github.com/adwski/shorty/internal/user"
+	"go.uber.org/zap"
+)
+
+const (
+	// copyChunkSize is number of urls stored with single StoreBatch call during export and import.
+	copyChunkSize = 1000
+
+	usage = `Usage: shortener [flags] [command]
+
+Without command shortener starts the server. Commands use the same flags,
+config file and environment as the server.
+
+Commands:
+  migrate up|down [steps]|status  manage database schema, down rolls back one migration by default
+  export <storage>                copy urls from configured storage to another storage
+  import <storage>                copy urls from another storage to configured storage
+  migrate-storage <src> <dst> [checkpoint]
+                                  move all urls including deleted ones keeping their ids and timestamps,
+                                  progress is saved to checkpoint file and interrupted run is resumed from it
+  verify-storage <src> <dst>      compare record counts and checksums of two storages
+  purge-deleted                   permanently remove deleted urls
+  stats                           print storage statistics
+  issue-token [user-id]           issue auth token for user, new user is created if id is omitted
+
+Storage is a postgres DSN (postgres://...) or a path to a file storage.
+
+Storage migration can be run while server is serving requests. Repeated run overwrites
+records in destination and catches up with changes made in source since previous run.
+Run it once more after server is stopped or switched to destination and verify storages.
+`
+)
+
+// ErrUnknownCommand is returned for unknown admin commands.
+var ErrUnknownCommand = errors.New("unknown command")
+
+// RunCommand runs admin command specified with positional arguments in config.
+// Command output is written to out. Non-zero code is returned if command fails.
+func RunCommand(logger *zap.Logger, cfg *config.Config, out io.Writer) int {
+	ctx, cancel := signal.NotifyContext(context.Background(),
+		os.Interrupt,
+		syscall.SIGTERM,
+		syscall.SIGQUIT,
+	)
+	defer cancel()
+
+	if err := runCommand(ctx, logger, cfg, out); err != nil {
+		if errors.Is(err, ErrUnknownCommand) {
+			_, _ = io.WriteString(out, usage)
+		}
+		logger.Error("command failed", zap.Strings("args", cfg.Args), zap.Error(err))
+		return 1
+	}
+	return 0
+}
+
+func runCommand(ctx context.Context, logger *zap.Logger, cfg *config.Config, out io.Writer) error {
+	var (
+		cmd  = cfg.Args[0]
+		args = cfg.Args[1:]
+	)
+	switch cmd {
+	case "migrate":
+		return migrateCommand(cfg.Storage.DatabaseDSN, args, out)
+	case "export":
+		if len(args) != 1 {
+			return errors.New("export requires destination storage")
+		}
+		return copyCommand(ctx, logger, cfg.Storage, storageConfig(args[0], cfg.Storage.TraceDB), out)
+	case "import":
+		if len(args) != 1 {
+			return errors.New("import requires source storage")
+		}
+		return copyCommand(ctx, logger, storageConfig(args[0], cfg.Storage.TraceDB), cfg.Storage, out)
+	case "migrate-storage":
+		if len(args) < 2 || len(args) > 3 {
+			return errors.New("migrate-storage requires source and destination storages")
+		}
+		var checkpoint string
+		if len(args) == 3 {
+			checkpoint = args[2]
+		}
+		return migrateStorageCommand(ctx, logger,
+			storageConfig(args[0], cfg.Storage.TraceDB), storageConfig(args[1], cfg.Storage.TraceDB), checkpoint, out)
+	case "verify-storage":
+		if len(args) != 2 {
+			return errors.New("verify-storage requires two storages")
+		}
+		return verifyStorageCommand(ctx, logger,
+			storageConfig(args[0], cfg.Storage.TraceDB), storageConfig(args[1], cfg.Storage.TraceDB), out)
+	case "purge-deleted":
+		return withStorage(ctx, logger, cfg.Storage, func(store Storage) error {
+			num, err := store.PurgeDeleted(ctx)
+			if err != nil {
+				return fmt.Errorf("cannot purge deleted urls: %w", err)
+			}
+			_, _ = fmt.Fprintf(out, "purged %d deleted urls\n", num)
+			return nil
+		})
+	case "stats":
+		return withStorage(ctx, logger, cfg.Storage, func(store Storage) error {
+			stats, err := store.Stats(ctx)
+			if err != nil {
+				return fmt.Errorf("cannot get stats: %w", err)
+			}
+			return writeJSON(out, stats)
+		})
+	case "issue-token":
+		return issueTokenCommand(cfg, args, out)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownCommand, cmd)
+	}
+}
+
+func migrateCommand(dsn string, args []string, out io.Writer) error {
+	if dsn == "" {
+		return errors.New("database dsn is not configured")
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("%w: migrate requires up, down or status", ErrUnknownCommand)
+	}
+	switch args[0] {
+	case "up":
+		changed, err := database.MigrateUp(dsn)
+		if err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+		if !changed {
+			_, _ = io.WriteString(out, "database is up to date\n")
+			return nil
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("invalid number of steps: %w", err)
+			}
+		}
+		if err := database.MigrateDown(dsn, steps); err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+	case "status":
+	default:
+		return fmt.Errorf("%w: migrate %q", ErrUnknownCommand, args[0])
+	}
+	status, err := database.GetMigrationStatus(dsn)
+	if err != nil {
+		return fmt.Errorf("cannot get migration status: %w", err)
+	}
+	_, _ = fmt.Fprintf(out, "version: %d, latest: %d, dirty: %t\n", status.Version, status.Latest, status.Dirty)
+	return nil
+}
+
+func issueTokenCommand(cfg *config.Config, args []string, out io.Writer) error {
+	var (
+		u   *user.User
+		err error
+	)
+	switch len(args) {
+	case 0:
+		u, err = user.New()
+	case 1:
+		u, err = user.NewFromUserID(args[0])
+	default:
+		return errors.New("issue-token accepts single user id")
+	}
+	if err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+	token, err := cfg.GetAuthorizer().CreateToken(u)
+	if err != nil {
+		return fmt.Errorf("cannot issue token: %w", err)
+	}
+	_, _ = fmt.Fprintf(out, "user_id: %s\ntoken: %s\n", u.ID, token)
+	return nil
+}
+
+// copyCommand copies all not deleted urls between storages. URLs are stored in chunks,
+// if chunk cannot be stored, its urls are stored one by one and failed ones are skipped.
+func copyCommand(ctx context.Context, logger *zap.Logger, srcCfg, dstCfg *config.Storage, out io.Writer) error {
+	if *srcCfg == *dstCfg {
+		return errors.New("source and destination storages are the same")
+	}
+	return withStorage(ctx, logger, srcCfg, func(src Storage) error {
+		return withStorage(ctx, logger, dstCfg, func(dst Storage) error {
+			var (
+				copied, failed int
+				chunk          = make([]model.URL, 0, copyChunkSize)
+			)
+			flush := func() error {
+				c, f, err := storeChunk(ctx, logger, dst, chunk)
+				copied, failed = copied+c, failed+f
+				chunk = chunk[:0]
+				return err
+			}
+			err := src.ScanURLs(ctx, "", func(url *model.URL) error {
+				if url.Deleted {
+					return nil
+				}
+				chunk = append(chunk, *url)
+				if len(chunk) < copyChunkSize {
+					return nil
+				}
+				return flush()
+			})
+			if err == nil && len(chunk) > 0 {
+				err = flush()
+			}
+			_, _ = fmt.Fprintf(out, "copied %d urls, failed %d\n", copied, failed)
+			if err != nil {
+				return fmt.Errorf("copy interrupted: %w", err)
+			}
+			return nil
+		})
+	})
+}
+
+func migrateStorageCommand(
+	ctx context.Context,
+	logger *zap.Logger,
+	srcCfg, dstCfg *config.Storage,
+	checkpoint string,
+	out io.Writer,
+) error {
+	if *srcCfg == *dstCfg {
+		return errors.New("source and destination storages are the same")
+	}
+	return withStorage(ctx, logger, srcCfg, func(src Storage) error {
+		return withStorage(ctx, logger, dstCfg, func(dst Storage) error {
+			m, err := migration.New(&migration.Config{
+				Logger:         logger,
+				Source:         src,
+				Destination:    dst,
+				CheckpointPath: checkpoint,
+			})
+			if err != nil {
+				return fmt.Errorf("cannot create migrator: %w", err)
+			}
+			result, err := m.Run(ctx)
+			if result != nil {
+				_, _ = fmt.Fprintf(out, "migrated %d urls (%d deleted), failed %d\n",
+					result.Migrated, result.Deleted, result.Failed)
+			}
+			if err != nil {
+				return fmt.Errorf("migration interrupted: %w", err)
+			}
+			return nil
+		})
+	})
+}
+
+func verifyStorageCommand(
+	ctx context.Context,
+	logger *zap.Logger,
+	srcCfg, dstCfg *config.Storage,
+	out io.Writer,
+) error {
+	return withStorage(ctx, logger, srcCfg, func(src Storage) error {
+		return withStorage(ctx, logger, dstCfg, func(dst Storage) error {
+			srcSummary, dstSummary, err := migration.Verify(ctx, src, dst)
+			if srcSummary != nil {
+				_ = writeJSON(out, map[string]*migration.Summary{
+					"source":      srcSummary,
+					"destination": dstSummary,
+				})
+			}
+			if err != nil {
+				return fmt.Errorf("verification failed: %w", err)
+			}
+			return nil
+		})
+	})
+}
+
+func storeChunk(ctx context.Context, logger *zap.Logger, dst Storage, chunk []model.URL) (int, int, error) {
+	err := dst.StoreBatch(ctx, chunk)
+	if err == nil {
+		return len(chunk), 0, nil
+	}
+	if ctx.Err() != nil {
+		return 0, 0, fmt.Errorf("cannot store urls: %w", err)
+	}
+	var copied, failed int
+	for i := range chunk {
+		if _, err = dst.Store(ctx, &chunk[i], false); err != nil {
+			if ctx.Err() != nil {
+				return copied, failed, fmt.Errorf("cannot store url: %w", err)
+			}
+			logger.Warn("cannot copy url", zap.String("short", chunk[i].Short), zap.Error(err))
+			failed++
+			continue
+		}
+		copied++
+	}
+	return copied, failed, nil
+}
+
+// withStorage opens storage, calls fn and closes storage afterwards.
+func withStorage(ctx context.Context, logger *zap.Logger, cfg *config.Storage, fn func(store Storage) error) error {
+	store, err := createStorage(ctx, logger, cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	return fn(store)
+}
+
+// storageConfig creates storage config from command argument.
+// Postgres DSNs are recognized by scheme, anything else is a file storage path.
+func storageConfig(arg string, trace bool) *config.Storage {
+	if strings.HasPrefix(arg, "postgres://") || strings.HasPrefix(arg, "postgresql://") {
+		return &config.Storage{DatabaseDSN: arg, TraceDB: trace}
+	}
+	return &config.Storage{FileStoragePath: arg}
+}
+
+func writeJSON(out io.Writer, v any) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("cannot encode output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adwski/shorty/internal/config"
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/storage/file"
+	"github.com/adwski/shorty/internal/storage/migration"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRunCommand(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	require.NoError(t, err)
+	ctx := context.Background()
+	user1, err := user.New()
+	require.NoError(t, err)
+	user2, err := user.New()
+	require.NoError(t, err)
+
+	var (
+		dir  = t.TempDir()
+		src  = filepath.Join(dir, "src.json")
+		dst  = filepath.Join(dir, "dst.json")
+		urls = []model.URL{
+			{Short: "aaa", Orig: "https://aaa.bbb/1", UserID: user1.ID, Title: "One"},
+			{Short: "bbb", Orig: "https://aaa.bbb/2", UserID: user1.ID},
+			{Short: "ccc", Orig: "https://aaa.bbb/3", UserID: user2.ID},
+		}
+	)
+	st, err := file.New(ctx, &file.Config{FilePath: src, Logger: logger})
+	require.NoError(t, err)
+	require.NoError(t, st.StoreBatch(ctx, urls))
+	_, err = st.DeleteUserURLs(ctx, []model.URL{{Short: "ccc", UserID: user2.ID}})
+	require.NoError(t, err)
+	st.Close()
+
+	cfg, err := config.New(logger)
+	require.NoError(t, err)
+	cfg.Storage = &config.Storage{FileStoragePath: src}
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		cfg.Args = args
+		errC := runCommand(ctx, logger, cfg, &out)
+		return out.String(), errC
+	}
+
+	out, err := run("stats")
+	require.NoError(t, err)
+	assert.Contains(t, out, `"urls": 3`)
+
+	out, err = run("export", dst)
+	require.NoError(t, err)
+	assert.Equal(t, "copied 2 urls, failed 0\n", out)
+
+	// second import into the same storage fails for existing urls
+	cfg.Storage = &config.Storage{FileStoragePath: dst}
+	out, err = run("import", src)
+	require.NoError(t, err)
+	assert.Equal(t, "copied 0 urls, failed 2\n", out)
+
+	exported, err := file.New(ctx, &file.Config{FilePath: dst, Logger: logger})
+	require.NoError(t, err)
+	u, err := exported.Get(ctx, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, user1.ID, u.UserID)
+	assert.Equal(t, "One", u.Title)
+	_, err = exported.Get(ctx, "ccc")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	exported.Close()
+
+	migrated := filepath.Join(dir, "migrated.json")
+	out, err = run("migrate-storage", src, migrated, filepath.Join(dir, "checkpoint.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "migrated 3 urls (1 deleted), failed 0\n", out)
+	assert.NoFileExists(t, filepath.Join(dir, "checkpoint.json"))
+
+	out, err = run("verify-storage", src, migrated)
+	require.NoError(t, err)
+	assert.Contains(t, out, `"records": 3`)
+
+	// copied urls get new ids
+	_, err = run("verify-storage", src, dst)
+	assert.ErrorIs(t, err, migration.ErrMismatch)
+
+	cfg.Storage = &config.Storage{FileStoragePath: src}
+	out, err = run("purge-deleted")
+	require.NoError(t, err)
+	assert.Equal(t, "purged 1 deleted urls\n", out)
+
+	out, err = run("stats")
+	require.NoError(t, err)
+	assert.Contains(t, out, `"urls": 2`)
+
+	_, err = run("export", src)
+	assert.Error(t, err)
+
+	out, err = run("issue-token")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out, "user_id: "))
+	assert.Contains(t, out, "token: ")
+
+	_, err = run("issue-token", "notauser")
+	assert.Error(t, err)
+
+	_, err = run("migrate", "status")
+	assert.Error(t, err)
+
+	_, err = run("unknown")
+	assert.ErrorIs(t, err, ErrUnknownCommand)
+}
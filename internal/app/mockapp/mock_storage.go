@@ -4,6 +4,7 @@ package mockapp
 
 import (
 	context "context"
+	time "time"
 
 	model "github.com/adwski/shorty/internal/model"
 	mock "github.com/stretchr/testify/mock"
@@ -22,59 +23,1803 @@ func (_m *Storage) EXPECT() *Storage_Expecter {
 	return &Storage_Expecter{mock: &_m.Mock}
 }
 
+// AddVariantClicks provides a mock function with given fields: ctx, clicks
+func (_m *Storage) AddVariantClicks(ctx context.Context, clicks []model.Click) error {
+	ret := _m.Called(ctx, clicks)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddVariantClicks")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []model.Click) error); ok {
+		r0 = rf(ctx, clicks)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_AddVariantClicks_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddVariantClicks'
+type Storage_AddVariantClicks_Call struct {
+	*mock.Call
+}
+
+// AddVariantClicks is a helper method to define mock.On call
+//   - ctx context.Context
+//   - clicks []model.Click
+func (_e *Storage_Expecter) AddVariantClicks(ctx interface{}, clicks interface{}) *Storage_AddVariantClicks_Call {
+	return &Storage_AddVariantClicks_Call{Call: _e.mock.On("AddVariantClicks", ctx, clicks)}
+}
+
+func (_c *Storage_AddVariantClicks_Call) Run(run func(ctx context.Context, clicks []model.Click)) *Storage_AddVariantClicks_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]model.Click))
+	})
+	return _c
+}
+
+func (_c *Storage_AddVariantClicks_Call) Return(_a0 error) *Storage_AddVariantClicks_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_AddVariantClicks_Call) RunAndReturn(run func(context.Context, []model.Click) error) *Storage_AddVariantClicks_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ClaimUserURLs provides a mock function with given fields: ctx, fromUserID, toUserID
+func (_m *Storage) ClaimUserURLs(ctx context.Context, fromUserID string, toUserID string) (int64, error) {
+	ret := _m.Called(ctx, fromUserID, toUserID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimUserURLs")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (int64, error)); ok {
+		return rf(ctx, fromUserID, toUserID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) int64); ok {
+		r0 = rf(ctx, fromUserID, toUserID)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, fromUserID, toUserID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_ClaimUserURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ClaimUserURLs'
+type Storage_ClaimUserURLs_Call struct {
+	*mock.Call
+}
+
+// ClaimUserURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - fromUserID string
+//   - toUserID string
+func (_e *Storage_Expecter) ClaimUserURLs(ctx interface{}, fromUserID interface{}, toUserID interface{}) *Storage_ClaimUserURLs_Call {
+	return &Storage_ClaimUserURLs_Call{Call: _e.mock.On("ClaimUserURLs", ctx, fromUserID, toUserID)}
+}
+
+func (_c *Storage_ClaimUserURLs_Call) Run(run func(ctx context.Context, fromUserID string, toUserID string)) *Storage_ClaimUserURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_ClaimUserURLs_Call) Return(_a0 int64, _a1 error) *Storage_ClaimUserURLs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_ClaimUserURLs_Call) RunAndReturn(run func(context.Context, string, string) (int64, error)) *Storage_ClaimUserURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Close provides a mock function with given fields:
 func (_m *Storage) Close() {
 	_m.Called()
 }
 
-// Storage_Close_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Close'
-type Storage_Close_Call struct {
+// Storage_Close_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Close'
+type Storage_Close_Call struct {
+	*mock.Call
+}
+
+// Close is a helper method to define mock.On call
+func (_e *Storage_Expecter) Close() *Storage_Close_Call {
+	return &Storage_Close_Call{Call: _e.mock.On("Close")}
+}
+
+func (_c *Storage_Close_Call) Run(run func()) *Storage_Close_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Storage_Close_Call) Return() *Storage_Close_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Storage_Close_Call) RunAndReturn(run func()) *Storage_Close_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CountUserURLs provides a mock function with given fields: ctx, userID
+func (_m *Storage) CountUserURLs(ctx context.Context, userID string) (int, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountUserURLs")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_CountUserURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CountUserURLs'
+type Storage_CountUserURLs_Call struct {
+	*mock.Call
+}
+
+// CountUserURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID string
+func (_e *Storage_Expecter) CountUserURLs(ctx interface{}, userID interface{}) *Storage_CountUserURLs_Call {
+	return &Storage_CountUserURLs_Call{Call: _e.mock.On("CountUserURLs", ctx, userID)}
+}
+
+func (_c *Storage_CountUserURLs_Call) Run(run func(ctx context.Context, userID string)) *Storage_CountUserURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_CountUserURLs_Call) Return(_a0 int, _a1 error) *Storage_CountUserURLs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_CountUserURLs_Call) RunAndReturn(run func(context.Context, string) (int, error)) *Storage_CountUserURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreateAPIKey provides a mock function with given fields: ctx, key
+func (_m *Storage) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAPIKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.APIKey) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_CreateAPIKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateAPIKey'
+type Storage_CreateAPIKey_Call struct {
+	*mock.Call
+}
+
+// CreateAPIKey is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key *model.APIKey
+func (_e *Storage_Expecter) CreateAPIKey(ctx interface{}, key interface{}) *Storage_CreateAPIKey_Call {
+	return &Storage_CreateAPIKey_Call{Call: _e.mock.On("CreateAPIKey", ctx, key)}
+}
+
+func (_c *Storage_CreateAPIKey_Call) Run(run func(ctx context.Context, key *model.APIKey)) *Storage_CreateAPIKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*model.APIKey))
+	})
+	return _c
+}
+
+func (_c *Storage_CreateAPIKey_Call) Return(_a0 error) *Storage_CreateAPIKey_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_CreateAPIKey_Call) RunAndReturn(run func(context.Context, *model.APIKey) error) *Storage_CreateAPIKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreateAccount provides a mock function with given fields: ctx, acc
+func (_m *Storage) CreateAccount(ctx context.Context, acc *model.Account) error {
+	ret := _m.Called(ctx, acc)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAccount")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Account) error); ok {
+		r0 = rf(ctx, acc)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_CreateAccount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateAccount'
+type Storage_CreateAccount_Call struct {
+	*mock.Call
+}
+
+// CreateAccount is a helper method to define mock.On call
+//   - ctx context.Context
+//   - acc *model.Account
+func (_e *Storage_Expecter) CreateAccount(ctx interface{}, acc interface{}) *Storage_CreateAccount_Call {
+	return &Storage_CreateAccount_Call{Call: _e.mock.On("CreateAccount", ctx, acc)}
+}
+
+func (_c *Storage_CreateAccount_Call) Run(run func(ctx context.Context, acc *model.Account)) *Storage_CreateAccount_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*model.Account))
+	})
+	return _c
+}
+
+func (_c *Storage_CreateAccount_Call) Return(_a0 error) *Storage_CreateAccount_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_CreateAccount_Call) RunAndReturn(run func(context.Context, *model.Account) error) *Storage_CreateAccount_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreateIdentity provides a mock function with given fields: ctx, identity
+func (_m *Storage) CreateIdentity(ctx context.Context, identity *model.Identity) error {
+	ret := _m.Called(ctx, identity)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateIdentity")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Identity) error); ok {
+		r0 = rf(ctx, identity)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_CreateIdentity_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateIdentity'
+type Storage_CreateIdentity_Call struct {
+	*mock.Call
+}
+
+// CreateIdentity is a helper method to define mock.On call
+//   - ctx context.Context
+//   - identity *model.Identity
+func (_e *Storage_Expecter) CreateIdentity(ctx interface{}, identity interface{}) *Storage_CreateIdentity_Call {
+	return &Storage_CreateIdentity_Call{Call: _e.mock.On("CreateIdentity", ctx, identity)}
+}
+
+func (_c *Storage_CreateIdentity_Call) Run(run func(ctx context.Context, identity *model.Identity)) *Storage_CreateIdentity_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*model.Identity))
+	})
+	return _c
+}
+
+func (_c *Storage_CreateIdentity_Call) Return(_a0 error) *Storage_CreateIdentity_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_CreateIdentity_Call) RunAndReturn(run func(context.Context, *model.Identity) error) *Storage_CreateIdentity_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreateTransfer provides a mock function with given fields: ctx, transfer
+func (_m *Storage) CreateTransfer(ctx context.Context, transfer *model.Transfer) error {
+	ret := _m.Called(ctx, transfer)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateTransfer")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Transfer) error); ok {
+		r0 = rf(ctx, transfer)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_CreateTransfer_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateTransfer'
+type Storage_CreateTransfer_Call struct {
+	*mock.Call
+}
+
+// CreateTransfer is a helper method to define mock.On call
+//   - ctx context.Context
+//   - transfer *model.Transfer
+func (_e *Storage_Expecter) CreateTransfer(ctx interface{}, transfer interface{}) *Storage_CreateTransfer_Call {
+	return &Storage_CreateTransfer_Call{Call: _e.mock.On("CreateTransfer", ctx, transfer)}
+}
+
+func (_c *Storage_CreateTransfer_Call) Run(run func(ctx context.Context, transfer *model.Transfer)) *Storage_CreateTransfer_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*model.Transfer))
+	})
+	return _c
+}
+
+func (_c *Storage_CreateTransfer_Call) Return(_a0 error) *Storage_CreateTransfer_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_CreateTransfer_Call) RunAndReturn(run func(context.Context, *model.Transfer) error) *Storage_CreateTransfer_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreateWorkspace provides a mock function with given fields: ctx, ws, ownerID
+func (_m *Storage) CreateWorkspace(ctx context.Context, ws *model.Workspace, ownerID string) error {
+	ret := _m.Called(ctx, ws, ownerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateWorkspace")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Workspace, string) error); ok {
+		r0 = rf(ctx, ws, ownerID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_CreateWorkspace_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateWorkspace'
+type Storage_CreateWorkspace_Call struct {
+	*mock.Call
+}
+
+// CreateWorkspace is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ws *model.Workspace
+//   - ownerID string
+func (_e *Storage_Expecter) CreateWorkspace(ctx interface{}, ws interface{}, ownerID interface{}) *Storage_CreateWorkspace_Call {
+	return &Storage_CreateWorkspace_Call{Call: _e.mock.On("CreateWorkspace", ctx, ws, ownerID)}
+}
+
+func (_c *Storage_CreateWorkspace_Call) Run(run func(ctx context.Context, ws *model.Workspace, ownerID string)) *Storage_CreateWorkspace_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*model.Workspace), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_CreateWorkspace_Call) Return(_a0 error) *Storage_CreateWorkspace_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_CreateWorkspace_Call) RunAndReturn(run func(context.Context, *model.Workspace, string) error) *Storage_CreateWorkspace_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteAPIKey provides a mock function with given fields: ctx, userID, id
+func (_m *Storage) DeleteAPIKey(ctx context.Context, userID string, id string) error {
+	ret := _m.Called(ctx, userID, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteAPIKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, userID, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_DeleteAPIKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteAPIKey'
+type Storage_DeleteAPIKey_Call struct {
+	*mock.Call
+}
+
+// DeleteAPIKey is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID string
+//   - id string
+func (_e *Storage_Expecter) DeleteAPIKey(ctx interface{}, userID interface{}, id interface{}) *Storage_DeleteAPIKey_Call {
+	return &Storage_DeleteAPIKey_Call{Call: _e.mock.On("DeleteAPIKey", ctx, userID, id)}
+}
+
+func (_c *Storage_DeleteAPIKey_Call) Run(run func(ctx context.Context, userID string, id string)) *Storage_DeleteAPIKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_DeleteAPIKey_Call) Return(_a0 error) *Storage_DeleteAPIKey_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_DeleteAPIKey_Call) RunAndReturn(run func(context.Context, string, string) error) *Storage_DeleteAPIKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteMember provides a mock function with given fields: ctx, workspaceID, userID
+func (_m *Storage) DeleteMember(ctx context.Context, workspaceID string, userID string) error {
+	ret := _m.Called(ctx, workspaceID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteMember")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, workspaceID, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_DeleteMember_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteMember'
+type Storage_DeleteMember_Call struct {
+	*mock.Call
+}
+
+// DeleteMember is a helper method to define mock.On call
+//   - ctx context.Context
+//   - workspaceID string
+//   - userID string
+func (_e *Storage_Expecter) DeleteMember(ctx interface{}, workspaceID interface{}, userID interface{}) *Storage_DeleteMember_Call {
+	return &Storage_DeleteMember_Call{Call: _e.mock.On("DeleteMember", ctx, workspaceID, userID)}
+}
+
+func (_c *Storage_DeleteMember_Call) Run(run func(ctx context.Context, workspaceID string, userID string)) *Storage_DeleteMember_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_DeleteMember_Call) Return(_a0 error) *Storage_DeleteMember_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_DeleteMember_Call) RunAndReturn(run func(context.Context, string, string) error) *Storage_DeleteMember_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteQuota provides a mock function with given fields: ctx, userID
+func (_m *Storage) DeleteQuota(ctx context.Context, userID string) error {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteQuota")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_DeleteQuota_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteQuota'
+type Storage_DeleteQuota_Call struct {
+	*mock.Call
+}
+
+// DeleteQuota is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID string
+func (_e *Storage_Expecter) DeleteQuota(ctx interface{}, userID interface{}) *Storage_DeleteQuota_Call {
+	return &Storage_DeleteQuota_Call{Call: _e.mock.On("DeleteQuota", ctx, userID)}
+}
+
+func (_c *Storage_DeleteQuota_Call) Run(run func(ctx context.Context, userID string)) *Storage_DeleteQuota_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_DeleteQuota_Call) Return(_a0 error) *Storage_DeleteQuota_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_DeleteQuota_Call) RunAndReturn(run func(context.Context, string) error) *Storage_DeleteQuota_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteTransfer provides a mock function with given fields: ctx, id
+func (_m *Storage) DeleteTransfer(ctx context.Context, id string) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteTransfer")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_DeleteTransfer_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteTransfer'
+type Storage_DeleteTransfer_Call struct {
+	*mock.Call
+}
+
+// DeleteTransfer is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *Storage_Expecter) DeleteTransfer(ctx interface{}, id interface{}) *Storage_DeleteTransfer_Call {
+	return &Storage_DeleteTransfer_Call{Call: _e.mock.On("DeleteTransfer", ctx, id)}
+}
+
+func (_c *Storage_DeleteTransfer_Call) Run(run func(ctx context.Context, id string)) *Storage_DeleteTransfer_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_DeleteTransfer_Call) Return(_a0 error) *Storage_DeleteTransfer_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_DeleteTransfer_Call) RunAndReturn(run func(context.Context, string) error) *Storage_DeleteTransfer_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteUserURLs provides a mock function with given fields: ctx, urls
+func (_m *Storage) DeleteUserURLs(ctx context.Context, urls []model.URL) (int64, error) {
+	ret := _m.Called(ctx, urls)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteUserURLs")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []model.URL) (int64, error)); ok {
+		return rf(ctx, urls)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []model.URL) int64); ok {
+		r0 = rf(ctx, urls)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []model.URL) error); ok {
+		r1 = rf(ctx, urls)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_DeleteUserURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteUserURLs'
+type Storage_DeleteUserURLs_Call struct {
+	*mock.Call
+}
+
+// DeleteUserURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - urls []model.URL
+func (_e *Storage_Expecter) DeleteUserURLs(ctx interface{}, urls interface{}) *Storage_DeleteUserURLs_Call {
+	return &Storage_DeleteUserURLs_Call{Call: _e.mock.On("DeleteUserURLs", ctx, urls)}
+}
+
+func (_c *Storage_DeleteUserURLs_Call) Run(run func(ctx context.Context, urls []model.URL)) *Storage_DeleteUserURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]model.URL))
+	})
+	return _c
+}
+
+func (_c *Storage_DeleteUserURLs_Call) Return(_a0 int64, _a1 error) *Storage_DeleteUserURLs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_DeleteUserURLs_Call) RunAndReturn(run func(context.Context, []model.URL) (int64, error)) *Storage_DeleteUserURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Get provides a mock function with given fields: ctx, key
+func (_m *Storage) Get(ctx context.Context, key string) (*model.URL, error) {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Get")
+	}
+
+	var r0 *model.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.URL, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.URL); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_Get_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Get'
+type Storage_Get_Call struct {
+	*mock.Call
+}
+
+// Get is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+func (_e *Storage_Expecter) Get(ctx interface{}, key interface{}) *Storage_Get_Call {
+	return &Storage_Get_Call{Call: _e.mock.On("Get", ctx, key)}
+}
+
+func (_c *Storage_Get_Call) Run(run func(ctx context.Context, key string)) *Storage_Get_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_Get_Call) Return(url *model.URL, err error) *Storage_Get_Call {
+	_c.Call.Return(url, err)
+	return _c
+}
+
+func (_c *Storage_Get_Call) RunAndReturn(run func(context.Context, string) (*model.URL, error)) *Storage_Get_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAPIKey provides a mock function with given fields: ctx, id
+func (_m *Storage) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAPIKey")
+	}
+
+	var r0 *model.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.APIKey, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.APIKey); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_GetAPIKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAPIKey'
+type Storage_GetAPIKey_Call struct {
+	*mock.Call
+}
+
+// GetAPIKey is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *Storage_Expecter) GetAPIKey(ctx interface{}, id interface{}) *Storage_GetAPIKey_Call {
+	return &Storage_GetAPIKey_Call{Call: _e.mock.On("GetAPIKey", ctx, id)}
+}
+
+func (_c *Storage_GetAPIKey_Call) Run(run func(ctx context.Context, id string)) *Storage_GetAPIKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_GetAPIKey_Call) Return(_a0 *model.APIKey, _a1 error) *Storage_GetAPIKey_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_GetAPIKey_Call) RunAndReturn(run func(context.Context, string) (*model.APIKey, error)) *Storage_GetAPIKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAccount provides a mock function with given fields: ctx, login
+func (_m *Storage) GetAccount(ctx context.Context, login string) (*model.Account, error) {
+	ret := _m.Called(ctx, login)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccount")
+	}
+
+	var r0 *model.Account
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.Account, error)); ok {
+		return rf(ctx, login)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.Account); ok {
+		r0 = rf(ctx, login)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Account)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, login)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_GetAccount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAccount'
+type Storage_GetAccount_Call struct {
+	*mock.Call
+}
+
+// GetAccount is a helper method to define mock.On call
+//   - ctx context.Context
+//   - login string
+func (_e *Storage_Expecter) GetAccount(ctx interface{}, login interface{}) *Storage_GetAccount_Call {
+	return &Storage_GetAccount_Call{Call: _e.mock.On("GetAccount", ctx, login)}
+}
+
+func (_c *Storage_GetAccount_Call) Run(run func(ctx context.Context, login string)) *Storage_GetAccount_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_GetAccount_Call) Return(_a0 *model.Account, _a1 error) *Storage_GetAccount_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_GetAccount_Call) RunAndReturn(run func(context.Context, string) (*model.Account, error)) *Storage_GetAccount_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetIdentity provides a mock function with given fields: ctx, issuer, subject
+func (_m *Storage) GetIdentity(ctx context.Context, issuer string, subject string) (*model.Identity, error) {
+	ret := _m.Called(ctx, issuer, subject)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetIdentity")
+	}
+
+	var r0 *model.Identity
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.Identity, error)); ok {
+		return rf(ctx, issuer, subject)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.Identity); ok {
+		r0 = rf(ctx, issuer, subject)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Identity)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, issuer, subject)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_GetIdentity_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetIdentity'
+type Storage_GetIdentity_Call struct {
+	*mock.Call
+}
+
+// GetIdentity is a helper method to define mock.On call
+//   - ctx context.Context
+//   - issuer string
+//   - subject string
+func (_e *Storage_Expecter) GetIdentity(ctx interface{}, issuer interface{}, subject interface{}) *Storage_GetIdentity_Call {
+	return &Storage_GetIdentity_Call{Call: _e.mock.On("GetIdentity", ctx, issuer, subject)}
+}
+
+func (_c *Storage_GetIdentity_Call) Run(run func(ctx context.Context, issuer string, subject string)) *Storage_GetIdentity_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_GetIdentity_Call) Return(_a0 *model.Identity, _a1 error) *Storage_GetIdentity_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_GetIdentity_Call) RunAndReturn(run func(context.Context, string, string) (*model.Identity, error)) *Storage_GetIdentity_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetMember provides a mock function with given fields: ctx, workspaceID, userID
+func (_m *Storage) GetMember(ctx context.Context, workspaceID string, userID string) (*model.Member, error) {
+	ret := _m.Called(ctx, workspaceID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMember")
+	}
+
+	var r0 *model.Member
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.Member, error)); ok {
+		return rf(ctx, workspaceID, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.Member); ok {
+		r0 = rf(ctx, workspaceID, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Member)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, workspaceID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_GetMember_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetMember'
+type Storage_GetMember_Call struct {
+	*mock.Call
+}
+
+// GetMember is a helper method to define mock.On call
+//   - ctx context.Context
+//   - workspaceID string
+//   - userID string
+func (_e *Storage_Expecter) GetMember(ctx interface{}, workspaceID interface{}, userID interface{}) *Storage_GetMember_Call {
+	return &Storage_GetMember_Call{Call: _e.mock.On("GetMember", ctx, workspaceID, userID)}
+}
+
+func (_c *Storage_GetMember_Call) Run(run func(ctx context.Context, workspaceID string, userID string)) *Storage_GetMember_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_GetMember_Call) Return(_a0 *model.Member, _a1 error) *Storage_GetMember_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_GetMember_Call) RunAndReturn(run func(context.Context, string, string) (*model.Member, error)) *Storage_GetMember_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetQuota provides a mock function with given fields: ctx, userID
+func (_m *Storage) GetQuota(ctx context.Context, userID string) (*model.Quota, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetQuota")
+	}
+
+	var r0 *model.Quota
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.Quota, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.Quota); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Quota)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_GetQuota_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetQuota'
+type Storage_GetQuota_Call struct {
+	*mock.Call
+}
+
+// GetQuota is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID string
+func (_e *Storage_Expecter) GetQuota(ctx interface{}, userID interface{}) *Storage_GetQuota_Call {
+	return &Storage_GetQuota_Call{Call: _e.mock.On("GetQuota", ctx, userID)}
+}
+
+func (_c *Storage_GetQuota_Call) Run(run func(ctx context.Context, userID string)) *Storage_GetQuota_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_GetQuota_Call) Return(_a0 *model.Quota, _a1 error) *Storage_GetQuota_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_GetQuota_Call) RunAndReturn(run func(context.Context, string) (*model.Quota, error)) *Storage_GetQuota_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetTransfer provides a mock function with given fields: ctx, id
+func (_m *Storage) GetTransfer(ctx context.Context, id string) (*model.Transfer, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTransfer")
+	}
+
+	var r0 *model.Transfer
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.Transfer, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.Transfer); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Transfer)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_GetTransfer_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetTransfer'
+type Storage_GetTransfer_Call struct {
+	*mock.Call
+}
+
+// GetTransfer is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *Storage_Expecter) GetTransfer(ctx interface{}, id interface{}) *Storage_GetTransfer_Call {
+	return &Storage_GetTransfer_Call{Call: _e.mock.On("GetTransfer", ctx, id)}
+}
+
+func (_c *Storage_GetTransfer_Call) Run(run func(ctx context.Context, id string)) *Storage_GetTransfer_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_GetTransfer_Call) Return(_a0 *model.Transfer, _a1 error) *Storage_GetTransfer_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_GetTransfer_Call) RunAndReturn(run func(context.Context, string) (*model.Transfer, error)) *Storage_GetTransfer_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetVariantClicks provides a mock function with given fields: ctx, short
+func (_m *Storage) GetVariantClicks(ctx context.Context, short string) ([]int64, error) {
+	ret := _m.Called(ctx, short)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVariantClicks")
+	}
+
+	var r0 []int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]int64, error)); ok {
+		return rf(ctx, short)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []int64); ok {
+		r0 = rf(ctx, short)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int64)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, short)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_GetVariantClicks_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetVariantClicks'
+type Storage_GetVariantClicks_Call struct {
+	*mock.Call
+}
+
+// GetVariantClicks is a helper method to define mock.On call
+//   - ctx context.Context
+//   - short string
+func (_e *Storage_Expecter) GetVariantClicks(ctx interface{}, short interface{}) *Storage_GetVariantClicks_Call {
+	return &Storage_GetVariantClicks_Call{Call: _e.mock.On("GetVariantClicks", ctx, short)}
+}
+
+func (_c *Storage_GetVariantClicks_Call) Run(run func(ctx context.Context, short string)) *Storage_GetVariantClicks_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_GetVariantClicks_Call) Return(_a0 []int64, _a1 error) *Storage_GetVariantClicks_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_GetVariantClicks_Call) RunAndReturn(run func(context.Context, string) ([]int64, error)) *Storage_GetVariantClicks_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetWorkspace provides a mock function with given fields: ctx, id
+func (_m *Storage) GetWorkspace(ctx context.Context, id string) (*model.Workspace, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWorkspace")
+	}
+
+	var r0 *model.Workspace
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.Workspace, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.Workspace); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Workspace)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_GetWorkspace_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetWorkspace'
+type Storage_GetWorkspace_Call struct {
+	*mock.Call
+}
+
+// GetWorkspace is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *Storage_Expecter) GetWorkspace(ctx interface{}, id interface{}) *Storage_GetWorkspace_Call {
+	return &Storage_GetWorkspace_Call{Call: _e.mock.On("GetWorkspace", ctx, id)}
+}
+
+func (_c *Storage_GetWorkspace_Call) Run(run func(ctx context.Context, id string)) *Storage_GetWorkspace_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_GetWorkspace_Call) Return(_a0 *model.Workspace, _a1 error) *Storage_GetWorkspace_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_GetWorkspace_Call) RunAndReturn(run func(context.Context, string) (*model.Workspace, error)) *Storage_GetWorkspace_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// IsTokenRevoked provides a mock function with given fields: ctx, id
+func (_m *Storage) IsTokenRevoked(ctx context.Context, id string) (bool, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsTokenRevoked")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_IsTokenRevoked_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IsTokenRevoked'
+type Storage_IsTokenRevoked_Call struct {
+	*mock.Call
+}
+
+// IsTokenRevoked is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *Storage_Expecter) IsTokenRevoked(ctx interface{}, id interface{}) *Storage_IsTokenRevoked_Call {
+	return &Storage_IsTokenRevoked_Call{Call: _e.mock.On("IsTokenRevoked", ctx, id)}
+}
+
+func (_c *Storage_IsTokenRevoked_Call) Run(run func(ctx context.Context, id string)) *Storage_IsTokenRevoked_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_IsTokenRevoked_Call) Return(_a0 bool, _a1 error) *Storage_IsTokenRevoked_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_IsTokenRevoked_Call) RunAndReturn(run func(context.Context, string) (bool, error)) *Storage_IsTokenRevoked_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListAPIKeys provides a mock function with given fields: ctx, userID
+func (_m *Storage) ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAPIKeys")
+	}
+
+	var r0 []*model.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.APIKey, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.APIKey); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_ListAPIKeys_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListAPIKeys'
+type Storage_ListAPIKeys_Call struct {
+	*mock.Call
+}
+
+// ListAPIKeys is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID string
+func (_e *Storage_Expecter) ListAPIKeys(ctx interface{}, userID interface{}) *Storage_ListAPIKeys_Call {
+	return &Storage_ListAPIKeys_Call{Call: _e.mock.On("ListAPIKeys", ctx, userID)}
+}
+
+func (_c *Storage_ListAPIKeys_Call) Run(run func(ctx context.Context, userID string)) *Storage_ListAPIKeys_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_ListAPIKeys_Call) Return(_a0 []*model.APIKey, _a1 error) *Storage_ListAPIKeys_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_ListAPIKeys_Call) RunAndReturn(run func(context.Context, string) ([]*model.APIKey, error)) *Storage_ListAPIKeys_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListAuditEvents provides a mock function with given fields: ctx, limit
+func (_m *Storage) ListAuditEvents(ctx context.Context, limit int) ([]*model.AuditEvent, error) {
+	ret := _m.Called(ctx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAuditEvents")
+	}
+
+	var r0 []*model.AuditEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]*model.AuditEvent, error)); ok {
+		return rf(ctx, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []*model.AuditEvent); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.AuditEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_ListAuditEvents_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListAuditEvents'
+type Storage_ListAuditEvents_Call struct {
+	*mock.Call
+}
+
+// ListAuditEvents is a helper method to define mock.On call
+//   - ctx context.Context
+//   - limit int
+func (_e *Storage_Expecter) ListAuditEvents(ctx interface{}, limit interface{}) *Storage_ListAuditEvents_Call {
+	return &Storage_ListAuditEvents_Call{Call: _e.mock.On("ListAuditEvents", ctx, limit)}
+}
+
+func (_c *Storage_ListAuditEvents_Call) Run(run func(ctx context.Context, limit int)) *Storage_ListAuditEvents_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *Storage_ListAuditEvents_Call) Return(_a0 []*model.AuditEvent, _a1 error) *Storage_ListAuditEvents_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_ListAuditEvents_Call) RunAndReturn(run func(context.Context, int) ([]*model.AuditEvent, error)) *Storage_ListAuditEvents_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListMembers provides a mock function with given fields: ctx, workspaceID
+func (_m *Storage) ListMembers(ctx context.Context, workspaceID string) ([]*model.Member, error) {
+	ret := _m.Called(ctx, workspaceID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListMembers")
+	}
+
+	var r0 []*model.Member
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.Member, error)); ok {
+		return rf(ctx, workspaceID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.Member); ok {
+		r0 = rf(ctx, workspaceID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Member)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, workspaceID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_ListMembers_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListMembers'
+type Storage_ListMembers_Call struct {
+	*mock.Call
+}
+
+// ListMembers is a helper method to define mock.On call
+//   - ctx context.Context
+//   - workspaceID string
+func (_e *Storage_Expecter) ListMembers(ctx interface{}, workspaceID interface{}) *Storage_ListMembers_Call {
+	return &Storage_ListMembers_Call{Call: _e.mock.On("ListMembers", ctx, workspaceID)}
+}
+
+func (_c *Storage_ListMembers_Call) Run(run func(ctx context.Context, workspaceID string)) *Storage_ListMembers_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_ListMembers_Call) Return(_a0 []*model.Member, _a1 error) *Storage_ListMembers_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_ListMembers_Call) RunAndReturn(run func(context.Context, string) ([]*model.Member, error)) *Storage_ListMembers_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListTransfers provides a mock function with given fields: ctx, userID
+func (_m *Storage) ListTransfers(ctx context.Context, userID string) ([]*model.Transfer, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListTransfers")
+	}
+
+	var r0 []*model.Transfer
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.Transfer, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.Transfer); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Transfer)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_ListTransfers_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListTransfers'
+type Storage_ListTransfers_Call struct {
+	*mock.Call
+}
+
+// ListTransfers is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID string
+func (_e *Storage_Expecter) ListTransfers(ctx interface{}, userID interface{}) *Storage_ListTransfers_Call {
+	return &Storage_ListTransfers_Call{Call: _e.mock.On("ListTransfers", ctx, userID)}
+}
+
+func (_c *Storage_ListTransfers_Call) Run(run func(ctx context.Context, userID string)) *Storage_ListTransfers_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_ListTransfers_Call) Return(_a0 []*model.Transfer, _a1 error) *Storage_ListTransfers_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_ListTransfers_Call) RunAndReturn(run func(context.Context, string) ([]*model.Transfer, error)) *Storage_ListTransfers_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListUserURLs provides a mock function with given fields: ctx, userid, tag
+func (_m *Storage) ListUserURLs(ctx context.Context, userid string, tag string) ([]*model.URL, error) {
+	ret := _m.Called(ctx, userid, tag)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListUserURLs")
+	}
+
+	var r0 []*model.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]*model.URL, error)); ok {
+		return rf(ctx, userid, tag)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*model.URL); ok {
+		r0 = rf(ctx, userid, tag)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, userid, tag)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_ListUserURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListUserURLs'
+type Storage_ListUserURLs_Call struct {
+	*mock.Call
+}
+
+// ListUserURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userid string
+//   - tag string
+func (_e *Storage_Expecter) ListUserURLs(ctx interface{}, userid interface{}, tag interface{}) *Storage_ListUserURLs_Call {
+	return &Storage_ListUserURLs_Call{Call: _e.mock.On("ListUserURLs", ctx, userid, tag)}
+}
+
+func (_c *Storage_ListUserURLs_Call) Run(run func(ctx context.Context, userid string, tag string)) *Storage_ListUserURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_ListUserURLs_Call) Return(_a0 []*model.URL, _a1 error) *Storage_ListUserURLs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_ListUserURLs_Call) RunAndReturn(run func(context.Context, string, string) ([]*model.URL, error)) *Storage_ListUserURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListWorkspaceURLs provides a mock function with given fields: ctx, workspaceID, tag
+func (_m *Storage) ListWorkspaceURLs(ctx context.Context, workspaceID string, tag string) ([]*model.URL, error) {
+	ret := _m.Called(ctx, workspaceID, tag)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListWorkspaceURLs")
+	}
+
+	var r0 []*model.URL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]*model.URL, error)); ok {
+		return rf(ctx, workspaceID, tag)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*model.URL); ok {
+		r0 = rf(ctx, workspaceID, tag)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.URL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, workspaceID, tag)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_ListWorkspaceURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListWorkspaceURLs'
+type Storage_ListWorkspaceURLs_Call struct {
+	*mock.Call
+}
+
+// ListWorkspaceURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - workspaceID string
+//   - tag string
+func (_e *Storage_Expecter) ListWorkspaceURLs(ctx interface{}, workspaceID interface{}, tag interface{}) *Storage_ListWorkspaceURLs_Call {
+	return &Storage_ListWorkspaceURLs_Call{Call: _e.mock.On("ListWorkspaceURLs", ctx, workspaceID, tag)}
+}
+
+func (_c *Storage_ListWorkspaceURLs_Call) Run(run func(ctx context.Context, workspaceID string, tag string)) *Storage_ListWorkspaceURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_ListWorkspaceURLs_Call) Return(_a0 []*model.URL, _a1 error) *Storage_ListWorkspaceURLs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_ListWorkspaceURLs_Call) RunAndReturn(run func(context.Context, string, string) ([]*model.URL, error)) *Storage_ListWorkspaceURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListWorkspaces provides a mock function with given fields: ctx, userID
+func (_m *Storage) ListWorkspaces(ctx context.Context, userID string) ([]*model.Workspace, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListWorkspaces")
+	}
+
+	var r0 []*model.Workspace
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.Workspace, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.Workspace); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Workspace)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_ListWorkspaces_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListWorkspaces'
+type Storage_ListWorkspaces_Call struct {
+	*mock.Call
+}
+
+// ListWorkspaces is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userID string
+func (_e *Storage_Expecter) ListWorkspaces(ctx interface{}, userID interface{}) *Storage_ListWorkspaces_Call {
+	return &Storage_ListWorkspaces_Call{Call: _e.mock.On("ListWorkspaces", ctx, userID)}
+}
+
+func (_c *Storage_ListWorkspaces_Call) Run(run func(ctx context.Context, userID string)) *Storage_ListWorkspaces_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_ListWorkspaces_Call) Return(_a0 []*model.Workspace, _a1 error) *Storage_ListWorkspaces_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_ListWorkspaces_Call) RunAndReturn(run func(context.Context, string) ([]*model.Workspace, error)) *Storage_ListWorkspaces_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// LoadURLs provides a mock function with given fields: ctx, next, replace
+func (_m *Storage) LoadURLs(ctx context.Context, next func() (*model.URL, error), replace bool) error {
+	ret := _m.Called(ctx, next, replace)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LoadURLs")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func() (*model.URL, error), bool) error); ok {
+		r0 = rf(ctx, next, replace)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_LoadURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LoadURLs'
+type Storage_LoadURLs_Call struct {
+	*mock.Call
+}
+
+// LoadURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - next func() (*model.URL, error)
+//   - replace bool
+func (_e *Storage_Expecter) LoadURLs(ctx interface{}, next interface{}, replace interface{}) *Storage_LoadURLs_Call {
+	return &Storage_LoadURLs_Call{Call: _e.mock.On("LoadURLs", ctx, next, replace)}
+}
+
+func (_c *Storage_LoadURLs_Call) Run(run func(ctx context.Context, next func() (*model.URL, error), replace bool)) *Storage_LoadURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(func() (*model.URL, error)), args[2].(bool))
+	})
+	return _c
+}
+
+func (_c *Storage_LoadURLs_Call) Return(_a0 error) *Storage_LoadURLs_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_LoadURLs_Call) RunAndReturn(run func(context.Context, func() (*model.URL, error), bool) error) *Storage_LoadURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Ping provides a mock function with given fields: ctx
+func (_m *Storage) Ping(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Ping")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_Ping_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Ping'
+type Storage_Ping_Call struct {
 	*mock.Call
 }
 
-// Close is a helper method to define mock.On call
-func (_e *Storage_Expecter) Close() *Storage_Close_Call {
-	return &Storage_Close_Call{Call: _e.mock.On("Close")}
+// Ping is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Storage_Expecter) Ping(ctx interface{}) *Storage_Ping_Call {
+	return &Storage_Ping_Call{Call: _e.mock.On("Ping", ctx)}
 }
 
-func (_c *Storage_Close_Call) Run(run func()) *Storage_Close_Call {
+func (_c *Storage_Ping_Call) Run(run func(ctx context.Context)) *Storage_Ping_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run()
+		run(args[0].(context.Context))
 	})
 	return _c
 }
 
-func (_c *Storage_Close_Call) Return() *Storage_Close_Call {
-	_c.Call.Return()
+func (_c *Storage_Ping_Call) Return(_a0 error) *Storage_Ping_Call {
+	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *Storage_Close_Call) RunAndReturn(run func()) *Storage_Close_Call {
+func (_c *Storage_Ping_Call) RunAndReturn(run func(context.Context) error) *Storage_Ping_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// DeleteUserURLs provides a mock function with given fields: ctx, urls
-func (_m *Storage) DeleteUserURLs(ctx context.Context, urls []model.URL) (int64, error) {
-	ret := _m.Called(ctx, urls)
+// PurgeDeleted provides a mock function with given fields: ctx
+func (_m *Storage) PurgeDeleted(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteUserURLs")
+		panic("no return value specified for PurgeDeleted")
 	}
 
 	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, []model.URL) (int64, error)); ok {
-		return rf(ctx, urls)
+	if rf, ok := ret.Get(0).(func(context.Context) (int64, error)); ok {
+		return rf(ctx)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, []model.URL) int64); ok {
-		r0 = rf(ctx, urls)
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, []model.URL) error); ok {
-		r1 = rf(ctx, urls)
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -82,162 +1827,188 @@ func (_m *Storage) DeleteUserURLs(ctx context.Context, urls []model.URL) (int64,
 	return r0, r1
 }
 
-// Storage_DeleteUserURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteUserURLs'
-type Storage_DeleteUserURLs_Call struct {
+// Storage_PurgeDeleted_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PurgeDeleted'
+type Storage_PurgeDeleted_Call struct {
 	*mock.Call
 }
 
-// DeleteUserURLs is a helper method to define mock.On call
+// PurgeDeleted is a helper method to define mock.On call
 //   - ctx context.Context
-//   - urls []model.URL
-func (_e *Storage_Expecter) DeleteUserURLs(ctx interface{}, urls interface{}) *Storage_DeleteUserURLs_Call {
-	return &Storage_DeleteUserURLs_Call{Call: _e.mock.On("DeleteUserURLs", ctx, urls)}
+func (_e *Storage_Expecter) PurgeDeleted(ctx interface{}) *Storage_PurgeDeleted_Call {
+	return &Storage_PurgeDeleted_Call{Call: _e.mock.On("PurgeDeleted", ctx)}
 }
 
-func (_c *Storage_DeleteUserURLs_Call) Run(run func(ctx context.Context, urls []model.URL)) *Storage_DeleteUserURLs_Call {
+func (_c *Storage_PurgeDeleted_Call) Run(run func(ctx context.Context)) *Storage_PurgeDeleted_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].([]model.URL))
+		run(args[0].(context.Context))
 	})
 	return _c
 }
 
-func (_c *Storage_DeleteUserURLs_Call) Return(_a0 int64, _a1 error) *Storage_DeleteUserURLs_Call {
+func (_c *Storage_PurgeDeleted_Call) Return(_a0 int64, _a1 error) *Storage_PurgeDeleted_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *Storage_DeleteUserURLs_Call) RunAndReturn(run func(context.Context, []model.URL) (int64, error)) *Storage_DeleteUserURLs_Call {
+func (_c *Storage_PurgeDeleted_Call) RunAndReturn(run func(context.Context) (int64, error)) *Storage_PurgeDeleted_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Get provides a mock function with given fields: ctx, key
-func (_m *Storage) Get(ctx context.Context, key string) (string, error) {
-	ret := _m.Called(ctx, key)
+// RevokeToken provides a mock function with given fields: ctx, id, expires
+func (_m *Storage) RevokeToken(ctx context.Context, id string, expires time.Time) error {
+	ret := _m.Called(ctx, id, expires)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Get")
-	}
-
-	var r0 string
-	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
-		return rf(ctx, key)
-	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
-		r0 = rf(ctx, key)
-	} else {
-		r0 = ret.Get(0).(string)
+		panic("no return value specified for RevokeToken")
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, key)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = rf(ctx, id, expires)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
 
-// Storage_Get_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Get'
-type Storage_Get_Call struct {
+// Storage_RevokeToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RevokeToken'
+type Storage_RevokeToken_Call struct {
 	*mock.Call
 }
 
-// Get is a helper method to define mock.On call
+// RevokeToken is a helper method to define mock.On call
 //   - ctx context.Context
-//   - key string
-func (_e *Storage_Expecter) Get(ctx interface{}, key interface{}) *Storage_Get_Call {
-	return &Storage_Get_Call{Call: _e.mock.On("Get", ctx, key)}
+//   - id string
+//   - expires time.Time
+func (_e *Storage_Expecter) RevokeToken(ctx interface{}, id interface{}, expires interface{}) *Storage_RevokeToken_Call {
+	return &Storage_RevokeToken_Call{Call: _e.mock.On("RevokeToken", ctx, id, expires)}
 }
 
-func (_c *Storage_Get_Call) Run(run func(ctx context.Context, key string)) *Storage_Get_Call {
+func (_c *Storage_RevokeToken_Call) Run(run func(ctx context.Context, id string, expires time.Time)) *Storage_RevokeToken_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string))
+		run(args[0].(context.Context), args[1].(string), args[2].(time.Time))
 	})
 	return _c
 }
 
-func (_c *Storage_Get_Call) Return(url string, err error) *Storage_Get_Call {
-	_c.Call.Return(url, err)
+func (_c *Storage_RevokeToken_Call) Return(_a0 error) *Storage_RevokeToken_Call {
+	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *Storage_Get_Call) RunAndReturn(run func(context.Context, string) (string, error)) *Storage_Get_Call {
+func (_c *Storage_RevokeToken_Call) RunAndReturn(run func(context.Context, string, time.Time) error) *Storage_RevokeToken_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// ListUserURLs provides a mock function with given fields: ctx, userid
-func (_m *Storage) ListUserURLs(ctx context.Context, userid string) ([]*model.URL, error) {
-	ret := _m.Called(ctx, userid)
+// ScanURLs provides a mock function with given fields: ctx, after, fn
+func (_m *Storage) ScanURLs(ctx context.Context, after string, fn func(url *model.URL) error) error {
+	ret := _m.Called(ctx, after, fn)
 
 	if len(ret) == 0 {
-		panic("no return value specified for ListUserURLs")
+		panic("no return value specified for ScanURLs")
 	}
 
-	var r0 []*model.URL
-	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.URL, error)); ok {
-		return rf(ctx, userid)
-	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.URL); ok {
-		r0 = rf(ctx, userid)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, func(url *model.URL) error) error); ok {
+		r0 = rf(ctx, after, fn)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*model.URL)
-		}
+		r0 = ret.Error(0)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, userid)
+	return r0
+}
+
+// Storage_ScanURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ScanURLs'
+type Storage_ScanURLs_Call struct {
+	*mock.Call
+}
+
+// ScanURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - after string
+//   - fn func(url *model.URL) error
+func (_e *Storage_Expecter) ScanURLs(ctx interface{}, after interface{}, fn interface{}) *Storage_ScanURLs_Call {
+	return &Storage_ScanURLs_Call{Call: _e.mock.On("ScanURLs", ctx, after, fn)}
+}
+
+func (_c *Storage_ScanURLs_Call) Run(run func(ctx context.Context, after string, fn func(url *model.URL) error)) *Storage_ScanURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(func(url *model.URL) error))
+	})
+	return _c
+}
+
+func (_c *Storage_ScanURLs_Call) Return(_a0 error) *Storage_ScanURLs_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_ScanURLs_Call) RunAndReturn(run func(context.Context, string, func(url *model.URL) error) error) *Storage_ScanURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetMember provides a mock function with given fields: ctx, member
+func (_m *Storage) SetMember(ctx context.Context, member *model.Member) error {
+	ret := _m.Called(ctx, member)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetMember")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Member) error); ok {
+		r0 = rf(ctx, member)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
 
-// Storage_ListUserURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListUserURLs'
-type Storage_ListUserURLs_Call struct {
+// Storage_SetMember_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetMember'
+type Storage_SetMember_Call struct {
 	*mock.Call
 }
 
-// ListUserURLs is a helper method to define mock.On call
+// SetMember is a helper method to define mock.On call
 //   - ctx context.Context
-//   - userid string
-func (_e *Storage_Expecter) ListUserURLs(ctx interface{}, userid interface{}) *Storage_ListUserURLs_Call {
-	return &Storage_ListUserURLs_Call{Call: _e.mock.On("ListUserURLs", ctx, userid)}
+//   - member *model.Member
+func (_e *Storage_Expecter) SetMember(ctx interface{}, member interface{}) *Storage_SetMember_Call {
+	return &Storage_SetMember_Call{Call: _e.mock.On("SetMember", ctx, member)}
 }
 
-func (_c *Storage_ListUserURLs_Call) Run(run func(ctx context.Context, userid string)) *Storage_ListUserURLs_Call {
+func (_c *Storage_SetMember_Call) Run(run func(ctx context.Context, member *model.Member)) *Storage_SetMember_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string))
+		run(args[0].(context.Context), args[1].(*model.Member))
 	})
 	return _c
 }
 
-func (_c *Storage_ListUserURLs_Call) Return(_a0 []*model.URL, _a1 error) *Storage_ListUserURLs_Call {
-	_c.Call.Return(_a0, _a1)
+func (_c *Storage_SetMember_Call) Return(_a0 error) *Storage_SetMember_Call {
+	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *Storage_ListUserURLs_Call) RunAndReturn(run func(context.Context, string) ([]*model.URL, error)) *Storage_ListUserURLs_Call {
+func (_c *Storage_SetMember_Call) RunAndReturn(run func(context.Context, *model.Member) error) *Storage_SetMember_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Ping provides a mock function with given fields: ctx
-func (_m *Storage) Ping(ctx context.Context) error {
-	ret := _m.Called(ctx)
+// SetQuota provides a mock function with given fields: ctx, quota
+func (_m *Storage) SetQuota(ctx context.Context, quota *model.Quota) error {
+	ret := _m.Called(ctx, quota)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Ping")
+		panic("no return value specified for SetQuota")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
-		r0 = rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Quota) error); ok {
+		r0 = rf(ctx, quota)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -245,30 +2016,31 @@ func (_m *Storage) Ping(ctx context.Context) error {
 	return r0
 }
 
-// Storage_Ping_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Ping'
-type Storage_Ping_Call struct {
+// Storage_SetQuota_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetQuota'
+type Storage_SetQuota_Call struct {
 	*mock.Call
 }
 
-// Ping is a helper method to define mock.On call
+// SetQuota is a helper method to define mock.On call
 //   - ctx context.Context
-func (_e *Storage_Expecter) Ping(ctx interface{}) *Storage_Ping_Call {
-	return &Storage_Ping_Call{Call: _e.mock.On("Ping", ctx)}
+//   - quota *model.Quota
+func (_e *Storage_Expecter) SetQuota(ctx interface{}, quota interface{}) *Storage_SetQuota_Call {
+	return &Storage_SetQuota_Call{Call: _e.mock.On("SetQuota", ctx, quota)}
 }
 
-func (_c *Storage_Ping_Call) Run(run func(ctx context.Context)) *Storage_Ping_Call {
+func (_c *Storage_SetQuota_Call) Run(run func(ctx context.Context, quota *model.Quota)) *Storage_SetQuota_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context))
+		run(args[0].(context.Context), args[1].(*model.Quota))
 	})
 	return _c
 }
 
-func (_c *Storage_Ping_Call) Return(_a0 error) *Storage_Ping_Call {
+func (_c *Storage_SetQuota_Call) Return(_a0 error) *Storage_SetQuota_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *Storage_Ping_Call) RunAndReturn(run func(context.Context) error) *Storage_Ping_Call {
+func (_c *Storage_SetQuota_Call) RunAndReturn(run func(context.Context, *model.Quota) error) *Storage_SetQuota_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -436,6 +2208,147 @@ func (_c *Storage_StoreBatch_Call) RunAndReturn(run func(context.Context, []mode
 	return _c
 }
 
+// TransferURLs provides a mock function with given fields: ctx, event
+func (_m *Storage) TransferURLs(ctx context.Context, event *model.AuditEvent) error {
+	ret := _m.Called(ctx, event)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TransferURLs")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.AuditEvent) error); ok {
+		r0 = rf(ctx, event)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_TransferURLs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TransferURLs'
+type Storage_TransferURLs_Call struct {
+	*mock.Call
+}
+
+// TransferURLs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - event *model.AuditEvent
+func (_e *Storage_Expecter) TransferURLs(ctx interface{}, event interface{}) *Storage_TransferURLs_Call {
+	return &Storage_TransferURLs_Call{Call: _e.mock.On("TransferURLs", ctx, event)}
+}
+
+func (_c *Storage_TransferURLs_Call) Run(run func(ctx context.Context, event *model.AuditEvent)) *Storage_TransferURLs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*model.AuditEvent))
+	})
+	return _c
+}
+
+func (_c *Storage_TransferURLs_Call) Return(_a0 error) *Storage_TransferURLs_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_TransferURLs_Call) RunAndReturn(run func(context.Context, *model.AuditEvent) error) *Storage_TransferURLs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateAPIKey provides a mock function with given fields: ctx, key
+func (_m *Storage) UpdateAPIKey(ctx context.Context, key *model.APIKey) error {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateAPIKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.APIKey) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_UpdateAPIKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateAPIKey'
+type Storage_UpdateAPIKey_Call struct {
+	*mock.Call
+}
+
+// UpdateAPIKey is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key *model.APIKey
+func (_e *Storage_Expecter) UpdateAPIKey(ctx interface{}, key interface{}) *Storage_UpdateAPIKey_Call {
+	return &Storage_UpdateAPIKey_Call{Call: _e.mock.On("UpdateAPIKey", ctx, key)}
+}
+
+func (_c *Storage_UpdateAPIKey_Call) Run(run func(ctx context.Context, key *model.APIKey)) *Storage_UpdateAPIKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*model.APIKey))
+	})
+	return _c
+}
+
+func (_c *Storage_UpdateAPIKey_Call) Return(_a0 error) *Storage_UpdateAPIKey_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_UpdateAPIKey_Call) RunAndReturn(run func(context.Context, *model.APIKey) error) *Storage_UpdateAPIKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateMeta provides a mock function with given fields: ctx, url
+func (_m *Storage) UpdateMeta(ctx context.Context, url *model.URL) error {
+	ret := _m.Called(ctx, url)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateMeta")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.URL) error); ok {
+		r0 = rf(ctx, url)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_UpdateMeta_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateMeta'
+type Storage_UpdateMeta_Call struct {
+	*mock.Call
+}
+
+// UpdateMeta is a helper method to define mock.On call
+//   - ctx context.Context
+//   - url *model.URL
+func (_e *Storage_Expecter) UpdateMeta(ctx interface{}, url interface{}) *Storage_UpdateMeta_Call {
+	return &Storage_UpdateMeta_Call{Call: _e.mock.On("UpdateMeta", ctx, url)}
+}
+
+func (_c *Storage_UpdateMeta_Call) Run(run func(ctx context.Context, url *model.URL)) *Storage_UpdateMeta_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*model.URL))
+	})
+	return _c
+}
+
+func (_c *Storage_UpdateMeta_Call) Return(_a0 error) *Storage_UpdateMeta_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_UpdateMeta_Call) RunAndReturn(run func(context.Context, *model.URL) error) *Storage_UpdateMeta_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewStorage creates a new instance of Storage. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewStorage(t interface {
@@ -2,48 +2,165 @@
 package auth
 
 import (
+	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/adwski/shorty/internal/user"
+	"github.com/gofrs/uuid/v5"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 const (
-	defaultJWTCookieExpiration = 24 * time.Hour
+	// DefaultSessionTTL is default lifetime of session token.
+	DefaultSessionTTL = 24 * time.Hour
+
+	bearerPrefix = "Bearer "
+)
+
+// Token errors.
+var (
+	ErrTokenRevoked = errors.New("session is revoked")
+	ErrDenylist     = errors.New("cannot check session denylist")
 )
 
-// Claims is jwt token claims.
+// Denylist stores ids of revoked sessions until their tokens expire.
+type Denylist interface {
+	RevokeToken(ctx context.Context, id string, expires time.Time) error
+	IsTokenRevoked(ctx context.Context, id string) (bool, error)
+}
+
+// Claims is jwt token claims. Token id (jti) is session id, it's kept when token is re-issued.
 type Claims struct {
 	jwt.RegisteredClaims
 	UserID string `json:"user_id,omitempty"`
+	Login  string `json:"login,omitempty"`
 }
 
 // Auth is authenticator component providing hi level user operations.
+// Tokens are signed with HS256 using jwt secret unless key set is configured.
 type Auth struct {
-	jwtSecret string
+	keys         *KeySet
+	denylist     Denylist
+	jwtSecret    string
+	ttl          time.Duration
+	refresh      time.Duration
+	acceptSecret bool
 }
 
 // New creates authenticator.
 func New(jwtSecret string) *Auth {
-	return &Auth{jwtSecret: jwtSecret}
+	return &Auth{jwtSecret: jwtSecret, ttl: DefaultSessionTTL}
+}
+
+// WithSessionTTL sets lifetime of session tokens. Token expiring in less than refresh
+// is re-issued with prolonged expiration when it's used, so sessions of active users slide.
+// Zero refresh disables re-issue.
+func (a *Auth) WithSessionTTL(ttl, refresh time.Duration) *Auth {
+	a.ttl = ttl
+	a.refresh = refresh
+	return a
+}
+
+// WithDenylist enables session revocation. Tokens of revoked sessions are rejected.
+func (a *Auth) WithDenylist(denylist Denylist) *Auth {
+	a.denylist = denylist
+	return a
+}
+
+// WithKeySet makes authenticator sign tokens with key set. Tokens without kid signed with jwt secret
+// are accepted only if acceptSecret is set, it allows to migrate to key set without logging users out.
+func (a *Auth) WithKeySet(keys *KeySet, acceptSecret bool) *Auth {
+	a.keys = keys
+	a.acceptSecret = acceptSecret
+	return a
+}
+
+// JWKS returns public keys of key set. It's empty if key set is not configured.
+func (a *Auth) JWKS() *JWKS {
+	if a.keys == nil {
+		return &JWKS{Keys: []JWK{}}
+	}
+	return a.keys.JWKS()
 }
 
 // CreateOrParseUserFromJWTString parses user from jwt string.
-// If user is parsed successfully and jwt is not expired, parsed user is returned
-// and returned cookie will be nil. If user could not be parsed, new user will be created
-// and corresponding cookie value will be generated. In this case both new user and cookie are returned.
-func (a *Auth) CreateOrParseUserFromJWTString(usr string) (*user.User, string, error) {
-	u, err := a.getUserFromJWT(usr)
-	if err == nil {
-		return u, "", nil
-	}
-	// Missing or invalid session cookie
+// If user is parsed successfully and jwt is not expired or revoked, parsed user is returned
+// and returned cookie will be empty unless token is re-issued. If user could not be parsed,
+// new user will be created and corresponding cookie value will be generated.
+// In this case both new user and cookie are returned.
+// Error is returned only if denylist cannot be checked or token cannot be created.
+func (a *Auth) CreateOrParseUserFromJWTString(ctx context.Context, usr string) (*user.User, string, error) {
+	u, err := a.ParseUserFromJWTString(ctx, usr)
+	switch {
+	case err == nil:
+		token, errR := a.RefreshToken(u)
+		return u, token, errR
+	case errors.Is(err, ErrDenylist):
+		return nil, "", err
+	}
+	// Missing, invalid or revoked session cookie
 	// Generate a new user
 	return a.CreateUserAndToken()
 }
 
+// ParseUserFromJWTString parses user from jwt string. Unlike CreateOrParseUserFromJWTString
+// it does not create new user, error describes why token was rejected.
+func (a *Auth) ParseUserFromJWTString(ctx context.Context, token string) (*user.User, error) {
+	u, err := a.getUserFromJWT(token)
+	if err != nil {
+		return nil, err
+	}
+	if a.denylist == nil || u.SessionID == "" {
+		return u, nil
+	}
+	revoked, err := a.denylist.IsTokenRevoked(ctx, u.SessionID)
+	if err != nil {
+		return nil, errors.Join(ErrDenylist, err)
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return u, nil
+}
+
+// RefreshToken re-issues session token of parsed user if it expires in less than refresh interval.
+// Tokens without session id are re-issued with new session, so they can be revoked.
+// Empty string is returned if token should not be re-issued.
+func (a *Auth) RefreshToken(u *user.User) (string, error) {
+	if a.refresh == 0 || u.IsAPIKey() {
+		return "", nil
+	}
+	if u.SessionID != "" && time.Until(u.SessionExpires) >= a.refresh {
+		return "", nil
+	}
+	return a.createJWT(u)
+}
+
+// RevokeSession adds session of user to denylist, so all its tokens are rejected.
+// It does nothing if user has no session.
+func (a *Auth) RevokeSession(ctx context.Context, u *user.User) error {
+	if a.denylist == nil {
+		return errors.New("session revocation is not enabled")
+	}
+	if u.SessionID == "" {
+		return nil
+	}
+	// Tokens of session cannot outlive latest re-issued token,
+	// and it cannot be re-issued after revocation.
+	expires := time.Now().Add(a.ttl)
+	if u.SessionExpires.After(expires) {
+		expires = u.SessionExpires
+	}
+	if err := a.denylist.RevokeToken(ctx, u.SessionID, expires); err != nil {
+		return fmt.Errorf("cannot revoke session: %w", err)
+	}
+	return nil
+}
+
 // createJWT creates new jwt token for specified user.
 func (a *Auth) createJWT(u *user.User) (string, error) {
 	token, err := a.newToken(u)
@@ -53,6 +170,12 @@ func (a *Auth) createJWT(u *user.User) (string, error) {
 	return token, nil
 }
 
+// CreateToken creates jwt token for existing user. Account login of registered user is kept in token.
+// New session is started if user has no session, session id and expiration are set to user.
+func (a *Auth) CreateToken(u *user.User) (string, error) {
+	return a.createJWT(u)
+}
+
 // CreateUserAndToken creates new user and corresponding jwt token.
 func (a *Auth) CreateUserAndToken() (*user.User, string, error) {
 	// Create user with unique ID
@@ -70,12 +193,7 @@ func (a *Auth) CreateUserAndToken() (*user.User, string, error) {
 }
 
 func (a *Auth) getUserFromJWT(signedToken string) (*user.User, error) {
-	token, err := jwt.ParseWithClaims(signedToken, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-		return []byte(a.jwtSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(signedToken, &Claims{}, a.verificationKey)
 	if err != nil {
 		return nil, fmt.Errorf("cannot parse token from session cookie: %w", err)
 	}
@@ -93,21 +211,101 @@ func (a *Auth) getUserFromJWT(signedToken string) (*user.User, error) {
 	case claims.UserID == "":
 		return nil, errors.New("user id is empty")
 	default:
-		return user.NewWithID(claims.UserID), nil
+		u := user.NewWithID(claims.UserID)
+		u.Login = claims.Login
+		u.SessionID = claims.ID
+		u.SessionExpires = claims.ExpiresAt.Time
+		return u, nil
+	}
+}
+
+// verificationKey returns key that verifies token.
+func (a *Auth) verificationKey(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if a.keys == nil || (kid == "" && a.acceptSecret) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(a.jwtSecret), nil
+	}
+	if kid == "" {
+		return nil, errors.New("token has no key id")
 	}
+	key, ok := a.keys.key(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+	return key.public, nil
 }
 
 func (a *Auth) newToken(u *user.User) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+	if u.SessionID == "" {
+		id, err := uuid.NewV4()
+		if err != nil {
+			return "", fmt.Errorf("cannot generate session id: %w", err)
+		}
+		u.SessionID = base64.RawURLEncoding.EncodeToString(id.Bytes())
+	}
+	now := time.Now()
+	expires := jwt.NewNumericDate(now.Add(a.ttl))
+	u.SessionExpires = expires.Time
+	return a.sign(Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(defaultJWTCookieExpiration)),
+			ID:        u.SessionID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: expires,
 		},
 		UserID: u.ID,
+		Login:  u.Login,
 	})
+}
+
+// SignClaims signs arbitrary claims with session signing key. It's used for short-lived tokens
+// of external login flows, they cannot be used as session tokens since they have no user id.
+func (a *Auth) SignClaims(claims jwt.Claims) (string, error) {
+	return a.sign(claims)
+}
+
+// ParseClaims verifies token signed with SignClaims and parses its claims.
+func (a *Auth) ParseClaims(token string, claims jwt.Claims, opts ...jwt.ParserOption) error {
+	if _, err := jwt.ParseWithClaims(token, claims, a.verificationKey, opts...); err != nil {
+		return fmt.Errorf("cannot parse token: %w", err)
+	}
+	return nil
+}
 
-	signedToken, err := token.SignedString([]byte(a.jwtSecret))
+// sign signs claims with newest active key of key set or with jwt secret.
+func (a *Auth) sign(claims jwt.Claims) (string, error) {
+	var (
+		method  jwt.SigningMethod = jwt.SigningMethodHS256
+		signKey interface{}       = []byte(a.jwtSecret)
+		kid     string
+	)
+	if a.keys != nil {
+		key, err := a.keys.signingKey()
+		if err != nil {
+			return "", err
+		}
+		method, signKey, kid = key.method, key.private, key.ID
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signedToken, err := token.SignedString(signKey)
 	if err != nil {
 		return "", fmt.Errorf("cannot sign jwt token: %w", err)
 	}
 	return signedToken, nil
 }
+
+// BearerToken extracts token from Authorization header value with Bearer scheme.
+func BearerToken(header string) (string, bool) {
+	if len(header) < len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(bearerPrefix):]), true
+}
@@ -1,7 +1,10 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/adwski/shorty/internal/user"
 	"github.com/stretchr/testify/require"
@@ -23,6 +26,13 @@ func TestAuth_CreateAndGetJWT(t *testing.T) {
 				jwtSecret: "super-secret",
 			},
 		},
+		{
+			name: "create jwt cookie for registered user",
+			args: args{
+				u:         &user.User{ID: "tdGk2USqTvWW8jyz7HnhlA", Login: "alice"},
+				jwtSecret: "super-secret",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -34,6 +44,91 @@ func TestAuth_CreateAndGetJWT(t *testing.T) {
 			userFromCookie, errU := a.getUserFromJWT(token)
 			require.NoError(t, errU)
 			require.Equal(t, tt.args.u.ID, userFromCookie.ID)
+			require.Equal(t, tt.args.u.Login, userFromCookie.Login)
 		})
 	}
 }
+
+func TestBearerToken(t *testing.T) {
+	token, ok := BearerToken("Bearer shk_1_2")
+	require.True(t, ok)
+	require.Equal(t, "shk_1_2", token)
+	token, ok = BearerToken("bearer  shk_1_2 ")
+	require.True(t, ok)
+	require.Equal(t, "shk_1_2", token)
+	_, ok = BearerToken("Basic dXNlcjpwYXNz")
+	require.False(t, ok)
+	_, ok = BearerToken("Bear")
+	require.False(t, ok)
+}
+
+type testDenylist map[string]time.Time
+
+func (d testDenylist) RevokeToken(_ context.Context, id string, expires time.Time) error {
+	d[id] = expires
+	return nil
+}
+
+func (d testDenylist) IsTokenRevoked(_ context.Context, id string) (bool, error) {
+	if id == "broken" {
+		return false, errors.New("storage is down")
+	}
+	_, ok := d[id]
+	return ok, nil
+}
+
+func TestAuth_Sessions(t *testing.T) {
+	ctx := context.Background()
+	denylist := make(testDenylist)
+	a := New("secret").WithSessionTTL(time.Hour, 30*time.Minute).WithDenylist(denylist)
+
+	u, token, err := a.CreateUserAndToken()
+	require.NoError(t, err)
+	require.NotEmpty(t, u.SessionID)
+
+	// fresh token is not re-issued
+	parsed, refreshed, err := a.CreateOrParseUserFromJWTString(ctx, token)
+	require.NoError(t, err)
+	require.Equal(t, u.ID, parsed.ID)
+	require.Equal(t, u.SessionID, parsed.SessionID)
+	require.Empty(t, refreshed)
+
+	// token expiring soon is re-issued within the same session
+	parsed.SessionExpires = time.Now().Add(10 * time.Minute)
+	slid, err := a.RefreshToken(parsed)
+	require.NoError(t, err)
+	require.NotEmpty(t, slid)
+	parsed, err = a.ParseUserFromJWTString(ctx, slid)
+	require.NoError(t, err)
+	require.Equal(t, u.SessionID, parsed.SessionID)
+	require.WithinDuration(t, time.Now().Add(time.Hour), parsed.SessionExpires, time.Minute)
+
+	// token without session id is re-issued with new session
+	legacy := user.NewWithID(u.ID)
+	refreshed, err = a.RefreshToken(legacy)
+	require.NoError(t, err)
+	require.NotEmpty(t, refreshed)
+	require.NotEmpty(t, legacy.SessionID)
+
+	// revocation rejects all tokens of session
+	require.NoError(t, a.RevokeSession(ctx, parsed))
+	require.Contains(t, denylist, u.SessionID)
+	for _, tok := range []string{token, slid} {
+		_, err = a.ParseUserFromJWTString(ctx, tok)
+		require.ErrorIs(t, err, ErrTokenRevoked)
+	}
+	newUser, newToken, err := a.CreateOrParseUserFromJWTString(ctx, token)
+	require.NoError(t, err)
+	require.NotEqual(t, u.ID, newUser.ID)
+	require.NotEmpty(t, newToken)
+
+	// denylist errors are not hidden
+	broken := &user.User{ID: u.ID, SessionID: "broken"}
+	brokenToken, err := a.CreateToken(broken)
+	require.NoError(t, err)
+	_, _, err = a.CreateOrParseUserFromJWTString(ctx, brokenToken)
+	require.ErrorIs(t, err, ErrDenylist)
+
+	// revocation requires denylist
+	require.Error(t, New("secret").RevokeSession(ctx, parsed))
+}
@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const minRSAKeyBits = 2048
+
+// Key is asymmetric jwt key. Key loaded from public key file can only verify tokens.
+// Private key signs tokens starting from NotBefore time.
+type Key struct {
+	NotBefore time.Time
+	method    jwt.SigningMethod
+	private   crypto.Signer
+	public    crypto.PublicKey
+	ID        string
+}
+
+// KeySet is a set of jwt keys identified by kid.
+// Tokens are signed with the newest active private key and verified with any key of set,
+// so keys can be rotated by adding new key with future activation time
+// and removing old key after tokens signed with it expire.
+type KeySet struct {
+	now  func() time.Time
+	keys []*Key
+}
+
+// JWKS is json web key set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK is public json web key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// NewKeySet creates key set. Key ids must be unique and at least one key must be private.
+func NewKeySet(keys ...*Key) (*KeySet, error) {
+	seen := make(map[string]struct{}, len(keys))
+	var hasPrivate bool
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("key id is empty")
+		}
+		if _, ok := seen[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %s", key.ID)
+		}
+		seen[key.ID] = struct{}{}
+		hasPrivate = hasPrivate || key.private != nil
+	}
+	if !hasPrivate {
+		return nil, errors.New("key set has no private keys")
+	}
+	return &KeySet{keys: keys, now: time.Now}, nil
+}
+
+// LoadKeySet loads key set from spec. Spec is comma separated list of kid=path[@not_before] entries,
+// where path is PEM encoded RSA, ECDSA or Ed25519 key file and optional not_before is RFC3339
+// time when private key becomes active.
+func LoadKeySet(spec string) (*KeySet, error) {
+	var keys []*Key
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid key entry %q, kid=path[@not_before] expected", entry)
+		}
+		var notBefore time.Time
+		if p, nb, found := strings.Cut(path, "@"); found {
+			var err error
+			if notBefore, err = time.Parse(time.RFC3339, nb); err != nil {
+				return nil, fmt.Errorf("invalid activation time of key %s: %w", kid, err)
+			}
+			path = p
+		}
+		key, err := LoadKey(kid, path, notBefore)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return NewKeySet(keys...)
+}
+
+// LoadKey loads PEM encoded private or public key from file.
+func LoadKey(kid, path string, notBefore time.Time) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key %s: %w", kid, err)
+	}
+	key, err := ParseKey(kid, data)
+	if err != nil {
+		return nil, err
+	}
+	key.NotBefore = notBefore
+	return key, nil
+}
+
+// ParseKey parses PEM encoded private or public key.
+func ParseKey(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s is not PEM encoded", kid)
+	}
+	var (
+		parsed any
+		err    error
+	)
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q of key %s", block.Type, kid)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse key %s: %w", kid, err)
+	}
+	key := &Key{ID: kid}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.private = signer
+		parsed = signer.Public()
+	}
+	key.public = parsed
+	if key.method, err = signingMethod(parsed); err != nil {
+		return nil, fmt.Errorf("unsupported key %s: %w", kid, err)
+	}
+	return key, nil
+}
+
+func signingMethod(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("rsa key must be at least %d bits", minRSAKeyBits)
+		}
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, errors.New("unsupported elliptic curve")
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", public)
+}
+
+// signingKey returns newest active private key.
+func (ks *KeySet) signingKey() (*Key, error) {
+	var (
+		now    = ks.now()
+		signer *Key
+	)
+	for _, key := range ks.keys {
+		if key.private == nil || key.NotBefore.After(now) {
+			continue
+		}
+		if signer == nil || !key.NotBefore.Before(signer.NotBefore) {
+			signer = key
+		}
+	}
+	if signer == nil {
+		return nil, errors.New("no active signing key")
+	}
+	return signer, nil
+}
+
+// key returns key by id.
+func (ks *KeySet) key(kid string) (*Key, bool) {
+	for _, key := range ks.keys {
+		if key.ID == kid {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// JWKS returns public keys of set including keys that are not active yet,
+// so token verifiers can fetch them before rotation.
+func (ks *KeySet) JWKS() *JWKS {
+	jwks := &JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		jwks.Keys = append(jwks.Keys, key.jwk())
+	}
+	return jwks
+}
+
+// PublicKey decodes public key of json web key. RSA, EC and Ed25519 keys are supported.
+func (jwk *JWK) PublicKey() (crypto.PublicKey, error) {
+	dec := base64.RawURLEncoding.DecodeString
+	switch jwk.Kty {
+	case "RSA":
+		n, err := dec(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rsa modulus: %w", err)
+		}
+		e, err := dec(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid rsa exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var (
+			curve elliptic.Curve
+			ecdhC ecdh.Curve
+		)
+		switch jwk.Crv {
+		case "P-256":
+			curve, ecdhC = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ecdhC = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, ecdhC = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported elliptic curve %q", jwk.Crv)
+		}
+		x, errX := dec(jwk.X)
+		y, errY := dec(jwk.Y)
+		size := (curve.Params().BitSize + 7) / 8 //nolint:gomnd // bits to bytes
+		if errX != nil || errY != nil || len(x) != size || len(y) != size {
+			return nil, errors.New("invalid elliptic curve point")
+		}
+		// ecdh validates that point is on curve
+		if _, err := ecdhC.NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("invalid elliptic curve point: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		x, err := dec(jwk.X)
+		if jwk.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+func (key *Key) jwk() JWK {
+	enc := base64.RawURLEncoding.EncodeToString
+	jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.method.Alg()}
+	switch k := key.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = enc(k.N.Bytes())
+		jwk.E = enc(big.NewInt(int64(k.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8 //nolint:gomnd // bits to bytes
+		jwk.Kty = "EC"
+		jwk.Crv = k.Curve.Params().Name
+		jwk.X = enc(k.X.FillBytes(make([]byte, size)))
+		jwk.Y = enc(k.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = enc(k)
+	}
+	return jwk
+}
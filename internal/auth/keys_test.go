@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adwski/shorty/internal/user"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKey(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600))
+	return path
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaPath := writeKey(t, dir, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+	ecPath := writeKey(t, dir, "ec.pem", "EC PRIVATE KEY", ecDER)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	edPath := writeKey(t, dir, "ed.pem", "PRIVATE KEY", edDER)
+
+	pubDER, err := x509.MarshalPKIXPublicKey(edKey.Public())
+	require.NoError(t, err)
+	pubPath := writeKey(t, dir, "ed.pub", "PUBLIC KEY", pubDER)
+
+	ks, err := LoadKeySet("rsa=" + rsaPath + "@2024-01-01T00:00:00Z, ec=" + ecPath + "@2024-02-01T00:00:00Z," +
+		"ed=" + edPath + "@2024-03-01T00:00:00Z,edpub=" + pubPath)
+	require.NoError(t, err)
+
+	jwks := ks.JWKS()
+	require.Len(t, jwks.Keys, 4)
+	assert.Equal(t, JWK{Kty: "RSA", Kid: "rsa", Use: "sig", Alg: "RS256", N: jwks.Keys[0].N, E: "AQAB"}, jwks.Keys[0])
+	assert.Equal(t, "EC", jwks.Keys[1].Kty)
+	assert.Equal(t, "P-256", jwks.Keys[1].Crv)
+	assert.Equal(t, "ES256", jwks.Keys[1].Alg)
+	assert.Equal(t, "OKP", jwks.Keys[2].Kty)
+	assert.Equal(t, "EdDSA", jwks.Keys[2].Alg)
+	assert.Equal(t, jwks.Keys[2].X, jwks.Keys[3].X)
+	for i, jwk := range jwks.Keys {
+		pub, errK := jwk.PublicKey()
+		require.NoError(t, errK, jwk.Kid)
+		assert.True(t, ks.keys[i].public.(interface{ Equal(crypto.PublicKey) bool }).Equal(pub), jwk.Kid)
+	}
+	_, err = (&JWK{Kty: "EC", Crv: "P-256", X: jwks.Keys[1].X, Y: jwks.Keys[1].X}).PublicKey()
+	assert.Error(t, err)
+
+	a := New("secret").WithKeySet(ks, false)
+	u := &user.User{ID: "tdGk2USqTvWW8jyz7HnhlA"}
+	tokens := make(map[string]string)
+	for _, tt := range []struct {
+		now time.Time
+		kid string
+		alg string
+	}{
+		{now: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), kid: "rsa", alg: "RS256"},
+		{now: time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), kid: "ec", alg: "ES256"},
+		{now: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), kid: "ed", alg: "EdDSA"},
+	} {
+		ks.now = func() time.Time { return tt.now }
+		token, errT := a.CreateToken(u)
+		require.NoError(t, errT)
+		parsed, _, errP := jwt.NewParser().ParseUnverified(token, &Claims{})
+		require.NoError(t, errP)
+		assert.Equal(t, tt.kid, parsed.Header["kid"])
+		assert.Equal(t, tt.alg, parsed.Method.Alg())
+		tokens[tt.kid] = token
+	}
+
+	// tokens signed with older keys are still valid
+	for kid, token := range tokens {
+		parsedUser, errP := a.ParseUserFromJWTString(ctx, token)
+		require.NoError(t, errP, kid)
+		assert.Equal(t, u.ID, parsedUser.ID)
+	}
+
+	// no active key
+	ks.now = func() time.Time { return time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) }
+	_, err = a.CreateToken(u)
+	assert.Error(t, err)
+
+	// secret tokens are accepted only during migration
+	secretToken, err := New("secret").CreateToken(u)
+	require.NoError(t, err)
+	_, err = a.ParseUserFromJWTString(ctx, secretToken)
+	assert.Error(t, err)
+	_, err = New("secret").WithKeySet(ks, true).ParseUserFromJWTString(ctx, secretToken)
+	assert.NoError(t, err)
+
+	// token of unknown key is rejected
+	other, err := NewKeySet(&Key{ID: "other", method: jwt.SigningMethodEdDSA, private: edKey, public: edKey.Public()})
+	require.NoError(t, err)
+	otherToken, err := New("secret").WithKeySet(other, false).CreateToken(u)
+	require.NoError(t, err)
+	_, err = a.ParseUserFromJWTString(ctx, otherToken)
+	assert.ErrorContains(t, err, "unknown key id")
+}
+
+func TestLoadKeySet_Errors(t *testing.T) {
+	dir := t.TempDir()
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(edKey.Public())
+	require.NoError(t, err)
+	pubPath := writeKey(t, dir, "ed.pub", "PUBLIC KEY", pubDER)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	edPath := writeKey(t, dir, "ed.pem", "PRIVATE KEY", edDER)
+	smallRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	rsaPath := writeKey(t, dir, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(smallRSA))
+
+	for _, spec := range []string{
+		"pub=" + pubPath,
+		edPath,
+		"a=" + edPath + ",a=" + edPath,
+		"a=" + edPath + "@tomorrow",
+		"a=" + filepath.Join(dir, "missing.pem"),
+		"a=" + rsaPath,
+	} {
+		_, err = LoadKeySet(spec)
+		assert.Error(t, err, spec)
+	}
+}
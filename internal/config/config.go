@@ -5,17 +5,28 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net/url"
+	"slices"
+	"strings"
+	"time"
 
 	authorizer "github.com/adwski/shorty/internal/auth"
 	"github.com/adwski/shorty/internal/filter"
+	"github.com/adwski/shorty/internal/geoip"
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/ratelimit"
+	"github.com/adwski/shorty/internal/services/quota"
 	"go.uber.org/zap"
 )
 
 // Config holds Shorty app config params.
 type Config struct {
-	Storage *Storage `json:"storage"`
-	TLS     *TLS     `json:"tls"`
-	Filter  *Filter  `json:"filter"`
+	Storage   *Storage   `json:"storage"`
+	TLS       *TLS       `json:"tls"`
+	Filter    *Filter    `json:"filter"`
+	Normalize *Normalize `json:"normalize"`
+	OIDC      *OIDC      `json:"oidc"`
+	RateLimit *RateLimit `json:"rate_limit"`
+	Quota     *Quota     `json:"quota"`
 
 	tls *tls.Config
 
@@ -23,6 +34,16 @@ type Config struct {
 
 	filter *filter.Filter
 
+	geoIP *geoip.DB
+
+	scheduleTZ *time.Location
+
+	redirectMaxAge time.Duration
+
+	rateLimits []ratelimit.Rule
+
+	quotaTiers []model.Tier
+
 	configFilePath string
 
 	ListenAddr      string `json:"listen_addr"`
@@ -30,12 +51,29 @@ type Config struct {
 	BaseURL         string `json:"base_url"`
 	RedirectScheme  string `json:"redirect_scheme"`
 	JWTSecret       string `json:"jwt_secret"`
+	JWTKeys         string `json:"jwt_keys"`
 	PprofServerAddr string `json:"pprof_listen_addr"`
+	GeoIPPath       string `json:"geoip_db"`
+	ScheduleTZ      string `json:"schedule_timezone"`
+	BackupDir       string `json:"backup_dir"`
+	SessionTTL      string `json:"session_ttl"`
+	SessionRefresh  string `json:"session_refresh"`
+	RedirectMaxAge  string `json:"redirect_max_age"`
 	ServedHost      string `json:"-"`
 	ServedScheme    string `json:"-"`
 
+	// Args are positional command line arguments, they select admin command.
+	Args []string `json:"-"`
+
+	RedirectCode int `json:"redirect_code"`
+
 	GRPCReflection bool `json:"grpc_reflection"`
 	TrustRequestID bool `json:"trust_request_id"`
+	// StrictAuth rejects mutating requests without valid credentials
+	// instead of creating new anonymous users.
+	StrictAuth bool `json:"strict_auth"`
+	// JWTAcceptSecret keeps tokens signed with jwt secret valid when jwt keys are configured.
+	JWTAcceptSecret bool `json:"jwt_accept_secret"`
 }
 
 // GetTLSConfig returns crypto/tls.Config if tls was enabled in configuration,
@@ -54,6 +92,32 @@ func (cfg *Config) GetFilter() *filter.Filter {
 	return cfg.filter
 }
 
+// GetGeoIP retrieves application's GeoIP database,
+// it will return nil if database was not configured.
+func (cfg *Config) GetGeoIP() *geoip.DB {
+	return cfg.geoIP
+}
+
+// GetScheduleTimezone returns default timezone of link schedules.
+func (cfg *Config) GetScheduleTimezone() *time.Location {
+	return cfg.scheduleTZ
+}
+
+// GetRedirectMaxAge returns how long clients may cache permanent redirects.
+func (cfg *Config) GetRedirectMaxAge() time.Duration {
+	return cfg.redirectMaxAge
+}
+
+// GetRateLimits returns parsed rate limit rules, requests are not limited if there are no rules.
+func (cfg *Config) GetRateLimits() []ratelimit.Rule {
+	return cfg.rateLimits
+}
+
+// GetQuotaTiers returns parsed quota tiers.
+func (cfg *Config) GetQuotaTiers() []model.Tier {
+	return cfg.quotaTiers
+}
+
 // TLS holds Shorty tls configuration params.
 type TLS struct {
 	CertPath      string `json:"cert"`
@@ -69,6 +133,61 @@ type Filter struct {
 	TrustXRealIP bool   `json:"trust_x_real_ip"`
 }
 
+// Normalize holds URL normalization config params.
+type Normalize struct {
+	TrackingParams string `json:"tracking_params"`
+	SortQuery      bool   `json:"sort_query"`
+	RemoveFragment bool   `json:"remove_fragment"`
+	StripTracking  bool   `json:"strip_tracking"`
+}
+
+// GetTrackingParams returns list of tracking params.
+func (n *Normalize) GetTrackingParams() []string {
+	var params []string
+	for _, param := range strings.Split(n.TrackingParams, ",") {
+		if param = strings.ToLower(strings.TrimSpace(param)); param != "" {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// OIDC holds single sign-on config params, sign-on is disabled if issuer is empty.
+type OIDC struct {
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RedirectURL  string `json:"redirect_url"`
+	Scopes       string `json:"scopes"`
+}
+
+// GetScopes returns list of requested scopes.
+func (o *OIDC) GetScopes() []string {
+	var scopes []string
+	for _, scope := range strings.Split(o.Scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// RateLimit holds rate limiting config params.
+type RateLimit struct {
+	// Rules is comma separated list of <route>=<requests>/<period>[:<burst>] rules.
+	Rules string `json:"rules"`
+	// Shared enables keeping of token buckets in database, so they're shared by all instances.
+	Shared bool `json:"shared"`
+}
+
+// Quota holds user quota config params.
+type Quota struct {
+	// Tiers is comma separated list of <name>=<max links>/<max batch> tiers.
+	Tiers string `json:"tiers"`
+	// DefaultTier is tier of users without assigned quota, users are not limited if it's empty.
+	DefaultTier string `json:"default_tier"`
+}
+
 // Storage holds Shorty storage config params.
 type Storage struct {
 	DatabaseDSN     string `json:"database_dsn"`
@@ -97,6 +216,7 @@ func New(logger *zap.Logger) (*Config, error) {
 		}
 		// Merge configs
 		merge(cfgFromFile, cfgFromArgs)
+		cfgFromFile.Args = cfgFromArgs.Args
 		cfg = cfgFromFile
 	} else {
 		cfg = cfgFromArgs
@@ -107,6 +227,21 @@ func New(logger *zap.Logger) (*Config, error) {
 		return nil, err
 	}
 
+	if !model.IsValidRedirect(cfg.RedirectCode) {
+		return nil, fmt.Errorf("unsupported redirect code: %d", cfg.RedirectCode)
+	}
+
+	if cfg.redirectMaxAge, err = time.ParseDuration(cfg.RedirectMaxAge); err != nil {
+		return nil, fmt.Errorf("invalid redirect max age: %w", err)
+	}
+	if cfg.redirectMaxAge < 0 {
+		return nil, fmt.Errorf("redirect max age must not be negative: %s", cfg.redirectMaxAge)
+	}
+
+	if cfg.scheduleTZ, err = time.LoadLocation(cfg.ScheduleTZ); err != nil {
+		return nil, fmt.Errorf("cannot load schedule timezone: %w", err)
+	}
+
 	// Parse server base URL
 	if err = cfg.parseBaseURL(); err != nil {
 		return nil, err
@@ -121,6 +256,19 @@ func New(logger *zap.Logger) (*Config, error) {
 	}
 
 	cfg.auth = authorizer.New(cfg.JWTSecret)
+	if cfg.JWTKeys != "" {
+		keys, errK := authorizer.LoadKeySet(cfg.JWTKeys)
+		if errK != nil {
+			return nil, fmt.Errorf("cannot load jwt keys: %w", errK)
+		}
+		cfg.auth.WithKeySet(keys, cfg.JWTAcceptSecret)
+	}
+	if err = cfg.setSessionTTL(); err != nil {
+		return nil, err
+	}
+	if cfg.OIDC.Issuer != "" && cfg.OIDC.RedirectURL == "" {
+		cfg.OIDC.RedirectURL = strings.TrimSuffix(cfg.BaseURL, "/") + defaultOIDCCallbackPath
+	}
 
 	cfg.filter, err = filter.New(&filter.Config{
 		Logger:             logger,
@@ -132,9 +280,47 @@ func New(logger *zap.Logger) (*Config, error) {
 		return nil, fmt.Errorf("cannot configure filter: %w", err)
 	}
 
+	if cfg.rateLimits, err = ratelimit.ParseRules(cfg.RateLimit.Rules); err != nil {
+		return nil, fmt.Errorf("cannot parse rate limits: %w", err)
+	}
+
+	if cfg.quotaTiers, err = quota.ParseTiers(cfg.Quota.Tiers); err != nil {
+		return nil, fmt.Errorf("cannot parse quota tiers: %w", err)
+	}
+	if cfg.Quota.DefaultTier != "" && !slices.ContainsFunc(cfg.quotaTiers, func(t model.Tier) bool {
+		return t.Name == cfg.Quota.DefaultTier
+	}) {
+		return nil, fmt.Errorf("default quota tier %q is not configured", cfg.Quota.DefaultTier)
+	}
+
+	if cfg.GeoIPPath != "" {
+		if cfg.geoIP, err = geoip.Open(cfg.GeoIPPath); err != nil {
+			return nil, fmt.Errorf("cannot load geoip database: %w", err)
+		}
+	}
+
 	return cfg, nil
 }
 
+func (cfg *Config) setSessionTTL() error {
+	ttl, err := time.ParseDuration(cfg.SessionTTL)
+	if err != nil {
+		return fmt.Errorf("invalid session ttl: %w", err)
+	}
+	refresh, err := time.ParseDuration(cfg.SessionRefresh)
+	if err != nil {
+		return fmt.Errorf("invalid session refresh interval: %w", err)
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("session ttl must be positive: %s", ttl)
+	}
+	if refresh < 0 || refresh >= ttl {
+		return fmt.Errorf("session refresh interval must be from 0 to session ttl: %s", refresh)
+	}
+	cfg.auth.WithSessionTTL(ttl, refresh)
+	return nil
+}
+
 func (cfg *Config) parseBaseURL() error {
 	baseURL, err := url.Parse(cfg.BaseURL)
 	if err != nil {
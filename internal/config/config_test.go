@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -33,13 +34,40 @@ var testConfig = `
   "pprof_listen_addr": "0.0.0.0:2222",
   "base_url": "http://qwe.asd",
   "redirect_scheme": "http",
+  "redirect_code": 308,
+  "redirect_max_age": "1h",
   "jwt_secret": "qweqwe",
+  "jwt_keys": "main=%s",
+  "jwt_accept_secret": true,
+  "session_ttl": "2h",
+  "session_refresh": "30m",
   "trust_request_id": true,
   "grpc_reflection": true,
+  "strict_auth": true,
   "filter": {
     "trusted_subnets": "1.1.0.0/16,fedc::/16",
     "trust_x_forwarded_for": true,
     "trust_x_real_ip": true
+  },
+  "normalize": {
+    "tracking_params": "utm_*, FBCLID",
+    "sort_query": true,
+    "remove_fragment": true,
+    "strip_tracking": true
+  },
+  "oidc": {
+    "issuer": "https://idp.asd",
+    "client_id": "shorty",
+    "client_secret": "qwerty",
+    "scopes": "openid, email"
+  },
+  "rate_limit": {
+    "rules": "POST /=10/1m:20",
+    "shared": true
+  },
+  "quota": {
+    "tiers": "free=100/10,pro=0/1000",
+    "default_tier": "free"
   }
 }
 `
@@ -147,7 +175,7 @@ func TestNew(t *testing.T) {
 	require.NoError(t, err)
 	defer func() { _ = os.Remove(fKey.Name()) }()
 
-	configBytes := []byte(fmt.Sprintf(testConfig, fCert.Name(), fKey.Name()))
+	configBytes := []byte(fmt.Sprintf(testConfig, fCert.Name(), fKey.Name(), fKey.Name()))
 	err = os.WriteFile(fConfig.Name(), configBytes, os.ModePerm)
 	require.NoError(t, err)
 	err = os.WriteFile(fKey.Name(), []byte(testKey), os.ModePerm)
@@ -166,11 +194,32 @@ func TestNew(t *testing.T) {
 	assert.NotNil(t, cfg.GetTLSConfig())
 	assert.NotNil(t, cfg.Storage)
 	assert.NotNil(t, cfg.Filter)
+	assert.NotNil(t, cfg.Normalize)
 
 	assert.Equal(t, "1.1.0.0/16,fedc::/16", cfg.Filter.Subnets)
 	assert.True(t, cfg.Filter.TrustXFF)
 	assert.True(t, cfg.Filter.TrustXRealIP)
 
+	assert.Equal(t, []string{"utm_*", "fbclid"}, cfg.Normalize.GetTrackingParams())
+	assert.True(t, cfg.Normalize.SortQuery)
+	assert.True(t, cfg.Normalize.RemoveFragment)
+	assert.True(t, cfg.Normalize.StripTracking)
+
+	assert.Equal(t, "https://idp.asd", cfg.OIDC.Issuer)
+	assert.Equal(t, "shorty", cfg.OIDC.ClientID)
+	assert.Equal(t, "qwerty", cfg.OIDC.ClientSecret)
+	assert.Equal(t, "http://qwe.asd/api/user/sso/callback", cfg.OIDC.RedirectURL)
+	assert.Equal(t, []string{"openid", "email"}, cfg.OIDC.GetScopes())
+
+	require.Len(t, cfg.GetRateLimits(), 1)
+	assert.Equal(t, "POST /", cfg.GetRateLimits()[0].Pattern)
+	assert.Equal(t, 20, cfg.GetRateLimits()[0].Limit.Burst)
+	assert.True(t, cfg.RateLimit.Shared)
+
+	require.Len(t, cfg.GetQuotaTiers(), 2)
+	assert.Equal(t, 100, cfg.GetQuotaTiers()[0].MaxURLs)
+	assert.Equal(t, "free", cfg.Quota.DefaultTier)
+
 	assert.Equal(t, "/qwe/qweasd", cfg.Storage.FileStoragePath)
 	assert.Equal(t, "postgres://qweasd.asd/db", cfg.Storage.DatabaseDSN)
 	assert.True(t, cfg.Storage.TraceDB)
@@ -186,6 +235,15 @@ func TestNew(t *testing.T) {
 	assert.Equal(t, "qwe.asd", cfg.ServedHost)
 	assert.Equal(t, "http", cfg.ServedScheme)
 	assert.Equal(t, "qweqwe", cfg.JWTSecret)
+	assert.Equal(t, "main="+fKey.Name(), cfg.JWTKeys)
+	assert.True(t, cfg.JWTAcceptSecret)
+	assert.Equal(t, "2h", cfg.SessionTTL)
+	assert.Equal(t, "30m", cfg.SessionRefresh)
+	require.Len(t, cfg.GetAuthorizer().JWKS().Keys, 1)
+	assert.Equal(t, "RS256", cfg.GetAuthorizer().JWKS().Keys[0].Alg)
 	assert.True(t, cfg.TrustRequestID)
 	assert.True(t, cfg.GRPCReflection)
+	assert.True(t, cfg.StrictAuth)
+	assert.Equal(t, 308, cfg.RedirectCode)
+	assert.Equal(t, time.Hour, cfg.GetRedirectMaxAge())
 }
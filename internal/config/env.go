@@ -13,10 +13,32 @@ func mergeEnvs(cfg *Config) error {
 	envOverride("FILE_STORAGE_PATH", &cfg.Storage.FileStoragePath)
 	envOverride("DATABASE_DSN", &cfg.Storage.DatabaseDSN)
 	envOverride("JWT_SECRET", &cfg.JWTSecret)
+	envOverride("JWT_KEYS", &cfg.JWTKeys)
+	envOverride("SESSION_TTL", &cfg.SessionTTL)
+	envOverride("SESSION_REFRESH", &cfg.SessionRefresh)
+	envOverride("REDIRECT_MAX_AGE", &cfg.RedirectMaxAge)
 	envOverride("TRUSTED_SUBNETS", &cfg.Filter.Subnets)
+	envOverride("GEOIP_DB", &cfg.GeoIPPath)
+	envOverride("BACKUP_DIR", &cfg.BackupDir)
+	envOverride("OIDC_ISSUER", &cfg.OIDC.Issuer)
+	envOverride("OIDC_CLIENT_ID", &cfg.OIDC.ClientID)
+	envOverride("OIDC_CLIENT_SECRET", &cfg.OIDC.ClientSecret)
+	envOverride("OIDC_REDIRECT_URL", &cfg.OIDC.RedirectURL)
+	envOverride("RATE_LIMITS", &cfg.RateLimit.Rules)
+	envOverride("QUOTA_TIERS", &cfg.Quota.Tiers)
+	envOverride("QUOTA_DEFAULT_TIER", &cfg.Quota.DefaultTier)
 	if err := envOverrideBool("ENABLE_HTTPS", &cfg.TLS.Enable); err != nil {
 		return err
 	}
+	if err := envOverrideBool("STRICT_AUTH", &cfg.StrictAuth); err != nil {
+		return err
+	}
+	if err := envOverrideBool("JWT_ACCEPT_SECRET", &cfg.JWTAcceptSecret); err != nil {
+		return err
+	}
+	if err := envOverrideBool("RATE_LIMIT_SHARED", &cfg.RateLimit.Shared); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/spf13/pflag"
@@ -14,15 +15,28 @@ const (
 	defaultBaseURL         = "http://localhost:8080"
 	defaultJWTSecret       = "supersecret"
 	defaultFileStoragePath = "/tmp/short-url-db.json"
+	defaultTrackingParams  = "utm_*,fbclid,gclid"
+	defaultRedirectCode    = http.StatusTemporaryRedirect
+	defaultRedirectMaxAge  = "24h"
+	defaultScheduleTZ      = "UTC"
+	defaultSessionTTL      = "24h"
+	defaultSessionRefresh  = "12h"
+	defaultOIDCScopes      = "openid,email,profile"
+
+	defaultOIDCCallbackPath = "/api/user/sso/callback"
 )
 
 func newFromFlags() (*Config, error) {
 	fs := pflag.NewFlagSet("common", pflag.ContinueOnError)
 
 	cfg := &Config{
-		TLS:     &TLS{},
-		Storage: &Storage{},
-		Filter:  &Filter{},
+		TLS:       &TLS{},
+		Storage:   &Storage{},
+		Filter:    &Filter{},
+		Normalize: &Normalize{},
+		OIDC:      &OIDC{},
+		RateLimit: &RateLimit{},
+		Quota:     &Quota{},
 	}
 
 	fs.StringVarP(&cfg.configFilePath, "config", "c", "", "path to config file")
@@ -33,11 +47,33 @@ func newFromFlags() (*Config, error) {
 		"pprof server listen address, it will not start if left empty")
 	fs.StringVarP(&cfg.BaseURL, "base_url", "b", defaultBaseURL, "base server URL")
 	fs.StringVar(&cfg.JWTSecret, "jwt_secret", defaultJWTSecret, "jwt cookie secret key")
+	fs.StringVar(&cfg.JWTKeys, "jwt_keys", "",
+		"comma separated list of kid=path[@not_before] PEM encoded RSA, ECDSA or Ed25519 jwt keys, "+
+			"tokens are signed with newest active private key instead of jwt_secret")
+	fs.BoolVar(&cfg.JWTAcceptSecret, "jwt_accept_secret", false,
+		"accept tokens signed with jwt_secret when jwt_keys are set, use it during migration to jwt keys")
+	fs.StringVar(&cfg.SessionTTL, "session_ttl", defaultSessionTTL, "lifetime of session token")
+	fs.StringVar(&cfg.SessionRefresh, "session_refresh", defaultSessionRefresh,
+		"session token is re-issued when it's used and expires in less than this interval, 0 disables re-issue")
 	fs.StringVar(&cfg.RedirectScheme, "redirect_scheme", "", "enforce redirect scheme, leave empty to allow all")
+	fs.IntVar(&cfg.RedirectCode, "redirect_code", defaultRedirectCode,
+		"default redirect status code for links without explicit one, can be 301, 302, 307 or 308")
+	fs.StringVar(&cfg.RedirectMaxAge, "redirect_max_age", defaultRedirectMaxAge,
+		"how long clients may cache permanent redirects of links without targets, variants or schedule, "+
+			"0 disables caching")
+	fs.StringVar(&cfg.GeoIPPath, "geoip_db", "",
+		"path to GeoIP database file (mmdb or csv) used for country targets, leave empty to disable")
+	fs.StringVar(&cfg.ScheduleTZ, "schedule_timezone", defaultScheduleTZ,
+		"default timezone of link schedule rules, IANA name or 'Local'")
+	fs.StringVar(&cfg.BackupDir, "backup_dir", "",
+		"directory of storage snapshots created with backup api, leave empty to disable backups")
 	fs.BoolVar(&cfg.TrustRequestID, "trust_request_id", false,
 		"trust X-Request-Id header, if disabled unique id will be generated for each request even if header exists")
 	fs.BoolVar(&cfg.GRPCReflection, "grpc_reflection", false,
 		"enables grpc reflection api, useful for testing with gui clients")
+	fs.BoolVar(&cfg.StrictAuth, "strict_auth", false,
+		"reject mutating requests with missing, invalid or expired credentials instead of creating new anonymous users, "+
+			"anonymous users can be created only with explicit api call")
 
 	fs.StringVarP(&cfg.Storage.FileStoragePath, "file_storage_path", "f", defaultFileStoragePath, "file storage path")
 	fs.StringVarP(&cfg.Storage.DatabaseDSN, "dsn", "d", "", "postgres connection DSN")
@@ -55,9 +91,40 @@ func newFromFlags() (*Config, error) {
 		"trust x-forwarded-for header during request filtering")
 	fs.BoolVar(&cfg.Filter.TrustXRealIP, "trust_x_real_ip", false, "trust x-real-ip header during request filtering")
 
+	fs.BoolVar(&cfg.Normalize.SortQuery, "normalize_sort_query", false,
+		"sort query params of original url before storing")
+	fs.BoolVar(&cfg.Normalize.RemoveFragment, "normalize_remove_fragment", false,
+		"remove fragment of original url before storing")
+	fs.BoolVar(&cfg.Normalize.StripTracking, "normalize_strip_tracking", false,
+		"strip tracking query params of original url before storing")
+	fs.StringVar(&cfg.Normalize.TrackingParams, "normalize_tracking_params", defaultTrackingParams,
+		"comma separated list of tracking query params, '*' at the end matches any suffix")
+
+	fs.StringVar(&cfg.OIDC.Issuer, "oidc_issuer", "",
+		"OpenID Connect issuer url used for single sign-on, leave empty to disable")
+	fs.StringVar(&cfg.OIDC.ClientID, "oidc_client_id", "", "OpenID Connect client id")
+	fs.StringVar(&cfg.OIDC.ClientSecret, "oidc_client_secret", "",
+		"OpenID Connect client secret, leave empty for public clients")
+	fs.StringVar(&cfg.OIDC.RedirectURL, "oidc_redirect_url", "",
+		"OpenID Connect redirect url, defaults to base url with "+defaultOIDCCallbackPath)
+	fs.StringVar(&cfg.OIDC.Scopes, "oidc_scopes", defaultOIDCScopes, "comma separated list of requested scopes")
+
+	fs.StringVar(&cfg.RateLimit.Rules, "rate_limits", "",
+		"comma separated list of <route>=<requests>/<period>[:<burst>] rate limits, routes are '<METHOD> <path>' "+
+			"or grpc method names, trailing '*' matches any suffix, e.g. 'POST /=10/1m:20', leave empty to disable")
+	fs.BoolVar(&cfg.RateLimit.Shared, "rate_limit_shared", false,
+		"keep rate limit buckets in database to share them between instances, requires database storage")
+
+	fs.StringVar(&cfg.Quota.Tiers, "quota_tiers", "",
+		"comma separated list of <name>=<max links>/<max batch> user quota tiers, zero means no limit, "+
+			"e.g. 'free=100/10,pro=10000/1000', leave empty to disable quotas")
+	fs.StringVar(&cfg.Quota.DefaultTier, "quota_default_tier", "",
+		"quota tier of users without assigned quota, leave empty to not limit such users")
+
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		return nil, fmt.Errorf("cannot parse command line arguments: %w", err)
 	}
+	cfg.Args = fs.Args()
 
 	return cfg, nil
 }
@@ -3,6 +3,10 @@ package config
 func merge(dst, src *Config) {
 	mergeTLS(dst, src)
 	mergeStorage(dst, src)
+	mergeNormalize(dst, src)
+	mergeOIDC(dst, src)
+	mergeRateLimit(dst, src)
+	mergeQuota(dst, src)
 	mergeCommon(dst, src)
 }
 
@@ -12,9 +16,19 @@ func mergeCommon(dst, src *Config) {
 	mergeStringDef(&dst.BaseURL, &src.BaseURL, defaultBaseURL)
 	mergeString(&dst.RedirectScheme, &src.RedirectScheme)
 	mergeStringDef(&dst.JWTSecret, &src.JWTSecret, defaultJWTSecret)
+	mergeString(&dst.JWTKeys, &src.JWTKeys)
 	mergeString(&dst.PprofServerAddr, &src.PprofServerAddr)
+	mergeString(&dst.GeoIPPath, &src.GeoIPPath)
+	mergeStringDef(&dst.ScheduleTZ, &src.ScheduleTZ, defaultScheduleTZ)
+	mergeStringDef(&dst.SessionTTL, &src.SessionTTL, defaultSessionTTL)
+	mergeStringDef(&dst.SessionRefresh, &src.SessionRefresh, defaultSessionRefresh)
+	mergeString(&dst.BackupDir, &src.BackupDir)
+	mergeIntDef(&dst.RedirectCode, &src.RedirectCode, defaultRedirectCode)
+	mergeStringDef(&dst.RedirectMaxAge, &src.RedirectMaxAge, defaultRedirectMaxAge)
 	mergeBool(&dst.TrustRequestID, &src.TrustRequestID)
 	mergeBool(&dst.GRPCReflection, &src.GRPCReflection)
+	mergeBool(&dst.StrictAuth, &src.StrictAuth)
+	mergeBool(&dst.JWTAcceptSecret, &src.JWTAcceptSecret)
 }
 
 func mergeStorage(dst, src *Config) {
@@ -27,6 +41,47 @@ func mergeStorage(dst, src *Config) {
 	}
 }
 
+func mergeNormalize(dst, src *Config) {
+	if dst.Normalize == nil {
+		dst.Normalize = src.Normalize
+	} else if src.Normalize != nil {
+		mergeStringDef(&dst.Normalize.TrackingParams, &src.Normalize.TrackingParams, defaultTrackingParams)
+		mergeBool(&dst.Normalize.SortQuery, &src.Normalize.SortQuery)
+		mergeBool(&dst.Normalize.RemoveFragment, &src.Normalize.RemoveFragment)
+		mergeBool(&dst.Normalize.StripTracking, &src.Normalize.StripTracking)
+	}
+}
+
+func mergeOIDC(dst, src *Config) {
+	if dst.OIDC == nil {
+		dst.OIDC = src.OIDC
+	} else if src.OIDC != nil {
+		mergeString(&dst.OIDC.Issuer, &src.OIDC.Issuer)
+		mergeString(&dst.OIDC.ClientID, &src.OIDC.ClientID)
+		mergeString(&dst.OIDC.ClientSecret, &src.OIDC.ClientSecret)
+		mergeString(&dst.OIDC.RedirectURL, &src.OIDC.RedirectURL)
+		mergeStringDef(&dst.OIDC.Scopes, &src.OIDC.Scopes, defaultOIDCScopes)
+	}
+}
+
+func mergeRateLimit(dst, src *Config) {
+	if dst.RateLimit == nil {
+		dst.RateLimit = src.RateLimit
+	} else if src.RateLimit != nil {
+		mergeString(&dst.RateLimit.Rules, &src.RateLimit.Rules)
+		mergeBool(&dst.RateLimit.Shared, &src.RateLimit.Shared)
+	}
+}
+
+func mergeQuota(dst, src *Config) {
+	if dst.Quota == nil {
+		dst.Quota = src.Quota
+	} else if src.Quota != nil {
+		mergeString(&dst.Quota.Tiers, &src.Quota.Tiers)
+		mergeString(&dst.Quota.DefaultTier, &src.Quota.DefaultTier)
+	}
+}
+
 func mergeTLS(dst, src *Config) {
 	if dst.TLS == nil {
 		dst.TLS = src.TLS
@@ -46,6 +101,14 @@ func mergeStringDef(dst, src *string, def string) {
 	}
 }
 
+func mergeIntDef(dst, src *int, def int) {
+	if *src != 0 {
+		if *dst == 0 || *src != def {
+			*dst = *src
+		}
+	}
+}
+
 func mergeString(dst, src *string) {
 	if *src != "" {
 		*dst = *src
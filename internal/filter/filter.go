@@ -185,3 +185,72 @@ func parseSliceFromString[T any](s string, parse func(string) (T, error)) ([]T,
 	}
 	return entities, nil
 }
+
+// ClientIP returns client IP address using the same request parameters
+// as CheckRequestParams(). X-Real-IP and X-Forwarded-For are used only
+// if they are trusted in config and RemoteAddr itself is inside trusted subnets,
+// i.e. request came from trusted proxy. Otherwise RemoteAddr is used.
+//
+// X-Forwarded-For chain is walked from the right, first address outside
+// of trusted subnets is taken as client address. Addresses to the left of it
+// are set by client and cannot be relied on.
+//
+// Empty string is returned if address cannot be determined.
+func (f *Filter) ClientIP(remoteAddr, xRealIP, xForwardedFor string) string {
+	remote, ok := parseRemoteAddr(remoteAddr)
+	if !ok {
+		return ""
+	}
+	if !f.contains(remote) {
+		return remote.String()
+	}
+	if f.trustXRealIP {
+		if addr, err := netip.ParseAddr(strings.TrimSpace(xRealIP)); err == nil {
+			return addr.Unmap().String()
+		}
+	}
+	if f.trustXFF && xForwardedFor != "" {
+		client := remote
+		chain := strings.Split(xForwardedFor, ",")
+		for i := len(chain) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(chain[i]))
+			if err != nil {
+				// malformed hop, nearest trusted address is used
+				break
+			}
+			client = addr.Unmap()
+			if !f.contains(client) {
+				break
+			}
+		}
+		return client.String()
+	}
+	return remote.String()
+}
+
+// IsTrustedProxy checks whether RemoteAddr is inside trusted subnets, i.e. request came
+// from trusted proxy and client address it reports can be relied on.
+// Unlike CheckRequestParams(), false is returned if there's no trusted subnets configured.
+func (f *Filter) IsTrustedProxy(remoteAddr string) bool {
+	remote, ok := parseRemoteAddr(remoteAddr)
+	return ok && f.contains(remote)
+}
+
+func (f *Filter) contains(addr netip.Addr) bool {
+	for i := range f.subnets {
+		if f.subnets[i].Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRemoteAddr(remoteAddr string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(remoteAddr); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	if addr, err := netip.ParseAddr(remoteAddr); err == nil {
+		return addr.Unmap(), true
+	}
+	return netip.Addr{}, false
+}
@@ -218,3 +218,169 @@ func TestFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestFilter_ClientIP(t *testing.T) {
+	type args struct {
+		xFF          string
+		xRealIP      string
+		remoteAdd    string
+		trusted      string
+		trustXFF     bool
+		trustXRealIP bool
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "remote addr",
+			args: args{
+				remoteAdd: "1.1.1.1:1111",
+				xRealIP:   "2.2.2.2",
+				xFF:       "3.3.3.3",
+			},
+			want: "1.1.1.1",
+		},
+		{
+			name: "trusted x-real-ip",
+			args: args{
+				trustXRealIP: true,
+				trustXFF:     true,
+				trusted:      "1.1.1.0/24",
+				remoteAdd:    "1.1.1.1:1111",
+				xRealIP:      "2.2.2.2",
+				xFF:          "3.3.3.3",
+			},
+			want: "2.2.2.2",
+		},
+		{
+			name: "trusted xff",
+			args: args{
+				trustXFF:  true,
+				trusted:   "1.1.1.0/24",
+				remoteAdd: "1.1.1.1:1111",
+				xRealIP:   "2.2.2.2",
+				xFF:       "3.3.3.3, 4.4.4.4",
+			},
+			want: "4.4.4.4",
+		},
+		{
+			name: "trusted xff with trusted proxies in chain",
+			args: args{
+				trustXFF:  true,
+				trusted:   "1.1.1.0/24,10.0.0.0/8",
+				remoteAdd: "1.1.1.1:1111",
+				xFF:       "5.5.5.5, 3.3.3.3, 10.1.1.1, 10.2.2.2",
+			},
+			want: "3.3.3.3",
+		},
+		{
+			name: "trusted xff with all trusted addresses",
+			args: args{
+				trustXFF:  true,
+				trusted:   "1.1.1.0/24,10.0.0.0/8",
+				remoteAdd: "1.1.1.1:1111",
+				xFF:       "10.1.1.1, 10.2.2.2",
+			},
+			want: "10.1.1.1",
+		},
+		{
+			name: "trusted xff with malformed hop",
+			args: args{
+				trustXFF:  true,
+				trusted:   "1.1.1.0/24,10.0.0.0/8",
+				remoteAdd: "1.1.1.1:1111",
+				xFF:       "3.3.3.3, asdasd, 10.1.1.1",
+			},
+			want: "10.1.1.1",
+		},
+		{
+			name: "headers from untrusted remote addr",
+			args: args{
+				trustXRealIP: true,
+				trustXFF:     true,
+				trusted:      "10.0.0.0/8",
+				remoteAdd:    "1.1.1.1:1111",
+				xRealIP:      "2.2.2.2",
+				xFF:          "3.3.3.3",
+			},
+			want: "1.1.1.1",
+		},
+		{
+			name: "headers without trusted subnets",
+			args: args{
+				trustXRealIP: true,
+				trustXFF:     true,
+				remoteAdd:    "1.1.1.1:1111",
+				xRealIP:      "2.2.2.2",
+				xFF:          "3.3.3.3",
+			},
+			want: "1.1.1.1",
+		},
+		{
+			name: "malformed x-real-ip",
+			args: args{
+				trustXRealIP: true,
+				trustXFF:     true,
+				trusted:      "1.1.1.0/24",
+				remoteAdd:    "1.1.1.1:1111",
+				xRealIP:      "asdasd",
+				xFF:          "3.3.3.3",
+			},
+			want: "3.3.3.3",
+		},
+		{
+			name: "ipv6 remote addr",
+			args: args{
+				remoteAdd: "[abcd::abcd]:1111",
+			},
+			want: "abcd::abcd",
+		},
+		{
+			name: "ipv4-mapped remote addr",
+			args: args{
+				remoteAdd: "[::ffff:1.1.1.1]:1111",
+			},
+			want: "1.1.1.1",
+		},
+		{
+			name: "malformed remote addr",
+			args: args{
+				trustXFF:  true,
+				remoteAdd: "asdasd",
+				xFF:       "3.3.3.3",
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, errL := zap.NewDevelopment()
+			require.NoError(t, errL)
+
+			f, err := New(&Config{
+				Logger:             logger,
+				Subnets:            tt.args.trusted,
+				TrustXForwardedFor: tt.args.trustXFF,
+				TrustXRealIP:       tt.args.trustXRealIP,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, f.ClientIP(tt.args.remoteAdd, tt.args.xRealIP, tt.args.xFF))
+		})
+	}
+}
+
+func TestFilter_IsTrustedProxy(t *testing.T) {
+	f, err := New(&Config{Logger: zap.NewNop(), Subnets: "1.1.1.0/24"})
+	require.NoError(t, err)
+	assert.True(t, f.IsTrustedProxy("1.1.1.1:1111"))
+	assert.False(t, f.IsTrustedProxy("2.2.2.2:1111"))
+	assert.False(t, f.IsTrustedProxy("asdasd"))
+
+	// no trusted subnets means no trusted proxies
+	f, err = New(&Config{Logger: zap.NewNop()})
+	require.NoError(t, err)
+	assert.False(t, f.IsTrustedProxy("1.1.1.1:1111"))
+}
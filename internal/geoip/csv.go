@@ -0,0 +1,109 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+const (
+	csvFieldsNetwork = 2
+	csvFieldsRange   = 3
+)
+
+type ipRange struct {
+	from    netip.Addr
+	to      netip.Addr
+	country string
+}
+
+// rangeDB is a sorted list of non-overlapping ip ranges.
+type rangeDB struct {
+	ranges []ipRange
+}
+
+func parseCSV(data []byte) (*rangeDB, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+
+	var db rangeDB
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read csv geoip database: %w", err)
+		}
+		if rng, ok := parseCSVRecord(record); ok {
+			db.ranges = append(db.ranges, rng)
+		}
+	}
+	if len(db.ranges) == 0 {
+		return nil, errors.New("csv geoip database has no valid records")
+	}
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return db.ranges[i].from.Less(db.ranges[j].from)
+	})
+	return &db, nil
+}
+
+func parseCSVRecord(record []string) (ipRange, bool) {
+	var (
+		rng ipRange
+		err error
+	)
+	switch len(record) {
+	case csvFieldsNetwork:
+		var prefix netip.Prefix
+		if prefix, err = netip.ParsePrefix(record[0]); err != nil {
+			return rng, false
+		}
+		prefix = prefix.Masked()
+		rng.from = prefix.Addr().Unmap()
+		rng.to = lastIP(prefix).Unmap()
+	case csvFieldsRange:
+		if rng.from, err = netip.ParseAddr(record[0]); err != nil {
+			return rng, false
+		}
+		if rng.to, err = netip.ParseAddr(record[1]); err != nil {
+			return rng, false
+		}
+		rng.from, rng.to = rng.from.Unmap(), rng.to.Unmap()
+		if rng.from.Is4() != rng.to.Is4() || rng.to.Less(rng.from) {
+			return rng, false
+		}
+	default:
+		return rng, false
+	}
+	rng.country = strings.TrimSpace(record[len(record)-1])
+	return rng, rng.country != ""
+}
+
+func (db *rangeDB) lookup(addr netip.Addr) (string, error) {
+	// find last range that starts before or at addr
+	i := sort.Search(len(db.ranges), func(i int) bool {
+		return addr.Less(db.ranges[i].from)
+	}) - 1
+	if i >= 0 && db.ranges[i].from.Is4() == addr.Is4() && !db.ranges[i].to.Less(addr) {
+		return db.ranges[i].country, nil
+	}
+	return "", nil
+}
+
+// lastIP returns last address of network prefix.
+func lastIP(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
@@ -0,0 +1,61 @@
+// Package geoip implements IP to country resolution using local GeoIP database file.
+//
+// Two database formats are supported:
+//   - MaxMind DB (mmdb), country is taken from country.iso_code
+//     or registered_country.iso_code fields of data record.
+//   - CSV, each line is either "network,country" where network is CIDR,
+//     or "start_ip,end_ip,country". Lines that cannot be parsed
+//     are treated as headers or comments and skipped.
+//
+// Format is selected by file extension, mmdb is used by default.
+package geoip
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DB is GeoIP database loaded in memory.
+type DB struct {
+	lookup func(addr netip.Addr) (string, error)
+}
+
+// Open loads GeoIP database from file.
+func Open(path string) (*DB, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read geoip database: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		var ranges *rangeDB
+		if ranges, err = parseCSV(data); err != nil {
+			return nil, err
+		}
+		return &DB{lookup: ranges.lookup}, nil
+	}
+	reader, err := newMMDB(data)
+	if err != nil {
+		return nil, err
+	}
+	return &DB{lookup: reader.lookupCountry}, nil
+}
+
+// Country returns ISO 3166-1 alpha-2 country code for IP address.
+// Empty string is returned if country is unknown or address is invalid.
+func (db *DB) Country(ip string) string {
+	if db == nil || ip == "" {
+		return ""
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	country, err := db.lookup(addr.Unmap())
+	if err != nil {
+		return ""
+	}
+	return strings.ToUpper(country)
+}
@@ -0,0 +1,210 @@
+package geoip
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	type want struct {
+		countries map[string]string
+		err       bool
+	}
+	tests := []struct {
+		name  string
+		file  string
+		write func() []byte
+		want  want
+	}{
+		{
+			name: "mmdb ipv4",
+			file: "countries.mmdb",
+			write: func() []byte {
+				return writeMMDB(t, 4, map[string]string{
+					"1.1.1.0/24":   "au",
+					"10.0.0.0/16":  "US",
+					"10.10.0.0/16": "DE",
+				})
+			},
+			want: want{
+				countries: map[string]string{
+					"1.1.1.1":          "AU",
+					"10.0.1.1":         "US",
+					"10.1.1.1":         "",
+					"10.10.1.1":        "DE",
+					"::ffff:10.10.1.1": "DE",
+					"1.1.2.1":          "",
+					"2000::1":          "",
+					"asdasd":           "",
+					"":                 "",
+				},
+			},
+		},
+		{
+			name: "mmdb ipv6",
+			file: "countries.mmdb",
+			write: func() []byte {
+				return writeMMDB(t, 6, map[string]string{
+					"::1.1.1.0/120": "AU",
+					"2000::/16":     "NL",
+				})
+			},
+			want: want{
+				countries: map[string]string{
+					"1.1.1.1":     "AU",
+					"2000::1":     "NL",
+					"2001::1":     "",
+					"2.2.2.2":     "",
+					"2000:abcd::": "NL",
+				},
+			},
+		},
+		{
+			name: "csv",
+			file: "countries.csv",
+			write: func() []byte {
+				return []byte("# comment\n" +
+					"network,country\n" +
+					"1.1.1.0/24,AU\n" +
+					"10.0.0.0,10.0.255.255,us\n" +
+					"2000::/16,NL\n" +
+					"bad,line,here\n")
+			},
+			want: want{
+				countries: map[string]string{
+					"1.1.1.1":   "AU",
+					"10.0.10.1": "US",
+					"10.1.0.0":  "",
+					"2000::1":   "NL",
+					"3.3.3.3":   "",
+				},
+			},
+		},
+		{
+			name: "empty csv",
+			file: "countries.csv",
+			write: func() []byte {
+				return []byte("network,country\n")
+			},
+			want: want{err: true},
+		},
+		{
+			name: "invalid mmdb",
+			file: "countries.mmdb",
+			write: func() []byte {
+				return []byte("qweqweqwe")
+			},
+			want: want{err: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, tt.write(), 0600))
+
+			db, err := Open(path)
+			if tt.want.err {
+				assert.Error(t, err)
+				assert.Nil(t, db)
+				return
+			}
+			require.NoError(t, err)
+			for ip, country := range tt.want.countries {
+				assert.Equal(t, country, db.Country(ip), ip)
+			}
+		})
+	}
+}
+
+func TestOpen_NotExist(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "nonexistent.mmdb"))
+	assert.Error(t, err)
+	assert.Nil(t, db)
+
+	// nil db is safe to use
+	assert.Empty(t, db.Country("1.1.1.1"))
+}
+
+// writeMMDB creates MaxMind DB with 24 bit records
+// that maps networks to country iso codes.
+// Networks must not overlap.
+func writeMMDB(t *testing.T, ipVersion int, networks map[string]string) []byte {
+	t.Helper()
+
+	const empty = -1
+	type node [2]int
+
+	var (
+		nodes   = []node{{empty, empty}}
+		leaves  = map[[2]int]int{}
+		data    []byte
+		offsets = map[string]int{}
+	)
+	for network, country := range networks {
+		prefix := netip.MustParsePrefix(network)
+		ip := prefix.Addr().AsSlice()
+		if _, ok := offsets[country]; !ok {
+			offsets[country] = len(data)
+			data = append(data, encodeMap(1)...)
+			data = append(data, encodeString("country")...)
+			data = append(data, encodeMap(1)...)
+			data = append(data, encodeString("iso_code")...)
+			data = append(data, encodeString(country)...)
+		}
+		n := 0
+		for i := 0; i < prefix.Bits()-1; i++ {
+			bit := int(ip[i/8]>>(7-i%8)) & 1
+			if nodes[n][bit] == empty {
+				nodes = append(nodes, node{empty, empty})
+				nodes[n][bit] = len(nodes) - 1
+			}
+			n = nodes[n][bit]
+		}
+		last := prefix.Bits() - 1
+		leaves[[2]int{n, int(ip[last/8]>>(7-last%8)) & 1}] = offsets[country]
+	}
+
+	nodeCount := len(nodes)
+	tree := make([]byte, 0, nodeCount*6)
+	for n := range nodes {
+		for bit := 0; bit < 2; bit++ {
+			record := nodeCount
+			if offset, ok := leaves[[2]int{n, bit}]; ok {
+				record = nodeCount + mmdbDataSeparatorSize + offset
+			} else if nodes[n][bit] != empty {
+				record = nodes[n][bit]
+			}
+			tree = append(tree, byte(record>>16), byte(record>>8), byte(record))
+		}
+	}
+
+	buf := append(tree, make([]byte, mmdbDataSeparatorSize)...)
+	buf = append(buf, data...)
+	buf = append(buf, mmdbMetadataMarker...)
+	buf = append(buf, encodeMap(3)...)
+	buf = append(buf, encodeString("node_count")...)
+	buf = append(buf, encodeUint32(uint32(nodeCount))...)
+	buf = append(buf, encodeString("record_size")...)
+	buf = append(buf, encodeUint32(24)...)
+	buf = append(buf, encodeString("ip_version")...)
+	buf = append(buf, encodeUint32(uint32(ipVersion))...)
+	return buf
+}
+
+func encodeMap(size int) []byte {
+	return []byte{byte(mmdbMap<<5 | size)}
+}
+
+func encodeString(s string) []byte {
+	return append([]byte{byte(mmdbString<<5 | len(s))}, s...)
+}
+
+func encodeUint32(v uint32) []byte {
+	return binary.BigEndian.AppendUint32([]byte{byte(mmdbUint32<<5 | 4)}, v)
+}
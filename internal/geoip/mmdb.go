@@ -0,0 +1,342 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+)
+
+// MaxMind DB format specification:
+// https://maxmind.github.io/MaxMind-DB/
+
+const (
+	mmdbDataSeparatorSize = 16
+	mmdbMaxMetadataSize   = 128 * 1024
+	mmdbMaxDepth          = 32
+	mmdbIPv4StartBits     = 96
+)
+
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// mmdb data field types.
+const (
+	mmdbExtended = iota
+	mmdbPointer
+	mmdbString
+	mmdbDouble
+	mmdbBytes
+	mmdbUint16
+	mmdbUint32
+	mmdbMap
+	mmdbInt32
+	mmdbUint64
+	mmdbUint128
+	mmdbArray
+	mmdbContainer
+	mmdbEndMarker
+	mmdbBool
+	mmdbFloat
+)
+
+var errMMDBInvalid = errors.New("invalid mmdb database")
+
+type mmdb struct {
+	tree           []byte
+	data           []byte
+	nodeCount      uint
+	recordSize     uint
+	ipVersion      uint
+	ipv4StartNode  uint
+	ipv4StartDepth int
+}
+
+func newMMDB(buf []byte) (*mmdb, error) {
+	start := 0
+	if len(buf) > mmdbMaxMetadataSize {
+		start = len(buf) - mmdbMaxMetadataSize
+	}
+	pos := bytes.LastIndex(buf[start:], mmdbMetadataMarker)
+	if pos < 0 {
+		return nil, fmt.Errorf("%w: metadata marker is not found", errMMDBInvalid)
+	}
+	metaStart := start + pos + len(mmdbMetadataMarker)
+	meta, _, err := (&decoder{buf: buf[metaStart:]}).decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot decode metadata: %w", errMMDBInvalid, err)
+	}
+	metaMap, ok := meta.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", errMMDBInvalid)
+	}
+	db := &mmdb{
+		nodeCount:  metaUint(metaMap, "node_count"),
+		recordSize: metaUint(metaMap, "record_size"),
+		ipVersion:  metaUint(metaMap, "ip_version"),
+	}
+	switch db.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported record size %d", errMMDBInvalid, db.recordSize)
+	}
+	if db.ipVersion != 4 && db.ipVersion != 6 {
+		return nil, fmt.Errorf("%w: unsupported ip version %d", errMMDBInvalid, db.ipVersion)
+	}
+	treeSize := db.nodeCount * db.recordSize / 4
+	dataStart := treeSize + mmdbDataSeparatorSize
+	if dataStart > uint(start+pos) {
+		return nil, fmt.Errorf("%w: search tree is too large", errMMDBInvalid)
+	}
+	db.tree = buf[:treeSize]
+	db.data = buf[dataStart : start+pos]
+	if db.ipVersion == 6 {
+		// IPv4 addresses are looked up in ::/96 subtree
+		for db.ipv4StartDepth < mmdbIPv4StartBits && db.ipv4StartNode < db.nodeCount {
+			if db.ipv4StartNode, err = db.readRecord(db.ipv4StartNode, 0); err != nil {
+				return nil, err
+			}
+			db.ipv4StartDepth++
+		}
+	}
+	return db, nil
+}
+
+func metaUint(meta map[string]any, key string) uint {
+	if v, ok := meta[key].(uint64); ok {
+		return uint(v)
+	}
+	return 0
+}
+
+// lookupCountry returns country iso code for address.
+func (db *mmdb) lookupCountry(addr netip.Addr) (string, error) {
+	record, err := db.lookup(addr)
+	if err != nil || record == nil {
+		return "", err
+	}
+	for _, key := range []string{"country", "registered_country"} {
+		if country, ok := record[key].(map[string]any); ok {
+			if code, okC := country["iso_code"].(string); okC {
+				return code, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+func (db *mmdb) lookup(addr netip.Addr) (map[string]any, error) {
+	if addr.Is6() && db.ipVersion == 4 {
+		return nil, nil
+	}
+	var (
+		ip    = addr.AsSlice()
+		node  uint
+		depth int
+		err   error
+	)
+	if addr.Is4() && db.ipVersion == 6 {
+		node, depth = db.ipv4StartNode, db.ipv4StartDepth
+		ip = append(make([]byte, mmdbIPv4StartBits/8), ip...)
+	}
+	for ; depth < len(ip)*8 && node < db.nodeCount; depth++ {
+		bit := (ip[depth/8] >> (7 - depth%8)) & 1
+		if node, err = db.readRecord(node, uint(bit)); err != nil {
+			return nil, err
+		}
+	}
+	if node <= db.nodeCount {
+		// not found
+		return nil, nil
+	}
+	offset := node - db.nodeCount - mmdbDataSeparatorSize
+	value, _, err := (&decoder{buf: db.data}).decode(offset, 0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode data record: %w", err)
+	}
+	record, _ := value.(map[string]any)
+	return record, nil
+}
+
+func (db *mmdb) readRecord(node, bit uint) (uint, error) {
+	nodeSize := db.recordSize / 4
+	off := node * nodeSize
+	if off+nodeSize > uint(len(db.tree)) {
+		return 0, fmt.Errorf("%w: node is out of tree", errMMDBInvalid)
+	}
+	b := db.tree[off : off+nodeSize]
+	switch db.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6]), nil
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:])), nil
+	}
+}
+
+// decoder decodes mmdb data section values.
+type decoder struct {
+	buf []byte
+}
+
+// decode decodes value at offset and returns it with offset of next value.
+func (d *decoder) decode(offset uint, depth int) (any, uint, error) {
+	if depth > mmdbMaxDepth {
+		return nil, 0, errors.New("maximum data depth exceeded")
+	}
+	typ, size, offset, err := d.decodeControl(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if typ == mmdbPointer {
+		var pointer uint
+		if pointer, offset, err = d.decodePointer(size, offset); err != nil {
+			return nil, 0, err
+		}
+		value, _, errP := d.decode(pointer, depth+1)
+		return value, offset, errP
+	}
+	switch typ {
+	case mmdbMap:
+		return d.decodeMap(size, offset, depth)
+	case mmdbArray:
+		return d.decodeArray(size, offset, depth)
+	case mmdbBool:
+		return size != 0, offset, nil
+	}
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, errors.New("value is out of data section")
+	}
+	b := d.buf[offset : offset+size]
+	offset += size
+	switch typ {
+	case mmdbString:
+		return string(b), offset, nil
+	case mmdbBytes, mmdbUint128:
+		return append([]byte(nil), b...), offset, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, errors.New("invalid double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, errors.New("invalid float size")
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(b)), offset, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64:
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, offset, nil
+	case mmdbInt32:
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return int32(v), offset, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported data type %d", typ)
+}
+
+func (d *decoder) decodeControl(offset uint) (typ, size, next uint, err error) {
+	if offset >= uint(len(d.buf)) {
+		return 0, 0, 0, errors.New("control byte is out of data section")
+	}
+	ctrl := d.buf[offset]
+	offset++
+	typ = uint(ctrl >> 5)
+	if typ == mmdbPointer {
+		return typ, uint(ctrl & 0x1F), offset, nil
+	}
+	if typ == mmdbExtended {
+		if offset >= uint(len(d.buf)) {
+			return 0, 0, 0, errors.New("extended type is out of data section")
+		}
+		typ = uint(d.buf[offset]) + 7
+		offset++
+	}
+	size = uint(ctrl & 0x1F)
+	if size < 29 {
+		return typ, size, offset, nil
+	}
+	extra := size - 28
+	if offset+extra > uint(len(d.buf)) {
+		return 0, 0, 0, errors.New("size is out of data section")
+	}
+	var v uint
+	for _, c := range d.buf[offset : offset+extra] {
+		v = v<<8 | uint(c)
+	}
+	switch extra {
+	case 1:
+		size = 29 + v
+	case 2:
+		size = 285 + v
+	default:
+		size = 65821 + v
+	}
+	return typ, size, offset + extra, nil
+}
+
+func (d *decoder) decodePointer(ctrl, offset uint) (pointer, next uint, err error) {
+	pointerSize := ((ctrl >> 3) & 0x3) + 1
+	if offset+pointerSize > uint(len(d.buf)) {
+		return 0, 0, errors.New("pointer is out of data section")
+	}
+	b := d.buf[offset : offset+pointerSize]
+	var v uint
+	if pointerSize != 4 {
+		v = ctrl & 0x7
+	}
+	for _, c := range b {
+		v = v<<8 | uint(c)
+	}
+	switch pointerSize {
+	case 2:
+		v += 2048
+	case 3:
+		v += 526336
+	}
+	return v, offset + pointerSize, nil
+}
+
+func (d *decoder) decodeMap(size, offset uint, depth int) (any, uint, error) {
+	m := make(map[string]any, size)
+	for i := uint(0); i < size; i++ {
+		key, next, err := d.decode(offset, depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		keyStr, ok := key.(string)
+		if !ok {
+			return nil, 0, errors.New("map key is not a string")
+		}
+		var value any
+		if value, offset, err = d.decode(next, depth+1); err != nil {
+			return nil, 0, err
+		}
+		m[keyStr] = value
+	}
+	return m, offset, nil
+}
+
+func (d *decoder) decodeArray(size, offset uint, depth int) (any, uint, error) {
+	arr := make([]any, 0, size)
+	for i := uint(0); i < size; i++ {
+		value, next, err := d.decode(offset, depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		arr = append(arr, value)
+		offset = next
+	}
+	return arr, offset, nil
+}
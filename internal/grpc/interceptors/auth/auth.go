@@ -1,18 +1,28 @@
 // Package auth implements authentication interceptor.
 //
 // It uses imported authenticator to handle jwt metadata parameters.
+// If api key authenticator is set, api keys passed in authorization metadata
+// with Bearer scheme are accepted instead of session tokens.
 // User object is propagated via request context.
 //
 // Interceptor guarantees that user object will always exist in context,
-// either new or parsed from cookie.
+// either new or parsed from cookie. Session token is sent back in header
+// when it's created or re-issued because it expires soon.
+//
+// In strict mode new users are not created for mutating methods. Call of such method
+// without valid credentials is rejected with Unauthenticated code and reason.
 //
 //nolint:wrapcheck // return grpc errors
 package auth
 
 import (
 	"context"
+	"errors"
+	"slices"
 
 	authorizer "github.com/adwski/shorty/internal/auth"
+	"github.com/adwski/shorty/internal/grpc/interceptors/stream"
+	"github.com/adwski/shorty/internal/services/apikey"
 	"github.com/adwski/shorty/internal/session"
 	"github.com/adwski/shorty/internal/user"
 	"go.uber.org/zap"
@@ -23,13 +33,22 @@ import (
 )
 
 const (
-	sessionKey = "shorty-sess-id"
+	sessionKey       = "shorty-sess-id"
+	authorizationKey = "authorization"
 )
 
+// KeyAuthenticator authenticates api keys.
+type KeyAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (*user.User, error)
+}
+
 // Interceptor is an auth interceptor.
 type Interceptor struct {
 	*authorizer.Auth
-	logger *zap.Logger
+	keys     KeyAuthenticator
+	mutating []string
+	strict   bool
+	logger   *zap.Logger
 }
 
 // NewFromAuthorizer creates auth interceptor using exiting authorizer.
@@ -40,6 +59,19 @@ func NewFromAuthorizer(logger *zap.Logger, a *authorizer.Auth) *Interceptor {
 	}
 }
 
+// WithKeys enables api key authentication.
+func (i *Interceptor) WithKeys(keys KeyAuthenticator) *Interceptor {
+	i.keys = keys
+	return i
+}
+
+// WithStrict enables strict mode for mutating methods. Calls of other methods are served as in normal mode.
+func (i *Interceptor) WithStrict(mutating []string) *Interceptor {
+	i.strict = true
+	i.mutating = mutating
+	return i
+}
+
 // Get returns UnaryServerInterceptor func.
 func (i *Interceptor) Get() grpc.UnaryServerInterceptor {
 	return func(
@@ -53,10 +85,15 @@ func (i *Interceptor) Get() grpc.UnaryServerInterceptor {
 			i.logger.Error("cannot get request metadata")
 			return nil, gstatus.Error(codes.Internal, "cannot get metadata")
 		}
-		u, token, err := i.createOrParseUserFromMetadata(md)
+		if u, ok, err := i.authenticateKey(ctx, md); ok {
+			if err != nil {
+				return nil, err
+			}
+			return handler(session.SetUserContext(ctx, u), req)
+		}
+		u, token, err := i.userFromMetadata(ctx, md, info.FullMethod)
 		if err != nil {
-			i.logger.Error("cannot create user session")
-			return nil, gstatus.Error(codes.Internal, "cannot create user session")
+			return nil, err
 		}
 		if token != "" {
 			if err = grpc.SendHeader(ctx, metadata.Pairs(
@@ -70,10 +107,112 @@ func (i *Interceptor) Get() grpc.UnaryServerInterceptor {
 	}
 }
 
-func (i *Interceptor) createOrParseUserFromMetadata(md metadata.MD) (*user.User, string, error) {
+// GetStream returns StreamServerInterceptor func.
+func (i *Interceptor) GetStream() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx := ss.Context()
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			i.logger.Error("cannot get request metadata")
+			return gstatus.Error(codes.Internal, "cannot get metadata")
+		}
+		if u, ok, err := i.authenticateKey(ctx, md); ok {
+			if err != nil {
+				return err
+			}
+			return handler(srv, stream.WithContext(session.SetUserContext(ctx, u), ss))
+		}
+		u, token, err := i.userFromMetadata(ctx, md, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		if token != "" {
+			if err = ss.SendHeader(metadata.Pairs(
+				sessionKey, token,
+			)); err != nil {
+				i.logger.Error("cannot send header")
+				return gstatus.Error(codes.Internal, "cannot send header")
+			}
+		}
+		return handler(srv, stream.WithContext(session.SetUserContext(ctx, u), ss))
+	}
+}
+
+// authenticateKey authenticates api key from metadata. It returns false if key is not provided.
+func (i *Interceptor) authenticateKey(ctx context.Context, md metadata.MD) (*user.User, bool, error) {
+	if i.keys == nil {
+		return nil, false, nil
+	}
+	val := md.Get(authorizationKey)
+	if len(val) == 0 {
+		return nil, false, nil
+	}
+	token, ok := authorizer.BearerToken(val[0])
+	if !ok {
+		return nil, false, nil
+	}
+	u, err := i.keys.Authenticate(ctx, token)
+	if err != nil {
+		if errors.Is(err, apikey.ErrInvalidKey) {
+			return nil, true, gstatus.Error(codes.Unauthenticated, "invalid api key")
+		}
+		i.logger.Error("cannot authenticate api key", zap.Error(err))
+		return nil, true, gstatus.Error(codes.Internal, "cannot authenticate api key")
+	}
+	return u, true, nil
+}
+
+// userFromMetadata returns session user and token that should be sent to client,
+// token is empty if session token is not created or re-issued.
+// In strict mode user is not created for mutating methods.
+func (i *Interceptor) userFromMetadata(ctx context.Context, md metadata.MD, method string) (*user.User, string, error) {
+	if i.strict && slices.Contains(i.mutating, method) {
+		u, err := i.parseUserFromMetadata(ctx, md)
+		if err != nil {
+			return nil, "", err
+		}
+		token, err := i.RefreshToken(u)
+		if err != nil {
+			i.logger.Error("cannot refresh user session", zap.Error(err))
+			return nil, "", gstatus.Error(codes.Internal, "cannot refresh user session")
+		}
+		return u, token, nil
+	}
+	u, token, err := i.createOrParseUserFromMetadata(ctx, md)
+	if err != nil {
+		i.logger.Error("cannot create user session", zap.Error(err))
+		return nil, "", gstatus.Error(codes.Internal, "cannot create user session")
+	}
+	return u, token, nil
+}
+
+// parseUserFromMetadata parses user from session token.
+// If user cannot be parsed, Unauthenticated error with reason is returned.
+func (i *Interceptor) parseUserFromMetadata(ctx context.Context, md metadata.MD) (*user.User, error) {
+	val := md.Get(sessionKey)
+	if len(val) == 0 {
+		return nil, gstatus.Error(codes.Unauthenticated, "session token is missing")
+	}
+	u, err := i.ParseUserFromJWTString(ctx, val[0])
+	if err != nil {
+		if errors.Is(err, authorizer.ErrDenylist) {
+			i.logger.Error("cannot check user session", zap.Error(err))
+			return nil, gstatus.Error(codes.Internal, "cannot check user session")
+		}
+		return nil, gstatus.Error(codes.Unauthenticated, err.Error())
+	}
+	return u, nil
+}
+
+func (i *Interceptor) createOrParseUserFromMetadata(ctx context.Context, md metadata.MD) (*user.User, string, error) {
 	val := md.Get(sessionKey)
 	if len(val) == 0 {
 		return i.CreateUserAndToken()
 	}
-	return i.CreateOrParseUserFromJWTString(val[0])
+	return i.CreateOrParseUserFromJWTString(ctx, val[0])
 }
@@ -64,3 +64,43 @@ func (i *Interceptor) Get() grpc.UnaryServerInterceptor {
 		return resp, err
 	}
 }
+
+// GetStream returns StreamServerInterceptor that can be used for chaining.
+func (i *Interceptor) GetStream() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		var (
+			start    = time.Now()
+			reqID, _ = session.GetRequestID(ss.Context())
+		)
+
+		i.logger.Info("stream",
+			zap.String("id", reqID),
+			zap.String("method", info.FullMethod))
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			i.logger.Error("panic in stream handler chain",
+				zap.Any("panic", rec),
+				zap.String("stack", string(debug.Stack())),
+				zap.String("id", reqID),
+				zap.Duration("duration", time.Since(start)))
+		}()
+
+		err := handler(srv, ss)
+
+		i.logger.With(zap.Error(err),
+			zap.String("id", reqID),
+			zap.Duration("duration", time.Since(start))).Info("stream closed")
+
+		return err
+	}
+}
@@ -0,0 +1,75 @@
+// Package ratelimit contains rate limiting interceptor.
+// When included in chain after auth interceptor, it limits calls
+// of authenticated users by user id and calls of other clients
+// by ip address. Client ip is determined with the same metadata trust rules
+// as in ip filter. Methods are matched by full method name.
+//
+// If call is limited, ResourceExhausted code is sent back
+// and retry-after header is set to number of seconds to wait.
+//
+//nolint:wrapcheck // return grpc errors
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/adwski/shorty/internal/filter"
+	"github.com/adwski/shorty/internal/ratelimit"
+	"github.com/adwski/shorty/internal/session"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// Interceptor is rate limiting interceptor.
+type Interceptor struct {
+	limiter *ratelimit.Limiter
+	filter  *filter.Filter
+}
+
+// New creates rate limiting interceptor. If limiter is nil, calls are not limited.
+func New(limiter *ratelimit.Limiter, f *filter.Filter) *Interceptor {
+	return &Interceptor{
+		limiter: limiter,
+		filter:  f,
+	}
+}
+
+// Get returns UnaryServerInterceptor func that can be used for chaining.
+func (i *Interceptor) Get() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if i.limiter == nil {
+			return handler(ctx, req)
+		}
+		u, _ := session.GetUserFromContext(ctx)
+		client := ratelimit.ClientKey(u, i.clientIP(ctx))
+		if wait := i.limiter.Allow(ctx, info.FullMethod, client); wait > 0 {
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", ratelimit.RetryAfter(wait)))
+			return nil, gstatus.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (i *Interceptor) clientIP(ctx context.Context) string {
+	var remoteAddr, xff, xRealIP string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if xffS := md.Get("x-forwarded-for"); len(xffS) > 0 {
+			xff = xffS[0]
+		}
+		if xRealIPS := md.Get("x-real-ip"); len(xRealIPS) > 0 {
+			xRealIP = xRealIPS[0]
+		}
+	}
+	return i.filter.ClientIP(remoteAddr, xRealIP, xff)
+}
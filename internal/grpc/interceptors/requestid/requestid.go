@@ -9,6 +9,7 @@ package requestid
 import (
 	"context"
 
+	"github.com/adwski/shorty/internal/grpc/interceptors/stream"
 	"github.com/adwski/shorty/internal/session"
 	"github.com/gofrs/uuid/v5"
 	"go.uber.org/zap"
@@ -42,26 +43,44 @@ func (i *Interceptor) Get() grpc.UnaryServerInterceptor {
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
-		var newCtx context.Context
-		if i.trust {
-			reqID, ok := getRequestIDFromContext(ctx)
-			if ok {
-				newCtx = session.SetRequestID(ctx, reqID)
-			}
-			i.logger.Debug("incoming request without id but trust is enabled")
+		newCtx, err := i.requestContext(ctx)
+		if err != nil {
+			return nil, err
 		}
+		return handler(newCtx, req)
+	}
+}
 
-		if newCtx == nil {
-			uuidV4, err := i.gen.NewV4()
-			if err != nil {
-				i.logger.Error("cannot generate request id", zap.Error(err))
-				return nil, gstatus.Error(codes.Internal, "cannot generate request id")
-			}
-			newCtx = session.SetRequestID(ctx, uuidV4.String())
+// GetStream returns StreamServerInterceptor func that can be used for chaining.
+func (i *Interceptor) GetStream() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		newCtx, err := i.requestContext(ss.Context())
+		if err != nil {
+			return err
 		}
+		return handler(srv, stream.WithContext(newCtx, ss))
+	}
+}
 
-		return handler(newCtx, req)
+func (i *Interceptor) requestContext(ctx context.Context) (context.Context, error) {
+	if i.trust {
+		reqID, ok := getRequestIDFromContext(ctx)
+		if ok {
+			return session.SetRequestID(ctx, reqID), nil
+		}
+		i.logger.Debug("incoming request without id but trust is enabled")
+	}
+	uuidV4, err := i.gen.NewV4()
+	if err != nil {
+		i.logger.Error("cannot generate request id", zap.Error(err))
+		return nil, gstatus.Error(codes.Internal, "cannot generate request id")
 	}
+	return session.SetRequestID(ctx, uuidV4.String()), nil
 }
 
 func getRequestIDFromContext(ctx context.Context) (string, bool) {
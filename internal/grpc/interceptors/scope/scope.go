@@ -0,0 +1,72 @@
+// Package scope contains scope checking interceptor.
+// When included in chain after auth interceptor, it checks that user
+// authenticated with api key has scope required by called method.
+// Users authenticated with session token are passed unchecked.
+//
+// Interceptor has map of methods to required scopes. Empty scope means
+// that method is available to any api key. Methods that are not in the map
+// are not available to api keys, PermissionDenied code is sent back.
+//
+//nolint:wrapcheck // return grpc errors
+package scope
+
+import (
+	"context"
+
+	"github.com/adwski/shorty/internal/session"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// Interceptor is scope checking interceptor.
+type Interceptor struct {
+	methods map[string]string
+}
+
+// New creates scope checking interceptor with method->scope map.
+func New(methods map[string]string) *Interceptor {
+	return &Interceptor{methods: methods}
+}
+
+// Get returns UnaryServerInterceptor func that can be used for chaining.
+func (i *Interceptor) Get() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if err := i.check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// GetStream returns StreamServerInterceptor func that can be used for chaining.
+func (i *Interceptor) GetStream() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := i.check(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (i *Interceptor) check(ctx context.Context, method string) error {
+	u, ok := session.GetUserFromContext(ctx)
+	if !ok || !u.IsAPIKey() {
+		return nil
+	}
+	scope, ok := i.methods[method]
+	if !ok || (scope != "" && !u.HasScope(scope)) {
+		return gstatus.Error(codes.PermissionDenied, "api key has no access to method")
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+// Package stream contains helpers for stream interceptors.
+package stream
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns context of wrapped stream.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// WithContext wraps server stream so handlers get provided context
+// instead of original stream context.
+func WithContext(ctx context.Context, ss grpc.ServerStream) grpc.ServerStream {
+	return &serverStream{ServerStream: ss, ctx: ctx}
+}
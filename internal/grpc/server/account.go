@@ -0,0 +1,124 @@
+//nolint:wrapcheck // using gstatus.Error() to return grpc errors
+package server
+
+import (
+	"context"
+	"errors"
+
+	g "github.com/adwski/shorty/internal/grpc"
+	"github.com/adwski/shorty/internal/services/account"
+	"github.com/adwski/shorty/internal/session"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// Register creates user account. Links of current anonymous user are claimed into account.
+// Account session token is returned in response.
+func (srv *Server) Register(ctx context.Context, r *g.RegisterRequest) (*g.AuthResponse, error) {
+	return srv.handleAccount(ctx, r.Login, func(ctx context.Context) (*account.Session, error) {
+		u, _ := session.GetUserFromContext(ctx)
+		return srv.accountSvc.Register(ctx, u, r.Login, r.Password)
+	})
+}
+
+// Login authenticates user account with password. Account session token is returned in response.
+// Links of current anonymous user are claimed into account if requested.
+func (srv *Server) Login(ctx context.Context, r *g.LoginRequest) (*g.AuthResponse, error) {
+	return srv.handleAccount(ctx, r.Login, func(ctx context.Context) (*account.Session, error) {
+		u, _ := session.GetUserFromContext(ctx)
+		return srv.accountSvc.Login(ctx, u, r.Login, r.Password, r.Claim)
+	})
+}
+
+// Anonymous creates anonymous user and returns its session token.
+// It's the only way to get anonymous session when strict authentication is enabled.
+func (srv *Server) Anonymous(ctx context.Context, _ *g.AnonymousRequest) (*g.AuthResponse, error) {
+	if srv.accountSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "accounts are not enabled")
+	}
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+	sess, err := srv.accountSvc.Anonymous(u)
+	if err != nil {
+		srv.logger.Error("cannot create anonymous user", zap.String("id", reqID), zap.Error(err))
+		return nil, gstatus.Error(codes.Internal, "internal error occurred")
+	}
+	return &g.AuthResponse{
+		UserId: sess.User.ID,
+		Token:  sess.Token,
+	}, nil
+}
+
+// Logout revokes current session, its tokens are rejected after logout.
+func (srv *Server) Logout(ctx context.Context, _ *g.LogoutRequest) (*g.LogoutResponse, error) {
+	if srv.accountSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "accounts are not enabled")
+	}
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+	err = srv.accountSvc.Logout(ctx, u)
+	srv.logger.With(
+		zap.String("id", reqID),
+		zap.String("userID", u.ID),
+		zap.Error(err),
+	).Debug("logout called")
+	if err != nil && !errors.Is(err, account.ErrNoSession) {
+		srv.logger.Error("cannot revoke session", zap.String("id", reqID), zap.Error(err))
+		return nil, gstatus.Error(codes.Internal, "internal error occurred")
+	}
+	return &g.LogoutResponse{}, nil
+}
+
+func (srv *Server) handleAccount(
+	ctx context.Context,
+	login string,
+	fn func(ctx context.Context) (*account.Session, error),
+) (*g.AuthResponse, error) {
+	if srv.accountSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "accounts are not enabled")
+	}
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	sess, err := fn(ctx)
+	srv.logger.With(
+		zap.String("id", reqID),
+		zap.String("userID", u.ID),
+		zap.String("login", login),
+		zap.Error(err),
+	).Debug("account request handled")
+	if err != nil {
+		if qErr := quotaExceededError(err); qErr != nil {
+			return nil, qErr
+		}
+		switch {
+		case errors.Is(err, account.ErrInvalidLogin):
+			return nil, gstatus.Error(codes.InvalidArgument, "invalid login")
+		case errors.Is(err, account.ErrInvalidPassword):
+			return nil, gstatus.Error(codes.InvalidArgument, "invalid password")
+		case errors.Is(err, account.ErrLoginTaken):
+			return nil, gstatus.Error(codes.AlreadyExists, "login is already taken")
+		case errors.Is(err, account.ErrInvalidCredentials):
+			return nil, gstatus.Error(codes.Unauthenticated, "invalid login or password")
+		default:
+			srv.logger.Error("account request failed", zap.String("id", reqID), zap.Error(err))
+			return nil, gstatus.Error(codes.Internal, "internal error occurred")
+		}
+	}
+	return &g.AuthResponse{
+		UserId:  sess.User.ID,
+		Login:   sess.User.Login,
+		Token:   sess.Token,
+		Claimed: sess.Claimed,
+	}, nil
+}
@@ -4,6 +4,8 @@ package server
 import (
 	"context"
 	"errors"
+	"net"
+	"time"
 
 	g "github.com/adwski/shorty/internal/grpc"
 	"github.com/adwski/shorty/internal/model"
@@ -12,6 +14,8 @@ import (
 	"github.com/adwski/shorty/internal/session"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	gstatus "google.golang.org/grpc/status"
 )
 
@@ -40,10 +44,18 @@ func (srv *Server) Resolve(ctx context.Context, r *g.ResolveRequest) (*g.Resolve
 		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
 	}
 
-	result, err := srv.resolverSvc.Resolve(ctx, r.Path)
+	result, err := srv.resolverSvc.Resolve(ctx, &resolver.Request{
+		Path:      r.Path,
+		Query:     r.Query,
+		UserAgent: r.UserAgent,
+		VisitorID: r.VisitorId,
+		ClientIP:  srv.clientIP(ctx, r.ClientIp),
+		Password:  r.Password,
+		Preview:   r.Preview,
+	})
 	srv.logger.With(
 		zap.String("path", r.Path),
-		zap.String("orig", result),
+		zap.Any("result", result),
 		zap.String("id", reqID),
 		zap.Error(err),
 	).Debug("resolve called")
@@ -52,6 +64,14 @@ func (srv *Server) Resolve(ctx context.Context, r *g.ResolveRequest) (*g.Resolve
 		switch {
 		case errors.Is(err, resolver.ErrInvalidPath):
 			return nil, gstatus.Errorf(codes.InvalidArgument, "invalid path")
+		case errors.Is(err, resolver.ErrInvalidQuery):
+			return nil, gstatus.Errorf(codes.InvalidArgument, "invalid query")
+		case errors.Is(err, resolver.ErrPasswordRequired):
+			return nil, gstatus.Errorf(codes.Unauthenticated, "password required")
+		case errors.Is(err, resolver.ErrWrongPassword):
+			return nil, gstatus.Errorf(codes.PermissionDenied, "wrong password")
+		case errors.Is(err, resolver.ErrTooManyAttempts):
+			return nil, gstatus.Errorf(codes.ResourceExhausted, "too many password attempts")
 		case errors.Is(err, model.ErrNotFound):
 			return nil, gstatus.Errorf(codes.NotFound, "path is not found")
 		case errors.Is(err, model.ErrDeleted):
@@ -60,10 +80,19 @@ func (srv *Server) Resolve(ctx context.Context, r *g.ResolveRequest) (*g.Resolve
 			return nil, gstatus.Error(codes.Internal, "internal error occurred")
 		}
 	}
-	return &g.ResolveResponse{OriginalUrl: result}, nil
+	return &g.ResolveResponse{
+		OriginalUrl:  result.URL,
+		RedirectCode: int32(result.Code),
+		VisitorId:    result.VisitorID,
+		Preview:      result.Preview,
+		Title:        result.Title,
+		CreatedAt:    createdToProto(result.Created),
+		CacheMaxAge:  int64(result.MaxAge.Seconds()),
+	}, nil
 }
 
 // Shorten generates short URL for provided original URL and stores it.
+// If workspace is set, URL is created in workspace.
 // Short URL is returned back.
 func (srv *Server) Shorten(ctx context.Context, r *g.ShortenRequest) (*g.ShortenResponse, error) {
 	u, reqID, err := session.GetUserAndReqID(ctx)
@@ -72,7 +101,20 @@ func (srv *Server) Shorten(ctx context.Context, r *g.ShortenRequest) (*g.Shorten
 		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
 	}
 
-	result, err := srv.shortenerSvc.Shorten(ctx, u, r.OriginalUrl)
+	result, err := srv.shortenerSvc.Shorten(ctx, u, &model.URL{
+		Orig:        r.OriginalUrl,
+		Redirect:    int(r.RedirectCode),
+		Passthrough: r.Passthrough,
+		Targets:     targetsFromProto(r.Targets),
+		Variants:    variantsFromProto(r.Variants),
+		Schedule:    scheduleFromProto(r.Schedule),
+		Password:    r.Password,
+		Title:       r.Title,
+		Tags:        r.Tags,
+		Notes:       r.Notes,
+		Preview:     r.Preview,
+		WorkspaceID: r.Workspace,
+	})
 	srv.logger.With(
 		zap.String("result", result),
 		zap.String("orig", r.OriginalUrl),
@@ -82,9 +124,24 @@ func (srv *Server) Shorten(ctx context.Context, r *g.ShortenRequest) (*g.Shorten
 	).Debug("shorten called")
 
 	if err != nil {
+		if qErr := quotaExceededError(err); qErr != nil {
+			return nil, qErr
+		}
 		switch {
+		case errors.Is(err, shortener.ErrUnauthorized),
+			errors.Is(err, shortener.ErrForbidden),
+			errors.Is(err, shortener.ErrWorkspaceNotFound):
+			return nil, workspaceError(err)
 		case errors.Is(shortener.ErrInvalidURL, err),
-			errors.Is(shortener.ErrUnsupportedURLScheme, err):
+			errors.Is(shortener.ErrUnsupportedURLScheme, err),
+			errors.Is(shortener.ErrInvalidRedirect, err),
+			errors.Is(shortener.ErrInvalidTarget, err),
+			errors.Is(shortener.ErrInvalidVariant, err),
+			errors.Is(shortener.ErrInvalidSchedule, err),
+			errors.Is(shortener.ErrInvalidPassword, err),
+			errors.Is(shortener.ErrInvalidTitle, err),
+			errors.Is(shortener.ErrInvalidTags, err),
+			errors.Is(shortener.ErrInvalidNotes, err):
 			return nil, gstatus.Error(codes.InvalidArgument, err.Error())
 
 		case errors.Is(model.ErrConflict, err):
@@ -98,6 +155,7 @@ func (srv *Server) Shorten(ctx context.Context, r *g.ShortenRequest) (*g.Shorten
 
 // ShortenBatch shortens batch of original URLs. It returns batch of short URLs
 // that can be matched with originals using correlation ID.
+// If workspace is set, URLs are created in workspace.
 func (srv *Server) ShortenBatch(ctx context.Context, r *g.ShortenBatchRequest) (*g.ShortenBatchResponse, error) {
 	u, reqID, err := session.GetUserAndReqID(ctx)
 	if err != nil {
@@ -108,12 +166,22 @@ func (srv *Server) ShortenBatch(ctx context.Context, r *g.ShortenBatchRequest) (
 	batchURLs := make([]shortener.BatchURL, 0, len(r.BatchUrl))
 	for i := range r.BatchUrl {
 		batchURLs = append(batchURLs, shortener.BatchURL{
-			ID:  r.BatchUrl[i].CorrelationId,
-			URL: r.BatchUrl[i].OriginalUrl,
+			ID:          r.BatchUrl[i].CorrelationId,
+			URL:         r.BatchUrl[i].OriginalUrl,
+			Redirect:    int(r.BatchUrl[i].RedirectCode),
+			Passthrough: r.BatchUrl[i].Passthrough,
+			Targets:     targetsFromProto(r.BatchUrl[i].Targets),
+			Variants:    variantsFromProto(r.BatchUrl[i].Variants),
+			Schedule:    scheduleFromProto(r.BatchUrl[i].Schedule),
+			Password:    r.BatchUrl[i].Password,
+			Title:       r.BatchUrl[i].Title,
+			Tags:        r.BatchUrl[i].Tags,
+			Notes:       r.BatchUrl[i].Notes,
+			Preview:     r.BatchUrl[i].Preview,
 		})
 	}
 
-	shortURLs, err := srv.shortenerSvc.ShortenBatch(ctx, u, batchURLs)
+	shortURLs, err := srv.shortenerSvc.ShortenBatch(ctx, u, r.Workspace, batchURLs)
 	srv.logger.With(
 		zap.Int("shortURLs", len(shortURLs)),
 		zap.String("id", reqID),
@@ -122,6 +190,26 @@ func (srv *Server) ShortenBatch(ctx context.Context, r *g.ShortenBatchRequest) (
 		zap.Error(err),
 	).Debug("ShortenBatch called")
 	if err != nil {
+		if qErr := quotaExceededError(err); qErr != nil {
+			return nil, qErr
+		}
+		if errors.Is(err, shortener.ErrUnauthorized) ||
+			errors.Is(err, shortener.ErrForbidden) ||
+			errors.Is(err, shortener.ErrWorkspaceNotFound) {
+			return nil, workspaceError(err)
+		}
+		if errors.Is(err, shortener.ErrInvalidURL) ||
+			errors.Is(err, shortener.ErrUnsupportedURLScheme) ||
+			errors.Is(err, shortener.ErrInvalidRedirect) ||
+			errors.Is(err, shortener.ErrInvalidTarget) ||
+			errors.Is(err, shortener.ErrInvalidVariant) ||
+			errors.Is(err, shortener.ErrInvalidSchedule) ||
+			errors.Is(err, shortener.ErrInvalidPassword) ||
+			errors.Is(err, shortener.ErrInvalidTitle) ||
+			errors.Is(err, shortener.ErrInvalidTags) ||
+			errors.Is(err, shortener.ErrInvalidNotes) {
+			return nil, gstatus.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, gstatus.Error(codes.Internal, "internal error")
 	}
 
@@ -137,6 +225,7 @@ func (srv *Server) ShortenBatch(ctx context.Context, r *g.ShortenBatchRequest) (
 }
 
 // DeleteBatch schedules list of URLs for deletion. Actual deletion is done asynchronously.
+// If workspace is set, URLs are deleted from workspace.
 func (srv *Server) DeleteBatch(ctx context.Context, r *g.DeleteBatchRequest) (*g.DeleteBatchResponse, error) {
 	u, reqID, err := session.GetUserAndReqID(ctx)
 	if err != nil {
@@ -144,7 +233,7 @@ func (srv *Server) DeleteBatch(ctx context.Context, r *g.DeleteBatchRequest) (*g
 		return nil, gstatus.Error(codes.Internal, ErrRequestCtx)
 	}
 
-	err = srv.shortenerSvc.DeleteBatch(ctx, u, r.Hashes)
+	err = srv.shortenerSvc.DeleteBatch(ctx, u, r.Workspace, r.Hashes)
 	srv.logger.With(
 		zap.String("id", reqID),
 		zap.String("userID", u.ID),
@@ -154,6 +243,9 @@ func (srv *Server) DeleteBatch(ctx context.Context, r *g.DeleteBatchRequest) (*g
 		switch {
 		case errors.Is(shortener.ErrUnauthorized, err):
 			return nil, gstatus.Error(codes.Unauthenticated, "unauthorized")
+		case errors.Is(err, shortener.ErrForbidden),
+			errors.Is(err, shortener.ErrWorkspaceNotFound):
+			return nil, workspaceError(err)
 		case errors.Is(shortener.ErrEmptyBatch, err):
 			return nil, gstatus.Error(codes.InvalidArgument, "empty batch")
 		default:
@@ -164,14 +256,15 @@ func (srv *Server) DeleteBatch(ctx context.Context, r *g.DeleteBatchRequest) (*g
 }
 
 // GetAll returns all URLs created by single user.
-func (srv *Server) GetAll(ctx context.Context, _ *g.GetAllRequest) (*g.GetAllResponse, error) {
+// If workspace is set, all URLs of workspace are returned instead.
+func (srv *Server) GetAll(ctx context.Context, r *g.GetAllRequest) (*g.GetAllResponse, error) {
 	u, reqID, err := session.GetUserAndReqID(ctx)
 	if err != nil {
 		srv.logger.Error(ErrRequestCtx, zap.Error(err))
 		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
 	}
 
-	urls, err := srv.shortenerSvc.GetAll(ctx, u)
+	urls, err := srv.shortenerSvc.GetAll(ctx, u, r.Workspace, r.Tag)
 	srv.logger.With(
 		zap.Int("urls", len(urls)),
 		zap.String("id", reqID),
@@ -179,6 +272,11 @@ func (srv *Server) GetAll(ctx context.Context, _ *g.GetAllRequest) (*g.GetAllRes
 		zap.Error(err),
 	).Debug("getAll called")
 	if err != nil {
+		if errors.Is(err, shortener.ErrUnauthorized) ||
+			errors.Is(err, shortener.ErrForbidden) ||
+			errors.Is(err, shortener.ErrWorkspaceNotFound) {
+			return nil, workspaceError(err)
+		}
 		if errors.Is(err, model.ErrNotFound) {
 			return nil, gstatus.Errorf(codes.NotFound, "no urls")
 		}
@@ -188,10 +286,296 @@ func (srv *Server) GetAll(ctx context.Context, _ *g.GetAllRequest) (*g.GetAllRes
 	var resp g.GetAllResponse
 	resp.Urls = make([]*g.URL, 0, len(urls))
 	for i := range urls {
-		resp.Urls = append(resp.Urls, &g.URL{
-			ShortUrl:    urls[i].Short,
-			OriginalUrl: urls[i].Orig,
+		resp.Urls = append(resp.Urls, urlToProto(urls[i]))
+	}
+	return &resp, nil
+}
+
+// UpdateURLMeta updates title, tags and notes of user URL.
+// Only fields present in request are changed, updated URL is returned.
+func (srv *Server) UpdateURLMeta(ctx context.Context, r *g.UpdateURLMetaRequest) (*g.URL, error) {
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	update := &model.MetaUpdate{
+		Title: r.Title,
+		Notes: r.Notes,
+	}
+	if r.Tags != nil {
+		update.Tags = &r.Tags.Tags
+	}
+	url, err := srv.shortenerSvc.UpdateMeta(ctx, u, r.Short, update)
+	srv.logger.With(
+		zap.String("short", r.Short),
+		zap.String("id", reqID),
+		zap.String("userID", u.ID),
+		zap.Error(err),
+	).Debug("updateURLMeta called")
+	if err != nil {
+		switch {
+		case errors.Is(err, shortener.ErrUnauthorized):
+			return nil, gstatus.Error(codes.Unauthenticated, "unauthorized")
+		case errors.Is(err, shortener.ErrForbidden):
+			return nil, workspaceError(err)
+		case errors.Is(err, model.ErrNotFound),
+			errors.Is(err, model.ErrDeleted):
+			return nil, gstatus.Error(codes.NotFound, "url is not found")
+		case errors.Is(err, shortener.ErrInvalidTitle),
+			errors.Is(err, shortener.ErrInvalidTags),
+			errors.Is(err, shortener.ErrInvalidNotes):
+			return nil, gstatus.Error(codes.InvalidArgument, err.Error())
+		default:
+			return nil, gstatus.Error(codes.Internal, "internal error")
+		}
+	}
+	return urlToProto(url), nil
+}
+
+func urlToProto(u *model.URL) *g.URL {
+	return &g.URL{
+		ShortUrl:     u.Short,
+		OriginalUrl:  u.Orig,
+		RedirectCode: int32(u.Redirect),
+		Passthrough:  u.Passthrough,
+		Targets:      targetsToProto(u.Targets),
+		Variants:     variantsToProto(u.Variants),
+		Schedule:     scheduleToProto(u.Schedule),
+		Title:        u.Title,
+		Preview:      u.Preview,
+		Tags:         u.Tags,
+		Notes:        u.Notes,
+	}
+}
+
+// GetVariantStats returns click statistics of URL variants to URL owner.
+func (srv *Server) GetVariantStats(
+	ctx context.Context,
+	r *g.GetVariantStatsRequest,
+) (*g.GetVariantStatsResponse, error) {
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	stats, err := srv.shortenerSvc.GetVariantStats(ctx, u, r.Short)
+	srv.logger.With(
+		zap.Int("variants", len(stats)),
+		zap.String("id", reqID),
+		zap.String("userID", u.ID),
+		zap.Error(err),
+	).Debug("getVariantStats called")
+	if err != nil {
+		switch {
+		case errors.Is(err, shortener.ErrUnauthorized):
+			return nil, gstatus.Error(codes.Unauthenticated, "unauthorized")
+		case errors.Is(err, shortener.ErrForbidden):
+			return nil, workspaceError(err)
+		case errors.Is(err, model.ErrNotFound),
+			errors.Is(err, model.ErrDeleted):
+			return nil, gstatus.Error(codes.NotFound, "url is not found")
+		default:
+			return nil, gstatus.Error(codes.Internal, "internal error")
+		}
+	}
+
+	var resp g.GetVariantStatsResponse
+	resp.Stats = make([]*g.VariantStats, 0, len(stats))
+	for i := range stats {
+		resp.Stats = append(resp.Stats, &g.VariantStats{
+			Variant: int32(stats[i].Variant),
+			Url:     stats[i].URL,
+			Weight:  int32(stats[i].Weight),
+			Clicks:  stats[i].Clicks,
 		})
 	}
 	return &resp, nil
 }
+
+// GetQRCode renders QR code image of short URL.
+func (srv *Server) GetQRCode(ctx context.Context, r *g.GetQRCodeRequest) (*g.GetQRCodeResponse, error) {
+	reqID, ok := session.GetRequestID(ctx)
+	if !ok {
+		srv.logger.Error("request id was not provided in context")
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	req := &resolver.QRRequest{
+		Path:   "/" + r.Short,
+		Format: r.Format,
+		Level:  r.Level,
+		Size:   int(r.Size),
+	}
+	if r.Margin != nil {
+		margin := int(*r.Margin)
+		req.Margin = &margin
+	}
+	code, err := srv.resolverSvc.QRCode(ctx, req)
+	srv.logger.With(
+		zap.String("short", r.Short),
+		zap.String("id", reqID),
+		zap.Error(err),
+	).Debug("getQRCode called")
+	if err != nil {
+		switch {
+		case errors.Is(err, resolver.ErrInvalidPath):
+			return nil, gstatus.Errorf(codes.InvalidArgument, "invalid path")
+		case errors.Is(err, resolver.ErrInvalidQRParams):
+			return nil, gstatus.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, model.ErrNotFound):
+			return nil, gstatus.Errorf(codes.NotFound, "path is not found")
+		case errors.Is(err, model.ErrDeleted):
+			return nil, gstatus.Errorf(codes.FailedPrecondition, "path is deleted")
+		default:
+			return nil, gstatus.Error(codes.Internal, "internal error occurred")
+		}
+	}
+	return &g.GetQRCodeResponse{
+		Image:       code.Data,
+		ContentType: code.ContentType,
+	}, nil
+}
+
+func targetsFromProto(targets []*g.Target) []model.Target {
+	if len(targets) == 0 {
+		return nil
+	}
+	result := make([]model.Target, 0, len(targets))
+	for _, target := range targets {
+		result = append(result, model.Target{
+			Platform: target.Platform,
+			Country:  target.Country,
+			URL:      target.Url,
+		})
+	}
+	return result
+}
+
+func targetsToProto(targets []model.Target) []*g.Target {
+	if len(targets) == 0 {
+		return nil
+	}
+	result := make([]*g.Target, 0, len(targets))
+	for _, target := range targets {
+		result = append(result, &g.Target{
+			Platform: target.Platform,
+			Country:  target.Country,
+			Url:      target.URL,
+		})
+	}
+	return result
+}
+
+func variantsFromProto(variants []*g.Variant) []model.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+	result := make([]model.Variant, 0, len(variants))
+	for _, variant := range variants {
+		result = append(result, model.Variant{
+			URL:    variant.Url,
+			Weight: int(variant.Weight),
+		})
+	}
+	return result
+}
+
+func variantsToProto(variants []model.Variant) []*g.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+	result := make([]*g.Variant, 0, len(variants))
+	for _, variant := range variants {
+		result = append(result, &g.Variant{
+			Url:    variant.URL,
+			Weight: int32(variant.Weight),
+		})
+	}
+	return result
+}
+
+func scheduleFromProto(schedule *g.Schedule) *model.Schedule {
+	if schedule == nil {
+		return nil
+	}
+	result := &model.Schedule{
+		Timezone: schedule.Timezone,
+		Rules:    make([]model.ScheduleRule, 0, len(schedule.Rules)),
+	}
+	for _, rule := range schedule.Rules {
+		result.Rules = append(result.Rules, model.ScheduleRule{
+			From: rule.From,
+			To:   rule.To,
+			URL:  rule.Url,
+		})
+	}
+	return result
+}
+
+func scheduleToProto(schedule *model.Schedule) *g.Schedule {
+	if schedule == nil {
+		return nil
+	}
+	result := &g.Schedule{
+		Timezone: schedule.Timezone,
+		Rules:    make([]*g.ScheduleRule, 0, len(schedule.Rules)),
+	}
+	for _, rule := range schedule.Rules {
+		result.Rules = append(result.Rules, &g.ScheduleRule{
+			From: rule.From,
+			To:   rule.To,
+			Url:  rule.URL,
+		})
+	}
+	return result
+}
+
+// clientIP returns client address provided in request if it's sent by trusted proxy,
+// otherwise address is derived from peer address and trusted proxy metadata
+// the same way as for http requests.
+func (srv *Server) clientIP(ctx context.Context, clientIP string) string {
+	remoteAddr, xRealIP, xff := requestAddrs(ctx)
+	if srv.filter == nil {
+		host, _, err := net.SplitHostPort(remoteAddr)
+		if err != nil {
+			return remoteAddr
+		}
+		return host
+	}
+	if clientIP != "" && srv.filter.IsTrustedProxy(remoteAddr) {
+		return clientIP
+	}
+	return srv.filter.ClientIP(remoteAddr, xRealIP, xff)
+}
+
+// isTrusted checks whether client is inside trusted subnets.
+func (srv *Server) isTrusted(ctx context.Context) bool {
+	return srv.filter != nil && srv.filter.CheckRequestParams(requestAddrs(ctx))
+}
+
+// requestAddrs returns peer address and proxy metadata values.
+func requestAddrs(ctx context.Context) (remoteAddr, xRealIP, xff string) {
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if xffS := md.Get("x-forwarded-for"); len(xffS) > 0 {
+			xff = xffS[0]
+		}
+		if xRealIPS := md.Get("x-real-ip"); len(xRealIPS) > 0 {
+			xRealIP = xRealIPS[0]
+		}
+	}
+	return
+}
+
+// createdToProto returns creation unix timestamp, zero if creation time is unknown.
+func createdToProto(created time.Time) int64 {
+	if created.IsZero() {
+		return 0
+	}
+	return created.Unix()
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/adwski/shorty/internal/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestServer_clientIP(t *testing.T) {
+	f, err := filter.New(&filter.Config{
+		Logger:       zap.NewNop(),
+		Subnets:      "10.0.0.0/8",
+		TrustXRealIP: true,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		filter   *filter.Filter
+		peer     string
+		xRealIP  string
+		clientIP string
+		want     string
+	}{
+		{
+			name:     "client ip from trusted proxy",
+			filter:   f,
+			peer:     "10.1.1.1:5000",
+			clientIP: "1.1.1.1",
+			want:     "1.1.1.1",
+		},
+		{
+			name:     "spoofed client ip from untrusted peer",
+			filter:   f,
+			peer:     "2.2.2.2:5000",
+			xRealIP:  "10.1.1.1",
+			clientIP: "1.1.1.1",
+			want:     "2.2.2.2",
+		},
+		{
+			name:    "x-real-ip from trusted proxy",
+			filter:  f,
+			peer:    "10.1.1.1:5000",
+			xRealIP: "3.3.3.3",
+			want:    "3.3.3.3",
+		},
+		{
+			name:     "peer address without filter",
+			peer:     "2.2.2.2:5000",
+			clientIP: "1.1.1.1",
+			want:     "2.2.2.2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, errA := net.ResolveTCPAddr("tcp", tt.peer)
+			require.NoError(t, errA)
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+			if tt.xRealIP != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-real-ip", tt.xRealIP))
+			}
+			srv := &Server{filter: tt.filter}
+			assert.Equal(t, tt.want, srv.clientIP(ctx, tt.clientIP))
+		})
+	}
+}
@@ -0,0 +1,182 @@
+//nolint:wrapcheck // using gstatus.Error() to return grpc errors
+package server
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	g "github.com/adwski/shorty/internal/grpc"
+	"github.com/adwski/shorty/internal/services/shortener"
+	"github.com/adwski/shorty/internal/session"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// ImportURLs imports user links from client stream. Import options are taken
+// from the first message, preserving owners is allowed only for trusted clients.
+// Errors are reported with sequence numbers of stream messages.
+func (srv *Server) ImportURLs(stream g.Shortener_ImportURLsServer) error {
+	ctx := stream.Context()
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String("userID", u.ID))
+
+	first, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return stream.SendAndClose(&g.ImportURLsResponse{})
+	}
+	if err != nil {
+		return err
+	}
+	opts := shortener.ImportOptions{
+		PreserveShort: first.PreserveShort,
+		PreserveOwner: first.PreserveOwner,
+	}
+	if opts.PreserveOwner && !srv.isTrusted(ctx) {
+		return gstatus.Error(codes.PermissionDenied, "preserving owners is not allowed")
+	}
+
+	result, err := srv.shortenerSvc.Import(ctx, u, &importStream{stream: stream, first: first}, opts)
+	logf.With(
+		zap.Int("imported", result.Imported),
+		zap.Int("failed", result.Failed),
+		zap.Error(err),
+	).Debug("importURLs called")
+	if err != nil {
+		switch {
+		case errors.Is(err, shortener.ErrUnauthorized):
+			return gstatus.Error(codes.Unauthenticated, "unauthorized")
+		case errors.Is(err, shortener.ErrStorageError):
+			return gstatus.Error(codes.Internal, "internal error")
+		default:
+			return gstatus.Error(codes.Aborted, err.Error())
+		}
+	}
+
+	resp := &g.ImportURLsResponse{
+		Imported: int64(result.Imported),
+		Failed:   int64(result.Failed),
+		Errors:   make([]*g.ImportError, 0, len(result.Errors)),
+	}
+	for _, e := range result.Errors {
+		resp.Errors = append(resp.Errors, &g.ImportError{Line: int64(e.Line), Error: e.Error})
+	}
+	return stream.SendAndClose(resp)
+}
+
+// ExportURLs streams all user links.
+func (srv *Server) ExportURLs(_ *g.ExportURLsRequest, stream g.Shortener_ExportURLsServer) error {
+	ctx := stream.Context()
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	n, err := srv.shortenerSvc.Export(ctx, u, &exportStream{stream: stream})
+	srv.logger.With(
+		zap.Int("records", n),
+		zap.String("id", reqID),
+		zap.String("userID", u.ID),
+		zap.Error(err),
+	).Debug("exportURLs called")
+	if err != nil {
+		if errors.Is(err, shortener.ErrUnauthorized) {
+			return gstatus.Error(codes.Unauthenticated, "unauthorized")
+		}
+		return gstatus.Error(codes.Internal, "internal error")
+	}
+	return nil
+}
+
+// importStream reads import records from client stream,
+// record of the first message is returned first.
+type importStream struct {
+	stream g.Shortener_ImportURLsServer
+	first  *g.ImportURLsRequest
+	line   int
+}
+
+func (s *importStream) Read() (*shortener.LinkRecord, int, error) {
+	for {
+		msg := s.first
+		if msg != nil {
+			s.first = nil
+		} else {
+			var err error
+			if msg, err = s.stream.Recv(); err != nil {
+				return nil, s.line, err
+			}
+		}
+		s.line++
+		if msg.Record == nil {
+			if s.line == 1 {
+				// first message can hold only options
+				continue
+			}
+			return nil, s.line, errors.Join(shortener.ErrInvalidRecord, errors.New("empty record"))
+		}
+		return recordFromProto(msg.Record), s.line, nil
+	}
+}
+
+// exportStream sends export records to server stream.
+type exportStream struct {
+	stream g.Shortener_ExportURLsServer
+}
+
+func (s *exportStream) Write(rec *shortener.LinkRecord) error {
+	return s.stream.Send(recordToProto(rec))
+}
+
+func recordFromProto(rec *g.LinkRecord) *shortener.LinkRecord {
+	link := &shortener.LinkRecord{
+		Short:        rec.Short,
+		URL:          rec.OriginalUrl,
+		Owner:        rec.Owner,
+		Title:        rec.Title,
+		Tags:         rec.Tags,
+		Notes:        rec.Notes,
+		Redirect:     int(rec.RedirectCode),
+		Passthrough:  rec.Passthrough,
+		Preview:      rec.Preview,
+		Targets:      targetsFromProto(rec.Targets),
+		Variants:     variantsFromProto(rec.Variants),
+		Schedule:     scheduleFromProto(rec.Schedule),
+		PasswordHash: rec.PasswordHash,
+	}
+	if rec.CreatedAt != 0 {
+		created := time.Unix(rec.CreatedAt, 0).UTC()
+		link.Created = &created
+	}
+	return link
+}
+
+func recordToProto(rec *shortener.LinkRecord) *g.LinkRecord {
+	var created time.Time
+	if rec.Created != nil {
+		created = *rec.Created
+	}
+	return &g.LinkRecord{
+		Short:        rec.Short,
+		ShortUrl:     rec.ShortURL,
+		OriginalUrl:  rec.URL,
+		Owner:        rec.Owner,
+		Title:        rec.Title,
+		Tags:         rec.Tags,
+		Notes:        rec.Notes,
+		RedirectCode: int32(rec.Redirect),
+		Passthrough:  rec.Passthrough,
+		Preview:      rec.Preview,
+		CreatedAt:    createdToProto(created),
+		Targets:      targetsToProto(rec.Targets),
+		Variants:     variantsToProto(rec.Variants),
+		Schedule:     scheduleToProto(rec.Schedule),
+		PasswordHash: rec.PasswordHash,
+	}
+}
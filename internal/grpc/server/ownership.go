@@ -0,0 +1,216 @@
+//nolint:wrapcheck // using gstatus.Error() to return grpc errors
+package server
+
+import (
+	"context"
+	"errors"
+
+	g "github.com/adwski/shorty/internal/grpc"
+	"github.com/adwski/shorty/internal/services/transfer"
+	"github.com/adwski/shorty/internal/session"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// OfferTransfer offers personal links of user to another user.
+func (srv *Server) OfferTransfer(ctx context.Context, r *g.OfferTransferRequest) (*g.Transfer, error) {
+	if srv.transferSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "transfers are not enabled")
+	}
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	offer, err := srv.transferSvc.Offer(ctx, u, &transfer.Recipient{UserID: r.ToUserId, Login: r.ToLogin}, r.Shorts)
+	srv.logger.With(
+		zap.String("id", reqID),
+		zap.String("userID", u.ID),
+		zap.Error(err),
+	).Debug("offerTransfer called")
+	if err != nil {
+		return nil, srv.transferSvcError(reqID, err)
+	}
+	return transferToProto(offer), nil
+}
+
+// ListTransfers returns pending transfer offers sent or received by user.
+func (srv *Server) ListTransfers(ctx context.Context, _ *g.ListTransfersRequest) (*g.ListTransfersResponse, error) {
+	if srv.transferSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "transfers are not enabled")
+	}
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	transfers, err := srv.transferSvc.List(ctx, u)
+	srv.logger.With(
+		zap.Int("transfers", len(transfers)),
+		zap.String("id", reqID),
+		zap.String("userID", u.ID),
+		zap.Error(err),
+	).Debug("listTransfers called")
+	if err != nil {
+		return nil, srv.transferSvcError(reqID, err)
+	}
+	resp := &g.ListTransfersResponse{Transfers: make([]*g.Transfer, 0, len(transfers))}
+	for _, t := range transfers {
+		resp.Transfers = append(resp.Transfers, transferToProto(t))
+	}
+	return resp, nil
+}
+
+// AcceptTransfer accepts transfer offer received by user, links are moved to user.
+func (srv *Server) AcceptTransfer(ctx context.Context, r *g.AcceptTransferRequest) (*g.AuditEvent, error) {
+	if srv.transferSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "transfers are not enabled")
+	}
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	event, err := srv.transferSvc.Accept(ctx, u, r.Id)
+	srv.logger.With(
+		zap.String("transfer", r.Id),
+		zap.String("id", reqID),
+		zap.String("userID", u.ID),
+		zap.Error(err),
+	).Debug("acceptTransfer called")
+	if err != nil {
+		return nil, srv.transferSvcError(reqID, err)
+	}
+	return eventToProto(event), nil
+}
+
+// DeclineTransfer declines received transfer offer or cancels sent one.
+func (srv *Server) DeclineTransfer(
+	ctx context.Context,
+	r *g.DeclineTransferRequest,
+) (*g.DeclineTransferResponse, error) {
+	if srv.transferSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "transfers are not enabled")
+	}
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	err = srv.transferSvc.Decline(ctx, u, r.Id)
+	srv.logger.With(
+		zap.String("transfer", r.Id),
+		zap.String("id", reqID),
+		zap.String("userID", u.ID),
+		zap.Error(err),
+	).Debug("declineTransfer called")
+	if err != nil {
+		return nil, srv.transferSvcError(reqID, err)
+	}
+	return &g.DeclineTransferResponse{}, nil
+}
+
+// ForceTransfer moves personal links of user to another user without their consent.
+// It's available only to trusted subnets.
+func (srv *Server) ForceTransfer(ctx context.Context, r *g.ForceTransferRequest) (*g.AuditEvent, error) {
+	if srv.transferSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "transfers are not enabled")
+	}
+	reqID, ok := session.GetRequestID(ctx)
+	if !ok {
+		srv.logger.Error("request id was not provided in context")
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	event, err := srv.transferSvc.Force(ctx, r.FromUserId,
+		&transfer.Recipient{UserID: r.ToUserId, Login: r.ToLogin}, r.Shorts)
+	srv.logger.With(
+		zap.String("id", reqID),
+		zap.Error(err),
+	).Debug("forceTransfer called")
+	if err != nil {
+		return nil, srv.transferSvcError(reqID, err)
+	}
+	return eventToProto(event), nil
+}
+
+// ListAuditEvents returns latest audit events, newest first.
+// It's available only to trusted subnets.
+func (srv *Server) ListAuditEvents(
+	ctx context.Context,
+	r *g.ListAuditEventsRequest,
+) (*g.ListAuditEventsResponse, error) {
+	if srv.transferSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "transfers are not enabled")
+	}
+	reqID, ok := session.GetRequestID(ctx)
+	if !ok {
+		srv.logger.Error("request id was not provided in context")
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	events, err := srv.transferSvc.Audit(ctx, int(r.Limit))
+	srv.logger.With(
+		zap.Int("events", len(events)),
+		zap.String("id", reqID),
+		zap.Error(err),
+	).Debug("listAuditEvents called")
+	if err != nil {
+		return nil, srv.transferSvcError(reqID, err)
+	}
+	resp := &g.ListAuditEventsResponse{Events: make([]*g.AuditEvent, 0, len(events))}
+	for _, e := range events {
+		resp.Events = append(resp.Events, eventToProto(e))
+	}
+	return resp, nil
+}
+
+func (srv *Server) transferSvcError(reqID string, err error) error {
+	if qErr := quotaExceededError(err); qErr != nil {
+		return qErr
+	}
+	switch {
+	case errors.Is(err, transfer.ErrUnauthorized):
+		return gstatus.Error(codes.Unauthenticated, "unauthorized")
+	case errors.Is(err, transfer.ErrInvalidUser),
+		errors.Is(err, transfer.ErrInvalidURLs),
+		errors.Is(err, transfer.ErrTooManyURLs),
+		errors.Is(err, transfer.ErrInvalidAuditLimit):
+		return gstatus.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, transfer.ErrNotFound):
+		return gstatus.Error(codes.NotFound, err.Error())
+	case errors.Is(err, transfer.ErrTooManyTransfers):
+		return gstatus.Error(codes.FailedPrecondition, err.Error())
+	default:
+		srv.logger.Error("transfer request failed", zap.String("id", reqID), zap.Error(err))
+		return gstatus.Error(codes.Internal, "internal error occurred")
+	}
+}
+
+func transferToProto(t *transfer.Transfer) *g.Transfer {
+	return &g.Transfer{
+		Id:         t.ID,
+		FromUserId: t.From,
+		ToUserId:   t.To,
+		Shorts:     t.Shorts,
+		CreatedAt:  createdToProto(t.Created),
+	}
+}
+
+func eventToProto(e *transfer.Event) *g.AuditEvent {
+	return &g.AuditEvent{
+		Id:          e.ID,
+		Action:      e.Action,
+		ActorUserId: e.Actor,
+		TransferId:  e.Transfer,
+		FromUserId:  e.From,
+		ToUserId:    e.To,
+		Shorts:      e.Shorts,
+		CreatedAt:   createdToProto(e.Created),
+	}
+}
@@ -0,0 +1,189 @@
+//nolint:wrapcheck // using gstatus.Error() to return grpc errors
+package server
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	g "github.com/adwski/shorty/internal/grpc"
+	"github.com/adwski/shorty/internal/services/quota"
+	"github.com/adwski/shorty/internal/session"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// GetUserQuota returns quota limits of user and their usage.
+func (srv *Server) GetUserQuota(ctx context.Context, _ *g.GetUserQuotaRequest) (*g.Quota, error) {
+	if srv.quotaSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "quotas are not enabled")
+	}
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	usage, err := srv.quotaSvc.Get(ctx, u)
+	srv.logger.With(
+		zap.String("id", reqID),
+		zap.String("userID", u.ID),
+		zap.Error(err),
+	).Debug("getUserQuota called")
+	if err != nil {
+		return nil, srv.quotaSvcError(reqID, err)
+	}
+	return usageToProto(usage), nil
+}
+
+// GetQuota returns quota limits of any user and their usage.
+// It's available only to trusted subnets.
+func (srv *Server) GetQuota(ctx context.Context, r *g.GetQuotaRequest) (*g.Quota, error) {
+	if srv.quotaSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "quotas are not enabled")
+	}
+	reqID, ok := session.GetRequestID(ctx)
+	if !ok {
+		srv.logger.Error("request id was not provided in context")
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	usage, err := srv.quotaSvc.Lookup(ctx, r.UserId)
+	srv.logger.With(
+		zap.String("id", reqID),
+		zap.Error(err),
+	).Debug("getQuota called")
+	if err != nil {
+		return nil, srv.quotaSvcError(reqID, err)
+	}
+	return usageToProto(usage), nil
+}
+
+// SetQuota assigns quota tier or custom limits to user.
+// It's available only to trusted subnets.
+func (srv *Server) SetQuota(ctx context.Context, r *g.SetQuotaRequest) (*g.Quota, error) {
+	if srv.quotaSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "quotas are not enabled")
+	}
+	reqID, ok := session.GetRequestID(ctx)
+	if !ok {
+		srv.logger.Error("request id was not provided in context")
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	usage, err := srv.quotaSvc.Set(ctx, r.UserId, r.Tier, int(r.MaxUrls), int(r.MaxBatch))
+	srv.logger.With(
+		zap.String("id", reqID),
+		zap.Error(err),
+	).Debug("setQuota called")
+	if err != nil {
+		return nil, srv.quotaSvcError(reqID, err)
+	}
+	return usageToProto(usage), nil
+}
+
+// ResetQuota removes quota assignment of user, so user gets back to default tier.
+// It's available only to trusted subnets.
+func (srv *Server) ResetQuota(ctx context.Context, r *g.ResetQuotaRequest) (*g.ResetQuotaResponse, error) {
+	if srv.quotaSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "quotas are not enabled")
+	}
+	reqID, ok := session.GetRequestID(ctx)
+	if !ok {
+		srv.logger.Error("request id was not provided in context")
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	err := srv.quotaSvc.Reset(ctx, r.UserId)
+	srv.logger.With(
+		zap.String("id", reqID),
+		zap.Error(err),
+	).Debug("resetQuota called")
+	if err != nil {
+		return nil, srv.quotaSvcError(reqID, err)
+	}
+	return &g.ResetQuotaResponse{}, nil
+}
+
+// ListQuotaTiers returns configured quota tiers.
+// It's available only to trusted subnets.
+func (srv *Server) ListQuotaTiers(_ context.Context, _ *g.ListQuotaTiersRequest) (*g.ListQuotaTiersResponse, error) {
+	if srv.quotaSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "quotas are not enabled")
+	}
+	tiers := srv.quotaSvc.Tiers()
+	resp := &g.ListQuotaTiersResponse{
+		DefaultTier: srv.quotaSvc.DefaultTier(),
+		Tiers:       make([]*g.QuotaTier, 0, len(tiers)),
+	}
+	for _, t := range tiers {
+		resp.Tiers = append(resp.Tiers, &g.QuotaTier{
+			Name:     t.Name,
+			MaxUrls:  int64(t.MaxURLs),
+			MaxBatch: int64(t.MaxBatch),
+		})
+	}
+	return resp, nil
+}
+
+func (srv *Server) quotaSvcError(reqID string, err error) error {
+	switch {
+	case errors.Is(err, quota.ErrUnauthorized):
+		return gstatus.Error(codes.Unauthenticated, "unauthorized")
+	case errors.Is(err, quota.ErrInvalidUser),
+		errors.Is(err, quota.ErrInvalidQuota):
+		return gstatus.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, quota.ErrNotFound):
+		return gstatus.Error(codes.NotFound, err.Error())
+	default:
+		srv.logger.Error("quota request failed", zap.String("id", reqID), zap.Error(err))
+		return gstatus.Error(codes.Internal, "internal error occurred")
+	}
+}
+
+// quotaExceededError returns ResourceExhausted status if err is quota error, otherwise nil is returned.
+// Limit and remaining count are attached as error details.
+func quotaExceededError(err error) error {
+	var exceeded *quota.ExceededError
+	if !errors.As(err, &exceeded) {
+		return nil
+	}
+	st := gstatus.New(codes.ResourceExhausted, exceeded.Error())
+	if detailed, errD := st.WithDetails(
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{
+			Subject:     exceeded.Quota,
+			Description: exceeded.Error(),
+		}}},
+		&errdetails.ErrorInfo{
+			Reason: "QUOTA_EXCEEDED",
+			Domain: "shorty",
+			Metadata: map[string]string{
+				"quota":     exceeded.Quota,
+				"limit":     strconv.Itoa(exceeded.Limit),
+				"requested": strconv.Itoa(exceeded.Requested),
+				"remaining": strconv.Itoa(exceeded.Remaining),
+			},
+		},
+	); errD == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func usageToProto(u *quota.Usage) *g.Quota {
+	q := &g.Quota{
+		UserId:   u.UserID,
+		Tier:     u.Tier,
+		Custom:   u.Custom,
+		MaxUrls:  int64(u.MaxURLs),
+		MaxBatch: int64(u.MaxBatch),
+		Urls:     int64(u.URLs),
+	}
+	if u.RemainingURLs != nil {
+		remaining := int64(*u.RemainingURLs)
+		q.RemainingUrls = &remaining
+	}
+	return q
+}
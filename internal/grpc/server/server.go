@@ -8,14 +8,23 @@ import (
 	"time"
 
 	"github.com/adwski/shorty/internal/config"
+	ipfilter "github.com/adwski/shorty/internal/filter"
 	g "github.com/adwski/shorty/internal/grpc"
 	"github.com/adwski/shorty/internal/grpc/interceptors/auth"
 	"github.com/adwski/shorty/internal/grpc/interceptors/filter"
 	"github.com/adwski/shorty/internal/grpc/interceptors/logging"
+	"github.com/adwski/shorty/internal/grpc/interceptors/ratelimit"
 	"github.com/adwski/shorty/internal/grpc/interceptors/requestid"
+	"github.com/adwski/shorty/internal/grpc/interceptors/scope"
+	limits "github.com/adwski/shorty/internal/ratelimit"
+	"github.com/adwski/shorty/internal/services/account"
+	"github.com/adwski/shorty/internal/services/apikey"
+	"github.com/adwski/shorty/internal/services/quota"
 	"github.com/adwski/shorty/internal/services/resolver"
 	"github.com/adwski/shorty/internal/services/shortener"
 	"github.com/adwski/shorty/internal/services/status"
+	"github.com/adwski/shorty/internal/services/transfer"
+	"github.com/adwski/shorty/internal/services/workspace"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -28,6 +37,56 @@ const (
 	defaultRPCTimeout = 5 * time.Second
 )
 
+// mutatingMethods change user data, they require valid credentials in strict auth mode.
+// Admin methods are not listed since they are authorized with admin token.
+var mutatingMethods = []string{
+	"/shorty.shortener/Shorten",
+	"/shorty.shortener/ShortenBatch",
+	"/shorty.shortener/DeleteBatch",
+	"/shorty.shortener/UpdateURLMeta",
+	"/shorty.shortener/ImportURLs",
+	"/shorty.shortener/Logout",
+	"/shorty.shortener/CreateWorkspace",
+	"/shorty.shortener/SetWorkspaceMember",
+	"/shorty.shortener/RemoveWorkspaceMember",
+	"/shorty.shortener/OfferTransfer",
+	"/shorty.shortener/AcceptTransfer",
+	"/shorty.shortener/DeclineTransfer",
+}
+
+// methodScopes maps methods available to api keys to required key scopes.
+var methodScopes = map[string]string{
+	"/shorty.shortener/Resolve":         "",
+	"/shorty.shortener/Stats":           "",
+	"/shorty.shortener/GetQRCode":       "",
+	"/shorty.shortener/Shorten":         apikey.ScopeShorten,
+	"/shorty.shortener/ShortenBatch":    apikey.ScopeShorten,
+	"/shorty.shortener/UpdateURLMeta":   apikey.ScopeShorten,
+	"/shorty.shortener/ImportURLs":      apikey.ScopeShorten,
+	"/shorty.shortener/GetAll":          apikey.ScopeRead,
+	"/shorty.shortener/GetVariantStats": apikey.ScopeRead,
+	"/shorty.shortener/ExportURLs":      apikey.ScopeRead,
+	"/shorty.shortener/DeleteBatch":     apikey.ScopeDelete,
+	"/shorty.shortener/ForceTransfer":   "",
+	"/shorty.shortener/ListAuditEvents": "",
+	"/shorty.shortener/GetUserQuota":    apikey.ScopeRead,
+	"/shorty.shortener/GetQuota":        "",
+	"/shorty.shortener/SetQuota":        "",
+	"/shorty.shortener/ResetQuota":      "",
+	"/shorty.shortener/ListQuotaTiers":  "",
+}
+
+// internalMethods are administrative methods available only to trusted subnets.
+var internalMethods = []string{
+	"/shorty.shortener/Stats",
+	"/shorty.shortener/ForceTransfer",
+	"/shorty.shortener/ListAuditEvents",
+	"/shorty.shortener/GetQuota",
+	"/shorty.shortener/SetQuota",
+	"/shorty.shortener/ResetQuota",
+	"/shorty.shortener/ListQuotaTiers",
+}
+
 // Server is grpc transport server for shorty app.
 type Server struct {
 	g.UnimplementedShortenerServer
@@ -37,6 +96,12 @@ type Server struct {
 	shortenerSvc *shortener.Service
 	resolverSvc  *resolver.Service
 	statusSvc    *status.Service
+	accountSvc   *account.Service
+	workspaceSvc *workspace.Service
+	transferSvc  *transfer.Service
+	quotaSvc     *quota.Service
+
+	filter *ipfilter.Filter
 
 	addr string
 	opts []grpc.ServerOption
@@ -45,13 +110,30 @@ type Server struct {
 }
 
 // NewServer creates new grpc transport server.
+// Account, workspace, transfer and quota services are optional, their rpcs return Unimplemented if they're nil.
+// Api keys are accepted only if api key service is set.
+// Unary calls are rate limited if limiter is set.
 func NewServer(
 	logger *zap.Logger,
 	cfg *config.Config,
 	resolverSvc *resolver.Service,
 	shortenerSvc *shortener.Service,
 	statusSvc *status.Service,
+	accountSvc *account.Service,
+	apikeySvc *apikey.Service,
+	workspaceSvc *workspace.Service,
+	transferSvc *transfer.Service,
+	quotaSvc *quota.Service,
+	limiter *limits.Limiter,
 ) *Server {
+	authInterceptor := auth.NewFromAuthorizer(logger, cfg.GetAuthorizer())
+	if apikeySvc != nil {
+		authInterceptor.WithKeys(apikeySvc)
+	}
+	if cfg.StrictAuth {
+		authInterceptor.WithStrict(mutatingMethods)
+	}
+
 	// assign options
 	var opts []grpc.ServerOption
 	opts = append(opts, grpc.ConnectionTimeout(defaultServerConnectionTimeout))
@@ -68,15 +150,30 @@ func NewServer(
 		// logging
 		grpc.ChainUnaryInterceptor(logging.New(logger).Get()),
 		// filter
-		grpc.ChainUnaryInterceptor(filter.NewFromFilter(cfg.GetFilter(), []string{"/shorty.shortener/Stats"}).Get()),
+		grpc.ChainUnaryInterceptor(filter.NewFromFilter(cfg.GetFilter(), internalMethods).Get()),
 		// auth
-		grpc.ChainUnaryInterceptor(auth.NewFromAuthorizer(logger, cfg.GetAuthorizer()).Get()))
+		grpc.ChainUnaryInterceptor(authInterceptor.Get()),
+		// api key scopes
+		grpc.ChainUnaryInterceptor(scope.New(methodScopes).Get()),
+		// rate limits, users are limited by user id and other clients by ip
+		grpc.ChainUnaryInterceptor(ratelimit.New(limiter, cfg.GetFilter()).Get()),
+		// stream interceptors, streams have no deadline since import and export can be long
+		grpc.ChainStreamInterceptor(
+			requestid.New(logger, cfg.TrustRequestID).GetStream(),
+			logging.New(logger).GetStream(),
+			authInterceptor.GetStream(),
+			scope.New(methodScopes).GetStream()))
 
 	return &Server{
 		logger:       logger.With(zap.String("component", "grpc")),
 		shortenerSvc: shortenerSvc,
 		resolverSvc:  resolverSvc,
 		statusSvc:    statusSvc,
+		accountSvc:   accountSvc,
+		workspaceSvc: workspaceSvc,
+		transferSvc:  transferSvc,
+		quotaSvc:     quotaSvc,
+		filter:       cfg.GetFilter(),
 		opts:         opts,
 		addr:         cfg.GRPCListenAddr,
 		reflection:   cfg.GRPCReflection,
@@ -0,0 +1,195 @@
+//nolint:wrapcheck // using gstatus.Error() to return grpc errors
+package server
+
+import (
+	"context"
+	"errors"
+
+	g "github.com/adwski/shorty/internal/grpc"
+	"github.com/adwski/shorty/internal/services/shortener"
+	"github.com/adwski/shorty/internal/services/workspace"
+	"github.com/adwski/shorty/internal/session"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// CreateWorkspace creates workspace, user becomes its owner.
+func (srv *Server) CreateWorkspace(ctx context.Context, r *g.CreateWorkspaceRequest) (*g.Workspace, error) {
+	return srv.handleWorkspace(ctx, "createWorkspace", func(ctx context.Context) (*workspace.Workspace, error) {
+		u, _ := session.GetUserFromContext(ctx)
+		return srv.workspaceSvc.Create(ctx, u, r.Name)
+	})
+}
+
+// GetWorkspace returns workspace with its members.
+func (srv *Server) GetWorkspace(ctx context.Context, r *g.GetWorkspaceRequest) (*g.Workspace, error) {
+	return srv.handleWorkspace(ctx, "getWorkspace", func(ctx context.Context) (*workspace.Workspace, error) {
+		u, _ := session.GetUserFromContext(ctx)
+		return srv.workspaceSvc.Get(ctx, u, r.Id)
+	})
+}
+
+// ListWorkspaces returns workspaces user is member of.
+func (srv *Server) ListWorkspaces(ctx context.Context, _ *g.ListWorkspacesRequest) (*g.ListWorkspacesResponse, error) {
+	if srv.workspaceSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "workspaces are not enabled")
+	}
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	workspaces, err := srv.workspaceSvc.List(ctx, u)
+	srv.logger.With(
+		zap.Int("workspaces", len(workspaces)),
+		zap.String("id", reqID),
+		zap.String("userID", u.ID),
+		zap.Error(err),
+	).Debug("listWorkspaces called")
+	if err != nil {
+		return nil, srv.workspaceSvcError(reqID, err)
+	}
+	resp := &g.ListWorkspacesResponse{Workspaces: make([]*g.Workspace, 0, len(workspaces))}
+	for _, ws := range workspaces {
+		resp.Workspaces = append(resp.Workspaces, workspaceToProto(ws))
+	}
+	return resp, nil
+}
+
+// SetWorkspaceMember adds workspace member or changes its role.
+func (srv *Server) SetWorkspaceMember(ctx context.Context, r *g.SetWorkspaceMemberRequest) (*g.WorkspaceMember, error) {
+	if srv.workspaceSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "workspaces are not enabled")
+	}
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	member, err := srv.workspaceSvc.SetMember(ctx, u, r.Workspace, &workspace.MemberUpdate{
+		UserID: r.UserId,
+		Login:  r.Login,
+		Role:   r.Role,
+	})
+	srv.logger.With(
+		zap.String("workspace", r.Workspace),
+		zap.String("id", reqID),
+		zap.String("userID", u.ID),
+		zap.Error(err),
+	).Debug("setWorkspaceMember called")
+	if err != nil {
+		return nil, srv.workspaceSvcError(reqID, err)
+	}
+	return memberToProto(member), nil
+}
+
+// RemoveWorkspaceMember removes workspace member. Members can remove themselves to leave workspace.
+func (srv *Server) RemoveWorkspaceMember(
+	ctx context.Context,
+	r *g.RemoveWorkspaceMemberRequest,
+) (*g.RemoveWorkspaceMemberResponse, error) {
+	if srv.workspaceSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "workspaces are not enabled")
+	}
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	err = srv.workspaceSvc.RemoveMember(ctx, u, r.Workspace, r.UserId)
+	srv.logger.With(
+		zap.String("workspace", r.Workspace),
+		zap.String("id", reqID),
+		zap.String("userID", u.ID),
+		zap.Error(err),
+	).Debug("removeWorkspaceMember called")
+	if err != nil {
+		return nil, srv.workspaceSvcError(reqID, err)
+	}
+	return &g.RemoveWorkspaceMemberResponse{}, nil
+}
+
+func (srv *Server) handleWorkspace(
+	ctx context.Context,
+	method string,
+	fn func(ctx context.Context) (*workspace.Workspace, error),
+) (*g.Workspace, error) {
+	if srv.workspaceSvc == nil {
+		return nil, gstatus.Error(codes.Unimplemented, "workspaces are not enabled")
+	}
+	u, reqID, err := session.GetUserAndReqID(ctx)
+	if err != nil {
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return nil, gstatus.Errorf(codes.Internal, ErrRequestCtx)
+	}
+
+	ws, err := fn(ctx)
+	srv.logger.With(
+		zap.String("id", reqID),
+		zap.String("userID", u.ID),
+		zap.Error(err),
+	).Debug(method + " called")
+	if err != nil {
+		return nil, srv.workspaceSvcError(reqID, err)
+	}
+	return workspaceToProto(ws), nil
+}
+
+func (srv *Server) workspaceSvcError(reqID string, err error) error {
+	switch {
+	case errors.Is(err, workspace.ErrUnauthorized):
+		return gstatus.Error(codes.Unauthenticated, "unauthorized")
+	case errors.Is(err, workspace.ErrForbidden):
+		return gstatus.Error(codes.PermissionDenied, "insufficient workspace role")
+	case errors.Is(err, workspace.ErrInvalidName),
+		errors.Is(err, workspace.ErrInvalidRole),
+		errors.Is(err, workspace.ErrInvalidMember):
+		return gstatus.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, workspace.ErrNotFound),
+		errors.Is(err, workspace.ErrMemberNotFound):
+		return gstatus.Error(codes.NotFound, err.Error())
+	case errors.Is(err, workspace.ErrLastOwner),
+		errors.Is(err, workspace.ErrTooManyWorkspaces):
+		return gstatus.Error(codes.FailedPrecondition, err.Error())
+	default:
+		srv.logger.Error("workspace request failed", zap.String("id", reqID), zap.Error(err))
+		return gstatus.Error(codes.Internal, "internal error occurred")
+	}
+}
+
+// workspaceError converts workspace access errors returned by shortener service.
+func workspaceError(err error) error {
+	switch {
+	case errors.Is(err, shortener.ErrUnauthorized):
+		return gstatus.Error(codes.Unauthenticated, "unauthorized")
+	case errors.Is(err, shortener.ErrForbidden):
+		return gstatus.Error(codes.PermissionDenied, "insufficient workspace role")
+	default:
+		return gstatus.Error(codes.NotFound, "workspace is not found")
+	}
+}
+
+func workspaceToProto(ws *workspace.Workspace) *g.Workspace {
+	result := &g.Workspace{
+		Id:        ws.ID,
+		Name:      ws.Name,
+		Role:      ws.Role,
+		CreatedAt: createdToProto(ws.Created),
+	}
+	for _, m := range ws.Members {
+		result.Members = append(result.Members, memberToProto(m))
+	}
+	return result
+}
+
+func memberToProto(m *workspace.Member) *g.WorkspaceMember {
+	return &g.WorkspaceMember{
+		UserId:  m.UserID,
+		Role:    m.Role,
+		AddedAt: createdToProto(m.Added),
+	}
+}
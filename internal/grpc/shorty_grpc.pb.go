@@ -20,12 +20,37 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Shortener_Resolve_FullMethodName      = "/shorty.shortener/Resolve"
-	Shortener_Shorten_FullMethodName      = "/shorty.shortener/Shorten"
-	Shortener_ShortenBatch_FullMethodName = "/shorty.shortener/ShortenBatch"
-	Shortener_DeleteBatch_FullMethodName  = "/shorty.shortener/DeleteBatch"
-	Shortener_GetAll_FullMethodName       = "/shorty.shortener/GetAll"
-	Shortener_Stats_FullMethodName        = "/shorty.shortener/Stats"
+	Shortener_Resolve_FullMethodName               = "/shorty.shortener/Resolve"
+	Shortener_Shorten_FullMethodName               = "/shorty.shortener/Shorten"
+	Shortener_ShortenBatch_FullMethodName          = "/shorty.shortener/ShortenBatch"
+	Shortener_DeleteBatch_FullMethodName           = "/shorty.shortener/DeleteBatch"
+	Shortener_GetAll_FullMethodName                = "/shorty.shortener/GetAll"
+	Shortener_Stats_FullMethodName                 = "/shorty.shortener/Stats"
+	Shortener_GetVariantStats_FullMethodName       = "/shorty.shortener/GetVariantStats"
+	Shortener_GetQRCode_FullMethodName             = "/shorty.shortener/GetQRCode"
+	Shortener_UpdateURLMeta_FullMethodName         = "/shorty.shortener/UpdateURLMeta"
+	Shortener_ImportURLs_FullMethodName            = "/shorty.shortener/ImportURLs"
+	Shortener_ExportURLs_FullMethodName            = "/shorty.shortener/ExportURLs"
+	Shortener_Register_FullMethodName              = "/shorty.shortener/Register"
+	Shortener_Login_FullMethodName                 = "/shorty.shortener/Login"
+	Shortener_Anonymous_FullMethodName             = "/shorty.shortener/Anonymous"
+	Shortener_Logout_FullMethodName                = "/shorty.shortener/Logout"
+	Shortener_CreateWorkspace_FullMethodName       = "/shorty.shortener/CreateWorkspace"
+	Shortener_ListWorkspaces_FullMethodName        = "/shorty.shortener/ListWorkspaces"
+	Shortener_GetWorkspace_FullMethodName          = "/shorty.shortener/GetWorkspace"
+	Shortener_SetWorkspaceMember_FullMethodName    = "/shorty.shortener/SetWorkspaceMember"
+	Shortener_RemoveWorkspaceMember_FullMethodName = "/shorty.shortener/RemoveWorkspaceMember"
+	Shortener_OfferTransfer_FullMethodName         = "/shorty.shortener/OfferTransfer"
+	Shortener_ListTransfers_FullMethodName         = "/shorty.shortener/ListTransfers"
+	Shortener_AcceptTransfer_FullMethodName        = "/shorty.shortener/AcceptTransfer"
+	Shortener_DeclineTransfer_FullMethodName       = "/shorty.shortener/DeclineTransfer"
+	Shortener_ForceTransfer_FullMethodName         = "/shorty.shortener/ForceTransfer"
+	Shortener_ListAuditEvents_FullMethodName       = "/shorty.shortener/ListAuditEvents"
+	Shortener_GetUserQuota_FullMethodName          = "/shorty.shortener/GetUserQuota"
+	Shortener_GetQuota_FullMethodName              = "/shorty.shortener/GetQuota"
+	Shortener_SetQuota_FullMethodName              = "/shorty.shortener/SetQuota"
+	Shortener_ResetQuota_FullMethodName            = "/shorty.shortener/ResetQuota"
+	Shortener_ListQuotaTiers_FullMethodName        = "/shorty.shortener/ListQuotaTiers"
 )
 
 // ShortenerClient is the client API for Shortener service.
@@ -38,6 +63,31 @@ type ShortenerClient interface {
 	DeleteBatch(ctx context.Context, in *DeleteBatchRequest, opts ...grpc.CallOption) (*DeleteBatchResponse, error)
 	GetAll(ctx context.Context, in *GetAllRequest, opts ...grpc.CallOption) (*GetAllResponse, error)
 	Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
+	GetVariantStats(ctx context.Context, in *GetVariantStatsRequest, opts ...grpc.CallOption) (*GetVariantStatsResponse, error)
+	GetQRCode(ctx context.Context, in *GetQRCodeRequest, opts ...grpc.CallOption) (*GetQRCodeResponse, error)
+	UpdateURLMeta(ctx context.Context, in *UpdateURLMetaRequest, opts ...grpc.CallOption) (*URL, error)
+	ImportURLs(ctx context.Context, opts ...grpc.CallOption) (Shortener_ImportURLsClient, error)
+	ExportURLs(ctx context.Context, in *ExportURLsRequest, opts ...grpc.CallOption) (Shortener_ExportURLsClient, error)
+	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*AuthResponse, error)
+	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*AuthResponse, error)
+	Anonymous(ctx context.Context, in *AnonymousRequest, opts ...grpc.CallOption) (*AuthResponse, error)
+	Logout(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutResponse, error)
+	CreateWorkspace(ctx context.Context, in *CreateWorkspaceRequest, opts ...grpc.CallOption) (*Workspace, error)
+	ListWorkspaces(ctx context.Context, in *ListWorkspacesRequest, opts ...grpc.CallOption) (*ListWorkspacesResponse, error)
+	GetWorkspace(ctx context.Context, in *GetWorkspaceRequest, opts ...grpc.CallOption) (*Workspace, error)
+	SetWorkspaceMember(ctx context.Context, in *SetWorkspaceMemberRequest, opts ...grpc.CallOption) (*WorkspaceMember, error)
+	RemoveWorkspaceMember(ctx context.Context, in *RemoveWorkspaceMemberRequest, opts ...grpc.CallOption) (*RemoveWorkspaceMemberResponse, error)
+	OfferTransfer(ctx context.Context, in *OfferTransferRequest, opts ...grpc.CallOption) (*Transfer, error)
+	ListTransfers(ctx context.Context, in *ListTransfersRequest, opts ...grpc.CallOption) (*ListTransfersResponse, error)
+	AcceptTransfer(ctx context.Context, in *AcceptTransferRequest, opts ...grpc.CallOption) (*AuditEvent, error)
+	DeclineTransfer(ctx context.Context, in *DeclineTransferRequest, opts ...grpc.CallOption) (*DeclineTransferResponse, error)
+	ForceTransfer(ctx context.Context, in *ForceTransferRequest, opts ...grpc.CallOption) (*AuditEvent, error)
+	ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error)
+	GetUserQuota(ctx context.Context, in *GetUserQuotaRequest, opts ...grpc.CallOption) (*Quota, error)
+	GetQuota(ctx context.Context, in *GetQuotaRequest, opts ...grpc.CallOption) (*Quota, error)
+	SetQuota(ctx context.Context, in *SetQuotaRequest, opts ...grpc.CallOption) (*Quota, error)
+	ResetQuota(ctx context.Context, in *ResetQuotaRequest, opts ...grpc.CallOption) (*ResetQuotaResponse, error)
+	ListQuotaTiers(ctx context.Context, in *ListQuotaTiersRequest, opts ...grpc.CallOption) (*ListQuotaTiersResponse, error)
 }
 
 type shortenerClient struct {
@@ -102,6 +152,279 @@ func (c *shortenerClient) Stats(ctx context.Context, in *StatsRequest, opts ...g
 	return out, nil
 }
 
+func (c *shortenerClient) GetVariantStats(ctx context.Context, in *GetVariantStatsRequest, opts ...grpc.CallOption) (*GetVariantStatsResponse, error) {
+	out := new(GetVariantStatsResponse)
+	err := c.cc.Invoke(ctx, Shortener_GetVariantStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GetQRCode(ctx context.Context, in *GetQRCodeRequest, opts ...grpc.CallOption) (*GetQRCodeResponse, error) {
+	out := new(GetQRCodeResponse)
+	err := c.cc.Invoke(ctx, Shortener_GetQRCode_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) UpdateURLMeta(ctx context.Context, in *UpdateURLMetaRequest, opts ...grpc.CallOption) (*URL, error) {
+	out := new(URL)
+	err := c.cc.Invoke(ctx, Shortener_UpdateURLMeta_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) ImportURLs(ctx context.Context, opts ...grpc.CallOption) (Shortener_ImportURLsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Shortener_ServiceDesc.Streams[0], Shortener_ImportURLs_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shortenerImportURLsClient{stream}
+	return x, nil
+}
+
+type Shortener_ImportURLsClient interface {
+	Send(*ImportURLsRequest) error
+	CloseAndRecv() (*ImportURLsResponse, error)
+	grpc.ClientStream
+}
+
+type shortenerImportURLsClient struct {
+	grpc.ClientStream
+}
+
+func (x *shortenerImportURLsClient) Send(m *ImportURLsRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *shortenerImportURLsClient) CloseAndRecv() (*ImportURLsResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ImportURLsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *shortenerClient) ExportURLs(ctx context.Context, in *ExportURLsRequest, opts ...grpc.CallOption) (Shortener_ExportURLsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Shortener_ServiceDesc.Streams[1], Shortener_ExportURLs_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shortenerExportURLsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Shortener_ExportURLsClient interface {
+	Recv() (*LinkRecord, error)
+	grpc.ClientStream
+}
+
+type shortenerExportURLsClient struct {
+	grpc.ClientStream
+}
+
+func (x *shortenerExportURLsClient) Recv() (*LinkRecord, error) {
+	m := new(LinkRecord)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *shortenerClient) Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*AuthResponse, error) {
+	out := new(AuthResponse)
+	err := c.cc.Invoke(ctx, Shortener_Register_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*AuthResponse, error) {
+	out := new(AuthResponse)
+	err := c.cc.Invoke(ctx, Shortener_Login_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) Anonymous(ctx context.Context, in *AnonymousRequest, opts ...grpc.CallOption) (*AuthResponse, error) {
+	out := new(AuthResponse)
+	err := c.cc.Invoke(ctx, Shortener_Anonymous_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) Logout(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutResponse, error) {
+	out := new(LogoutResponse)
+	err := c.cc.Invoke(ctx, Shortener_Logout_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) CreateWorkspace(ctx context.Context, in *CreateWorkspaceRequest, opts ...grpc.CallOption) (*Workspace, error) {
+	out := new(Workspace)
+	err := c.cc.Invoke(ctx, Shortener_CreateWorkspace_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) ListWorkspaces(ctx context.Context, in *ListWorkspacesRequest, opts ...grpc.CallOption) (*ListWorkspacesResponse, error) {
+	out := new(ListWorkspacesResponse)
+	err := c.cc.Invoke(ctx, Shortener_ListWorkspaces_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GetWorkspace(ctx context.Context, in *GetWorkspaceRequest, opts ...grpc.CallOption) (*Workspace, error) {
+	out := new(Workspace)
+	err := c.cc.Invoke(ctx, Shortener_GetWorkspace_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) SetWorkspaceMember(ctx context.Context, in *SetWorkspaceMemberRequest, opts ...grpc.CallOption) (*WorkspaceMember, error) {
+	out := new(WorkspaceMember)
+	err := c.cc.Invoke(ctx, Shortener_SetWorkspaceMember_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) RemoveWorkspaceMember(ctx context.Context, in *RemoveWorkspaceMemberRequest, opts ...grpc.CallOption) (*RemoveWorkspaceMemberResponse, error) {
+	out := new(RemoveWorkspaceMemberResponse)
+	err := c.cc.Invoke(ctx, Shortener_RemoveWorkspaceMember_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) OfferTransfer(ctx context.Context, in *OfferTransferRequest, opts ...grpc.CallOption) (*Transfer, error) {
+	out := new(Transfer)
+	err := c.cc.Invoke(ctx, Shortener_OfferTransfer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) ListTransfers(ctx context.Context, in *ListTransfersRequest, opts ...grpc.CallOption) (*ListTransfersResponse, error) {
+	out := new(ListTransfersResponse)
+	err := c.cc.Invoke(ctx, Shortener_ListTransfers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) AcceptTransfer(ctx context.Context, in *AcceptTransferRequest, opts ...grpc.CallOption) (*AuditEvent, error) {
+	out := new(AuditEvent)
+	err := c.cc.Invoke(ctx, Shortener_AcceptTransfer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) DeclineTransfer(ctx context.Context, in *DeclineTransferRequest, opts ...grpc.CallOption) (*DeclineTransferResponse, error) {
+	out := new(DeclineTransferResponse)
+	err := c.cc.Invoke(ctx, Shortener_DeclineTransfer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) ForceTransfer(ctx context.Context, in *ForceTransferRequest, opts ...grpc.CallOption) (*AuditEvent, error) {
+	out := new(AuditEvent)
+	err := c.cc.Invoke(ctx, Shortener_ForceTransfer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error) {
+	out := new(ListAuditEventsResponse)
+	err := c.cc.Invoke(ctx, Shortener_ListAuditEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GetUserQuota(ctx context.Context, in *GetUserQuotaRequest, opts ...grpc.CallOption) (*Quota, error) {
+	out := new(Quota)
+	err := c.cc.Invoke(ctx, Shortener_GetUserQuota_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GetQuota(ctx context.Context, in *GetQuotaRequest, opts ...grpc.CallOption) (*Quota, error) {
+	out := new(Quota)
+	err := c.cc.Invoke(ctx, Shortener_GetQuota_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) SetQuota(ctx context.Context, in *SetQuotaRequest, opts ...grpc.CallOption) (*Quota, error) {
+	out := new(Quota)
+	err := c.cc.Invoke(ctx, Shortener_SetQuota_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) ResetQuota(ctx context.Context, in *ResetQuotaRequest, opts ...grpc.CallOption) (*ResetQuotaResponse, error) {
+	out := new(ResetQuotaResponse)
+	err := c.cc.Invoke(ctx, Shortener_ResetQuota_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) ListQuotaTiers(ctx context.Context, in *ListQuotaTiersRequest, opts ...grpc.CallOption) (*ListQuotaTiersResponse, error) {
+	out := new(ListQuotaTiersResponse)
+	err := c.cc.Invoke(ctx, Shortener_ListQuotaTiers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ShortenerServer is the server API for Shortener service.
 // All implementations must embed UnimplementedShortenerServer
 // for forward compatibility
@@ -112,6 +435,31 @@ type ShortenerServer interface {
 	DeleteBatch(context.Context, *DeleteBatchRequest) (*DeleteBatchResponse, error)
 	GetAll(context.Context, *GetAllRequest) (*GetAllResponse, error)
 	Stats(context.Context, *StatsRequest) (*StatsResponse, error)
+	GetVariantStats(context.Context, *GetVariantStatsRequest) (*GetVariantStatsResponse, error)
+	GetQRCode(context.Context, *GetQRCodeRequest) (*GetQRCodeResponse, error)
+	UpdateURLMeta(context.Context, *UpdateURLMetaRequest) (*URL, error)
+	ImportURLs(Shortener_ImportURLsServer) error
+	ExportURLs(*ExportURLsRequest, Shortener_ExportURLsServer) error
+	Register(context.Context, *RegisterRequest) (*AuthResponse, error)
+	Login(context.Context, *LoginRequest) (*AuthResponse, error)
+	Anonymous(context.Context, *AnonymousRequest) (*AuthResponse, error)
+	Logout(context.Context, *LogoutRequest) (*LogoutResponse, error)
+	CreateWorkspace(context.Context, *CreateWorkspaceRequest) (*Workspace, error)
+	ListWorkspaces(context.Context, *ListWorkspacesRequest) (*ListWorkspacesResponse, error)
+	GetWorkspace(context.Context, *GetWorkspaceRequest) (*Workspace, error)
+	SetWorkspaceMember(context.Context, *SetWorkspaceMemberRequest) (*WorkspaceMember, error)
+	RemoveWorkspaceMember(context.Context, *RemoveWorkspaceMemberRequest) (*RemoveWorkspaceMemberResponse, error)
+	OfferTransfer(context.Context, *OfferTransferRequest) (*Transfer, error)
+	ListTransfers(context.Context, *ListTransfersRequest) (*ListTransfersResponse, error)
+	AcceptTransfer(context.Context, *AcceptTransferRequest) (*AuditEvent, error)
+	DeclineTransfer(context.Context, *DeclineTransferRequest) (*DeclineTransferResponse, error)
+	ForceTransfer(context.Context, *ForceTransferRequest) (*AuditEvent, error)
+	ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error)
+	GetUserQuota(context.Context, *GetUserQuotaRequest) (*Quota, error)
+	GetQuota(context.Context, *GetQuotaRequest) (*Quota, error)
+	SetQuota(context.Context, *SetQuotaRequest) (*Quota, error)
+	ResetQuota(context.Context, *ResetQuotaRequest) (*ResetQuotaResponse, error)
+	ListQuotaTiers(context.Context, *ListQuotaTiersRequest) (*ListQuotaTiersResponse, error)
 	mustEmbedUnimplementedShortenerServer()
 }
 
@@ -137,6 +485,81 @@ func (UnimplementedShortenerServer) GetAll(context.Context, *GetAllRequest) (*Ge
 func (UnimplementedShortenerServer) Stats(context.Context, *StatsRequest) (*StatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
 }
+func (UnimplementedShortenerServer) GetVariantStats(context.Context, *GetVariantStatsRequest) (*GetVariantStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVariantStats not implemented")
+}
+func (UnimplementedShortenerServer) GetQRCode(context.Context, *GetQRCodeRequest) (*GetQRCodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQRCode not implemented")
+}
+func (UnimplementedShortenerServer) UpdateURLMeta(context.Context, *UpdateURLMetaRequest) (*URL, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateURLMeta not implemented")
+}
+func (UnimplementedShortenerServer) ImportURLs(Shortener_ImportURLsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ImportURLs not implemented")
+}
+func (UnimplementedShortenerServer) ExportURLs(*ExportURLsRequest, Shortener_ExportURLsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExportURLs not implemented")
+}
+func (UnimplementedShortenerServer) Register(context.Context, *RegisterRequest) (*AuthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedShortenerServer) Login(context.Context, *LoginRequest) (*AuthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (UnimplementedShortenerServer) Anonymous(context.Context, *AnonymousRequest) (*AuthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Anonymous not implemented")
+}
+func (UnimplementedShortenerServer) Logout(context.Context, *LogoutRequest) (*LogoutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Logout not implemented")
+}
+func (UnimplementedShortenerServer) CreateWorkspace(context.Context, *CreateWorkspaceRequest) (*Workspace, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateWorkspace not implemented")
+}
+func (UnimplementedShortenerServer) ListWorkspaces(context.Context, *ListWorkspacesRequest) (*ListWorkspacesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWorkspaces not implemented")
+}
+func (UnimplementedShortenerServer) GetWorkspace(context.Context, *GetWorkspaceRequest) (*Workspace, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWorkspace not implemented")
+}
+func (UnimplementedShortenerServer) SetWorkspaceMember(context.Context, *SetWorkspaceMemberRequest) (*WorkspaceMember, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetWorkspaceMember not implemented")
+}
+func (UnimplementedShortenerServer) RemoveWorkspaceMember(context.Context, *RemoveWorkspaceMemberRequest) (*RemoveWorkspaceMemberResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveWorkspaceMember not implemented")
+}
+func (UnimplementedShortenerServer) OfferTransfer(context.Context, *OfferTransferRequest) (*Transfer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method OfferTransfer not implemented")
+}
+func (UnimplementedShortenerServer) ListTransfers(context.Context, *ListTransfersRequest) (*ListTransfersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTransfers not implemented")
+}
+func (UnimplementedShortenerServer) AcceptTransfer(context.Context, *AcceptTransferRequest) (*AuditEvent, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcceptTransfer not implemented")
+}
+func (UnimplementedShortenerServer) DeclineTransfer(context.Context, *DeclineTransferRequest) (*DeclineTransferResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeclineTransfer not implemented")
+}
+func (UnimplementedShortenerServer) ForceTransfer(context.Context, *ForceTransferRequest) (*AuditEvent, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ForceTransfer not implemented")
+}
+func (UnimplementedShortenerServer) ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAuditEvents not implemented")
+}
+func (UnimplementedShortenerServer) GetUserQuota(context.Context, *GetUserQuotaRequest) (*Quota, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserQuota not implemented")
+}
+func (UnimplementedShortenerServer) GetQuota(context.Context, *GetQuotaRequest) (*Quota, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQuota not implemented")
+}
+func (UnimplementedShortenerServer) SetQuota(context.Context, *SetQuotaRequest) (*Quota, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetQuota not implemented")
+}
+func (UnimplementedShortenerServer) ResetQuota(context.Context, *ResetQuotaRequest) (*ResetQuotaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetQuota not implemented")
+}
+func (UnimplementedShortenerServer) ListQuotaTiers(context.Context, *ListQuotaTiersRequest) (*ListQuotaTiersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListQuotaTiers not implemented")
+}
 func (UnimplementedShortenerServer) mustEmbedUnimplementedShortenerServer() {}
 
 // UnsafeShortenerServer may be embedded to opt out of forward compatibility for this service.
@@ -258,38 +681,602 @@ func _Shortener_Stats_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
-// Shortener_ServiceDesc is the grpc.ServiceDesc for Shortener service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var Shortener_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "shorty.shortener",
-	HandlerType: (*ShortenerServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "Resolve",
-			Handler:    _Shortener_Resolve_Handler,
-		},
-		{
-			MethodName: "Shorten",
-			Handler:    _Shortener_Shorten_Handler,
-		},
-		{
-			MethodName: "ShortenBatch",
-			Handler:    _Shortener_ShortenBatch_Handler,
-		},
-		{
-			MethodName: "DeleteBatch",
-			Handler:    _Shortener_DeleteBatch_Handler,
-		},
-		{
-			MethodName: "GetAll",
-			Handler:    _Shortener_GetAll_Handler,
-		},
-		{
-			MethodName: "Stats",
-			Handler:    _Shortener_Stats_Handler,
+func _Shortener_GetVariantStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVariantStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetVariantStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetVariantStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetVariantStats(ctx, req.(*GetVariantStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_GetQRCode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetQRCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetQRCode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetQRCode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetQRCode(ctx, req.(*GetQRCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_UpdateURLMeta_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateURLMetaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).UpdateURLMeta(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_UpdateURLMeta_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).UpdateURLMeta(ctx, req.(*UpdateURLMetaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_ImportURLs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ShortenerServer).ImportURLs(&shortenerImportURLsServer{stream})
+}
+
+type Shortener_ImportURLsServer interface {
+	SendAndClose(*ImportURLsResponse) error
+	Recv() (*ImportURLsRequest, error)
+	grpc.ServerStream
+}
+
+type shortenerImportURLsServer struct {
+	grpc.ServerStream
+}
+
+func (x *shortenerImportURLsServer) SendAndClose(m *ImportURLsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *shortenerImportURLsServer) Recv() (*ImportURLsRequest, error) {
+	m := new(ImportURLsRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Shortener_ExportURLs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportURLsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShortenerServer).ExportURLs(m, &shortenerExportURLsServer{stream})
+}
+
+type Shortener_ExportURLsServer interface {
+	Send(*LinkRecord) error
+	grpc.ServerStream
+}
+
+type shortenerExportURLsServer struct {
+	grpc.ServerStream
+}
+
+func (x *shortenerExportURLsServer) Send(m *LinkRecord) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Shortener_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_Register_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).Register(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_Login_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).Login(ctx, req.(*LoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_Anonymous_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnonymousRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).Anonymous(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_Anonymous_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).Anonymous(ctx, req.(*AnonymousRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_Logout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).Logout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_Logout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).Logout(ctx, req.(*LogoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_CreateWorkspace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateWorkspaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).CreateWorkspace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_CreateWorkspace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).CreateWorkspace(ctx, req.(*CreateWorkspaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_ListWorkspaces_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWorkspacesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).ListWorkspaces(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_ListWorkspaces_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).ListWorkspaces(ctx, req.(*ListWorkspacesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_GetWorkspace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWorkspaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetWorkspace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetWorkspace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetWorkspace(ctx, req.(*GetWorkspaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_SetWorkspaceMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetWorkspaceMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).SetWorkspaceMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_SetWorkspaceMember_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).SetWorkspaceMember(ctx, req.(*SetWorkspaceMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_RemoveWorkspaceMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveWorkspaceMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).RemoveWorkspaceMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_RemoveWorkspaceMember_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).RemoveWorkspaceMember(ctx, req.(*RemoveWorkspaceMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_OfferTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OfferTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).OfferTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_OfferTransfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).OfferTransfer(ctx, req.(*OfferTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_ListTransfers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTransfersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).ListTransfers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_ListTransfers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).ListTransfers(ctx, req.(*ListTransfersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_AcceptTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcceptTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).AcceptTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_AcceptTransfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).AcceptTransfer(ctx, req.(*AcceptTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_DeclineTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeclineTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).DeclineTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_DeclineTransfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).DeclineTransfer(ctx, req.(*DeclineTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_ForceTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForceTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).ForceTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_ForceTransfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).ForceTransfer(ctx, req.(*ForceTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_ListAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAuditEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).ListAuditEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_ListAuditEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).ListAuditEvents(ctx, req.(*ListAuditEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_GetUserQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetUserQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetUserQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetUserQuota(ctx, req.(*GetUserQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_GetQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetQuota(ctx, req.(*GetQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_SetQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).SetQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_SetQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).SetQuota(ctx, req.(*SetQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_ResetQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).ResetQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_ResetQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).ResetQuota(ctx, req.(*ResetQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_ListQuotaTiers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListQuotaTiersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).ListQuotaTiers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_ListQuotaTiers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).ListQuotaTiers(ctx, req.(*ListQuotaTiersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Shortener_ServiceDesc is the grpc.ServiceDesc for Shortener service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Shortener_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "shorty.shortener",
+	HandlerType: (*ShortenerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Resolve",
+			Handler:    _Shortener_Resolve_Handler,
+		},
+		{
+			MethodName: "Shorten",
+			Handler:    _Shortener_Shorten_Handler,
+		},
+		{
+			MethodName: "ShortenBatch",
+			Handler:    _Shortener_ShortenBatch_Handler,
+		},
+		{
+			MethodName: "DeleteBatch",
+			Handler:    _Shortener_DeleteBatch_Handler,
+		},
+		{
+			MethodName: "GetAll",
+			Handler:    _Shortener_GetAll_Handler,
+		},
+		{
+			MethodName: "Stats",
+			Handler:    _Shortener_Stats_Handler,
+		},
+		{
+			MethodName: "GetVariantStats",
+			Handler:    _Shortener_GetVariantStats_Handler,
+		},
+		{
+			MethodName: "GetQRCode",
+			Handler:    _Shortener_GetQRCode_Handler,
+		},
+		{
+			MethodName: "UpdateURLMeta",
+			Handler:    _Shortener_UpdateURLMeta_Handler,
+		},
+		{
+			MethodName: "Register",
+			Handler:    _Shortener_Register_Handler,
+		},
+		{
+			MethodName: "Login",
+			Handler:    _Shortener_Login_Handler,
+		},
+		{
+			MethodName: "Anonymous",
+			Handler:    _Shortener_Anonymous_Handler,
+		},
+		{
+			MethodName: "Logout",
+			Handler:    _Shortener_Logout_Handler,
+		},
+		{
+			MethodName: "CreateWorkspace",
+			Handler:    _Shortener_CreateWorkspace_Handler,
+		},
+		{
+			MethodName: "ListWorkspaces",
+			Handler:    _Shortener_ListWorkspaces_Handler,
+		},
+		{
+			MethodName: "GetWorkspace",
+			Handler:    _Shortener_GetWorkspace_Handler,
+		},
+		{
+			MethodName: "SetWorkspaceMember",
+			Handler:    _Shortener_SetWorkspaceMember_Handler,
+		},
+		{
+			MethodName: "RemoveWorkspaceMember",
+			Handler:    _Shortener_RemoveWorkspaceMember_Handler,
+		},
+		{
+			MethodName: "OfferTransfer",
+			Handler:    _Shortener_OfferTransfer_Handler,
+		},
+		{
+			MethodName: "ListTransfers",
+			Handler:    _Shortener_ListTransfers_Handler,
+		},
+		{
+			MethodName: "AcceptTransfer",
+			Handler:    _Shortener_AcceptTransfer_Handler,
+		},
+		{
+			MethodName: "DeclineTransfer",
+			Handler:    _Shortener_DeclineTransfer_Handler,
+		},
+		{
+			MethodName: "ForceTransfer",
+			Handler:    _Shortener_ForceTransfer_Handler,
+		},
+		{
+			MethodName: "ListAuditEvents",
+			Handler:    _Shortener_ListAuditEvents_Handler,
+		},
+		{
+			MethodName: "GetUserQuota",
+			Handler:    _Shortener_GetUserQuota_Handler,
+		},
+		{
+			MethodName: "GetQuota",
+			Handler:    _Shortener_GetQuota_Handler,
+		},
+		{
+			MethodName: "SetQuota",
+			Handler:    _Shortener_SetQuota_Handler,
+		},
+		{
+			MethodName: "ResetQuota",
+			Handler:    _Shortener_ResetQuota_Handler,
+		},
+		{
+			MethodName: "ListQuotaTiers",
+			Handler:    _Shortener_ListQuotaTiers_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ImportURLs",
+			Handler:       _Shortener_ImportURLs_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ExportURLs",
+			Handler:       _Shortener_ExportURLs_Handler,
+			ServerStreams: true,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/grpc/protobuf/shorty.proto",
 }
@@ -1,18 +1,28 @@
 // Package auth implements authentication middleware.
 //
 // It uses imported authenticator to handle jwt cookies.
+// If api key authenticator is set, api keys passed in Authorization header
+// with Bearer scheme are accepted instead of cookies.
 // User object is propagated via request context.
 //
 // Middleware guarantees that user object will always exist in context,
 // either new or parsed from cookie.
+//
+// In strict mode new users are not created for mutating requests, i.e. requests
+// with methods other than GET and HEAD. Such request without valid credentials
+// is rejected with 401 status and reason in body, unless its path is exempt.
 package auth
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"slices"
 
 	"github.com/adwski/shorty/internal/user"
 
 	authorizer "github.com/adwski/shorty/internal/auth"
+	"github.com/adwski/shorty/internal/services/apikey"
 	"github.com/adwski/shorty/internal/session"
 	"go.uber.org/zap"
 )
@@ -21,9 +31,17 @@ const (
 	sessionCookieName = "shortySessID"
 )
 
+// KeyAuthenticator authenticates api keys.
+type KeyAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (*user.User, error)
+}
+
 // Middleware is authentication middleware.
 type Middleware struct {
 	*authorizer.Auth
+	keys    KeyAuthenticator
+	exempt  []string
+	strict  bool
 	handler http.Handler
 	log     *zap.Logger
 }
@@ -41,8 +59,23 @@ func NewFromAuthorizer(logger *zap.Logger, a *authorizer.Auth) *Middleware {
 	}
 }
 
+// WithKeys enables api key authentication.
+func (mw *Middleware) WithKeys(keys KeyAuthenticator) *Middleware {
+	mw.keys = keys
+	return mw
+}
+
+// WithStrict enables strict mode for mutating requests.
+// Requests to exempt paths are served as in normal mode.
+func (mw *Middleware) WithStrict(exempt []string) *Middleware {
+	mw.strict = true
+	mw.exempt = exempt
+	return mw
+}
+
 // ServeHTTP implements auth flow for incoming request.
-// If jwt cookie is present and valid, user is added to request context.
+// If jwt cookie is present and valid, user is added to request context
+// and cookie is re-issued if it expires soon.
 // In other cases new user is generated.
 func (mw *Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	requestID, ok := session.GetRequestID(r.Context())
@@ -53,6 +86,47 @@ func (mw *Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	logf := mw.log.With(zap.String("id", requestID))
 
+	if token, ok := authorizer.BearerToken(r.Header.Get("Authorization")); ok && mw.keys != nil {
+		u, err := mw.keys.Authenticate(r.Context(), token)
+		if err != nil {
+			if errors.Is(err, apikey.ErrInvalidKey) {
+				logf.Debug("invalid api key")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			logf.Error("cannot authenticate api key", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		mw.handler.ServeHTTP(w, r.WithContext(session.SetUserContext(r.Context(), u)))
+		return
+	}
+
+	if mw.strict && isMutating(r.Method) && !slices.Contains(mw.exempt, r.URL.Path) {
+		u, reason, err := mw.parseUserFromRequest(r)
+		if err != nil {
+			logf.Error("cannot check user session", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if u == nil {
+			logf.Debug("unauthenticated request rejected", zap.String("reason", reason))
+			http.Error(w, "unauthenticated: "+reason, http.StatusUnauthorized)
+			return
+		}
+		token, err := mw.RefreshToken(u)
+		if err != nil {
+			logf.Error("cannot refresh user session", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if token != "" {
+			SetCookie(w, token)
+		}
+		mw.handler.ServeHTTP(w, r.WithContext(session.SetUserContext(r.Context(), u)))
+		return
+	}
+
 	u, token, err := mw.createOrParseUserFromRequest(r)
 	if err != nil {
 		logf.Error("cannot create user session", zap.Error(err))
@@ -60,27 +134,66 @@ func (mw *Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if token != "" {
-		c := &http.Cookie{
-			Name:  sessionCookieName,
-			Value: token,
-		}
-		w.Header().Set("Set-Cookie", c.String())
+		SetCookie(w, token)
 	}
 
 	// Call next handler with user context
 	mw.handler.ServeHTTP(w, r.WithContext(session.SetUserContext(r.Context(), u)))
 }
 
+// parseUserFromRequest parses user from session cookie.
+// If user cannot be parsed, nil user is returned with reason.
+// Error is returned only if session denylist cannot be checked.
+func (mw *Middleware) parseUserFromRequest(r *http.Request) (*user.User, string, error) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil, "session cookie is missing", nil
+	}
+	u, err := mw.ParseUserFromJWTString(r.Context(), cookie.Value)
+	if err != nil {
+		if errors.Is(err, authorizer.ErrDenylist) {
+			return nil, "", err //nolint:wrapcheck // err is checked in func above
+		}
+		return nil, err.Error(), nil
+	}
+	return u, "", nil
+}
+
 func (mw *Middleware) createOrParseUserFromRequest(r *http.Request) (*user.User, string, error) {
 	cookie, err := r.Cookie(sessionCookieName)
 	if err != nil {
 		return mw.CreateUserAndToken() //nolint:wrapcheck // err is checked in func above
 	}
-	return mw.CreateOrParseUserFromJWTString(cookie.Value) //nolint:wrapcheck // err is checked in func above
+	return mw.CreateOrParseUserFromJWTString(r.Context(), cookie.Value) //nolint:wrapcheck // err is checked in func above
 }
 
-// HandlerFunc sets upstream middleware handler.
+// SetCookie sets session cookie with provided token, replacing cookie set earlier during request.
+func SetCookie(w http.ResponseWriter, token string) {
+	c := &http.Cookie{
+		Name:  sessionCookieName,
+		Value: token,
+	}
+	w.Header().Set("Set-Cookie", c.String())
+}
+
+// ClearCookie removes session cookie, replacing cookie set earlier during request.
+func ClearCookie(w http.ResponseWriter) {
+	c := &http.Cookie{
+		Name:   sessionCookieName,
+		MaxAge: -1,
+	}
+	w.Header().Set("Set-Cookie", c.String())
+}
+
+// HandlerFunc returns authenticating handler with h as upstream handler.
+// Middleware can be chained to several routes, each route gets its own handler.
 func (mw *Middleware) HandlerFunc(h http.Handler) http.Handler {
-	mw.handler = h
-	return mw
+	next := *mw
+	next.handler = h
+	return &next
+}
+
+// isMutating checks if request with given method can change user data.
+func isMutating(method string) bool {
+	return method != http.MethodGet && method != http.MethodHead
 }
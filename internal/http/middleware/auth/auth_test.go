@@ -1,12 +1,16 @@
 package auth
 
 import (
+	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/adwski/shorty/internal/services/apikey"
 	"github.com/adwski/shorty/internal/session"
+	"github.com/adwski/shorty/internal/storage/memory"
 	"github.com/adwski/shorty/internal/user"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
@@ -73,9 +77,9 @@ func TestMiddleware(t *testing.T) {
 			}
 
 			s := &stub{}
-			mw.HandlerFunc(s)
+			handler := mw.HandlerFunc(s)
 			w := httptest.NewRecorder()
-			mw.ServeHTTP(w, r)
+			handler.ServeHTTP(w, r)
 			resp := w.Result()
 			require.NoError(t, resp.Body.Close())
 
@@ -99,3 +103,125 @@ func TestMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestMiddleware_APIKey(t *testing.T) {
+	keys := apikey.New(&apikey.Config{Storage: memory.New(), Logger: zap.NewNop()})
+	owner := user.NewWithID("tdGk2USqTvWW8jyz7HnhlA")
+	key, err := keys.Create(context.Background(), owner, "ci", []string{apikey.ScopeRead})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{name: "valid key", header: "Bearer " + key.Key, status: http.StatusOK},
+		{name: "invalid key", header: "Bearer " + key.Key + "x", status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := New(zap.NewNop(), "super-secret").WithKeys(keys)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r = r.WithContext(session.SetRequestID(r.Context(), "test-request"))
+			r.Header.Set("Authorization", tt.header)
+
+			s := &stub{}
+			handler := mw.HandlerFunc(s)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			resp := w.Result()
+			require.NoError(t, resp.Body.Close())
+
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Empty(t, resp.Cookies())
+			if tt.status == http.StatusOK {
+				require.True(t, s.wasCalled)
+				assert.Equal(t, owner.ID, s.u.ID)
+				assert.True(t, s.u.HasScope(apikey.ScopeRead))
+				assert.False(t, s.u.HasScope(apikey.ScopeShorten))
+			} else {
+				assert.False(t, s.wasCalled)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Strict(t *testing.T) {
+	secret := "super-secret"
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp":     jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+		"user_id": "tdGk2USqTvWW8jyz7HnhlA",
+	}).SignedString([]byte(secret))
+	require.NoError(t, err)
+	valid, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp":     jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		"user_id": "tdGk2USqTvWW8jyz7HnhlA",
+	}).SignedString([]byte(secret))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		cookie string
+		status int
+		reason string
+		minted bool
+	}{
+		{name: "missing cookie", path: "/api/shorten", status: http.StatusUnauthorized, reason: "missing"},
+		{name: "expired cookie", path: "/api/shorten", cookie: expired, status: http.StatusUnauthorized, reason: "expired"},
+		{name: "invalid cookie", path: "/api/shorten", cookie: "qwe", status: http.StatusUnauthorized, reason: "malformed"},
+		{name: "valid cookie", path: "/api/shorten", cookie: valid, status: http.StatusOK},
+		{name: "exempt path", path: "/api/user/anonymous", status: http.StatusOK, minted: true},
+		{name: "read request", method: http.MethodGet, path: "/api/user/urls", status: http.StatusOK, minted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := New(zap.NewNop(), secret).WithStrict([]string{"/api/user/anonymous"})
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			r := httptest.NewRequest(method, tt.path, nil)
+			r = r.WithContext(session.SetRequestID(r.Context(), "test-request"))
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: "shortySessID", Value: tt.cookie})
+			}
+
+			s := &stub{}
+			handler := mw.HandlerFunc(s)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			resp := w.Result()
+			body, errB := io.ReadAll(resp.Body)
+			require.NoError(t, errB)
+			require.NoError(t, resp.Body.Close())
+
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Contains(t, string(body), tt.reason)
+			assert.Equal(t, tt.status == http.StatusOK, s.wasCalled)
+			assert.Equal(t, tt.minted, len(resp.Cookies()) == 1)
+		})
+	}
+}
+
+func TestMiddleware_SeveralHandlers(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	require.NoError(t, err)
+	mw := New(logger, "super-secret")
+
+	first, second := &stub{}, &stub{}
+	firstHandler := mw.HandlerFunc(first)
+	secondHandler := mw.HandlerFunc(second)
+
+	for _, h := range []http.Handler{firstHandler, secondHandler} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r = r.WithContext(session.SetRequestID(r.Context(), "test-request"))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		require.NoError(t, w.Result().Body.Close())
+	}
+
+	assert.True(t, first.wasCalled)
+	assert.True(t, second.wasCalled)
+}
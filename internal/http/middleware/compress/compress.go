@@ -61,6 +61,11 @@ func (rw *rwWrapper) Write(b []byte) (n int, err error) {
 	return
 }
 
+// Unwrap returns original response writer, it's used by http.ResponseController.
+func (rw *rwWrapper) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func (rw *rwWrapper) needCompression() bool {
 	switch rw.Header().Get("Content-Type") {
 	case "application/json",
@@ -44,10 +44,14 @@ func NewFromFilter(f *filter.Filter) *Middleware {
 	}
 }
 
-// HandlerFunc sets upstream middleware handler.
+// HandlerFunc returns filtering handler with h as upstream handler.
+// Middleware can be chained to several routes, each route gets its own handler.
 func (mw *Middleware) HandlerFunc(h http.Handler) http.Handler {
-	mw.handler = h
-	return mw
+	return &Middleware{
+		Filter:  mw.Filter,
+		handler: h,
+		block:   mw.block,
+	}
 }
 
 // ServeHTTP matches incoming request with filter
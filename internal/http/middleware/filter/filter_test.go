@@ -224,7 +224,7 @@ func TestMiddleware(t *testing.T) {
 
 			// chain stub handler
 			stubHandler := &stub{}
-			mw.HandlerFunc(stubHandler)
+			handler := mw.HandlerFunc(stubHandler)
 
 			// create request
 			r := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -240,7 +240,7 @@ func TestMiddleware(t *testing.T) {
 
 			// exec request
 			w := httptest.NewRecorder()
-			mw.ServeHTTP(w, r)
+			handler.ServeHTTP(w, r)
 			resp := w.Result()
 			_ = resp.Body.Close()
 
@@ -80,6 +80,11 @@ func (rw *rwWrapper) WriteHeader(statusCode int) {
 	rw.status = statusCode
 }
 
+// Unwrap returns original response writer, it's used by http.ResponseController.
+func (rw *rwWrapper) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // HandlerFunc sets upstream middleware handler.
 func (mw *Middleware) HandlerFunc(h http.Handler) http.Handler {
 	mw.handler = h
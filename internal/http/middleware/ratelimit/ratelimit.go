@@ -0,0 +1,52 @@
+// Package ratelimit implements rate limiting middleware.
+//
+// When included in chain after auth middleware, it limits requests
+// of authenticated users by user id and requests of other clients
+// by ip address. Client ip is determined with the same header trust rules
+// as in ip filter. Routes are matched as "<METHOD> <path>".
+//
+// If request is limited, 429 response with Retry-After header is sent back.
+package ratelimit
+
+import (
+	"net/http"
+
+	"github.com/adwski/shorty/internal/filter"
+	"github.com/adwski/shorty/internal/ratelimit"
+	"github.com/adwski/shorty/internal/session"
+)
+
+// Middleware is rate limiting middleware.
+type Middleware struct {
+	limiter *ratelimit.Limiter
+	filter  *filter.Filter
+}
+
+// New creates rate limiting middleware. If limiter is nil, requests are not limited.
+func New(limiter *ratelimit.Limiter, f *filter.Filter) *Middleware {
+	return &Middleware{
+		limiter: limiter,
+		filter:  f,
+	}
+}
+
+// HandlerFunc returns rate limiting handler with h as upstream handler.
+func (mw *Middleware) HandlerFunc(h http.Handler) http.Handler {
+	if mw.limiter == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, _ := session.GetUserFromContext(r.Context())
+		client := ratelimit.ClientKey(u, mw.filter.ClientIP(
+			r.RemoteAddr,
+			r.Header.Get("X-Real-IP"),
+			r.Header.Get("X-Forwarded-For"),
+		))
+		if wait := mw.limiter.Allow(r.Context(), r.Method+" "+r.URL.Path, client); wait > 0 {
+			w.Header().Set("Retry-After", ratelimit.RetryAfter(wait))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adwski/shorty/internal/filter"
+	"github.com/adwski/shorty/internal/ratelimit"
+	"github.com/adwski/shorty/internal/session"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMiddleware(t *testing.T) {
+	logger := zap.NewNop()
+	// requests come from trusted proxy, client ip is taken from header
+	f, err := filter.New(&filter.Config{Logger: logger, Subnets: "192.0.2.0/24", TrustXRealIP: true})
+	require.NoError(t, err)
+	rules, err := ratelimit.ParseRules("POST /=1/1m")
+	require.NoError(t, err)
+	mw := New(ratelimit.New(&ratelimit.Config{Logger: logger, Rules: rules}), f)
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := mw.HandlerFunc(upstream)
+
+	u, err := user.New()
+	require.NoError(t, err)
+	do := func(method, realIP string, u *user.User) *http.Response {
+		r := httptest.NewRequest(method, "/", nil)
+		r.Header.Set("X-Real-IP", realIP)
+		if u != nil {
+			r = r.WithContext(session.SetUserContext(r.Context(), u))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		res := w.Result()
+		_ = res.Body.Close()
+		return res
+	}
+
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "1.1.1.1", nil).StatusCode)
+	res := do(http.MethodPost, "1.1.1.1", nil)
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.Equal(t, "60", res.Header.Get("Retry-After"))
+
+	// new users are limited by ip, existing users by user id
+	assert.Equal(t, http.StatusTooManyRequests, do(http.MethodPost, "1.1.1.1", u).StatusCode)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "1.1.1.1", user.NewWithID(u.ID)).StatusCode)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "2.2.2.2", nil).StatusCode)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "1.1.1.1", nil).StatusCode)
+
+	// requests are not limited without limiter
+	handler = New(nil, f).HandlerFunc(upstream)
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, do(http.MethodPost, "1.1.1.1", nil).StatusCode)
+	}
+}
@@ -1,12 +1,107 @@
 // Package model contains http api related data types.
 package model
 
+import "github.com/adwski/shorty/internal/model"
+
 // ShortenRequest is single URL shorten request.
 type ShortenRequest struct {
-	URL string `json:"url"`
+	URL         string `json:"url"`
+	Redirect    int    `json:"redirect,omitempty"`
+	Passthrough bool   `json:"passthrough,omitempty"`
+
+	Targets  []model.Target  `json:"targets,omitempty"`
+	Variants []model.Variant `json:"variants,omitempty"`
+	Schedule *model.Schedule `json:"schedule,omitempty"`
+	Password string          `json:"password,omitempty"`
+	Title    string          `json:"title,omitempty"`
+	Tags     []string        `json:"tags,omitempty"`
+	Notes    string          `json:"notes,omitempty"`
+	Preview  bool            `json:"preview,omitempty"`
 }
 
 // ShortenResponse is a single URL shorten response.
 type ShortenResponse struct {
 	Result string `json:"result"`
 }
+
+// RestoreRequest is a storage restore request.
+type RestoreRequest struct {
+	// Name is snapshot name returned by backup.
+	Name string `json:"name"`
+	// Mode is either "merge" (default) or "replace".
+	Mode string `json:"mode,omitempty"`
+}
+
+// AccountRequest is account registration or login request.
+type AccountRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+	// Claim requests links of current anonymous user to be moved into account on login.
+	// Links are always claimed on registration.
+	Claim bool `json:"claim,omitempty"`
+}
+
+// AccountResponse is successful registration, login or anonymous session response.
+type AccountResponse struct {
+	UserID  string `json:"user_id"`
+	Login   string `json:"login,omitempty"`
+	Claimed int64  `json:"claimed"`
+}
+
+// APIKeyRequest is api key create or update request.
+// On update only provided fields are changed.
+type APIKeyRequest struct {
+	Name   *string  `json:"name,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// WorkspaceRequest is workspace create request.
+type WorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+// MemberRequest adds workspace member or changes its role.
+// Member is identified by account login if it's set, or by user id otherwise.
+type MemberRequest struct {
+	UserID string `json:"user_id,omitempty"`
+	Login  string `json:"login,omitempty"`
+	Role   string `json:"role"`
+}
+
+// TransferRequest offers personal links to another user.
+// Recipient is identified by account login if it's set, or by user id otherwise.
+type TransferRequest struct {
+	ToUserID string   `json:"to_user_id,omitempty"`
+	ToLogin  string   `json:"to_login,omitempty"`
+	Shorts   []string `json:"shorts"`
+}
+
+// ForcedTransferRequest moves personal links of user to another user without their consent.
+// All personal links of user are moved if shorts are not provided.
+type ForcedTransferRequest struct {
+	FromUserID string `json:"from_user_id"`
+	TransferRequest
+}
+
+// QuotaRequest assigns quota tier or custom limits to user.
+// Custom limits with zero values remove all limits of user.
+type QuotaRequest struct {
+	Tier     string `json:"tier,omitempty"`
+	MaxURLs  int    `json:"max_urls,omitempty"`
+	MaxBatch int    `json:"max_batch,omitempty"`
+}
+
+// TiersResponse lists configured quota tiers.
+type TiersResponse struct {
+	DefaultTier string       `json:"default_tier,omitempty"`
+	Tiers       []model.Tier `json:"tiers"`
+}
+
+// QuotaExceededResponse describes quota that was exceeded by request.
+type QuotaExceededResponse struct {
+	Error     string `json:"error"`
+	Quota     string `json:"quota"`
+	Limit     int    `json:"limit"`
+	Requested int    `json:"requested"`
+	Remaining int    `json:"remaining"`
+}
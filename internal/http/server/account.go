@@ -0,0 +1,140 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/adwski/shorty/internal/http/middleware/auth"
+	httpmodel "github.com/adwski/shorty/internal/http/model"
+	"github.com/adwski/shorty/internal/services/account"
+	"github.com/adwski/shorty/internal/session"
+	"go.uber.org/zap"
+)
+
+// Register creates user account. Links of current anonymous user are claimed into account
+// and session cookie is replaced with account session.
+func (srv *Server) Register(w http.ResponseWriter, r *http.Request) {
+	srv.handleAccount(w, r, http.StatusCreated, func(req *httpmodel.AccountRequest) (*account.Session, error) {
+		u, _ := session.GetUserFromContext(r.Context())
+		return srv.accountSvc.Register(r.Context(), u, req.Login, req.Password) //nolint:wrapcheck // checked by caller
+	})
+}
+
+// Login authenticates user account with password and replaces session cookie with account session.
+// Links of current anonymous user are claimed into account if requested.
+func (srv *Server) Login(w http.ResponseWriter, r *http.Request) {
+	srv.handleAccount(w, r, http.StatusOK, func(req *httpmodel.AccountRequest) (*account.Session, error) {
+		u, _ := session.GetUserFromContext(r.Context())
+		//nolint:wrapcheck // checked by caller
+		return srv.accountSvc.Login(r.Context(), u, req.Login, req.Password, req.Claim)
+	})
+}
+
+// Anonymous creates anonymous user and sets its session cookie.
+// It's the only way to get anonymous session when strict authentication is enabled.
+func (srv *Server) Anonymous(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID))
+
+	sess, err := srv.accountSvc.Anonymous(u)
+	logf.With(zap.Error(err)).Debug("anonymous called")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		logf.Error("cannot create anonymous user", zap.Error(err))
+		return
+	}
+	auth.SetCookie(w, sess.Token)
+	srv.writeJSON(w, logf, http.StatusCreated, &httpmodel.AccountResponse{UserID: sess.User.ID})
+}
+
+// Logout revokes current session and removes session cookie.
+// Tokens of session are rejected after logout, including ones issued before refresh.
+func (srv *Server) Logout(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	err = srv.accountSvc.Logout(r.Context(), u)
+	logf.With(zap.Error(err)).Debug("logout called")
+	if err != nil && !errors.Is(err, account.ErrNoSession) {
+		w.WriteHeader(http.StatusInternalServerError)
+		logf.Error("cannot revoke session", zap.Error(err))
+		return
+	}
+	auth.ClearCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (srv *Server) handleAccount(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	fn func(req *httpmodel.AccountRequest) (*account.Session, error),
+) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	if ct := r.Header.Get(headerNameContentType); ct != contentTypeJSON {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("incorrect Content-Type",
+			zap.String("expected", contentTypeJSON),
+			zap.String("got", ct))
+		return
+	}
+	body, err := readBody(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("cannot read body", zap.Error(err))
+		return
+	}
+	var req httpmodel.AccountRequest
+	if err = json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("cannot unmarshal account request", zap.Error(err))
+		return
+	}
+
+	sess, err := fn(&req)
+	logf.With(
+		zap.String("login", req.Login),
+		zap.Error(err),
+	).Debug("account request handled")
+	if err != nil {
+		switch {
+		case srv.writeQuotaExceeded(w, logf, err):
+		case errors.Is(err, account.ErrInvalidLogin),
+			errors.Is(err, account.ErrInvalidPassword):
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, account.ErrLoginTaken):
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, account.ErrInvalidCredentials):
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			logf.Error("account request failed", zap.Error(err))
+		}
+		return
+	}
+
+	auth.SetCookie(w, sess.Token)
+	srv.writeJSON(w, logf, status, &httpmodel.AccountResponse{
+		UserID:  sess.User.ID,
+		Login:   sess.User.Login,
+		Claimed: sess.Claimed,
+	})
+}
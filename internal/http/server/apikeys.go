@@ -0,0 +1,184 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	httpmodel "github.com/adwski/shorty/internal/http/model"
+	"github.com/adwski/shorty/internal/services/apikey"
+	"github.com/adwski/shorty/internal/session"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// CreateAPIKey creates api key of user. Key secret is returned only in this response.
+func (srv *Server) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	req, ok := readAPIKeyRequest(w, r, logf)
+	if !ok {
+		return
+	}
+	var name string
+	if req.Name != nil {
+		name = *req.Name
+	}
+	key, err := srv.apikeySvc.Create(r.Context(), u, name, req.Scopes)
+	logf.With(zap.Error(err)).Debug("createAPIKey called")
+	if err != nil {
+		srv.writeAPIKeyError(w, logf, err)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusCreated, key)
+}
+
+// ListAPIKeys returns api keys of user without secrets.
+func (srv *Server) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	keys, err := srv.apikeySvc.List(r.Context(), u)
+	logf.With(zap.Int("keys", len(keys)), zap.Error(err)).Debug("listAPIKeys called")
+	if err != nil {
+		srv.writeAPIKeyError(w, logf, err)
+		return
+	}
+	if len(keys) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusOK, keys)
+}
+
+// UpdateAPIKey changes name and scopes of user api key.
+func (srv *Server) UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	req, ok := readAPIKeyRequest(w, r, logf)
+	if !ok {
+		return
+	}
+	key, err := srv.apikeySvc.Update(r.Context(), u, chi.URLParam(r, "key"), req.Name, req.Scopes)
+	logf.With(zap.Error(err)).Debug("updateAPIKey called")
+	if err != nil {
+		srv.writeAPIKeyError(w, logf, err)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusOK, key)
+}
+
+// RevokeAPIKey deletes user api key.
+func (srv *Server) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	err = srv.apikeySvc.Revoke(r.Context(), u, chi.URLParam(r, "key"))
+	logf.With(zap.Error(err)).Debug("revokeAPIKey called")
+	if err != nil {
+		srv.writeAPIKeyError(w, logf, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireScope allows request only if user has scope. Session users have all scopes.
+func (srv *Server) requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u, ok := session.GetUserFromContext(r.Context())
+			if !ok || !u.HasScope(scope) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// sessionOnly allows request only for session users, api keys are denied.
+func (srv *Server) sessionOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, ok := session.GetUserFromContext(r.Context()); !ok || u.IsAPIKey() {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func readAPIKeyRequest(w http.ResponseWriter, r *http.Request, logf *zap.Logger) (*httpmodel.APIKeyRequest, bool) {
+	if ct := r.Header.Get(headerNameContentType); ct != contentTypeJSON {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("incorrect Content-Type",
+			zap.String("expected", contentTypeJSON),
+			zap.String("got", ct))
+		return nil, false
+	}
+	body, err := readBody(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("cannot read body", zap.Error(err))
+		return nil, false
+	}
+	var req httpmodel.APIKeyRequest
+	if err = json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("cannot unmarshal api key request", zap.Error(err))
+		return nil, false
+	}
+	return &req, true
+}
+
+func (srv *Server) writeAPIKeyError(w http.ResponseWriter, logf *zap.Logger, err error) {
+	switch {
+	case errors.Is(err, apikey.ErrUnauthorized):
+		w.WriteHeader(http.StatusUnauthorized)
+	case errors.Is(err, apikey.ErrInvalidName),
+		errors.Is(err, apikey.ErrInvalidScope):
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, apikey.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, apikey.ErrTooManyKeys):
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		logf.Error("api key request failed", zap.Error(err))
+	}
+}
+
+func (srv *Server) writeJSON(w http.ResponseWriter, logf *zap.Logger, status int, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		logf.Error("cannot marshal response", zap.Error(err))
+		return
+	}
+	w.Header().Set(headerNameContentType, contentTypeJSON)
+	w.WriteHeader(status)
+	if _, err = w.Write(b); err != nil {
+		logf.Error("error while writing response body", zap.Error(err))
+	}
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	httpmodel "github.com/adwski/shorty/internal/http/model"
+	"github.com/adwski/shorty/internal/services/backup"
+	"github.com/adwski/shorty/internal/session"
+	"go.uber.org/zap"
+)
+
+const (
+	restoreModeMerge   = "merge"
+	restoreModeReplace = "replace"
+)
+
+// Backup creates storage snapshot in configured directory and responds with its description.
+func (srv *Server) Backup(w http.ResponseWriter, r *http.Request) {
+	reqID, ok := session.GetRequestID(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error("request id was not provided in context")
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID))
+
+	// Backup of large storage can take much longer than server timeouts.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		logf.Debug("cannot reset write deadline", zap.Error(err))
+	}
+	snapshot, err := srv.backupSvc.Backup(r.Context())
+	logf.With(
+		zap.Any("snapshot", snapshot),
+		zap.Error(err),
+	).Debug("backup called")
+	if err != nil {
+		if errors.Is(err, backup.ErrBusy) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		logf.Error("backup failed", zap.Error(err))
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusCreated, snapshot)
+}
+
+// Restore loads storage snapshot. Snapshot is merged into storage by default,
+// storage contents are replaced with snapshot if replace mode is requested.
+func (srv *Server) Restore(w http.ResponseWriter, r *http.Request) {
+	reqID, ok := session.GetRequestID(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error("request id was not provided in context")
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID))
+
+	if ct := r.Header.Get(headerNameContentType); ct != contentTypeJSON {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("incorrect Content-Type",
+			zap.String("expected", contentTypeJSON),
+			zap.String("got", ct))
+		return
+	}
+	body, err := readBody(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("cannot read body", zap.Error(err))
+		return
+	}
+	var req httpmodel.RestoreRequest
+	if err = json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("cannot unmarshal restore request", zap.Error(err))
+		return
+	}
+	if req.Mode != "" && req.Mode != restoreModeMerge && req.Mode != restoreModeReplace {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("invalid restore mode", zap.String("mode", req.Mode))
+		return
+	}
+
+	if err = http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		logf.Debug("cannot reset write deadline", zap.Error(err))
+	}
+	snapshot, err := srv.backupSvc.Restore(r.Context(), req.Name, req.Mode == restoreModeReplace)
+	logf.With(
+		zap.String("name", req.Name),
+		zap.String("mode", req.Mode),
+		zap.Error(err),
+	).Debug("restore called")
+	if err != nil {
+		switch {
+		case errors.Is(err, backup.ErrInvalidName):
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, backup.ErrNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, backup.ErrBusy):
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, backup.ErrChecksumMismatch),
+			errors.Is(err, backup.ErrInvalidSnapshot):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			logf.Error("cannot restore corrupted snapshot", zap.Error(err))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			logf.Error("restore failed", zap.Error(err))
+		}
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusOK, snapshot)
+}
@@ -7,22 +7,31 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 
 	httpmodel "github.com/adwski/shorty/internal/http/model"
 	"github.com/adwski/shorty/internal/model"
 	"github.com/adwski/shorty/internal/services/resolver"
 	"github.com/adwski/shorty/internal/services/shortener"
 	"github.com/adwski/shorty/internal/session"
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
 const (
 	contentTypeJSON       = "application/json"
 	contentTypePlain      = "text/plain"
+	contentTypeHTML       = "text/html; charset=utf-8"
 	headerNameContentType = "Content-Type"
 
 	logFieldUserID = "userID"
+
+	visitorCookieName      = "shortyVisitor"
+	visitorCookieMaxAge    = 365 * 24 * 3600
+	visitorCookieMaxLength = 64
 )
 
 // ErrRequestCtx indicates error while getting info from request context.
@@ -40,6 +49,13 @@ func (srv *Server) Ping(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// JWKS returns public keys used to sign session tokens, so other services can verify them.
+// Key set is empty if tokens are signed with shared secret.
+func (srv *Server) JWKS(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Cache-Control", jwksCacheControl)
+	srv.writeJSON(w, srv.logger, http.StatusOK, srv.jwks)
+}
+
 // Stats returns storage statistics.
 func (srv *Server) Stats(w http.ResponseWriter, r *http.Request) {
 	reqID, ok := session.GetRequestID(r.Context())
@@ -69,37 +85,132 @@ func (srv *Server) Stats(w http.ResponseWriter, r *http.Request) {
 }
 
 // Resolve retrieves original URL of corresponding shortened URL.
+// For password protected URLs password form is served instead of redirect.
+// Preview page is served if it's requested with /{short}+ or ?preview=1
+// or if link forces it.
 func (srv *Server) Resolve(w http.ResponseWriter, r *http.Request) {
+	srv.resolve(w, r, "")
+}
+
+func (srv *Server) resolve(w http.ResponseWriter, r *http.Request, password string) {
 	reqID, ok := session.GetRequestID(r.Context())
 	if !ok {
 		w.WriteHeader(http.StatusInternalServerError)
 		srv.logger.Error("request id was not provided in context")
 		return
 	}
-	redirect, err := srv.resolverSvc.Resolve(r.Context(), r.URL.Path)
+	path, query, preview := previewRequest(r.URL)
+	redirect, err := srv.resolverSvc.Resolve(r.Context(), &resolver.Request{
+		Path:      path,
+		Query:     query,
+		UserAgent: r.UserAgent(),
+		VisitorID: getVisitorID(r),
+		ClientIP:  srv.clientIP(r),
+		Password:  password,
+		Preview:   preview,
+	})
 	srv.logger.With(
-		zap.String("redirect", redirect),
+		zap.Any("redirect", redirect),
 		zap.String("id", reqID),
 		zap.Error(err),
 	).Debug("resolve called")
 	if err != nil {
 		switch {
-		case errors.Is(err, resolver.ErrInvalidPath):
+		case errors.Is(err, resolver.ErrInvalidPath),
+			errors.Is(err, resolver.ErrInvalidQuery):
 			w.WriteHeader(http.StatusBadRequest)
 		case errors.Is(err, model.ErrNotFound):
 			w.WriteHeader(http.StatusNotFound)
 		case errors.Is(err, model.ErrDeleted):
 			w.WriteHeader(http.StatusGone)
+		case errors.Is(err, resolver.ErrPasswordRequired):
+			srv.writePasswordForm(w, http.StatusOK, "")
+		case errors.Is(err, resolver.ErrWrongPassword):
+			srv.writePasswordForm(w, http.StatusUnauthorized, "Wrong password.")
+		case errors.Is(err, resolver.ErrTooManyAttempts):
+			srv.writePasswordForm(w, http.StatusTooManyRequests, "Too many attempts, try again later.")
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+	if password != "" {
+		// Redirect after form submission must be followed with GET
+		// and never cached since it's available only with password.
+		redirect.Code, redirect.MaxAge = http.StatusSeeOther, 0
+	}
+	if redirect.MaxAge > 0 {
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(redirect.MaxAge.Seconds())))
+	} else {
+		// Temporary redirects and redirects depending on visitor can change, so they must not be cached.
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	if len(redirect.Vary) > 0 {
+		w.Header().Set("Vary", strings.Join(redirect.Vary, ", "))
+	}
+	if redirect.VisitorID != "" && redirect.VisitorID != getVisitorID(r) {
+		http.SetCookie(w, &http.Cookie{
+			Name:     visitorCookieName,
+			Value:    redirect.VisitorID,
+			Path:     "/",
+			MaxAge:   visitorCookieMaxAge,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	if redirect.Preview {
+		srv.writePreviewPage(w, redirect)
+		return
+	}
+	w.Header().Set("Location", redirect.URL)
+	w.WriteHeader(redirect.Code)
+}
+
+// GetVariantStats returns click statistics of URL variants to URL owner.
+func (srv *Server) GetVariantStats(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	stats, err := srv.shortenerSvc.GetVariantStats(r.Context(), u, chi.URLParam(r, "short"))
+	logf.With(
+		zap.Int("variants", len(stats)),
+		zap.Error(err),
+	).Debug("getVariantStats called")
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrNotFound),
+			errors.Is(err, model.ErrDeleted):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, shortener.ErrUnauthorized):
+			w.WriteHeader(http.StatusUnauthorized)
+		case errors.Is(err, shortener.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
 		default:
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 		return
 	}
-	w.Header().Set("Location", redirect)
-	w.WriteHeader(http.StatusTemporaryRedirect)
+
+	b, err := json.Marshal(&stats)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		logf.Error("cannot marshal variant stats response", zap.Error(err))
+		return
+	}
+	w.Header().Set(headerNameContentType, contentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(b); err != nil {
+		logf.Error("error while writing response body", zap.Error(err))
+	}
 }
 
 // Shorten generates short URL for provided original URL and stores it.
+// If route has workspace param, URL is created in workspace.
 // Short URL is returned back.
 func (srv *Server) Shorten(w http.ResponseWriter, r *http.Request) {
 	u, reqID, err := session.GetUserAndReqID(r.Context())
@@ -133,7 +244,20 @@ func (srv *Server) Shorten(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	shortenResp.Result, err = srv.shortenerSvc.Shorten(r.Context(), u, shortenReq.URL)
+	shortenResp.Result, err = srv.shortenerSvc.Shorten(r.Context(), u, &model.URL{
+		Orig:        shortenReq.URL,
+		Redirect:    shortenReq.Redirect,
+		Passthrough: shortenReq.Passthrough,
+		Targets:     shortenReq.Targets,
+		Variants:    shortenReq.Variants,
+		Schedule:    shortenReq.Schedule,
+		Password:    shortenReq.Password,
+		Title:       shortenReq.Title,
+		Tags:        shortenReq.Tags,
+		Notes:       shortenReq.Notes,
+		Preview:     shortenReq.Preview,
+		WorkspaceID: chi.URLParam(r, "workspace"),
+	})
 	logf.With(
 		zap.String("result", shortenResp.Result),
 		zap.Error(err),
@@ -141,8 +265,24 @@ func (srv *Server) Shorten(w http.ResponseWriter, r *http.Request) {
 	respStatus := http.StatusCreated
 	if err != nil {
 		switch {
+		case srv.writeQuotaExceeded(w, logf, err):
+			return
+		case errors.Is(err, shortener.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+			return
+		case errors.Is(err, shortener.ErrWorkspaceNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			return
 		case errors.Is(shortener.ErrInvalidURL, err),
-			errors.Is(shortener.ErrUnsupportedURLScheme, err):
+			errors.Is(shortener.ErrUnsupportedURLScheme, err),
+			errors.Is(shortener.ErrInvalidRedirect, err),
+			errors.Is(shortener.ErrInvalidTarget, err),
+			errors.Is(shortener.ErrInvalidVariant, err),
+			errors.Is(shortener.ErrInvalidSchedule, err),
+			errors.Is(shortener.ErrInvalidPassword, err),
+			errors.Is(shortener.ErrInvalidTitle, err),
+			errors.Is(shortener.ErrInvalidTags, err),
+			errors.Is(shortener.ErrInvalidNotes, err):
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		case errors.Is(model.ErrConflict, err):
@@ -184,7 +324,7 @@ func (srv *Server) ShortenPlain(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := srv.shortenerSvc.Shorten(r.Context(), u, string(body))
+	result, err := srv.shortenerSvc.Shorten(r.Context(), u, &model.URL{Orig: string(body)})
 	logf.With(
 		zap.String("result", result),
 		zap.Error(err),
@@ -192,8 +332,18 @@ func (srv *Server) ShortenPlain(w http.ResponseWriter, r *http.Request) {
 	respStatus := http.StatusCreated
 	if err != nil {
 		switch {
+		case srv.writeQuotaExceeded(w, logf, err):
+			return
 		case errors.Is(shortener.ErrInvalidURL, err),
-			errors.Is(shortener.ErrUnsupportedURLScheme, err):
+			errors.Is(shortener.ErrUnsupportedURLScheme, err),
+			errors.Is(shortener.ErrInvalidRedirect, err),
+			errors.Is(shortener.ErrInvalidTarget, err),
+			errors.Is(shortener.ErrInvalidVariant, err),
+			errors.Is(shortener.ErrInvalidSchedule, err),
+			errors.Is(shortener.ErrInvalidPassword, err),
+			errors.Is(shortener.ErrInvalidTitle, err),
+			errors.Is(shortener.ErrInvalidTags, err),
+			errors.Is(shortener.ErrInvalidNotes, err):
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		case errors.Is(model.ErrConflict, err):
@@ -213,6 +363,8 @@ func (srv *Server) ShortenPlain(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetAll retrieves all urls created by one user.
+// If route has workspace param, all urls of workspace are retrieved instead.
+// Urls can be filtered by tag using tag query param.
 func (srv *Server) GetAll(w http.ResponseWriter, r *http.Request) {
 	u, reqID, err := session.GetUserAndReqID(r.Context())
 	if err != nil {
@@ -222,13 +374,17 @@ func (srv *Server) GetAll(w http.ResponseWriter, r *http.Request) {
 	}
 	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
 
-	urls, err := srv.shortenerSvc.GetAll(r.Context(), u)
+	urls, err := srv.shortenerSvc.GetAll(r.Context(), u, chi.URLParam(r, "workspace"), r.URL.Query().Get("tag"))
 	logf.With(
 		zap.Int("urls", len(urls)),
 		zap.Error(err),
 	).Debug("getURLs called")
 	if err != nil {
 		switch {
+		case errors.Is(err, shortener.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, shortener.ErrWorkspaceNotFound):
+			w.WriteHeader(http.StatusNotFound)
 		case errors.Is(err, model.ErrNotFound):
 			w.WriteHeader(http.StatusNoContent)
 		case errors.Is(err, shortener.ErrUnauthorized):
@@ -252,8 +408,75 @@ func (srv *Server) GetAll(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// UpdateMeta updates title, tags and notes of user URL.
+// Only fields present in request are changed, updated URL is returned.
+func (srv *Server) UpdateMeta(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	if ct := r.Header.Get(headerNameContentType); ct != contentTypeJSON {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Error("incorrect Content-Type",
+			zap.String("expected", contentTypeJSON),
+			zap.String("got", ct))
+		return
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Error("cannot read body", zap.Error(err))
+		return
+	}
+	var update model.MetaUpdate
+	if err = json.Unmarshal(body, &update); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Error("cannot unmarshall json", zap.Error(err))
+		return
+	}
+
+	url, err := srv.shortenerSvc.UpdateMeta(r.Context(), u, chi.URLParam(r, "short"), &update)
+	logf.With(zap.Error(err)).Debug("updateMeta called")
+	if err != nil {
+		switch {
+		case errors.Is(err, shortener.ErrUnauthorized):
+			w.WriteHeader(http.StatusUnauthorized)
+		case errors.Is(err, shortener.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, model.ErrNotFound),
+			errors.Is(err, model.ErrDeleted):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, shortener.ErrInvalidTitle),
+			errors.Is(err, shortener.ErrInvalidTags),
+			errors.Is(err, shortener.ErrInvalidNotes):
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	b, err := json.Marshal(url)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		logf.Error("cannot marshal url response", zap.Error(err))
+		return
+	}
+	w.Header().Set(headerNameContentType, contentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(b); err != nil {
+		logf.Error("error while writing response body", zap.Error(err))
+	}
+}
+
 // ShortenBatch shortens batch of original URLs. It returns batch of short URLs
 // that can be matched with originals using correlation ID.
+// If route has workspace param, URLs are created in workspace.
 func (srv *Server) ShortenBatch(w http.ResponseWriter, r *http.Request) {
 	u, reqID, err := session.GetUserAndReqID(r.Context())
 	if err != nil {
@@ -288,9 +511,30 @@ func (srv *Server) ShortenBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	shortURLs, err := srv.shortenerSvc.ShortenBatch(r.Context(), u, batchURLs)
+	shortURLs, err := srv.shortenerSvc.ShortenBatch(r.Context(), u, chi.URLParam(r, "workspace"), batchURLs)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		switch {
+		case srv.writeQuotaExceeded(w, logf, err):
+		case errors.Is(err, shortener.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, shortener.ErrWorkspaceNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, shortener.ErrUnauthorized):
+			w.WriteHeader(http.StatusUnauthorized)
+		case errors.Is(err, shortener.ErrInvalidURL),
+			errors.Is(err, shortener.ErrUnsupportedURLScheme),
+			errors.Is(err, shortener.ErrInvalidRedirect),
+			errors.Is(err, shortener.ErrInvalidTarget),
+			errors.Is(err, shortener.ErrInvalidVariant),
+			errors.Is(err, shortener.ErrInvalidSchedule),
+			errors.Is(err, shortener.ErrInvalidPassword),
+			errors.Is(err, shortener.ErrInvalidTitle),
+			errors.Is(err, shortener.ErrInvalidTags),
+			errors.Is(err, shortener.ErrInvalidNotes):
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
 		logf.Error("cannot store url batch", zap.Error(err))
 		return
 	}
@@ -310,6 +554,7 @@ func (srv *Server) ShortenBatch(w http.ResponseWriter, r *http.Request) {
 }
 
 // DeleteBatch processes batch delete request.
+// If route has workspace param, URLs are deleted from workspace.
 // URLs are pushed to flusher queue and deleted asynchronously.
 func (srv *Server) DeleteBatch(w http.ResponseWriter, r *http.Request) {
 	u, reqID, err := session.GetUserAndReqID(r.Context())
@@ -340,10 +585,14 @@ func (srv *Server) DeleteBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = srv.shortenerSvc.DeleteBatch(r.Context(), u, shorts)
+	err = srv.shortenerSvc.DeleteBatch(r.Context(), u, chi.URLParam(r, "workspace"), shorts)
 	logf.With(zap.Error(err)).Debug("DeleteBatch called")
 	if err != nil {
 		switch {
+		case errors.Is(err, shortener.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, shortener.ErrWorkspaceNotFound):
+			w.WriteHeader(http.StatusNotFound)
 		case errors.Is(err, shortener.ErrUnauthorized):
 			w.WriteHeader(http.StatusUnauthorized)
 		case errors.Is(err, shortener.ErrEmptyBatch):
@@ -351,6 +600,7 @@ func (srv *Server) DeleteBatch(w http.ResponseWriter, r *http.Request) {
 		default:
 			w.WriteHeader(http.StatusInternalServerError)
 		}
+		return
 	}
 	w.WriteHeader(http.StatusAccepted)
 }
@@ -382,3 +632,23 @@ func getContentReader(req *http.Request) (r io.ReadCloser, err error) {
 	}
 	return
 }
+
+func getVisitorID(r *http.Request) string {
+	cookie, err := r.Cookie(visitorCookieName)
+	if err != nil || len(cookie.Value) > visitorCookieMaxLength {
+		return ""
+	}
+	return cookie.Value
+}
+
+// clientIP returns client address using trusted proxy headers if filter is configured.
+func (srv *Server) clientIP(r *http.Request) string {
+	if srv.filter != nil {
+		return srv.filter.ClientIP(r.RemoteAddr, r.Header.Get("X-Real-IP"), r.Header.Get("X-Forwarded-For"))
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
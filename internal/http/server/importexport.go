@@ -0,0 +1,197 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/adwski/shorty/internal/services/shortener"
+	"github.com/adwski/shorty/internal/session"
+	"go.uber.org/zap"
+)
+
+const (
+	contentTypeCSV    = "text/csv"
+	contentTypeNDJSON = "application/x-ndjson"
+)
+
+// Import imports user links from CSV or NDJSON request body.
+// Format is selected with Content-Type, short paths and owners are preserved
+// if preserve_short and preserve_owner query params are set. Preserving owners
+// is allowed only for clients from trusted subnets, password hashes of protected
+// links are imported only along with owners. Response holds number of imported records
+// and per-line errors.
+func (srv *Server) Import(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	format, err := importFormat(r.Header.Get(headerNameContentType))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("unsupported import format", zap.Error(err))
+		return
+	}
+	opts, err := importOptions(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("invalid import options", zap.Error(err))
+		return
+	}
+	if opts.PreserveOwner && (srv.filter == nil ||
+		!srv.filter.CheckRequestParams(r.RemoteAddr, r.Header.Get("X-Real-IP"), r.Header.Get("X-Forwarded-For"))) {
+		w.WriteHeader(http.StatusForbidden)
+		logf.Debug("preserving owners is not allowed for untrusted client")
+		return
+	}
+
+	// Import can take much longer than server timeouts.
+	rc := http.NewResponseController(w)
+	if err = rc.SetReadDeadline(time.Time{}); err != nil {
+		logf.Debug("cannot reset read deadline", zap.Error(err))
+	}
+	if err = rc.SetWriteDeadline(time.Time{}); err != nil {
+		logf.Debug("cannot reset write deadline", zap.Error(err))
+	}
+
+	defer func() { _ = r.Body.Close() }()
+	body, err := getContentReader(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("cannot read body", zap.Error(err))
+		return
+	}
+	defer func() { _ = body.Close() }()
+	dec, err := shortener.NewDecoder(body, format)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Error("cannot create decoder", zap.Error(err))
+		return
+	}
+
+	result, err := srv.shortenerSvc.Import(r.Context(), u, dec, opts)
+	logf.With(
+		zap.Int("imported", result.Imported),
+		zap.Int("failed", result.Failed),
+		zap.Error(err),
+	).Debug("import called")
+	status := http.StatusOK
+	if err != nil {
+		switch {
+		case errors.Is(err, shortener.ErrUnauthorized):
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		case errors.Is(err, shortener.ErrStorageError):
+			status = http.StatusInternalServerError
+		default:
+			status = http.StatusBadRequest
+		}
+		result.Error = err.Error()
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		logf.Error("cannot marshal import result", zap.Error(err))
+		return
+	}
+	w.Header().Set(headerNameContentType, contentTypeJSON)
+	w.WriteHeader(status)
+	if _, err = w.Write(b); err != nil {
+		logf.Error("error while writing response body", zap.Error(err))
+	}
+}
+
+// Export streams all user links as NDJSON or CSV depending on format query param.
+func (srv *Server) Export(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = shortener.FormatNDJSON
+	}
+	enc, err := shortener.NewEncoder(w, format)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("unsupported export format", zap.Error(err))
+		return
+	}
+	if err = http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		logf.Debug("cannot reset write deadline", zap.Error(err))
+	}
+	contentType := contentTypeNDJSON
+	if format == shortener.FormatCSV {
+		contentType = contentTypeCSV + "; charset=utf-8"
+	}
+	w.Header().Set(headerNameContentType, contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="links.%s"`, format))
+
+	n, err := srv.shortenerSvc.Export(r.Context(), u, enc)
+	logf.With(
+		zap.Int("records", n),
+		zap.Error(err),
+	).Debug("export called")
+	if err != nil && n == 0 {
+		// nothing is flushed yet, so status can be changed
+		w.Header().Del("Content-Disposition")
+		w.Header().Del(headerNameContentType)
+		switch {
+		case errors.Is(err, shortener.ErrUnauthorized):
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+	if err != nil {
+		logf.Error("export interrupted", zap.Error(err))
+		return
+	}
+	if err = enc.Flush(); err != nil {
+		logf.Error("cannot flush export records", zap.Error(err))
+	}
+}
+
+func importFormat(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse content type: %w", err)
+	}
+	switch mediaType {
+	case contentTypeCSV:
+		return shortener.FormatCSV, nil
+	case contentTypeNDJSON:
+		return shortener.FormatNDJSON, nil
+	default:
+		return "", fmt.Errorf("%w: %q", shortener.ErrUnsupportedFormat, mediaType)
+	}
+}
+
+func importOptions(r *http.Request) (opts shortener.ImportOptions, err error) {
+	query := r.URL.Query()
+	if v := query.Get("preserve_short"); v != "" {
+		if opts.PreserveShort, err = strconv.ParseBool(v); err != nil {
+			return opts, fmt.Errorf("invalid preserve_short: %w", err)
+		}
+	}
+	if v := query.Get("preserve_owner"); v != "" {
+		if opts.PreserveOwner, err = strconv.ParseBool(v); err != nil {
+			return opts, fmt.Errorf("invalid preserve_owner: %w", err)
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	httpmodel "github.com/adwski/shorty/internal/http/model"
+	"github.com/adwski/shorty/internal/services/transfer"
+	"github.com/adwski/shorty/internal/session"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// OfferTransfer offers personal links of user to another user.
+func (srv *Server) OfferTransfer(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	var req httpmodel.TransferRequest
+	if !readJSONRequest(w, r, logf, &req) {
+		return
+	}
+	offer, err := srv.transferSvc.Offer(r.Context(), u,
+		&transfer.Recipient{UserID: req.ToUserID, Login: req.ToLogin}, req.Shorts)
+	logf.With(zap.Error(err)).Debug("offerTransfer called")
+	if err != nil {
+		srv.writeTransferError(w, logf, err)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusCreated, offer)
+}
+
+// ListTransfers returns pending transfer offers sent or received by user.
+func (srv *Server) ListTransfers(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	transfers, err := srv.transferSvc.List(r.Context(), u)
+	logf.With(zap.Int("transfers", len(transfers)), zap.Error(err)).Debug("listTransfers called")
+	if err != nil {
+		srv.writeTransferError(w, logf, err)
+		return
+	}
+	if len(transfers) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusOK, transfers)
+}
+
+// AcceptTransfer accepts transfer offer received by user, links are moved to user.
+// Audit event of transfer is returned.
+func (srv *Server) AcceptTransfer(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	event, err := srv.transferSvc.Accept(r.Context(), u, chi.URLParam(r, "transfer"))
+	logf.With(zap.Error(err)).Debug("acceptTransfer called")
+	if err != nil {
+		srv.writeTransferError(w, logf, err)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusOK, event)
+}
+
+// DeclineTransfer declines received transfer offer or cancels sent one.
+func (srv *Server) DeclineTransfer(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	err = srv.transferSvc.Decline(r.Context(), u, chi.URLParam(r, "transfer"))
+	logf.With(zap.Error(err)).Debug("declineTransfer called")
+	if err != nil {
+		srv.writeTransferError(w, logf, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ForceTransfer moves personal links of user to another user without their consent.
+// Audit event of transfer is returned.
+func (srv *Server) ForceTransfer(w http.ResponseWriter, r *http.Request) {
+	reqID, ok := session.GetRequestID(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error("request id was not provided in context")
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID))
+
+	var req httpmodel.ForcedTransferRequest
+	if !readJSONRequest(w, r, logf, &req) {
+		return
+	}
+	event, err := srv.transferSvc.Force(r.Context(), req.FromUserID,
+		&transfer.Recipient{UserID: req.ToUserID, Login: req.ToLogin}, req.Shorts)
+	logf.With(zap.Error(err)).Debug("forceTransfer called")
+	if err != nil {
+		srv.writeTransferError(w, logf, err)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusOK, event)
+}
+
+// AuditEvents returns latest audit events, newest first.
+// Number of events can be set with limit query param.
+func (srv *Server) AuditEvents(w http.ResponseWriter, r *http.Request) {
+	reqID, ok := session.GetRequestID(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error("request id was not provided in context")
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID))
+
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			logf.Debug("invalid limit", zap.Error(err))
+			return
+		}
+	}
+	events, err := srv.transferSvc.Audit(r.Context(), limit)
+	logf.With(zap.Int("events", len(events)), zap.Error(err)).Debug("auditEvents called")
+	if err != nil {
+		srv.writeTransferError(w, logf, err)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusOK, events)
+}
+
+func (srv *Server) writeTransferError(w http.ResponseWriter, logf *zap.Logger, err error) {
+	switch {
+	case srv.writeQuotaExceeded(w, logf, err):
+	case errors.Is(err, transfer.ErrUnauthorized):
+		w.WriteHeader(http.StatusUnauthorized)
+	case errors.Is(err, transfer.ErrInvalidUser),
+		errors.Is(err, transfer.ErrInvalidURLs),
+		errors.Is(err, transfer.ErrTooManyURLs),
+		errors.Is(err, transfer.ErrInvalidAuditLimit):
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, transfer.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, transfer.ErrTooManyTransfers):
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		logf.Error("transfer request failed", zap.Error(err))
+	}
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+const (
+	passwordFormField   = "password"
+	passwordFormMaxSize = 1024
+)
+
+// ResolvePassword resolves password protected URL using password
+// submitted with form. Redirect is made only if password is correct.
+func (srv *Server) ResolvePassword(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, passwordFormMaxSize)
+	if err := r.ParseForm(); err != nil {
+		srv.logger.Debug("cannot parse password form", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	password := r.PostForm.Get(passwordFormField)
+	if password == "" {
+		srv.writePasswordForm(w, http.StatusUnauthorized, "Password is required.")
+		return
+	}
+	srv.resolve(w, r, password)
+}
+
+func (srv *Server) writePasswordForm(w http.ResponseWriter, status int, message string) {
+	w.Header().Set(headerNameContentType, contentTypeHTML)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := templates.ExecuteTemplate(w, templatePassword, message); err != nil {
+		srv.logger.Error("cannot write password form", zap.Error(err))
+	}
+}
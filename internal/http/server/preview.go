@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/adwski/shorty/internal/services/resolver"
+	"go.uber.org/zap"
+)
+
+const (
+	previewSuffix     = "+"
+	previewQueryParam = "preview"
+	previewQueryValue = "1"
+)
+
+// previewRequest detects preview request made either with "+" appended
+// to short path or with preview=1 query param. It returns path and query
+// with preview marks removed.
+func previewRequest(u *url.URL) (path, query string, preview bool) {
+	path, query = u.Path, u.RawQuery
+	if short, ok := strings.CutSuffix(path, previewSuffix); ok && strings.LastIndexByte(short, '/') == 0 {
+		// only bare short path can be previewed this way, suffix belongs to passthrough
+		path, preview = short, true
+	}
+	if values, err := url.ParseQuery(query); err == nil && values.Get(previewQueryParam) == previewQueryValue {
+		values.Del(previewQueryParam)
+		query, preview = values.Encode(), true
+	}
+	return path, query, preview
+}
+
+// writePreviewPage writes interstitial page with redirect destination
+// and continue link instead of redirect.
+func (srv *Server) writePreviewPage(w http.ResponseWriter, redirect *resolver.Redirect) {
+	w.Header().Set(headerNameContentType, contentTypeHTML)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := templates.ExecuteTemplate(w, templatePreview, redirect); err != nil {
+		srv.logger.Error("cannot write preview page", zap.Error(err))
+	}
+}
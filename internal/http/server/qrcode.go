@@ -0,0 +1,80 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/services/resolver"
+	"github.com/adwski/shorty/internal/session"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// QRCode serves QR code image of short URL.
+// Format, size, margin and error correction level are set with query params.
+func (srv *Server) QRCode(w http.ResponseWriter, r *http.Request) {
+	reqID, ok := session.GetRequestID(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error("request id was not provided in context")
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID))
+
+	req, err := qrRequest(r)
+	if err != nil {
+		logf.Debug("invalid qr code params", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	code, err := srv.resolverSvc.QRCode(r.Context(), req)
+	logf.With(zap.Error(err)).Debug("qrcode called")
+	if err != nil {
+		switch {
+		case errors.Is(err, resolver.ErrInvalidPath),
+			errors.Is(err, resolver.ErrInvalidQRParams):
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, model.ErrNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, model.ErrDeleted):
+			w.WriteHeader(http.StatusGone)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set(headerNameContentType, code.ContentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(code.Data); err != nil {
+		logf.Error("error writing qr code", zap.Error(err))
+	}
+}
+
+func qrRequest(r *http.Request) (*resolver.QRRequest, error) {
+	var (
+		query = r.URL.Query()
+		req   = &resolver.QRRequest{
+			Path:   "/" + chi.URLParam(r, "path"),
+			Format: query.Get("format"),
+			Level:  query.Get("level"),
+		}
+	)
+	if size := query.Get("size"); size != "" {
+		val, err := strconv.Atoi(size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size: %w", err)
+		}
+		req.Size = val
+	}
+	if margin := query.Get("margin"); margin != "" {
+		val, err := strconv.Atoi(margin)
+		if err != nil {
+			return nil, fmt.Errorf("invalid margin: %w", err)
+		}
+		req.Margin = &val
+	}
+	return req, nil
+}
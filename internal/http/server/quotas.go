@@ -0,0 +1,137 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	httpmodel "github.com/adwski/shorty/internal/http/model"
+	"github.com/adwski/shorty/internal/services/quota"
+	"github.com/adwski/shorty/internal/session"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// GetUserQuota returns quota limits of user and their usage.
+func (srv *Server) GetUserQuota(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	usage, err := srv.quotaSvc.Get(r.Context(), u)
+	logf.With(zap.Error(err)).Debug("getUserQuota called")
+	if err != nil {
+		srv.writeQuotaError(w, logf, err)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusOK, usage)
+}
+
+// GetQuota returns quota limits of any user and their usage.
+func (srv *Server) GetQuota(w http.ResponseWriter, r *http.Request) {
+	reqID, ok := session.GetRequestID(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error("request id was not provided in context")
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID))
+
+	usage, err := srv.quotaSvc.Lookup(r.Context(), chi.URLParam(r, "user"))
+	logf.With(zap.Error(err)).Debug("getQuota called")
+	if err != nil {
+		srv.writeQuotaError(w, logf, err)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusOK, usage)
+}
+
+// SetQuota assigns quota tier or custom limits to user. Updated quota of user is returned.
+func (srv *Server) SetQuota(w http.ResponseWriter, r *http.Request) {
+	reqID, ok := session.GetRequestID(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error("request id was not provided in context")
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID))
+
+	var req httpmodel.QuotaRequest
+	if !readJSONRequest(w, r, logf, &req) {
+		return
+	}
+	usage, err := srv.quotaSvc.Set(r.Context(), chi.URLParam(r, "user"), req.Tier, req.MaxURLs, req.MaxBatch)
+	logf.With(zap.Error(err)).Debug("setQuota called")
+	if err != nil {
+		srv.writeQuotaError(w, logf, err)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusOK, usage)
+}
+
+// ResetQuota removes quota assignment of user, so user gets back to default tier.
+func (srv *Server) ResetQuota(w http.ResponseWriter, r *http.Request) {
+	reqID, ok := session.GetRequestID(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error("request id was not provided in context")
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID))
+
+	err := srv.quotaSvc.Reset(r.Context(), chi.URLParam(r, "user"))
+	logf.With(zap.Error(err)).Debug("resetQuota called")
+	if err != nil {
+		srv.writeQuotaError(w, logf, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// QuotaTiers returns configured quota tiers.
+func (srv *Server) QuotaTiers(w http.ResponseWriter, r *http.Request) {
+	reqID, ok := session.GetRequestID(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error("request id was not provided in context")
+		return
+	}
+	srv.writeJSON(w, srv.logger.With(zap.String("id", reqID)), http.StatusOK, &httpmodel.TiersResponse{
+		DefaultTier: srv.quotaSvc.DefaultTier(),
+		Tiers:       srv.quotaSvc.Tiers(),
+	})
+}
+
+// writeQuotaExceeded writes quota exceeded response if err is quota error.
+func (srv *Server) writeQuotaExceeded(w http.ResponseWriter, logf *zap.Logger, err error) bool {
+	var exceeded *quota.ExceededError
+	if !errors.As(err, &exceeded) {
+		return false
+	}
+	srv.writeJSON(w, logf, http.StatusForbidden, &httpmodel.QuotaExceededResponse{
+		Error:     exceeded.Error(),
+		Quota:     exceeded.Quota,
+		Limit:     exceeded.Limit,
+		Requested: exceeded.Requested,
+		Remaining: exceeded.Remaining,
+	})
+	return true
+}
+
+func (srv *Server) writeQuotaError(w http.ResponseWriter, logf *zap.Logger, err error) {
+	switch {
+	case errors.Is(err, quota.ErrUnauthorized):
+		w.WriteHeader(http.StatusUnauthorized)
+	case errors.Is(err, quota.ErrInvalidUser),
+		errors.Is(err, quota.ErrInvalidQuota):
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, quota.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		logf.Error("quota request failed", zap.Error(err))
+	}
+}
@@ -10,15 +10,26 @@ import (
 	"sync"
 	"time"
 
+	authorizer "github.com/adwski/shorty/internal/auth"
 	"github.com/adwski/shorty/internal/config"
+	ipfilter "github.com/adwski/shorty/internal/filter"
 	"github.com/adwski/shorty/internal/http/middleware/auth"
 	"github.com/adwski/shorty/internal/http/middleware/compress"
 	"github.com/adwski/shorty/internal/http/middleware/filter"
 	"github.com/adwski/shorty/internal/http/middleware/logging"
+	"github.com/adwski/shorty/internal/http/middleware/ratelimit"
 	"github.com/adwski/shorty/internal/http/middleware/requestid"
+	limits "github.com/adwski/shorty/internal/ratelimit"
+	"github.com/adwski/shorty/internal/services/account"
+	"github.com/adwski/shorty/internal/services/apikey"
+	"github.com/adwski/shorty/internal/services/backup"
+	"github.com/adwski/shorty/internal/services/oidc"
+	"github.com/adwski/shorty/internal/services/quota"
 	"github.com/adwski/shorty/internal/services/resolver"
 	"github.com/adwski/shorty/internal/services/shortener"
 	"github.com/adwski/shorty/internal/services/status"
+	"github.com/adwski/shorty/internal/services/transfer"
+	"github.com/adwski/shorty/internal/services/workspace"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
@@ -28,8 +39,19 @@ const (
 	defaultReadTimeout       = 5 * time.Second
 	defaultWriteTimeout      = 5 * time.Second
 	defaultIdleTimeout       = 10 * time.Second
+
+	jwksCacheControl = "public, max-age=300"
 )
 
+// sessionPaths issue new sessions, they are available without credentials in strict auth mode.
+var sessionPaths = []string{
+	"/api/user/register",
+	"/api/user/login",
+	"/api/user/anonymous",
+	"/api/user/sso/login",
+	"/api/user/sso/callback",
+}
+
 // Server is http server instance.
 // It includes router and pointers to business logic handlers.
 type Server struct {
@@ -37,31 +59,68 @@ type Server struct {
 	shortenerSvc *shortener.Service
 	resolverSvc  *resolver.Service
 	statusSvc    *status.Service
+	backupSvc    *backup.Service
+	accountSvc   *account.Service
+	apikeySvc    *apikey.Service
+	oidcSvc      *oidc.Service
+	workspaceSvc *workspace.Service
+	transferSvc  *transfer.Service
+	quotaSvc     *quota.Service
+	filter       *ipfilter.Filter
+	jwks         *authorizer.JWKS
 	tls          *tls.Config
 	hSrv         *http.Server
 }
 
 // NewServer creates Server instance.
+// Backup, account, api key, single sign-on, workspace, transfer and quota services are optional,
+// their api is not served if they're nil.
+// Api keys are accepted only if api key service is set.
+// Requests are rate limited if limiter is set.
 func NewServer(
 	logger *zap.Logger,
 	cfg *config.Config,
 	resolverSvc *resolver.Service,
 	shortenerSvc *shortener.Service,
 	statusSvc *status.Service,
+	backupSvc *backup.Service,
+	accountSvc *account.Service,
+	apikeySvc *apikey.Service,
+	oidcSvc *oidc.Service,
+	workspaceSvc *workspace.Service,
+	transferSvc *transfer.Service,
+	quotaSvc *quota.Service,
+	limiter *limits.Limiter,
 ) *Server {
 	srv := &Server{
 		logger:       logger.With(zap.String("component", "httpserver")),
 		resolverSvc:  resolverSvc,
 		shortenerSvc: shortenerSvc,
 		statusSvc:    statusSvc,
+		backupSvc:    backupSvc,
+		accountSvc:   accountSvc,
+		apikeySvc:    apikeySvc,
+		oidcSvc:      oidcSvc,
+		workspaceSvc: workspaceSvc,
+		transferSvc:  transferSvc,
+		quotaSvc:     quotaSvc,
+		filter:       cfg.GetFilter(),
+		jwks:         cfg.GetAuthorizer().JWKS(),
 		tls:          cfg.GetTLSConfig(),
 	}
 	var (
-		router     = getRouterWithMiddleware(logger, cfg.TrustRequestID)
-		authorizer = auth.NewFromAuthorizer(logger, cfg.GetAuthorizer())
-		filterMW   = filter.NewFromFilter(cfg.GetFilter())
+		router   = getRouterWithMiddleware(logger, cfg.TrustRequestID)
+		authMW   = auth.NewFromAuthorizer(logger, cfg.GetAuthorizer())
+		filterMW = filter.NewFromFilter(cfg.GetFilter())
+		limitMW  = ratelimit.New(limiter, cfg.GetFilter())
 	)
-	srv.registerHandlers(router, authorizer, filterMW)
+	if apikeySvc != nil {
+		authMW.WithKeys(apikeySvc)
+	}
+	if cfg.StrictAuth {
+		authMW.WithStrict(sessionPaths)
+	}
+	srv.registerHandlers(router, authMW, filterMW, limitMW)
 	srv.hSrv = &http.Server{
 		TLSConfig:         cfg.GetTLSConfig(),
 		Addr:              cfg.ListenAddr,
@@ -110,17 +169,88 @@ func (srv *Server) Run(ctx context.Context, wg *sync.WaitGroup, errc chan error)
 	wg.Done()
 }
 
-func (srv *Server) registerHandlers(r chi.Router, authMW *auth.Middleware, filterMW *filter.Middleware) {
-	r.With(authMW.HandlerFunc).Route("/", func(r chi.Router) {
-		r.Get("/api/user/urls", srv.GetAll)
-		r.Delete("/api/user/urls", srv.DeleteBatch)
-		r.Post("/api/shorten", srv.Shorten)
-		r.Post("/api/shorten/batch", srv.ShortenBatch)
-		r.Post("/", srv.ShortenPlain)
+func (srv *Server) registerHandlers(
+	r chi.Router,
+	authMW *auth.Middleware,
+	filterMW *filter.Middleware,
+	limitMW *ratelimit.Middleware,
+) {
+	// API is mounted under its own prefix, so it's not shadowed by short path routes.
+	// Routes require api key scopes, session users have all scopes.
+	// Rate limiting follows authentication, so users are limited by user id.
+	r.With(authMW.HandlerFunc, limitMW.HandlerFunc).Route("/api", func(r chi.Router) {
+		r.With(srv.requireScope(apikey.ScopeRead)).Get("/user/urls", srv.GetAll)
+		r.With(srv.requireScope(apikey.ScopeRead)).Get("/user/urls/{short}/variants", srv.GetVariantStats)
+		r.With(srv.requireScope(apikey.ScopeShorten)).Patch("/user/urls/{short}", srv.UpdateMeta)
+		r.With(srv.requireScope(apikey.ScopeDelete)).Delete("/user/urls", srv.DeleteBatch)
+		r.With(srv.requireScope(apikey.ScopeShorten)).Post("/user/import", srv.Import)
+		r.With(srv.requireScope(apikey.ScopeRead)).Get("/user/export", srv.Export)
+		r.With(srv.requireScope(apikey.ScopeShorten)).Post("/shorten", srv.Shorten)
+		r.With(srv.requireScope(apikey.ScopeShorten)).Post("/shorten/batch", srv.ShortenBatch)
+		if srv.accountSvc != nil {
+			r.With(srv.sessionOnly).Post("/user/register", srv.Register)
+			r.With(srv.sessionOnly).Post("/user/login", srv.Login)
+			r.With(srv.sessionOnly).Post("/user/anonymous", srv.Anonymous)
+			r.With(srv.sessionOnly).Post("/user/logout", srv.Logout)
+		}
+		if srv.apikeySvc != nil {
+			r.With(srv.sessionOnly).Post("/user/keys", srv.CreateAPIKey)
+			r.With(srv.sessionOnly).Get("/user/keys", srv.ListAPIKeys)
+			r.With(srv.sessionOnly).Patch("/user/keys/{key}", srv.UpdateAPIKey)
+			r.With(srv.sessionOnly).Delete("/user/keys/{key}", srv.RevokeAPIKey)
+		}
+		if srv.oidcSvc != nil {
+			r.With(srv.sessionOnly).Get("/user/sso/login", srv.SSOLogin)
+			r.With(srv.sessionOnly).Get("/user/sso/callback", srv.SSOCallback)
+		}
+		if srv.workspaceSvc != nil {
+			r.With(srv.sessionOnly).Post("/workspaces", srv.CreateWorkspace)
+			r.With(srv.sessionOnly).Get("/workspaces", srv.ListWorkspaces)
+			r.With(srv.sessionOnly).Get("/workspaces/{workspace}", srv.GetWorkspace)
+			r.With(srv.sessionOnly).Put("/workspaces/{workspace}/members", srv.SetWorkspaceMember)
+			r.With(srv.sessionOnly).Delete("/workspaces/{workspace}/members/{member}", srv.RemoveWorkspaceMember)
+			r.With(srv.requireScope(apikey.ScopeRead)).Get("/workspaces/{workspace}/urls", srv.GetAll)
+			r.With(srv.requireScope(apikey.ScopeDelete)).Delete("/workspaces/{workspace}/urls", srv.DeleteBatch)
+			r.With(srv.requireScope(apikey.ScopeShorten)).Post("/workspaces/{workspace}/shorten", srv.Shorten)
+			r.With(srv.requireScope(apikey.ScopeShorten)).Post("/workspaces/{workspace}/shorten/batch", srv.ShortenBatch)
+		}
+		if srv.transferSvc != nil {
+			r.With(srv.sessionOnly).Post("/user/transfers", srv.OfferTransfer)
+			r.With(srv.sessionOnly).Get("/user/transfers", srv.ListTransfers)
+			r.With(srv.sessionOnly).Post("/user/transfers/{transfer}/accept", srv.AcceptTransfer)
+			r.With(srv.sessionOnly).Delete("/user/transfers/{transfer}", srv.DeclineTransfer)
+		}
+		if srv.quotaSvc != nil {
+			r.With(srv.requireScope(apikey.ScopeRead)).Get("/user/quota", srv.GetUserQuota)
+		}
 	})
-	r.Get("/{path}", srv.Resolve)
-	r.Get("/ping", srv.Ping)
-	r.With(filterMW.HandlerFunc).Get("/api/internal/stats", srv.Stats)
+	r.With(authMW.HandlerFunc, limitMW.HandlerFunc, srv.requireScope(apikey.ScopeShorten)).
+		Post("/", srv.ShortenPlain)
+
+	// Other routes do not authenticate users, clients are limited by ip address.
+	public := r.With(limitMW.HandlerFunc)
+	public.Get("/{path}", srv.Resolve)
+	public.Get("/{path}/qr", srv.QRCode)
+	public.Get("/{path}/*", srv.Resolve)
+	public.Post("/{path}", srv.ResolvePassword)
+	public.Post("/{path}/*", srv.ResolvePassword)
+	public.Get("/ping", srv.Ping)
+	public.Get("/.well-known/jwks.json", srv.JWKS)
+	public.With(filterMW.HandlerFunc).Get("/api/internal/stats", srv.Stats)
+	if srv.backupSvc != nil {
+		public.With(filterMW.HandlerFunc).Post("/api/internal/backup", srv.Backup)
+		public.With(filterMW.HandlerFunc).Post("/api/internal/restore", srv.Restore)
+	}
+	if srv.transferSvc != nil {
+		public.With(filterMW.HandlerFunc).Post("/api/internal/transfers", srv.ForceTransfer)
+		public.With(filterMW.HandlerFunc).Get("/api/internal/audit", srv.AuditEvents)
+	}
+	if srv.quotaSvc != nil {
+		public.With(filterMW.HandlerFunc).Get("/api/internal/quotas", srv.QuotaTiers)
+		public.With(filterMW.HandlerFunc).Get("/api/internal/quotas/{user}", srv.GetQuota)
+		public.With(filterMW.HandlerFunc).Put("/api/internal/quotas/{user}", srv.SetQuota)
+		public.With(filterMW.HandlerFunc).Delete("/api/internal/quotas/{user}", srv.ResetQuota)
+	}
 }
 
 func getRouterWithMiddleware(logger *zap.Logger, trustRequestID bool) chi.Router {
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/adwski/shorty/internal/http/middleware/auth"
+	httpmodel "github.com/adwski/shorty/internal/http/model"
+	"github.com/adwski/shorty/internal/services/oidc"
+	"github.com/adwski/shorty/internal/session"
+	"go.uber.org/zap"
+)
+
+const (
+	ssoCookieName   = "shortySSO"
+	ssoCookiePath   = "/api/user/sso"
+	ssoCookieMaxAge = 600
+)
+
+// SSOLogin starts single sign-on flow and redirects user to identity provider.
+// After login user is redirected to local path from 'return' query param if it's set.
+func (srv *Server) SSOLogin(w http.ResponseWriter, r *http.Request) {
+	_, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID))
+
+	flow, err := srv.oidcSvc.Start(r.Context(), r.URL.Query().Get("return"))
+	logf.With(zap.Error(err)).Debug("sso login called")
+	if err != nil {
+		if errors.Is(err, oidc.ErrProvider) {
+			w.WriteHeader(http.StatusBadGateway)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logf.Error("cannot start sso flow", zap.Error(err))
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoCookieName,
+		Value:    flow.State,
+		Path:     ssoCookiePath,
+		MaxAge:   ssoCookieMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, flow.URL, http.StatusFound)
+}
+
+// SSOCallback completes single sign-on flow and replaces session cookie with session of signed in user.
+// Links of current anonymous user are claimed on first login of identity.
+// User is redirected to path passed to SSOLogin, or login result is returned if it was not set.
+func (srv *Server) SSOCallback(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	query := r.URL.Query()
+	if idpErr := query.Get("error"); idpErr != "" {
+		clearSSOCookie(w)
+		w.WriteHeader(http.StatusUnauthorized)
+		logf.Debug("sso login denied",
+			zap.String("error", idpErr),
+			zap.String("description", query.Get("error_description")))
+		return
+	}
+	var flowState string
+	if cookie, errC := r.Cookie(ssoCookieName); errC == nil {
+		flowState = cookie.Value
+	}
+
+	sess, err := srv.oidcSvc.Callback(r.Context(), u, flowState, query.Get("state"), query.Get("code"))
+	logf.With(zap.Error(err)).Debug("sso callback called")
+	if err != nil {
+		clearSSOCookie(w)
+		switch {
+		case srv.writeQuotaExceeded(w, logf, err):
+		case errors.Is(err, oidc.ErrInvalidState):
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, oidc.ErrInvalidToken):
+			w.WriteHeader(http.StatusUnauthorized)
+		case errors.Is(err, oidc.ErrProvider):
+			w.WriteHeader(http.StatusBadGateway)
+			logf.Error("identity provider error", zap.Error(err))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			logf.Error("sso callback failed", zap.Error(err))
+		}
+		return
+	}
+
+	// session cookie replaces the one possibly set by middleware, so it's set first
+	auth.SetCookie(w, sess.Token)
+	clearSSOCookie(w)
+	if sess.Return != "" {
+		http.Redirect(w, r, sess.Return, http.StatusFound)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusOK, &httpmodel.AccountResponse{
+		UserID:  sess.User.ID,
+		Login:   sess.User.Login,
+		Claimed: sess.Claimed,
+	})
+}
+
+func clearSSOCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:   ssoCookieName,
+		Path:   ssoCookiePath,
+		MaxAge: -1,
+	})
+}
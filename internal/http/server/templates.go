@@ -0,0 +1,17 @@
+package server
+
+import (
+	"embed"
+	"html/template"
+)
+
+const (
+	templatePassword = "password.html"
+	templatePreview  = "preview.html"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+// templates holds HTML pages served instead of redirects.
+var templates = template.Must(template.ParseFS(templatesFS, "templates/*.html"))
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	httpmodel "github.com/adwski/shorty/internal/http/model"
+	"github.com/adwski/shorty/internal/services/workspace"
+	"github.com/adwski/shorty/internal/session"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// CreateWorkspace creates workspace, user becomes its owner.
+func (srv *Server) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	var req httpmodel.WorkspaceRequest
+	if !readJSONRequest(w, r, logf, &req) {
+		return
+	}
+	ws, err := srv.workspaceSvc.Create(r.Context(), u, req.Name)
+	logf.With(zap.Error(err)).Debug("createWorkspace called")
+	if err != nil {
+		srv.writeWorkspaceError(w, logf, err)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusCreated, ws)
+}
+
+// ListWorkspaces returns workspaces user is member of.
+func (srv *Server) ListWorkspaces(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	workspaces, err := srv.workspaceSvc.List(r.Context(), u)
+	logf.With(zap.Int("workspaces", len(workspaces)), zap.Error(err)).Debug("listWorkspaces called")
+	if err != nil {
+		srv.writeWorkspaceError(w, logf, err)
+		return
+	}
+	if len(workspaces) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusOK, workspaces)
+}
+
+// GetWorkspace returns workspace with its members.
+func (srv *Server) GetWorkspace(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	ws, err := srv.workspaceSvc.Get(r.Context(), u, chi.URLParam(r, "workspace"))
+	logf.With(zap.Error(err)).Debug("getWorkspace called")
+	if err != nil {
+		srv.writeWorkspaceError(w, logf, err)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusOK, ws)
+}
+
+// SetWorkspaceMember adds workspace member or changes its role.
+func (srv *Server) SetWorkspaceMember(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	var req httpmodel.MemberRequest
+	if !readJSONRequest(w, r, logf, &req) {
+		return
+	}
+	member, err := srv.workspaceSvc.SetMember(r.Context(), u, chi.URLParam(r, "workspace"), &workspace.MemberUpdate{
+		UserID: req.UserID,
+		Login:  req.Login,
+		Role:   req.Role,
+	})
+	logf.With(zap.Error(err)).Debug("setWorkspaceMember called")
+	if err != nil {
+		srv.writeWorkspaceError(w, logf, err)
+		return
+	}
+	srv.writeJSON(w, logf, http.StatusOK, member)
+}
+
+// RemoveWorkspaceMember removes workspace member. Members can remove themselves to leave workspace.
+func (srv *Server) RemoveWorkspaceMember(w http.ResponseWriter, r *http.Request) {
+	u, reqID, err := session.GetUserAndReqID(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		srv.logger.Error(ErrRequestCtx, zap.Error(err))
+		return
+	}
+	logf := srv.logger.With(zap.String("id", reqID), zap.String(logFieldUserID, u.ID))
+
+	err = srv.workspaceSvc.RemoveMember(r.Context(), u, chi.URLParam(r, "workspace"), chi.URLParam(r, "member"))
+	logf.With(zap.Error(err)).Debug("removeWorkspaceMember called")
+	if err != nil {
+		srv.writeWorkspaceError(w, logf, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func readJSONRequest(w http.ResponseWriter, r *http.Request, logf *zap.Logger, v any) bool {
+	if ct := r.Header.Get(headerNameContentType); ct != contentTypeJSON {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("incorrect Content-Type",
+			zap.String("expected", contentTypeJSON),
+			zap.String("got", ct))
+		return false
+	}
+	body, err := readBody(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("cannot read body", zap.Error(err))
+		return false
+	}
+	if err = json.Unmarshal(body, v); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		logf.Debug("cannot unmarshal request", zap.Error(err))
+		return false
+	}
+	return true
+}
+
+func (srv *Server) writeWorkspaceError(w http.ResponseWriter, logf *zap.Logger, err error) {
+	switch {
+	case errors.Is(err, workspace.ErrUnauthorized):
+		w.WriteHeader(http.StatusUnauthorized)
+	case errors.Is(err, workspace.ErrForbidden):
+		w.WriteHeader(http.StatusForbidden)
+	case errors.Is(err, workspace.ErrInvalidName),
+		errors.Is(err, workspace.ErrInvalidRole),
+		errors.Is(err, workspace.ErrInvalidMember):
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, workspace.ErrNotFound),
+		errors.Is(err, workspace.ErrMemberNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, workspace.ErrLastOwner),
+		errors.Is(err, workspace.ErrTooManyWorkspaces):
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		logf.Error("workspace request failed", zap.Error(err))
+	}
+}
@@ -1,7 +1,13 @@
 // Package model contains common application data types.
 package model
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
 
 // Storage errors.
 var (
@@ -17,6 +23,296 @@ type URL struct {
 	Orig   string `json:"original_url"`
 	UserID string `json:"-"`
 	TS     int64  `json:"-"`
+
+	// WorkspaceID is id of workspace link belongs to, it's empty for personal links.
+	// Workspace links are managed by workspace members, UserID is their creator.
+	WorkspaceID string `json:"-"`
+
+	// Redirect is HTTP status code used to redirect to original URL.
+	// Zero value means server default.
+	Redirect int `json:"redirect,omitempty"`
+
+	// Passthrough enables forwarding of incoming query params
+	// and path suffix to original URL.
+	Passthrough bool `json:"passthrough,omitempty"`
+
+	// Targets is ordered list of conditional destinations.
+	// First matched target is used instead of original URL.
+	Targets []Target `json:"targets,omitempty"`
+
+	// Variants is a list of weighted destinations for split traffic.
+	// If set, one of variants is used instead of original URL.
+	Variants []Variant `json:"variants,omitempty"`
+
+	// Schedule is a set of time-window destinations.
+	// Destination of active rule is used instead of original URL.
+	Schedule *Schedule `json:"schedule,omitempty"`
+
+	// Password is plain text password provided at shorten time,
+	// it is never stored, only PasswordHash is.
+	Password string `json:"-"`
+	// PasswordHash is a bcrypt hash of link password.
+	// Links with password are resolved only after password is verified.
+	PasswordHash string `json:"-"`
+
+	// Title is owner-supplied link title shown on preview page.
+	Title string `json:"title,omitempty"`
+	// Tags are free-form labels used to organize and filter links.
+	Tags []string `json:"tags,omitempty"`
+	// Notes is owner-supplied free-form text.
+	Notes string `json:"notes,omitempty"`
+	// Preview forces interstitial preview page for every visit.
+	Preview bool `json:"preview,omitempty"`
+	// Created is link creation time, it's set by storage.
+	Created time.Time `json:"-"`
+
+	// UUID is storage record identifier, it's kept when records are moved between storages.
+	UUID string `json:"-"`
+	// Deleted is set for soft deleted URLs returned by storage scans.
+	Deleted bool `json:"-"`
+	// Clicks holds click counters of variants returned by storage scans, indexes match Variants.
+	// Counters are kept when records are moved between storages.
+	Clicks []int64 `json:"-"`
+}
+
+// Account is registered user account. UserID is owner id of account links.
+type Account struct {
+	Created      time.Time
+	UserID       string
+	Login        string
+	PasswordHash string
+}
+
+// APIKey is long-lived credential of programmatic client. Key acts on behalf of user
+// and is limited to its scopes. Only hash of key secret is stored.
+type APIKey struct {
+	Created    time.Time
+	ID         string
+	UserID     string
+	Name       string
+	SecretHash string
+	Scopes     []string
+}
+
+// Identity links user to subject of external identity provider.
+// Subjects are unique within issuer.
+type Identity struct {
+	Created time.Time
+	Issuer  string
+	Subject string
+	UserID  string
+	Email   string
+}
+
+// Workspace member roles, each role includes permissions of previous ones.
+const (
+	// RoleViewer allows to list workspace links and read their statistics.
+	RoleViewer = "viewer"
+	// RoleEditor allows to create, edit and delete workspace links.
+	RoleEditor = "editor"
+	// RoleOwner allows to manage workspace and its members.
+	RoleOwner = "owner"
+)
+
+// Workspace is shared space of links managed by its members.
+type Workspace struct {
+	Created time.Time
+	ID      string
+	Name    string
+	// Role is role of user workspace was listed for, it's empty otherwise.
+	Role string
+}
+
+// Member is membership of user in workspace.
+type Member struct {
+	Created     time.Time
+	WorkspaceID string
+	UserID      string
+	Role        string
+}
+
+// IsValidRole returns whether role is known workspace member role.
+func IsValidRole(role string) bool {
+	return roleRank(role) > 0
+}
+
+// RoleAllows returns whether role grants permissions of required role.
+func RoleAllows(role, required string) bool {
+	return IsValidRole(required) && roleRank(role) >= roleRank(required)
+}
+
+// roles are ordered from least to most privileged.
+var roles = []string{RoleViewer, RoleEditor, RoleOwner}
+
+// roleRank returns rank of role, unknown roles have zero rank.
+func roleRank(role string) int {
+	return slices.Index(roles, role) + 1
+}
+
+// Transfer is pending offer to move personal links of one user to another.
+// Links change owner only when recipient accepts offer.
+type Transfer struct {
+	Created    time.Time
+	ID         string
+	FromUserID string
+	ToUserID   string
+	Shorts     []string
+}
+
+// Audit event actions.
+const (
+	// AuditTransferAccepted is recorded when recipient accepts transfer offer.
+	AuditTransferAccepted = "transfer_accepted"
+	// AuditTransferForced is recorded when administrator transfers links without consent of users.
+	AuditTransferForced = "transfer_forced"
+)
+
+// AuditEvent records change of link ownership.
+type AuditEvent struct {
+	Created time.Time
+	ID      string
+	Action  string
+	// ActorID is id of user who made change, it's empty for administrator.
+	ActorID string
+	// TransferID is id of accepted transfer offer, it's empty for forced transfers.
+	TransferID string
+	FromUserID string
+	ToUserID   string
+	Shorts     []string
+}
+
+// Tier is named set of user quotas. Zero limit means no limit.
+type Tier struct {
+	Name string `json:"name"`
+	// MaxURLs is maximum number of active personal links of user.
+	MaxURLs int `json:"max_urls"`
+	// MaxBatch is maximum number of links in single batch.
+	MaxBatch int `json:"max_batch"`
+}
+
+// Quota is quota assignment of user. User either belongs to tier
+// or has custom limits if tier is empty. Zero limit means no limit.
+type Quota struct {
+	Updated  time.Time
+	UserID   string
+	Tier     string
+	MaxURLs  int
+	MaxBatch int
+}
+
+// RateLimit is a token bucket limit. Bucket holds up to Burst tokens
+// and is refilled with Requests tokens every Period, each request takes one token.
+//
+// Bucket state is kept as theoretical arrival time (TAT) of next request,
+// bucket is full if TAT is not after current time. This allows to update
+// bucket with single compare-and-set operation.
+type RateLimit struct {
+	Period   time.Duration
+	Requests int
+	Burst    int
+}
+
+// Interval returns time in which single token is refilled.
+func (l *RateLimit) Interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// Tolerance returns how far TAT of bucket can be ahead of current time.
+func (l *RateLimit) Tolerance() time.Duration {
+	return l.Interval() * time.Duration(l.Burst)
+}
+
+// Take takes token from bucket with specified TAT. If request is allowed,
+// new TAT and zero duration are returned. Otherwise TAT is returned unchanged
+// along with time after which request will be allowed.
+func (l *RateLimit) Take(tat, now time.Time) (time.Time, time.Duration) {
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(l.Interval())
+	if wait := next.Sub(now) - l.Tolerance(); wait > 0 {
+		return tat, wait
+	}
+	return next, 0
+}
+
+// MetaUpdate is a partial update of link metadata, nil fields are not changed.
+type MetaUpdate struct {
+	Title *string   `json:"title,omitempty"`
+	Tags  *[]string `json:"tags,omitempty"`
+	Notes *string   `json:"notes,omitempty"`
+}
+
+// IsProtected returns whether URL is protected by password.
+func (u *URL) IsProtected() bool {
+	return u.PasswordHash != ""
+}
+
+// IsDynamic returns whether URL destination depends on visitor or time,
+// i.e. it has conditional targets, variants or schedule.
+func (u *URL) IsDynamic() bool {
+	return len(u.Targets) > 0 || len(u.Variants) > 0 || u.Schedule != nil
+}
+
+// Variant is weighted destination of URL.
+type Variant struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// Click is a single redirect to URL variant.
+type Click struct {
+	Short   string
+	Variant int
+}
+
+// VariantStats is a click statistics of URL variant.
+type VariantStats struct {
+	URL     string `json:"url"`
+	Variant int    `json:"variant"`
+	Weight  int    `json:"weight"`
+	Clicks  int64  `json:"clicks"`
+}
+
+// Target is conditional destination of URL.
+// Target is matched if all its non-empty conditions are satisfied.
+type Target struct {
+	// Platform is client platform or platform group, see platform package.
+	Platform string `json:"platform,omitempty"`
+	// Country is ISO 3166-1 alpha-2 country code of client.
+	Country string `json:"country,omitempty"`
+	URL     string `json:"url"`
+}
+
+// Schedule is a set of time-window destinations of URL.
+type Schedule struct {
+	// Timezone is IANA time zone name which is used for rule times without offset.
+	// Empty value means server default.
+	Timezone string         `json:"timezone,omitempty"`
+	Rules    []ScheduleRule `json:"rules"`
+}
+
+// ScheduleRule is destination of URL that is active within time window.
+// Window start is inclusive and end is exclusive, either of them can be omitted.
+// Times are in ScheduleTimeLayout or RFC3339 formats.
+type ScheduleRule struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	URL  string `json:"url"`
+}
+
+// ScheduleTimeLayout is a layout of schedule rule time without timezone offset.
+const ScheduleTimeLayout = "2006-01-02T15:04:05"
+
+// ParseScheduleTime parses schedule rule time. Times without offset
+// are parsed in provided location. Seconds can be omitted.
+func ParseScheduleTime(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, ScheduleTimeLayout, "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time format: %q", value)
 }
 
 // Stats is a storage statistics.
@@ -24,3 +320,15 @@ type Stats struct {
 	URLs  int `json:"urls"`
 	Users int `json:"users"`
 }
+
+// IsValidRedirect returns whether status code can be used as URL redirect code.
+func IsValidRedirect(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently,
+		http.StatusFound,
+		http.StatusTemporaryRedirect,
+		http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
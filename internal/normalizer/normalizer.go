@@ -0,0 +1,131 @@
+// Package normalizer implements canonical form of original URLs.
+//
+// Normalization is applied before URL is stored, so different spellings
+// of the same resource are deduplicated by storage backends.
+//
+// Scheme and host are always lower-cased and default ports are stripped.
+// Query sorting, fragment removal and tracking params stripping are optional.
+package normalizer
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	wildcard = "*"
+)
+
+var (
+	defaultPorts = map[string]string{
+		"http":  "80",
+		"https": "443",
+	}
+)
+
+// Normalizer brings URLs to canonical form.
+type Normalizer struct {
+	trackingParams []string
+	sortQuery      bool
+	removeFragment bool
+	stripTracking  bool
+}
+
+// Config is normalizer configuration.
+type Config struct {
+	// TrackingParams is a list of query param names that are considered
+	// as tracking params. Name can end with '*' which matches any suffix.
+	TrackingParams []string
+	SortQuery      bool
+	RemoveFragment bool
+	StripTracking  bool
+}
+
+// New creates normalizer.
+func New(cfg *Config) *Normalizer {
+	return &Normalizer{
+		trackingParams: cfg.TrackingParams,
+		sortQuery:      cfg.SortQuery,
+		removeFragment: cfg.RemoveFragment,
+		stripTracking:  cfg.StripTracking,
+	}
+}
+
+// Normalize modifies parsed URL in place bringing it to canonical form.
+func (n *Normalizer) Normalize(u *url.URL) {
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = normalizeHost(u.Scheme, u.Host)
+
+	if n.removeFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+
+	if u.RawQuery == "" {
+		return
+	}
+	pairs := strings.Split(u.RawQuery, "&")
+	if n.stripTracking {
+		pairs = n.filterTracking(pairs)
+	}
+	if n.sortQuery {
+		sort.SliceStable(pairs, func(i, j int) bool {
+			return queryKey(pairs[i]) < queryKey(pairs[j])
+		})
+	}
+	u.RawQuery = strings.Join(pairs, "&")
+	u.ForceQuery = false
+}
+
+func (n *Normalizer) filterTracking(pairs []string) []string {
+	filtered := pairs[:0]
+	for _, pair := range pairs {
+		if pair == "" || n.isTracking(queryKey(pair)) {
+			continue
+		}
+		filtered = append(filtered, pair)
+	}
+	return filtered
+}
+
+func (n *Normalizer) isTracking(key string) bool {
+	key = strings.ToLower(key)
+	for _, param := range n.trackingParams {
+		if prefix, ok := strings.CutSuffix(param, wildcard); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == param {
+			return true
+		}
+	}
+	return false
+}
+
+// queryKey returns unescaped key of key=value query pair.
+func queryKey(pair string) string {
+	key, _, _ := strings.Cut(pair, "=")
+	if unescaped, err := url.QueryUnescape(key); err == nil {
+		return unescaped
+	}
+	return key
+}
+
+func normalizeHost(scheme, host string) string {
+	host = strings.ToLower(host)
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		// no port
+		return host
+	}
+	if port == "" || defaultPorts[scheme] == port {
+		if strings.Contains(hostname, ":") {
+			// ipv6
+			return "[" + hostname + "]"
+		}
+		return hostname
+	}
+	return host
+}
@@ -0,0 +1,136 @@
+package normalizer
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizer_Normalize(t *testing.T) {
+	type args struct {
+		url            string
+		trackingParams []string
+		sortQuery      bool
+		removeFragment bool
+		stripTracking  bool
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "lower case scheme and host",
+			args: args{
+				url: "HTTP://Example.COM/Some/Path",
+			},
+			want: "http://example.com/Some/Path",
+		},
+		{
+			name: "strip default http port",
+			args: args{
+				url: "http://example.com:80/a",
+			},
+			want: "http://example.com/a",
+		},
+		{
+			name: "strip default https port",
+			args: args{
+				url: "https://example.com:443/a",
+			},
+			want: "https://example.com/a",
+		},
+		{
+			name: "keep non default port",
+			args: args{
+				url: "https://example.com:80/a",
+			},
+			want: "https://example.com:80/a",
+		},
+		{
+			name: "strip default port ipv6",
+			args: args{
+				url: "http://[::1]:80/a",
+			},
+			want: "http://[::1]/a",
+		},
+		{
+			name: "keep query order and fragment by default",
+			args: args{
+				url: "http://example.com/a?b=1&a=2#frag",
+			},
+			want: "http://example.com/a?b=1&a=2#frag",
+		},
+		{
+			name: "sort query",
+			args: args{
+				url:       "HTTP://Example.com:80/a?b=1&a=2",
+				sortQuery: true,
+			},
+			want: "http://example.com/a?a=2&b=1",
+		},
+		{
+			name: "sort query stable for same keys",
+			args: args{
+				url:       "http://example.com/a?b=2&a=1&b=1",
+				sortQuery: true,
+			},
+			want: "http://example.com/a?a=1&b=2&b=1",
+		},
+		{
+			name: "remove fragment",
+			args: args{
+				url:            "http://example.com/a?b=1#frag",
+				removeFragment: true,
+			},
+			want: "http://example.com/a?b=1",
+		},
+		{
+			name: "strip tracking params",
+			args: args{
+				url:            "http://example.com/a?utm_source=x&b=1&fbclid=123&UTM_Medium=y&c",
+				trackingParams: []string{"utm_*", "fbclid"},
+				stripTracking:  true,
+			},
+			want: "http://example.com/a?b=1&c",
+		},
+		{
+			name: "strip all query params",
+			args: args{
+				url:            "http://example.com/a?utm_source=x&fbclid=123",
+				trackingParams: []string{"utm_*", "fbclid"},
+				stripTracking:  true,
+			},
+			want: "http://example.com/a",
+		},
+		{
+			name: "all options",
+			args: args{
+				url:            "HTTPS://Example.com:443/a?utm_campaign=x&z=1&a=2#top",
+				trackingParams: []string{"utm_*", "fbclid"},
+				sortQuery:      true,
+				removeFragment: true,
+				stripTracking:  true,
+			},
+			want: "https://example.com/a?a=2&z=1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := New(&Config{
+				TrackingParams: tt.args.trackingParams,
+				SortQuery:      tt.args.sortQuery,
+				RemoveFragment: tt.args.removeFragment,
+				StripTracking:  tt.args.stripTracking,
+			})
+
+			u, err := url.Parse(tt.args.url)
+			require.NoError(t, err)
+
+			n.Normalize(u)
+			assert.Equal(t, tt.want, u.String())
+		})
+	}
+}
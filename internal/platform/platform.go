@@ -0,0 +1,72 @@
+// Package platform implements client platform detection using User-Agent header.
+//
+// Detection is intentionally simple and based on well-known UA tokens,
+// it's sufficient to route users between app stores and web.
+package platform
+
+import "strings"
+
+// Platform is client platform.
+type Platform string
+
+// Detectable platforms.
+const (
+	Unknown Platform = ""
+	IOS     Platform = "ios"
+	Android Platform = "android"
+	Windows Platform = "windows"
+	MacOS   Platform = "macos"
+	Linux   Platform = "linux"
+)
+
+// Platform groups that can be used as match conditions.
+const (
+	Mobile  = "mobile"
+	Desktop = "desktop"
+)
+
+// Detect returns client platform using User-Agent header value.
+func Detect(userAgent string) Platform {
+	ua := strings.ToLower(userAgent)
+	switch {
+	// iOS must be checked before macOS since iOS UAs contain "like Mac OS X"
+	case strings.Contains(ua, "iphone"),
+		strings.Contains(ua, "ipad"),
+		strings.Contains(ua, "ipod"):
+		return IOS
+	// Android must be checked before Linux since Android UAs contain "Linux"
+	case strings.Contains(ua, "android"):
+		return Android
+	case strings.Contains(ua, "windows"):
+		return Windows
+	case strings.Contains(ua, "macintosh"),
+		strings.Contains(ua, "mac os x"):
+		return MacOS
+	case strings.Contains(ua, "linux"),
+		strings.Contains(ua, "x11"):
+		return Linux
+	}
+	return Unknown
+}
+
+// IsValid returns whether condition can be used for platform matching.
+// Condition is either platform name or platform group.
+func IsValid(cond string) bool {
+	switch cond {
+	case string(IOS), string(Android), string(Windows), string(MacOS), string(Linux),
+		Mobile, Desktop:
+		return true
+	}
+	return false
+}
+
+// Matches checks if platform satisfies condition.
+func (p Platform) Matches(cond string) bool {
+	switch cond {
+	case Mobile:
+		return p == IOS || p == Android
+	case Desktop:
+		return p == Windows || p == MacOS || p == Linux
+	}
+	return p != Unknown && string(p) == cond
+}
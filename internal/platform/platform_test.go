@@ -0,0 +1,110 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want Platform
+	}{
+		{
+			name: "iphone",
+			ua: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 " +
+				"(KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			want: IOS,
+		},
+		{
+			name: "ipad",
+			ua:   "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko)",
+			want: IOS,
+		},
+		{
+			name: "android",
+			ua: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 " +
+				"(KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			want: Android,
+		},
+		{
+			name: "windows",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0",
+			want: Windows,
+		},
+		{
+			name: "macos",
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko)",
+			want: MacOS,
+		},
+		{
+			name: "linux",
+			ua:   "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			want: Linux,
+		},
+		{
+			name: "unknown",
+			ua:   "curl/8.4.0",
+			want: Unknown,
+		},
+		{
+			name: "empty",
+			want: Unknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Detect(tt.ua))
+		})
+	}
+}
+
+func TestPlatform_Matches(t *testing.T) {
+	tests := []struct {
+		name     string
+		platform Platform
+		cond     string
+		want     bool
+	}{
+		{
+			name:     "exact match",
+			platform: IOS,
+			cond:     "ios",
+			want:     true,
+		},
+		{
+			name:     "exact mismatch",
+			platform: Android,
+			cond:     "ios",
+		},
+		{
+			name:     "mobile group",
+			platform: Android,
+			cond:     Mobile,
+			want:     true,
+		},
+		{
+			name:     "desktop group",
+			platform: MacOS,
+			cond:     Desktop,
+			want:     true,
+		},
+		{
+			name:     "desktop group mismatch",
+			platform: IOS,
+			cond:     Desktop,
+		},
+		{
+			name:     "unknown never matches",
+			platform: Unknown,
+			cond:     "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.platform.Matches(tt.cond))
+		})
+	}
+}
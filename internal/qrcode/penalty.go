@@ -0,0 +1,91 @@
+package qrcode
+
+// Penalty weights from specification.
+const (
+	penaltyN1 = 3
+	penaltyN2 = 3
+	penaltyN3 = 40
+	penaltyN4 = 10
+
+	// quietWidth is number of light modules assumed around symbol
+	// when looking for finder-like patterns.
+	quietWidth = 4
+)
+
+// finderLike is 1:1:3:1:1 dark-light pattern preceded or followed by 4 light modules.
+var finderLike = [2][11]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty computes penalty score of current modules.
+func (c *Code) penalty() int {
+	var (
+		result int
+		line   = make([]bool, c.size+2*quietWidth)
+	)
+	for i := 0; i < c.size; i++ {
+		// rows
+		for j := 0; j < c.size; j++ {
+			line[quietWidth+j] = c.modules[i][j]
+		}
+		result += linePenalty(line)
+		// columns
+		for j := 0; j < c.size; j++ {
+			line[quietWidth+j] = c.modules[j][i]
+		}
+		result += linePenalty(line)
+	}
+
+	dark := 0
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.size && y+1 < c.size {
+				color := c.modules[y][x]
+				if color == c.modules[y][x+1] && color == c.modules[y+1][x] && color == c.modules[y+1][x+1] {
+					result += penaltyN2
+				}
+			}
+		}
+	}
+
+	// deviation of dark modules proportion from 50% in 5% steps
+	total := c.size * c.size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return result + k*penaltyN4
+}
+
+// linePenalty computes penalties for runs of same color and for finder-like patterns
+// in a single row or column padded with light quiet zone.
+func linePenalty(line []bool) int {
+	result := 0
+	for start, i := quietWidth, quietWidth; i <= len(line)-quietWidth; i++ {
+		if i < len(line)-quietWidth && line[i] == line[start] {
+			continue
+		}
+		if run := i - start; run >= 5 {
+			result += penaltyN1 + run - 5
+		}
+		start = i
+	}
+	for i := 0; i+len(finderLike[0]) <= len(line); i++ {
+		for _, pattern := range finderLike {
+			if matches(line[i:], pattern[:]) {
+				result += penaltyN3
+			}
+		}
+	}
+	return result
+}
+
+func matches(line, pattern []bool) bool {
+	for i := range pattern {
+		if line[i] != pattern[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,389 @@
+// Package qrcode implements QR code encoder (ISO/IEC 18004).
+//
+// Data is always encoded in byte mode using the smallest version
+// that fits data with requested error correction level.
+// Mask is chosen using penalty rules from specification.
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	minVersion = 1
+	maxVersion = 40
+
+	numMasks = 8
+
+	modeByte = 0x4
+
+	padByte1 = 0xEC
+	padByte2 = 0x11
+)
+
+// ErrTooLong is returned if data does not fit into QR code of the largest version.
+var ErrTooLong = errors.New("data is too long")
+
+// Level is error correction level.
+type Level int
+
+// Error correction levels, they allow to restore approximately
+// 7%, 15%, 25% and 30% of damaged codewords respectively.
+const (
+	LevelL Level = iota
+	LevelM
+	LevelQ
+	LevelH
+)
+
+// ParseLevel parses error correction level from its letter.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return LevelL, nil
+	case "M":
+		return LevelM, nil
+	case "Q":
+		return LevelQ, nil
+	case "H":
+		return LevelH, nil
+	default:
+		return 0, fmt.Errorf("unknown error correction level: %q", s)
+	}
+}
+
+// formatBits returns level bits used in format information.
+func (l Level) formatBits() int {
+	return [...]int{1, 0, 3, 2}[l]
+}
+
+// Code is encoded QR code.
+type Code struct {
+	modules    [][]bool
+	isFunction [][]bool
+	size       int
+	version    int
+	level      Level
+}
+
+// Size returns number of modules on a side of QR code.
+func (c *Code) Size() int {
+	return c.size
+}
+
+// Black returns whether module at specified column and row is dark.
+// Modules outside of code are light.
+func (c *Code) Black(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.size || y >= c.size {
+		return false
+	}
+	return c.modules[y][x]
+}
+
+// Encode encodes data to QR code with specified error correction level.
+func Encode(data []byte, level Level) (*Code, error) {
+	if level < LevelL || level > LevelH {
+		return nil, fmt.Errorf("invalid error correction level: %d", level)
+	}
+	version, err := chooseVersion(len(data), level)
+	if err != nil {
+		return nil, err
+	}
+	c := newCode(version, level)
+	c.drawFunctionPatterns()
+	c.drawCodewords(addErrorCorrection(encodeData(data, version, level), version, level))
+	c.applyBestMask()
+	return c, nil
+}
+
+// chooseVersion returns the smallest version that fits data of specified length.
+func chooseVersion(length int, level Level) (int, error) {
+	for version := minVersion; version <= maxVersion; version++ {
+		if dataBits(length, version) <= numDataCodewords(version, level)*8 {
+			return version, nil
+		}
+	}
+	return 0, ErrTooLong
+}
+
+// dataBits returns number of bits used by byte mode segment.
+func dataBits(length, version int) int {
+	return 4 + charCountBits(version) + length*8
+}
+
+// charCountBits returns length of byte mode character count indicator.
+func charCountBits(version int) int {
+	if version < 10 {
+		return 8 //nolint:gomnd // specification value
+	}
+	return 16 //nolint:gomnd // specification value
+}
+
+// encodeData creates data codewords: segment, terminator and padding.
+func encodeData(data []byte, version int, level Level) []byte {
+	var (
+		bb       bitBuffer
+		capacity = numDataCodewords(version, level) * 8
+	)
+	bb.append(modeByte, 4)
+	bb.append(len(data), charCountBits(version))
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+	bb.append(0, min(4, capacity-bb.len()))
+	bb.append(0, (8-bb.len()%8)%8)
+	for pad := padByte1; bb.len() < capacity; pad ^= padByte1 ^ padByte2 {
+		bb.append(pad, 8)
+	}
+	return bb.bytes()
+}
+
+func newCode(version int, level Level) *Code {
+	size := version*4 + 17
+	c := &Code{
+		modules:    make([][]bool, size),
+		isFunction: make([][]bool, size),
+		size:       size,
+		version:    version,
+		level:      level,
+	}
+	for i := 0; i < size; i++ {
+		c.modules[i] = make([]bool, size)
+		c.isFunction[i] = make([]bool, size)
+	}
+	return c
+}
+
+func (c *Code) setFunctionModule(x, y int, black bool) {
+	c.modules[y][x] = black
+	c.isFunction[y][x] = true
+}
+
+// drawFunctionPatterns draws finder, timing, alignment patterns,
+// and reserves format and version information areas.
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.size; i++ {
+		c.setFunctionModule(6, i, i%2 == 0)
+		c.setFunctionModule(i, 6, i%2 == 0)
+	}
+	c.drawFinderPattern(3, 3)
+	c.drawFinderPattern(c.size-4, 3)
+	c.drawFinderPattern(3, c.size-4)
+
+	positions := alignmentPositions(c.version)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// skip corners occupied by finder patterns
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignmentPattern(x, y)
+		}
+	}
+	c.drawFormatBits(0)
+	c.drawVersion()
+}
+
+func (c *Code) drawFinderPattern(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= c.size || yy >= c.size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunctionModule(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignmentPattern(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunctionModule(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormatBits draws both copies of format information and dark module.
+func (c *Code) drawFormatBits(mask int) {
+	bits := formatInfo(c.level, mask)
+	for i := 0; i <= 5; i++ {
+		c.setFunctionModule(8, i, bit(bits, i))
+	}
+	c.setFunctionModule(8, 7, bit(bits, 6))
+	c.setFunctionModule(8, 8, bit(bits, 7))
+	c.setFunctionModule(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		c.setFunctionModule(14-i, 8, bit(bits, i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunctionModule(c.size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunctionModule(8, c.size-15+i, bit(bits, i))
+	}
+	c.setFunctionModule(8, c.size-8, true)
+}
+
+// drawVersion draws both copies of version information, it's present since version 7.
+func (c *Code) drawVersion() {
+	if c.version < 7 {
+		return
+	}
+	bits := versionInfo(c.version)
+	for i := 0; i < 18; i++ {
+		a, b := c.size-11+i%3, i/3
+		c.setFunctionModule(a, b, bit(bits, i))
+		c.setFunctionModule(b, a, bit(bits, i))
+	}
+}
+
+// formatInfo returns 15 bit format information with BCH error correction.
+func formatInfo(level Level, mask int) int {
+	data := level.formatBits()<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// versionInfo returns 18 bit version information with BCH error correction.
+func versionInfo(version int) int {
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	return version<<12 | rem
+}
+
+// drawCodewords places codewords into data area in zigzag order.
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// skip vertical timing pattern
+			right = 5
+		}
+		for vert := 0; vert < c.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					// upward direction
+					y = c.size - 1 - vert
+				}
+				if c.isFunction[y][x] || i >= len(data)*8 {
+					continue
+				}
+				c.modules[y][x] = bit(int(data[i>>3]), 7-i&7)
+				i++
+			}
+		}
+	}
+}
+
+// applyBestMask applies mask with the lowest penalty score.
+func (c *Code) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < numMasks; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if penalty := c.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		c.applyMask(mask) // XOR again to undo
+	}
+	c.applyMask(best)
+	c.drawFormatBits(best)
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !c.isFunction[y][x] && maskBit(mask, x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+func maskBit(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// alignmentPositions returns center coordinates of alignment patterns.
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	var (
+		num       = version/7 + 2
+		step      = (version*8 + num*3 + 5) / (num*4 - 4) * 2
+		positions = make([]int, num)
+	)
+	positions[0] = 6
+	for i, pos := num-1, version*4+10; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+func bit(x, i int) bool {
+	return (x>>i)&1 != 0
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+type bitBuffer struct {
+	data []byte
+	n    int
+}
+
+func (bb *bitBuffer) len() int {
+	return bb.n
+}
+
+// append appends specified number of low bits of value, most significant first.
+func (bb *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		if bb.n%8 == 0 {
+			bb.data = append(bb.data, 0)
+		}
+		if bit(value, i) {
+			bb.data[bb.n/8] |= 1 << (7 - bb.n%8)
+		}
+		bb.n++
+	}
+}
+
+func (bb *bitBuffer) bytes() []byte {
+	return bb.data
+}
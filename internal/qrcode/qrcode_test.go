@@ -0,0 +1,218 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// 1-M "HELLO WORLD" example from specification
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	ecc := reedSolomonRemainder(data, reedSolomonDivisor(10))
+	assert.Equal(t, []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}, ecc)
+}
+
+func TestFormatAndVersionInfo(t *testing.T) {
+	assert.Equal(t, 0b111011111000100, formatInfo(LevelL, 0))
+	assert.Equal(t, 0b101010000010010, formatInfo(LevelM, 0))
+	assert.Equal(t, 0b011010101011111, formatInfo(LevelQ, 0))
+	assert.Equal(t, 0b001011010001001, formatInfo(LevelH, 0))
+	assert.Equal(t, 0b100101010100000, formatInfo(LevelM, 7))
+
+	assert.Equal(t, 0b000111110010010100, versionInfo(7))
+	assert.Equal(t, 0b101000110001101001, versionInfo(40))
+}
+
+func TestNumDataCodewords(t *testing.T) {
+	assert.Equal(t, 19, numDataCodewords(1, LevelL))
+	assert.Equal(t, 16, numDataCodewords(1, LevelM))
+	assert.Equal(t, 9, numDataCodewords(1, LevelH))
+	assert.Equal(t, 62, numDataCodewords(5, LevelQ))
+	assert.Equal(t, 216, numDataCodewords(10, LevelM))
+	assert.Equal(t, 2956, numDataCodewords(40, LevelL))
+	assert.Equal(t, 1276, numDataCodewords(40, LevelH))
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		level   Level
+		version int
+		err     error
+	}{
+		{name: "empty", data: "", level: LevelL, version: 1},
+		{name: "short url", data: "http://localhost:8080/qweasdzx", level: LevelM, version: 3},
+		{name: "version with single block max", data: strings.Repeat("a", 17), level: LevelL, version: 1},
+		{name: "next version", data: strings.Repeat("a", 18), level: LevelL, version: 2},
+		{name: "version info", data: strings.Repeat("a", 200), level: LevelQ, version: 12},
+		{name: "long count indicator", data: strings.Repeat("a", 400), level: LevelH, version: 21},
+		{name: "largest", data: strings.Repeat("a", 2953), level: LevelL, version: 40},
+		{name: "too long", data: strings.Repeat("a", 1274), level: LevelH, err: ErrTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Encode([]byte(tt.data), tt.level)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, c.version)
+			assert.Equal(t, tt.version*4+17, c.Size())
+			assert.Equal(t, []byte(tt.data), decode(t, c))
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for s, want := range map[string]Level{"l": LevelL, "M": LevelM, "q": LevelQ, "H": LevelH} {
+		level, err := ParseLevel(s)
+		require.NoError(t, err)
+		assert.Equal(t, want, level)
+	}
+	_, err := ParseLevel("X")
+	assert.Error(t, err)
+}
+
+func TestCode_Render(t *testing.T) {
+	c, err := Encode([]byte("http://localhost:8080/qweasdzx"), LevelM)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, c.WritePNG(&buf, 256, 4))
+	img, err := png.Decode(&buf)
+	require.NoError(t, err)
+	// 29 modules + 8 margin, 6 pixels per module
+	assert.Equal(t, 222, img.Bounds().Dx())
+	assert.Equal(t, 222, img.Bounds().Dy())
+	// quiet zone is light, top-left finder corner is dark
+	assertDark(t, false, img.At(23, 23))
+	assertDark(t, true, img.At(24, 24))
+
+	buf.Reset()
+	require.NoError(t, c.WriteSVG(&buf, 300, 2))
+	svg := buf.String()
+	assert.True(t, strings.HasPrefix(svg, "<svg "))
+	assert.Contains(t, svg, `width="300" height="300" viewBox="0 0 33 33"`)
+	// top row of top-left finder pattern
+	assert.Contains(t, svg, `d="M2 2h7v1h-7z`)
+}
+
+func assertDark(t *testing.T, dark bool, c interface{ RGBA() (r, g, b, a uint32) }) {
+	t.Helper()
+	r, _, _, _ := c.RGBA()
+	assert.Equal(t, dark, r == 0)
+}
+
+// decode reads data back from code using format information
+// to check placement of codewords and masking.
+func decode(t *testing.T, c *Code) []byte {
+	t.Helper()
+
+	var format int
+	for i := 0; i <= 5; i++ {
+		format |= b2i(c.modules[i][8]) << i
+	}
+	format |= b2i(c.modules[7][8])<<6 | b2i(c.modules[8][8])<<7 | b2i(c.modules[8][7])<<8
+	for i := 9; i < 15; i++ {
+		format |= b2i(c.modules[8][14-i]) << i
+	}
+	format ^= 0x5412
+	require.Equal(t, c.level.formatBits(), format>>13)
+	mask := format >> 10 & 7
+
+	// read codewords in zigzag order
+	var raw []byte
+	bits := 0
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = c.size - 1 - vert
+				}
+				if c.isFunction[y][x] {
+					continue
+				}
+				if bits%8 == 0 {
+					raw = append(raw, 0)
+				}
+				if c.modules[y][x] != maskBit(mask, x, y) {
+					raw[bits/8] |= 1 << (7 - bits%8)
+				}
+				bits++
+			}
+		}
+	}
+
+	// de-interleave data codewords and check error correction of each block
+	var (
+		numBlocks     = numErrorCorrectionBlocks[c.level][c.version]
+		eccLen        = eccCodewordsPerBlock[c.level][c.version]
+		rawCodewords  = numRawDataModules(c.version) / 8
+		numShort      = numBlocks - rawCodewords%numBlocks
+		shortDataLen  = rawCodewords/numBlocks - eccLen
+		blocks        = make([][]byte, numBlocks)
+		numDataTotal  = numDataCodewords(c.version, c.level)
+		divisor       = reedSolomonDivisor(eccLen)
+		dataCodewords []byte
+	)
+	k := 0
+	for i := 0; i <= shortDataLen; i++ {
+		for j := range blocks {
+			if i == shortDataLen && j < numShort {
+				continue
+			}
+			blocks[j] = append(blocks[j], raw[k])
+			k++
+		}
+	}
+	require.Equal(t, numDataTotal, k)
+	eccs := make([][]byte, numBlocks)
+	for i := 0; i < eccLen; i++ {
+		for j := range blocks {
+			eccs[j] = append(eccs[j], raw[k])
+			k++
+		}
+	}
+	for j, block := range blocks {
+		require.Equal(t, reedSolomonRemainder(block, divisor), eccs[j])
+		dataCodewords = append(dataCodewords, block...)
+	}
+
+	// parse byte mode segment
+	var (
+		pos  = 0
+		read = func(n int) int {
+			v := 0
+			for i := 0; i < n; i++ {
+				v = v<<1 | int(dataCodewords[pos/8]>>(7-pos%8)&1)
+				pos++
+			}
+			return v
+		}
+	)
+	require.Equal(t, modeByte, read(4))
+	length := read(charCountBits(c.version))
+	result := make([]byte, length)
+	for i := range result {
+		result[i] = byte(read(8))
+	}
+	return result
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
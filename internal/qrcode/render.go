@@ -0,0 +1,74 @@
+package qrcode
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// Image returns QR code image with quiet zone of margin modules.
+// Image side is the largest multiple of modules count that does not exceed size,
+// but every module takes at least one pixel.
+func (c *Code) Image(size, margin int) image.Image {
+	var (
+		side    = c.size + 2*margin
+		scale   = max(1, size/side)
+		palette = color.Palette{color.White, color.Black}
+		img     = image.NewPaletted(image.Rect(0, 0, side*scale, side*scale), palette)
+	)
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				offset := img.PixOffset((margin+x)*scale, (margin+y)*scale+dy)
+				for dx := 0; dx < scale; dx++ {
+					img.Pix[offset+dx] = 1
+				}
+			}
+		}
+	}
+	return img
+}
+
+// WritePNG writes QR code as PNG image.
+func (c *Code) WritePNG(w io.Writer, size, margin int) error {
+	if err := png.Encode(w, c.Image(size, margin)); err != nil {
+		return fmt.Errorf("cannot encode png: %w", err)
+	}
+	return nil
+}
+
+// WriteSVG writes QR code as SVG image of specified size.
+// Dark modules of each row are merged into horizontal runs.
+func (c *Code) WriteSVG(w io.Writer, size, margin int) error {
+	var (
+		bw   = bufio.NewWriter(w)
+		side = c.size + 2*margin
+	)
+	_, _ = fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" `+
+		`width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="#fff"/><path fill="#000" d="`, size, size, side, side)
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			run := 1
+			for x+run < c.size && c.modules[y][x+run] {
+				run++
+			}
+			_, _ = fmt.Fprintf(bw, "M%d %dh%dv1h-%dz", margin+x, margin+y, run, run)
+			x += run
+		}
+	}
+	_, _ = bw.WriteString(`"/></svg>`)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot write svg: %w", err)
+	}
+	return nil
+}
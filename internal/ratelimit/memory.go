@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+)
+
+// sweepInterval is interval of removal of full buckets from memory store.
+const sweepInterval = time.Minute
+
+// MemoryStore is in-memory store of token buckets, it's used by single instance.
+type MemoryStore struct {
+	buckets   map[string]time.Time
+	lastSweep time.Time
+	mux       *sync.Mutex
+}
+
+// NewMemoryStore creates in-memory store of token buckets.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]time.Time),
+		mux:     &sync.Mutex{},
+	}
+}
+
+// TakeToken takes token from bucket with specified key.
+// Full buckets are removed periodically, so memory is not held by inactive clients.
+func (s *MemoryStore) TakeToken(
+	_ context.Context,
+	key string,
+	limit *model.RateLimit,
+	now time.Time,
+) (time.Duration, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for k, tat := range s.buckets {
+			if !tat.After(now) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	tat, wait := limit.Take(s.buckets[key], now)
+	if wait == 0 {
+		s.buckets[key] = tat
+	}
+	return wait, nil
+}
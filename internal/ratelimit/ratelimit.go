@@ -0,0 +1,158 @@
+// Package ratelimit contains token bucket rate limiter.
+//
+// Limiter has ordered list of rules, each rule matches routes by pattern
+// and defines token bucket limit. Every client has its own bucket per rule,
+// clients are identified by user id or by ip address.
+//
+// Bucket states are kept in Store, it can be in-memory store of single instance
+// or shared store used by several instances.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+	"go.uber.org/zap"
+)
+
+// Store keeps token buckets.
+type Store interface {
+	// TakeToken takes token from bucket with specified key. It returns zero duration if request is allowed,
+	// otherwise time after which request will be allowed is returned.
+	TakeToken(ctx context.Context, key string, limit *model.RateLimit, now time.Time) (time.Duration, error)
+}
+
+// Rule is rate limit of routes matched by pattern.
+type Rule struct {
+	// Pattern matches route exactly, trailing '*' matches any suffix.
+	// Routes are "<METHOD> <path>" for http requests and full method names for rpcs.
+	Pattern string
+	Limit   model.RateLimit
+}
+
+// Match returns whether rule pattern matches route.
+func (r *Rule) Match(route string) bool {
+	if prefix, ok := strings.CutSuffix(r.Pattern, "*"); ok {
+		return strings.HasPrefix(route, prefix)
+	}
+	return r.Pattern == route
+}
+
+// Config is rate limiter configuration.
+type Config struct {
+	Store  Store
+	Logger *zap.Logger
+	Rules  []Rule
+}
+
+// Limiter is token bucket rate limiter.
+type Limiter struct {
+	store Store
+	log   *zap.Logger
+	rules []Rule
+}
+
+// New creates rate limiter. In-memory store is used if store is not set in config.
+func New(cfg *Config) *Limiter {
+	store := cfg.Store
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &Limiter{
+		store: store,
+		log:   cfg.Logger.With(zap.String("component", "ratelimit")),
+		rules: cfg.Rules,
+	}
+}
+
+// Allow takes token from bucket of client on route. It returns zero duration if request is allowed,
+// otherwise time after which client can retry is returned. Routes without matching rule are not limited.
+//
+// Limiter fails open, so requests are allowed if store returns error.
+func (l *Limiter) Allow(ctx context.Context, route, client string) time.Duration {
+	for i := range l.rules {
+		rule := &l.rules[i]
+		if !rule.Match(route) {
+			continue
+		}
+		wait, err := l.store.TakeToken(ctx, rule.Pattern+"|"+client, &rule.Limit, time.Now())
+		if err != nil {
+			l.log.Error("cannot take token", zap.String("client", client), zap.Error(err))
+			return 0
+		}
+		return wait
+	}
+	return 0
+}
+
+// ClientKey returns client identifier. Authenticated users are identified
+// by user id, other clients by ip address.
+func ClientKey(u *user.User, clientIP string) string {
+	if u != nil && !u.IsNew() {
+		return "user:" + u.ID
+	}
+	return "ip:" + clientIP
+}
+
+// RetryAfter returns value of Retry-After header, it's wait duration rounded up to seconds.
+func RetryAfter(wait time.Duration) string {
+	return strconv.FormatInt(int64((wait+time.Second-1)/time.Second), 10)
+}
+
+// ParseRules parses comma separated list of rules.
+// Rule format is <pattern>=<requests>/<period>[:<burst>], e.g. "POST /=10/1m:20".
+// Burst is equal to requests if omitted.
+func ParseRules(rules string) ([]Rule, error) {
+	var result []Rule
+	for _, ruleS := range strings.Split(rules, ",") {
+		if ruleS = strings.TrimSpace(ruleS); ruleS == "" {
+			continue
+		}
+		rule, err := parseRule(ruleS)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit rule %q: %w", ruleS, err)
+		}
+		result = append(result, rule)
+	}
+	return result, nil
+}
+
+func parseRule(ruleS string) (Rule, error) {
+	pattern, limitS, ok := strings.Cut(ruleS, "=")
+	if !ok || strings.TrimSpace(pattern) == "" {
+		return Rule{}, errors.New("route pattern is missing")
+	}
+	limitS, burstS, hasBurst := strings.Cut(limitS, ":")
+	requestsS, periodS, ok := strings.Cut(limitS, "/")
+	if !ok {
+		return Rule{}, errors.New("limit must be <requests>/<period>")
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(requestsS))
+	if err != nil || requests <= 0 {
+		return Rule{}, errors.New("requests must be positive integer")
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(periodS))
+	if err != nil || period < time.Duration(requests) {
+		return Rule{}, errors.New("period must be positive duration")
+	}
+	burst := requests
+	if hasBurst {
+		if burst, err = strconv.Atoi(strings.TrimSpace(burstS)); err != nil || burst <= 0 {
+			return Rule{}, errors.New("burst must be positive integer")
+		}
+	}
+	return Rule{
+		Pattern: strings.TrimSpace(pattern),
+		Limit: model.RateLimit{
+			Period:   period,
+			Requests: requests,
+			Burst:    burst,
+		},
+	}, nil
+}
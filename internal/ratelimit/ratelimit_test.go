@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("POST /=10/1m:20, /shorty.shortener/*=5/1s ,")
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, Rule{
+		Pattern: "POST /",
+		Limit:   model.RateLimit{Period: time.Minute, Requests: 10, Burst: 20},
+	}, rules[0])
+	assert.Equal(t, Rule{
+		Pattern: "/shorty.shortener/*",
+		Limit:   model.RateLimit{Period: time.Second, Requests: 5, Burst: 5},
+	}, rules[1])
+
+	for _, rule := range []string{"POST /", "=1/1s", "*=0/1s", "*=1/qwe", "*=1/-1s", "*=1/1s:0", "*=1"} {
+		_, err = ParseRules(rule)
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestMemoryStore_TakeToken(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = NewMemoryStore()
+		limit = &model.RateLimit{Period: time.Minute, Requests: 2, Burst: 3}
+		now   = time.Now()
+	)
+	// burst is available at once, then tokens are refilled every 30s
+	for i := 0; i < 3; i++ {
+		wait, err := store.TakeToken(ctx, "client", limit, now)
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+	}
+	wait, err := store.TakeToken(ctx, "client", limit, now.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 20*time.Second, wait)
+	wait, err = store.TakeToken(ctx, "other", limit, now.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+	wait, err = store.TakeToken(ctx, "client", limit, now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+
+	// full buckets are removed
+	_, err = store.TakeToken(ctx, "client", limit, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, store.buckets, 1)
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	ctx := context.Background()
+	rules, err := ParseRules("POST /=1/1m,POST /api/*=2/1m")
+	require.NoError(t, err)
+	limiter := New(&Config{Rules: rules, Logger: zap.NewNop()})
+
+	assert.Zero(t, limiter.Allow(ctx, "POST /", "ip:1.1.1.1"))
+	assert.NotZero(t, limiter.Allow(ctx, "POST /", "ip:1.1.1.1"))
+	assert.Zero(t, limiter.Allow(ctx, "POST /", "ip:2.2.2.2"))
+
+	// routes without rule are not limited
+	for i := 0; i < 3; i++ {
+		assert.Zero(t, limiter.Allow(ctx, "GET /", "ip:1.1.1.1"))
+	}
+
+	// routes matched by one rule share bucket
+	assert.Zero(t, limiter.Allow(ctx, "POST /api/shorten", "ip:1.1.1.1"))
+	assert.Zero(t, limiter.Allow(ctx, "POST /api/shorten/batch", "ip:1.1.1.1"))
+	assert.NotZero(t, limiter.Allow(ctx, "POST /api/shorten", "ip:1.1.1.1"))
+}
+
+func TestClientKey(t *testing.T) {
+	u, err := user.New()
+	require.NoError(t, err)
+	assert.Equal(t, "ip:1.1.1.1", ClientKey(nil, "1.1.1.1"))
+	assert.Equal(t, "ip:1.1.1.1", ClientKey(u, "1.1.1.1"))
+	assert.Equal(t, "user:"+u.ID, ClientKey(user.NewWithID(u.ID), "1.1.1.1"))
+	assert.Equal(t, "2", RetryAfter(1001*time.Millisecond))
+}
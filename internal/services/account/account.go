@@ -0,0 +1,266 @@
+// Package account is user account service.
+// It provides registration and login with password, issues anonymous sessions
+// and revokes sessions on logout.
+//
+// Registered user gets new user id, links of anonymous user
+// who registers or logs in can be claimed into account.
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minLoginLength    = 3
+	maxLoginLength    = 64
+	minPasswordLength = 8
+	// bcrypt ignores password bytes beyond 72.
+	maxPasswordLength = 72
+)
+
+// Service errors.
+var (
+	ErrInvalidLogin       = errors.New("invalid login")
+	ErrInvalidPassword    = errors.New("invalid password")
+	ErrLoginTaken         = errors.New("login is already taken")
+	ErrInvalidCredentials = errors.New("invalid login or password")
+	ErrStorageError       = errors.New("storage error")
+	ErrNoSession          = errors.New("user has no session")
+)
+
+// Storage is account storage.
+type Storage interface {
+	CreateAccount(ctx context.Context, acc *model.Account) error
+	GetAccount(ctx context.Context, login string) (*model.Account, error)
+	ClaimUserURLs(ctx context.Context, fromUserID, toUserID string) (int64, error)
+	CountUserURLs(ctx context.Context, userID string) (int, error)
+}
+
+// Quotas enforces link quota of account which claims links. Quota errors are returned to caller as is.
+type Quotas interface {
+	CheckURLs(ctx context.Context, userID string, links int) error
+}
+
+// Authorizer issues and revokes auth tokens.
+type Authorizer interface {
+	CreateToken(u *user.User) (string, error)
+	RevokeSession(ctx context.Context, u *user.User) error
+}
+
+// Service is user account service.
+type Service struct {
+	store  Storage
+	auth   Authorizer
+	quotas Quotas
+	log    *zap.Logger
+
+	// dummyHash is compared with password of unknown login,
+	// so response time does not reveal whether account exists.
+	dummyHash []byte
+}
+
+// Config is account service config.
+type Config struct {
+	Storage    Storage
+	Authorizer Authorizer
+	// Quotas is optional, account quota is not checked on claim if it's not set.
+	Quotas Quotas
+	Logger *zap.Logger
+}
+
+// Session is authenticated account session.
+type Session struct {
+	User  *user.User
+	Token string
+	// Claimed is number of links claimed from anonymous user.
+	Claimed int64
+}
+
+// New creates account service.
+func New(cfg *Config) (*Service, error) {
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("cannot hash dummy password: %w", err)
+	}
+	return &Service{
+		store:     cfg.Storage,
+		auth:      cfg.Authorizer,
+		quotas:    cfg.Quotas,
+		log:       cfg.Logger.With(zap.String("component", "account")),
+		dummyHash: dummyHash,
+	}, nil
+}
+
+// Register creates account with new user id. Links of current anonymous user
+// are claimed into account, so registration upgrades anonymous session.
+// Account is not created if claimed links exceed its quota.
+func (svc *Service) Register(ctx context.Context, u *user.User, login, password string) (*Session, error) {
+	login, err := prepareLogin(login)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidLogin, err)
+	}
+	if err = validatePassword(password); err != nil {
+		return nil, errors.Join(ErrInvalidPassword, err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("cannot hash password: %w", err)
+	}
+	accUser, err := user.New()
+	if err != nil {
+		return nil, fmt.Errorf("cannot create account user: %w", err)
+	}
+	if err = svc.checkClaim(ctx, u, accUser.ID); err != nil {
+		return nil, err
+	}
+	err = svc.store.CreateAccount(ctx, &model.Account{
+		UserID:       accUser.ID,
+		Login:        login,
+		PasswordHash: string(hash),
+	})
+	if err != nil {
+		if errors.Is(err, model.ErrAlreadyExists) {
+			return nil, ErrLoginTaken
+		}
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	return svc.newSession(ctx, u, &model.Account{UserID: accUser.ID, Login: login}, true)
+}
+
+// Login authenticates account with password.
+// If claim is set, links of current anonymous user are claimed into account,
+// login is rejected if they exceed account quota.
+func (svc *Service) Login(ctx context.Context, u *user.User, login, password string, claim bool) (*Session, error) {
+	login, err := prepareLogin(login)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	acc, err := svc.store.GetAccount(ctx, login)
+	if err != nil {
+		if !errors.Is(err, model.ErrNotFound) {
+			return nil, errors.Join(ErrStorageError, err)
+		}
+		_ = bcrypt.CompareHashAndPassword(svc.dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err = bcrypt.CompareHashAndPassword([]byte(acc.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if claim {
+		if err = svc.checkClaim(ctx, u, acc.UserID); err != nil {
+			return nil, err
+		}
+	}
+	return svc.newSession(ctx, u, acc, claim)
+}
+
+// Anonymous issues session of new anonymous user. User created for current request
+// is reused, otherwise new user is generated, so anonymous user can be created
+// even when authentication does not create users.
+func (svc *Service) Anonymous(u *user.User) (*Session, error) {
+	if !u.IsNew() {
+		var err error
+		if u, err = user.New(); err != nil {
+			return nil, fmt.Errorf("cannot create anonymous user: %w", err)
+		}
+	}
+	token, err := svc.auth.CreateToken(u)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token: %w", err)
+	}
+	return &Session{User: u, Token: token}, nil
+}
+
+// Logout revokes session of user, so its tokens are no longer accepted.
+// User created during current request and api key user have no session to revoke.
+func (svc *Service) Logout(ctx context.Context, u *user.User) error {
+	if u.IsNew() || u.IsAPIKey() {
+		return ErrNoSession
+	}
+	if err := svc.auth.RevokeSession(ctx, u); err != nil {
+		return errors.Join(ErrStorageError, err)
+	}
+	svc.log.Debug("session revoked",
+		zap.String("user", u.ID),
+		zap.String("session", u.SessionID))
+	return nil
+}
+
+// newSession claims links of anonymous user if requested and issues account token.
+// Account quota must be checked by caller with checkClaim.
+func (svc *Service) newSession(ctx context.Context, u *user.User, acc *model.Account, claim bool) (*Session, error) {
+	sess := &Session{User: user.NewWithID(acc.UserID)}
+	sess.User.Login = acc.Login
+	if claim && canClaim(u, acc.UserID) {
+		num, err := svc.store.ClaimUserURLs(ctx, u.ID, acc.UserID)
+		if err != nil {
+			return nil, errors.Join(ErrStorageError, err)
+		}
+		sess.Claimed = num
+		svc.log.Debug("links claimed",
+			zap.String("from", u.ID),
+			zap.String("login", acc.Login),
+			zap.Int64("claimed", num))
+	}
+	token, err := svc.auth.CreateToken(sess.User)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token: %w", err)
+	}
+	sess.Token = token
+	return sess, nil
+}
+
+// checkClaim checks that active personal links of user can be claimed
+// into account without exceeding its quota.
+func (svc *Service) checkClaim(ctx context.Context, u *user.User, accUserID string) error {
+	if svc.quotas == nil || !canClaim(u, accUserID) {
+		return nil
+	}
+	num, err := svc.store.CountUserURLs(ctx, u.ID)
+	if err != nil {
+		return errors.Join(ErrStorageError, err)
+	}
+	if num == 0 {
+		return nil
+	}
+	return svc.quotas.CheckURLs(ctx, accUserID, num) //nolint:wrapcheck // quota errors carry limits
+}
+
+// canClaim checks if links of user can be claimed into account.
+// Links are never claimed from another account or from user without session.
+func canClaim(u *user.User, accUserID string) bool {
+	return !u.IsNew() && !u.IsRegistered() && u.ID != accUserID
+}
+
+// prepareLogin validates login and brings it to canonical lower case form.
+func prepareLogin(login string) (string, error) {
+	login = strings.ToLower(strings.TrimSpace(login))
+	if len(login) < minLoginLength || len(login) > maxLoginLength {
+		return "", fmt.Errorf("login length must be from %d to %d characters", minLoginLength, maxLoginLength)
+	}
+	for i := 0; i < len(login); i++ {
+		c := login[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '.' && c != '_' && c != '-' && c != '@' {
+			return "", fmt.Errorf("invalid character in login: %q", c)
+		}
+	}
+	return login, nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password is shorter than %d bytes", minPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("password is longer than %d bytes", maxPasswordLength)
+	}
+	return nil
+}
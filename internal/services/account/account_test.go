@@ -0,0 +1,155 @@
+package account
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adwski/shorty/internal/auth"
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/services/quota"
+	"github.com/adwski/shorty/internal/storage/memory"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestService_RegisterAndLogin(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	svc, err := New(&Config{
+		Storage:    store,
+		Authorizer: auth.New("secret"),
+		Logger:     zap.NewNop(),
+	})
+	require.NoError(t, err)
+
+	anon := user.NewWithID("anonymous")
+	require.NoError(t, store.StoreBatch(ctx, []model.URL{
+		{Short: "aaa", Orig: "https://aaa.bbb/1", UserID: anon.ID},
+		{Short: "bbb", Orig: "https://aaa.bbb/2", UserID: anon.ID},
+	}))
+
+	_, err = svc.Register(ctx, anon, "x", "password1")
+	assert.ErrorIs(t, err, ErrInvalidLogin)
+	_, err = svc.Register(ctx, anon, "bad login", "password1")
+	assert.ErrorIs(t, err, ErrInvalidLogin)
+	_, err = svc.Register(ctx, anon, "alice", "short")
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+
+	// registration upgrades anonymous user and claims its links
+	sess, err := svc.Register(ctx, anon, " Alice ", "password1")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", sess.User.Login)
+	assert.NotEqual(t, anon.ID, sess.User.ID)
+	assert.Equal(t, int64(2), sess.Claimed)
+	assert.NotEmpty(t, sess.Token)
+	urls, err := store.ListUserURLs(ctx, sess.User.ID, "")
+	require.NoError(t, err)
+	assert.Len(t, urls, 2)
+
+	_, err = svc.Register(ctx, anon, "ALICE", "password2")
+	assert.ErrorIs(t, err, ErrLoginTaken)
+
+	_, err = svc.Login(ctx, anon, "alice", "password2", true)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = svc.Login(ctx, anon, "bob", "password1", true)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// login claims links only if requested
+	anon2 := user.NewWithID("anonymous2")
+	_, err = store.Store(ctx, &model.URL{Short: "ccc", Orig: "https://aaa.bbb/3", UserID: anon2.ID}, false)
+	require.NoError(t, err)
+	loggedIn, err := svc.Login(ctx, anon2, "Alice", "password1", false)
+	require.NoError(t, err)
+	assert.Equal(t, sess.User.ID, loggedIn.User.ID)
+	assert.Zero(t, loggedIn.Claimed)
+
+	loggedIn, err = svc.Login(ctx, anon2, "alice", "password1", true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), loggedIn.Claimed)
+
+	parsed, _, err := auth.New("secret").CreateOrParseUserFromJWTString(ctx, loggedIn.Token)
+	require.NoError(t, err)
+	assert.Equal(t, sess.User.ID, parsed.ID)
+	assert.Equal(t, "alice", parsed.Login)
+
+	// links are not claimed from other accounts
+	other, err := svc.Register(ctx, user.NewWithID("anonymous3"), "bob", "password3")
+	require.NoError(t, err)
+	loggedIn, err = svc.Login(ctx, other.User, "alice", "password1", true)
+	require.NoError(t, err)
+	assert.Zero(t, loggedIn.Claimed)
+}
+
+func TestService_Anonymous(t *testing.T) {
+	svc, err := New(&Config{
+		Storage:    memory.New(),
+		Authorizer: auth.New("secret"),
+		Logger:     zap.NewNop(),
+	})
+	require.NoError(t, err)
+
+	newUser, err := user.New()
+	require.NoError(t, err)
+	sess, err := svc.Anonymous(newUser)
+	require.NoError(t, err)
+	assert.Equal(t, newUser.ID, sess.User.ID)
+
+	// existing session is replaced with new anonymous user
+	sess, err = svc.Anonymous(user.NewWithID("existing"))
+	require.NoError(t, err)
+	assert.NotEqual(t, "existing", sess.User.ID)
+	parsed, err := auth.New("secret").ParseUserFromJWTString(context.Background(), sess.Token)
+	require.NoError(t, err)
+	assert.Equal(t, sess.User.ID, parsed.ID)
+	assert.False(t, parsed.IsRegistered())
+}
+
+func TestService_ClaimQuota(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	svc, err := New(&Config{
+		Storage:    store,
+		Authorizer: auth.New("secret"),
+		Quotas: quota.New(&quota.Config{
+			Storage:     store,
+			Logger:      zap.NewNop(),
+			DefaultTier: "free",
+			Tiers:       []model.Tier{{Name: "free", MaxURLs: 2}},
+		}),
+		Logger: zap.NewNop(),
+	})
+	require.NoError(t, err)
+
+	anon, anon2 := user.NewWithID("anonymous"), user.NewWithID("anonymous2")
+	require.NoError(t, store.StoreBatch(ctx, []model.URL{
+		{Short: "aaa", Orig: "https://aaa.bbb/1", UserID: anon.ID},
+		{Short: "bbb", Orig: "https://aaa.bbb/2", UserID: anon.ID},
+		{Short: "ccc", Orig: "https://aaa.bbb/3", UserID: anon.ID},
+		{Short: "ddd", Orig: "https://aaa.bbb/4", UserID: anon2.ID},
+		{Short: "eee", Orig: "https://aaa.bbb/5", UserID: anon2.ID},
+	}))
+
+	// account is not created if anonymous links exceed its quota
+	_, err = svc.Register(ctx, anon, "alice", "password1")
+	assert.Equal(t, &quota.ExceededError{Quota: quota.QuotaURLs, Limit: 2, Requested: 3, Remaining: 2}, err)
+	_, err = svc.Login(ctx, anon, "alice", "password1", false)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	sess, err := svc.Register(ctx, user.NewWithID("anonymous3"), "alice", "password1")
+	require.NoError(t, err)
+	_, err = store.Store(ctx, &model.URL{Short: "fff", Orig: "https://aaa.bbb/6", UserID: sess.User.ID}, false)
+	require.NoError(t, err)
+
+	// login with claim is rejected, links stay with anonymous user
+	_, err = svc.Login(ctx, anon2, "alice", "password1", true)
+	assert.Equal(t, &quota.ExceededError{Quota: quota.QuotaURLs, Limit: 2, Requested: 2, Remaining: 1}, err)
+	num, err := store.CountUserURLs(ctx, anon2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, num)
+
+	loggedIn, err := svc.Login(ctx, anon2, "alice", "password1", false)
+	require.NoError(t, err)
+	assert.Zero(t, loggedIn.Claimed)
+}
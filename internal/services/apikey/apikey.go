@@ -0,0 +1,297 @@
+// Package apikey is api key service.
+// It manages long-lived keys of programmatic clients and authenticates requests made with them.
+//
+// Key has form shk_<id>_<secret>. Only sha256 hash of secret is stored,
+// secret is shown once when key is created.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+	"go.uber.org/zap"
+)
+
+// Key scopes.
+const (
+	// ScopeShorten allows to create links and edit their metadata.
+	ScopeShorten = "shorten"
+	// ScopeRead allows to list and export links and read their statistics.
+	ScopeRead = "read"
+	// ScopeDelete allows to delete links.
+	ScopeDelete = "delete"
+)
+
+const (
+	keyPrefix      = "shk_"
+	idBytes        = 8
+	secretBytes    = 32
+	maxNameLength  = 128
+	maxKeysPerUser = 100
+)
+
+// Service errors.
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrInvalidKey   = errors.New("invalid api key")
+	ErrInvalidScope = errors.New("invalid scope")
+	ErrInvalidName  = errors.New("invalid key name")
+	ErrNotFound     = errors.New("api key not found")
+	ErrTooManyKeys  = errors.New("too many api keys")
+	ErrStorageError = errors.New("storage error")
+)
+
+var validScopes = []string{ScopeShorten, ScopeRead, ScopeDelete}
+
+// Storage is api key storage.
+type Storage interface {
+	CreateAPIKey(ctx context.Context, key *model.APIKey) error
+	GetAPIKey(ctx context.Context, id string) (*model.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error)
+	UpdateAPIKey(ctx context.Context, key *model.APIKey) error
+	DeleteAPIKey(ctx context.Context, userID, id string) error
+}
+
+// Service is api key service.
+type Service struct {
+	store Storage
+	log   *zap.Logger
+}
+
+// Config is api key service config.
+type Config struct {
+	Storage Storage
+	Logger  *zap.Logger
+}
+
+// Key describes api key. Key itself is set only when key is created.
+type Key struct {
+	Created time.Time `json:"created"`
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Key     string    `json:"key,omitempty"`
+	Scopes  []string  `json:"scopes"`
+}
+
+// New creates api key service.
+func New(cfg *Config) *Service {
+	return &Service{
+		store: cfg.Storage,
+		log:   cfg.Logger.With(zap.String("component", "apikey")),
+	}
+}
+
+// Create generates new api key of user.
+func (svc *Service) Create(ctx context.Context, u *user.User, name string, scopes []string) (*Key, error) {
+	if err := checkUser(u); err != nil {
+		return nil, err
+	}
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+	scopes, err := prepareScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := svc.store.ListAPIKeys(ctx, u.ID)
+	if err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	if len(keys) >= maxKeysPerUser {
+		return nil, ErrTooManyKeys
+	}
+
+	id, err := randomString(idBytes, hex.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomString(secretBytes, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+	key := &model.APIKey{
+		Created:    time.Now().UTC().Truncate(time.Second),
+		ID:         id,
+		UserID:     u.ID,
+		Name:       name,
+		SecretHash: hashSecret(secret),
+		Scopes:     scopes,
+	}
+	if err = svc.store.CreateAPIKey(ctx, key); err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	svc.log.Debug("api key created",
+		zap.String("userID", u.ID),
+		zap.String("keyID", id),
+		zap.Strings("scopes", scopes))
+	k := newKey(key)
+	k.Key = keyPrefix + id + "_" + secret
+	return k, nil
+}
+
+// List returns api keys of user without secrets.
+func (svc *Service) List(ctx context.Context, u *user.User) ([]*Key, error) {
+	if err := checkUser(u); err != nil {
+		return nil, err
+	}
+	keys, err := svc.store.ListAPIKeys(ctx, u.ID)
+	if err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	result := make([]*Key, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, newKey(key))
+	}
+	return result, nil
+}
+
+// Update changes name and scopes of user api key. Nil name or scopes are not changed.
+func (svc *Service) Update(ctx context.Context, u *user.User, id string, name *string, scopes []string) (*Key, error) {
+	if err := checkUser(u); err != nil {
+		return nil, err
+	}
+	key, err := svc.getUserKey(ctx, u, id)
+	if err != nil {
+		return nil, err
+	}
+	if name != nil {
+		if err = validateName(*name); err != nil {
+			return nil, err
+		}
+		key.Name = *name
+	}
+	if scopes != nil {
+		if key.Scopes, err = prepareScopes(scopes); err != nil {
+			return nil, err
+		}
+	}
+	if err = svc.store.UpdateAPIKey(ctx, key); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	return newKey(key), nil
+}
+
+// Revoke deletes user api key, it can no longer be used.
+func (svc *Service) Revoke(ctx context.Context, u *user.User, id string) error {
+	if err := checkUser(u); err != nil {
+		return err
+	}
+	if err := svc.store.DeleteAPIKey(ctx, u.ID, id); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return ErrNotFound
+		}
+		return errors.Join(ErrStorageError, err)
+	}
+	svc.log.Debug("api key revoked",
+		zap.String("userID", u.ID),
+		zap.String("keyID", id))
+	return nil
+}
+
+// Authenticate checks api key and returns key owner limited to key scopes.
+func (svc *Service) Authenticate(ctx context.Context, token string) (*user.User, error) {
+	rest, ok := strings.CutPrefix(token, keyPrefix)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(id) != 2*idBytes || secret == "" {
+		return nil, ErrInvalidKey
+	}
+	key, err := svc.store.GetAPIKey(ctx, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, ErrInvalidKey
+		}
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, ErrInvalidKey
+	}
+	u := user.NewWithID(key.UserID)
+	u.KeyID = key.ID
+	u.Scopes = key.Scopes
+	return u, nil
+}
+
+func (svc *Service) getUserKey(ctx context.Context, u *user.User, id string) (*model.APIKey, error) {
+	key, err := svc.store.GetAPIKey(ctx, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	if key.UserID != u.ID {
+		return nil, ErrNotFound
+	}
+	return key, nil
+}
+
+// checkUser checks that user can manage api keys. Keys are managed only within
+// existing session, api keys cannot be used to manage other keys.
+func checkUser(u *user.User) error {
+	if u.IsNew() || u.IsAPIKey() {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+func validateName(name string) error {
+	if len(name) > maxNameLength {
+		return fmt.Errorf("%w: name is longer than %d bytes", ErrInvalidName, maxNameLength)
+	}
+	return nil
+}
+
+// prepareScopes validates scopes and returns sorted unique list of them.
+func prepareScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !slices.Contains(validScopes, scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+		result = append(result, scope)
+	}
+	slices.Sort(result)
+	return slices.Compact(result), nil
+}
+
+func newKey(key *model.APIKey) *Key {
+	return &Key{
+		Created: key.Created,
+		ID:      key.ID,
+		Name:    key.Name,
+		Scopes:  key.Scopes,
+	}
+}
+
+func hashSecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("cannot generate random bytes: %w", err)
+	}
+	return encode(b), nil
+}
@@ -0,0 +1,73 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adwski/shorty/internal/storage/memory"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestService_KeyLifecycle(t *testing.T) {
+	ctx := context.Background()
+	svc := New(&Config{Storage: memory.New(), Logger: zap.NewNop()})
+	owner := user.NewWithID("owner")
+
+	newUser, err := user.New()
+	require.NoError(t, err)
+	_, err = svc.Create(ctx, newUser, "ci", []string{ScopeRead})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = svc.Create(ctx, owner, "ci", nil)
+	assert.ErrorIs(t, err, ErrInvalidScope)
+	_, err = svc.Create(ctx, owner, "ci", []string{"admin"})
+	assert.ErrorIs(t, err, ErrInvalidScope)
+
+	key, err := svc.Create(ctx, owner, "ci", []string{ScopeShorten, ScopeRead, ScopeShorten})
+	require.NoError(t, err)
+	assert.Equal(t, []string{ScopeRead, ScopeShorten}, key.Scopes)
+	require.NotEmpty(t, key.Key)
+
+	// key authenticates owner with key scopes
+	u, err := svc.Authenticate(ctx, key.Key)
+	require.NoError(t, err)
+	assert.Equal(t, owner.ID, u.ID)
+	assert.True(t, u.IsAPIKey())
+	assert.True(t, u.HasScope(ScopeRead))
+	assert.False(t, u.HasScope(ScopeDelete))
+
+	for _, token := range []string{"", "shk_", key.Key + "x", "shk_" + key.ID + "_", "Bearer " + key.Key} {
+		_, err = svc.Authenticate(ctx, token)
+		assert.ErrorIs(t, err, ErrInvalidKey, token)
+	}
+
+	// key cannot manage keys
+	_, err = svc.List(ctx, u)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	keys, err := svc.List(ctx, owner)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, key.ID, keys[0].ID)
+	assert.Empty(t, keys[0].Key)
+
+	name := "deploy"
+	updated, err := svc.Update(ctx, owner, key.ID, &name, []string{ScopeDelete})
+	require.NoError(t, err)
+	assert.Equal(t, "deploy", updated.Name)
+	assert.Equal(t, []string{ScopeDelete}, updated.Scopes)
+	u, err = svc.Authenticate(ctx, key.Key)
+	require.NoError(t, err)
+	assert.True(t, u.HasScope(ScopeDelete))
+	assert.False(t, u.HasScope(ScopeRead))
+
+	_, err = svc.Update(ctx, user.NewWithID("other"), key.ID, &name, nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, svc.Revoke(ctx, user.NewWithID("other"), key.ID), ErrNotFound)
+
+	require.NoError(t, svc.Revoke(ctx, owner, key.ID))
+	_, err = svc.Authenticate(ctx, key.Key)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
@@ -0,0 +1,284 @@
+// Package backup provides point-in-time snapshots of URL storage.
+//
+// Snapshot is gzip compressed stream of JSON encoded URL records, one per line,
+// in the same format as file storage uses. Records include variant click counters.
+// SHA256 checksum of compressed snapshot is saved next to it in a file
+// compatible with sha256sum utility.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/storage/memory/db"
+	"go.uber.org/zap"
+)
+
+const (
+	snapshotPrefix  = "snapshot-"
+	snapshotSuffix  = ".ndjson.gz"
+	checksumSuffix  = ".sha256"
+	snapshotTimeFmt = "20060102T150405.000Z"
+
+	dirPermission      = 0700
+	snapshotPermission = 0600
+	maxRecordSize      = 1024 * 1024
+)
+
+// Backup errors.
+var (
+	ErrStorageError     = errors.New("storage error")
+	ErrBusy             = errors.New("backup or restore is already running")
+	ErrInvalidName      = errors.New("invalid snapshot name")
+	ErrNotFound         = errors.New("snapshot not found")
+	ErrChecksumMismatch = errors.New("snapshot checksum mismatch")
+	ErrInvalidSnapshot  = errors.New("invalid snapshot")
+)
+
+// Storage is a storage that supports consistent snapshots.
+type Storage interface {
+	ScanURLs(ctx context.Context, after string, fn func(url *model.URL) error) error
+	LoadURLs(ctx context.Context, next func() (*model.URL, error), replace bool) error
+}
+
+// Service creates snapshots of storage in configured directory and restores storage from them.
+// Only one backup or restore can run at a time.
+type Service struct {
+	store Storage
+	log   *zap.Logger
+	dir   string
+	mux   sync.Mutex
+}
+
+// Config is backup service config.
+type Config struct {
+	Storage Storage
+	Logger  *zap.Logger
+	Dir     string
+}
+
+// Snapshot describes stored snapshot.
+type Snapshot struct {
+	Created  time.Time `json:"created"`
+	Name     string    `json:"name"`
+	Checksum string    `json:"sha256"`
+	Records  int64     `json:"records"`
+	Size     int64     `json:"size"`
+}
+
+// New creates backup service. Snapshot directory is created if it does not exist.
+func New(cfg *Config) (*Service, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("snapshot directory is not set")
+	}
+	if err := os.MkdirAll(cfg.Dir, dirPermission); err != nil {
+		return nil, fmt.Errorf("cannot create snapshot directory: %w", err)
+	}
+	return &Service{
+		store: cfg.Storage,
+		log:   cfg.Logger.With(zap.String("component", "backup")),
+		dir:   cfg.Dir,
+	}, nil
+}
+
+// Backup writes snapshot of all URLs including deleted ones.
+// Snapshot is written to temporary file and renamed when it's complete,
+// so partially written snapshots are never visible.
+func (svc *Service) Backup(ctx context.Context) (*Snapshot, error) {
+	if !svc.mux.TryLock() {
+		return nil, ErrBusy
+	}
+	defer svc.mux.Unlock()
+
+	snapshot := &Snapshot{Created: time.Now().UTC()}
+	snapshot.Name = snapshotPrefix + snapshot.Created.Format(snapshotTimeFmt) + snapshotSuffix
+
+	tmp, err := os.CreateTemp(svc.dir, "."+snapshotPrefix+"*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create snapshot file: %w", err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+	if err = svc.writeSnapshot(ctx, tmp, snapshot); err != nil {
+		return nil, err
+	}
+	if err = tmp.Sync(); err != nil {
+		return nil, fmt.Errorf("cannot sync snapshot file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return nil, fmt.Errorf("cannot close snapshot file: %w", err)
+	}
+	path := filepath.Join(svc.dir, snapshot.Name)
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("cannot save snapshot file: %w", err)
+	}
+	sum := fmt.Sprintf("%s  %s\n", snapshot.Checksum, snapshot.Name)
+	if err = os.WriteFile(path+checksumSuffix, []byte(sum), snapshotPermission); err != nil {
+		return nil, fmt.Errorf("cannot write snapshot checksum: %w", err)
+	}
+	svc.log.Info("snapshot created",
+		zap.String("name", snapshot.Name),
+		zap.Int64("records", snapshot.Records))
+	return snapshot, nil
+}
+
+func (svc *Service) writeSnapshot(ctx context.Context, f *os.File, snapshot *Snapshot) error {
+	var (
+		h  = sha256.New()
+		cw = &countingWriter{w: io.MultiWriter(f, h)}
+		gz = gzip.NewWriter(cw)
+		bw = bufio.NewWriter(gz)
+	)
+	err := svc.store.ScanURLs(ctx, "", func(url *model.URL) error {
+		data, err := json.Marshal(db.NewRecordAsIs(url.UUID, url))
+		if err != nil {
+			return fmt.Errorf("cannot marshal url %s: %w", url.Short, err)
+		}
+		if _, err = bw.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("cannot write snapshot: %w", err)
+		}
+		snapshot.Records++
+		return nil
+	})
+	if err != nil {
+		return errors.Join(ErrStorageError, err)
+	}
+	if err = bw.Flush(); err != nil {
+		return fmt.Errorf("cannot write snapshot: %w", err)
+	}
+	if err = gz.Close(); err != nil {
+		return fmt.Errorf("cannot compress snapshot: %w", err)
+	}
+	snapshot.Checksum = hex.EncodeToString(h.Sum(nil))
+	snapshot.Size = cw.n
+	return nil
+}
+
+// Restore loads URLs from snapshot into storage. If replace is set, storage contents
+// are replaced with snapshot, otherwise snapshot URLs are merged into storage overwriting
+// URLs with the same short path. Snapshot checksum is verified before storage is changed.
+func (svc *Service) Restore(ctx context.Context, name string, replace bool) (*Snapshot, error) {
+	if !validName(name) {
+		return nil, ErrInvalidName
+	}
+	if !svc.mux.TryLock() {
+		return nil, ErrBusy
+	}
+	defer svc.mux.Unlock()
+
+	path := filepath.Join(svc.dir, name)
+	snapshot, err := svc.verify(path, name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open snapshot: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidSnapshot, err)
+	}
+	sc := bufio.NewScanner(gz)
+	sc.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxRecordSize)
+	next := func() (*model.URL, error) {
+		if !sc.Scan() {
+			if errS := sc.Err(); errS != nil {
+				return nil, errors.Join(ErrInvalidSnapshot, errS)
+			}
+			return nil, io.EOF
+		}
+		record, errR := db.NewURLRecordFromBytes(sc.Bytes())
+		if errR != nil {
+			return nil, errors.Join(ErrInvalidSnapshot, fmt.Errorf("record %d: %w", snapshot.Records+1, errR))
+		}
+		snapshot.Records++
+		return record.URLAsIs(), nil
+	}
+	if err = svc.store.LoadURLs(ctx, next, replace); err != nil {
+		if errors.Is(err, ErrInvalidSnapshot) {
+			return nil, err
+		}
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	svc.log.Info("snapshot restored",
+		zap.String("name", name),
+		zap.Int64("records", snapshot.Records),
+		zap.Bool("replace", replace))
+	return snapshot, nil
+}
+
+// verify checks snapshot file against its checksum file.
+func (svc *Service) verify(path, name string) (*Snapshot, error) {
+	sum, err := os.ReadFile(path + checksumSuffix)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("cannot read snapshot checksum: %w", err)
+	}
+	expected, _, _ := strings.Cut(string(sum), " ")
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("cannot open snapshot: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read snapshot: %w", err)
+	}
+	snapshot := &Snapshot{
+		Name:     name,
+		Checksum: hex.EncodeToString(h.Sum(nil)),
+		Size:     size,
+	}
+	if snapshot.Checksum != expected {
+		return nil, ErrChecksumMismatch
+	}
+	created, err := time.Parse(snapshotTimeFmt, strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix),
+		snapshotSuffix))
+	if err == nil {
+		snapshot.Created = created
+	}
+	return snapshot, nil
+}
+
+// validName checks that name is a snapshot file name without directories.
+func validName(name string) bool {
+	return name == filepath.Base(name) &&
+		strings.HasPrefix(name, snapshotPrefix) &&
+		strings.HasSuffix(name, snapshotSuffix)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err //nolint:wrapcheck // transparent writer
+}
@@ -0,0 +1,86 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/storage/memory"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestService_BackupRestore(t *testing.T) {
+	ctx := context.Background()
+	owner, err := user.New()
+	require.NoError(t, err)
+
+	store := memory.New()
+	require.NoError(t, store.StoreBatch(ctx, []model.URL{
+		{Short: "aaa", Orig: "https://aaa.bbb/1", UserID: owner.ID, Title: "One", Created: time.Unix(1700000000, 0)},
+		{Short: "bbb", Orig: "https://aaa.bbb/2", UserID: owner.ID, Tags: []string{"x"}, Variants: []model.Variant{
+			{URL: "https://aaa.bbb/v1", Weight: 1}, {URL: "https://aaa.bbb/v2", Weight: 1},
+		}},
+		{Short: "ccc", Orig: "https://aaa.bbb/3", UserID: owner.ID},
+	}))
+	require.NoError(t, store.AddVariantClicks(ctx, []model.Click{{Short: "bbb", Variant: 1}}))
+	_, err = store.DeleteUserURLs(ctx, []model.URL{{Short: "ccc", UserID: owner.ID}})
+	require.NoError(t, err)
+	before := store.Dump()
+
+	dir := filepath.Join(t.TempDir(), "snapshots")
+	svc, err := New(&Config{Storage: store, Logger: zap.NewNop(), Dir: dir})
+	require.NoError(t, err)
+
+	snapshot, err := svc.Backup(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), snapshot.Records)
+	assert.FileExists(t, filepath.Join(dir, snapshot.Name))
+	sum, err := os.ReadFile(filepath.Join(dir, snapshot.Name+".sha256"))
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.Checksum+"  "+snapshot.Name+"\n", string(sum))
+
+	// merge keeps urls created after backup and brings back changed ones
+	require.NoError(t, store.UpdateMeta(ctx, &model.URL{Short: "aaa", UserID: owner.ID, Title: "changed"}))
+	_, err = store.Store(ctx, &model.URL{Short: "ddd", Orig: "https://aaa.bbb/4", UserID: owner.ID}, false)
+	require.NoError(t, err)
+	require.NoError(t, store.AddVariantClicks(ctx, []model.Click{{Short: "bbb", Variant: 0}}))
+
+	restored, err := svc.Restore(ctx, snapshot.Name, false)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.Records, restored.Records)
+	assert.Equal(t, snapshot.Checksum, restored.Checksum)
+	url, err := store.Get(ctx, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, "One", url.Title)
+	_, err = store.Get(ctx, "ddd")
+	require.NoError(t, err)
+	clicks, err := store.GetVariantClicks(ctx, "bbb")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0, 1}, clicks)
+
+	// replace restores exact dataset
+	_, err = svc.Restore(ctx, snapshot.Name, true)
+	require.NoError(t, err)
+	assert.Equal(t, before, store.Dump())
+
+	_, err = svc.Restore(ctx, "../"+snapshot.Name, true)
+	assert.ErrorIs(t, err, ErrInvalidName)
+	_, err = svc.Restore(ctx, "snapshot-20200101T000000.000Z.ndjson.gz", true)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// corrupted snapshot is not loaded
+	f, err := os.OpenFile(filepath.Join(dir, snapshot.Name), os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("garbage")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	_, err = svc.Restore(ctx, snapshot.Name, true)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.Equal(t, before, store.Dump())
+}
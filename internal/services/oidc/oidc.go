@@ -0,0 +1,549 @@
+// Package oidc is single sign-on service.
+// It implements OpenID Connect authorization code flow with PKCE against external identity provider.
+//
+// Provider endpoints are discovered from issuer on first use. State, nonce and PKCE verifier
+// of flow are kept in short-lived signed token, so flow does not need server side storage.
+// Subject of identity provider is mapped to user id on first login and links
+// of current anonymous user are claimed into new user if they fit its quota.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	authorizer "github.com/adwski/shorty/internal/auth"
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+
+	flowTTL      = 10 * time.Minute
+	flowAudience = "shorty-oidc-flow"
+	randomBytes  = 32
+
+	defaultClientTimeout = 10 * time.Second
+	// keysRefreshInterval limits provider key set refetches caused by unknown key ids.
+	keysRefreshInterval = time.Minute
+	clockSkew           = time.Minute
+	maxResponseSize     = 1 << 20
+)
+
+// Service errors.
+var (
+	ErrInvalidState = errors.New("invalid login state")
+	ErrProvider     = errors.New("identity provider error")
+	ErrInvalidToken = errors.New("invalid id token")
+	ErrStorageError = errors.New("storage error")
+)
+
+var idTokenMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Storage is external identity storage.
+type Storage interface {
+	CreateIdentity(ctx context.Context, identity *model.Identity) error
+	GetIdentity(ctx context.Context, issuer, subject string) (*model.Identity, error)
+	ClaimUserURLs(ctx context.Context, fromUserID, toUserID string) (int64, error)
+	CountUserURLs(ctx context.Context, userID string) (int, error)
+}
+
+// Quotas enforces link quota of new user which claims links. Quota errors are returned to caller as is.
+type Quotas interface {
+	CheckURLs(ctx context.Context, userID string, links int) error
+}
+
+// Authorizer issues session tokens and signs flow state.
+type Authorizer interface {
+	CreateToken(u *user.User) (string, error)
+	SignClaims(claims jwt.Claims) (string, error)
+	ParseClaims(token string, claims jwt.Claims, opts ...jwt.ParserOption) error
+}
+
+// Service is single sign-on service.
+type Service struct {
+	store  Storage
+	auth   Authorizer
+	quotas Quotas
+	client *http.Client
+	log    *zap.Logger
+
+	// provider and keys are fetched lazily, so identity provider
+	// does not have to be available on start.
+	provider    *provider
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+	mux         sync.Mutex
+
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       string
+}
+
+// Config is single sign-on service config.
+type Config struct {
+	Storage    Storage
+	Authorizer Authorizer
+	// Quotas is optional, quota of new user is not checked on claim if it's not set.
+	Quotas Quotas
+	Logger *zap.Logger
+	// Client is used for requests to identity provider, default client with timeout is used if nil.
+	Client       *http.Client
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes are requested scopes, openid scope is always requested.
+	Scopes []string
+}
+
+// Flow is started login flow.
+type Flow struct {
+	// URL is authorization endpoint url user should be redirected to.
+	URL string
+	// State is signed state of flow, it should be passed to Callback.
+	State string
+}
+
+// Session is session of user logged in with identity provider.
+type Session struct {
+	User  *user.User
+	Token string
+	// Return is local path user should be redirected to after login.
+	Return string
+	// Claimed is number of links claimed from anonymous user.
+	Claimed int64
+}
+
+type provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type flowClaims struct {
+	jwt.RegisteredClaims
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Return   string `json:"return,omitempty"`
+}
+
+type idClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp"`
+	Email             string `json:"email"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// New creates single sign-on service.
+func New(cfg *Config) *Service {
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultClientTimeout}
+	}
+	scopes := []string{"openid"}
+	for _, scope := range cfg.Scopes {
+		if scope != "" && !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return &Service{
+		store:        cfg.Storage,
+		auth:         cfg.Authorizer,
+		quotas:       cfg.Quotas,
+		client:       client,
+		log:          cfg.Logger.With(zap.String("component", "oidc")),
+		issuer:       strings.TrimSuffix(cfg.Issuer, "/"),
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		redirectURL:  cfg.RedirectURL,
+		scopes:       strings.Join(scopes, " "),
+	}
+}
+
+// Start starts login flow. User should be redirected to returned url and state should be kept
+// until callback, e.g. in cookie. After login user is redirected to returnTo path if it's local.
+func (svc *Service) Start(ctx context.Context, returnTo string) (*Flow, error) {
+	p, err := svc.getProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims := flowClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{flowAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(flowTTL)),
+		},
+	}
+	if isLocalPath(returnTo) {
+		claims.Return = returnTo
+	}
+	for _, v := range []*string{&claims.State, &claims.Nonce, &claims.Verifier} {
+		if *v, err = randomString(); err != nil {
+			return nil, err
+		}
+	}
+	state, err := svc.auth.SignClaims(claims)
+	if err != nil {
+		return nil, fmt.Errorf("cannot sign flow state: %w", err)
+	}
+	challenge := sha256.Sum256([]byte(claims.Verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {svc.clientID},
+		"redirect_uri":          {svc.redirectURL},
+		"scope":                 {svc.scopes},
+		"state":                 {claims.State},
+		"nonce":                 {claims.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return &Flow{URL: p.AuthorizationEndpoint + sep + query.Encode(), State: state}, nil
+}
+
+// Callback completes login flow. Code is exchanged for id token, subject of token is mapped to user
+// and session token is issued. Links of current anonymous user are claimed on first login of subject.
+func (svc *Service) Callback(ctx context.Context, u *user.User, flowState, state, code string) (*Session, error) {
+	var flow flowClaims
+	if err := svc.auth.ParseClaims(flowState, &flow,
+		jwt.WithAudience(flowAudience), jwt.WithExpirationRequired()); err != nil {
+		return nil, errors.Join(ErrInvalidState, err)
+	}
+	if flow.State == "" || subtle.ConstantTimeCompare([]byte(flow.State), []byte(state)) != 1 {
+		return nil, errors.Join(ErrInvalidState, errors.New("state mismatch"))
+	}
+	if code == "" {
+		return nil, errors.Join(ErrInvalidState, errors.New("code is empty"))
+	}
+	p, err := svc.getProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rawToken, err := svc.exchange(ctx, p, code, flow.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := svc.verifyIDToken(ctx, p, rawToken, flow.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := svc.newSession(ctx, u, p.Issuer, claims)
+	if err != nil {
+		return nil, err
+	}
+	sess.Return = flow.Return
+	return sess, nil
+}
+
+// newSession maps subject to user and issues session token.
+func (svc *Service) newSession(ctx context.Context, u *user.User, issuer string, claims *idClaims) (*Session, error) {
+	identity, created, err := svc.getOrCreateIdentity(ctx, u, issuer, claims)
+	if err != nil {
+		return nil, err
+	}
+	sess := &Session{User: user.NewWithID(identity.UserID)}
+	// login is shown to user, it is not used to authenticate
+	sess.User.Login = claims.PreferredUsername
+	if claims.Email != "" {
+		sess.User.Login = claims.Email
+	}
+	if sess.User.Login == "" {
+		sess.User.Login = claims.Subject
+	}
+	if created && canClaim(u) {
+		if sess.Claimed, err = svc.store.ClaimUserURLs(ctx, u.ID, identity.UserID); err != nil {
+			return nil, errors.Join(ErrStorageError, err)
+		}
+	}
+	if sess.Token, err = svc.auth.CreateToken(sess.User); err != nil {
+		return nil, fmt.Errorf("cannot create token: %w", err)
+	}
+	svc.log.Debug("user logged in",
+		zap.String("subject", claims.Subject),
+		zap.String("user", identity.UserID),
+		zap.Bool("created", created),
+		zap.Int64("claimed", sess.Claimed))
+	return sess, nil
+}
+
+// getOrCreateIdentity returns identity of subject and whether it was created.
+// Identity is not created if links of user that will be claimed exceed quota of new user.
+func (svc *Service) getOrCreateIdentity(
+	ctx context.Context,
+	u *user.User,
+	issuer string,
+	claims *idClaims,
+) (*model.Identity, bool, error) {
+	identity, err := svc.store.GetIdentity(ctx, issuer, claims.Subject)
+	if err == nil {
+		return identity, false, nil
+	}
+	if !errors.Is(err, model.ErrNotFound) {
+		return nil, false, errors.Join(ErrStorageError, err)
+	}
+	newUser, err := user.New()
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot create user: %w", err)
+	}
+	if err = svc.checkClaim(ctx, u, newUser.ID); err != nil {
+		return nil, false, err
+	}
+	identity = &model.Identity{
+		Issuer:  issuer,
+		Subject: claims.Subject,
+		UserID:  newUser.ID,
+		Email:   claims.Email,
+	}
+	if err = svc.store.CreateIdentity(ctx, identity); err != nil {
+		if !errors.Is(err, model.ErrAlreadyExists) {
+			return nil, false, errors.Join(ErrStorageError, err)
+		}
+		// subject logged in concurrently
+		if identity, err = svc.store.GetIdentity(ctx, issuer, claims.Subject); err != nil {
+			return nil, false, errors.Join(ErrStorageError, err)
+		}
+		return identity, false, nil
+	}
+	return identity, true, nil
+}
+
+// checkClaim checks that active personal links of user can be claimed
+// into new user without exceeding its quota.
+func (svc *Service) checkClaim(ctx context.Context, u *user.User, newUserID string) error {
+	if svc.quotas == nil || !canClaim(u) {
+		return nil
+	}
+	num, err := svc.store.CountUserURLs(ctx, u.ID)
+	if err != nil {
+		return errors.Join(ErrStorageError, err)
+	}
+	if num == 0 {
+		return nil
+	}
+	return svc.quotas.CheckURLs(ctx, newUserID, num) //nolint:wrapcheck // quota errors carry limits
+}
+
+// canClaim checks if links of user can be claimed into new user.
+func canClaim(u *user.User) bool {
+	return !u.IsNew() && !u.IsRegistered() && !u.IsAPIKey()
+}
+
+// exchange exchanges authorization code for id token.
+func (svc *Service) exchange(ctx context.Context, p *provider, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {svc.redirectURL},
+		"code_verifier": {verifier},
+	}
+	if svc.clientSecret == "" {
+		form.Set("client_id", svc.clientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("cannot create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if svc.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(svc.clientID), url.QueryEscape(svc.clientSecret))
+	}
+	resp, err := svc.client.Do(req)
+	if err != nil {
+		return "", errors.Join(ErrProvider, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var tr tokenResponse
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&tr); err != nil {
+		return "", errors.Join(ErrProvider, fmt.Errorf("cannot decode token response with status %d: %w",
+			resp.StatusCode, err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Join(ErrProvider, fmt.Errorf("token request failed with status %d: %s %s",
+			resp.StatusCode, tr.Error, tr.ErrorDescription))
+	}
+	if tr.IDToken == "" {
+		return "", errors.Join(ErrProvider, errors.New("id token is missing in token response"))
+	}
+	return tr.IDToken, nil
+}
+
+// verifyIDToken validates signature, issuer, audience, expiration and nonce of id token.
+func (svc *Service) verifyIDToken(ctx context.Context, p *provider, rawToken, nonce string) (*idClaims, error) {
+	var claims idClaims
+	_, err := jwt.ParseWithClaims(rawToken, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return svc.getKey(ctx, p, kid)
+	},
+		jwt.WithValidMethods(idTokenMethods),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(svc.clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew))
+	if err != nil {
+		return nil, errors.Join(ErrInvalidToken, err)
+	}
+	switch {
+	case claims.Subject == "":
+		return nil, errors.Join(ErrInvalidToken, errors.New("subject is empty"))
+	case subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1:
+		return nil, errors.Join(ErrInvalidToken, errors.New("nonce mismatch"))
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != svc.clientID:
+		return nil, errors.Join(ErrInvalidToken, errors.New("token is not issued for this client"))
+	}
+	return &claims, nil
+}
+
+// getProvider returns discovered provider metadata.
+func (svc *Service) getProvider(ctx context.Context) (*provider, error) {
+	svc.mux.Lock()
+	defer svc.mux.Unlock()
+	if svc.provider != nil {
+		return svc.provider, nil
+	}
+	var p provider
+	if err := svc.getJSON(ctx, svc.issuer+discoveryPath, &p); err != nil {
+		return nil, errors.Join(ErrProvider, fmt.Errorf("discovery failed: %w", err))
+	}
+	if strings.TrimSuffix(p.Issuer, "/") != svc.issuer {
+		return nil, errors.Join(ErrProvider, fmt.Errorf("discovered issuer %q does not match %q", p.Issuer, svc.issuer))
+	}
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.JWKSURI == "" {
+		return nil, errors.Join(ErrProvider, errors.New("provider metadata is incomplete"))
+	}
+	svc.provider = &p
+	return svc.provider, nil
+}
+
+// getKey returns provider key by id. Key set is refetched if key is unknown, so provider key rotation
+// is picked up. Token without key id is accepted if provider has single key.
+func (svc *Service) getKey(ctx context.Context, p *provider, kid string) (crypto.PublicKey, error) {
+	svc.mux.Lock()
+	defer svc.mux.Unlock()
+	if key, ok := svc.lookupKey(kid); ok {
+		return key, nil
+	}
+	if time.Since(svc.keysFetched) < keysRefreshInterval {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+	var jwks authorizer.JWKS
+	if err := svc.getJSON(ctx, p.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("cannot fetch provider keys: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			// provider may publish keys of types we do not support
+			svc.log.Debug("provider key skipped", zap.String("kid", jwk.Kid), zap.Error(err))
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	svc.keys = keys
+	svc.keysFetched = time.Now()
+	if key, ok := svc.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id: %s", kid)
+}
+
+func (svc *Service) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(svc.keys) == 1 {
+		for _, key := range svc.keys {
+			return key, true
+		}
+	}
+	key, ok := svc.keys[kid]
+	return key, ok
+}
+
+func (svc *Service) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("cannot create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := svc.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v); err != nil {
+		return fmt.Errorf("cannot decode response: %w", err)
+	}
+	return nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, randomBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("cannot generate random string: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// isLocalPath checks that path points to this server, so login cannot redirect to other site.
+// Browsers ignore control characters and treat backslash as slash, so such paths
+// are rejected both in raw and in unescaped form.
+func isLocalPath(path string) bool {
+	if !isSafePath(path) {
+		return false
+	}
+	u, err := url.Parse(path)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil || u.Opaque != "" {
+		return false
+	}
+	return isSafePath(u.Path)
+}
+
+// isSafePath checks that path has single leading slash and no control characters or backslashes.
+func isSafePath(path string) bool {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") {
+		return false
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] < 0x20 || path[i] == 0x7f || path[i] == '\\' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,238 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/adwski/shorty/internal/auth"
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/services/oidc/oidctest"
+	"github.com/adwski/shorty/internal/services/quota"
+	"github.com/adwski/shorty/internal/storage/memory"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testRedirectURL = "http://shorty.test/api/user/sso/callback"
+
+// signIn follows redirect of authorization endpoint and returns state and code passed to callback.
+func signIn(t *testing.T, flow *Flow) (string, string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(flow.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, testRedirectURL, callback.Scheme+"://"+callback.Host+callback.Path)
+	return callback.Query().Get("state"), callback.Query().Get("code")
+}
+
+func TestService_Login(t *testing.T) {
+	ctx := context.Background()
+	idp, err := oidctest.NewProvider("shorty", "secret")
+	require.NoError(t, err)
+	defer idp.Close()
+
+	store := memory.New()
+	a := auth.New("jwt-secret")
+	svc := New(&Config{
+		Storage:      store,
+		Authorizer:   a,
+		Logger:       zap.NewNop(),
+		Issuer:       idp.Issuer() + "/",
+		ClientID:     "shorty",
+		ClientSecret: "secret",
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "email"},
+	})
+
+	flow, err := svc.Start(ctx, "/api/user/urls")
+	require.NoError(t, err)
+	authURL, err := url.Parse(flow.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "openid email", authURL.Query().Get("scope"))
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+
+	// first login maps subject to new user and claims anonymous links
+	anon := user.NewWithID("anonymous")
+	_, err = store.Store(ctx, &model.URL{Short: "aaa", Orig: "https://aaa.bbb/1", UserID: anon.ID}, false)
+	require.NoError(t, err)
+	state, code := signIn(t, flow)
+	sess, err := svc.Callback(ctx, anon, flow.State, state, code)
+	require.NoError(t, err)
+	assert.Equal(t, "/api/user/urls", sess.Return)
+	assert.Equal(t, int64(1), sess.Claimed)
+	assert.Equal(t, "user@example.com", sess.User.Login)
+	parsed, err := a.ParseUserFromJWTString(ctx, sess.Token)
+	require.NoError(t, err)
+	assert.Equal(t, sess.User.ID, parsed.ID)
+	assert.True(t, parsed.IsRegistered())
+
+	// code cannot be reused
+	_, err = svc.Callback(ctx, anon, flow.State, state, code)
+	assert.ErrorIs(t, err, ErrProvider)
+
+	// next login returns same user, links are not claimed again
+	flow, err = svc.Start(ctx, "https://evil.test/")
+	require.NoError(t, err)
+	state, code = signIn(t, flow)
+	again, err := svc.Callback(ctx, user.NewWithID("anonymous2"), flow.State, state, code)
+	require.NoError(t, err)
+	assert.Equal(t, sess.User.ID, again.User.ID)
+	assert.Empty(t, again.Return)
+	assert.Zero(t, again.Claimed)
+
+	// other subject gets other user
+	idp.Subject = "other-subject"
+	flow, err = svc.Start(ctx, "")
+	require.NoError(t, err)
+	state, code = signIn(t, flow)
+	other, err := svc.Callback(ctx, user.NewWithID("anonymous3"), flow.State, state, code)
+	require.NoError(t, err)
+	assert.NotEqual(t, sess.User.ID, other.User.ID)
+}
+
+func TestService_ClaimQuota(t *testing.T) {
+	ctx := context.Background()
+	idp, err := oidctest.NewProvider("shorty", "secret")
+	require.NoError(t, err)
+	defer idp.Close()
+
+	store := memory.New()
+	svc := New(&Config{
+		Storage:    store,
+		Authorizer: auth.New("jwt-secret"),
+		Quotas: quota.New(&quota.Config{
+			Storage:     store,
+			Logger:      zap.NewNop(),
+			DefaultTier: "free",
+			Tiers:       []model.Tier{{Name: "free", MaxURLs: 2}},
+		}),
+		Logger:       zap.NewNop(),
+		Issuer:       idp.Issuer(),
+		ClientID:     "shorty",
+		ClientSecret: "secret",
+		RedirectURL:  testRedirectURL,
+	})
+
+	anon := user.NewWithID("anonymous")
+	require.NoError(t, store.StoreBatch(ctx, []model.URL{
+		{Short: "aaa", Orig: "https://aaa.bbb/1", UserID: anon.ID},
+		{Short: "bbb", Orig: "https://aaa.bbb/2", UserID: anon.ID},
+		{Short: "ccc", Orig: "https://aaa.bbb/3", UserID: anon.ID},
+	}))
+
+	// subject is not mapped to user if anonymous links exceed quota of new user
+	flow, err := svc.Start(ctx, "")
+	require.NoError(t, err)
+	state, code := signIn(t, flow)
+	_, err = svc.Callback(ctx, anon, flow.State, state, code)
+	assert.Equal(t, &quota.ExceededError{Quota: quota.QuotaURLs, Limit: 2, Requested: 3, Remaining: 2}, err)
+	num, err := store.CountUserURLs(ctx, anon.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, num)
+	_, err = store.GetIdentity(ctx, idp.Issuer(), idp.Subject)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	anon2 := user.NewWithID("anonymous2")
+	_, err = store.Store(ctx, &model.URL{Short: "ddd", Orig: "https://aaa.bbb/4", UserID: anon2.ID}, false)
+	require.NoError(t, err)
+	flow, err = svc.Start(ctx, "")
+	require.NoError(t, err)
+	state, code = signIn(t, flow)
+	sess, err := svc.Callback(ctx, anon2, flow.State, state, code)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), sess.Claimed)
+}
+
+func TestService_CallbackErrors(t *testing.T) {
+	ctx := context.Background()
+	idp, err := oidctest.NewProvider("shorty", "")
+	require.NoError(t, err)
+	defer idp.Close()
+
+	svc := New(&Config{
+		Storage:     memory.New(),
+		Authorizer:  auth.New("jwt-secret"),
+		Logger:      zap.NewNop(),
+		Issuer:      idp.Issuer(),
+		ClientID:    "shorty",
+		RedirectURL: testRedirectURL,
+	})
+	u := user.NewWithID("anonymous")
+
+	flow, err := svc.Start(ctx, "")
+	require.NoError(t, err)
+	state, code := signIn(t, flow)
+
+	// state must match signed flow state
+	_, err = svc.Callback(ctx, u, flow.State, "other", code)
+	assert.ErrorIs(t, err, ErrInvalidState)
+	_, err = svc.Callback(ctx, u, flow.State+"x", state, code)
+	assert.ErrorIs(t, err, ErrInvalidState)
+	session, err := auth.New("jwt-secret").CreateToken(u)
+	require.NoError(t, err)
+	_, err = svc.Callback(ctx, u, session, state, code)
+	assert.ErrorIs(t, err, ErrInvalidState)
+
+	// nonce must match
+	idp.Nonce = "other"
+	_, err = svc.Callback(ctx, u, flow.State, state, code)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	idp.Nonce = ""
+
+	// provider rejects code exchanged with verifier of other flow
+	flow2, err := svc.Start(ctx, "")
+	require.NoError(t, err)
+	_, code2 := signIn(t, flow2)
+	_, err = svc.Callback(ctx, u, flow.State, state, code2)
+	assert.ErrorIs(t, err, ErrProvider)
+
+	// unavailable provider
+	_, err = New(&Config{
+		Storage:    memory.New(),
+		Authorizer: auth.New("jwt-secret"),
+		Logger:     zap.NewNop(),
+		Issuer:     "http://127.0.0.1:1",
+	}).Start(ctx, "")
+	assert.ErrorIs(t, err, ErrProvider)
+}
+
+func TestIsLocalPath(t *testing.T) {
+	tests := []struct {
+		path  string
+		local bool
+	}{
+		{path: "/", local: true},
+		{path: "/api/user/urls?tag=a#top", local: true},
+		{path: "/a/b%20c", local: true},
+		{path: ""},
+		{path: "api/user/urls"},
+		{path: "https://evil.test/"},
+		{path: "//evil.test/"},
+		{path: "/\\evil.test/"},
+		{path: "/\\/evil.test/"},
+		{path: "/\t/evil.test/"},
+		{path: "/\n/evil.test/"},
+		{path: "/\x00/evil.test/"},
+		{path: "/\x7f/evil.test/"},
+		{path: "/%09/evil.test/"},
+		{path: "/%0a/evil.test/"},
+		{path: "/%5C/evil.test/"},
+		{path: "/%2F/evil.test/"},
+		{path: "/%zz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.local, isLocalPath(tt.path))
+		})
+	}
+}
@@ -0,0 +1,315 @@
+// Package quota is user quota service.
+// Quotas limit number of active personal links of user and number of links in single batch.
+// Limits are defined by named tiers from configuration, every user belongs to default tier
+// unless administrator assigns another tier or custom limits to user.
+//
+// Link limit is approximate. Links are counted before they are stored and storage is not locked
+// in between, so concurrent requests of the same user can overrun limit by the size of those requests.
+// Batch size limit is exact.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+	"go.uber.org/zap"
+)
+
+// Quota names.
+const (
+	QuotaURLs  = "urls"
+	QuotaBatch = "batch"
+)
+
+// Service errors.
+var (
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrNotFound      = errors.New("quota not found")
+	ErrInvalidUser   = errors.New("invalid user")
+	ErrInvalidQuota  = errors.New("invalid quota")
+	ErrStorageError  = errors.New("storage error")
+)
+
+// Storage is quota storage.
+type Storage interface {
+	CountUserURLs(ctx context.Context, userID string) (int, error)
+	GetQuota(ctx context.Context, userID string) (*model.Quota, error)
+	SetQuota(ctx context.Context, quota *model.Quota) error
+	DeleteQuota(ctx context.Context, userID string) error
+}
+
+// Service is quota service.
+type Service struct {
+	store       Storage
+	log         *zap.Logger
+	tiers       []model.Tier
+	defaultTier string
+}
+
+// Config is quota service config.
+type Config struct {
+	Storage     Storage
+	Logger      *zap.Logger
+	DefaultTier string
+	Tiers       []model.Tier
+}
+
+// Usage describes quota limits of user and their usage. Zero limit means no limit.
+type Usage struct {
+	Updated  time.Time `json:"updated,omitempty"`
+	UserID   string    `json:"user_id"`
+	Tier     string    `json:"tier,omitempty"`
+	Custom   bool      `json:"custom,omitempty"`
+	MaxURLs  int       `json:"max_urls"`
+	MaxBatch int       `json:"max_batch"`
+	URLs     int       `json:"urls"`
+	// RemainingURLs is nil if number of links is not limited.
+	RemainingURLs *int `json:"remaining_urls,omitempty"`
+}
+
+// ExceededError is returned when request exceeds user quota.
+type ExceededError struct {
+	Quota     string `json:"quota"`
+	Limit     int    `json:"limit"`
+	Requested int    `json:"requested"`
+	Remaining int    `json:"remaining"`
+}
+
+// Error returns error message.
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s quota exceeded: limit %d, requested %d, remaining %d",
+		e.Quota, e.Limit, e.Requested, e.Remaining)
+}
+
+// Unwrap returns ErrQuotaExceeded.
+func (e *ExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// New creates quota service.
+func New(cfg *Config) *Service {
+	return &Service{
+		store:       cfg.Storage,
+		log:         cfg.Logger.With(zap.String("component", "quota")),
+		tiers:       cfg.Tiers,
+		defaultTier: cfg.DefaultTier,
+	}
+}
+
+// CheckBatch checks that user can submit batch of specified size.
+func (svc *Service) CheckBatch(ctx context.Context, userID string, size int) error {
+	usage, err := svc.limits(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if usage.MaxBatch > 0 && size > usage.MaxBatch {
+		return &ExceededError{
+			Quota:     QuotaBatch,
+			Limit:     usage.MaxBatch,
+			Requested: size,
+			Remaining: usage.MaxBatch,
+		}
+	}
+	return nil
+}
+
+// CheckURLs checks that user can create specified number of personal links.
+// Check and following link creation are not atomic, so concurrent requests
+// of the same user can slightly overrun limit.
+func (svc *Service) CheckURLs(ctx context.Context, userID string, links int) error {
+	usage, err := svc.limits(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if usage.MaxURLs == 0 {
+		return nil
+	}
+	if usage.URLs, err = svc.store.CountUserURLs(ctx, userID); err != nil {
+		return errors.Join(ErrStorageError, err)
+	}
+	if remaining := max(usage.MaxURLs-usage.URLs, 0); links > remaining {
+		return &ExceededError{
+			Quota:     QuotaURLs,
+			Limit:     usage.MaxURLs,
+			Requested: links,
+			Remaining: remaining,
+		}
+	}
+	return nil
+}
+
+// Get returns quota usage of user.
+func (svc *Service) Get(ctx context.Context, u *user.User) (*Usage, error) {
+	if u.IsNew() {
+		return nil, ErrUnauthorized
+	}
+	return svc.usage(ctx, u.ID)
+}
+
+// Lookup returns quota usage of any user.
+func (svc *Service) Lookup(ctx context.Context, userID string) (*Usage, error) {
+	if _, err := user.NewFromUserID(userID); err != nil {
+		return nil, errors.Join(ErrInvalidUser, err)
+	}
+	return svc.usage(ctx, userID)
+}
+
+// Set assigns tier or custom limits to user. Tier and custom limits are mutually exclusive,
+// custom limits with zero values remove all limits of user.
+func (svc *Service) Set(ctx context.Context, userID, tier string, maxURLs, maxBatch int) (*Usage, error) {
+	if _, err := user.NewFromUserID(userID); err != nil {
+		return nil, errors.Join(ErrInvalidUser, err)
+	}
+	switch {
+	case maxURLs < 0 || maxBatch < 0:
+		return nil, fmt.Errorf("%w: limits cannot be negative", ErrInvalidQuota)
+	case tier != "" && (maxURLs != 0 || maxBatch != 0):
+		return nil, fmt.Errorf("%w: either tier or custom limits must be set", ErrInvalidQuota)
+	case tier != "" && svc.tier(tier) == nil:
+		return nil, fmt.Errorf("%w: unknown tier %q", ErrInvalidQuota, tier)
+	}
+	if err := svc.store.SetQuota(ctx, &model.Quota{
+		Updated:  time.Now().UTC().Truncate(time.Second),
+		UserID:   userID,
+		Tier:     tier,
+		MaxURLs:  maxURLs,
+		MaxBatch: maxBatch,
+	}); err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	svc.log.Info("user quota was changed",
+		zap.String("userID", userID),
+		zap.String("tier", tier),
+		zap.Int("maxURLs", maxURLs),
+		zap.Int("maxBatch", maxBatch))
+	return svc.usage(ctx, userID)
+}
+
+// Reset removes quota assignment of user, so user gets back to default tier.
+func (svc *Service) Reset(ctx context.Context, userID string) error {
+	if _, err := user.NewFromUserID(userID); err != nil {
+		return errors.Join(ErrInvalidUser, err)
+	}
+	if err := svc.store.DeleteQuota(ctx, userID); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return ErrNotFound
+		}
+		return errors.Join(ErrStorageError, err)
+	}
+	svc.log.Info("user quota was reset", zap.String("userID", userID))
+	return nil
+}
+
+// Tiers returns configured tiers.
+func (svc *Service) Tiers() []model.Tier {
+	return slices.Clone(svc.tiers)
+}
+
+// DefaultTier returns name of tier users belong to by default.
+func (svc *Service) DefaultTier() string {
+	return svc.defaultTier
+}
+
+func (svc *Service) usage(ctx context.Context, userID string) (*Usage, error) {
+	usage, err := svc.limits(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if usage.URLs, err = svc.store.CountUserURLs(ctx, userID); err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	if usage.MaxURLs > 0 {
+		remaining := max(usage.MaxURLs-usage.URLs, 0)
+		usage.RemainingURLs = &remaining
+	}
+	return usage, nil
+}
+
+// limits returns limits of user without usage.
+// Users without assignment and users of tier that is no longer configured belong to default tier.
+func (svc *Service) limits(ctx context.Context, userID string) (*Usage, error) {
+	usage := &Usage{UserID: userID, Tier: svc.defaultTier}
+	quota, err := svc.store.GetQuota(ctx, userID)
+	switch {
+	case err == nil:
+		usage.Updated = quota.Updated
+		if quota.Tier == "" {
+			usage.Tier = ""
+			usage.Custom = true
+			usage.MaxURLs = quota.MaxURLs
+			usage.MaxBatch = quota.MaxBatch
+			return usage, nil
+		}
+		if svc.tier(quota.Tier) != nil {
+			usage.Tier = quota.Tier
+		} else {
+			svc.log.Warn("user is assigned to unknown tier, default tier is used",
+				zap.String("userID", userID),
+				zap.String("tier", quota.Tier))
+		}
+	case !errors.Is(err, model.ErrNotFound):
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	if tier := svc.tier(usage.Tier); tier != nil {
+		usage.MaxURLs = tier.MaxURLs
+		usage.MaxBatch = tier.MaxBatch
+	}
+	return usage, nil
+}
+
+func (svc *Service) tier(name string) *model.Tier {
+	for i := range svc.tiers {
+		if svc.tiers[i].Name == name {
+			return &svc.tiers[i]
+		}
+	}
+	return nil
+}
+
+// ParseTiers parses comma separated list of tiers.
+// Tier format is <name>=<max links>/<max batch>, e.g. "free=100/10". Zero limit means no limit.
+func ParseTiers(tiers string) ([]model.Tier, error) {
+	var result []model.Tier
+	for _, tierS := range strings.Split(tiers, ",") {
+		if tierS = strings.TrimSpace(tierS); tierS == "" {
+			continue
+		}
+		tier, err := parseTier(tierS)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quota tier %q: %w", tierS, err)
+		}
+		if slices.ContainsFunc(result, func(t model.Tier) bool { return t.Name == tier.Name }) {
+			return nil, fmt.Errorf("duplicate quota tier %q", tier.Name)
+		}
+		result = append(result, tier)
+	}
+	return result, nil
+}
+
+func parseTier(tierS string) (model.Tier, error) {
+	name, limitsS, ok := strings.Cut(tierS, "=")
+	if name = strings.TrimSpace(name); !ok || name == "" {
+		return model.Tier{}, errors.New("tier name is missing")
+	}
+	urlsS, batchS, ok := strings.Cut(limitsS, "/")
+	if !ok {
+		return model.Tier{}, errors.New("limits must be <max links>/<max batch>")
+	}
+	maxURLs, err := strconv.Atoi(strings.TrimSpace(urlsS))
+	if err != nil || maxURLs < 0 {
+		return model.Tier{}, errors.New("max links must be non-negative integer")
+	}
+	maxBatch, err := strconv.Atoi(strings.TrimSpace(batchS))
+	if err != nil || maxBatch < 0 {
+		return model.Tier{}, errors.New("max batch must be non-negative integer")
+	}
+	return model.Tier{Name: name, MaxURLs: maxURLs, MaxBatch: maxBatch}, nil
+}
@@ -0,0 +1,115 @@
+package quota
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/storage/memory"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseTiers(t *testing.T) {
+	tiers, err := ParseTiers("free=100/10, pro=0/1000,")
+	require.NoError(t, err)
+	assert.Equal(t, []model.Tier{
+		{Name: "free", MaxURLs: 100, MaxBatch: 10},
+		{Name: "pro", MaxURLs: 0, MaxBatch: 1000},
+	}, tiers)
+
+	for _, tier := range []string{"free", "=1/1", "free=1", "free=-1/1", "free=1/qwe", "free=1/1,free=2/2"} {
+		_, err = ParseTiers(tier)
+		assert.Error(t, err, tier)
+	}
+}
+
+func TestService_Quotas(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	svc := New(&Config{
+		Storage:     store,
+		Logger:      zap.NewNop(),
+		DefaultTier: "free",
+		Tiers: []model.Tier{
+			{Name: "free", MaxURLs: 2, MaxBatch: 2},
+			{Name: "pro", MaxURLs: 0, MaxBatch: 10},
+		},
+	})
+	u := existingUser(t)
+	for _, url := range []*model.URL{
+		{Short: "aaa", Orig: "https://aaa.bbb/1", UserID: u.ID},
+		{Short: "bbb", Orig: "https://aaa.bbb/2", UserID: u.ID, WorkspaceID: "ws"},
+	} {
+		_, err := store.Store(ctx, url, false)
+		require.NoError(t, err)
+	}
+
+	// users belong to default tier, workspace links are not counted
+	usage, err := svc.Get(ctx, u)
+	require.NoError(t, err)
+	assert.Equal(t, "free", usage.Tier)
+	assert.Equal(t, 1, usage.URLs)
+	require.NotNil(t, usage.RemainingURLs)
+	assert.Equal(t, 1, *usage.RemainingURLs)
+	require.NoError(t, svc.CheckURLs(ctx, u.ID, 1))
+	require.NoError(t, svc.CheckBatch(ctx, u.ID, 2))
+
+	err = svc.CheckURLs(ctx, u.ID, 2)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Equal(t, &ExceededError{Quota: QuotaURLs, Limit: 2, Requested: 2, Remaining: 1}, err)
+	err = svc.CheckBatch(ctx, u.ID, 3)
+	assert.Equal(t, &ExceededError{Quota: QuotaBatch, Limit: 2, Requested: 3, Remaining: 2}, err)
+
+	// tier and custom limits are mutually exclusive
+	for _, tc := range []struct {
+		tier          string
+		urls, batches int
+	}{{"unknown", 0, 0}, {"pro", 1, 0}, {"", -1, 0}} {
+		_, err = svc.Set(ctx, u.ID, tc.tier, tc.urls, tc.batches)
+		assert.ErrorIs(t, err, ErrInvalidQuota, tc)
+	}
+	_, err = svc.Set(ctx, "qwe", "pro", 0, 0)
+	assert.ErrorIs(t, err, ErrInvalidUser)
+
+	usage, err = svc.Set(ctx, u.ID, "pro", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "pro", usage.Tier)
+	assert.Nil(t, usage.RemainingURLs)
+	require.NoError(t, svc.CheckURLs(ctx, u.ID, 100))
+	assert.ErrorIs(t, svc.CheckBatch(ctx, u.ID, 11), ErrQuotaExceeded)
+
+	usage, err = svc.Set(ctx, u.ID, "", 5, 0)
+	require.NoError(t, err)
+	assert.True(t, usage.Custom)
+	assert.Equal(t, 5, usage.MaxURLs)
+	require.NoError(t, svc.CheckBatch(ctx, u.ID, 100))
+
+	// reset brings user back to default tier
+	require.NoError(t, svc.Reset(ctx, u.ID))
+	assert.ErrorIs(t, svc.Reset(ctx, u.ID), ErrNotFound)
+	usage, err = svc.Lookup(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "free", usage.Tier)
+
+	// assignment of tier that was removed from configuration falls back to default tier
+	require.NoError(t, store.SetQuota(ctx, &model.Quota{UserID: u.ID, Tier: "gone"}))
+	usage, err = svc.Lookup(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "free", usage.Tier)
+	assert.Equal(t, 2, usage.MaxURLs)
+
+	anonymous, err := user.New()
+	require.NoError(t, err)
+	_, err = svc.Get(ctx, anonymous)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func existingUser(t *testing.T) *user.User {
+	t.Helper()
+	u, err := user.New()
+	require.NoError(t, err)
+	return user.NewWithID(u.ID)
+}
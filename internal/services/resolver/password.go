@@ -0,0 +1,92 @@
+package resolver
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	maxPasswordAttempts    = 5
+	passwordAttemptsWindow = time.Minute
+
+	// attempt counters are cleaned up only if there's more of them.
+	attemptsCleanupSize = 1000
+)
+
+// checkPassword verifies password of protected link.
+// Failed attempts are limited per link. Attempt is reserved before comparison,
+// so concurrent requests cannot exceed the limit, and released if password matches.
+func (svc *Service) checkPassword(short, hash, password string) error {
+	if password == "" {
+		return ErrPasswordRequired
+	}
+	now := svc.now()
+	if !svc.attempts.reserve(short, now) {
+		return ErrTooManyAttempts
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return ErrWrongPassword
+	}
+	svc.attempts.release(short, now)
+	return nil
+}
+
+// attemptLimiter limits failed attempts per key using fixed time windows.
+type attemptLimiter struct {
+	counters map[string]*attempts
+	max      int
+	window   time.Duration
+	mx       sync.Mutex
+}
+
+type attempts struct {
+	reset time.Time
+	count int
+}
+
+func newAttemptLimiter(maxAttempts int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		counters: make(map[string]*attempts),
+		max:      maxAttempts,
+		window:   window,
+	}
+}
+
+// reserve registers attempt for key and returns true if attempt is allowed.
+// Reserved attempt is counted as failed unless it is released.
+func (l *attemptLimiter) reserve(key string, now time.Time) bool {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	a, ok := l.counters[key]
+	if !ok || now.After(a.reset) {
+		if len(l.counters) >= attemptsCleanupSize {
+			l.cleanup(now)
+		}
+		l.counters[key] = &attempts{reset: now.Add(l.window), count: 1}
+		return true
+	}
+	if a.count >= l.max {
+		return false
+	}
+	a.count++
+	return true
+}
+
+// release returns attempt reserved for key in the same window.
+func (l *attemptLimiter) release(key string, now time.Time) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	if a, ok := l.counters[key]; ok && !now.After(a.reset) && a.count > 0 {
+		a.count--
+	}
+}
+
+func (l *attemptLimiter) cleanup(now time.Time) {
+	for key, a := range l.counters {
+		if now.After(a.reset) {
+			delete(l.counters, key)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package resolver
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/adwski/shorty/internal/app/mockapp"
+	"github.com/adwski/shorty/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestService_ResolvePassword(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	st := mockapp.NewStorage(t)
+	st.EXPECT().Get(mock.Anything, "qweasdzxcr").Return(&model.URL{
+		Orig:         "https://aaa.bbb",
+		PasswordHash: string(hash),
+	}, nil)
+	st.EXPECT().Get(mock.Anything, "zxcasdqwer").Return(&model.URL{
+		Orig:         "https://ccc.ddd",
+		PasswordHash: string(hash),
+	}, nil)
+
+	now := time.Now()
+	svc := New(&Config{
+		Store:  st,
+		Logger: logger,
+	})
+	svc.now = func() time.Time { return now }
+
+	resolve := func(path, password string) (*Redirect, error) {
+		return svc.Resolve(context.Background(), &Request{
+			Path:     path,
+			Password: password,
+		})
+	}
+
+	// no password
+	redirect, err := resolve("/qweasdzxcr", "")
+	assert.Nil(t, redirect)
+	assert.ErrorIs(t, err, ErrPasswordRequired)
+
+	// correct password
+	redirect, err = resolve("/qweasdzxcr", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "https://aaa.bbb", redirect.URL)
+
+	// wrong password until limit is reached
+	for i := 0; i < maxPasswordAttempts; i++ {
+		redirect, err = resolve("/qweasdzxcr", "wrong")
+		assert.Nil(t, redirect)
+		assert.ErrorIs(t, err, ErrWrongPassword)
+	}
+
+	// correct password is also rejected
+	redirect, err = resolve("/qweasdzxcr", "secret")
+	assert.Nil(t, redirect)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+
+	// limit is per link
+	redirect, err = resolve("/zxcasdqwer", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "https://ccc.ddd", redirect.URL)
+
+	// limit is reset after window
+	now = now.Add(passwordAttemptsWindow + time.Second)
+	redirect, err = resolve("/qweasdzxcr", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "https://aaa.bbb", redirect.URL)
+}
+
+func TestAttemptLimiter_Cleanup(t *testing.T) {
+	var (
+		l   = newAttemptLimiter(1, time.Minute)
+		now = time.Now()
+	)
+	for i := 0; i < attemptsCleanupSize; i++ {
+		assert.True(t, l.reserve(string(rune(i)), now))
+	}
+	assert.Len(t, l.counters, attemptsCleanupSize)
+	assert.False(t, l.reserve(string(rune(0)), now))
+
+	now = now.Add(2 * time.Minute)
+	assert.True(t, l.reserve("qwe", now))
+	assert.Len(t, l.counters, 1)
+	assert.False(t, l.reserve("qwe", now))
+	assert.True(t, l.reserve(string(rune(0)), now))
+}
+
+func TestAttemptLimiter_Concurrent(t *testing.T) {
+	var (
+		l       = newAttemptLimiter(maxPasswordAttempts, time.Minute)
+		now     = time.Now()
+		wg      sync.WaitGroup
+		allowed atomic.Int32
+	)
+	for i := 0; i < 10*maxPasswordAttempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l.reserve("qwe", now) {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(maxPasswordAttempts), allowed.Load())
+
+	// released attempts can be reserved again
+	l.release("qwe", now)
+	assert.True(t, l.reserve("qwe", now))
+	assert.False(t, l.reserve("qwe", now))
+}
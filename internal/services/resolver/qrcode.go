@@ -0,0 +1,112 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/adwski/shorty/internal/qrcode"
+)
+
+// QR code image formats.
+const (
+	QRFormatPNG = "png"
+	QRFormatSVG = "svg"
+)
+
+const (
+	defaultQRSize   = 256
+	defaultQRMargin = 4
+	minQRSize       = 32
+	maxQRSize       = 2048
+	maxQRMargin     = 16
+)
+
+// QRRequest holds attributes of QR code request.
+// Zero values mean defaults: PNG format, 256 pixels, 4 modules margin and M level.
+type QRRequest struct {
+	// Margin is quiet zone width in modules, nil means default.
+	Margin *int
+	// Path is short path without suffix.
+	Path   string
+	Format string
+	Level  string
+	// Size is image side in pixels.
+	Size int
+}
+
+// QRCode is rendered QR code image.
+type QRCode struct {
+	Data        []byte
+	ContentType string
+}
+
+// QRCode renders QR code image of short URL. Short path is validated
+// the same way as in Resolve and link must exist and must not be deleted.
+func (svc *Service) QRCode(ctx context.Context, req *QRRequest) (*QRCode, error) {
+	short, suffix, err := validatePath(req.Path)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidPath, err)
+	}
+	if suffix != "" {
+		return nil, errors.Join(ErrInvalidPath, fmt.Errorf("unexpected path suffix"))
+	}
+	size, margin, level, err := qrParams(req)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidQRParams, err)
+	}
+	if _, err = svc.store.Get(ctx, short); err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+
+	code, err := qrcode.Encode([]byte(fmt.Sprintf("%s://%s/%s", svc.servedScheme, svc.host, short)), level)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode qr code: %w", err)
+	}
+	var (
+		buf    bytes.Buffer
+		result = &QRCode{}
+	)
+	switch req.Format {
+	case QRFormatSVG:
+		result.ContentType = "image/svg+xml"
+		err = code.WriteSVG(&buf, size, margin)
+	default:
+		result.ContentType = "image/png"
+		err = code.WritePNG(&buf, size, margin)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot render qr code: %w", err)
+	}
+	result.Data = buf.Bytes()
+	return result, nil
+}
+
+// qrParams validates QR code request params and applies defaults.
+func qrParams(req *QRRequest) (size, margin int, level qrcode.Level, err error) {
+	switch req.Format {
+	case "", QRFormatPNG, QRFormatSVG:
+	default:
+		return 0, 0, 0, fmt.Errorf("unsupported format: %q", req.Format)
+	}
+	size, margin, level = defaultQRSize, defaultQRMargin, qrcode.LevelM
+	if req.Size != 0 {
+		if req.Size < minQRSize || req.Size > maxQRSize {
+			return 0, 0, 0, fmt.Errorf("size must be between %d and %d", minQRSize, maxQRSize)
+		}
+		size = req.Size
+	}
+	if req.Margin != nil {
+		if *req.Margin < 0 || *req.Margin > maxQRMargin {
+			return 0, 0, 0, fmt.Errorf("margin must be between 0 and %d", maxQRMargin)
+		}
+		margin = *req.Margin
+	}
+	if req.Level != "" {
+		if level, err = qrcode.ParseLevel(req.Level); err != nil {
+			return 0, 0, 0, fmt.Errorf("invalid level: %w", err)
+		}
+	}
+	return size, margin, level, nil
+}
@@ -0,0 +1,123 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"testing"
+
+	"github.com/adwski/shorty/internal/app/mockapp"
+	"github.com/adwski/shorty/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestService_QRCode(t *testing.T) {
+	zero := 0
+	tests := []struct {
+		name        string
+		req         *QRRequest
+		storeErr    error
+		noStore     bool
+		err         error
+		contentType string
+		side        int
+	}{
+		{
+			name:        "png defaults",
+			req:         &QRRequest{Path: "/qweasdzxcr"},
+			contentType: "image/png",
+			// 29 modules + 8 margin, 6 pixels per module
+			side: 222,
+		},
+		{
+			name:        "png without margin",
+			req:         &QRRequest{Path: "/qweasdzxcr", Size: 100, Margin: &zero, Level: "L"},
+			contentType: "image/png",
+			// version 2 with level L, 25 modules, 4 pixels per module
+			side: 100,
+		},
+		{
+			name:        "svg",
+			req:         &QRRequest{Path: "/qweasdzxcr", Format: QRFormatSVG, Level: "h"},
+			contentType: "image/svg+xml",
+		},
+		{
+			name:     "not found",
+			req:      &QRRequest{Path: "/qweasdzxcr"},
+			storeErr: model.ErrNotFound,
+			err:      model.ErrNotFound,
+		},
+		{
+			name:     "deleted",
+			req:      &QRRequest{Path: "/qweasdzxcr"},
+			storeErr: model.ErrDeleted,
+			err:      model.ErrDeleted,
+		},
+		{
+			name:    "invalid path",
+			req:     &QRRequest{Path: "/qwe.asd"},
+			noStore: true,
+			err:     ErrInvalidPath,
+		},
+		{
+			name:    "path suffix",
+			req:     &QRRequest{Path: "/qweasdzxcr/asd"},
+			noStore: true,
+			err:     ErrInvalidPath,
+		},
+		{
+			name:    "unsupported format",
+			req:     &QRRequest{Path: "/qweasdzxcr", Format: "gif"},
+			noStore: true,
+			err:     ErrInvalidQRParams,
+		},
+		{
+			name:    "too large",
+			req:     &QRRequest{Path: "/qweasdzxcr", Size: 4096},
+			noStore: true,
+			err:     ErrInvalidQRParams,
+		},
+		{
+			name:    "invalid level",
+			req:     &QRRequest{Path: "/qweasdzxcr", Level: "X"},
+			noStore: true,
+			err:     ErrInvalidQRParams,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, err := zap.NewDevelopment()
+			require.NoError(t, err)
+
+			st := mockapp.NewStorage(t)
+			if !tt.noStore {
+				st.EXPECT().Get(mock.Anything, "qweasdzxcr").Return(&model.URL{Orig: "https://aaa.bbb"}, tt.storeErr)
+			}
+
+			svc := New(&Config{
+				Store:        st,
+				Logger:       logger,
+				ServedScheme: "http",
+				Host:         "localhost:8080",
+			})
+			code, err := svc.QRCode(context.Background(), tt.req)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				assert.Nil(t, code)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.contentType, code.ContentType)
+			if tt.contentType != "image/png" {
+				assert.Contains(t, string(code.Data), `width="256" height="256"`)
+				return
+			}
+			img, err := png.Decode(bytes.NewReader(code.Data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.side, img.Bounds().Dx())
+		})
+	}
+}
@@ -1,67 +1,365 @@
 // Package resolver implements shortened URLs redirects.
 // It's independent of shortener service and potentially could be used separately.
+//
+// Links with enabled passthrough accept path suffix after short path
+// (/{short}/rest/of/path) which is appended to original URL path,
+// and query params which are merged into original URL query.
+// Params already present in original URL are not overridden.
+//
+// Links can also have conditional targets that are matched
+// by client platform detected from User-Agent and by client country
+// resolved from client IP using GeoIP database. If country is unknown,
+// country targets are not matched and default destination is used.
+//
+// Links with schedule use destination of rule which time window
+// includes current time. Rule times without offset are evaluated
+// in link timezone or in configured default timezone.
+//
+// Links can be protected by password, such links are resolved only
+// if correct password is provided. Failed password attempts are limited per link.
+//
+// Instead of redirect, preview page with destination can be shown.
+// Preview is either requested explicitly or forced by link for every visit.
+//
+// QR codes of short URLs can be rendered for existing links.
+//
+// Links with weighted variants split traffic between destinations.
+// Variant choice is sticky per visitor, visitor is identified by cookie
+// or by client IP hash. Variant clicks are counted asynchronously using Flusher queue.
 package resolver
 
 import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 	"unicode"
 
+	"github.com/adwski/shorty/internal/buffer"
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/platform"
 	"go.uber.org/zap"
 )
 
+const (
+	headerUserAgent = "User-Agent"
+	headerCookie    = "Cookie"
+)
+
 // Service errors.
 var (
 	ErrInvalidPath  = errors.New("invalid path")
+	ErrInvalidQuery = errors.New("invalid query")
 	ErrStorageError = errors.New("storage error")
+
+	ErrInvalidQRParams = errors.New("invalid qr code params")
+
+	ErrPasswordRequired = errors.New("password required")
+	ErrWrongPassword    = errors.New("wrong password")
+	ErrTooManyAttempts  = errors.New("too many password attempts")
 )
 
 // Storage is URL storage used by resolver.
 type Storage interface {
-	Get(ctx context.Context, key string) (url string, err error)
+	Get(ctx context.Context, key string) (url *model.URL, err error)
+	AddVariantClicks(ctx context.Context, clicks []model.Click) error
+}
+
+// GeoIP resolves country of IP address.
+type GeoIP interface {
+	// Country returns ISO 3166-1 alpha-2 country code
+	// or empty string if country is unknown.
+	Country(ip string) string
 }
 
 // Service implements http handler for url redirects.
 // It uses url storage as source for short urls mappings.
 type Service struct {
-	store Storage
-	log   *zap.Logger
+	store           Storage
+	geoIP           GeoIP
+	flusher         *buffer.Flusher[model.Click]
+	attempts        *attemptLimiter
+	log             *zap.Logger
+	timezone        *time.Location
+	now             func() time.Time
+	locations       sync.Map
+	defaultRedirect int
+	cacheMaxAge     time.Duration
+	servedScheme    string
+	host            string
 }
 
 // Config is resolver service config.
 type Config struct {
 	Store  Storage
 	Logger *zap.Logger
+
+	// ServedScheme and Host are used to build short URLs encoded in QR codes.
+	ServedScheme string
+	Host         string
+
+	// GeoIP is optional, without it country targets never match.
+	GeoIP GeoIP
+
+	// Timezone is default timezone of schedule rules, UTC is used if not set.
+	Timezone *time.Location
+
+	// DefaultRedirect is redirect status code that is used
+	// for URLs without explicitly set redirect type.
+	DefaultRedirect int
+
+	// CacheMaxAge is how long clients may cache permanent redirects,
+	// permanent redirects are not cached if it's zero.
+	CacheMaxAge time.Duration
+}
+
+// Request holds attributes of incoming resolve request.
+type Request struct {
+	// Path is short path with optional suffix.
+	Path string
+	// Query is raw query string without leading '?'.
+	Query     string
+	UserAgent string
+	// VisitorID is previously issued visitor identifier, it's used for sticky variants.
+	VisitorID string
+	ClientIP  string
+	// Password is used to resolve protected links.
+	Password string
+	// Preview requests interstitial preview page instead of redirect.
+	Preview bool
+}
+
+// Redirect is resolved redirect.
+type Redirect struct {
+	URL  string
+	Code int
+
+	// Vary is a list of request headers that were used to resolve redirect.
+	Vary []string
+
+	// MaxAge is how long redirect can be cached by clients, zero means it must not be cached.
+	// Only permanent redirects of links with static destination can be cached.
+	MaxAge time.Duration
+
+	// VisitorID is set if redirect depends on visitor identity.
+	// It should be persisted by client to keep variant choice sticky.
+	VisitorID string
+
+	// Preview is set if preview page must be shown instead of redirect,
+	// either because it was requested or because link forces it.
+	Preview bool
+	// Title and Created are link attributes shown on preview page.
+	Title   string
+	Created time.Time
+}
+
+// IsPermanent returns whether redirect is permanent.
+func (r *Redirect) IsPermanent() bool {
+	return r.Code == http.StatusMovedPermanently || r.Code == http.StatusPermanentRedirect
 }
 
 // New creates new resolver service.
 func New(cfg *Config) *Service {
-	return &Service{
-		store: cfg.Store,
-		log:   cfg.Logger,
+	defaultRedirect := cfg.DefaultRedirect
+	if defaultRedirect == 0 {
+		defaultRedirect = http.StatusTemporaryRedirect
+	}
+	timezone := cfg.Timezone
+	if timezone == nil {
+		timezone = time.UTC
+	}
+	svc := &Service{
+		store:           cfg.Store,
+		geoIP:           cfg.GeoIP,
+		log:             cfg.Logger,
+		timezone:        timezone,
+		now:             time.Now,
+		attempts:        newAttemptLimiter(maxPasswordAttempts, passwordAttemptsWindow),
+		defaultRedirect: defaultRedirect,
+		cacheMaxAge:     cfg.CacheMaxAge,
+		servedScheme:    cfg.ServedScheme,
+		host:            cfg.Host,
 	}
+	svc.flusher = buffer.NewFlusher(&buffer.FlusherConfig{
+		Logger:        cfg.Logger,
+		FlushInterval: flusherFlushInterval,
+		FlushSize:     flusherFillSize,
+		AllocSize:     flusherAllocSize,
+	}, svc.addClicks)
+	return svc
 }
 
-// Resolve lookups original URL using incoming shortened path.
-func (svc *Service) Resolve(ctx context.Context, path string) (string, error) {
-	if err := validatePath(path); err != nil {
-		return "", errors.Join(ErrInvalidPath, err)
+// GetFlusher returns click counter flusher instance.
+func (svc *Service) GetFlusher() *buffer.Flusher[model.Click] {
+	return svc.flusher
+}
+
+// Resolve lookups original URL using incoming request attributes.
+// If link has conditional targets, first target matched by client platform
+// and country is used as destination. Otherwise, active schedule rule is used.
+// Otherwise, if link has variants, one of them is chosen using visitor identity.
+// Path suffix and query are used only if link has passthrough enabled.
+func (svc *Service) Resolve(ctx context.Context, req *Request) (*Redirect, error) {
+	short, suffix, err := validatePath(req.Path)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidPath, err)
 	}
-	origURL, err := svc.store.Get(ctx, path[1:])
+	u, err := svc.store.Get(ctx, short)
 	if err != nil {
-		return "", errors.Join(ErrStorageError, err)
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	if suffix != "" && !u.Passthrough {
+		return nil, model.ErrNotFound
+	}
+	if u.IsProtected() {
+		if err = svc.checkPassword(short, u.PasswordHash, req.Password); err != nil {
+			return nil, err
+		}
+	}
+	redirect := &Redirect{
+		URL:     u.Orig,
+		Code:    u.Redirect,
+		Preview: req.Preview || u.Preview,
+		Title:   u.Title,
+		Created: u.Created,
+	}
+	if redirect.Code == 0 {
+		redirect.Code = svc.defaultRedirect
+	}
+	if redirect.IsPermanent() && !u.IsDynamic() && !u.IsProtected() {
+		redirect.MaxAge = svc.cacheMaxAge
+	}
+	var matched bool
+	if len(u.Targets) > 0 {
+		redirect.Vary = append(redirect.Vary, headerUserAgent)
+		redirect.URL, matched = matchTarget(u, platform.Detect(req.UserAgent), svc.clientCountry(u, req))
+	}
+	if !matched && u.Schedule != nil {
+		var scheduled string
+		if scheduled, matched = svc.matchSchedule(u.Schedule, svc.now()); matched {
+			redirect.URL = scheduled
+		}
 	}
-	return origURL, nil
+	if !matched && len(u.Variants) > 0 {
+		redirect.Vary = append(redirect.Vary, headerCookie)
+		svc.resolveVariant(short, u, req, redirect)
+	}
+	if u.Passthrough && (suffix != "" || req.Query != "") {
+		if redirect.URL, err = passthrough(redirect.URL, suffix, req.Query); err != nil {
+			return nil, err
+		}
+	}
+	return redirect, nil
+}
+
+// clientCountry returns client country if link has country targets.
+func (svc *Service) clientCountry(u *model.URL, req *Request) string {
+	if svc.geoIP == nil {
+		return ""
+	}
+	for _, target := range u.Targets {
+		if target.Country != "" {
+			return svc.geoIP.Country(req.ClientIP)
+		}
+	}
+	return ""
+}
+
+// matchTarget returns URL of first target matched by platform and country,
+// or original URL if there's no match.
+func matchTarget(u *model.URL, p platform.Platform, country string) (string, bool) {
+	for _, target := range u.Targets {
+		if target.Platform != "" && !p.Matches(target.Platform) {
+			continue
+		}
+		if target.Country != "" && (country == "" || target.Country != country) {
+			continue
+		}
+		return target.URL, true
+	}
+	return u.Orig, false
+}
+
+// passthrough appends path suffix to original URL path
+// and merges incoming query params into original URL query.
+func passthrough(orig, suffix, rawQuery string) (string, error) {
+	u, err := url.Parse(orig)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse stored url: %w", err)
+	}
+	if suffix != "" {
+		u = u.JoinPath(suffix)
+	}
+	if rawQuery == "" {
+		return u.String(), nil
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", errors.Join(ErrInvalidQuery, err)
+	}
+	var (
+		origQuery = u.Query()
+		extra     = make(url.Values)
+	)
+	for key, values := range query {
+		if !origQuery.Has(key) {
+			extra[key] = values
+		}
+	}
+	if len(extra) > 0 {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += extra.Encode()
+	}
+	return u.String(), nil
 }
 
-func validatePath(path string) error {
-	if path[0] != '/' {
-		return fmt.Errorf("path is not starts with /")
+// validatePath splits incoming path into short path and optional suffix.
+// Suffix must consist of non-empty path segments without dot-segments,
+// backslashes and control characters, so it cannot alter destination host.
+func validatePath(path string) (short, suffix string, err error) {
+	if len(path) == 0 || path[0] != '/' {
+		return "", "", fmt.Errorf("path is not starts with /")
+	}
+	short, suffix, _ = strings.Cut(path[1:], "/")
+	if short == "" {
+		return "", "", fmt.Errorf("short path is empty")
+	}
+	for i := 0; i < len(short); i++ {
+		if !unicode.IsLetter(rune(short[i])) && !unicode.IsDigit(rune(short[i])) {
+			return "", "", fmt.Errorf("invalid character in path: 0x%x", short[i])
+		}
+	}
+	if err = validateSuffix(suffix); err != nil {
+		return "", "", err
+	}
+	return short, suffix, nil
+}
+
+func validateSuffix(suffix string) error {
+	if suffix == "" {
+		return nil
+	}
+	segments := strings.Split(suffix, "/")
+	for i, segment := range segments {
+		switch segment {
+		case "":
+			if i != len(segments)-1 {
+				// only trailing slash is allowed
+				return fmt.Errorf("empty segment in path suffix")
+			}
+		case ".", "..":
+			return fmt.Errorf("dot segment in path suffix")
+		}
 	}
-	for i := 1; i < len(path); i++ {
-		if !unicode.IsLetter(rune(path[i])) && !unicode.IsDigit(rune(path[i])) {
-			return fmt.Errorf("invalid character in path: 0x%x", path[i])
+	for _, r := range suffix {
+		if r == '\\' || unicode.IsControl(r) {
+			return fmt.Errorf("invalid character in path suffix: %q", r)
 		}
 	}
 	return nil
@@ -2,7 +2,10 @@ package resolver
 
 import (
 	"context"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/adwski/shorty/internal/app/mockapp"
 	"github.com/adwski/shorty/internal/model"
@@ -12,16 +15,47 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	testUAAndroid = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko)"
+	testUAiOS     = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko)"
+	testUADesktop = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko)"
+)
+
+var testTargets = []model.Target{
+	{Platform: "android", URL: "https://play.google.com/store/apps/details?id=bbb.aaa"},
+	{Platform: "mobile", URL: "https://apps.apple.com/app/id123"},
+}
+
+var testCountryTargets = []model.Target{
+	{Country: "DE", Platform: "ios", URL: "https://apps.apple.com/de/app/id123"},
+	{Country: "DE", URL: "https://aaa.bbb/de"},
+	{Country: "US", URL: "https://aaa.bbb/us"},
+}
+
+type testGeoIP map[string]string
+
+func (g testGeoIP) Country(ip string) string {
+	return g[ip]
+}
+
 func TestService_Redirect(t *testing.T) {
 	type args struct {
 		pathLength   uint
 		path         string
+		query        string
+		userAgent    string
+		visitorID    string
+		clientIP     string
 		invalid      bool
-		addToStorage map[string]string
+		addToStorage map[string]model.URL
 	}
 	type want struct {
-		orig string
-		err  error
+		orig      string
+		vary      []string
+		visitorID string
+		code      int
+		maxAge    time.Duration
+		err       error
 	}
 	tests := []struct {
 		name string
@@ -33,12 +67,13 @@ func TestService_Redirect(t *testing.T) {
 			args: args{
 				pathLength: 10,
 				path:       "/qweasdzxcr",
-				addToStorage: map[string]string{
-					"qweasdzxcr": "https://aaa.bbb",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb"},
 				},
 			},
 			want: want{
 				orig: "https://aaa.bbb",
+				code: http.StatusTemporaryRedirect,
 			},
 		},
 		{
@@ -46,12 +81,46 @@ func TestService_Redirect(t *testing.T) {
 			args: args{
 				pathLength: 20,
 				path:       "/qweasdzxcr",
-				addToStorage: map[string]string{
-					"qweasdzxcr": "https://aaa.bbb",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb"},
 				},
 			},
 			want: want{
 				orig: "https://aaa.bbb",
+				code: http.StatusTemporaryRedirect,
+			},
+		},
+		{
+			name: "redirect existing, explicit redirect code",
+			args: args{
+				pathLength: 10,
+				path:       "/qweasdzxcr",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb", Redirect: http.StatusMovedPermanently},
+				},
+			},
+			want: want{
+				orig:   "https://aaa.bbb",
+				code:   http.StatusMovedPermanently,
+				maxAge: time.Hour,
+			},
+		},
+		{
+			name: "permanent redirect with variants is not cached",
+			args: args{
+				path:      "/qweasdzxcr",
+				visitorID: "abc",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb", Redirect: http.StatusPermanentRedirect, Variants: []model.Variant{
+						{URL: "https://ccc.ddd", Weight: 10},
+					}},
+				},
+			},
+			want: want{
+				orig:      "https://ccc.ddd",
+				vary:      []string{"Cookie"},
+				visitorID: "abc",
+				code:      http.StatusPermanentRedirect,
 			},
 		},
 		{
@@ -59,8 +128,8 @@ func TestService_Redirect(t *testing.T) {
 			args: args{
 				pathLength: 10,
 				path:       "/qweasd1xcr",
-				addToStorage: map[string]string{
-					"qweasdzxcr": "https://aaa.bbb",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb"},
 				},
 			},
 			want: want{
@@ -73,22 +142,279 @@ func TestService_Redirect(t *testing.T) {
 				pathLength: 10,
 				path:       "/qweasd&*zxcrаб",
 				invalid:    true,
-				addToStorage: map[string]string{
-					"qweasdzxcr123": "https://aaa.bbb",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr123": {Orig: "https://aaa.bbb"},
 				},
 			},
 			want: want{
 				err: ErrInvalidPath,
 			},
 		},
+		{
+			name: "passthrough disabled, query is ignored",
+			args: args{
+				path:  "/qweasdzxcr",
+				query: "ref=tw",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb/x?a=1"},
+				},
+			},
+			want: want{
+				orig: "https://aaa.bbb/x?a=1",
+				code: http.StatusTemporaryRedirect,
+			},
+		},
+		{
+			name: "passthrough disabled, suffix is not found",
+			args: args{
+				path: "/qweasdzxcr/rest",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb/x"},
+				},
+			},
+			want: want{
+				err: model.ErrNotFound,
+			},
+		},
+		{
+			name: "passthrough query",
+			args: args{
+				path:  "/qweasdzxcr",
+				query: "ref=tw&a=2",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb/x?a=1", Passthrough: true},
+				},
+			},
+			want: want{
+				orig: "https://aaa.bbb/x?a=1&ref=tw",
+				code: http.StatusTemporaryRedirect,
+			},
+		},
+		{
+			name: "passthrough suffix and query",
+			args: args{
+				path:  "/qweasdzxcr/rest/of/path/",
+				query: "ref=tw",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb/base#top", Passthrough: true},
+				},
+			},
+			want: want{
+				orig: "https://aaa.bbb/base/rest/of/path/?ref=tw#top",
+				code: http.StatusTemporaryRedirect,
+			},
+		},
+		{
+			name: "passthrough invalid query",
+			args: args{
+				path:  "/qweasdzxcr",
+				query: "a=%zz",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb", Passthrough: true},
+				},
+			},
+			want: want{
+				err: ErrInvalidQuery,
+			},
+		},
+		{
+			name: "target matched by platform",
+			args: args{
+				path:      "/qweasdzxcr",
+				userAgent: testUAAndroid,
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb", Targets: testTargets},
+				},
+			},
+			want: want{
+				orig: "https://play.google.com/store/apps/details?id=bbb.aaa",
+				vary: []string{"User-Agent"},
+				code: http.StatusTemporaryRedirect,
+			},
+		},
+		{
+			name: "target matched by platform group",
+			args: args{
+				path:      "/qweasdzxcr",
+				userAgent: testUAiOS,
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb", Targets: testTargets},
+				},
+			},
+			want: want{
+				orig: "https://apps.apple.com/app/id123",
+				vary: []string{"User-Agent"},
+				code: http.StatusTemporaryRedirect,
+			},
+		},
+		{
+			name: "no target matched",
+			args: args{
+				path:      "/qweasdzxcr",
+				userAgent: testUADesktop,
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb", Targets: testTargets},
+				},
+			},
+			want: want{
+				orig: "https://aaa.bbb",
+				vary: []string{"User-Agent"},
+				code: http.StatusTemporaryRedirect,
+			},
+		},
+		{
+			name: "target matched by country",
+			args: args{
+				path:     "/qweasdzxcr",
+				clientIP: "2.2.2.2",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb", Targets: testCountryTargets},
+				},
+			},
+			want: want{
+				orig: "https://aaa.bbb/us",
+				vary: []string{"User-Agent"},
+				code: http.StatusTemporaryRedirect,
+			},
+		},
+		{
+			name: "target matched by country and platform",
+			args: args{
+				path:      "/qweasdzxcr",
+				clientIP:  "1.1.1.1",
+				userAgent: testUAiOS,
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb", Targets: testCountryTargets},
+				},
+			},
+			want: want{
+				orig: "https://apps.apple.com/de/app/id123",
+				vary: []string{"User-Agent"},
+				code: http.StatusTemporaryRedirect,
+			},
+		},
+		{
+			name: "unknown country",
+			args: args{
+				path:     "/qweasdzxcr",
+				clientIP: "3.3.3.3",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb", Targets: testCountryTargets},
+				},
+			},
+			want: want{
+				orig: "https://aaa.bbb",
+				vary: []string{"User-Agent"},
+				code: http.StatusTemporaryRedirect,
+			},
+		},
+		{
+			name: "matched target with passthrough",
+			args: args{
+				path:      "/qweasdzxcr",
+				query:     "ref=tw",
+				userAgent: testUAAndroid,
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb", Targets: testTargets, Passthrough: true},
+				},
+			},
+			want: want{
+				orig: "https://play.google.com/store/apps/details?id=bbb.aaa&ref=tw",
+				vary: []string{"User-Agent"},
+				code: http.StatusTemporaryRedirect,
+			},
+		},
+		{
+			name: "variant with visitor id",
+			args: args{
+				path:      "/qweasdzxcr",
+				visitorID: "abc",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb", Variants: []model.Variant{
+						{URL: "https://ccc.ddd", Weight: 10},
+					}},
+				},
+			},
+			want: want{
+				orig:      "https://ccc.ddd",
+				vary:      []string{"Cookie"},
+				visitorID: "abc",
+				code:      http.StatusTemporaryRedirect,
+			},
+		},
+		{
+			name: "variant with client ip",
+			args: args{
+				path:     "/qweasdzxcr",
+				clientIP: "1.2.3.4",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb", Variants: []model.Variant{
+						{URL: "https://ccc.ddd", Weight: 10},
+					}},
+				},
+			},
+			want: want{
+				orig:      "https://ccc.ddd",
+				vary:      []string{"Cookie"},
+				visitorID: visitorFromIP("1.2.3.4"),
+				code:      http.StatusTemporaryRedirect,
+			},
+		},
+		{
+			name: "matched target takes precedence over variants",
+			args: args{
+				path:      "/qweasdzxcr",
+				userAgent: testUAAndroid,
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr": {Orig: "https://aaa.bbb", Targets: testTargets, Variants: []model.Variant{
+						{URL: "https://ccc.ddd", Weight: 10},
+					}},
+				},
+			},
+			want: want{
+				orig: "https://play.google.com/store/apps/details?id=bbb.aaa",
+				vary: []string{"User-Agent"},
+				code: http.StatusTemporaryRedirect,
+			},
+		},
+		{
+			name: "suffix with dot segment",
+			args: args{
+				path:    "/qweasdzxcr/../../evil",
+				invalid: true,
+			},
+			want: want{
+				err: ErrInvalidPath,
+			},
+		},
+		{
+			name: "suffix with empty segment",
+			args: args{
+				path:    "/qweasdzxcr//evil.com",
+				invalid: true,
+			},
+			want: want{
+				err: ErrInvalidPath,
+			},
+		},
+		{
+			name: "suffix with backslash",
+			args: args{
+				path:    "/qweasdzxcr/\\\\evil.com",
+				invalid: true,
+			},
+			want: want{
+				err: ErrInvalidPath,
+			},
+		},
 		{
 			name: "invalid request path 2",
 			args: args{
 				pathLength: 10,
 				path:       "qweasdzxcr123",
 				invalid:    true,
-				addToStorage: map[string]string{
-					"qweasdzxcr123": "https://aaa.bbb",
+				addToStorage: map[string]model.URL{
+					"qweasdzxcr123": {Orig: "https://aaa.bbb"},
 				},
 			},
 			want: want{
@@ -103,26 +429,42 @@ func TestService_Redirect(t *testing.T) {
 
 			st := mockapp.NewStorage(t)
 
-			if v, ok := tt.args.addToStorage[tt.args.path[1:]]; !ok {
+			short, _, _ := strings.Cut(tt.args.path[1:], "/")
+			if v, ok := tt.args.addToStorage[short]; !ok {
 				if !tt.args.invalid {
-					st.EXPECT().Get(mock.Anything, tt.args.path[1:]).Return("", model.ErrNotFound)
+					st.EXPECT().Get(mock.Anything, short).Return(nil, model.ErrNotFound)
 				}
-			} else {
-				st.EXPECT().Get(mock.Anything, tt.args.path[1:]).Return(v, nil)
+			} else if !tt.args.invalid {
+				st.EXPECT().Get(mock.Anything, short).Return(&v, nil)
 			}
 
-			svc := &Service{
-				store: st,
-				log:   logger,
-			}
+			svc := New(&Config{
+				Store:       st,
+				Logger:      logger,
+				CacheMaxAge: time.Hour,
+				GeoIP: testGeoIP{
+					"1.1.1.1": "DE",
+					"2.2.2.2": "US",
+				},
+			})
 
-			orig, err := svc.Resolve(context.Background(), tt.args.path)
+			redirect, err := svc.Resolve(context.Background(), &Request{
+				Path:      tt.args.path,
+				Query:     tt.args.query,
+				UserAgent: tt.args.userAgent,
+				VisitorID: tt.args.visitorID,
+				ClientIP:  tt.args.clientIP,
+			})
 			if tt.want.err != nil {
-				assert.Empty(t, orig)
+				assert.Nil(t, redirect)
 				assert.ErrorIs(t, err, tt.want.err)
 			} else {
-				assert.NoError(t, err)
-				assert.Equal(t, tt.want.orig, orig)
+				require.NoError(t, err)
+				assert.Equal(t, tt.want.orig, redirect.URL)
+				assert.Equal(t, tt.want.code, redirect.Code)
+				assert.Equal(t, tt.want.vary, redirect.Vary)
+				assert.Equal(t, tt.want.visitorID, redirect.VisitorID)
+				assert.Equal(t, tt.want.maxAge, redirect.MaxAge)
 			}
 		})
 	}
@@ -0,0 +1,70 @@
+package resolver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"go.uber.org/zap"
+)
+
+// matchSchedule returns destination of first schedule rule active at specified time.
+func (svc *Service) matchSchedule(schedule *model.Schedule, now time.Time) (string, bool) {
+	loc := svc.timezone
+	if schedule.Timezone != "" {
+		var err error
+		if loc, err = svc.location(schedule.Timezone); err != nil {
+			svc.log.Warn("cannot load schedule timezone", zap.Error(err))
+			return "", false
+		}
+	}
+	for _, rule := range schedule.Rules {
+		active, err := ruleActive(&rule, now, loc)
+		if err != nil {
+			svc.log.Warn("cannot evaluate schedule rule", zap.Error(err))
+			continue
+		}
+		if active {
+			return rule.URL, true
+		}
+	}
+	return "", false
+}
+
+// location returns time zone by name, loaded locations are cached.
+func (svc *Service) location(name string) (*time.Location, error) {
+	if cached, ok := svc.locations.Load(name); ok {
+		if loc, okL := cached.(*time.Location); okL {
+			return loc, nil
+		}
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load location: %w", err)
+	}
+	svc.locations.Store(name, loc)
+	return loc, nil
+}
+
+// ruleActive checks if time is within rule window.
+func ruleActive(rule *model.ScheduleRule, now time.Time, loc *time.Location) (bool, error) {
+	if rule.From != "" {
+		from, err := model.ParseScheduleTime(rule.From, loc)
+		if err != nil {
+			return false, fmt.Errorf("cannot parse rule time: %w", err)
+		}
+		if now.Before(from) {
+			return false, nil
+		}
+	}
+	if rule.To != "" {
+		to, err := model.ParseScheduleTime(rule.To, loc)
+		if err != nil {
+			return false, fmt.Errorf("cannot parse rule time: %w", err)
+		}
+		if !now.Before(to) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,131 @@
+package resolver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/adwski/shorty/internal/app/mockapp"
+	"github.com/adwski/shorty/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestService_ResolveSchedule(t *testing.T) {
+	var (
+		berlin = &model.Schedule{
+			Timezone: "Europe/Berlin",
+			Rules: []model.ScheduleRule{
+				{To: "2024-05-01T10:00", URL: "https://aaa.bbb/teaser"},
+				{From: "2024-05-01T10:00", To: "2024-05-08T00:00:00", URL: "https://aaa.bbb/sale"},
+				{From: "2024-05-08T00:00:00+02:00", URL: "https://aaa.bbb/ended"},
+			},
+		}
+		serverTZ = &model.Schedule{
+			Rules: []model.ScheduleRule{
+				{From: "2024-05-01T10:00", To: "2024-05-08T00:00", URL: "https://aaa.bbb/sale"},
+			},
+		}
+		badTZ = &model.Schedule{
+			Timezone: "Mars/Olympus",
+			Rules: []model.ScheduleRule{
+				{From: "2024-05-01T10:00", URL: "https://aaa.bbb/sale"},
+			},
+		}
+	)
+	tests := []struct {
+		name     string
+		schedule *model.Schedule
+		variants []model.Variant
+		now      string
+		want     string
+	}{
+		{
+			name:     "before launch",
+			schedule: berlin,
+			now:      "2024-05-01T07:59:59Z",
+			want:     "https://aaa.bbb/teaser",
+		},
+		{
+			name:     "window start is inclusive",
+			schedule: berlin,
+			now:      "2024-05-01T08:00:00Z",
+			want:     "https://aaa.bbb/sale",
+		},
+		{
+			name:     "after end",
+			schedule: berlin,
+			now:      "2024-05-07T22:00:00Z",
+			want:     "https://aaa.bbb/ended",
+		},
+		{
+			name:     "server timezone",
+			schedule: serverTZ,
+			now:      "2024-05-01T06:30:00Z",
+			want:     "https://aaa.bbb/sale",
+		},
+		{
+			name:     "no active rule",
+			schedule: serverTZ,
+			now:      "2024-05-01T05:30:00Z",
+			want:     "https://aaa.bbb",
+		},
+		{
+			name:     "no active rule with variants",
+			schedule: serverTZ,
+			variants: []model.Variant{{URL: "https://ccc.ddd", Weight: 1}},
+			now:      "2024-05-09T00:00:00Z",
+			want:     "https://ccc.ddd",
+		},
+		{
+			name:     "active rule takes precedence over variants",
+			schedule: serverTZ,
+			variants: []model.Variant{{URL: "https://ccc.ddd", Weight: 1}},
+			now:      "2024-05-02T00:00:00Z",
+			want:     "https://aaa.bbb/sale",
+		},
+		{
+			name:     "unknown timezone",
+			schedule: badTZ,
+			now:      "2024-05-02T00:00:00Z",
+			want:     "https://aaa.bbb",
+		},
+	}
+	// server default is UTC+4
+	serverLoc := time.FixedZone("UTC+4", 4*60*60)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, err := zap.NewDevelopment()
+			require.NoError(t, err)
+
+			st := mockapp.NewStorage(t)
+			st.EXPECT().Get(mock.Anything, "qweasdzxcr").Return(&model.URL{
+				Orig:     "https://aaa.bbb",
+				Schedule: tt.schedule,
+				Variants: tt.variants,
+			}, nil)
+
+			now, err := time.Parse(time.RFC3339, tt.now)
+			require.NoError(t, err)
+
+			svc := New(&Config{
+				Store:    st,
+				Logger:   logger,
+				Timezone: serverLoc,
+			})
+			svc.now = func() time.Time { return now }
+
+			redirect, err := svc.Resolve(context.Background(), &Request{
+				Path:      "/qweasdzxcr",
+				VisitorID: "abc",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, redirect.URL)
+			assert.Equal(t, http.StatusTemporaryRedirect, redirect.Code)
+		})
+	}
+}
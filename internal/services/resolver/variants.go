@@ -0,0 +1,94 @@
+package resolver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"time"
+
+	"github.com/adwski/shorty/internal/generators"
+	"github.com/adwski/shorty/internal/model"
+	"go.uber.org/zap"
+)
+
+const (
+	flusherFillSize      = 1000
+	flusherAllocSize     = 2000
+	flusherFlushInterval = 5 * time.Second
+
+	visitorIDLength = 16
+)
+
+// resolveVariant chooses link variant for visitor and queues variant click.
+func (svc *Service) resolveVariant(short string, u *model.URL, req *Request, redirect *Redirect) {
+	visitorID := req.VisitorID
+	if visitorID == "" {
+		visitorID = visitorFromIP(req.ClientIP)
+	}
+	if visitorID == "" {
+		visitorID = generators.RandString(visitorIDLength)
+	}
+	variant := pickVariant(u.Variants, short, visitorID)
+	if variant < 0 {
+		// all weights are zero, this should not happen since weights
+		// are validated by shortener, use original URL as a fallback
+		return
+	}
+	redirect.URL = u.Variants[variant].URL
+	redirect.VisitorID = visitorID
+	if err := svc.flusher.Push(model.Click{
+		Short:   short,
+		Variant: variant,
+	}); err != nil {
+		// click counting should not affect redirects
+		svc.log.Warn("cannot queue variant click", zap.Error(err))
+	}
+}
+
+// pickVariant deterministically chooses variant index for visitor
+// according to variant weights.
+func pickVariant(variants []model.Variant, short, visitorID string) int {
+	var total uint64
+	for _, v := range variants {
+		if v.Weight > 0 {
+			total += uint64(v.Weight)
+		}
+	}
+	if total == 0 {
+		return -1
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(short))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(visitorID))
+	n := h.Sum64() % total
+	for i, v := range variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if n < uint64(v.Weight) {
+			return i
+		}
+		n -= uint64(v.Weight)
+	}
+	return -1
+}
+
+// visitorFromIP derives visitor identifier from client IP.
+// IP is hashed so it is not exposed in visitor cookie.
+func visitorFromIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(ip))
+	return hex.EncodeToString(sum[:visitorIDLength/2])
+}
+
+func (svc *Service) addClicks(ctx context.Context, clicks []model.Click) {
+	if err := svc.store.AddVariantClicks(ctx, clicks); err != nil {
+		svc.log.Error("storage error during clicks update", zap.Error(err))
+		return
+	}
+	svc.log.Debug("variant clicks updated", zap.Int("clicks", len(clicks)))
+}
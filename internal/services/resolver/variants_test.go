@@ -0,0 +1,79 @@
+package resolver
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickVariant(t *testing.T) {
+	type args struct {
+		variants []model.Variant
+	}
+	tests := []struct {
+		name string
+		args args
+		want []float64
+	}{
+		{
+			name: "single variant",
+			args: args{
+				variants: []model.Variant{
+					{URL: "https://aaa.bbb", Weight: 1},
+				},
+			},
+			want: []float64{1},
+		},
+		{
+			name: "weighted variants",
+			args: args{
+				variants: []model.Variant{
+					{URL: "https://aaa.bbb", Weight: 50},
+					{URL: "https://ccc.ddd", Weight: 30},
+					{URL: "https://eee.fff", Weight: 20},
+				},
+			},
+			want: []float64{0.5, 0.3, 0.2},
+		},
+		{
+			name: "zero weights",
+			args: args{
+				variants: []model.Variant{
+					{URL: "https://aaa.bbb"},
+				},
+			},
+		},
+	}
+	const (
+		visitors  = 20000
+		tolerance = 0.02
+	)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counters := make([]int, len(tt.args.variants))
+			for i := 0; i < visitors; i++ {
+				visitorID := strconv.Itoa(i)
+				variant := pickVariant(tt.args.variants, "qweasdzxcr", visitorID)
+				if tt.want == nil {
+					assert.Equal(t, -1, variant)
+					continue
+				}
+				// choice must be sticky
+				assert.Equal(t, variant, pickVariant(tt.args.variants, "qweasdzxcr", visitorID))
+				counters[variant]++
+			}
+			for i, share := range tt.want {
+				assert.InDelta(t, share, float64(counters[i])/visitors, tolerance)
+			}
+		})
+	}
+}
+
+func TestVisitorFromIP(t *testing.T) {
+	assert.Empty(t, visitorFromIP(""))
+	assert.Len(t, visitorFromIP("1.2.3.4"), visitorIDLength)
+	assert.Equal(t, visitorFromIP("1.2.3.4"), visitorFromIP("1.2.3.4"))
+	assert.NotEqual(t, visitorFromIP("1.2.3.4"), visitorFromIP("1.2.3.5"))
+}
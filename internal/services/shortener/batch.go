@@ -3,6 +3,8 @@ package shortener
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/adwski/shorty/internal/generators"
@@ -13,8 +15,19 @@ import (
 
 // BatchURL is single batch element in batch shorten request.
 type BatchURL struct {
-	ID  string `json:"correlation_id"`
-	URL string `json:"original_url"`
+	ID          string `json:"correlation_id"`
+	URL         string `json:"original_url"`
+	Redirect    int    `json:"redirect,omitempty"`
+	Passthrough bool   `json:"passthrough,omitempty"`
+
+	Targets  []model.Target  `json:"targets,omitempty"`
+	Variants []model.Variant `json:"variants,omitempty"`
+	Schedule *model.Schedule `json:"schedule,omitempty"`
+	Password string          `json:"password,omitempty"`
+	Title    string          `json:"title,omitempty"`
+	Tags     []string        `json:"tags,omitempty"`
+	Notes    string          `json:"notes,omitempty"`
+	Preview  bool            `json:"preview,omitempty"`
 }
 
 // BatchShortened is single batch element in batch shorten response.
@@ -24,15 +37,50 @@ type BatchShortened struct {
 }
 
 // ShortenBatch shortens batch of urls.
-func (svc *Service) ShortenBatch(ctx context.Context, u *user.User, batch []BatchURL) ([]BatchShortened, error) {
+// If workspace id is not empty, urls are created in workspace, user must be its editor.
+// Batch size is limited by user quota, as well as number of personal links.
+// Number of password protected urls in batch is always limited.
+func (svc *Service) ShortenBatch(
+	ctx context.Context,
+	u *user.User,
+	workspaceID string,
+	batch []BatchURL,
+) ([]BatchShortened, error) {
 	var (
 		err  error
 		urls = make([]model.URL, len(batch))
 	)
+	if err = checkBatchPasswords(batch); err != nil {
+		return nil, err
+	}
+	if err = svc.checkWorkspace(ctx, u, workspaceID, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	// quotas are checked before urls are prepared, since preparation includes password hashing
+	if err = svc.checkBatchQuotas(ctx, u.ID, workspaceID, len(batch)); err != nil {
+		return nil, err
+	}
 	for i := range batch {
+		var link *model.URL
+		if link, err = svc.prepareURL(&model.URL{
+			Orig:        batch[i].URL,
+			Redirect:    batch[i].Redirect,
+			Passthrough: batch[i].Passthrough,
+			Targets:     batch[i].Targets,
+			Variants:    batch[i].Variants,
+			Schedule:    batch[i].Schedule,
+			Password:    batch[i].Password,
+			Title:       batch[i].Title,
+			Tags:        batch[i].Tags,
+			Notes:       batch[i].Notes,
+			Preview:     batch[i].Preview,
+		}); err != nil {
+			return nil, err
+		}
+		urls[i] = *link
 		urls[i].Short = generators.RandString(svc.pathLength)
-		urls[i].Orig = batch[i].URL
 		urls[i].UserID = u.ID
+		urls[i].WorkspaceID = workspaceID
 	}
 	if err = svc.store.StoreBatch(ctx, urls); err != nil {
 		return nil, errors.Join(ErrStorageError, err)
@@ -48,14 +96,57 @@ func (svc *Service) ShortenBatch(ctx context.Context, u *user.User, batch []Batc
 	return result, nil
 }
 
-// GetAll retrieves all urls created by one user.
-func (svc *Service) GetAll(ctx context.Context, u *user.User) ([]*model.URL, error) {
+func checkBatchPasswords(batch []BatchURL) error {
+	var protected int
+	for i := range batch {
+		if batch[i].Password != "" {
+			protected++
+		}
+	}
+	if protected > maxBatchPasswords {
+		return errors.Join(ErrInvalidPassword,
+			fmt.Errorf("batch has more than %d password protected urls", maxBatchPasswords))
+	}
+	return nil
+}
+
+func (svc *Service) checkBatchQuotas(ctx context.Context, userID, workspaceID string, size int) error {
+	if svc.quotas == nil {
+		return nil
+	}
+	if err := svc.quotas.CheckBatch(ctx, userID, size); err != nil {
+		return err //nolint:wrapcheck // quota errors carry limits
+	}
+	if workspaceID == "" {
+		if err := svc.quotas.CheckURLs(ctx, userID, size); err != nil {
+			return err //nolint:wrapcheck // quota errors carry limits
+		}
+	}
+	return nil
+}
+
+// GetAll retrieves all personal urls created by one user.
+// If workspace id is not empty, all urls of workspace are retrieved instead, user must be its member.
+// If tag is not empty, only urls labeled with tag are returned.
+func (svc *Service) GetAll(ctx context.Context, u *user.User, workspaceID, tag string) ([]*model.URL, error) {
 	if u.IsNew() {
 		// Session was created during this request
 		// That means there is no valid cookie
 		return nil, ErrUnauthorized
 	}
-	urls, err := svc.store.ListUserURLs(ctx, u.ID)
+	var (
+		urls []*model.URL
+		err  error
+	)
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if workspaceID != "" {
+		if err = svc.checkRole(ctx, u, workspaceID, model.RoleViewer); err != nil {
+			return nil, err
+		}
+		urls, err = svc.store.ListWorkspaceURLs(ctx, workspaceID, tag)
+	} else {
+		urls, err = svc.store.ListUserURLs(ctx, u.ID, tag)
+	}
 	if err != nil {
 		return nil, errors.Join(ErrStorageError, err)
 	}
@@ -66,8 +157,9 @@ func (svc *Service) GetAll(ctx context.Context, u *user.User) ([]*model.URL, err
 }
 
 // DeleteBatch processes batch delete request.
+// If workspace id is not empty, URLs are deleted from workspace, user must be its editor.
 // URLs are pushed to flusher queue and deleted asynchronously.
-func (svc *Service) DeleteBatch(_ context.Context, u *user.User, shorts []string) error {
+func (svc *Service) DeleteBatch(ctx context.Context, u *user.User, workspaceID string, shorts []string) error {
 	if u.IsNew() {
 		// Session was created during this request
 		// That means there is no valid cookie
@@ -76,12 +168,16 @@ func (svc *Service) DeleteBatch(_ context.Context, u *user.User, shorts []string
 	if len(shorts) == 0 {
 		return ErrEmptyBatch
 	}
+	if err := svc.checkWorkspace(ctx, u, workspaceID, model.RoleEditor); err != nil {
+		return err
+	}
 	ts := time.Now().UnixMicro()
 	for _, short := range shorts {
 		if err := svc.flusher.Push(model.URL{
-			Short:  short,
-			UserID: u.ID,
-			TS:     ts,
+			Short:       short,
+			UserID:      u.ID,
+			WorkspaceID: workspaceID,
+			TS:          ts,
 		}); err != nil {
 			return errors.Join(ErrDelete, err)
 		}
@@ -2,8 +2,10 @@ package shortener
 
 import (
 	"context"
+	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -42,12 +44,14 @@ func TestService_ShortenBatch(t *testing.T) {
 				pathLen:     7,
 				batch: []BatchURL{
 					{
-						ID:  "123",
-						URL: "http://qwe.qwe",
+						ID:          "123",
+						URL:         "http://qwe.qwe",
+						Passthrough: true,
 					},
 					{
-						ID:  "456",
-						URL: "http://asd.asd",
+						ID:       "456",
+						URL:      "http://asd.asd",
+						Redirect: http.StatusFound,
 					},
 				},
 			},
@@ -66,8 +70,8 @@ func TestService_ShortenBatch(t *testing.T) {
 			// Prepare mock calls
 			st.EXPECT().StoreBatch(mock.Anything, mock.Anything).Once().Return(
 				func(ctx context.Context, urls []model.URL) error {
-					for _, u := range urls {
-						st.EXPECT().Get(mock.Anything, u.Short).Return(u.Orig, nil)
+					for i := range urls {
+						st.EXPECT().Get(mock.Anything, urls[i].Short).Return(&urls[i], nil)
 					}
 					return nil
 				})
@@ -84,7 +88,7 @@ func TestService_ShortenBatch(t *testing.T) {
 			usr, err := user.New()
 			require.NoError(t, err)
 
-			shortBatch, err := svc.ShortenBatch(ctx, usr, tt.args.batch)
+			shortBatch, err := svc.ShortenBatch(ctx, usr, "", tt.args.batch)
 
 			if tt.want.err != nil {
 				assert.Nil(t, shortBatch)
@@ -102,14 +106,38 @@ func TestService_ShortenBatch(t *testing.T) {
 				assert.Equal(t, tt.args.serveHost, u.Host)
 				assert.Equal(t, tt.args.serveScheme, u.Scheme)
 
-				orig, err := st.Get(ctx, u.Path[1:])
-				assert.NoError(t, err)
-				assert.Equal(t, tt.args.batch[i].URL, orig)
+				stored, err := st.Get(ctx, u.Path[1:])
+				require.NoError(t, err)
+				assert.Equal(t, tt.args.batch[i].URL, stored.Orig)
+				assert.Equal(t, tt.args.batch[i].Redirect, stored.Redirect)
+				assert.Equal(t, tt.args.batch[i].Passthrough, stored.Passthrough)
 			}
 		})
 	}
 }
 
+func TestService_ShortenBatchPasswords(t *testing.T) {
+	// storage must not be called, passwords are checked before hashing
+	svc := New(&Config{
+		Store:  mockapp.NewStorage(t),
+		Logger: zap.NewNop(),
+	})
+	usr, err := user.New()
+	require.NoError(t, err)
+
+	batch := make([]BatchURL, maxBatchPasswords+1)
+	for i := range batch {
+		batch[i] = BatchURL{
+			ID:       strconv.Itoa(i),
+			URL:      "http://qwe.qwe/" + strconv.Itoa(i),
+			Password: "secret",
+		}
+	}
+	shortBatch, err := svc.ShortenBatch(context.Background(), usr, "", batch)
+	assert.Nil(t, shortBatch)
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+}
+
 func TestService_DeleteURLs(t *testing.T) {
 	type args struct {
 		shorts  []string
@@ -193,7 +221,7 @@ func TestService_DeleteURLs(t *testing.T) {
 			}
 
 			// Execute
-			err = svc.DeleteBatch(ctx, u, tt.args.shorts)
+			err = svc.DeleteBatch(ctx, u, "", tt.args.shorts)
 			if tt.want.err != nil {
 				assert.ErrorIs(t, err, tt.want.err)
 				return
@@ -304,7 +332,7 @@ func TestService_GetURLs(t *testing.T) {
 			)
 			if len(tt.want.urls) > 0 {
 				// Prepare storage mock calls
-				st.EXPECT().ListUserURLs(ctx, tt.args.userID).Once().Return(tt.args.storageURLS, nil)
+				st.EXPECT().ListUserURLs(ctx, tt.args.userID, "").Once().Return(tt.args.storageURLS, nil)
 			}
 
 			// Prepare user
@@ -317,7 +345,7 @@ func TestService_GetURLs(t *testing.T) {
 			}
 
 			// Execute
-			urls, err := svc.GetAll(ctx, usr)
+			urls, err := svc.GetAll(ctx, usr, "", "")
 			if tt.want.err != nil {
 				assert.Nil(t, urls)
 				assert.ErrorIs(t, err, tt.want.err)
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/adwski/shorty/internal/buffer"
+	"github.com/adwski/shorty/internal/normalizer"
 	"go.uber.org/zap"
 )
 
@@ -17,6 +18,8 @@ const (
 type Config struct {
 	Store          Storage
 	Logger         *zap.Logger
+	Quotas         Quotas
+	Normalizer     *normalizer.Normalizer
 	ServedScheme   string
 	RedirectScheme string
 	Host           string
@@ -29,10 +32,12 @@ func New(cfg *Config) *Service {
 
 	svc := &Service{
 		store:          cfg.Store,
+		quotas:         cfg.Quotas,
 		servedScheme:   cfg.ServedScheme,
 		redirectScheme: cfg.RedirectScheme,
 		host:           cfg.Host,
 		pathLength:     cfg.PathLength,
+		normalizer:     cfg.Normalizer,
 		log:            logger,
 	}
 
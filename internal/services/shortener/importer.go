@@ -0,0 +1,397 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adwski/shorty/internal/generators"
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/services/quota"
+	"github.com/adwski/shorty/internal/user"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// importChunkSize is number of records stored with single StoreBatch call.
+	importChunkSize = 1000
+	// maxImportErrors limits number of per-line errors returned in import result,
+	// all failed records are still counted.
+	maxImportErrors = 1000
+
+	maxShortLength = 64
+)
+
+// Import errors.
+var (
+	ErrInvalidRecord = errors.New("invalid record")
+	ErrInvalidShort  = errors.New("invalid short path")
+	ErrInvalidOwner  = errors.New("invalid owner")
+	ErrShortExists   = errors.New("short path already exists")
+	ErrURLExists     = errors.New("url is already shortened")
+	ErrPasswordHash  = errors.New("password hash can be imported only with preserved owners")
+)
+
+// reservedShorts are paths of server routes that would shadow short URLs.
+var reservedShorts = []string{"api", "ping"}
+
+// LinkRecord is a single link in import and export streams.
+// ShortURL is only set in export and ignored during import.
+// PasswordHash keeps protected links protected when they're moved between servers,
+// it's imported only along with preserved owners.
+type LinkRecord struct {
+	Short        string          `json:"short,omitempty"`
+	ShortURL     string          `json:"short_url,omitempty"`
+	URL          string          `json:"original_url"`
+	Owner        string          `json:"owner,omitempty"`
+	Title        string          `json:"title,omitempty"`
+	Tags         []string        `json:"tags,omitempty"`
+	Notes        string          `json:"notes,omitempty"`
+	Redirect     int             `json:"redirect,omitempty"`
+	Passthrough  bool            `json:"passthrough,omitempty"`
+	Preview      bool            `json:"preview,omitempty"`
+	Targets      []model.Target  `json:"targets,omitempty"`
+	Variants     []model.Variant `json:"variants,omitempty"`
+	Schedule     *model.Schedule `json:"schedule,omitempty"`
+	PasswordHash string          `json:"password_hash,omitempty"`
+	Created      *time.Time      `json:"created,omitempty"`
+}
+
+// RecordReader is a source of import records.
+type RecordReader interface {
+	// Read returns next record and its line number in stream, io.EOF is returned at the end of stream.
+	// Errors wrapping ErrInvalidRecord affect only current record and reading can be continued.
+	Read() (rec *LinkRecord, line int, err error)
+}
+
+// RecordWriter is a destination of export records.
+type RecordWriter interface {
+	Write(rec *LinkRecord) error
+}
+
+// ImportOptions controls how imported records are stored.
+type ImportOptions struct {
+	// PreserveShort keeps short paths of imported records instead of generating new ones.
+	PreserveShort bool
+	// PreserveOwner keeps owners of imported records, otherwise all records
+	// are owned by importing user. It should be allowed only for trusted clients.
+	PreserveOwner bool
+}
+
+// ImportResult is a summary of import.
+type ImportResult struct {
+	Imported int           `json:"imported"`
+	Failed   int           `json:"failed"`
+	Errors   []ImportError `json:"errors,omitempty"`
+	// Error is set if import was aborted before the end of stream.
+	Error string `json:"error,omitempty"`
+}
+
+// ImportError is an error of a single import record.
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+func (res *ImportResult) addError(line int, err error) {
+	res.Failed++
+	if len(res.Errors) < maxImportErrors {
+		res.Errors = append(res.Errors, ImportError{Line: line, Error: err.Error()})
+	}
+}
+
+// Import reads link records from r and stores them in chunks.
+// Invalid records are reported in result with their line numbers and do not stop import.
+// Records exceeding link quota of their owner are reported the same way.
+// If chunk cannot be stored as a whole, its records are stored one by one,
+// so only conflicting records fail. Returned result is never nil,
+// it holds records processed before error if import is aborted.
+func (svc *Service) Import(
+	ctx context.Context,
+	u *user.User,
+	r RecordReader,
+	opts ImportOptions,
+) (*ImportResult, error) {
+	result := &ImportResult{}
+	if u.IsNew() {
+		// Session was created during this request
+		// That means there is no valid cookie
+		return result, ErrUnauthorized
+	}
+	var (
+		chunk  = make([]model.URL, 0, importChunkSize)
+		lines  = make([]int, 0, importChunkSize)
+		shorts = make(map[string]struct{}, importChunkSize)
+	)
+	for {
+		rec, line, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if !errors.Is(err, ErrInvalidRecord) {
+				return result, fmt.Errorf("cannot read record: %w", err)
+			}
+			result.addError(line, err)
+			continue
+		}
+		link, err := svc.prepareRecord(u, rec, opts)
+		if err != nil {
+			result.addError(line, err)
+			continue
+		}
+		if _, ok := shorts[link.Short]; ok {
+			result.addError(line, ErrShortExists)
+			continue
+		}
+		shorts[link.Short] = struct{}{}
+		chunk = append(chunk, *link)
+		lines = append(lines, line)
+		if len(chunk) < importChunkSize {
+			continue
+		}
+		if err = svc.storeChunk(ctx, chunk, lines, opts, result); err != nil {
+			return result, err
+		}
+		chunk, lines = chunk[:0], lines[:0]
+		clear(shorts)
+	}
+	if len(chunk) > 0 {
+		if err := svc.storeChunk(ctx, chunk, lines, opts, result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// prepareRecord validates import record and converts it to URL.
+func (svc *Service) prepareRecord(u *user.User, rec *LinkRecord, opts ImportOptions) (*model.URL, error) {
+	if rec.PasswordHash != "" {
+		if !opts.PreserveOwner {
+			return nil, ErrPasswordHash
+		}
+		if _, err := bcrypt.Cost([]byte(rec.PasswordHash)); err != nil {
+			return nil, errors.Join(ErrInvalidPassword, err)
+		}
+	}
+	link, err := svc.prepareURL(&model.URL{
+		Orig:        rec.URL,
+		Redirect:    rec.Redirect,
+		Passthrough: rec.Passthrough,
+		Title:       rec.Title,
+		Tags:        rec.Tags,
+		Notes:       rec.Notes,
+		Preview:     rec.Preview,
+		Targets:     rec.Targets,
+		Variants:    rec.Variants,
+		Schedule:    rec.Schedule,
+	})
+	if err != nil {
+		return nil, err
+	}
+	link.PasswordHash = rec.PasswordHash
+	if rec.Created != nil {
+		link.Created = *rec.Created
+	}
+	if link.UserID, err = recordOwner(u, rec.Owner, opts.PreserveOwner); err != nil {
+		return nil, errors.Join(ErrInvalidOwner, err)
+	}
+	if !opts.PreserveShort {
+		link.Short = generators.RandString(svc.pathLength)
+		return link, nil
+	}
+	if err = validateShort(rec.Short); err != nil {
+		return nil, errors.Join(ErrInvalidShort, err)
+	}
+	link.Short = rec.Short
+	return link, nil
+}
+
+// recordOwner returns owner of imported record. Records without owner belong to importing user.
+func recordOwner(u *user.User, owner string, preserve bool) (string, error) {
+	if owner == "" || owner == u.ID {
+		return u.ID, nil
+	}
+	if !preserve {
+		return "", errors.New("owner differs from current user")
+	}
+	if _, err := user.NewFromUserID(owner); err != nil {
+		return "", err
+	}
+	return owner, nil
+}
+
+// validateShort checks that short path can be served by resolver
+// and is not shadowed by server routes.
+func validateShort(short string) error {
+	if short == "" {
+		return errors.New("short path is empty")
+	}
+	if len(short) > maxShortLength {
+		return fmt.Errorf("short path is longer than %d characters", maxShortLength)
+	}
+	for i := 0; i < len(short); i++ {
+		c := short[i]
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return fmt.Errorf("invalid character in short path: 0x%x", c)
+		}
+	}
+	if slices.Contains(reservedShorts, strings.ToLower(short)) {
+		return fmt.Errorf("short path is reserved: %q", short)
+	}
+	return nil
+}
+
+// storeChunk stores chunk of imported URLs. If batch fails,
+// URLs are stored one by one to find out which of them are failing.
+func (svc *Service) storeChunk(
+	ctx context.Context,
+	chunk []model.URL,
+	lines []int,
+	opts ImportOptions,
+	result *ImportResult,
+) error {
+	chunk, lines, err := svc.applyImportQuotas(ctx, chunk, lines, result)
+	if err != nil {
+		return err
+	}
+	if len(chunk) == 0 {
+		return nil
+	}
+	err = svc.store.StoreBatch(ctx, chunk)
+	if err == nil {
+		result.Imported += len(chunk)
+		return nil
+	}
+	if ctx.Err() != nil {
+		return errors.Join(ErrStorageError, err)
+	}
+	svc.log.Debug("cannot store import chunk, storing urls one by one", zap.Error(err))
+	for i := range chunk {
+		if err = svc.storeRecord(ctx, &chunk[i], !opts.PreserveShort); err != nil {
+			if ctx.Err() != nil {
+				return errors.Join(ErrStorageError, err)
+			}
+			result.addError(lines[i], err)
+			continue
+		}
+		result.Imported++
+	}
+	return nil
+}
+
+// applyImportQuotas removes records exceeding link quotas of their owners from chunk,
+// removed records are reported in result. Records are removed from the end of chunk,
+// so owner gets as many records as quota allows.
+func (svc *Service) applyImportQuotas(
+	ctx context.Context,
+	chunk []model.URL,
+	lines []int,
+	result *ImportResult,
+) ([]model.URL, []int, error) {
+	if svc.quotas == nil {
+		return chunk, lines, nil
+	}
+	counts := make(map[string]int)
+	for i := range chunk {
+		counts[chunk[i].UserID]++
+	}
+	var (
+		allowed = make(map[string]int, len(counts))
+		errs    = make(map[string]error)
+	)
+	for owner, count := range counts {
+		err := svc.quotas.CheckURLs(ctx, owner, count)
+		var exceeded *quota.ExceededError
+		switch {
+		case err == nil:
+			allowed[owner] = count
+		case errors.As(err, &exceeded):
+			allowed[owner] = exceeded.Remaining
+			errs[owner] = err
+		default:
+			return nil, nil, err //nolint:wrapcheck // quota errors are returned as is
+		}
+	}
+	n := 0
+	for i := range chunk {
+		owner := chunk[i].UserID
+		if allowed[owner] == 0 {
+			result.addError(lines[i], errs[owner])
+			continue
+		}
+		allowed[owner]--
+		chunk[n], lines[n] = chunk[i], lines[i]
+		n++
+	}
+	return chunk[:n], lines[:n], nil
+}
+
+// storeRecord stores single imported URL. Generated short paths are regenerated on collision.
+func (svc *Service) storeRecord(ctx context.Context, link *model.URL, generated bool) error {
+	shortPath, err := svc.store.Store(ctx, link, false)
+	for i := 1; generated && i < defaultStoreRetries && errors.Is(err, model.ErrAlreadyExists); i++ {
+		link.Short = generators.RandString(svc.pathLength)
+		shortPath, err = svc.store.Store(ctx, link, false)
+	}
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, model.ErrAlreadyExists):
+		return ErrShortExists
+	case errors.Is(err, model.ErrConflict):
+		return errors.Join(ErrURLExists, fmt.Errorf("existing short path: %s", shortPath))
+	default:
+		return errors.Join(ErrStorageError, err)
+	}
+}
+
+// Export writes all urls of user to w and returns number of written records.
+func (svc *Service) Export(ctx context.Context, u *user.User, w RecordWriter) (int, error) {
+	if u.IsNew() {
+		// Session was created during this request
+		// That means there is no valid cookie
+		return 0, ErrUnauthorized
+	}
+	urls, err := svc.store.ListUserURLs(ctx, u.ID, "")
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return 0, nil
+		}
+		return 0, errors.Join(ErrStorageError, err)
+	}
+	slices.SortFunc(urls, func(a, b *model.URL) int {
+		return strings.Compare(a.Short, b.Short)
+	})
+	for i, url := range urls {
+		rec := &LinkRecord{
+			Short:        url.Short,
+			ShortURL:     svc.getServedURL(url.Short),
+			URL:          url.Orig,
+			Owner:        u.ID,
+			Title:        url.Title,
+			Tags:         url.Tags,
+			Notes:        url.Notes,
+			Redirect:     url.Redirect,
+			Passthrough:  url.Passthrough,
+			Preview:      url.Preview,
+			Targets:      url.Targets,
+			Variants:     url.Variants,
+			Schedule:     url.Schedule,
+			PasswordHash: url.PasswordHash,
+		}
+		if !url.Created.IsZero() {
+			created := url.Created.UTC().Truncate(time.Second)
+			rec.Created = &created
+		}
+		if err = w.Write(rec); err != nil {
+			return i, fmt.Errorf("cannot write record: %w", err)
+		}
+	}
+	return len(urls), nil
+}
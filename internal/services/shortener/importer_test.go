@@ -0,0 +1,305 @@
+package shortener
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adwski/shorty/internal/app/mockapp"
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/storage/memory"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestService_Import(t *testing.T) {
+	owner, err := user.New()
+	require.NoError(t, err)
+
+	type want struct {
+		batch    []string
+		stored   []string
+		imported int
+		errLines []int
+	}
+	tests := []struct {
+		name      string
+		format    string
+		body      string
+		opts      ImportOptions
+		batchErr  error
+		storeErrs map[string]error
+		want      want
+	}{
+		{
+			name:   "ndjson with generated shorts",
+			format: FormatNDJSON,
+			body: `{"short":"keep","original_url":"https://aaa.bbb/1","tags":["A"]}` + "\n" +
+				`{"original_url":"https://aaa.bbb/2"}` + "\n" +
+				"\n" +
+				`{"original_url":"://"}` + "\n" +
+				`{"original_url":` + "\n" +
+				`{"original_url":"https://aaa.bbb/3","owner":"` + owner.ID + `"}` + "\n",
+			want: want{
+				batch:    []string{"https://aaa.bbb/1", "https://aaa.bbb/2"},
+				imported: 2,
+				errLines: []int{4, 5, 6},
+			},
+		},
+		{
+			name:   "csv with preserved shorts and owners",
+			format: FormatCSV,
+			body: "original_url,short,owner,tags,redirect\n" +
+				"https://aaa.bbb/1,qwe,,\"a,b\",301\n" +
+				"https://aaa.bbb/2,asd," + owner.ID + ",,\n" +
+				"https://aaa.bbb/3,a-b,,,\n" +
+				"https://aaa.bbb/4,api,,,\n" +
+				"https://aaa.bbb/5,zxc,,,abc\n" +
+				"https://aaa.bbb/6,qwe,,,\n" +
+				"https://aaa.bbb/7,rty,notauser,,\n",
+			opts: ImportOptions{PreserveShort: true, PreserveOwner: true},
+			want: want{
+				batch:    []string{"qwe", "asd"},
+				imported: 2,
+				errLines: []int{4, 5, 6, 7, 8},
+			},
+		},
+		{
+			name:   "failed batch is stored one by one",
+			format: FormatNDJSON,
+			body: `{"short":"qwe","original_url":"https://aaa.bbb/1"}` + "\n" +
+				`{"short":"asd","original_url":"https://aaa.bbb/2"}`,
+			opts:     ImportOptions{PreserveShort: true},
+			batchErr: model.ErrAlreadyExists,
+			storeErrs: map[string]error{
+				"qwe": nil,
+				"asd": model.ErrAlreadyExists,
+			},
+			want: want{
+				batch:    []string{"qwe", "asd"},
+				stored:   []string{"qwe", "asd"},
+				imported: 1,
+				errLines: []int{2},
+			},
+		},
+		{
+			name:   "csv without url column",
+			format: FormatCSV,
+			body:   "short,url\nqwe,https://aaa.bbb\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, err := zap.NewDevelopment()
+			require.NoError(t, err)
+
+			usr := user.NewWithID("testuser")
+			st := mockapp.NewStorage(t)
+			if tt.want.batch != nil {
+				st.EXPECT().StoreBatch(mock.Anything, mock.Anything).RunAndReturn(
+					func(_ context.Context, urls []model.URL) error {
+						got := make([]string, 0, len(urls))
+						for _, u := range urls {
+							if tt.opts.PreserveShort {
+								got = append(got, u.Short)
+							} else {
+								assert.Len(t, u.Short, 10)
+								got = append(got, u.Orig)
+							}
+						}
+						assert.Equal(t, tt.want.batch, got)
+						return tt.batchErr
+					}).Once()
+			}
+			for _, short := range tt.want.stored {
+				short := short
+				st.EXPECT().Store(mock.Anything, mock.MatchedBy(func(u *model.URL) bool {
+					return u.Short == short
+				}), false).Return("", tt.storeErrs[short]).Once()
+			}
+			svc := &Service{
+				store:      st,
+				log:        logger,
+				pathLength: 10,
+			}
+
+			dec, err := NewDecoder(strings.NewReader(tt.body), tt.format)
+			require.NoError(t, err)
+			result, err := svc.Import(context.Background(), usr, dec, tt.opts)
+			if tt.want.batch == nil {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.imported, result.Imported)
+			assert.Equal(t, len(tt.want.errLines), result.Failed)
+			lines := make([]int, 0, len(result.Errors))
+			for _, e := range result.Errors {
+				lines = append(lines, e.Line)
+			}
+			assert.Equal(t, tt.want.errLines, lines)
+		})
+	}
+}
+
+func TestService_ImportDeletedShort(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	_, err := store.Store(ctx, &model.URL{Short: "victim1", Orig: "https://aaa.bbb/1", UserID: "owner"}, false)
+	require.NoError(t, err)
+	_, err = store.DeleteUserURLs(ctx, []model.URL{{Short: "victim1", UserID: "owner"}})
+	require.NoError(t, err)
+
+	svc := New(&Config{Store: store, Logger: zap.NewNop(), ServedScheme: "http", Host: "aaa", PathLength: 7})
+	dec, err := NewDecoder(strings.NewReader(`{"short":"victim1","original_url":"https://evil.ccc"}`+"\n"), FormatNDJSON)
+	require.NoError(t, err)
+	result, err := svc.Import(ctx, user.NewWithID("importer"), dec, ImportOptions{PreserveShort: true})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Imported)
+	require.Len(t, result.Errors, 1)
+
+	_, err = store.Get(ctx, "victim1")
+	assert.ErrorIs(t, err, model.ErrDeleted)
+}
+
+func TestService_ImportUnauthorized(t *testing.T) {
+	usr, err := user.New()
+	require.NoError(t, err)
+
+	svc := &Service{store: mockapp.NewStorage(t), log: zap.NewNop()}
+	result, err := svc.Import(context.Background(), usr, nil, ImportOptions{})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	assert.NotNil(t, result)
+
+	_, err = svc.Export(context.Background(), usr, nil)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestService_Export(t *testing.T) {
+	created := time.Date(2024, 3, 1, 10, 20, 30, 0, time.UTC)
+	urls := []*model.URL{
+		{Short: "zxc", Orig: "https://aaa.bbb/2", Redirect: 301, Passthrough: true},
+		{Short: "qwe", Orig: "https://aaa.bbb/1", Title: "One", Tags: []string{"a", "b"}, Created: created},
+	}
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{
+			name:   "csv",
+			format: FormatCSV,
+			want: "short,short_url,original_url,owner,title,tags,notes,redirect,passthrough,preview," +
+				"targets,variants,schedule,password_hash,created\n" +
+				"qwe,http://aaa.bbb/qwe,https://aaa.bbb/1,testuser,One,\"a,b\",,,false,false,,,,,2024-03-01T10:20:30Z\n" +
+				"zxc,http://aaa.bbb/zxc,https://aaa.bbb/2,testuser,,,,301,true,false,,,,,\n",
+		},
+		{
+			name:   "ndjson",
+			format: FormatNDJSON,
+			want: `{"short":"qwe","short_url":"http://aaa.bbb/qwe","original_url":"https://aaa.bbb/1",` +
+				`"owner":"testuser","title":"One","tags":["a","b"],"created":"2024-03-01T10:20:30Z"}` + "\n" +
+				`{"short":"zxc","short_url":"http://aaa.bbb/zxc","original_url":"https://aaa.bbb/2",` +
+				`"owner":"testuser","redirect":301,"passthrough":true}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := mockapp.NewStorage(t)
+			st.EXPECT().ListUserURLs(mock.Anything, "testuser", "").Return(urls, nil)
+			svc := &Service{
+				store:        st,
+				log:          zap.NewNop(),
+				servedScheme: "http",
+				host:         "aaa.bbb",
+			}
+
+			var buf bytes.Buffer
+			enc, err := NewEncoder(&buf, tt.format)
+			require.NoError(t, err)
+			n, err := svc.Export(context.Background(), user.NewWithID("testuser"), enc)
+			require.NoError(t, err)
+			require.NoError(t, enc.Flush())
+			assert.Equal(t, 2, n)
+			assert.Equal(t, tt.want, buf.String())
+
+			// exported stream can be imported back
+			dec, err := NewDecoder(&buf, tt.format)
+			require.NoError(t, err)
+			for _, short := range []string{"qwe", "zxc"} {
+				rec, _, errR := dec.Read()
+				require.NoError(t, errR)
+				assert.Equal(t, short, rec.Short)
+			}
+			_, _, err = dec.Read()
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestService_ExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	newService := func() *Service {
+		return New(&Config{Store: memory.New(), Logger: zap.NewNop(), ServedScheme: "http", Host: "aaa", PathLength: 7})
+	}
+	owner := user.NewWithID("owner")
+	src := newService()
+	_, err := src.Shorten(ctx, owner, &model.URL{
+		Orig:     "https://aaa.bbb/1",
+		Redirect: 308,
+		Targets:  []model.Target{{Platform: "ios", URL: "https://ios.bbb"}},
+		Variants: []model.Variant{{URL: "https://v1.bbb", Weight: 1}, {URL: "https://v2.bbb", Weight: 2}},
+		Schedule: &model.Schedule{Rules: []model.ScheduleRule{{From: "2024-01-01T00:00", URL: "https://s.bbb"}}},
+	})
+	require.NoError(t, err)
+	_, err = src.Shorten(ctx, owner, &model.URL{Orig: "https://aaa.bbb/2", Password: "secret"})
+	require.NoError(t, err)
+	exported, err := src.store.ListUserURLs(ctx, owner.ID, "")
+	require.NoError(t, err)
+
+	for _, format := range []string{FormatCSV, FormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, errE := NewEncoder(&buf, format)
+			require.NoError(t, errE)
+			_, err = src.Export(ctx, owner, enc)
+			require.NoError(t, err)
+			require.NoError(t, enc.Flush())
+			data := buf.String()
+
+			// password hash is not accepted from untrusted import
+			dst := newService()
+			dec, errD := NewDecoder(strings.NewReader(data), format)
+			require.NoError(t, errD)
+			result, errI := dst.Import(ctx, owner, dec, ImportOptions{PreserveShort: true})
+			require.NoError(t, errI)
+			assert.Equal(t, 1, result.Imported)
+			require.Len(t, result.Errors, 1)
+			assert.Contains(t, result.Errors[0].Error, ErrPasswordHash.Error())
+
+			dst = newService()
+			dec, errD = NewDecoder(strings.NewReader(data), format)
+			require.NoError(t, errD)
+			result, errI = dst.Import(ctx, owner, dec, ImportOptions{PreserveShort: true, PreserveOwner: true})
+			require.NoError(t, errI)
+			assert.Equal(t, 2, result.Imported)
+
+			for _, want := range exported {
+				got, errG := dst.store.Get(ctx, want.Short)
+				require.NoError(t, errG)
+				assert.Equal(t, want.Redirect, got.Redirect)
+				assert.Equal(t, want.Targets, got.Targets)
+				assert.Equal(t, want.Variants, got.Variants)
+				assert.Equal(t, want.Schedule, got.Schedule)
+				assert.Equal(t, want.PasswordHash, got.PasswordHash)
+				assert.Equal(t, want.Created.Truncate(time.Second).Unix(), got.Created.Unix())
+			}
+		})
+	}
+}
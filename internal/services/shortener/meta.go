@@ -0,0 +1,49 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+)
+
+// UpdateMeta updates title, tags and notes of URL, fields that are not set
+// in update are left unchanged. Metadata can be updated only by URL owner or workspace editors.
+// Updated URL is returned.
+func (svc *Service) UpdateMeta(
+	ctx context.Context,
+	u *user.User,
+	short string,
+	update *model.MetaUpdate,
+) (*model.URL, error) {
+	if u.IsNew() {
+		// Session was created during this request
+		// That means there is no valid cookie
+		return nil, ErrUnauthorized
+	}
+	url, err := svc.store.Get(ctx, short)
+	if err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	if err = svc.checkAccess(ctx, u, url, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	if update.Title != nil {
+		url.Title = *update.Title
+	}
+	if update.Tags != nil {
+		url.Tags = *update.Tags
+	}
+	if update.Notes != nil {
+		url.Notes = *update.Notes
+	}
+	if err = prepareMeta(url); err != nil {
+		return nil, err
+	}
+	if err = svc.store.UpdateMeta(ctx, url); err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	url.Short = svc.getServedURL(url.Short)
+	return url, nil
+}
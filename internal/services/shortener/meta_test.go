@@ -0,0 +1,160 @@
+package shortener
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/adwski/shorty/internal/app/mockapp"
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestService_UpdateMeta(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+	tagsPtr := func(tags ...string) *[]string { return &tags }
+
+	type args struct {
+		stored   *model.URL
+		storeErr error
+		update   *model.MetaUpdate
+		userID   string
+		newUser  bool
+	}
+	type want struct {
+		err     error
+		updated *model.URL
+	}
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "update title only",
+			args: args{
+				stored: &model.URL{Short: "qwe", UserID: "testuser", Title: "Old", Tags: []string{"a"}, Notes: "n"},
+				update: &model.MetaUpdate{Title: strPtr(" New ")},
+				userID: "testuser",
+			},
+			want: want{
+				updated: &model.URL{Short: "qwe", UserID: "testuser", Title: "New", Tags: []string{"a"}, Notes: "n"},
+			},
+		},
+		{
+			name: "replace tags and notes",
+			args: args{
+				stored: &model.URL{Short: "qwe", UserID: "testuser", Tags: []string{"a"}},
+				update: &model.MetaUpdate{
+					Tags:  tagsPtr("Promo", " spring ", "promo"),
+					Notes: strPtr("line 1\nline 2"),
+				},
+				userID: "testuser",
+			},
+			want: want{
+				updated: &model.URL{
+					Short:  "qwe",
+					UserID: "testuser",
+					Tags:   []string{"promo", "spring"},
+					Notes:  "line 1\nline 2",
+				},
+			},
+		},
+		{
+			name: "clear tags",
+			args: args{
+				stored: &model.URL{Short: "qwe", UserID: "testuser", Tags: []string{"a"}},
+				update: &model.MetaUpdate{Tags: tagsPtr()},
+				userID: "testuser",
+			},
+			want: want{
+				updated: &model.URL{Short: "qwe", UserID: "testuser"},
+			},
+		},
+		{
+			name: "invalid tag",
+			args: args{
+				stored: &model.URL{Short: "qwe", UserID: "testuser"},
+				update: &model.MetaUpdate{Tags: tagsPtr("a,b")},
+				userID: "testuser",
+			},
+			want: want{err: ErrInvalidTags},
+		},
+		{
+			name: "too long notes",
+			args: args{
+				stored: &model.URL{Short: "qwe", UserID: "testuser"},
+				update: &model.MetaUpdate{Notes: strPtr(strings.Repeat("a", 2001))},
+				userID: "testuser",
+			},
+			want: want{err: ErrInvalidNotes},
+		},
+		{
+			name: "other user url",
+			args: args{
+				stored: &model.URL{Short: "qwe", UserID: "otheruser"},
+				update: &model.MetaUpdate{Title: strPtr("New")},
+				userID: "testuser",
+			},
+			want: want{err: model.ErrNotFound},
+		},
+		{
+			name: "deleted url",
+			args: args{
+				storeErr: model.ErrDeleted,
+				update:   &model.MetaUpdate{Title: strPtr("New")},
+				userID:   "testuser",
+			},
+			want: want{err: model.ErrDeleted},
+		},
+		{
+			name: "new user",
+			args: args{
+				update:  &model.MetaUpdate{Title: strPtr("New")},
+				newUser: true,
+			},
+			want: want{err: ErrUnauthorized},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, err := zap.NewDevelopment()
+			require.NoError(t, err)
+
+			st := mockapp.NewStorage(t)
+			if !tt.args.newUser {
+				st.EXPECT().Get(mock.Anything, "qwe").Return(tt.args.stored, tt.args.storeErr)
+			}
+			if tt.want.updated != nil {
+				st.EXPECT().UpdateMeta(mock.Anything, tt.want.updated).Return(nil)
+			}
+			svc := &Service{
+				store:        st,
+				log:          logger,
+				servedScheme: "http",
+				host:         "aaa.bbb",
+			}
+
+			usr := &user.User{ID: tt.args.userID}
+			if tt.args.newUser {
+				usr, err = user.New()
+				require.NoError(t, err)
+			}
+			url, err := svc.UpdateMeta(context.Background(), usr, "qwe", tt.args.update)
+			if tt.want.err != nil {
+				assert.ErrorIs(t, err, tt.want.err)
+				assert.Nil(t, url)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "http://aaa.bbb/qwe", url.Short)
+			assert.Equal(t, tt.want.updated.Title, url.Title)
+			assert.Equal(t, tt.want.updated.Tags, url.Tags)
+			assert.Equal(t, tt.want.updated.Notes, url.Notes)
+		})
+	}
+}
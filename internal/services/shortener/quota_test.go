@@ -0,0 +1,103 @@
+package shortener
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/services/quota"
+	"github.com/adwski/shorty/internal/storage/memory"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestService_Quotas(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	svc := New(&Config{
+		Store:  store,
+		Logger: zap.NewNop(),
+		Quotas: quota.New(&quota.Config{
+			Storage:     store,
+			Logger:      zap.NewNop(),
+			DefaultTier: "free",
+			Tiers:       []model.Tier{{Name: "free", MaxURLs: 3, MaxBatch: 2}},
+		}),
+		ServedScheme: "http",
+		Host:         "aaa",
+		PathLength:   7,
+	})
+	u := user.NewWithID("user")
+	require.NoError(t, store.CreateWorkspace(ctx, &model.Workspace{ID: "ws", Name: "Marketing"}, u.ID))
+
+	_, err := svc.Shorten(ctx, u, &model.URL{Orig: "https://bbb.ccc/1"})
+	require.NoError(t, err)
+	_, err = svc.ShortenBatch(ctx, u, "", []BatchURL{
+		{ID: "1", URL: "https://bbb.ccc/2"}, {ID: "2", URL: "https://bbb.ccc/3"},
+	})
+	require.NoError(t, err)
+
+	// batch size is limited for all batches
+	_, err = svc.ShortenBatch(ctx, u, "ws", []BatchURL{
+		{ID: "1", URL: "https://bbb.ccc/4"}, {ID: "2", URL: "https://bbb.ccc/5"}, {ID: "3", URL: "https://bbb.ccc/6"},
+	})
+	assert.Equal(t, &quota.ExceededError{Quota: quota.QuotaBatch, Limit: 2, Requested: 3, Remaining: 2}, err)
+
+	// quotas are checked before urls are validated
+	_, err = svc.ShortenBatch(ctx, u, "ws", []BatchURL{
+		{ID: "1", URL: "ftp://"}, {ID: "2", URL: "ftp://"}, {ID: "3", URL: "ftp://", Password: "secret"},
+	})
+	assert.ErrorIs(t, err, quota.ErrQuotaExceeded)
+
+	// number of links is limited only for personal links
+	_, err = svc.Shorten(ctx, u, &model.URL{Orig: "https://bbb.ccc/4"})
+	assert.Equal(t, &quota.ExceededError{Quota: quota.QuotaURLs, Limit: 3, Requested: 1, Remaining: 0}, err)
+	_, err = svc.ShortenBatch(ctx, u, "", []BatchURL{{ID: "1", URL: "https://bbb.ccc/4"}})
+	assert.ErrorIs(t, err, quota.ErrQuotaExceeded)
+	_, err = svc.Shorten(ctx, u, &model.URL{Orig: "https://bbb.ccc/4", WorkspaceID: "ws"})
+	require.NoError(t, err)
+	urls, err := svc.GetAll(ctx, u, "", "")
+	require.NoError(t, err)
+	assert.Len(t, urls, 3)
+}
+
+func TestService_ImportQuotas(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	svc := New(&Config{
+		Store:  store,
+		Logger: zap.NewNop(),
+		Quotas: quota.New(&quota.Config{
+			Storage:     store,
+			Logger:      zap.NewNop(),
+			DefaultTier: "free",
+			Tiers:       []model.Tier{{Name: "free", MaxURLs: 3}},
+		}),
+		ServedScheme: "http",
+		Host:         "aaa",
+		PathLength:   7,
+	})
+	u := user.NewWithID("user")
+	_, err := svc.Shorten(ctx, u, &model.URL{Orig: "https://bbb.ccc/1"})
+	require.NoError(t, err)
+
+	// records beyond quota are reported as failed
+	dec, err := NewDecoder(strings.NewReader(
+		`{"original_url":"https://bbb.ccc/2"}`+"\n"+
+			`{"original_url":"https://bbb.ccc/3"}`+"\n"+
+			`{"original_url":"https://bbb.ccc/4"}`+"\n"), FormatNDJSON)
+	require.NoError(t, err)
+	result, err := svc.Import(ctx, u, dec, ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 3, result.Errors[0].Line)
+	assert.Contains(t, result.Errors[0].Error, "quota exceeded")
+
+	urls, err := svc.GetAll(ctx, u, "", "")
+	require.NoError(t, err)
+	assert.Len(t, urls, 3)
+}
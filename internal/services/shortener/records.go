@@ -0,0 +1,285 @@
+package shortener
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Import and export stream formats.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+
+	// maxRecordLineSize limits size of single NDJSON line.
+	maxRecordLineSize = 64 * 1024
+)
+
+// ErrUnsupportedFormat is returned for unknown stream formats.
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// csvColumns are CSV columns in the order they're written during export.
+// Import requires header row and accepts columns in any order, unknown columns are ignored.
+// Targets, variants and schedule are JSON encoded.
+var csvColumns = []string{
+	"short", "short_url", "original_url", "owner", "title", "tags", "notes",
+	"redirect", "passthrough", "preview", "targets", "variants", "schedule", "password_hash", "created",
+}
+
+// Decoder reads link records from CSV or NDJSON stream.
+type Decoder struct {
+	csv     *csv.Reader
+	lines   *bufio.Scanner
+	columns map[string]int
+	line    int
+}
+
+// NewDecoder creates link records decoder for specified format.
+func NewDecoder(r io.Reader, format string) (*Decoder, error) {
+	switch format {
+	case FormatCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		cr.ReuseRecord = true
+		return &Decoder{csv: cr}, nil
+	case FormatNDJSON:
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxRecordLineSize)
+		return &Decoder{lines: sc}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+}
+
+// Read returns next record and its line number.
+func (d *Decoder) Read() (*LinkRecord, int, error) {
+	if d.csv != nil {
+		return d.readCSV()
+	}
+	return d.readNDJSON()
+}
+
+func (d *Decoder) readNDJSON() (*LinkRecord, int, error) {
+	for d.lines.Scan() {
+		d.line++
+		line := d.lines.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var rec LinkRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, d.line, errors.Join(ErrInvalidRecord, err)
+		}
+		return &rec, d.line, nil
+	}
+	if err := d.lines.Err(); err != nil {
+		return nil, d.line + 1, fmt.Errorf("cannot read line: %w", err)
+	}
+	return nil, d.line, io.EOF
+}
+
+func (d *Decoder) readCSV() (*LinkRecord, int, error) {
+	if d.columns == nil {
+		if err := d.readHeader(); err != nil {
+			return nil, 1, err
+		}
+	}
+	fields, err := d.csv.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, parseErr.StartLine, errors.Join(ErrInvalidRecord, err)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil, 0, io.EOF
+		}
+		return nil, 0, fmt.Errorf("cannot read csv: %w", err)
+	}
+	line, _ := d.csv.FieldPos(0)
+	rec, err := d.csvRecord(fields)
+	if err != nil {
+		return nil, line, errors.Join(ErrInvalidRecord, err)
+	}
+	return rec, line, nil
+}
+
+func (d *Decoder) readHeader() error {
+	header, err := d.csv.Read()
+	if err != nil {
+		return fmt.Errorf("cannot read csv header: %w", err)
+	}
+	d.columns = make(map[string]int, len(header))
+	for i, name := range header {
+		d.columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := d.columns["original_url"]; !ok {
+		return errors.New("csv header has no original_url column")
+	}
+	return nil
+}
+
+func (d *Decoder) csvRecord(fields []string) (*LinkRecord, error) {
+	field := func(name string) string {
+		if i, ok := d.columns[name]; ok && i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	rec := &LinkRecord{
+		Short:        field("short"),
+		URL:          field("original_url"),
+		Owner:        field("owner"),
+		Title:        field("title"),
+		Notes:        field("notes"),
+		PasswordHash: field("password_hash"),
+	}
+	if tags := field("tags"); tags != "" {
+		rec.Tags = strings.Split(tags, ",")
+	}
+	var err error
+	if redirect := field("redirect"); redirect != "" {
+		if rec.Redirect, err = strconv.Atoi(redirect); err != nil {
+			return nil, fmt.Errorf("cannot parse redirect: %w", err)
+		}
+	}
+	if passthrough := field("passthrough"); passthrough != "" {
+		if rec.Passthrough, err = strconv.ParseBool(passthrough); err != nil {
+			return nil, fmt.Errorf("cannot parse passthrough: %w", err)
+		}
+	}
+	if preview := field("preview"); preview != "" {
+		if rec.Preview, err = strconv.ParseBool(preview); err != nil {
+			return nil, fmt.Errorf("cannot parse preview: %w", err)
+		}
+	}
+	if err = parseJSONField(field("targets"), &rec.Targets); err != nil {
+		return nil, fmt.Errorf("cannot parse targets: %w", err)
+	}
+	if err = parseJSONField(field("variants"), &rec.Variants); err != nil {
+		return nil, fmt.Errorf("cannot parse variants: %w", err)
+	}
+	if err = parseJSONField(field("schedule"), &rec.Schedule); err != nil {
+		return nil, fmt.Errorf("cannot parse schedule: %w", err)
+	}
+	if created := field("created"); created != "" {
+		ts, errT := time.Parse(time.RFC3339, created)
+		if errT != nil {
+			return nil, fmt.Errorf("cannot parse created: %w", errT)
+		}
+		rec.Created = &ts
+	}
+	return rec, nil
+}
+
+// parseJSONField decodes JSON encoded CSV field, empty field is skipped.
+func parseJSONField(field string, v any) error {
+	if field == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(field), v) //nolint:wrapcheck // wrapped by caller
+}
+
+// Encoder writes link records as CSV or NDJSON stream.
+// Records are buffered, Flush must be called after last record.
+type Encoder struct {
+	csv  *csv.Writer
+	buf  *bufio.Writer
+	json *json.Encoder
+}
+
+// NewEncoder creates link records encoder for specified format.
+// CSV header row is written immediately.
+func NewEncoder(w io.Writer, format string) (*Encoder, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvColumns); err != nil {
+			return nil, fmt.Errorf("cannot write csv header: %w", err)
+		}
+		return &Encoder{csv: cw}, nil
+	case FormatNDJSON:
+		buf := bufio.NewWriter(w)
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		return &Encoder{buf: buf, json: enc}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+}
+
+// Write writes single record.
+func (e *Encoder) Write(rec *LinkRecord) error {
+	if e.csv == nil {
+		if err := e.json.Encode(rec); err != nil {
+			return fmt.Errorf("cannot encode record: %w", err)
+		}
+		return nil
+	}
+	targets, err := formatJSONField(rec.Targets, len(rec.Targets) == 0)
+	if err != nil {
+		return err
+	}
+	variants, err := formatJSONField(rec.Variants, len(rec.Variants) == 0)
+	if err != nil {
+		return err
+	}
+	schedule, err := formatJSONField(rec.Schedule, rec.Schedule == nil)
+	if err != nil {
+		return err
+	}
+	if err = e.csv.Write([]string{
+		rec.Short, rec.ShortURL, rec.URL, rec.Owner, rec.Title, strings.Join(rec.Tags, ","), rec.Notes,
+		formatRedirect(rec.Redirect), strconv.FormatBool(rec.Passthrough), strconv.FormatBool(rec.Preview),
+		targets, variants, schedule, rec.PasswordHash, formatCreated(rec.Created),
+	}); err != nil {
+		return fmt.Errorf("cannot write csv record: %w", err)
+	}
+	return nil
+}
+
+// Flush writes buffered records to underlying writer.
+func (e *Encoder) Flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return fmt.Errorf("cannot flush csv: %w", err)
+		}
+		return nil
+	}
+	if err := e.buf.Flush(); err != nil {
+		return fmt.Errorf("cannot flush records: %w", err)
+	}
+	return nil
+}
+
+// formatJSONField encodes value as JSON for CSV field, empty value is written as empty field.
+func formatJSONField(v any, empty bool) (string, error) {
+	if empty {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("cannot encode csv field: %w", err)
+	}
+	return string(data), nil
+}
+
+func formatCreated(created *time.Time) string {
+	if created == nil {
+		return ""
+	}
+	return created.Format(time.RFC3339)
+}
+
+func formatRedirect(redirect int) string {
+	if redirect == 0 {
+		return ""
+	}
+	return strconv.Itoa(redirect)
+}
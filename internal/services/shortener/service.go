@@ -5,8 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/normalizer"
+	"github.com/adwski/shorty/internal/platform"
 
 	"github.com/adwski/shorty/internal/user"
 
@@ -15,35 +22,73 @@ import (
 	"github.com/adwski/shorty/internal/generators"
 
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 )
 
 const (
 	defaultStoreRetries = 3
+
+	maxVariants      = 100
+	maxScheduleRules = 100
+
+	countryCodeLength = 2
+
+	// bcrypt ignores password bytes beyond 72.
+	maxPasswordLength = 72
+	// every password is hashed with bcrypt, so number of protected urls in batch is limited.
+	maxBatchPasswords = 10
+
+	maxTitleLength = 200
+	maxTags        = 20
+	maxTagLength   = 50
+	maxNotesLength = 2000
 )
 
 // Service errors.
 var (
 	ErrInvalidURL           = errors.New("invalid url")
 	ErrUnsupportedURLScheme = errors.New("unsupported scheme")
+	ErrInvalidRedirect      = errors.New("invalid redirect code")
+	ErrInvalidTarget        = errors.New("invalid target")
+	ErrInvalidVariant       = errors.New("invalid variant")
+	ErrInvalidSchedule      = errors.New("invalid schedule")
+	ErrInvalidPassword      = errors.New("invalid password")
+	ErrInvalidTitle         = errors.New("invalid title")
+	ErrInvalidTags          = errors.New("invalid tags")
+	ErrInvalidNotes         = errors.New("invalid notes")
 	ErrStorageError         = errors.New("storage error")
 	ErrUnauthorized         = errors.New("unauthorized")
+	ErrForbidden            = errors.New("forbidden")
+	ErrWorkspaceNotFound    = errors.New("workspace not found")
 	ErrDelete               = errors.New("cannot queue url for deletion")
 	ErrEmptyBatch           = errors.New("empty batch")
 )
 
 // Storage is URL storage used by shortener.
 type Storage interface {
-	Get(ctx context.Context, key string) (url string, err error)
+	Get(ctx context.Context, key string) (url *model.URL, err error)
 	Store(ctx context.Context, url *model.URL, overwrite bool) (string, error)
 	StoreBatch(ctx context.Context, urls []model.URL) error
-	ListUserURLs(ctx context.Context, userid string) ([]*model.URL, error)
+	ListUserURLs(ctx context.Context, userid, tag string) ([]*model.URL, error)
+	ListWorkspaceURLs(ctx context.Context, workspaceID, tag string) ([]*model.URL, error)
+	GetMember(ctx context.Context, workspaceID, userID string) (*model.Member, error)
+	UpdateMeta(ctx context.Context, url *model.URL) error
 	DeleteUserURLs(ctx context.Context, urls []model.URL) (int64, error)
+	GetVariantClicks(ctx context.Context, short string) ([]int64, error)
+}
+
+// Quotas enforces user quotas on link creation. Quota errors are returned to caller as is.
+type Quotas interface {
+	CheckURLs(ctx context.Context, userID string, links int) error
+	CheckBatch(ctx context.Context, userID string, size int) error
 }
 
 // Service implements http handler for shortened urls management.
 type Service struct {
 	store          Storage
+	quotas         Quotas
 	flusher        *buffer.Flusher[model.URL]
+	normalizer     *normalizer.Normalizer
 	log            *zap.Logger
 	servedScheme   string
 	redirectScheme string
@@ -57,16 +102,24 @@ func (svc *Service) GetFlusher() *buffer.Flusher[model.URL] {
 }
 
 // Shorten generates short URL for incoming original URL and returns short url back.
-func (svc *Service) Shorten(ctx context.Context, user *user.User, origURL string) (string, error) {
-	u, err := url.Parse(origURL)
+// Besides original URL, link can hold optional per-link parameters.
+// If link has workspace id, it's created in workspace, user must be its editor.
+// Personal links are limited by user quota.
+func (svc *Service) Shorten(ctx context.Context, user *user.User, link *model.URL) (string, error) {
+	u, err := svc.prepareURL(link)
 	if err != nil {
-		return "", errors.Join(ErrInvalidURL, err)
+		return "", err
 	}
-	if svc.redirectScheme != "" && u.Scheme != svc.redirectScheme {
-		return "", ErrUnsupportedURLScheme
+	if err = svc.checkWorkspace(ctx, user, u.WorkspaceID, model.RoleEditor); err != nil {
+		return "", err
+	}
+	if svc.quotas != nil && u.WorkspaceID == "" {
+		if err = svc.quotas.CheckURLs(ctx, user.ID, 1); err != nil {
+			return "", err //nolint:wrapcheck // quota errors carry limits
+		}
 	}
 
-	shortPath, err := svc.storeURL(ctx, user, u.String())
+	shortPath, err := svc.storeURL(ctx, user, u)
 	if err != nil {
 		if !errors.Is(model.ErrConflict, err) {
 			return "", errors.Join(ErrStorageError, err)
@@ -75,20 +128,291 @@ func (svc *Service) Shorten(ctx context.Context, user *user.User, origURL string
 	return svc.getServedURL(shortPath), err // nil or conflict
 }
 
+// prepareURL validates link parameters and returns copy of link
+// with original URL in canonical form.
+func (svc *Service) prepareURL(link *model.URL) (*model.URL, error) {
+	var (
+		u   = *link
+		err error
+	)
+	if u.Orig, err = svc.parseURL(link.Orig); err != nil {
+		return nil, err
+	}
+	if u.Redirect != 0 && !model.IsValidRedirect(u.Redirect) {
+		return nil, ErrInvalidRedirect
+	}
+	if u.Targets, err = prepareTargets(u.Targets); err != nil {
+		return nil, errors.Join(ErrInvalidTarget, err)
+	}
+	if u.Variants, err = svc.prepareVariants(u.Variants); err != nil {
+		return nil, err
+	}
+	if u.Schedule, err = svc.prepareSchedule(u.Schedule); err != nil {
+		return nil, errors.Join(ErrInvalidSchedule, err)
+	}
+	if u.PasswordHash, err = hashPassword(u.Password); err != nil {
+		return nil, err
+	}
+	u.Password = ""
+	if err = prepareMeta(&u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// prepareVariants validates variant weights and brings variant URLs to canonical form.
+func (svc *Service) prepareVariants(variants []model.Variant) ([]model.Variant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	if len(variants) > maxVariants {
+		return nil, errors.Join(ErrInvalidVariant, fmt.Errorf("too many variants: %d", len(variants)))
+	}
+	result := make([]model.Variant, len(variants))
+	for i, v := range variants {
+		if v.Weight <= 0 {
+			return nil, errors.Join(ErrInvalidVariant, fmt.Errorf("variant weight must be positive: %d", v.Weight))
+		}
+		u, err := svc.parseURL(v.URL)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidVariant, err)
+		}
+		result[i] = model.Variant{URL: u, Weight: v.Weight}
+	}
+	return result, nil
+}
+
+// prepareSchedule validates schedule rules and brings rule URLs to canonical form.
+// Rule times are validated in UTC since actual timezone is known only during resolve.
+func (svc *Service) prepareSchedule(schedule *model.Schedule) (*model.Schedule, error) {
+	if schedule == nil || (schedule.Timezone == "" && len(schedule.Rules) == 0) {
+		return nil, nil
+	}
+	if len(schedule.Rules) == 0 {
+		return nil, errors.New("schedule has no rules")
+	}
+	if len(schedule.Rules) > maxScheduleRules {
+		return nil, fmt.Errorf("too many schedule rules: %d", len(schedule.Rules))
+	}
+	if schedule.Timezone != "" {
+		if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+			return nil, fmt.Errorf("unknown timezone: %w", err)
+		}
+	}
+	result := &model.Schedule{
+		Timezone: schedule.Timezone,
+		Rules:    make([]model.ScheduleRule, len(schedule.Rules)),
+	}
+	for i, rule := range schedule.Rules {
+		if err := validateRuleWindow(rule); err != nil {
+			return nil, err
+		}
+		u, err := svc.parseURL(rule.URL)
+		if err != nil {
+			return nil, err
+		}
+		result.Rules[i] = model.ScheduleRule{From: rule.From, To: rule.To, URL: u}
+	}
+	return result, nil
+}
+
+func validateRuleWindow(rule model.ScheduleRule) error {
+	if rule.From == "" && rule.To == "" {
+		return errors.New("schedule rule has no time window")
+	}
+	var (
+		from, to time.Time
+		err      error
+	)
+	if rule.From != "" {
+		if from, err = model.ParseScheduleTime(rule.From, time.UTC); err != nil {
+			return fmt.Errorf("cannot parse rule start: %w", err)
+		}
+	}
+	if rule.To != "" {
+		if to, err = model.ParseScheduleTime(rule.To, time.UTC); err != nil {
+			return fmt.Errorf("cannot parse rule end: %w", err)
+		}
+	}
+	if rule.From != "" && rule.To != "" && !from.Before(to) {
+		return fmt.Errorf("rule start %q is not before end %q", rule.From, rule.To)
+	}
+	return nil
+}
+
+// hashPassword returns bcrypt hash of link password,
+// empty password means link is not protected.
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	if len(password) > maxPasswordLength {
+		return "", errors.Join(ErrInvalidPassword,
+			fmt.Errorf("password is longer than %d bytes", maxPasswordLength))
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("cannot hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// prepareMeta validates link metadata and brings it to canonical form.
+func prepareMeta(u *model.URL) (err error) {
+	if u.Title, err = prepareTitle(u.Title); err != nil {
+		return errors.Join(ErrInvalidTitle, err)
+	}
+	if u.Tags, err = prepareTags(u.Tags); err != nil {
+		return errors.Join(ErrInvalidTags, err)
+	}
+	if u.Notes, err = prepareNotes(u.Notes); err != nil {
+		return errors.Join(ErrInvalidNotes, err)
+	}
+	return nil
+}
+
+// prepareTitle validates link title and trims surrounding spaces.
+func prepareTitle(title string) (string, error) {
+	title = strings.TrimSpace(title)
+	if !utf8.ValidString(title) {
+		return "", fmt.Errorf("title is not valid utf-8")
+	}
+	if utf8.RuneCountInString(title) > maxTitleLength {
+		return "", fmt.Errorf("title is longer than %d characters", maxTitleLength)
+	}
+	for _, r := range title {
+		if unicode.IsControl(r) {
+			return "", fmt.Errorf("invalid character in title: %q", r)
+		}
+	}
+	return title, nil
+}
+
+// prepareTags validates tags, trims surrounding spaces,
+// brings tags to lower case and removes duplicates.
+func prepareTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("too many tags: %d, max: %d", len(tags), maxTags)
+	}
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, fmt.Errorf("empty tag")
+		}
+		if !utf8.ValidString(tag) || utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag is not valid utf-8 or longer than %d characters", maxTagLength)
+		}
+		for _, r := range tag {
+			if unicode.IsControl(r) || r == ',' {
+				return nil, fmt.Errorf("invalid character in tag: %q", r)
+			}
+		}
+		if !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	return result, nil
+}
+
+// prepareNotes validates link notes, line breaks and tabs are allowed.
+func prepareNotes(notes string) (string, error) {
+	if !utf8.ValidString(notes) {
+		return "", fmt.Errorf("notes are not valid utf-8")
+	}
+	if utf8.RuneCountInString(notes) > maxNotesLength {
+		return "", fmt.Errorf("notes are longer than %d characters", maxNotesLength)
+	}
+	for _, r := range notes {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return "", fmt.Errorf("invalid character in notes: %q", r)
+		}
+	}
+	return notes, nil
+}
+
+// prepareTargets validates targets and brings country codes to upper case.
+func prepareTargets(targets []model.Target) ([]model.Target, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	result := make([]model.Target, len(targets))
+	for i, target := range targets {
+		target.Country = strings.ToUpper(target.Country)
+		if err := validateTarget(target); err != nil {
+			return nil, err
+		}
+		result[i] = target
+	}
+	return result, nil
+}
+
+// validateTarget checks target conditions and URL. Target URLs are not normalized
+// and redirect scheme is not enforced since they can point to app stores
+// or use custom app schemes.
+func validateTarget(target model.Target) error {
+	if target.Platform == "" && target.Country == "" {
+		return errors.New("target has no conditions")
+	}
+	if target.Platform != "" && !platform.IsValid(target.Platform) {
+		return fmt.Errorf("unknown platform: %q", target.Platform)
+	}
+	if target.Country != "" && !isCountryCode(target.Country) {
+		return fmt.Errorf("invalid country code: %q", target.Country)
+	}
+	u, err := url.Parse(target.URL)
+	if err != nil {
+		return fmt.Errorf("cannot parse target url: %w", err)
+	}
+	if !u.IsAbs() {
+		return fmt.Errorf("target url is not absolute: %q", target.URL)
+	}
+	return nil
+}
+
+// isCountryCode checks if s looks like ISO 3166-1 alpha-2 country code.
+func isCountryCode(s string) bool {
+	if len(s) != countryCodeLength {
+		return false
+	}
+	for _, c := range s {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseURL parses original URL, checks its scheme and brings it to canonical form.
+func (svc *Service) parseURL(origURL string) (string, error) {
+	u, err := url.Parse(origURL)
+	if err != nil {
+		return "", errors.Join(ErrInvalidURL, err)
+	}
+	if svc.redirectScheme != "" && u.Scheme != svc.redirectScheme {
+		return "", ErrUnsupportedURLScheme
+	}
+	if svc.normalizer != nil {
+		svc.normalizer.Normalize(u)
+	}
+	return u.String(), nil
+}
+
 func (svc *Service) getServedURL(shortPath string) string {
 	return fmt.Sprintf("%s://%s/%s", svc.servedScheme, svc.host, shortPath)
 }
 
-func (svc *Service) storeURL(ctx context.Context, user *user.User, u string) (path string, err error) {
+func (svc *Service) storeURL(ctx context.Context, user *user.User, u *model.URL) (path string, err error) {
+	u.UserID = user.ID
 	for i := 1; i <= defaultStoreRetries; i++ {
 		path = generators.RandString(svc.pathLength)
+		u.Short = path
 
 		var storedPath string
-		if storedPath, err = svc.store.Store(ctx, &model.URL{
-			Short:  path,
-			Orig:   u,
-			UserID: user.ID,
-		}, false); err != nil {
+		if storedPath, err = svc.store.Store(ctx, u, false); err != nil {
 			if errors.Is(err, model.ErrConflict) {
 				path = storedPath
 				return
@@ -2,30 +2,43 @@ package shortener
 
 import (
 	"context"
+	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/adwski/shorty/internal/app/mockapp"
 	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/normalizer"
 	"github.com/adwski/shorty/internal/user"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestService_Shorten(t *testing.T) {
 	type args struct {
 		pathLength        uint
 		url               string
+		redirect          int
+		targets           []model.Target
+		variants          []model.Variant
+		schedule          *model.Schedule
+		password          string
+		title             string
 		addToStorage      map[string]string
 		host              string
 		servedScheme      string
 		redirectScheme    string
 		doNotRegisterMock bool
+		normalize         bool
 	}
 	type want struct {
-		err error
+		err    error
+		stored string
+		title  string
 	}
 	tests := []struct {
 		name string
@@ -75,6 +88,317 @@ func TestService_Shorten(t *testing.T) {
 				err: ErrUnsupportedURLScheme,
 			},
 		},
+		{
+			name: "store normalized url",
+			args: args{
+				pathLength:   10,
+				url:          "HTTPS://AAA.bbb:443/Path?b=1&utm_source=x&a=2#frag",
+				servedScheme: "http",
+				host:         "ccc.ddd",
+				normalize:    true,
+			},
+			want: want{
+				stored: "https://aaa.bbb/Path?a=2&b=1",
+			},
+		},
+		{
+			name: "store url with redirect code",
+			args: args{
+				pathLength:   10,
+				url:          "https://aaa.bbb",
+				redirect:     http.StatusPermanentRedirect,
+				servedScheme: "http",
+				host:         "ccc.ddd",
+			},
+		},
+		{
+			name: "store url with invalid redirect code",
+			args: args{
+				pathLength:        10,
+				url:               "https://aaa.bbb",
+				redirect:          http.StatusOK,
+				servedScheme:      "http",
+				host:              "ccc.ddd",
+				doNotRegisterMock: true,
+			},
+			want: want{
+				err: ErrInvalidRedirect,
+			},
+		},
+		{
+			name: "store url with targets",
+			args: args{
+				pathLength:   10,
+				url:          "https://aaa.bbb",
+				servedScheme: "http",
+				host:         "ccc.ddd",
+				targets: []model.Target{
+					{Platform: "ios", URL: "itms-apps://apps.apple.com/app/id123"},
+					{Platform: "android", URL: "intent://aaa.bbb#Intent;scheme=https;package=bbb.aaa;end"},
+				},
+			},
+		},
+		{
+			name: "store url with country targets",
+			args: args{
+				pathLength:   10,
+				url:          "https://aaa.bbb",
+				servedScheme: "http",
+				host:         "ccc.ddd",
+				targets: []model.Target{
+					{Country: "DE", URL: "https://aaa.bbb/de"},
+					{Country: "US", Platform: "ios", URL: "https://apps.apple.com/us/app/id123"},
+				},
+			},
+		},
+		{
+			name: "store url with invalid target country",
+			args: args{
+				pathLength:        10,
+				url:               "https://aaa.bbb",
+				servedScheme:      "http",
+				host:              "ccc.ddd",
+				doNotRegisterMock: true,
+				targets: []model.Target{
+					{Country: "DEU", URL: "https://aaa.bbb/de"},
+				},
+			},
+			want: want{
+				err: ErrInvalidTarget,
+			},
+		},
+		{
+			name: "store url with target without conditions",
+			args: args{
+				pathLength:        10,
+				url:               "https://aaa.bbb",
+				servedScheme:      "http",
+				host:              "ccc.ddd",
+				doNotRegisterMock: true,
+				targets: []model.Target{
+					{URL: "https://aaa.bbb/de"},
+				},
+			},
+			want: want{
+				err: ErrInvalidTarget,
+			},
+		},
+		{
+			name: "store url with unknown target platform",
+			args: args{
+				pathLength:        10,
+				url:               "https://aaa.bbb",
+				servedScheme:      "http",
+				host:              "ccc.ddd",
+				doNotRegisterMock: true,
+				targets: []model.Target{
+					{Platform: "symbian", URL: "https://aaa.bbb"},
+				},
+			},
+			want: want{
+				err: ErrInvalidTarget,
+			},
+		},
+		{
+			name: "store url with relative target url",
+			args: args{
+				pathLength:        10,
+				url:               "https://aaa.bbb",
+				servedScheme:      "http",
+				host:              "ccc.ddd",
+				doNotRegisterMock: true,
+				targets: []model.Target{
+					{Platform: "ios", URL: "/app"},
+				},
+			},
+			want: want{
+				err: ErrInvalidTarget,
+			},
+		},
+		{
+			name: "store url with variants",
+			args: args{
+				pathLength:   10,
+				url:          "https://aaa.bbb",
+				servedScheme: "http",
+				host:         "ccc.ddd",
+				variants: []model.Variant{
+					{URL: "https://aaa.bbb/a", Weight: 50},
+					{URL: "https://aaa.bbb/b", Weight: 50},
+				},
+			},
+		},
+		{
+			name: "store url with zero variant weight",
+			args: args{
+				pathLength:        10,
+				url:               "https://aaa.bbb",
+				servedScheme:      "http",
+				host:              "ccc.ddd",
+				doNotRegisterMock: true,
+				variants: []model.Variant{
+					{URL: "https://aaa.bbb/a", Weight: 0},
+				},
+			},
+			want: want{
+				err: ErrInvalidVariant,
+			},
+		},
+		{
+			name: "store url with schedule",
+			args: args{
+				pathLength:   10,
+				url:          "https://aaa.bbb",
+				servedScheme: "http",
+				host:         "ccc.ddd",
+				schedule: &model.Schedule{
+					Timezone: "Europe/Berlin",
+					Rules: []model.ScheduleRule{
+						{To: "2024-05-01T10:00", URL: "https://aaa.bbb/teaser"},
+						{From: "2024-05-01T10:00", To: "2024-05-08T00:00:00", URL: "https://aaa.bbb/sale"},
+						{From: "2024-05-08T00:00:00+02:00", URL: "https://aaa.bbb/ended"},
+					},
+				},
+			},
+		},
+		{
+			name: "store url with unknown schedule timezone",
+			args: args{
+				pathLength:        10,
+				url:               "https://aaa.bbb",
+				servedScheme:      "http",
+				host:              "ccc.ddd",
+				doNotRegisterMock: true,
+				schedule: &model.Schedule{
+					Timezone: "Mars/Olympus",
+					Rules: []model.ScheduleRule{
+						{To: "2024-05-01T10:00", URL: "https://aaa.bbb/teaser"},
+					},
+				},
+			},
+			want: want{
+				err: ErrInvalidSchedule,
+			},
+		},
+		{
+			name: "store url with invalid schedule window",
+			args: args{
+				pathLength:        10,
+				url:               "https://aaa.bbb",
+				servedScheme:      "http",
+				host:              "ccc.ddd",
+				doNotRegisterMock: true,
+				schedule: &model.Schedule{
+					Rules: []model.ScheduleRule{
+						{From: "2024-05-08T00:00", To: "2024-05-01T10:00", URL: "https://aaa.bbb/sale"},
+					},
+				},
+			},
+			want: want{
+				err: ErrInvalidSchedule,
+			},
+		},
+		{
+			name: "store url with malformed schedule time",
+			args: args{
+				pathLength:        10,
+				url:               "https://aaa.bbb",
+				servedScheme:      "http",
+				host:              "ccc.ddd",
+				doNotRegisterMock: true,
+				schedule: &model.Schedule{
+					Rules: []model.ScheduleRule{
+						{From: "May 1st", URL: "https://aaa.bbb/sale"},
+					},
+				},
+			},
+			want: want{
+				err: ErrInvalidSchedule,
+			},
+		},
+		{
+			name: "store url with schedule rule without window",
+			args: args{
+				pathLength:        10,
+				url:               "https://aaa.bbb",
+				servedScheme:      "http",
+				host:              "ccc.ddd",
+				doNotRegisterMock: true,
+				schedule: &model.Schedule{
+					Rules: []model.ScheduleRule{
+						{URL: "https://aaa.bbb/sale"},
+					},
+				},
+			},
+			want: want{
+				err: ErrInvalidSchedule,
+			},
+		},
+		{
+			name: "store url with password",
+			args: args{
+				pathLength:   10,
+				url:          "https://aaa.bbb",
+				servedScheme: "http",
+				host:         "ccc.ddd",
+				password:     "secret",
+			},
+		},
+		{
+			name: "store url with too long password",
+			args: args{
+				pathLength:        10,
+				url:               "https://aaa.bbb",
+				servedScheme:      "http",
+				host:              "ccc.ddd",
+				doNotRegisterMock: true,
+				password:          strings.Repeat("a", 73),
+			},
+			want: want{
+				err: ErrInvalidPassword,
+			},
+		},
+		{
+			name: "store url with title",
+			args: args{
+				pathLength:   10,
+				url:          "https://aaa.bbb",
+				servedScheme: "http",
+				host:         "ccc.ddd",
+				title:        "  Spring sale  ",
+			},
+			want: want{
+				title: "Spring sale",
+			},
+		},
+		{
+			name: "store url with too long title",
+			args: args{
+				pathLength:        10,
+				url:               "https://aaa.bbb",
+				servedScheme:      "http",
+				host:              "ccc.ddd",
+				doNotRegisterMock: true,
+				title:             strings.Repeat("я", 201),
+			},
+			want: want{
+				err: ErrInvalidTitle,
+			},
+		},
+		{
+			name: "store url with control character in title",
+			args: args{
+				pathLength:        10,
+				url:               "https://aaa.bbb",
+				servedScheme:      "http",
+				host:              "ccc.ddd",
+				doNotRegisterMock: true,
+				title:             "Spring\nsale",
+			},
+			want: want{
+				err: ErrInvalidTitle,
+			},
+		},
 		{
 			name: "store arbitrary scheme",
 			args: args{
@@ -99,7 +423,7 @@ func TestService_Shorten(t *testing.T) {
 				st.On("Store", mock.Anything, mock.Anything, false).Return(
 					func(_ context.Context, url *model.URL, _ bool) (string, error) {
 						t.Log("registering mock get", url)
-						st.EXPECT().Get(ctx, url.Short).Return(url.Orig, nil)
+						st.EXPECT().Get(ctx, url.Short).Return(url, nil)
 						return "", nil
 					})
 			}
@@ -113,11 +437,27 @@ func TestService_Shorten(t *testing.T) {
 				store:          st,
 				log:            logger,
 			}
+			if tt.args.normalize {
+				svc.normalizer = normalizer.New(&normalizer.Config{
+					TrackingParams: []string{"utm_*"},
+					SortQuery:      true,
+					RemoveFragment: true,
+					StripTracking:  true,
+				})
+			}
 
 			// Make Shorten call
 			usr, err := user.New()
 			require.NoError(t, err)
-			shortURL, err := svc.Shorten(ctx, usr, tt.args.url)
+			shortURL, err := svc.Shorten(ctx, usr, &model.URL{
+				Orig:     tt.args.url,
+				Redirect: tt.args.redirect,
+				Targets:  tt.args.targets,
+				Variants: tt.args.variants,
+				Schedule: tt.args.schedule,
+				Password: tt.args.password,
+				Title:    tt.args.title,
+			})
 
 			// Check results
 			if tt.want.err != nil {
@@ -136,7 +476,22 @@ func TestService_Shorten(t *testing.T) {
 			// Check storage content
 			storedURL, err := st.Get(ctx, u.Path[1:])
 			require.NoError(t, err)
-			assert.Equal(t, tt.args.url, storedURL)
+			if tt.want.stored != "" {
+				assert.Equal(t, tt.want.stored, storedURL.Orig)
+			} else {
+				assert.Equal(t, tt.args.url, storedURL.Orig)
+			}
+			assert.Equal(t, tt.args.redirect, storedURL.Redirect)
+			assert.Equal(t, tt.args.targets, storedURL.Targets)
+			assert.Equal(t, tt.args.variants, storedURL.Variants)
+			assert.Equal(t, tt.args.schedule, storedURL.Schedule)
+			assert.Empty(t, storedURL.Password)
+			assert.Equal(t, tt.want.title, storedURL.Title)
+			if tt.args.password != "" {
+				assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(storedURL.PasswordHash), []byte(tt.args.password)))
+			} else {
+				assert.Empty(t, storedURL.PasswordHash)
+			}
 		})
 	}
 }
@@ -0,0 +1,47 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+)
+
+// GetVariantStats returns click statistics of URL variants.
+// Statistics is available only to URL owner or workspace members.
+func (svc *Service) GetVariantStats(ctx context.Context, u *user.User, short string) ([]model.VariantStats, error) {
+	if u.IsNew() {
+		// Session was created during this request
+		// That means there is no valid cookie
+		return nil, ErrUnauthorized
+	}
+	url, err := svc.store.Get(ctx, short)
+	if err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	if err = svc.checkAccess(ctx, u, url, model.RoleViewer); err != nil {
+		return nil, err
+	}
+	if len(url.Variants) == 0 {
+		return nil, model.ErrNotFound
+	}
+	clicks, err := svc.store.GetVariantClicks(ctx, short)
+	if err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	stats := make([]model.VariantStats, 0, len(url.Variants))
+	for i, v := range url.Variants {
+		var num int64
+		if i < len(clicks) {
+			num = clicks[i]
+		}
+		stats = append(stats, model.VariantStats{
+			Variant: i,
+			URL:     v.URL,
+			Weight:  v.Weight,
+			Clicks:  num,
+		})
+	}
+	return stats, nil
+}
@@ -0,0 +1,129 @@
+package shortener
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adwski/shorty/internal/app/mockapp"
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestService_GetVariantStats(t *testing.T) {
+	type args struct {
+		stored  *model.URL
+		clicks  []int64
+		userID  string
+		newUser bool
+	}
+	type want struct {
+		err   error
+		stats []model.VariantStats
+	}
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "get variant stats",
+			args: args{
+				stored: &model.URL{
+					Orig:   "https://aaa.bbb",
+					UserID: "testuser",
+					Variants: []model.Variant{
+						{URL: "https://ccc.ddd", Weight: 70},
+						{URL: "https://eee.fff", Weight: 30},
+					},
+				},
+				clicks: []int64{7, 3},
+				userID: "testuser",
+			},
+			want: want{
+				stats: []model.VariantStats{
+					{Variant: 0, URL: "https://ccc.ddd", Weight: 70, Clicks: 7},
+					{Variant: 1, URL: "https://eee.fff", Weight: 30, Clicks: 3},
+				},
+			},
+		},
+		{
+			name: "other user url",
+			args: args{
+				stored: &model.URL{
+					Orig:   "https://aaa.bbb",
+					UserID: "otheruser",
+					Variants: []model.Variant{
+						{URL: "https://ccc.ddd", Weight: 70},
+					},
+				},
+				userID: "testuser",
+			},
+			want: want{
+				err: model.ErrNotFound,
+			},
+		},
+		{
+			name: "url without variants",
+			args: args{
+				stored: &model.URL{
+					Orig:   "https://aaa.bbb",
+					UserID: "testuser",
+				},
+				userID: "testuser",
+			},
+			want: want{
+				err: model.ErrNotFound,
+			},
+		},
+		{
+			name: "new user",
+			args: args{
+				newUser: true,
+			},
+			want: want{
+				err: ErrUnauthorized,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, err := zap.NewDevelopment()
+			require.NoError(t, err)
+
+			var (
+				st  = mockapp.NewStorage(t)
+				ctx = context.Background()
+				svc = &Service{
+					store: st,
+					log:   logger,
+				}
+			)
+			if tt.args.stored != nil {
+				st.EXPECT().Get(ctx, "qweqwe").Once().Return(tt.args.stored, nil)
+			}
+			if tt.args.clicks != nil {
+				st.EXPECT().GetVariantClicks(ctx, "qweqwe").Once().Return(tt.args.clicks, nil)
+			}
+
+			var usr *user.User
+			if tt.args.newUser {
+				usr, err = user.New()
+				require.NoError(t, err)
+			} else {
+				usr = &user.User{ID: tt.args.userID}
+			}
+
+			stats, err := svc.GetVariantStats(ctx, usr, "qweqwe")
+			if tt.want.err != nil {
+				assert.Nil(t, stats)
+				assert.ErrorIs(t, err, tt.want.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.stats, stats)
+		})
+	}
+}
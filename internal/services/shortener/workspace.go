@@ -0,0 +1,56 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+)
+
+// checkRole checks that user has required role in workspace.
+// Workspace existence is not revealed to users who are not its members,
+// it's not found for them regardless.
+func (svc *Service) checkRole(ctx context.Context, u *user.User, workspaceID, role string) error {
+	member, err := svc.store.GetMember(ctx, workspaceID, u.ID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return ErrWorkspaceNotFound
+		}
+		return errors.Join(ErrStorageError, err)
+	}
+	if !model.RoleAllows(member.Role, role) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// checkAccess checks that user can access URL. Personal URLs are accessible only to their owner,
+// workspace URLs are accessible to workspace members with required role.
+func (svc *Service) checkAccess(ctx context.Context, u *user.User, url *model.URL, role string) error {
+	if url.WorkspaceID != "" {
+		if err := svc.checkRole(ctx, u, url.WorkspaceID, role); err != nil {
+			if errors.Is(err, ErrWorkspaceNotFound) {
+				return model.ErrNotFound
+			}
+			return err
+		}
+		return nil
+	}
+	if url.UserID != u.ID {
+		// do not reveal existence of other users urls
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+// checkWorkspace checks role of user if workspace is set. New users are not members of any workspace.
+func (svc *Service) checkWorkspace(ctx context.Context, u *user.User, workspaceID, role string) error {
+	if workspaceID == "" {
+		return nil
+	}
+	if u.IsNew() {
+		return ErrUnauthorized
+	}
+	return svc.checkRole(ctx, u, workspaceID, role)
+}
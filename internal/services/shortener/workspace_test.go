@@ -0,0 +1,72 @@
+package shortener
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/storage/memory"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestService_WorkspaceRoles(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	svc := New(&Config{
+		Store:        store,
+		Logger:       zap.NewNop(),
+		ServedScheme: "http",
+		Host:         "aaa",
+		PathLength:   7,
+	})
+	var (
+		owner    = user.NewWithID("owner")
+		editor   = user.NewWithID("editor")
+		viewer   = user.NewWithID("viewer")
+		outsider = user.NewWithID("outsider")
+	)
+	require.NoError(t, store.CreateWorkspace(ctx, &model.Workspace{ID: "ws", Name: "Marketing"}, owner.ID))
+	require.NoError(t, store.SetMember(ctx, &model.Member{WorkspaceID: "ws", UserID: editor.ID, Role: model.RoleEditor}))
+	require.NoError(t, store.SetMember(ctx, &model.Member{WorkspaceID: "ws", UserID: viewer.ID, Role: model.RoleViewer}))
+
+	// only editors and owners create workspace links
+	_, err := svc.Shorten(ctx, viewer, &model.URL{Orig: "https://bbb.ccc/1", WorkspaceID: "ws"})
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = svc.Shorten(ctx, outsider, &model.URL{Orig: "https://bbb.ccc/1", WorkspaceID: "ws"})
+	assert.ErrorIs(t, err, ErrWorkspaceNotFound)
+	shortURL, err := svc.Shorten(ctx, editor, &model.URL{Orig: "https://bbb.ccc/1", WorkspaceID: "ws"})
+	require.NoError(t, err)
+	short := shortURL[len("http://aaa/"):]
+	_, err = svc.ShortenBatch(ctx, viewer, "ws", []BatchURL{{ID: "1", URL: "https://bbb.ccc/2"}})
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	// every member lists workspace links, they're not personal links of creator
+	for _, u := range []*user.User{owner, editor, viewer} {
+		urls, errL := svc.GetAll(ctx, u, "ws", "")
+		require.NoError(t, errL)
+		require.Len(t, urls, 1)
+		assert.Equal(t, shortURL, urls[0].Short)
+	}
+	_, err = svc.GetAll(ctx, outsider, "ws", "")
+	assert.ErrorIs(t, err, ErrWorkspaceNotFound)
+	urls, err := svc.GetAll(ctx, editor, "", "")
+	require.NoError(t, err)
+	assert.Empty(t, urls)
+
+	// links are edited by editors and owners, not only by creator
+	title := "Spring"
+	_, err = svc.UpdateMeta(ctx, viewer, short, &model.MetaUpdate{Title: &title})
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = svc.UpdateMeta(ctx, outsider, short, &model.MetaUpdate{Title: &title})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	updated, err := svc.UpdateMeta(ctx, owner, short, &model.MetaUpdate{Title: &title})
+	require.NoError(t, err)
+	assert.Equal(t, title, updated.Title)
+
+	assert.ErrorIs(t, svc.DeleteBatch(ctx, viewer, "ws", []string{short}), ErrForbidden)
+	assert.ErrorIs(t, svc.DeleteBatch(ctx, outsider, "ws", []string{short}), ErrWorkspaceNotFound)
+	require.NoError(t, svc.DeleteBatch(ctx, editor, "ws", []string{short}))
+}
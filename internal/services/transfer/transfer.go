@@ -0,0 +1,424 @@
+// Package transfer is link ownership transfer service.
+// Owner offers personal links to another user, links change owner only when recipient accepts offer.
+// Administrator can transfer links without consent of users, for example when user leaves organization.
+// Every transfer atomically changes owner of all listed links and is recorded as audit event.
+package transfer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+	"go.uber.org/zap"
+)
+
+const (
+	idBytes = 8
+
+	maxTransferURLs     = 1000
+	maxPendingTransfers = 100
+
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// Service errors.
+var (
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrNotFound          = errors.New("transfer not found")
+	ErrInvalidUser       = errors.New("invalid user")
+	ErrInvalidURLs       = errors.New("links cannot be transferred")
+	ErrTooManyURLs       = errors.New("too many links")
+	ErrTooManyTransfers  = errors.New("too many pending transfers")
+	ErrInvalidAuditLimit = errors.New("invalid audit events limit")
+	ErrStorageError      = errors.New("storage error")
+)
+
+// Storage is transfer storage.
+type Storage interface {
+	Get(ctx context.Context, key string) (*model.URL, error)
+	ListUserURLs(ctx context.Context, userID, tag string) ([]*model.URL, error)
+	GetAccount(ctx context.Context, login string) (*model.Account, error)
+	CreateTransfer(ctx context.Context, transfer *model.Transfer) error
+	GetTransfer(ctx context.Context, id string) (*model.Transfer, error)
+	ListTransfers(ctx context.Context, userID string) ([]*model.Transfer, error)
+	DeleteTransfer(ctx context.Context, id string) error
+	TransferURLs(ctx context.Context, event *model.AuditEvent) error
+	ListAuditEvents(ctx context.Context, limit int) ([]*model.AuditEvent, error)
+}
+
+// Quotas enforces link quota of recipient. Quota errors are returned to caller as is.
+type Quotas interface {
+	CheckURLs(ctx context.Context, userID string, links int) error
+}
+
+// Service is transfer service.
+type Service struct {
+	store  Storage
+	quotas Quotas
+	log    *zap.Logger
+}
+
+// Config is transfer service config.
+type Config struct {
+	Storage Storage
+	// Quotas is optional, recipient quota is not checked if it's not set.
+	Quotas Quotas
+	Logger *zap.Logger
+}
+
+// Transfer describes pending transfer offer.
+type Transfer struct {
+	Created time.Time `json:"created"`
+	ID      string    `json:"id"`
+	From    string    `json:"from_user_id"`
+	To      string    `json:"to_user_id"`
+	Shorts  []string  `json:"shorts"`
+}
+
+// Event describes audit event of completed transfer.
+type Event struct {
+	Created  time.Time `json:"created"`
+	ID       string    `json:"id"`
+	Action   string    `json:"action"`
+	Actor    string    `json:"actor_user_id,omitempty"`
+	Transfer string    `json:"transfer_id,omitempty"`
+	From     string    `json:"from_user_id"`
+	To       string    `json:"to_user_id"`
+	Shorts   []string  `json:"shorts"`
+}
+
+// Recipient identifies user by account login if it's set, or by user id otherwise.
+type Recipient struct {
+	UserID string
+	Login  string
+}
+
+// New creates transfer service.
+func New(cfg *Config) *Service {
+	return &Service{
+		store:  cfg.Storage,
+		quotas: cfg.Quotas,
+		log:    cfg.Logger.With(zap.String("component", "transfer")),
+	}
+}
+
+// Offer creates transfer offer of user personal links to recipient.
+func (svc *Service) Offer(ctx context.Context, u *user.User, to *Recipient, shorts []string) (*Transfer, error) {
+	if err := checkUser(u); err != nil {
+		return nil, err
+	}
+	shorts, err := prepareShorts(shorts)
+	if err != nil {
+		return nil, err
+	}
+	toUserID, err := svc.resolveRecipient(ctx, u.ID, to)
+	if err != nil {
+		return nil, err
+	}
+	transfers, err := svc.store.ListTransfers(ctx, u.ID)
+	if err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	// only offers sent by user are limited, so others cannot prevent user from making offers
+	var pending int
+	for _, transfer := range transfers {
+		if transfer.FromUserID == u.ID {
+			pending++
+		}
+	}
+	if pending >= maxPendingTransfers {
+		return nil, ErrTooManyTransfers
+	}
+	for _, short := range shorts {
+		if err = svc.checkURL(ctx, u.ID, short); err != nil {
+			return nil, err
+		}
+	}
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	transfer := &model.Transfer{
+		Created:    time.Now().UTC().Truncate(time.Second),
+		ID:         id,
+		FromUserID: u.ID,
+		ToUserID:   toUserID,
+		Shorts:     shorts,
+	}
+	if err = svc.store.CreateTransfer(ctx, transfer); err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	svc.log.Debug("transfer offered",
+		zap.String("transferID", id),
+		zap.String("from", u.ID),
+		zap.String("to", toUserID),
+		zap.Int("urls", len(shorts)))
+	return newTransfer(transfer), nil
+}
+
+// List returns pending transfer offers sent or received by user.
+func (svc *Service) List(ctx context.Context, u *user.User) ([]*Transfer, error) {
+	if err := checkUser(u); err != nil {
+		return nil, err
+	}
+	transfers, err := svc.store.ListTransfers(ctx, u.ID)
+	if err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	result := make([]*Transfer, 0, len(transfers))
+	for _, transfer := range transfers {
+		result = append(result, newTransfer(transfer))
+	}
+	return result, nil
+}
+
+// Accept accepts transfer offer received by user, offered links are moved to user.
+// Offer fails if any of its links was deleted or changed owner after it was made,
+// or if user cannot own more links because of quota.
+func (svc *Service) Accept(ctx context.Context, u *user.User, id string) (*Event, error) {
+	if err := checkUser(u); err != nil {
+		return nil, err
+	}
+	transfer, err := svc.getTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.ToUserID != u.ID {
+		return nil, ErrNotFound
+	}
+	if svc.quotas != nil {
+		if err = svc.quotas.CheckURLs(ctx, u.ID, len(transfer.Shorts)); err != nil {
+			return nil, err //nolint:wrapcheck // quota errors carry limits
+		}
+	}
+	return svc.transfer(ctx, &model.AuditEvent{
+		Action:     model.AuditTransferAccepted,
+		ActorID:    u.ID,
+		TransferID: transfer.ID,
+		FromUserID: transfer.FromUserID,
+		ToUserID:   transfer.ToUserID,
+		Shorts:     transfer.Shorts,
+	})
+}
+
+// Decline removes transfer offer. Recipient declines offer, sender cancels it.
+func (svc *Service) Decline(ctx context.Context, u *user.User, id string) error {
+	if err := checkUser(u); err != nil {
+		return err
+	}
+	transfer, err := svc.getTransfer(ctx, id)
+	if err != nil {
+		return err
+	}
+	if transfer.ToUserID != u.ID && transfer.FromUserID != u.ID {
+		return ErrNotFound
+	}
+	if err = svc.store.DeleteTransfer(ctx, id); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return ErrNotFound
+		}
+		return errors.Join(ErrStorageError, err)
+	}
+	svc.log.Debug("transfer declined",
+		zap.String("transferID", id),
+		zap.String("userID", u.ID))
+	return nil
+}
+
+// Force transfers personal links of user to recipient without consent of users.
+// Quota of recipient is not checked.
+// If shorts are not provided, all active personal links of user are transferred.
+// It's administrative action, so caller must be authorized beforehand.
+func (svc *Service) Force(ctx context.Context, fromUserID string, to *Recipient, shorts []string) (*Event, error) {
+	if _, err := user.NewFromUserID(fromUserID); err != nil {
+		return nil, errors.Join(ErrInvalidUser, err)
+	}
+	toUserID, err := svc.resolveRecipient(ctx, fromUserID, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(shorts) == 0 {
+		urls, errL := svc.store.ListUserURLs(ctx, fromUserID, "")
+		if errL != nil {
+			return nil, errors.Join(ErrStorageError, errL)
+		}
+		if len(urls) == 0 {
+			return nil, fmt.Errorf("%w: user has no links", ErrInvalidURLs)
+		}
+		for _, url := range urls {
+			shorts = append(shorts, url.Short)
+		}
+	} else if shorts, err = prepareShorts(shorts); err != nil {
+		return nil, err
+	}
+	event, err := svc.transfer(ctx, &model.AuditEvent{
+		Action:     model.AuditTransferForced,
+		FromUserID: fromUserID,
+		ToUserID:   toUserID,
+		Shorts:     shorts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	svc.log.Info("links transferred by administrator",
+		zap.String("eventID", event.ID),
+		zap.String("from", fromUserID),
+		zap.String("to", toUserID),
+		zap.Int("urls", len(event.Shorts)))
+	return event, nil
+}
+
+// Audit returns latest audit events, newest first. Default limit is used if limit is zero.
+// It's administrative action, so caller must be authorized beforehand.
+func (svc *Service) Audit(ctx context.Context, limit int) ([]*Event, error) {
+	if limit == 0 {
+		limit = defaultAuditLimit
+	}
+	if limit < 0 || limit > maxAuditLimit {
+		return nil, fmt.Errorf("%w: must be between 1 and %d", ErrInvalidAuditLimit, maxAuditLimit)
+	}
+	events, err := svc.store.ListAuditEvents(ctx, limit)
+	if err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	result := make([]*Event, 0, len(events))
+	for _, event := range events {
+		result = append(result, newEvent(event))
+	}
+	return result, nil
+}
+
+// transfer moves links and records audit event.
+func (svc *Service) transfer(ctx context.Context, event *model.AuditEvent) (*Event, error) {
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	event.ID = id
+	event.Created = time.Now().UTC().Truncate(time.Second)
+	if err = svc.store.TransferURLs(ctx, event); err != nil {
+		switch {
+		case errors.Is(err, model.ErrNotFound):
+			return nil, ErrNotFound
+		case errors.Is(err, model.ErrConflict):
+			return nil, fmt.Errorf("%w: links were deleted or changed owner", ErrInvalidURLs)
+		default:
+			return nil, errors.Join(ErrStorageError, err)
+		}
+	}
+	svc.log.Debug("links transferred",
+		zap.String("eventID", id),
+		zap.String("action", event.Action),
+		zap.String("from", event.FromUserID),
+		zap.String("to", event.ToUserID))
+	return newEvent(event), nil
+}
+
+func (svc *Service) getTransfer(ctx context.Context, id string) (*model.Transfer, error) {
+	transfer, err := svc.store.GetTransfer(ctx, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	return transfer, nil
+}
+
+// checkURL checks that link is active personal link of user.
+func (svc *Service) checkURL(ctx context.Context, userID, short string) error {
+	url, err := svc.store.Get(ctx, short)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) || errors.Is(err, model.ErrDeleted) {
+			return fmt.Errorf("%w: %s is not found", ErrInvalidURLs, short)
+		}
+		return errors.Join(ErrStorageError, err)
+	}
+	if url.UserID != userID || url.WorkspaceID != "" {
+		// do not reveal existence of other users urls
+		return fmt.Errorf("%w: %s is not found", ErrInvalidURLs, short)
+	}
+	return nil
+}
+
+// resolveRecipient returns user id of recipient, recipient cannot be sender.
+func (svc *Service) resolveRecipient(ctx context.Context, fromUserID string, to *Recipient) (string, error) {
+	toUserID := to.UserID
+	if to.Login != "" {
+		acc, err := svc.store.GetAccount(ctx, to.Login)
+		if err != nil {
+			if errors.Is(err, model.ErrNotFound) {
+				return "", fmt.Errorf("%w: unknown login %q", ErrInvalidUser, to.Login)
+			}
+			return "", errors.Join(ErrStorageError, err)
+		}
+		toUserID = acc.UserID
+	} else if _, err := user.NewFromUserID(toUserID); err != nil {
+		return "", errors.Join(ErrInvalidUser, err)
+	}
+	if toUserID == fromUserID {
+		return "", fmt.Errorf("%w: links cannot be transferred to their owner", ErrInvalidUser)
+	}
+	return toUserID, nil
+}
+
+// checkUser checks that user can transfer links. Transfers are managed only within
+// existing session, api keys cannot be used to manage them.
+func checkUser(u *user.User) error {
+	if u.IsNew() || u.IsAPIKey() {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// prepareShorts removes duplicates from list of short keys and checks its size.
+func prepareShorts(shorts []string) ([]string, error) {
+	shorts = slices.Clone(shorts)
+	slices.Sort(shorts)
+	shorts = slices.Compact(shorts)
+	if len(shorts) == 0 || shorts[0] == "" {
+		return nil, fmt.Errorf("%w: no links provided", ErrInvalidURLs)
+	}
+	if len(shorts) > maxTransferURLs {
+		return nil, fmt.Errorf("%w: up to %d links can be transferred at once", ErrTooManyURLs, maxTransferURLs)
+	}
+	return shorts, nil
+}
+
+func randomID() (string, error) {
+	b := make([]byte, idBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("cannot generate id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func newTransfer(t *model.Transfer) *Transfer {
+	return &Transfer{
+		Created: t.Created,
+		ID:      t.ID,
+		From:    t.FromUserID,
+		To:      t.ToUserID,
+		Shorts:  t.Shorts,
+	}
+}
+
+func newEvent(e *model.AuditEvent) *Event {
+	return &Event{
+		Created:  e.Created,
+		ID:       e.ID,
+		Action:   e.Action,
+		Actor:    e.ActorID,
+		Transfer: e.TransferID,
+		From:     e.FromUserID,
+		To:       e.ToUserID,
+		Shorts:   e.Shorts,
+	}
+}
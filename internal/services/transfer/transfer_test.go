@@ -0,0 +1,149 @@
+package transfer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/services/quota"
+	"github.com/adwski/shorty/internal/storage/memory"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestService_Transfers(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	svc := New(&Config{Storage: store, Logger: zap.NewNop()})
+	var (
+		alice = existingUser(t)
+		bob   = existingUser(t)
+		carol = existingUser(t)
+	)
+	require.NoError(t, store.CreateAccount(ctx, &model.Account{Login: "bob", UserID: bob.ID}))
+	for _, url := range []*model.URL{
+		{Short: "aaa", Orig: "https://aaa.bbb/1", UserID: alice.ID},
+		{Short: "bbb", Orig: "https://aaa.bbb/2", UserID: alice.ID},
+		{Short: "ccc", Orig: "https://aaa.bbb/3", UserID: alice.ID},
+		{Short: "ddd", Orig: "https://aaa.bbb/4", UserID: carol.ID},
+		{Short: "eee", Orig: "https://aaa.bbb/5", UserID: alice.ID, WorkspaceID: "ws"},
+	} {
+		_, err := store.Store(ctx, url, false)
+		require.NoError(t, err)
+	}
+
+	// only own personal links are offered
+	for _, shorts := range [][]string{nil, {""}, {"aaa", "ddd"}, {"aaa", "eee"}, {"zzz"}} {
+		_, err := svc.Offer(ctx, alice, &Recipient{Login: "bob"}, shorts)
+		assert.ErrorIs(t, err, ErrInvalidURLs, shorts)
+	}
+	_, err := svc.Offer(ctx, alice, &Recipient{UserID: alice.ID}, []string{"aaa"})
+	assert.ErrorIs(t, err, ErrInvalidUser)
+	_, err = svc.Offer(ctx, alice, &Recipient{Login: "nobody"}, []string{"aaa"})
+	assert.ErrorIs(t, err, ErrInvalidUser)
+
+	offer, err := svc.Offer(ctx, alice, &Recipient{Login: "bob"}, []string{"bbb", "aaa", "bbb"})
+	require.NoError(t, err)
+	assert.Equal(t, bob.ID, offer.To)
+	assert.Equal(t, []string{"aaa", "bbb"}, offer.Shorts)
+	for _, u := range []*user.User{alice, bob} {
+		transfers, errL := svc.List(ctx, u)
+		require.NoError(t, errL)
+		require.Len(t, transfers, 1)
+		assert.Equal(t, offer.ID, transfers[0].ID)
+	}
+
+	// only recipient accepts offer, links change owner
+	_, err = svc.Accept(ctx, alice, offer.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	event, err := svc.Accept(ctx, bob, offer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.AuditTransferAccepted, event.Action)
+	assert.Equal(t, offer.ID, event.Transfer)
+	for _, short := range []string{"aaa", "bbb"} {
+		url, errG := store.Get(ctx, short)
+		require.NoError(t, errG)
+		assert.Equal(t, bob.ID, url.UserID)
+	}
+	_, err = svc.Accept(ctx, bob, offer.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// offer fails if links changed owner after it was made, nothing is transferred
+	offer, err = svc.Offer(ctx, alice, &Recipient{UserID: carol.ID}, []string{"ccc"})
+	require.NoError(t, err)
+	declined, err := svc.Offer(ctx, alice, &Recipient{UserID: carol.ID}, []string{"ccc"})
+	require.NoError(t, err)
+	_, err = svc.Force(ctx, alice.ID, &Recipient{Login: "bob"}, nil)
+	require.NoError(t, err)
+	_, err = svc.Accept(ctx, carol, offer.ID)
+	assert.ErrorIs(t, err, ErrInvalidURLs)
+	url, err := store.Get(ctx, "ccc")
+	require.NoError(t, err)
+	assert.Equal(t, bob.ID, url.UserID)
+
+	assert.ErrorIs(t, svc.Decline(ctx, bob, declined.ID), ErrNotFound)
+	require.NoError(t, svc.Decline(ctx, carol, declined.ID))
+	require.NoError(t, svc.Decline(ctx, alice, offer.ID))
+	_, err = svc.Force(ctx, alice.ID, &Recipient{Login: "bob"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidURLs)
+
+	// every transfer is audited
+	events, err := svc.Audit(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	actions := []string{events[0].Action, events[1].Action}
+	assert.ElementsMatch(t, []string{model.AuditTransferAccepted, model.AuditTransferForced}, actions)
+	_, err = svc.Audit(ctx, -1)
+	assert.ErrorIs(t, err, ErrInvalidAuditLimit)
+}
+
+func TestService_TransferQuota(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	svc := New(&Config{
+		Storage: store,
+		Logger:  zap.NewNop(),
+		Quotas: quota.New(&quota.Config{
+			Storage:     store,
+			Logger:      zap.NewNop(),
+			DefaultTier: "free",
+			Tiers:       []model.Tier{{Name: "free", MaxURLs: 2}},
+		}),
+	})
+	alice, bob := existingUser(t), existingUser(t)
+	for _, url := range []*model.URL{
+		{Short: "aaa", Orig: "https://aaa.bbb/1", UserID: alice.ID},
+		{Short: "bbb", Orig: "https://aaa.bbb/2", UserID: alice.ID},
+		{Short: "ccc", Orig: "https://aaa.bbb/3", UserID: bob.ID},
+	} {
+		_, err := store.Store(ctx, url, false)
+		require.NoError(t, err)
+	}
+
+	// recipient cannot accept more links than quota allows
+	offer, err := svc.Offer(ctx, alice, &Recipient{UserID: bob.ID}, []string{"aaa", "bbb"})
+	require.NoError(t, err)
+	_, err = svc.Accept(ctx, bob, offer.ID)
+	assert.Equal(t, &quota.ExceededError{Quota: quota.QuotaURLs, Limit: 2, Requested: 2, Remaining: 1}, err)
+	url, err := store.Get(ctx, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, url.UserID)
+
+	offer, err = svc.Offer(ctx, alice, &Recipient{UserID: bob.ID}, []string{"aaa"})
+	require.NoError(t, err)
+	_, err = svc.Accept(ctx, bob, offer.ID)
+	require.NoError(t, err)
+
+	// administrator is not limited by quota
+	_, err = svc.Force(ctx, alice.ID, &Recipient{UserID: bob.ID}, nil)
+	require.NoError(t, err)
+}
+
+func existingUser(t *testing.T) *user.User {
+	t.Helper()
+	u, err := user.New()
+	require.NoError(t, err)
+	return user.NewWithID(u.ID)
+}
@@ -0,0 +1,335 @@
+// Package workspace is workspace service.
+// Workspace holds links shared by its members. Members have roles: viewers can list workspace links
+// and read their statistics, editors can also create, edit and delete links, owners can also manage members.
+// Permissions on workspace links are checked by shortener service.
+package workspace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+	"go.uber.org/zap"
+)
+
+const (
+	idBytes          = 8
+	maxNameLength    = 100
+	maxUserWorkspace = 100
+)
+
+// Service errors.
+var (
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrForbidden         = errors.New("forbidden")
+	ErrNotFound          = errors.New("workspace not found")
+	ErrMemberNotFound    = errors.New("member not found")
+	ErrInvalidName       = errors.New("invalid workspace name")
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrInvalidMember     = errors.New("invalid member")
+	ErrLastOwner         = errors.New("workspace must have an owner")
+	ErrTooManyWorkspaces = errors.New("too many workspaces")
+	ErrStorageError      = errors.New("storage error")
+)
+
+// Storage is workspace storage.
+type Storage interface {
+	CreateWorkspace(ctx context.Context, ws *model.Workspace, ownerID string) error
+	GetWorkspace(ctx context.Context, id string) (*model.Workspace, error)
+	ListWorkspaces(ctx context.Context, userID string) ([]*model.Workspace, error)
+	GetMember(ctx context.Context, workspaceID, userID string) (*model.Member, error)
+	ListMembers(ctx context.Context, workspaceID string) ([]*model.Member, error)
+	SetMember(ctx context.Context, member *model.Member) error
+	DeleteMember(ctx context.Context, workspaceID, userID string) error
+	GetAccount(ctx context.Context, login string) (*model.Account, error)
+}
+
+// Service is workspace service.
+type Service struct {
+	store Storage
+	log   *zap.Logger
+}
+
+// Config is workspace service config.
+type Config struct {
+	Storage Storage
+	Logger  *zap.Logger
+}
+
+// Workspace describes workspace. Role is role of user who requested workspace,
+// members are set only when single workspace is requested.
+type Workspace struct {
+	Created time.Time `json:"created"`
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Role    string    `json:"role"`
+	Members []*Member `json:"members,omitempty"`
+}
+
+// Member describes workspace member.
+type Member struct {
+	Added  time.Time `json:"added"`
+	UserID string    `json:"user_id"`
+	Role   string    `json:"role"`
+}
+
+// MemberUpdate identifies user by id or account login and sets its role.
+type MemberUpdate struct {
+	UserID string
+	Login  string
+	Role   string
+}
+
+// New creates workspace service.
+func New(cfg *Config) *Service {
+	return &Service{
+		store: cfg.Storage,
+		log:   cfg.Logger.With(zap.String("component", "workspace")),
+	}
+}
+
+// Create creates workspace, user becomes its owner.
+func (svc *Service) Create(ctx context.Context, u *user.User, name string) (*Workspace, error) {
+	if err := checkUser(u); err != nil {
+		return nil, err
+	}
+	name, err := prepareName(name)
+	if err != nil {
+		return nil, err
+	}
+	workspaces, err := svc.store.ListWorkspaces(ctx, u.ID)
+	if err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	if len(workspaces) >= maxUserWorkspace {
+		return nil, ErrTooManyWorkspaces
+	}
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	ws := &model.Workspace{
+		Created: time.Now().UTC().Truncate(time.Second),
+		ID:      id,
+		Name:    name,
+	}
+	if err = svc.store.CreateWorkspace(ctx, ws, u.ID); err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	svc.log.Debug("workspace created",
+		zap.String("userID", u.ID),
+		zap.String("workspaceID", id))
+	return &Workspace{
+		Created: ws.Created,
+		ID:      ws.ID,
+		Name:    ws.Name,
+		Role:    model.RoleOwner,
+		Members: []*Member{{Added: ws.Created, UserID: u.ID, Role: model.RoleOwner}},
+	}, nil
+}
+
+// List returns workspaces user is member of.
+func (svc *Service) List(ctx context.Context, u *user.User) ([]*Workspace, error) {
+	if err := checkUser(u); err != nil {
+		return nil, err
+	}
+	workspaces, err := svc.store.ListWorkspaces(ctx, u.ID)
+	if err != nil {
+		return nil, errors.Join(ErrStorageError, err)
+	}
+	result := make([]*Workspace, 0, len(workspaces))
+	for _, ws := range workspaces {
+		result = append(result, &Workspace{
+			Created: ws.Created,
+			ID:      ws.ID,
+			Name:    ws.Name,
+			Role:    ws.Role,
+		})
+	}
+	return result, nil
+}
+
+// Get returns workspace with its members, user must be workspace member.
+func (svc *Service) Get(ctx context.Context, u *user.User, id string) (*Workspace, error) {
+	if err := checkUser(u); err != nil {
+		return nil, err
+	}
+	member, err := svc.checkRole(ctx, u, id, model.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := svc.store.GetWorkspace(ctx, id)
+	if err != nil {
+		return nil, storageError(err, ErrNotFound)
+	}
+	members, err := svc.store.ListMembers(ctx, id)
+	if err != nil {
+		return nil, storageError(err, ErrNotFound)
+	}
+	result := &Workspace{
+		Created: ws.Created,
+		ID:      ws.ID,
+		Name:    ws.Name,
+		Role:    member.Role,
+		Members: make([]*Member, 0, len(members)),
+	}
+	for _, m := range members {
+		result.Members = append(result.Members, newMember(m))
+	}
+	return result, nil
+}
+
+// SetMember adds user to workspace or changes role of existing member, user must be workspace owner.
+// Member is identified by account login if it's set, or by user id otherwise.
+func (svc *Service) SetMember(ctx context.Context, u *user.User, id string, update *MemberUpdate) (*Member, error) {
+	if err := checkUser(u); err != nil {
+		return nil, err
+	}
+	if !model.IsValidRole(update.Role) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRole, update.Role)
+	}
+	if _, err := svc.checkRole(ctx, u, id, model.RoleOwner); err != nil {
+		return nil, err
+	}
+	userID, err := svc.resolveMember(ctx, update)
+	if err != nil {
+		return nil, err
+	}
+	member := &model.Member{
+		Created:     time.Now().UTC().Truncate(time.Second),
+		WorkspaceID: id,
+		UserID:      userID,
+		Role:        update.Role,
+	}
+	if err = svc.store.SetMember(ctx, member); err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			return nil, ErrLastOwner
+		}
+		return nil, storageError(err, ErrNotFound)
+	}
+	svc.log.Debug("workspace member set",
+		zap.String("userID", u.ID),
+		zap.String("workspaceID", id),
+		zap.String("memberID", userID),
+		zap.String("role", update.Role))
+	// member may have existed before, so actual membership is returned
+	if member, err = svc.store.GetMember(ctx, id, userID); err != nil {
+		return nil, storageError(err, ErrMemberNotFound)
+	}
+	return newMember(member), nil
+}
+
+// RemoveMember removes member from workspace. Owners can remove any member,
+// other members can only leave workspace. Last owner cannot be removed.
+func (svc *Service) RemoveMember(ctx context.Context, u *user.User, id, memberID string) error {
+	if err := checkUser(u); err != nil {
+		return err
+	}
+	role := model.RoleOwner
+	if memberID == u.ID {
+		role = model.RoleViewer
+	}
+	if _, err := svc.checkRole(ctx, u, id, role); err != nil {
+		return err
+	}
+	if err := svc.store.DeleteMember(ctx, id, memberID); err != nil {
+		if errors.Is(err, model.ErrConflict) {
+			return ErrLastOwner
+		}
+		return storageError(err, ErrMemberNotFound)
+	}
+	svc.log.Debug("workspace member removed",
+		zap.String("userID", u.ID),
+		zap.String("workspaceID", id),
+		zap.String("memberID", memberID))
+	return nil
+}
+
+// checkRole checks that user has required role in workspace and returns its membership.
+// Workspace existence is not revealed to users who are not its members.
+func (svc *Service) checkRole(ctx context.Context, u *user.User, id, role string) (*model.Member, error) {
+	member, err := svc.store.GetMember(ctx, id, u.ID)
+	if err != nil {
+		return nil, storageError(err, ErrNotFound)
+	}
+	if !model.RoleAllows(member.Role, role) {
+		return nil, ErrForbidden
+	}
+	return member, nil
+}
+
+// resolveMember returns user id of member.
+func (svc *Service) resolveMember(ctx context.Context, update *MemberUpdate) (string, error) {
+	if update.Login == "" {
+		if _, err := user.NewFromUserID(update.UserID); err != nil {
+			return "", errors.Join(ErrInvalidMember, err)
+		}
+		return update.UserID, nil
+	}
+	acc, err := svc.store.GetAccount(ctx, update.Login)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return "", fmt.Errorf("%w: unknown login %q", ErrInvalidMember, update.Login)
+		}
+		return "", errors.Join(ErrStorageError, err)
+	}
+	return acc.UserID, nil
+}
+
+// checkUser checks that user can manage workspaces. Workspaces are managed only within
+// existing session, api keys cannot be used to manage them.
+func checkUser(u *user.User) error {
+	if u.IsNew() || u.IsAPIKey() {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// prepareName validates workspace name and trims surrounding spaces.
+func prepareName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: name is empty", ErrInvalidName)
+	}
+	if !utf8.ValidString(name) || utf8.RuneCountInString(name) > maxNameLength {
+		return "", fmt.Errorf("%w: name is not valid utf-8 or longer than %d characters", ErrInvalidName, maxNameLength)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return "", fmt.Errorf("%w: invalid character in name: %q", ErrInvalidName, r)
+		}
+	}
+	return name, nil
+}
+
+// storageError maps storage not found error to service error.
+func storageError(err, notFound error) error {
+	if errors.Is(err, model.ErrNotFound) {
+		return notFound
+	}
+	return errors.Join(ErrStorageError, err)
+}
+
+func randomID() (string, error) {
+	b := make([]byte, idBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("cannot generate workspace id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func newMember(m *model.Member) *Member {
+	return &Member{
+		Added:  m.Created,
+		UserID: m.UserID,
+		Role:   m.Role,
+	}
+}
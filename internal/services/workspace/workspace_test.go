@@ -0,0 +1,86 @@
+package workspace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/storage/memory"
+	"github.com/adwski/shorty/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestService_Members(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	svc := New(&Config{Storage: store, Logger: zap.NewNop()})
+	var (
+		owner  = existingUser(t)
+		editor = existingUser(t)
+		viewer = existingUser(t)
+	)
+	require.NoError(t, store.CreateAccount(ctx, &model.Account{Login: "viewer", UserID: viewer.ID}))
+
+	newUser, err := user.New()
+	require.NoError(t, err)
+	_, err = svc.Create(ctx, newUser, "Marketing")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = svc.Create(ctx, owner, " \t")
+	assert.ErrorIs(t, err, ErrInvalidName)
+
+	ws, err := svc.Create(ctx, owner, " Marketing ")
+	require.NoError(t, err)
+	assert.Equal(t, "Marketing", ws.Name)
+	assert.Equal(t, model.RoleOwner, ws.Role)
+
+	// members are added by user id or login
+	_, err = svc.SetMember(ctx, owner, ws.ID, &MemberUpdate{UserID: editor.ID, Role: "admin"})
+	assert.ErrorIs(t, err, ErrInvalidRole)
+	_, err = svc.SetMember(ctx, owner, ws.ID, &MemberUpdate{Login: "nobody", Role: model.RoleViewer})
+	assert.ErrorIs(t, err, ErrInvalidMember)
+	member, err := svc.SetMember(ctx, owner, ws.ID, &MemberUpdate{UserID: editor.ID, Role: model.RoleEditor})
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleEditor, member.Role)
+	member, err = svc.SetMember(ctx, owner, ws.ID, &MemberUpdate{Login: "viewer", Role: model.RoleViewer})
+	require.NoError(t, err)
+	assert.Equal(t, viewer.ID, member.UserID)
+
+	// only owners manage members, non-members do not see workspace
+	_, err = svc.SetMember(ctx, editor, ws.ID, &MemberUpdate{UserID: editor.ID, Role: model.RoleOwner})
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = svc.Get(ctx, existingUser(t), ws.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	got, err := svc.Get(ctx, viewer, ws.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleViewer, got.Role)
+	assert.Len(t, got.Members, 3)
+	list, err := svc.List(ctx, editor)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, ws.ID, list[0].ID)
+	assert.Equal(t, model.RoleEditor, list[0].Role)
+
+	// last owner cannot leave or be demoted
+	_, err = svc.SetMember(ctx, owner, ws.ID, &MemberUpdate{UserID: owner.ID, Role: model.RoleEditor})
+	assert.ErrorIs(t, err, ErrLastOwner)
+	assert.ErrorIs(t, svc.RemoveMember(ctx, owner, ws.ID, owner.ID), ErrLastOwner)
+
+	// members can leave, only owners remove others
+	assert.ErrorIs(t, svc.RemoveMember(ctx, editor, ws.ID, viewer.ID), ErrForbidden)
+	require.NoError(t, svc.RemoveMember(ctx, viewer, ws.ID, viewer.ID))
+	require.NoError(t, svc.RemoveMember(ctx, owner, ws.ID, editor.ID))
+	assert.ErrorIs(t, svc.RemoveMember(ctx, owner, ws.ID, editor.ID), ErrMemberNotFound)
+	list, err = svc.List(ctx, editor)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func existingUser(t *testing.T) *user.User {
+	t.Helper()
+	u, err := user.New()
+	require.NoError(t, err)
+	return user.NewWithID(u.ID)
+}
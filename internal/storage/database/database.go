@@ -9,6 +9,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"sync/atomic"
 	"time"
 
 	"github.com/adwski/shorty/internal/model"
@@ -22,6 +24,41 @@ import (
 const (
 	urlsIndexHash = "urls_hash"
 	urlsIndexOrig = "urls_orig_key"
+
+	// plainURLCondition matches links that are unique by original url, see urls_orig_key index.
+	// Protected links and links with options that change redirect behaviour are never deduplicated.
+	plainURLCondition = `deleted = false and password_hash = '' and redirect = 0 and passthrough = false ` +
+		`and preview = false and coalesce(targets, 'null') in ('null', '[]') ` +
+		`and coalesce(variants, 'null') in ('null', '[]') and coalesce(schedule, 'null') = 'null'`
+
+	queryInsertURL = `insert into urls(hash, orig, userid, redirect, passthrough, targets, variants, schedule, ` +
+		`password_hash, title, preview, tags, notes, ts, workspace_id) ` +
+		`values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, coalesce($14, current_timestamp), $15)`
+
+	// restoreBatchSize is number of urls stored with single batch during loading.
+	restoreBatchSize = 1000
+
+	// queryScanURLs selects all urls with click counters of their variants.
+	queryScanURLs = `select hash, uuid::text, orig, userid, deleted, redirect, passthrough, targets, variants, ` +
+		`schedule, password_hash, title, preview, tags, notes, ts, workspace_id, ` +
+		`array(select variant from variant_clicks c where c.hash = urls.hash order by variant), ` +
+		`array(select clicks from variant_clicks c where c.hash = urls.hash order by variant) ` +
+		`from urls where hash collate "C" > $1 order by hash collate "C"`
+
+	queryRestoreURL = `insert into urls(hash, uuid, orig, userid, deleted, redirect, passthrough, targets, variants, ` +
+		`schedule, password_hash, title, preview, tags, notes, ts, workspace_id) ` +
+		`values ($1, coalesce(nullif($2, '')::uuid, gen_random_uuid()), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, ` +
+		`$13, $14, $15, $16, $17) ` +
+		`on conflict (hash) do update set uuid = excluded.uuid, orig = excluded.orig, userid = excluded.userid, ` +
+		`deleted = excluded.deleted, redirect = excluded.redirect, passthrough = excluded.passthrough, ` +
+		`targets = excluded.targets, variants = excluded.variants, schedule = excluded.schedule, ` +
+		`password_hash = excluded.password_hash, title = excluded.title, preview = excluded.preview, ` +
+		`tags = excluded.tags, notes = excluded.notes, ts = excluded.ts, workspace_id = excluded.workspace_id`
+
+	// queryListURLs selects not deleted urls, owner condition must be appended.
+	queryListURLs = `select hash, orig, userid, redirect, passthrough, targets, variants, schedule, password_hash, ` +
+		`title, preview, tags, notes, ts, workspace_id from urls where deleted = false ` +
+		`and ($1 = '' or tags @> array[$1]) `
 )
 
 // Database is a relational database storage connector.
@@ -31,6 +68,9 @@ type Database struct {
 	log         *zap.Logger
 	dsn         string
 	doMigration bool
+
+	// rateLimitSweep is unix time of last removal of full rate limit buckets.
+	rateLimitSweep atomic.Int64
 }
 
 // Close closes pgx connection pool.
@@ -61,6 +101,8 @@ func (db *Database) Stats(ctx context.Context) (*model.Stats, error) {
 }
 
 // Store stores url in database. Overwrite flag controls if already stored url can be overwritten if hash is the same.
+// Plain urls are unique by original url, if such url is already stored, its hash is returned with model.ErrConflict.
+// Urls with password or options that change redirect are always stored as new records.
 func (db *Database) Store(ctx context.Context, url *model.URL, overwrite bool) (string, error) {
 	if overwrite {
 		// we could not do it in one query here
@@ -69,8 +111,10 @@ func (db *Database) Store(ctx context.Context, url *model.URL, overwrite bool) (
 	}
 
 	// insert new url
-	query := `insert into urls(hash, orig, userid) values ($1,$2,$3)`
-	tag, err := db.pool.Exec(ctx, query, url.Short, url.Orig, url.UserID)
+	tag, err := db.pool.Exec(ctx, queryInsertURL,
+		url.Short, url.Orig, url.UserID, url.Redirect, url.Passthrough,
+		url.Targets, url.Variants, url.Schedule, url.PasswordHash, url.Title, url.Preview, url.Tags, url.Notes,
+		createdParam(url.Created), url.WorkspaceID)
 	if err == nil {
 		if tag.RowsAffected() != 1 {
 			return "", fmt.Errorf("affected rows: %d, expected: 1", tag.RowsAffected())
@@ -104,7 +148,10 @@ func (db *Database) StoreBatch(ctx context.Context, urls []model.URL) error {
 		// There's an upper limit for number of queries that can be bundled in single batch,
 		// but it depends on a particular setup.
 		// https://youtu.be/sXMSWhcHCf8?t=33m55s
-		batch.Queue(`insert into urls(hash, orig, userid) values ($1, $2, $3)`, url.Short, url.Orig, url.UserID)
+		batch.Queue(queryInsertURL,
+			url.Short, url.Orig, url.UserID, url.Redirect, url.Passthrough,
+			url.Targets, url.Variants, url.Schedule, url.PasswordHash, url.Title, url.Preview, url.Tags, url.Notes,
+			createdParam(url.Created), url.WorkspaceID)
 	}
 
 	if err := db.pool.SendBatch(ctx, batch).Close(); err != nil {
@@ -121,38 +168,71 @@ func (db *Database) StoreBatch(ctx context.Context, urls []model.URL) error {
 }
 
 // Get retrieves stored url by its hash.
-func (db *Database) Get(ctx context.Context, hash string) (string, error) {
+func (db *Database) Get(ctx context.Context, hash string) (*model.URL, error) {
 	var (
-		url     string
+		url     = model.URL{Short: hash}
+		created *time.Time
 		deleted bool
 	)
-	err := db.pool.QueryRow(ctx, `select orig, deleted from urls where hash = $1`, hash).Scan(&url, &deleted)
-	if err != nil && errors.Is(err, pgx.ErrNoRows) {
-		return "", model.ErrNotFound
+	query := `select orig, userid, redirect, passthrough, targets, variants, schedule, password_hash, ` +
+		`title, preview, tags, notes, ts, deleted, workspace_id from urls where hash = $1`
+	err := db.pool.QueryRow(ctx, query, hash).
+		Scan(&url.Orig, &url.UserID, &url.Redirect, &url.Passthrough,
+			&url.Targets, &url.Variants, &url.Schedule, &url.PasswordHash,
+			&url.Title, &url.Preview, &url.Tags, &url.Notes, &created, &deleted, &url.WorkspaceID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrNotFound
+		}
+		return nil, fmt.Errorf("postgres error: %w", err)
 	}
 	if deleted {
-		return "", model.ErrDeleted
+		return nil, model.ErrDeleted
 	}
-	return url, nil
+	if created != nil {
+		url.Created = *created
+	}
+	return &url, nil
 }
 
-// ListUserURLs retrieves all urls that have specified user ID.
-func (db *Database) ListUserURLs(ctx context.Context, userID string) ([]*model.URL, error) {
-	query := `select hash, orig from urls where userid = $1 and deleted = false`
-	rows, err := db.pool.Query(ctx, query, userID)
+// ListUserURLs retrieves all personal urls that have specified user ID.
+// If tag is not empty, only urls labeled with tag are returned.
+func (db *Database) ListUserURLs(ctx context.Context, userID, tag string) ([]*model.URL, error) {
+	db.log.Debug("listing urls for user",
+		zap.String("userID", userID))
+	return db.listURLs(ctx, queryListURLs+`and workspace_id = '' and userid = $2`, tag, userID)
+}
+
+// ListWorkspaceURLs retrieves all urls of workspace.
+// If tag is not empty, only urls labeled with tag are returned.
+func (db *Database) ListWorkspaceURLs(ctx context.Context, workspaceID, tag string) ([]*model.URL, error) {
+	db.log.Debug("listing urls for workspace",
+		zap.String("workspaceID", workspaceID))
+	return db.listURLs(ctx, queryListURLs+`and workspace_id = $2`, tag, workspaceID)
+}
+
+func (db *Database) listURLs(ctx context.Context, query, tag, owner string) ([]*model.URL, error) {
+	rows, err := db.pool.Query(ctx, query, tag, owner)
 	if err != nil && errors.Is(err, pgx.ErrNoRows) {
 		err = model.ErrNotFound
 		return nil, err
 	}
-	db.log.Debug("listing urls for user",
-		zap.String("userID", userID))
 	// Use generic CollectRows()
 	// https://youtu.be/sXMSWhcHCf8?t=995
 	urls, errR := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.URL, error) {
-		var url model.URL
-		if errS := row.Scan(&url.Short, &url.Orig); errS != nil {
+		var (
+			url     model.URL
+			created *time.Time
+		)
+		errS := row.Scan(&url.Short, &url.Orig, &url.UserID, &url.Redirect, &url.Passthrough,
+			&url.Targets, &url.Variants, &url.Schedule, &url.PasswordHash, &url.Title, &url.Preview,
+			&url.Tags, &url.Notes, &created, &url.WorkspaceID)
+		if errS != nil {
 			return nil, fmt.Errorf("error while scanning row: %w", errS)
 		}
+		if created != nil {
+			url.Created = *created
+		}
 		return &url, nil
 	})
 	if errR != nil {
@@ -164,8 +244,128 @@ func (db *Database) ListUserURLs(ctx context.Context, userID string) ([]*model.U
 	return urls, nil
 }
 
+// UpdateMeta updates title, tags and notes of user or workspace url.
+func (db *Database) UpdateMeta(ctx context.Context, url *model.URL) error {
+	tag, err := db.pool.Exec(ctx, `update urls set title = $3, tags = $4, notes = $5 `+
+		`where hash = $1 and deleted = false and workspace_id = $6 and ($6 != '' or userid = $2)`,
+		url.Short, url.UserID, url.Title, url.Tags, url.Notes, url.WorkspaceID)
+	if err != nil {
+		return fmt.Errorf("postgres error: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+// PurgeDeleted permanently removes soft deleted urls and returns their number.
+func (db *Database) PurgeDeleted(ctx context.Context) (int64, error) {
+	tag, err := db.pool.Exec(ctx, `delete from urls where deleted = true`)
+	if err != nil {
+		return 0, fmt.Errorf("postgres error: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ScanURLs calls fn for every url including deleted ones with hash greater than after.
+// Urls are passed in byte order of hashes along with variant click counters.
+// Urls are read in repeatable read transaction, so they're consistent as of the moment of call.
+func (db *Database) ScanURLs(ctx context.Context, after string, fn func(url *model.URL) error) error {
+	tx, err := db.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	rows, err := tx.Query(ctx, queryScanURLs, after)
+	if err != nil {
+		return fmt.Errorf("postgres error: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		url, errS := scanURL(rows)
+		if errS != nil {
+			return errS
+		}
+		if err = fn(&url); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error while reading rows: %w", err)
+	}
+	return nil
+}
+
+// LoadURLs reads urls from next until it returns io.EOF and stores them as is,
+// keeping their uuids, owners, deleted flags, creation time and variant click counters.
+// Unknown creation time is stored as null. Urls with the same hash are overwritten.
+// If replace is set, all other urls and their click counters are removed.
+// Urls are loaded in single transaction, so tables are changed only if all urls are read and stored.
+func (db *Database) LoadURLs(ctx context.Context, next func() (*model.URL, error), replace bool) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if replace {
+		if _, err = tx.Exec(ctx, `delete from variant_clicks`); err != nil {
+			return fmt.Errorf("postgres error: %w", err)
+		}
+		if _, err = tx.Exec(ctx, `delete from urls`); err != nil {
+			return fmt.Errorf("postgres error: %w", err)
+		}
+	}
+	var (
+		batch = &pgx.Batch{}
+		num   int
+	)
+	for {
+		url, errN := next()
+		if errN != nil && !errors.Is(errN, io.EOF) {
+			return errN
+		}
+		if errN == nil {
+			queueLoadURL(batch, url)
+			num++
+		}
+		if num > 0 && (errN != nil || num == restoreBatchSize) {
+			if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+				var pgErr *pgconn.PgError
+				if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+					return errors.Join(model.ErrConflict, err)
+				}
+				return fmt.Errorf("pgx batch restore error: %w", err)
+			}
+			batch, num = &pgx.Batch{}, 0
+		}
+		if errN != nil {
+			break
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("cannot commit transaction: %w", err)
+	}
+	return nil
+}
+
+// queueLoadURL queues queries storing url as is and replacing its click counters.
+func queueLoadURL(batch *pgx.Batch, url *model.URL) {
+	batch.Queue(queryRestoreURL,
+		url.Short, url.UUID, url.Orig, url.UserID, url.Deleted, url.Redirect, url.Passthrough,
+		url.Targets, url.Variants, url.Schedule, url.PasswordHash, url.Title, url.Preview, url.Tags, url.Notes,
+		createdParam(url.Created), url.WorkspaceID)
+	batch.Queue(`delete from variant_clicks where hash = $1`, url.Short)
+	for variant, clicks := range url.Clicks {
+		if clicks != 0 {
+			batch.Queue(`insert into variant_clicks(hash, variant, clicks) values ($1, $2, $3)`,
+				url.Short, variant, clicks)
+		}
+	}
+}
+
 // DeleteUserURLs deletes list of urls using batch query. It performs soft delete, i.e. not actually deleting
-// records from db but just marks them as "deleted".
+// records from db but just marks them as "deleted". Urls with workspace id are deleted from workspace,
+// other urls are deleted only if they're personal urls of user.
 func (db *Database) DeleteUserURLs(ctx context.Context, urls []model.URL) (int64, error) {
 	var (
 		batch    = &pgx.Batch{}
@@ -187,8 +387,10 @@ func (db *Database) DeleteUserURLs(ctx context.Context, urls []model.URL) (int64
 			zap.String("orig", url.Orig),
 			zap.Int64("ts", ts))
 
-		batch.Queue(`update urls set deleted = true where hash = $1 and userid = $2 and ts < to_timestamp($3 / 1000000.0)`,
-			url.Short, url.UserID, ts).Exec(func(ct pgconn.CommandTag) error {
+		batch.Queue(`update urls set deleted = true where hash = $1 `+
+			`and workspace_id = $4 and ($4 != '' or userid = $2) `+
+			`and (ts is null or ts < to_timestamp($3 / 1000000.0))`,
+			url.Short, url.UserID, ts, url.WorkspaceID).Exec(func(ct pgconn.CommandTag) error {
 			affected += ct.RowsAffected()
 			return nil
 		})
@@ -199,8 +401,641 @@ func (db *Database) DeleteUserURLs(ctx context.Context, urls []model.URL) (int64
 	return affected, nil
 }
 
+// ClaimUserURLs transfers active personal urls of one user to another user.
+// Workspace urls keep their creator and deleted urls stay with former owner.
+func (db *Database) ClaimUserURLs(ctx context.Context, fromUserID, toUserID string) (int64, error) {
+	tag, err := db.pool.Exec(ctx,
+		`update urls set userid = $2 where userid = $1 and workspace_id = '' and deleted = false`, fromUserID, toUserID)
+	if err != nil {
+		return 0, fmt.Errorf("postgres error: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// CreateAccount stores new user account. Logins and user ids of accounts are unique.
+func (db *Database) CreateAccount(ctx context.Context, acc *model.Account) error {
+	_, err := db.pool.Exec(ctx, `insert into accounts(userid, login, password_hash) values ($1, $2, $3)`,
+		acc.UserID, acc.Login, acc.PasswordHash)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return model.ErrAlreadyExists
+		}
+		return fmt.Errorf("postgres error: %w", err)
+	}
+	return nil
+}
+
+// GetAccount retrieves user account by login.
+func (db *Database) GetAccount(ctx context.Context, login string) (*model.Account, error) {
+	acc := model.Account{Login: login}
+	err := db.pool.QueryRow(ctx, `select userid, password_hash, ts from accounts where login = $1`, login).
+		Scan(&acc.UserID, &acc.PasswordHash, &acc.Created)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrNotFound
+		}
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	return &acc, nil
+}
+
+// CreateIdentity stores link of external identity to user. Subjects are unique within issuer.
+func (db *Database) CreateIdentity(ctx context.Context, identity *model.Identity) error {
+	_, err := db.pool.Exec(ctx, `insert into identities(issuer, subject, userid, email) values ($1, $2, $3, $4)`,
+		identity.Issuer, identity.Subject, identity.UserID, identity.Email)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return model.ErrAlreadyExists
+		}
+		return fmt.Errorf("postgres error: %w", err)
+	}
+	return nil
+}
+
+// GetIdentity retrieves external identity by issuer and subject.
+func (db *Database) GetIdentity(ctx context.Context, issuer, subject string) (*model.Identity, error) {
+	identity := model.Identity{Issuer: issuer, Subject: subject}
+	err := db.pool.QueryRow(ctx, `select userid, email, ts from identities where issuer = $1 and subject = $2`,
+		issuer, subject).Scan(&identity.UserID, &identity.Email, &identity.Created)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrNotFound
+		}
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	return &identity, nil
+}
+
+// CreateWorkspace stores new workspace with its owner. Workspace ids are unique.
+func (db *Database) CreateWorkspace(ctx context.Context, ws *model.Workspace, ownerID string) error {
+	batch := &pgx.Batch{} // implicit BEGIN and COMMIT
+	batch.Queue(`insert into workspaces(id, name) values ($1, $2)`, ws.ID, ws.Name)
+	batch.Queue(`insert into workspace_members(workspace_id, userid, role) values ($1, $2, $3)`,
+		ws.ID, ownerID, model.RoleOwner)
+	if err := db.pool.SendBatch(ctx, batch).Close(); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return model.ErrAlreadyExists
+		}
+		return fmt.Errorf("pgx batch error: %w", err)
+	}
+	return nil
+}
+
+// GetWorkspace retrieves workspace by id.
+func (db *Database) GetWorkspace(ctx context.Context, id string) (*model.Workspace, error) {
+	ws := model.Workspace{ID: id}
+	err := db.pool.QueryRow(ctx, `select name, ts from workspaces where id = $1`, id).Scan(&ws.Name, &ws.Created)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrNotFound
+		}
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	return &ws, nil
+}
+
+// ListWorkspaces returns workspaces user is member of ordered by creation time.
+// Role of user is set in every workspace.
+func (db *Database) ListWorkspaces(ctx context.Context, userID string) ([]*model.Workspace, error) {
+	rows, err := db.pool.Query(ctx, `select w.id, w.name, w.ts, m.role from workspaces w `+
+		`join workspace_members m on m.workspace_id = w.id where m.userid = $1 order by w.ts, w.id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	defer rows.Close()
+	var workspaces []*model.Workspace
+	for rows.Next() {
+		ws := &model.Workspace{}
+		if err = rows.Scan(&ws.ID, &ws.Name, &ws.Created, &ws.Role); err != nil {
+			return nil, fmt.Errorf("cannot scan workspace: %w", err)
+		}
+		workspaces = append(workspaces, ws)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	return workspaces, nil
+}
+
+// GetMember retrieves membership of user in workspace.
+func (db *Database) GetMember(ctx context.Context, workspaceID, userID string) (*model.Member, error) {
+	member := model.Member{WorkspaceID: workspaceID, UserID: userID}
+	err := db.pool.QueryRow(ctx, `select role, ts from workspace_members where workspace_id = $1 and userid = $2`,
+		workspaceID, userID).Scan(&member.Role, &member.Created)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrNotFound
+		}
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	return &member, nil
+}
+
+// ListMembers returns members of workspace in order they were added.
+func (db *Database) ListMembers(ctx context.Context, workspaceID string) ([]*model.Member, error) {
+	if _, err := db.GetWorkspace(ctx, workspaceID); err != nil {
+		return nil, err
+	}
+	rows, err := db.pool.Query(ctx, `select userid, role, ts from workspace_members `+
+		`where workspace_id = $1 order by ts, userid`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	defer rows.Close()
+	var members []*model.Member
+	for rows.Next() {
+		member := &model.Member{WorkspaceID: workspaceID}
+		if err = rows.Scan(&member.UserID, &member.Role, &member.Created); err != nil {
+			return nil, fmt.Errorf("cannot scan workspace member: %w", err)
+		}
+		members = append(members, member)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	return members, nil
+}
+
+// SetMember adds member to workspace or changes role of existing member.
+// Workspace must keep at least one owner, otherwise ErrConflict is returned.
+func (db *Database) SetMember(ctx context.Context, member *model.Member) error {
+	return db.updateMembers(ctx, member.WorkspaceID, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `insert into workspace_members(workspace_id, userid, role) values ($1, $2, $3) `+
+			`on conflict (workspace_id, userid) do update set role = excluded.role`,
+			member.WorkspaceID, member.UserID, member.Role)
+		if err != nil {
+			return fmt.Errorf("postgres error: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteMember removes member from workspace.
+// Workspace must keep at least one owner, otherwise ErrConflict is returned.
+func (db *Database) DeleteMember(ctx context.Context, workspaceID, userID string) error {
+	return db.updateMembers(ctx, workspaceID, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `delete from workspace_members where workspace_id = $1 and userid = $2`,
+			workspaceID, userID)
+		if err != nil {
+			return fmt.Errorf("postgres error: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return model.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// updateMembers changes workspace members in transaction. Workspace row is locked,
+// so concurrent updates cannot remove all owners.
+func (db *Database) updateMembers(ctx context.Context, workspaceID string, update func(tx pgx.Tx) error) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err = tx.QueryRow(ctx, `select id from workspaces where id = $1 for update`, workspaceID).
+		Scan(&workspaceID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.ErrNotFound
+		}
+		return fmt.Errorf("postgres error: %w", err)
+	}
+	if err = update(tx); err != nil {
+		return err
+	}
+	var owners int
+	if err = tx.QueryRow(ctx, `select count(*) from workspace_members where workspace_id = $1 and role = $2`,
+		workspaceID, model.RoleOwner).Scan(&owners); err != nil {
+		return fmt.Errorf("postgres error: %w", err)
+	}
+	if owners == 0 {
+		return model.ErrConflict
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("cannot commit transaction: %w", err)
+	}
+	return nil
+}
+
+// CreateTransfer stores new transfer offer. Transfer ids are unique.
+func (db *Database) CreateTransfer(ctx context.Context, transfer *model.Transfer) error {
+	_, err := db.pool.Exec(ctx, `insert into transfers(id, from_userid, to_userid, shorts) values ($1, $2, $3, $4)`,
+		transfer.ID, transfer.FromUserID, transfer.ToUserID, transfer.Shorts)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return model.ErrAlreadyExists
+		}
+		return fmt.Errorf("postgres error: %w", err)
+	}
+	return nil
+}
+
+// GetTransfer retrieves transfer offer by id.
+func (db *Database) GetTransfer(ctx context.Context, id string) (*model.Transfer, error) {
+	transfer := model.Transfer{ID: id}
+	err := db.pool.QueryRow(ctx, `select from_userid, to_userid, shorts, ts from transfers where id = $1`, id).
+		Scan(&transfer.FromUserID, &transfer.ToUserID, &transfer.Shorts, &transfer.Created)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrNotFound
+		}
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	return &transfer, nil
+}
+
+// ListTransfers returns transfer offers sent or received by user ordered by creation time.
+func (db *Database) ListTransfers(ctx context.Context, userID string) ([]*model.Transfer, error) {
+	rows, err := db.pool.Query(ctx, `select id, from_userid, to_userid, shorts, ts from transfers `+
+		`where from_userid = $1 or to_userid = $1 order by ts, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	defer rows.Close()
+	var transfers []*model.Transfer
+	for rows.Next() {
+		transfer := &model.Transfer{}
+		if err = rows.Scan(&transfer.ID, &transfer.FromUserID, &transfer.ToUserID,
+			&transfer.Shorts, &transfer.Created); err != nil {
+			return nil, fmt.Errorf("cannot scan transfer: %w", err)
+		}
+		transfers = append(transfers, transfer)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	return transfers, nil
+}
+
+// DeleteTransfer deletes transfer offer.
+func (db *Database) DeleteTransfer(ctx context.Context, id string) error {
+	tag, err := db.pool.Exec(ctx, `delete from transfers where id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("postgres error: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+// TransferURLs atomically changes owner of personal URLs listed in audit event and records event.
+// Every URL must be active personal URL of previous owner, otherwise ErrConflict is returned
+// and nothing is changed. If event has transfer id, transfer offer is consumed.
+func (db *Database) TransferURLs(ctx context.Context, event *model.AuditEvent) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if event.TransferID != "" {
+		tag, errT := tx.Exec(ctx, `delete from transfers where id = $1`, event.TransferID)
+		if errT != nil {
+			return fmt.Errorf("postgres error: %w", errT)
+		}
+		if tag.RowsAffected() == 0 {
+			return model.ErrNotFound
+		}
+	}
+	tag, err := tx.Exec(ctx, `update urls set userid = $2 where hash = any($3) `+
+		`and userid = $1 and workspace_id = '' and deleted = false`,
+		event.FromUserID, event.ToUserID, event.Shorts)
+	if err != nil {
+		return fmt.Errorf("postgres error: %w", err)
+	}
+	if tag.RowsAffected() != int64(len(event.Shorts)) {
+		return model.ErrConflict
+	}
+	if _, err = tx.Exec(ctx, `insert into audit_events(id, action, actor, transfer_id, from_userid, to_userid, shorts) `+
+		`values ($1, $2, $3, $4, $5, $6, $7)`,
+		event.ID, event.Action, event.ActorID, event.TransferID, event.FromUserID, event.ToUserID, event.Shorts); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return model.ErrAlreadyExists
+		}
+		return fmt.Errorf("postgres error: %w", err)
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("cannot commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEvents returns up to limit latest audit events, newest first.
+func (db *Database) ListAuditEvents(ctx context.Context, limit int) ([]*model.AuditEvent, error) {
+	rows, err := db.pool.Query(ctx, `select id, action, actor, transfer_id, from_userid, to_userid, shorts, ts `+
+		`from audit_events order by ts desc, id desc limit $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	defer rows.Close()
+	var events []*model.AuditEvent
+	for rows.Next() {
+		event := &model.AuditEvent{}
+		if err = rows.Scan(&event.ID, &event.Action, &event.ActorID, &event.TransferID,
+			&event.FromUserID, &event.ToUserID, &event.Shorts, &event.Created); err != nil {
+			return nil, fmt.Errorf("cannot scan audit event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	return events, nil
+}
+
+// CountUserURLs returns number of active personal URLs of user.
+func (db *Database) CountUserURLs(ctx context.Context, userID string) (int, error) {
+	var count int
+	if err := db.pool.QueryRow(ctx, `select count(*) from urls `+
+		`where deleted = false and workspace_id = '' and userid = $1`, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("postgres error: %w", err)
+	}
+	return count, nil
+}
+
+// GetQuota retrieves quota assignment of user.
+func (db *Database) GetQuota(ctx context.Context, userID string) (*model.Quota, error) {
+	quota := model.Quota{UserID: userID}
+	err := db.pool.QueryRow(ctx, `select tier, max_urls, max_batch, ts from user_quotas where userid = $1`, userID).
+		Scan(&quota.Tier, &quota.MaxURLs, &quota.MaxBatch, &quota.Updated)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrNotFound
+		}
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	return &quota, nil
+}
+
+// SetQuota creates or replaces quota assignment of user.
+func (db *Database) SetQuota(ctx context.Context, quota *model.Quota) error {
+	_, err := db.pool.Exec(ctx, `insert into user_quotas(userid, tier, max_urls, max_batch) values ($1, $2, $3, $4) `+
+		`on conflict (userid) do update set tier = excluded.tier, max_urls = excluded.max_urls, `+
+		`max_batch = excluded.max_batch, ts = current_timestamp`,
+		quota.UserID, quota.Tier, quota.MaxURLs, quota.MaxBatch)
+	if err != nil {
+		return fmt.Errorf("postgres error: %w", err)
+	}
+	return nil
+}
+
+// DeleteQuota deletes quota assignment of user.
+func (db *Database) DeleteQuota(ctx context.Context, userID string) error {
+	tag, err := db.pool.Exec(ctx, `delete from user_quotas where userid = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("postgres error: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+// CreateAPIKey stores new api key. Key ids are unique.
+func (db *Database) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	_, err := db.pool.Exec(ctx, `insert into api_keys(id, userid, name, secret_hash, scopes) `+
+		`values ($1, $2, $3, $4, $5)`,
+		key.ID, key.UserID, key.Name, key.SecretHash, key.Scopes)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return model.ErrAlreadyExists
+		}
+		return fmt.Errorf("postgres error: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKey retrieves api key by id.
+func (db *Database) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	key := model.APIKey{ID: id}
+	err := db.pool.QueryRow(ctx, `select userid, name, secret_hash, scopes, ts from api_keys where id = $1`, id).
+		Scan(&key.UserID, &key.Name, &key.SecretHash, &key.Scopes, &key.Created)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrNotFound
+		}
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	return &key, nil
+}
+
+// ListAPIKeys returns api keys of user ordered by creation time.
+func (db *Database) ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	rows, err := db.pool.Query(ctx, `select id, name, secret_hash, scopes, ts from api_keys `+
+		`where userid = $1 order by ts, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	defer rows.Close()
+	var keys []*model.APIKey
+	for rows.Next() {
+		key := &model.APIKey{UserID: userID}
+		if err = rows.Scan(&key.ID, &key.Name, &key.SecretHash, &key.Scopes, &key.Created); err != nil {
+			return nil, fmt.Errorf("cannot scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	return keys, nil
+}
+
+// UpdateAPIKey updates name and scopes of user api key.
+func (db *Database) UpdateAPIKey(ctx context.Context, key *model.APIKey) error {
+	tag, err := db.pool.Exec(ctx, `update api_keys set name = $3, scopes = $4 where id = $1 and userid = $2`,
+		key.ID, key.UserID, key.Name, key.Scopes)
+	if err != nil {
+		return fmt.Errorf("postgres error: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteAPIKey deletes user api key.
+func (db *Database) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	tag, err := db.pool.Exec(ctx, `delete from api_keys where id = $1 and userid = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("postgres error: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+// RevokeToken adds session token id to denylist until token expires.
+// Expired records are removed.
+func (db *Database) RevokeToken(ctx context.Context, id string, expires time.Time) error {
+	batch := &pgx.Batch{}
+	batch.Queue(`delete from revoked_tokens where expires < current_timestamp`)
+	batch.Queue(`insert into revoked_tokens(id, expires) values ($1, $2) `+
+		`on conflict (id) do update set expires = greatest(revoked_tokens.expires, excluded.expires)`,
+		id, expires)
+	if err := db.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("pgx batch revoke error: %w", err)
+	}
+	return nil
+}
+
+// IsTokenRevoked returns whether session token id is in denylist.
+func (db *Database) IsTokenRevoked(ctx context.Context, id string) (bool, error) {
+	var revoked bool
+	err := db.pool.QueryRow(ctx, `select exists(select 1 from revoked_tokens `+
+		`where id = $1 and expires >= current_timestamp)`, id).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("postgres error: %w", err)
+	}
+	return revoked, nil
+}
+
+// TakeToken takes token from rate limit bucket with specified key. Bucket is updated
+// with single conditional upsert, so it can be shared by several instances.
+// It returns zero duration if request is allowed, otherwise time after which request will be allowed.
+func (db *Database) TakeToken(
+	ctx context.Context,
+	key string,
+	limit *model.RateLimit,
+	now time.Time,
+) (time.Duration, error) {
+	db.sweepRateLimits(ctx, now)
+	var tat int64
+	err := db.pool.QueryRow(ctx, `insert into rate_limits(key, tat) values ($1, $2::bigint + $3) `+
+		`on conflict (key) do update set tat = greatest(rate_limits.tat, $2) + $3 `+
+		`where greatest(rate_limits.tat, $2) + $3 - $2 <= $4 returning tat`,
+		key, now.UnixNano(), limit.Interval().Nanoseconds(), limit.Tolerance().Nanoseconds()).Scan(&tat)
+	if err == nil {
+		return 0, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("postgres error: %w", err)
+	}
+	// request is not allowed, get bucket state to find out wait time
+	if err = db.pool.QueryRow(ctx, `select tat from rate_limits where key = $1`, key).Scan(&tat); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return limit.Interval(), nil
+		}
+		return 0, fmt.Errorf("postgres error: %w", err)
+	}
+	if _, wait := limit.Take(time.Unix(0, tat), now); wait > 0 {
+		return wait, nil
+	}
+	return limit.Interval(), nil
+}
+
+// sweepRateLimits removes full rate limit buckets, it's done at most once a minute.
+func (db *Database) sweepRateLimits(ctx context.Context, now time.Time) {
+	last := db.rateLimitSweep.Load()
+	if now.Unix()-last < int64(time.Minute/time.Second) || !db.rateLimitSweep.CompareAndSwap(last, now.Unix()) {
+		return
+	}
+	if _, err := db.pool.Exec(ctx, `delete from rate_limits where tat <= $1`, now.UnixNano()); err != nil {
+		db.log.Error("cannot remove full rate limit buckets", zap.Error(err))
+	}
+}
+
+// AddVariantClicks increments click counters of URL variants.
+// Clicks are aggregated before sending, so each counter is updated once per call.
+func (db *Database) AddVariantClicks(ctx context.Context, clicks []model.Click) error {
+	counters := make(map[model.Click]int64, len(clicks))
+	for _, click := range clicks {
+		counters[click]++
+	}
+	batch := &pgx.Batch{}
+	for click, num := range counters {
+		batch.Queue(`insert into variant_clicks(hash, variant, clicks) values ($1, $2, $3) `+
+			`on conflict (hash, variant) do update set clicks = variant_clicks.clicks + excluded.clicks`,
+			click.Short, click.Variant, num)
+	}
+	if err := db.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("pgx batch clicks error: %w", err)
+	}
+	return nil
+}
+
+// GetVariantClicks returns click counters of URL variants.
+func (db *Database) GetVariantClicks(ctx context.Context, hash string) ([]int64, error) {
+	var variants []model.Variant
+	err := db.pool.QueryRow(ctx, `select variants from urls where hash = $1`, hash).Scan(&variants)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrNotFound
+		}
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	clicks := make([]int64, len(variants))
+	rows, err := db.pool.Query(ctx, `select variant, clicks from variant_clicks where hash = $1`, hash)
+	if err != nil {
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			variant int
+			num     int64
+		)
+		if err = rows.Scan(&variant, &num); err != nil {
+			return nil, fmt.Errorf("error while scanning row: %w", err)
+		}
+		if variant >= 0 && variant < len(clicks) {
+			clicks[variant] = num
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("postgres error: %w", err)
+	}
+	return clicks, nil
+}
+
+// scanURL scans row selected with queryScanURLs.
+func scanURL(row pgx.Row) (model.URL, error) {
+	var (
+		url      model.URL
+		created  *time.Time
+		variants []int16
+		clicks   []int64
+	)
+	err := row.Scan(&url.Short, &url.UUID, &url.Orig, &url.UserID, &url.Deleted, &url.Redirect,
+		&url.Passthrough, &url.Targets, &url.Variants, &url.Schedule, &url.PasswordHash,
+		&url.Title, &url.Preview, &url.Tags, &url.Notes, &created, &url.WorkspaceID, &variants, &clicks)
+	if err != nil {
+		return url, fmt.Errorf("error while scanning row: %w", err)
+	}
+	if created != nil {
+		url.Created = *created
+	}
+	if len(variants) > 0 {
+		url.Clicks = make([]int64, len(url.Variants))
+		for i, variant := range variants {
+			if variant >= 0 && int(variant) < len(url.Clicks) {
+				url.Clicks[variant] = clicks[i]
+			}
+		}
+	}
+	return url, nil
+}
+
+// createdParam returns creation time query param, nil means current time.
+// Column has no time zone, so time is always passed in UTC.
+func createdParam(created time.Time) *time.Time {
+	if created.IsZero() {
+		return nil
+	}
+	created = created.UTC()
+	return &created
+}
+
 func (db *Database) getHashByURL(ctx context.Context, url string) (hash string, err error) {
-	err = db.pool.QueryRow(ctx, `select hash from urls where orig = $1 and deleted = false`, url).Scan(&hash)
+	err = db.pool.QueryRow(ctx, `select hash from urls where orig = $1 and `+plainURLCondition, url).Scan(&hash)
 	if err != nil && errors.Is(err, pgx.ErrNoRows) {
 		err = model.ErrNotFound
 	}
@@ -215,7 +1050,7 @@ func (db *Database) storeWithOverwrite(ctx context.Context, url *model.URL) (str
 		}
 		// some other error
 		return "", err
-	} else if storedURL == url.Orig {
+	} else if storedURL.Orig == url.Orig {
 		// stored orig value is the same, no need to update
 		return "", nil
 	}
@@ -224,7 +1059,7 @@ func (db *Database) storeWithOverwrite(ctx context.Context, url *model.URL) (str
 }
 
 func (db *Database) updateOrig(ctx context.Context, url *model.URL) error {
-	query := "update urls set hash = $1 where orig = $2"
+	query := "update urls set hash = $1 where orig = $2 and " + plainURLCondition
 	tag, err := db.pool.Exec(ctx, query, url.Short, url.Orig)
 	if err != nil {
 		return fmt.Errorf("database update error: %w", err)
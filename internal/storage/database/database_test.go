@@ -4,9 +4,12 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -115,7 +118,7 @@ func TestDatabase_Get(t *testing.T) {
 			url, errU := db.Get(ctx, tt.args.get)
 			require.Equal(t, tt.want.err, errU)
 			if tt.want.err == nil {
-				assert.Equal(t, tt.want.url, url)
+				assert.Equal(t, tt.want.url, url.Orig)
 			}
 
 			// clean up
@@ -225,6 +228,70 @@ func TestDatabase_Store(t *testing.T) {
 	}
 }
 
+func TestDatabase_StoreSameOrigWithOptions(t *testing.T) {
+	ctx := context.Background()
+	const orig = "http://testopts.test/doc"
+
+	// protected link is never returned for plain one
+	_, err := db.Store(ctx, &model.URL{Short: "testopts1", Orig: orig, UserID: "testuser", PasswordHash: "hash"}, false)
+	require.NoError(t, err)
+	_, err = db.Store(ctx, &model.URL{Short: "testopts2", Orig: orig, UserID: "testuser2"}, false)
+	require.NoError(t, err)
+
+	// links with password or options are stored separately from plain one
+	var (
+		variants = []model.Variant{{URL: "http://v.test", Weight: 1}}
+		targets  = []model.Target{{Platform: "ios", URL: "http://t.test"}}
+	)
+	for _, url := range []model.URL{
+		{Short: "testopts3", Orig: orig, UserID: "testuser", PasswordHash: "hash"},
+		{Short: "testopts4", Orig: orig, UserID: "testuser", Variants: variants},
+		{Short: "testopts5", Orig: orig, UserID: "testuser", Targets: targets},
+		{Short: "testopts6", Orig: orig, UserID: "testuser", Preview: true},
+	} {
+		hash, errS := db.Store(ctx, &url, false)
+		require.NoError(t, errS, url.Short)
+		assert.Empty(t, hash)
+		stored, errG := db.Get(ctx, url.Short)
+		require.NoError(t, errG)
+		assert.Equal(t, url.PasswordHash, stored.PasswordHash)
+		assert.Equal(t, url.Preview, stored.Preview)
+	}
+
+	// plain links are still deduplicated
+	hash, err := db.Store(ctx, &model.URL{Short: "testopts7", Orig: orig, UserID: "testuser3"}, false)
+	assert.Equal(t, model.ErrConflict, err)
+	assert.Equal(t, "testopts2", hash)
+
+	cleanUpTestHashes(ctx, t, db.pool)
+}
+
+func TestDatabase_ClaimUserURLs(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, db.StoreBatch(ctx, []model.URL{
+		{Short: "testclaim1", Orig: "http://testclaim.test/1", UserID: "testanon"},
+		{Short: "testclaim2", Orig: "http://testclaim.test/2", UserID: "testanon", WorkspaceID: "testws"},
+		{Short: "testclaim3", Orig: "http://testclaim.test/3", UserID: "testanon"},
+	}))
+	_, err := db.DeleteUserURLs(ctx, []model.URL{{Short: "testclaim3", UserID: "testanon", TS: time.Now().UnixMicro()}})
+	require.NoError(t, err)
+
+	num, err := db.ClaimUserURLs(ctx, "testanon", "testaccount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), num)
+	for short, owner := range map[string]string{
+		"testclaim1": "testaccount",
+		"testclaim2": "testanon",
+		"testclaim3": "testanon",
+	} {
+		var userID string
+		require.NoError(t, db.pool.QueryRow(ctx, `select userid from urls where hash = $1`, short).Scan(&userID))
+		assert.Equal(t, owner, userID, short)
+	}
+
+	cleanUpTestHashes(ctx, t, db.pool)
+}
+
 func TestDatabase_StoreBatch(t *testing.T) {
 	type args struct {
 		urlInDB *model.URL
@@ -306,6 +373,7 @@ func TestDatabase_ListUserURLs(t *testing.T) {
 	type args struct {
 		urlsInDB []model.URL
 		userID   string
+		tag      string
 	}
 	type want struct {
 		err    error
@@ -338,6 +406,36 @@ func TestDatabase_ListUserURLs(t *testing.T) {
 				hashes: []string{"test456"},
 			},
 		},
+		{
+			name: "list urls by tag",
+			args: args{
+				urlsInDB: []model.URL{
+					{
+						Short:  "test456",
+						Orig:   "http://test456.test456/test456",
+						UserID: "testuser",
+						Tags:   []string{"promo", "spring"},
+					},
+					{
+						Short:  "test789",
+						Orig:   "http://test789.test789/test789",
+						UserID: "testuser",
+						Tags:   []string{"docs"},
+					},
+					{
+						Short:  "test012",
+						Orig:   "http://test012.test012/test012",
+						UserID: "testuser",
+					},
+				},
+				userID: "testuser",
+				tag:    "spring",
+			},
+			want: want{
+				err:    nil,
+				hashes: []string{"test456"},
+			},
+		},
 		{
 			name: "empty urls",
 			args: args{
@@ -366,14 +464,14 @@ func TestDatabase_ListUserURLs(t *testing.T) {
 
 			// prepare data
 			for _, u := range tt.args.urlsInDB {
-				tag, errT := db.pool.Exec(ctx, "insert into urls (hash, orig, userid) values ($1, $2, $3)",
-					u.Short, u.Orig, u.UserID)
+				tag, errT := db.pool.Exec(ctx, "insert into urls (hash, orig, userid, tags) values ($1, $2, $3, $4)",
+					u.Short, u.Orig, u.UserID, u.Tags)
 				require.NoError(t, errT)
 				require.Equal(t, int64(1), tag.RowsAffected())
 			}
 
 			// test
-			urls, err := db.ListUserURLs(ctx, tt.args.userID)
+			urls, err := db.ListUserURLs(ctx, tt.args.userID, tt.args.tag)
 			require.Equal(t, tt.want.err, err)
 
 			if tt.want.err == nil {
@@ -499,9 +597,9 @@ func TestDatabase_DeleteUserURLs(t *testing.T) {
 			require.Equal(t, tt.want.affected, affected)
 
 			for _, url := range tt.want.urlsRemain {
-				orig, errU := db.Get(ctx, url.Short)
+				stored, errU := db.Get(ctx, url.Short)
 				require.NoError(t, errU)
-				assert.Equal(t, url.Orig, orig)
+				assert.Equal(t, url.Orig, stored.Orig)
 			}
 
 			// clean up
@@ -510,8 +608,362 @@ func TestDatabase_DeleteUserURLs(t *testing.T) {
 	}
 }
 
+func TestDatabase_ScanAndLoad(t *testing.T) {
+	ctx := context.Background()
+	created := time.Date(2024, 3, 1, 10, 20, 30, 0, time.UTC)
+	urls := []model.URL{
+		{
+			Short:   "testscan2",
+			Orig:    "http://testscan2.test/2",
+			UserID:  "testuser",
+			UUID:    "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			Deleted: true,
+			Created: created,
+		},
+		{
+			Short:    "testscan1",
+			Orig:     "http://testscan1.test/1",
+			UserID:   "testuser2",
+			Tags:     []string{"a"},
+			Variants: []model.Variant{{URL: "http://v1.test", Weight: 1}, {URL: "http://v2.test", Weight: 1}},
+			Clicks:   []int64{0, 7},
+		},
+	}
+	require.NoError(t, db.LoadURLs(ctx, urlReader(urls), false))
+
+	// overwrite keeps single record
+	urls[1].Title = "title"
+	require.NoError(t, db.LoadURLs(ctx, urlReader(urls[1:]), false))
+
+	found := scanTestURLs(ctx, t, "testscan")
+	require.Len(t, found, 2)
+	assert.Equal(t, "testscan1", found[0].Short)
+	assert.Equal(t, "title", found[0].Title)
+	assert.NotEmpty(t, found[0].UUID)
+	assert.True(t, found[0].Created.IsZero())
+	assert.Equal(t, []int64{0, 7}, found[0].Clicks)
+	assert.Equal(t, urls[0].UUID, found[1].UUID)
+	assert.True(t, found[1].Deleted)
+	assert.Equal(t, created, found[1].Created)
+	assert.Empty(t, found[1].Clicks)
+
+	_, err := db.Get(ctx, "testscan2")
+	assert.ErrorIs(t, err, model.ErrDeleted)
+	clicks, err := db.GetVariantClicks(ctx, "testscan1")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0, 7}, clicks)
+
+	// scan starts after cursor
+	found = scanTestURLs(ctx, t, "testscan1")
+	require.Len(t, found, 1)
+	assert.Equal(t, "testscan2", found[0].Short)
+
+	cleanUpTestHashes(ctx, t, db.pool)
+}
+
+func TestDatabase_LoadURLs(t *testing.T) {
+	ctx := context.Background()
+	variants := []model.Variant{{URL: "http://v1.test", Weight: 1}}
+	require.NoError(t, db.LoadURLs(ctx, urlReader([]model.URL{
+		{Short: "testsnap1", Orig: "http://testsnap1.test/1", UserID: "testuser", Variants: variants, Clicks: []int64{2}},
+		{Short: "testsnap2", Orig: "http://testsnap2.test/2", UserID: "testuser", Deleted: true},
+	}), false))
+
+	snapshot := scanTestURLs(ctx, t, "testsnap")
+	require.Len(t, snapshot, 2)
+
+	// failed load does not change table
+	i := 0
+	err := db.LoadURLs(ctx, func() (*model.URL, error) {
+		if i == 1 {
+			return nil, errors.New("read error")
+		}
+		i++
+		return &model.URL{Short: "testsnap3", Orig: "http://testsnap3.test/3", UserID: "testuser"}, nil
+	}, false)
+	require.Error(t, err)
+	_, err = db.Get(ctx, "testsnap3")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	// loaded click counters replace existing ones
+	snapshot[0].Title = "restored"
+	require.NoError(t, db.AddVariantClicks(ctx, []model.Click{{Short: "testsnap1", Variant: 0}}))
+	require.NoError(t, db.LoadURLs(ctx, urlReader(snapshot), false))
+	url, err := db.Get(ctx, "testsnap1")
+	require.NoError(t, err)
+	assert.Equal(t, "restored", url.Title)
+	clicks, err := db.GetVariantClicks(ctx, "testsnap1")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2}, clicks)
+	_, err = db.Get(ctx, "testsnap2")
+	assert.ErrorIs(t, err, model.ErrDeleted)
+
+	cleanUpTestHashes(ctx, t, db.pool)
+}
+
+func urlReader(urls []model.URL) func() (*model.URL, error) {
+	var i int
+	return func() (*model.URL, error) {
+		if i == len(urls) {
+			return nil, io.EOF
+		}
+		i++
+		return &urls[i-1], nil
+	}
+}
+
+func scanTestURLs(ctx context.Context, t *testing.T, after string) []model.URL {
+	t.Helper()
+	var urls []model.URL
+	require.NoError(t, db.ScanURLs(ctx, after, func(url *model.URL) error {
+		if strings.HasPrefix(url.Short, "test") {
+			urls = append(urls, *url)
+		}
+		return nil
+	}))
+	return urls
+}
+
+func TestDatabase_APIKeys(t *testing.T) {
+	ctx := context.Background()
+	key := &model.APIKey{
+		ID:         "testkey1",
+		UserID:     "testuser",
+		Name:       "ci",
+		SecretHash: "hash",
+		Scopes:     []string{"read"},
+	}
+	require.NoError(t, db.CreateAPIKey(ctx, key))
+	t.Cleanup(func() {
+		_, err := db.pool.Exec(ctx, "delete from api_keys where id like 'test%'")
+		require.NoError(t, err)
+	})
+	assert.ErrorIs(t, db.CreateAPIKey(ctx, key), model.ErrAlreadyExists)
+
+	key.Name = "deploy"
+	key.Scopes = []string{"read", "shorten"}
+	require.NoError(t, db.UpdateAPIKey(ctx, key))
+	got, err := db.GetAPIKey(ctx, key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "deploy", got.Name)
+	assert.Equal(t, key.Scopes, got.Scopes)
+
+	keys, err := db.ListAPIKeys(ctx, "testuser")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, key.ID, keys[0].ID)
+
+	assert.ErrorIs(t, db.DeleteAPIKey(ctx, "otheruser", key.ID), model.ErrNotFound)
+	require.NoError(t, db.DeleteAPIKey(ctx, "testuser", key.ID))
+	_, err = db.GetAPIKey(ctx, key.ID)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestDatabase_Identities(t *testing.T) {
+	ctx := context.Background()
+	identity := &model.Identity{
+		Issuer:  "https://idp.test",
+		Subject: "testsubject",
+		UserID:  "testuser",
+		Email:   "alice@example.com",
+	}
+	require.NoError(t, db.CreateIdentity(ctx, identity))
+	t.Cleanup(func() {
+		_, err := db.pool.Exec(ctx, "delete from identities where subject like 'test%'")
+		require.NoError(t, err)
+	})
+	assert.ErrorIs(t, db.CreateIdentity(ctx, identity), model.ErrAlreadyExists)
+
+	got, err := db.GetIdentity(ctx, identity.Issuer, identity.Subject)
+	require.NoError(t, err)
+	assert.Equal(t, identity.UserID, got.UserID)
+	assert.Equal(t, identity.Email, got.Email)
+
+	_, err = db.GetIdentity(ctx, "https://other.test", identity.Subject)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestDatabase_Workspaces(t *testing.T) {
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.pool.Exec(ctx, "delete from workspaces where id like 'test%'")
+		require.NoError(t, err)
+		cleanUpTestHashes(ctx, t, db.pool)
+	})
+	require.NoError(t, db.CreateWorkspace(ctx, &model.Workspace{ID: "testws", Name: "Marketing"}, "testowner"))
+	assert.ErrorIs(t, db.CreateWorkspace(ctx, &model.Workspace{ID: "testws"}, "testowner"), model.ErrAlreadyExists)
+	require.NoError(t, db.SetMember(ctx,
+		&model.Member{WorkspaceID: "testws", UserID: "testeditor", Role: model.RoleEditor}))
+	assert.ErrorIs(t, db.SetMember(ctx, &model.Member{WorkspaceID: "testzz", UserID: "testeditor"}), model.ErrNotFound)
+
+	workspaces, err := db.ListWorkspaces(ctx, "testeditor")
+	require.NoError(t, err)
+	require.Len(t, workspaces, 1)
+	assert.Equal(t, "Marketing", workspaces[0].Name)
+	assert.Equal(t, model.RoleEditor, workspaces[0].Role)
+	members, err := db.ListMembers(ctx, "testws")
+	require.NoError(t, err)
+	assert.Len(t, members, 2)
+
+	// last owner cannot be demoted or removed
+	assert.ErrorIs(t, db.SetMember(ctx, &model.Member{WorkspaceID: "testws", UserID: "testowner", Role: model.RoleViewer}),
+		model.ErrConflict)
+	assert.ErrorIs(t, db.DeleteMember(ctx, "testws", "testowner"), model.ErrConflict)
+	require.NoError(t, db.DeleteMember(ctx, "testws", "testeditor"))
+	_, err = db.GetMember(ctx, "testws", "testeditor")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	// workspace links are managed within workspace, not by creator
+	_, err = db.Store(ctx, &model.URL{Short: "testws1", Orig: "https://test.ws/1", UserID: "testowner",
+		WorkspaceID: "testws"}, false)
+	require.NoError(t, err)
+	urls, err := db.ListWorkspaceURLs(ctx, "testws", "")
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "testowner", urls[0].UserID)
+	_, err = db.ListUserURLs(ctx, "testowner", "")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	err = db.UpdateMeta(ctx, &model.URL{Short: "testws1", UserID: "testowner", Title: "Docs"})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	require.NoError(t, db.UpdateMeta(ctx, &model.URL{Short: "testws1", UserID: "testother", WorkspaceID: "testws"}))
+	num, err := db.DeleteUserURLs(ctx, []model.URL{{Short: "testws1", UserID: "testowner"}})
+	require.NoError(t, err)
+	assert.Zero(t, num)
+	num, err = db.DeleteUserURLs(ctx, []model.URL{{Short: "testws1", UserID: "testother", WorkspaceID: "testws"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), num)
+}
+
+func TestDatabase_Transfers(t *testing.T) {
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.pool.Exec(ctx, "delete from transfers where id like 'test%'")
+		require.NoError(t, err)
+		_, err = db.pool.Exec(ctx, "delete from audit_events where id like 'test%'")
+		require.NoError(t, err)
+		cleanUpTestHashes(ctx, t, db.pool)
+	})
+	for _, u := range []*model.URL{
+		{Short: "testtr1", Orig: "https://test.tr/1", UserID: "testfrom"},
+		{Short: "testtr2", Orig: "https://test.tr/2", UserID: "testfrom", WorkspaceID: "testws"},
+	} {
+		_, err := db.Store(ctx, u, false)
+		require.NoError(t, err)
+	}
+	transfer := &model.Transfer{ID: "testt1", FromUserID: "testfrom", ToUserID: "testto", Shorts: []string{"testtr1"}}
+	require.NoError(t, db.CreateTransfer(ctx, transfer))
+	assert.ErrorIs(t, db.CreateTransfer(ctx, transfer), model.ErrAlreadyExists)
+	transfers, err := db.ListTransfers(ctx, "testto")
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	assert.Equal(t, []string{"testtr1"}, transfers[0].Shorts)
+
+	// workspace links cannot be transferred, nothing is changed
+	event := &model.AuditEvent{ID: "teste1", Action: model.AuditTransferAccepted, TransferID: "testt1",
+		FromUserID: "testfrom", ToUserID: "testto", Shorts: []string{"testtr1", "testtr2"}}
+	assert.ErrorIs(t, db.TransferURLs(ctx, event), model.ErrConflict)
+	_, err = db.GetTransfer(ctx, "testt1")
+	require.NoError(t, err)
+
+	event.Shorts = []string{"testtr1"}
+	require.NoError(t, db.TransferURLs(ctx, event))
+	url, err := db.Get(ctx, "testtr1")
+	require.NoError(t, err)
+	assert.Equal(t, "testto", url.UserID)
+	_, err = db.GetTransfer(ctx, "testt1")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	events, err := db.ListAuditEvents(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "teste1", events[0].ID)
+}
+
+func TestDatabase_RevokedTokens(t *testing.T) {
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.pool.Exec(ctx, "delete from revoked_tokens where id like 'test%'")
+		require.NoError(t, err)
+	})
+	revoked, err := db.IsTokenRevoked(ctx, "testtoken1")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, db.RevokeToken(ctx, "testtoken1", time.Now().Add(time.Hour)))
+	require.NoError(t, db.RevokeToken(ctx, "testtoken1", time.Now().Add(time.Minute)))
+	require.NoError(t, db.RevokeToken(ctx, "testtoken2", time.Now().Add(-time.Hour)))
+
+	revoked, err = db.IsTokenRevoked(ctx, "testtoken1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = db.IsTokenRevoked(ctx, "testtoken2")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestDatabase_TakeToken(t *testing.T) {
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.pool.Exec(ctx, "delete from rate_limits where key like 'test%'")
+		require.NoError(t, err)
+	})
+	var (
+		limit = &model.RateLimit{Period: time.Minute, Requests: 2, Burst: 3}
+		now   = time.Now()
+	)
+	for i := 0; i < 3; i++ {
+		wait, err := db.TakeToken(ctx, "testclient", limit, now)
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+	}
+	wait, err := db.TakeToken(ctx, "testclient", limit, now.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 20*time.Second, wait)
+	wait, err = db.TakeToken(ctx, "testclient", limit, now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+}
+
+func TestDatabase_Quotas(t *testing.T) {
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, err := db.pool.Exec(ctx, "delete from user_quotas where userid like 'test%'")
+		require.NoError(t, err)
+		cleanUpTestHashes(ctx, t, db.pool)
+	})
+	for _, u := range []*model.URL{
+		{Short: "testq1", Orig: "https://test.q/1", UserID: "testquser"},
+		{Short: "testq2", Orig: "https://test.q/2", UserID: "testquser"},
+		{Short: "testq3", Orig: "https://test.q/3", UserID: "testquser", WorkspaceID: "testws"},
+	} {
+		_, err := db.Store(ctx, u, false)
+		require.NoError(t, err)
+	}
+	_, err := db.DeleteUserURLs(ctx, []model.URL{{Short: "testq2", UserID: "testquser", TS: time.Now().UnixMicro()}})
+	require.NoError(t, err)
+	count, err := db.CountUserURLs(ctx, "testquser")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	_, err = db.GetQuota(ctx, "testquser")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	require.NoError(t, db.SetQuota(ctx, &model.Quota{UserID: "testquser", Tier: "free"}))
+	require.NoError(t, db.SetQuota(ctx, &model.Quota{UserID: "testquser", MaxURLs: 5, MaxBatch: 2}))
+	quota, err := db.GetQuota(ctx, "testquser")
+	require.NoError(t, err)
+	assert.Equal(t, "", quota.Tier)
+	assert.Equal(t, 5, quota.MaxURLs)
+	assert.Equal(t, 2, quota.MaxBatch)
+	require.NoError(t, db.DeleteQuota(ctx, "testquser"))
+	assert.ErrorIs(t, db.DeleteQuota(ctx, "testquser"), model.ErrNotFound)
+}
+
 func cleanUpTestHashes(ctx context.Context, t *testing.T, pool *pgxpool.Pool) {
 	t.Helper()
+	if _, errE := pool.Exec(ctx, "delete from variant_clicks where hash like 'test%'"); errE != nil {
+		require.NoError(t, errE)
+	}
 	tag, errE := pool.Exec(ctx, "delete from urls where hash like 'test%'")
 	if errE != nil {
 		require.NoError(t, errE)
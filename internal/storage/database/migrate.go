@@ -30,16 +30,81 @@ func (db *Database) migrate() error {
 //go:embed migrations/*.sql
 var migrationsDir embed.FS
 
-func runMigrations(dsn string) (bool, error) {
+// MigrationStatus is a schema version of database.
+type MigrationStatus struct {
+	// Version is current schema version, zero means no migrations were applied.
+	Version uint
+	// Latest is the latest version of embedded migrations.
+	Latest uint
+	// Dirty indicates that last migration has failed.
+	Dirty bool
+}
+
+// MigrateUp applies all pending migrations, false is returned if database is up to date.
+func MigrateUp(dsn string) (bool, error) {
+	return runMigrations(dsn)
+}
+
+// MigrateDown rolls back specified number of applied migrations.
+func MigrateDown(dsn string, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("invalid number of steps: %d", steps)
+	}
+	m, err := newMigrate(dsn)
+	if err != nil {
+		return err
+	}
+	defer closeMigrate(m)
+	if err = m.Steps(-steps); err != nil {
+		return fmt.Errorf("failed to roll back migrations: %w", err)
+	}
+	return nil
+}
+
+// GetMigrationStatus returns current and latest schema versions.
+func GetMigrationStatus(dsn string) (*MigrationStatus, error) {
 	d, err := iofs.New(migrationsDir, "migrations")
 	if err != nil {
-		return false, fmt.Errorf("failed to return an iofs driver: %w", err)
+		return nil, fmt.Errorf("failed to return an iofs driver: %w", err)
+	}
+	var status MigrationStatus
+	for v, errV := d.First(); errV == nil; v, errV = d.Next(v) {
+		status.Latest = v
 	}
 
+	m, err := newMigrate(dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer closeMigrate(m)
+	if status.Version, status.Dirty, err = m.Version(); err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return &status, nil
+}
+
+func newMigrate(dsn string) (*migrate.Migrate, error) {
+	d, err := iofs.New(migrationsDir, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to return an iofs driver: %w", err)
+	}
 	m, err := migrate.NewWithSourceInstance("iofs", d, dsn)
 	if err != nil {
-		return false, fmt.Errorf("failed to get a new migrate instance: %w", err)
+		return nil, fmt.Errorf("failed to get a new migrate instance: %w", err)
+	}
+	return m, nil
+}
+
+func closeMigrate(m *migrate.Migrate) {
+	_, _ = m.Close()
+}
+
+func runMigrations(dsn string) (bool, error) {
+	m, err := newMigrate(dsn)
+	if err != nil {
+		return false, err
 	}
+	defer closeMigrate(m)
 
 	if err = m.Up(); err != nil {
 		if !errors.Is(err, migrate.ErrNoChange) {
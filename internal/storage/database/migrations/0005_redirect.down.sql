@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE urls RENAME COLUMN redirect TO __redirect;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE urls RENAME COLUMN passthrough TO __passthrough;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS passthrough BOOLEAN NOT NULL DEFAULT false;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE variant_clicks RENAME TO __variant_clicks;
+ALTER TABLE urls RENAME COLUMN variants TO __variants;
+
+COMMIT;
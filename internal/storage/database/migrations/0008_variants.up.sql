@@ -0,0 +1,13 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS variants JSONB;
+
+CREATE TABLE IF NOT EXISTS variant_clicks (
+    hash VARCHAR(20) NOT NULL,
+    variant SMALLINT NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (hash, variant)
+);
+
+COMMIT;
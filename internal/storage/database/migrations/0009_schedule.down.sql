@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE urls RENAME COLUMN schedule TO __schedule;
+
+COMMIT;
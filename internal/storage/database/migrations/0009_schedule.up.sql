@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS schedule JSONB;
+
+COMMIT;
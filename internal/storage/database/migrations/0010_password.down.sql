@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE urls RENAME COLUMN password_hash TO __password_hash;
+
+COMMIT;
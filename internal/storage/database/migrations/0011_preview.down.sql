@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE urls RENAME COLUMN title TO __title;
+ALTER TABLE urls RENAME COLUMN preview TO __preview;
+
+COMMIT;
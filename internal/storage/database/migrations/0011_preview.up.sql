@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS preview BOOLEAN NOT NULL DEFAULT false;
+
+COMMIT;
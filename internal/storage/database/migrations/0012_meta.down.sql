@@ -0,0 +1,8 @@
+BEGIN TRANSACTION;
+
+DROP INDEX urls_tags;
+
+ALTER TABLE urls RENAME COLUMN tags TO __tags;
+ALTER TABLE urls RENAME COLUMN notes TO __notes;
+
+COMMIT;
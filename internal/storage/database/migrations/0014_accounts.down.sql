@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE accounts RENAME TO __accounts;
+ALTER INDEX accounts_login RENAME TO __accounts_login;
+ALTER INDEX accounts_pkey RENAME TO __accounts_pkey;
+
+COMMIT;
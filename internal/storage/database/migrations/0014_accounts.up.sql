@@ -0,0 +1,13 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS accounts (
+    userid VARCHAR(30) PRIMARY KEY,
+    login VARCHAR(64) NOT NULL,
+    password_hash TEXT NOT NULL,
+    ts timestamp NOT NULL DEFAULT current_timestamp,
+    CONSTRAINT login_not_empty CHECK (login != '')
+);
+
+CREATE UNIQUE INDEX accounts_login ON accounts (login);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE api_keys RENAME TO __api_keys;
+ALTER INDEX api_keys_userid RENAME TO __api_keys_userid;
+ALTER INDEX api_keys_pkey RENAME TO __api_keys_pkey;
+
+COMMIT;
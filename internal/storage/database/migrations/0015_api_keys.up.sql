@@ -0,0 +1,14 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id VARCHAR(32) PRIMARY KEY,
+    userid VARCHAR(30) NOT NULL,
+    name VARCHAR(128) NOT NULL DEFAULT '',
+    secret_hash VARCHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    ts timestamp NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX api_keys_userid ON api_keys (userid);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE revoked_tokens RENAME TO __revoked_tokens;
+ALTER INDEX revoked_tokens_expires RENAME TO __revoked_tokens_expires;
+ALTER INDEX revoked_tokens_pkey RENAME TO __revoked_tokens_pkey;
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    id VARCHAR(32) PRIMARY KEY,
+    expires timestamptz NOT NULL
+);
+
+CREATE INDEX revoked_tokens_expires ON revoked_tokens (expires);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE identities RENAME TO __identities;
+ALTER INDEX identities_userid RENAME TO __identities_userid;
+ALTER INDEX identities_pkey RENAME TO __identities_pkey;
+
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS identities (
+    issuer VARCHAR(256) NOT NULL,
+    subject VARCHAR(256) NOT NULL,
+    userid VARCHAR(30) NOT NULL,
+    email VARCHAR(256) NOT NULL DEFAULT '',
+    ts timestamp NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX identities_userid ON identities (userid);
+
+COMMIT;
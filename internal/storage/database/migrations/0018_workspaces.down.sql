@@ -0,0 +1,14 @@
+BEGIN TRANSACTION;
+
+DROP INDEX urls_workspace_id;
+
+ALTER TABLE urls RENAME COLUMN workspace_id TO __workspace_id;
+
+ALTER TABLE workspace_members RENAME TO __workspace_members;
+ALTER INDEX workspace_members_userid RENAME TO __workspace_members_userid;
+ALTER INDEX workspace_members_pkey RENAME TO __workspace_members_pkey;
+
+ALTER TABLE workspaces RENAME TO __workspaces;
+ALTER INDEX workspaces_pkey RENAME TO __workspaces_pkey;
+
+COMMIT;
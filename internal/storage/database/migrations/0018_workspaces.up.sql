@@ -0,0 +1,24 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS workspaces (
+    id VARCHAR(32) PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    ts timestamp NOT NULL DEFAULT current_timestamp
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id VARCHAR(32) NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    userid VARCHAR(30) NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    ts timestamp NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (workspace_id, userid)
+);
+
+CREATE INDEX workspace_members_userid ON workspace_members (userid);
+
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS workspace_id VARCHAR(32) NOT NULL DEFAULT '';
+
+CREATE INDEX urls_workspace_id ON urls (workspace_id);
+
+COMMIT;
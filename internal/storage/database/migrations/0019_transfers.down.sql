@@ -0,0 +1,12 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE audit_events RENAME TO __audit_events;
+ALTER INDEX audit_events_ts RENAME TO __audit_events_ts;
+ALTER INDEX audit_events_pkey RENAME TO __audit_events_pkey;
+
+ALTER TABLE transfers RENAME TO __transfers;
+ALTER INDEX transfers_to_userid RENAME TO __transfers_to_userid;
+ALTER INDEX transfers_from_userid RENAME TO __transfers_from_userid;
+ALTER INDEX transfers_pkey RENAME TO __transfers_pkey;
+
+COMMIT;
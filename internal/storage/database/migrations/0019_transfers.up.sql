@@ -0,0 +1,27 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS transfers (
+    id VARCHAR(32) PRIMARY KEY,
+    from_userid VARCHAR(30) NOT NULL,
+    to_userid VARCHAR(30) NOT NULL,
+    shorts TEXT[] NOT NULL,
+    ts timestamp NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX transfers_from_userid ON transfers (from_userid);
+CREATE INDEX transfers_to_userid ON transfers (to_userid);
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id VARCHAR(32) PRIMARY KEY,
+    action VARCHAR(32) NOT NULL,
+    actor VARCHAR(30) NOT NULL DEFAULT '',
+    transfer_id VARCHAR(32) NOT NULL DEFAULT '',
+    from_userid VARCHAR(30) NOT NULL,
+    to_userid VARCHAR(30) NOT NULL,
+    shorts TEXT[] NOT NULL,
+    ts timestamp NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX audit_events_ts ON audit_events (ts);
+
+COMMIT;
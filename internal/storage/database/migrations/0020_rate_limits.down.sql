@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE rate_limits RENAME TO __rate_limits;
+ALTER INDEX rate_limits_tat RENAME TO __rate_limits_tat;
+ALTER INDEX rate_limits_pkey RENAME TO __rate_limits_pkey;
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key VARCHAR(512) PRIMARY KEY,
+    tat BIGINT NOT NULL
+);
+
+CREATE INDEX rate_limits_tat ON rate_limits (tat);
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN TRANSACTION;
+
+DROP INDEX urls_userid_active;
+
+ALTER TABLE user_quotas RENAME TO __user_quotas;
+ALTER INDEX user_quotas_pkey RENAME TO __user_quotas_pkey;
+
+COMMIT;
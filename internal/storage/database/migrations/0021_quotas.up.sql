@@ -0,0 +1,13 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS user_quotas (
+    userid VARCHAR(30) PRIMARY KEY,
+    tier VARCHAR(64) NOT NULL DEFAULT '',
+    max_urls INTEGER NOT NULL DEFAULT 0,
+    max_batch INTEGER NOT NULL DEFAULT 0,
+    ts timestamp NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX urls_userid_active ON urls (userid) WHERE deleted = false AND workspace_id = '';
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+DROP INDEX urls_orig_key;
+ALTER TABLE urls ADD CONSTRAINT urls_orig_key UNIQUE (orig);
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN TRANSACTION;
+
+-- Only plain links are deduplicated by original url. Links with password
+-- or options that change redirect behaviour are always stored separately.
+ALTER TABLE urls DROP CONSTRAINT IF EXISTS urls_orig_key;
+CREATE UNIQUE INDEX urls_orig_key ON urls (orig)
+    WHERE deleted = false AND password_hash = '' AND redirect = 0 AND passthrough = false AND preview = false
+        AND coalesce(targets, 'null') IN ('null', '[]')
+        AND coalesce(variants, 'null') IN ('null', '[]')
+        AND coalesce(schedule, 'null') = 'null';
+
+COMMIT;
@@ -27,12 +27,31 @@ const (
 	flushInterval = 2 * time.Second
 
 	storageFilePermission = 0600
+
+	// accountsFileSuffix is appended to storage file path to get accounts file path.
+	accountsFileSuffix = ".accounts"
+	// apiKeysFileSuffix is appended to storage file path to get api keys file path.
+	apiKeysFileSuffix = ".apikeys"
+	// revokedFileSuffix is appended to storage file path to get revoked tokens file path.
+	revokedFileSuffix = ".revoked"
+	// identitiesFileSuffix is appended to storage file path to get external identities file path.
+	identitiesFileSuffix = ".identities"
+	// workspacesFileSuffix is appended to storage file path to get workspaces file path.
+	workspacesFileSuffix = ".workspaces"
+	// transfersFileSuffix is appended to storage file path to get transfer offers file path.
+	transfersFileSuffix = ".transfers"
+	// auditFileSuffix is appended to storage file path to get audit events file path.
+	auditFileSuffix = ".audit"
+	// quotasFileSuffix is appended to storage file path to get quota assignments file path.
+	quotasFileSuffix = ".quotas"
 )
 
 // File is a simple in-memory store with file persistence.
 // Saving into file is done in background without affecting
 // Get/Store operations. Since file is completely rewritten on each
 // interval this store is not suited for large quantities of records.
+// User accounts, external identities, workspaces, transfer offers, audit events,
+// quotas, api keys and revoked tokens are saved in separate files next to storage file.
 type File struct {
 	*memory.Memory
 	log *zap.Logger
@@ -74,6 +93,38 @@ func New(ctx context.Context, cfg *Config) (*File, error) {
 	if st.DB, err = readURLsFromFile(cfg.FilePath); err != nil {
 		return nil, err
 	}
+	if st.Accounts, err = readRecordsFromFile(cfg.FilePath+accountsFileSuffix, db.NewAccountRecordFromBytes,
+		func(rec *db.AccountRecord) string { return rec.Login }); err != nil {
+		return nil, fmt.Errorf("cannot read accounts: %w", err)
+	}
+	if st.APIKeys, err = readRecordsFromFile(cfg.FilePath+apiKeysFileSuffix, db.NewAPIKeyRecordFromBytes,
+		func(rec *db.APIKeyRecord) string { return rec.ID }); err != nil {
+		return nil, fmt.Errorf("cannot read api keys: %w", err)
+	}
+	if st.Revoked, err = readRecordsFromFile(cfg.FilePath+revokedFileSuffix, db.NewRevokedTokenRecordFromBytes,
+		func(rec *db.RevokedTokenRecord) string { return rec.ID }); err != nil {
+		return nil, fmt.Errorf("cannot read revoked tokens: %w", err)
+	}
+	if st.Identities, err = readRecordsFromFile(cfg.FilePath+identitiesFileSuffix, db.NewIdentityRecordFromBytes,
+		(*db.IdentityRecord).Key); err != nil {
+		return nil, fmt.Errorf("cannot read identities: %w", err)
+	}
+	if st.Workspaces, err = readRecordsFromFile(cfg.FilePath+workspacesFileSuffix, db.NewWorkspaceRecordFromBytes,
+		func(rec *db.WorkspaceRecord) string { return rec.ID }); err != nil {
+		return nil, fmt.Errorf("cannot read workspaces: %w", err)
+	}
+	if st.Transfers, err = readRecordsFromFile(cfg.FilePath+transfersFileSuffix, db.NewTransferRecordFromBytes,
+		func(rec *db.TransferRecord) string { return rec.ID }); err != nil {
+		return nil, fmt.Errorf("cannot read transfers: %w", err)
+	}
+	if st.Audit, err = readRecordsFromFile(cfg.FilePath+auditFileSuffix, db.NewAuditEventRecordFromBytes,
+		func(rec *db.AuditEventRecord) string { return rec.ID }); err != nil {
+		return nil, fmt.Errorf("cannot read audit events: %w", err)
+	}
+	if st.Quotas, err = readRecordsFromFile(cfg.FilePath+quotasFileSuffix, db.NewQuotaRecordFromBytes,
+		func(rec *db.QuotaRecord) string { return rec.UserID }); err != nil {
+		return nil, fmt.Errorf("cannot read quotas: %w", err)
+	}
 
 	if ln := len(st.DB); ln > 0 {
 		cfg.Logger.Info("loaded db from file",
@@ -138,6 +189,240 @@ func (s *File) DeleteUserURLs(ctx context.Context, urls []model.URL) (int64, err
 	return affected, nil
 }
 
+// PurgeDeleted permanently removes deleted URLs and returns their number.
+func (s *File) PurgeDeleted(ctx context.Context) (int64, error) {
+	if s.shutdown.Load() {
+		return 0, errors.New("storage is shutting down")
+	}
+	num, err := s.Memory.PurgeDeleted(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("memory storage error: %w", err)
+	}
+	if num > 0 {
+		s.changed.Store(true)
+	}
+	return num, nil
+}
+
+// LoadURLs reads URLs from next and stores them as is, other URLs are removed if replace is set.
+func (s *File) LoadURLs(ctx context.Context, next func() (*model.URL, error), replace bool) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.LoadURLs(ctx, next, replace); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// ClaimUserURLs transfers active personal URLs of one user to another user.
+func (s *File) ClaimUserURLs(ctx context.Context, fromUserID, toUserID string) (int64, error) {
+	if s.shutdown.Load() {
+		return 0, errors.New("storage is shutting down")
+	}
+	num, err := s.Memory.ClaimUserURLs(ctx, fromUserID, toUserID)
+	if err != nil {
+		return 0, fmt.Errorf("memory storage error: %w", err)
+	}
+	if num > 0 {
+		s.changed.Store(true)
+	}
+	return num, nil
+}
+
+// CreateAccount stores new user account.
+func (s *File) CreateAccount(ctx context.Context, acc *model.Account) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.CreateAccount(ctx, acc); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// CreateIdentity stores link of external identity to user.
+func (s *File) CreateIdentity(ctx context.Context, identity *model.Identity) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.CreateIdentity(ctx, identity); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// CreateWorkspace stores new workspace with its owner.
+func (s *File) CreateWorkspace(ctx context.Context, ws *model.Workspace, ownerID string) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.CreateWorkspace(ctx, ws, ownerID); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// SetMember adds member to workspace or changes role of existing member.
+func (s *File) SetMember(ctx context.Context, member *model.Member) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.SetMember(ctx, member); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// DeleteMember removes member from workspace.
+func (s *File) DeleteMember(ctx context.Context, workspaceID, userID string) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.DeleteMember(ctx, workspaceID, userID); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// CreateTransfer stores new transfer offer.
+func (s *File) CreateTransfer(ctx context.Context, transfer *model.Transfer) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.CreateTransfer(ctx, transfer); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// DeleteTransfer deletes transfer offer.
+func (s *File) DeleteTransfer(ctx context.Context, id string) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.DeleteTransfer(ctx, id); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// TransferURLs changes owner of personal URLs listed in audit event and records event.
+func (s *File) TransferURLs(ctx context.Context, event *model.AuditEvent) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.TransferURLs(ctx, event); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// SetQuota creates or replaces quota assignment of user.
+func (s *File) SetQuota(ctx context.Context, quota *model.Quota) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.SetQuota(ctx, quota); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// DeleteQuota deletes quota assignment of user.
+func (s *File) DeleteQuota(ctx context.Context, userID string) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.DeleteQuota(ctx, userID); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// CreateAPIKey stores new api key.
+func (s *File) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.CreateAPIKey(ctx, key); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// UpdateAPIKey updates name and scopes of user api key.
+func (s *File) UpdateAPIKey(ctx context.Context, key *model.APIKey) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.UpdateAPIKey(ctx, key); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// DeleteAPIKey deletes user api key.
+func (s *File) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.DeleteAPIKey(ctx, userID, id); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// RevokeToken adds session token id to denylist.
+func (s *File) RevokeToken(ctx context.Context, id string, expires time.Time) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.RevokeToken(ctx, id, expires); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// UpdateMeta updates title, tags and notes of user URL.
+func (s *File) UpdateMeta(ctx context.Context, url *model.URL) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.UpdateMeta(ctx, url); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
+// AddVariantClicks increments click counters of URL variants.
+func (s *File) AddVariantClicks(ctx context.Context, clicks []model.Click) error {
+	if s.shutdown.Load() {
+		return errors.New("storage is shutting down")
+	}
+	if err := s.Memory.AddVariantClicks(ctx, clicks); err != nil {
+		return fmt.Errorf("memory storage error: %w", err)
+	}
+	s.changed.Store(true)
+	return nil
+}
+
 func (s *File) maintainPersistence(ctx context.Context) {
 Loop:
 	for {
@@ -162,6 +447,30 @@ func (s *File) persist() {
 	if err := s.dumpDB2File(); err != nil {
 		s.log.Error("cannot save db to file",
 			zap.Error(err))
+	} else if err = dumpRecords2File(s.filePath+accountsFileSuffix, s.DumpAccounts()); err != nil {
+		s.log.Error("cannot save accounts to file",
+			zap.Error(err))
+	} else if err = dumpRecords2File(s.filePath+apiKeysFileSuffix, s.DumpAPIKeys()); err != nil {
+		s.log.Error("cannot save api keys to file",
+			zap.Error(err))
+	} else if err = dumpRecords2File(s.filePath+identitiesFileSuffix, s.DumpIdentities()); err != nil {
+		s.log.Error("cannot save identities to file",
+			zap.Error(err))
+	} else if err = dumpRecords2File(s.filePath+workspacesFileSuffix, s.DumpWorkspaces()); err != nil {
+		s.log.Error("cannot save workspaces to file",
+			zap.Error(err))
+	} else if err = dumpRecords2File(s.filePath+transfersFileSuffix, s.DumpTransfers()); err != nil {
+		s.log.Error("cannot save transfers to file",
+			zap.Error(err))
+	} else if err = dumpRecords2File(s.filePath+auditFileSuffix, s.DumpAuditLog()); err != nil {
+		s.log.Error("cannot save audit events to file",
+			zap.Error(err))
+	} else if err = dumpRecords2File(s.filePath+quotasFileSuffix, s.DumpQuotas()); err != nil {
+		s.log.Error("cannot save quotas to file",
+			zap.Error(err))
+	} else if err = dumpRecords2File(s.filePath+revokedFileSuffix, s.DumpRevokedTokens()); err != nil {
+		s.log.Error("cannot save revoked tokens to file",
+			zap.Error(err))
 	} else {
 		s.changed.Store(false)
 		s.log.Debug("db was saved to file",
@@ -191,6 +500,70 @@ func (s *File) dumpDB2File() error {
 	return nil
 }
 
+// dumpRecords2File writes records to file one json per line.
+// File is removed if there are no records, so it's not created until it's needed.
+func dumpRecords2File[T any](path string, records map[string]T) error {
+	if len(records) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("cannot remove file: %w", err)
+		}
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, storageFilePermission)
+	if err != nil {
+		return fmt.Errorf("cannot open file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	w := bufio.NewWriter(f)
+	for _, record := range records {
+		var data []byte
+		if data, err = json.Marshal(record); err != nil {
+			return fmt.Errorf("cannot marshal to json: %w", err)
+		}
+		if _, err = w.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("cannot write to file: %w", err)
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("cannot write to file: %w", err)
+	}
+	return nil
+}
+
+// readRecordsFromFile reads records written by dumpRecords2File, missing file has no records.
+func readRecordsFromFile[T any](
+	filePath string,
+	parse func([]byte) (*T, error),
+	key func(*T) string,
+) (map[string]T, error) {
+	records := make(map[string]T)
+	f, err := os.Open(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return records, nil
+		}
+		return nil, fmt.Errorf("cannot open file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		record, errR := parse(sc.Bytes())
+		if errR != nil {
+			return nil, fmt.Errorf("cannot parse record: %w", errR)
+		}
+		records[key(record)] = *record
+	}
+	if err = sc.Err(); err != nil {
+		return nil, fmt.Errorf("error while reading file: %w", err)
+	}
+	return records, nil
+}
+
 func readURLsFromFile(filePath string) (db.DB, error) {
 	f, err := os.OpenFile(filePath, syscall.O_RDONLY|syscall.O_CREAT, storageFilePermission)
 	if err != nil {
@@ -3,12 +3,15 @@ package file
 import (
 	"context"
 	"encoding/json"
+	"net/http"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/adwski/shorty/internal/model"
 	"github.com/adwski/shorty/internal/storage/memory"
 	"github.com/adwski/shorty/internal/storage/memory/db"
+	"github.com/adwski/shorty/internal/user"
 	"github.com/gofrs/uuid/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -28,6 +31,37 @@ func TestFileStore(t *testing.T) {
 				Orig:  "https://bbb.ccc",
 			},
 		},
+		{
+			name: "store and get with redirect code",
+			args: &model.URL{
+				Short:    "aaa",
+				Orig:     "https://bbb.ccc",
+				Redirect: http.StatusMovedPermanently,
+			},
+		},
+		{
+			name: "store and get with targets",
+			args: &model.URL{
+				Short: "aaa",
+				Orig:  "https://bbb.ccc",
+				Targets: []model.Target{
+					{Platform: "ios", URL: "https://apps.apple.com/app/id123"},
+				},
+			},
+		},
+		{
+			name: "store and get with schedule",
+			args: &model.URL{
+				Short: "aaa",
+				Orig:  "https://bbb.ccc",
+				Schedule: &model.Schedule{
+					Timezone: "Europe/Berlin",
+					Rules: []model.ScheduleRule{
+						{From: "2024-05-01T10:00", URL: "https://bbb.ccc/sale"},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -49,10 +83,13 @@ func TestFileStore(t *testing.T) {
 			require.NoError(t, err)
 
 			// get
-			var url string
+			var url *model.URL
 			url, err = fs.Get(ctx, tt.args.Short)
 			require.NoError(t, err)
-			assert.Equal(t, tt.args.Orig, url)
+			assert.Equal(t, tt.args.Orig, url.Orig)
+			assert.Equal(t, tt.args.Redirect, url.Redirect)
+			assert.Equal(t, tt.args.Targets, url.Targets)
+			assert.Equal(t, tt.args.Schedule, url.Schedule)
 
 			// stop persistence
 			cancel()
@@ -72,6 +109,9 @@ func TestFileStore(t *testing.T) {
 			require.NoError(t, err)
 			assert.Equal(t, tt.args.Short, rec.ShortURL)
 			assert.Equal(t, tt.args.Orig, rec.OriginalURL)
+			assert.Equal(t, tt.args.Redirect, rec.Redirect)
+			assert.Equal(t, tt.args.Targets, rec.Targets)
+			assert.Equal(t, tt.args.Schedule, rec.Schedule)
 		})
 	}
 }
@@ -124,7 +164,7 @@ func TestStore_Get(t *testing.T) {
 			url, err := fs.Get(context.Background(), tt.args.url.Short)
 			if tt.err == nil {
 				require.NoError(t, err)
-				assert.Equal(t, tt.args.url.Orig, url)
+				assert.Equal(t, tt.args.url.Orig, url.Orig)
 			} else {
 				assert.Equal(t, tt.err, err)
 			}
@@ -210,3 +250,189 @@ func TestStore_Store(t *testing.T) {
 		})
 	}
 }
+
+func TestFileStore_Accounts(t *testing.T) {
+	logger := zap.NewNop()
+	fStore, err := os.CreateTemp("", "shorty-test-db-*.")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.Remove(fStore.Name())
+		_ = os.Remove(fStore.Name() + accountsFileSuffix)
+	}()
+
+	accUser, err := user.New()
+	require.NoError(t, err)
+	acc := &model.Account{UserID: accUser.ID, Login: "alice", PasswordHash: "hash"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fs, err := New(ctx, &Config{FilePath: fStore.Name(), Logger: logger})
+	require.NoError(t, err)
+	require.NoError(t, fs.CreateAccount(ctx, acc))
+	assert.ErrorIs(t, fs.CreateAccount(ctx, acc), model.ErrAlreadyExists)
+	cancel()
+	fs.Close()
+
+	// accounts are loaded on start
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	fs, err = New(ctx, &Config{FilePath: fStore.Name(), Logger: logger})
+	require.NoError(t, err)
+	defer fs.Close()
+	got, err := fs.GetAccount(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, acc.UserID, got.UserID)
+	assert.Equal(t, acc.PasswordHash, got.PasswordHash)
+}
+
+func TestFileStore_Workspaces(t *testing.T) {
+	logger := zap.NewNop()
+	fStore, err := os.CreateTemp("", "shorty-test-db-*.")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.Remove(fStore.Name())
+		_ = os.Remove(fStore.Name() + workspacesFileSuffix)
+	}()
+
+	owner, err := user.New()
+	require.NoError(t, err)
+	editor, err := user.New()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fs, err := New(ctx, &Config{FilePath: fStore.Name(), Logger: logger})
+	require.NoError(t, err)
+	require.NoError(t, fs.CreateWorkspace(ctx, &model.Workspace{ID: "ws", Name: "Marketing"}, owner.ID))
+	require.NoError(t, fs.SetMember(ctx, &model.Member{WorkspaceID: "ws", UserID: editor.ID, Role: model.RoleEditor}))
+	_, err = fs.Store(ctx, &model.URL{Short: "aaa", Orig: "https://bbb.ccc", UserID: editor.ID, WorkspaceID: "ws"}, false)
+	require.NoError(t, err)
+	cancel()
+	fs.Close()
+
+	// workspaces and workspace links are loaded on start
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	fs, err = New(ctx, &Config{FilePath: fStore.Name(), Logger: logger})
+	require.NoError(t, err)
+	defer fs.Close()
+	members, err := fs.ListMembers(ctx, "ws")
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, model.RoleOwner, members[0].Role)
+	assert.Equal(t, editor.ID, members[1].UserID)
+	urls, err := fs.ListWorkspaceURLs(ctx, "ws", "")
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, editor.ID, urls[0].UserID)
+}
+
+func TestFileStore_Transfers(t *testing.T) {
+	logger := zap.NewNop()
+	fStore, err := os.CreateTemp("", "shorty-test-db-*.")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.Remove(fStore.Name())
+		_ = os.Remove(fStore.Name() + transfersFileSuffix)
+		_ = os.Remove(fStore.Name() + auditFileSuffix)
+	}()
+
+	from, err := user.New()
+	require.NoError(t, err)
+	to, err := user.New()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fs, err := New(ctx, &Config{FilePath: fStore.Name(), Logger: logger})
+	require.NoError(t, err)
+	for _, short := range []string{"aaa", "bbb"} {
+		_, err = fs.Store(ctx, &model.URL{Short: short, Orig: "https://bbb.ccc/" + short, UserID: from.ID}, false)
+		require.NoError(t, err)
+	}
+	require.NoError(t, fs.CreateTransfer(ctx,
+		&model.Transfer{ID: "t1", FromUserID: from.ID, ToUserID: to.ID, Shorts: []string{"bbb"}}))
+	require.NoError(t, fs.TransferURLs(ctx, &model.AuditEvent{ID: "e1", Action: model.AuditTransferForced,
+		FromUserID: from.ID, ToUserID: to.ID, Shorts: []string{"aaa"}}))
+	cancel()
+	fs.Close()
+
+	// transfer offers, audit events and new owners are loaded on start
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	fs, err = New(ctx, &Config{FilePath: fStore.Name(), Logger: logger})
+	require.NoError(t, err)
+	defer fs.Close()
+	transfers, err := fs.ListTransfers(ctx, to.ID)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	assert.Equal(t, []string{"bbb"}, transfers[0].Shorts)
+	events, err := fs.ListAuditEvents(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, model.AuditTransferForced, events[0].Action)
+	urls, err := fs.ListUserURLs(ctx, to.ID, "")
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "aaa", urls[0].Short)
+}
+
+func TestFileStore_Quotas(t *testing.T) {
+	logger := zap.NewNop()
+	fStore, err := os.CreateTemp("", "shorty-test-db-*.")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.Remove(fStore.Name())
+		_ = os.Remove(fStore.Name() + quotasFileSuffix)
+	}()
+
+	u, err := user.New()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fs, err := New(ctx, &Config{FilePath: fStore.Name(), Logger: logger})
+	require.NoError(t, err)
+	require.NoError(t, fs.SetQuota(ctx, &model.Quota{UserID: u.ID, MaxURLs: 10, MaxBatch: 2}))
+	cancel()
+	fs.Close()
+
+	// quota assignments are loaded on start
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	fs, err = New(ctx, &Config{FilePath: fStore.Name(), Logger: logger})
+	require.NoError(t, err)
+	defer fs.Close()
+	quota, err := fs.GetQuota(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 10, quota.MaxURLs)
+	assert.Equal(t, 2, quota.MaxBatch)
+	require.NoError(t, fs.DeleteQuota(ctx, u.ID))
+	_, err = fs.GetQuota(ctx, u.ID)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestFileStore_RevokedTokens(t *testing.T) {
+	logger := zap.NewNop()
+	fStore, err := os.CreateTemp("", "shorty-test-db-*.")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.Remove(fStore.Name())
+		_ = os.Remove(fStore.Name() + revokedFileSuffix)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fs, err := New(ctx, &Config{FilePath: fStore.Name(), Logger: logger})
+	require.NoError(t, err)
+	require.NoError(t, fs.RevokeToken(ctx, "session1", time.Now().Add(time.Hour)))
+	require.NoError(t, fs.RevokeToken(ctx, "session2", time.Now().Add(-time.Hour)))
+	cancel()
+	fs.Close()
+
+	// revoked tokens are loaded on start, expired ones are not saved
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	fs, err = New(ctx, &Config{FilePath: fStore.Name(), Logger: logger})
+	require.NoError(t, err)
+	defer fs.Close()
+	revoked, err := fs.IsTokenRevoked(ctx, "session1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	assert.Len(t, fs.DumpRevokedTokens(), 1)
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+)
+
+// Accounts is in-memory database of user accounts.
+// It represented as map login->Account.
+type Accounts map[string]AccountRecord
+
+// NewAccounts creates new in-memory accounts database.
+func NewAccounts() Accounts {
+	return make(Accounts)
+}
+
+// AccountRecord is single user account record.
+type AccountRecord struct {
+	UserID       string `json:"user"`
+	Login        string `json:"login"`
+	PasswordHash string `json:"password_hash"`
+	// Created is creation unix timestamp.
+	Created int64 `json:"created"`
+}
+
+// NewAccountRecord creates account record from model representation.
+func NewAccountRecord(acc *model.Account) AccountRecord {
+	return AccountRecord{
+		UserID:       acc.UserID,
+		Login:        acc.Login,
+		PasswordHash: acc.PasswordHash,
+		Created:      createdTS(acc.Created),
+	}
+}
+
+// Account returns model representation of account record.
+func (rec *AccountRecord) Account() *model.Account {
+	return &model.Account{
+		UserID:       rec.UserID,
+		Login:        rec.Login,
+		PasswordHash: rec.PasswordHash,
+		Created:      time.Unix(rec.Created, 0),
+	}
+}
+
+// NewAccountRecordFromBytes parses json encoded byte string and creates account record from it.
+func NewAccountRecordFromBytes(data []byte) (*AccountRecord, error) {
+	record := &AccountRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("malformed json data: %w", err)
+	}
+	if record.Login == "" || record.PasswordHash == "" {
+		return nil, errors.New("login or password hash is empty")
+	}
+	if _, err := user.NewFromUserID(record.UserID); err != nil {
+		return nil, fmt.Errorf("malformed user id of account %s: %w", record.Login, err)
+	}
+	return record, nil
+}
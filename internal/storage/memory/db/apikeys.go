@@ -0,0 +1,71 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+)
+
+// APIKeys is in-memory database of api keys.
+// It represented as map id->APIKey.
+type APIKeys map[string]APIKeyRecord
+
+// NewAPIKeys creates new in-memory api keys database.
+func NewAPIKeys() APIKeys {
+	return make(APIKeys)
+}
+
+// APIKeyRecord is single api key record.
+type APIKeyRecord struct {
+	ID         string   `json:"id"`
+	UserID     string   `json:"user"`
+	Name       string   `json:"name,omitempty"`
+	SecretHash string   `json:"secret_hash"`
+	Scopes     []string `json:"scopes"`
+	// Created is creation unix timestamp.
+	Created int64 `json:"created"`
+}
+
+// NewAPIKeyRecord creates api key record from model representation.
+func NewAPIKeyRecord(key *model.APIKey) APIKeyRecord {
+	return APIKeyRecord{
+		ID:         key.ID,
+		UserID:     key.UserID,
+		Name:       key.Name,
+		SecretHash: key.SecretHash,
+		Scopes:     slices.Clone(key.Scopes),
+		Created:    createdTS(key.Created),
+	}
+}
+
+// APIKey returns model representation of api key record.
+func (rec *APIKeyRecord) APIKey() *model.APIKey {
+	return &model.APIKey{
+		ID:         rec.ID,
+		UserID:     rec.UserID,
+		Name:       rec.Name,
+		SecretHash: rec.SecretHash,
+		Scopes:     slices.Clone(rec.Scopes),
+		Created:    time.Unix(rec.Created, 0),
+	}
+}
+
+// NewAPIKeyRecordFromBytes parses json encoded byte string and creates api key record from it.
+func NewAPIKeyRecordFromBytes(data []byte) (*APIKeyRecord, error) {
+	record := &APIKeyRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("malformed json data: %w", err)
+	}
+	if record.ID == "" || record.SecretHash == "" {
+		return nil, errors.New("key id or secret hash is empty")
+	}
+	if _, err := user.NewFromUserID(record.UserID); err != nil {
+		return nil, fmt.Errorf("malformed user id of api key %s: %w", record.ID, err)
+	}
+	return record, nil
+}
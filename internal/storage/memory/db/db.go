@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"slices"
+	"time"
 
 	"github.com/adwski/shorty/internal/model"
 	"github.com/adwski/shorty/internal/user"
@@ -55,7 +57,126 @@ type Record struct {
 	ShortURL    string `json:"short_url"`
 	OriginalURL string `json:"original_url"`
 	UserID      string `json:"user"`
+	WorkspaceID string `json:"workspace,omitempty"`
 	Deleted     bool   `json:"deleted"`
+	Redirect    int    `json:"redirect,omitempty"`
+	Passthrough bool   `json:"passthrough,omitempty"`
+
+	Targets  []model.Target  `json:"targets,omitempty"`
+	Variants []model.Variant `json:"variants,omitempty"`
+	Schedule *model.Schedule `json:"schedule,omitempty"`
+
+	PasswordHash string `json:"password_hash,omitempty"`
+
+	Title   string   `json:"title,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Notes   string   `json:"notes,omitempty"`
+	Preview bool     `json:"preview,omitempty"`
+	// Created is creation unix timestamp.
+	Created int64 `json:"created,omitempty"`
+
+	// Clicks holds click counters of variants, indexes match Variants.
+	Clicks []int64 `json:"clicks,omitempty"`
+}
+
+// NewRecord creates URL record from model representation.
+func NewRecord(id string, url *model.URL) Record {
+	return Record{
+		UUID:        id,
+		ShortURL:    url.Short,
+		OriginalURL: url.Orig,
+		UserID:      url.UserID,
+		WorkspaceID: url.WorkspaceID,
+		Redirect:    url.Redirect,
+		Passthrough: url.Passthrough,
+		Targets:     url.Targets,
+		Variants:    url.Variants,
+		Schedule:    url.Schedule,
+
+		PasswordHash: url.PasswordHash,
+
+		Title:   url.Title,
+		Tags:    url.Tags,
+		Notes:   url.Notes,
+		Preview: url.Preview,
+		Created: createdTS(url.Created),
+	}
+}
+
+// NewRecordAsIs creates URL record keeping URL deleted flag, unknown creation time
+// and variant click counters. It's used when URLs are restored or moved from another storage.
+func NewRecordAsIs(id string, url *model.URL) Record {
+	record := NewRecord(id, url)
+	record.Deleted = url.Deleted
+	if url.Created.IsZero() {
+		record.Created = 0
+	}
+	record.Clicks = slices.Clone(url.Clicks)
+	return record
+}
+
+// URL returns model representation of URL record.
+func (rec *Record) URL() *model.URL {
+	return &model.URL{
+		Short:       rec.ShortURL,
+		Orig:        rec.OriginalURL,
+		UserID:      rec.UserID,
+		WorkspaceID: rec.WorkspaceID,
+		Redirect:    rec.Redirect,
+		Passthrough: rec.Passthrough,
+		Targets:     rec.Targets,
+		Variants:    rec.Variants,
+		Schedule:    rec.Schedule,
+
+		PasswordHash: rec.PasswordHash,
+
+		Title:   rec.Title,
+		Tags:    rec.Tags,
+		Notes:   rec.Notes,
+		Preview: rec.Preview,
+		Created: rec.created(),
+
+		UUID:    rec.UUID,
+		Deleted: rec.Deleted,
+	}
+}
+
+// URLAsIs returns model representation of URL record including variant click counters.
+// It's used when URLs are backed up or moved to another storage.
+func (rec *Record) URLAsIs() *model.URL {
+	url := rec.URL()
+	url.Clicks = slices.Clone(rec.Clicks)
+	return url
+}
+
+// createdTS returns creation timestamp of new record, current time is used if not set.
+func createdTS(created time.Time) int64 {
+	if created.IsZero() {
+		return time.Now().Unix()
+	}
+	return created.Unix()
+}
+
+func (rec *Record) created() time.Time {
+	if rec.Created == 0 {
+		return time.Time{}
+	}
+	return time.Unix(rec.Created, 0)
+}
+
+// IsOwnedBy checks if record belongs to workspace, or is personal link of user if workspace is empty.
+func (rec *Record) IsOwnedBy(userID, workspaceID string) bool {
+	return rec.WorkspaceID == workspaceID && (workspaceID != "" || rec.UserID == userID)
+}
+
+// HasTag checks if record is labeled with tag.
+func (rec *Record) HasTag(tag string) bool {
+	for _, t := range rec.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
 
 // NewURLRecordFromBytes parses json encoded byte string and creates URL record from it.
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+)
+
+// Identities is in-memory database of external identities.
+// It represented as map issuer+subject->IdentityRecord.
+type Identities map[string]IdentityRecord
+
+// NewIdentities creates new in-memory identities database.
+func NewIdentities() Identities {
+	return make(Identities)
+}
+
+// IdentityKey returns key of identity record.
+func IdentityKey(issuer, subject string) string {
+	return issuer + " " + subject
+}
+
+// IdentityRecord is single external identity record.
+type IdentityRecord struct {
+	Issuer  string `json:"iss"`
+	Subject string `json:"sub"`
+	UserID  string `json:"user"`
+	Email   string `json:"email,omitempty"`
+	// Created is creation unix timestamp.
+	Created int64 `json:"created"`
+}
+
+// NewIdentityRecord creates identity record from model representation.
+func NewIdentityRecord(identity *model.Identity) IdentityRecord {
+	return IdentityRecord{
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+		UserID:  identity.UserID,
+		Email:   identity.Email,
+		Created: createdTS(identity.Created),
+	}
+}
+
+// Identity returns model representation of identity record.
+func (rec *IdentityRecord) Identity() *model.Identity {
+	return &model.Identity{
+		Issuer:  rec.Issuer,
+		Subject: rec.Subject,
+		UserID:  rec.UserID,
+		Email:   rec.Email,
+		Created: time.Unix(rec.Created, 0),
+	}
+}
+
+// Key returns key of identity record.
+func (rec *IdentityRecord) Key() string {
+	return IdentityKey(rec.Issuer, rec.Subject)
+}
+
+// NewIdentityRecordFromBytes parses json encoded byte string and creates identity record from it.
+func NewIdentityRecordFromBytes(data []byte) (*IdentityRecord, error) {
+	record := &IdentityRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("malformed json data: %w", err)
+	}
+	if record.Issuer == "" || record.Subject == "" {
+		return nil, errors.New("issuer or subject is empty")
+	}
+	if _, err := user.NewFromUserID(record.UserID); err != nil {
+		return nil, fmt.Errorf("malformed user id of identity %s: %w", record.Subject, err)
+	}
+	return record, nil
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+)
+
+// Quotas is in-memory database of user quota assignments.
+// It represented as map userID->QuotaRecord.
+type Quotas map[string]QuotaRecord
+
+// NewQuotas creates new in-memory quotas database.
+func NewQuotas() Quotas {
+	return make(Quotas)
+}
+
+// QuotaRecord is single user quota assignment record.
+type QuotaRecord struct {
+	UserID   string `json:"user_id"`
+	Tier     string `json:"tier,omitempty"`
+	MaxURLs  int    `json:"max_urls,omitempty"`
+	MaxBatch int    `json:"max_batch,omitempty"`
+	// Updated is update unix timestamp.
+	Updated int64 `json:"updated"`
+}
+
+// NewQuotaRecord creates quota record from model representation.
+func NewQuotaRecord(quota *model.Quota) QuotaRecord {
+	return QuotaRecord{
+		UserID:   quota.UserID,
+		Tier:     quota.Tier,
+		MaxURLs:  quota.MaxURLs,
+		MaxBatch: quota.MaxBatch,
+		Updated:  createdTS(quota.Updated),
+	}
+}
+
+// Quota returns model representation of quota record.
+func (rec *QuotaRecord) Quota() *model.Quota {
+	return &model.Quota{
+		UserID:   rec.UserID,
+		Tier:     rec.Tier,
+		MaxURLs:  rec.MaxURLs,
+		MaxBatch: rec.MaxBatch,
+		Updated:  time.Unix(rec.Updated, 0),
+	}
+}
+
+// NewQuotaRecordFromBytes parses json encoded byte string and creates quota record from it.
+func NewQuotaRecordFromBytes(data []byte) (*QuotaRecord, error) {
+	record := &QuotaRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("malformed json data: %w", err)
+	}
+	if _, err := user.NewFromUserID(record.UserID); err != nil {
+		return nil, fmt.Errorf("malformed user id of quota: %w", err)
+	}
+	return record, nil
+}
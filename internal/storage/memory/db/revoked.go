@@ -0,0 +1,44 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// RevokedTokens is in-memory denylist of revoked session tokens.
+// It represented as map id->RevokedTokenRecord.
+type RevokedTokens map[string]RevokedTokenRecord
+
+// NewRevokedTokens creates new in-memory revoked tokens database.
+func NewRevokedTokens() RevokedTokens {
+	return make(RevokedTokens)
+}
+
+// RevokedTokenRecord is single revoked token record.
+type RevokedTokenRecord struct {
+	ID string `json:"id"`
+	// Expires is unix timestamp after which token is expired anyway and record can be removed.
+	Expires int64 `json:"expires"`
+}
+
+// NewRevokedTokenRecordFromBytes parses json encoded byte string and creates revoked token record from it.
+func NewRevokedTokenRecordFromBytes(data []byte) (*RevokedTokenRecord, error) {
+	record := &RevokedTokenRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("malformed json data: %w", err)
+	}
+	if record.ID == "" {
+		return nil, errors.New("token id is empty")
+	}
+	return record, nil
+}
+
+// Prune removes records expired before now unix timestamp.
+func (rt RevokedTokens) Prune(now int64) {
+	for id, record := range rt {
+		if record.Expires < now {
+			delete(rt, id)
+		}
+	}
+}
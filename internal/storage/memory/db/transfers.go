@@ -0,0 +1,137 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+)
+
+// Transfers is in-memory database of pending transfer offers.
+// It represented as map id->TransferRecord.
+type Transfers map[string]TransferRecord
+
+// NewTransfers creates new in-memory transfers database.
+func NewTransfers() Transfers {
+	return make(Transfers)
+}
+
+// TransferRecord is single transfer offer record.
+type TransferRecord struct {
+	ID     string   `json:"id"`
+	From   string   `json:"from"`
+	To     string   `json:"to"`
+	Shorts []string `json:"shorts"`
+	// Created is creation unix timestamp.
+	Created int64 `json:"created"`
+}
+
+// NewTransferRecord creates transfer record from model representation.
+func NewTransferRecord(transfer *model.Transfer) TransferRecord {
+	return TransferRecord{
+		ID:      transfer.ID,
+		From:    transfer.FromUserID,
+		To:      transfer.ToUserID,
+		Shorts:  slices.Clone(transfer.Shorts),
+		Created: createdTS(transfer.Created),
+	}
+}
+
+// Transfer returns model representation of transfer record.
+func (rec *TransferRecord) Transfer() *model.Transfer {
+	return &model.Transfer{
+		ID:         rec.ID,
+		FromUserID: rec.From,
+		ToUserID:   rec.To,
+		Shorts:     slices.Clone(rec.Shorts),
+		Created:    time.Unix(rec.Created, 0),
+	}
+}
+
+// NewTransferRecordFromBytes parses json encoded byte string and creates transfer record from it.
+func NewTransferRecordFromBytes(data []byte) (*TransferRecord, error) {
+	record := &TransferRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("malformed json data: %w", err)
+	}
+	if record.ID == "" {
+		return nil, errors.New("transfer id is empty")
+	}
+	for _, userID := range []string{record.From, record.To} {
+		if _, err := user.NewFromUserID(userID); err != nil {
+			return nil, fmt.Errorf("malformed user id of transfer %s: %w", record.ID, err)
+		}
+	}
+	return record, nil
+}
+
+// AuditLog is in-memory database of audit events.
+// It represented as map id->AuditEventRecord.
+type AuditLog map[string]AuditEventRecord
+
+// NewAuditLog creates new in-memory audit events database.
+func NewAuditLog() AuditLog {
+	return make(AuditLog)
+}
+
+// AuditEventRecord is single audit event record.
+type AuditEventRecord struct {
+	ID       string   `json:"id"`
+	Action   string   `json:"action"`
+	Actor    string   `json:"actor,omitempty"`
+	Transfer string   `json:"transfer,omitempty"`
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Shorts   []string `json:"shorts"`
+	// Created is creation unix timestamp.
+	Created int64 `json:"created"`
+}
+
+// NewAuditEventRecord creates audit event record from model representation.
+func NewAuditEventRecord(event *model.AuditEvent) AuditEventRecord {
+	return AuditEventRecord{
+		ID:       event.ID,
+		Action:   event.Action,
+		Actor:    event.ActorID,
+		Transfer: event.TransferID,
+		From:     event.FromUserID,
+		To:       event.ToUserID,
+		Shorts:   slices.Clone(event.Shorts),
+		Created:  createdTS(event.Created),
+	}
+}
+
+// AuditEvent returns model representation of audit event record.
+func (rec *AuditEventRecord) AuditEvent() *model.AuditEvent {
+	return &model.AuditEvent{
+		ID:         rec.ID,
+		Action:     rec.Action,
+		ActorID:    rec.Actor,
+		TransferID: rec.Transfer,
+		FromUserID: rec.From,
+		ToUserID:   rec.To,
+		Shorts:     slices.Clone(rec.Shorts),
+		Created:    time.Unix(rec.Created, 0),
+	}
+}
+
+// NewAuditEventRecordFromBytes parses json encoded byte string and creates audit event record from it.
+func NewAuditEventRecordFromBytes(data []byte) (*AuditEventRecord, error) {
+	record := &AuditEventRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("malformed json data: %w", err)
+	}
+	if record.ID == "" || record.Action == "" {
+		return nil, errors.New("audit event id or action is empty")
+	}
+	for _, userID := range []string{record.From, record.To} {
+		if _, err := user.NewFromUserID(userID); err != nil {
+			return nil, fmt.Errorf("malformed user id of audit event %s: %w", record.ID, err)
+		}
+	}
+	return record, nil
+}
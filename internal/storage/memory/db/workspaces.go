@@ -0,0 +1,135 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/user"
+)
+
+// Workspaces is in-memory database of workspaces.
+// It represented as map id->WorkspaceRecord.
+type Workspaces map[string]WorkspaceRecord
+
+// NewWorkspaces creates new in-memory workspaces database.
+func NewWorkspaces() Workspaces {
+	return make(Workspaces)
+}
+
+// WorkspaceRecord is single workspace record, it holds workspace members.
+type WorkspaceRecord struct {
+	ID      string         `json:"id"`
+	Name    string         `json:"name"`
+	Members []MemberRecord `json:"members"`
+	// Created is creation unix timestamp.
+	Created int64 `json:"created"`
+}
+
+// MemberRecord is single workspace member record.
+type MemberRecord struct {
+	UserID string `json:"user"`
+	Role   string `json:"role"`
+	// Created is creation unix timestamp.
+	Created int64 `json:"created"`
+}
+
+// NewWorkspaceRecord creates workspace record from model representation.
+func NewWorkspaceRecord(ws *model.Workspace) WorkspaceRecord {
+	return WorkspaceRecord{
+		ID:      ws.ID,
+		Name:    ws.Name,
+		Created: createdTS(ws.Created),
+	}
+}
+
+// Workspace returns model representation of workspace record.
+func (rec *WorkspaceRecord) Workspace() *model.Workspace {
+	return &model.Workspace{
+		ID:      rec.ID,
+		Name:    rec.Name,
+		Created: time.Unix(rec.Created, 0),
+	}
+}
+
+// Member returns model representation of workspace member, it returns nil if user is not a member.
+func (rec *WorkspaceRecord) Member(userID string) *model.Member {
+	if i := rec.memberIndex(userID); i >= 0 {
+		return rec.Members[i].Member(rec.ID)
+	}
+	return nil
+}
+
+// SetMember adds member to workspace or changes role of existing member.
+// Members slice is copied, so record copies do not share it.
+func (rec *WorkspaceRecord) SetMember(member *model.Member) {
+	rec.Members = slices.Clone(rec.Members)
+	if i := rec.memberIndex(member.UserID); i >= 0 {
+		rec.Members[i].Role = member.Role
+		return
+	}
+	rec.Members = append(rec.Members, MemberRecord{
+		UserID:  member.UserID,
+		Role:    member.Role,
+		Created: createdTS(member.Created),
+	})
+}
+
+// DeleteMember removes member from workspace, it returns false if user is not a member.
+// Members slice is copied, so record copies do not share it.
+func (rec *WorkspaceRecord) DeleteMember(userID string) bool {
+	i := rec.memberIndex(userID)
+	if i < 0 {
+		return false
+	}
+	rec.Members = slices.Delete(slices.Clone(rec.Members), i, i+1)
+	return true
+}
+
+// Owners returns number of workspace owners.
+func (rec *WorkspaceRecord) Owners() int {
+	var num int
+	for _, m := range rec.Members {
+		if m.Role == model.RoleOwner {
+			num++
+		}
+	}
+	return num
+}
+
+func (rec *WorkspaceRecord) memberIndex(userID string) int {
+	return slices.IndexFunc(rec.Members, func(m MemberRecord) bool { return m.UserID == userID })
+}
+
+// Member returns model representation of member record.
+func (rec *MemberRecord) Member(workspaceID string) *model.Member {
+	return &model.Member{
+		WorkspaceID: workspaceID,
+		UserID:      rec.UserID,
+		Role:        rec.Role,
+		Created:     time.Unix(rec.Created, 0),
+	}
+}
+
+// NewWorkspaceRecordFromBytes parses json encoded byte string and creates workspace record from it.
+func NewWorkspaceRecordFromBytes(data []byte) (*WorkspaceRecord, error) {
+	record := &WorkspaceRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("malformed json data: %w", err)
+	}
+	if record.ID == "" {
+		return nil, errors.New("workspace id is empty")
+	}
+	for _, m := range record.Members {
+		if _, err := user.NewFromUserID(m.UserID); err != nil {
+			return nil, fmt.Errorf("malformed user id of workspace %s member: %w", record.ID, err)
+		}
+		if !model.IsValidRole(m.Role) {
+			return nil, fmt.Errorf("unknown role of workspace %s member: %q", record.ID, m.Role)
+		}
+	}
+	return record, nil
+}
@@ -3,9 +3,14 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"maps"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/adwski/shorty/internal/model"
 	"github.com/adwski/shorty/internal/storage/memory/db"
@@ -16,17 +21,33 @@ import (
 // based on map[string]string.
 // All map operations are thread-safe.
 type Memory struct {
-	DB  db.DB
-	mux *sync.Mutex
-	gen uuid.Generator
+	DB         db.DB
+	Accounts   db.Accounts
+	APIKeys    db.APIKeys
+	Revoked    db.RevokedTokens
+	Identities db.Identities
+	Workspaces db.Workspaces
+	Transfers  db.Transfers
+	Audit      db.AuditLog
+	Quotas     db.Quotas
+	mux        *sync.Mutex
+	gen        uuid.Generator
 }
 
 // New create new memory model.
 func New() *Memory {
 	return &Memory{
-		DB:  db.NewDB(),
-		mux: &sync.Mutex{},
-		gen: uuid.NewGen(),
+		DB:         db.NewDB(),
+		Accounts:   db.NewAccounts(),
+		APIKeys:    db.NewAPIKeys(),
+		Revoked:    db.NewRevokedTokens(),
+		Identities: db.NewIdentities(),
+		Workspaces: db.NewWorkspaces(),
+		Transfers:  db.NewTransfers(),
+		Audit:      db.NewAuditLog(),
+		Quotas:     db.NewQuotas(),
+		mux:        &sync.Mutex{},
+		gen:        uuid.NewGen(),
 	}
 }
 
@@ -34,64 +55,83 @@ func New() *Memory {
 func (m *Memory) Close() {}
 
 // Get retrieves URL from model.
-func (m *Memory) Get(_ context.Context, key string) (string, error) {
+func (m *Memory) Get(_ context.Context, key string) (*model.URL, error) {
 	m.mux.Lock()
 	defer m.mux.Unlock()
 	record, ok := m.DB[key]
 	if !ok {
-		return "", model.ErrNotFound
+		return nil, model.ErrNotFound
 	}
 	if record.Deleted {
-		return "", model.ErrDeleted
+		return nil, model.ErrDeleted
 	}
-	return record.OriginalURL, nil
+	return record.URL(), nil
 }
 
-// Store stores shortened URL in model.
+// Store stores shortened URL in model. Short paths of deleted URLs stay taken
+// until they're purged, so deleted URL cannot be replaced by another user's one.
 func (m *Memory) Store(_ context.Context, url *model.URL, overwrite bool) (string, error) {
 	m.mux.Lock()
 	defer m.mux.Unlock()
-	if u, ok := m.DB[url.Short]; ok && !overwrite && !u.Deleted {
+	if _, ok := m.DB[url.Short]; ok && !overwrite {
 		return "", model.ErrAlreadyExists
 	}
 	u, err := m.gen.NewV4()
 	if err != nil {
 		return "", fmt.Errorf("cannot generate key uuid: %w", err)
 	}
-	m.DB[url.Short] = db.Record{
-		UUID:        u.String(),
-		ShortURL:    url.Short,
-		OriginalURL: url.Orig,
-		UserID:      url.UserID,
-	}
+	m.DB[url.Short] = db.NewRecord(u.String(), url)
 	return "", nil
 }
 
-// ListUserURLs returns all URL by specified user.
-func (m *Memory) ListUserURLs(_ context.Context, userID string) ([]*model.URL, error) {
+// ListUserURLs returns all not deleted personal URL by specified user.
+// If tag is not empty, only URLs labeled with tag are returned.
+func (m *Memory) ListUserURLs(_ context.Context, userID, tag string) ([]*model.URL, error) {
+	return m.listURLs(userID, "", tag), nil
+}
+
+// ListWorkspaceURLs returns all not deleted URL of workspace.
+// If tag is not empty, only URLs labeled with tag are returned.
+func (m *Memory) ListWorkspaceURLs(_ context.Context, workspaceID, tag string) ([]*model.URL, error) {
+	return m.listURLs("", workspaceID, tag), nil
+}
+
+func (m *Memory) listURLs(userID, workspaceID, tag string) []*model.URL {
 	m.mux.Lock()
 	defer m.mux.Unlock()
 	var urls []*model.URL
 	for _, record := range m.DB {
-		if record.UserID == userID {
-			urls = append(urls, &model.URL{
-				Short:  record.ShortURL,
-				Orig:   record.OriginalURL,
-				UserID: userID,
-			})
+		if record.IsOwnedBy(userID, workspaceID) && !record.Deleted && (tag == "" || record.HasTag(tag)) {
+			urls = append(urls, record.URL())
 		}
 	}
-	return urls, nil
+	return urls
 }
 
-// DeleteUserURLs deleted batch of URLs.
+// UpdateMeta updates title, tags and notes of user or workspace URL.
+func (m *Memory) UpdateMeta(_ context.Context, url *model.URL) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	record, ok := m.DB[url.Short]
+	if !ok || record.Deleted || !record.IsOwnedBy(url.UserID, url.WorkspaceID) {
+		return model.ErrNotFound
+	}
+	record.Title = url.Title
+	record.Tags = url.Tags
+	record.Notes = url.Notes
+	m.DB[url.Short] = record
+	return nil
+}
+
+// DeleteUserURLs deleted batch of URLs. URLs with workspace id are deleted from workspace,
+// other URLs are deleted only if they're personal URLs of user.
 func (m *Memory) DeleteUserURLs(_ context.Context, urls []model.URL) (int64, error) {
 	m.mux.Lock()
 	defer m.mux.Unlock()
 	var num int64
 	for _, url := range urls {
 		if record, ok := m.DB[url.Short]; ok {
-			if record.UserID == url.UserID {
+			if record.IsOwnedBy(url.UserID, url.WorkspaceID) {
 				record.Deleted = true
 				m.DB[url.Short] = record
 				num++
@@ -101,13 +141,93 @@ func (m *Memory) DeleteUserURLs(_ context.Context, urls []model.URL) (int64, err
 	return num, nil
 }
 
-// StoreBatch stores URL batch.
+// PurgeDeleted permanently removes deleted URLs and returns their number.
+func (m *Memory) PurgeDeleted(_ context.Context) (int64, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	var num int64
+	for short, record := range m.DB {
+		if record.Deleted {
+			delete(m.DB, short)
+			num++
+		}
+	}
+	return num, nil
+}
+
+// ScanURLs calls fn for every URL including deleted ones with short path greater than after.
+// URLs are passed in short path order as they were at the moment of call along with
+// variant click counters. Storage is copied before iteration, so fn may use storage.
+func (m *Memory) ScanURLs(_ context.Context, after string, fn func(url *model.URL) error) error {
+	m.mux.Lock()
+	urls := make([]*model.URL, 0, len(m.DB))
+	for short, record := range m.DB {
+		if short > after {
+			urls = append(urls, record.URLAsIs())
+		}
+	}
+	m.mux.Unlock()
+	slices.SortFunc(urls, func(a, b *model.URL) int {
+		return strings.Compare(a.Short, b.Short)
+	})
+	for _, url := range urls {
+		if err := fn(url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadURLs reads URLs from next until it returns io.EOF and stores them as is,
+// keeping their UUIDs, owners, deleted flags, creation time and variant click counters.
+// Unknown creation time is kept unset. URLs with the same short path are overwritten.
+// If replace is set, all other URLs are removed. Storage is changed only if all URLs are read.
+func (m *Memory) LoadURLs(_ context.Context, next func() (*model.URL, error), replace bool) error {
+	loaded := db.NewDB()
+	for {
+		url, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if loaded[url.Short], err = m.recordAsIs(url); err != nil {
+			return err
+		}
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if replace {
+		m.DB = loaded
+		return nil
+	}
+	maps.Copy(m.DB, loaded)
+	return nil
+}
+
+// recordAsIs creates record keeping URL identity, UUID is generated only if URL has none.
+func (m *Memory) recordAsIs(url *model.URL) (db.Record, error) {
+	if url.UUID != "" {
+		if _, err := uuid.FromString(url.UUID); err != nil {
+			return db.Record{}, fmt.Errorf("malformed uuid of %s: %w", url.Short, err)
+		}
+		return db.NewRecordAsIs(url.UUID, url), nil
+	}
+	u, err := m.gen.NewV4()
+	if err != nil {
+		return db.Record{}, fmt.Errorf("cannot generate key uuid: %w", err)
+	}
+	return db.NewRecordAsIs(u.String(), url), nil
+}
+
+// StoreBatch stores URL batch. Like Store, it doesn't replace deleted URLs.
 func (m *Memory) StoreBatch(_ context.Context, urls []model.URL) error {
 	m.mux.Lock()
 	defer m.mux.Unlock()
 	IDs := make([]string, len(urls))
 	for i, url := range urls {
-		if u, ok := m.DB[url.Short]; ok && !u.Deleted {
+		if _, ok := m.DB[url.Short]; ok {
 			return model.ErrAlreadyExists
 		}
 		u, err := m.gen.NewV4()
@@ -116,17 +236,491 @@ func (m *Memory) StoreBatch(_ context.Context, urls []model.URL) error {
 		}
 		IDs[i] = u.String()
 	}
-	for i, url := range urls {
-		m.DB[url.Short] = db.Record{
-			UUID:        IDs[i],
-			ShortURL:    url.Short,
-			OriginalURL: url.Orig,
-			UserID:      url.UserID,
+	for i := range urls {
+		m.DB[urls[i].Short] = db.NewRecord(IDs[i], &urls[i])
+	}
+	return nil
+}
+
+// ClaimUserURLs transfers active personal URLs of one user to another user.
+// Workspace URLs keep their creator and deleted URLs stay with former owner.
+func (m *Memory) ClaimUserURLs(_ context.Context, fromUserID, toUserID string) (int64, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	var num int64
+	for short, record := range m.DB {
+		if record.UserID == fromUserID && record.WorkspaceID == "" && !record.Deleted {
+			record.UserID = toUserID
+			m.DB[short] = record
+			num++
+		}
+	}
+	return num, nil
+}
+
+// CreateAccount stores new user account. Logins and user ids of accounts are unique.
+func (m *Memory) CreateAccount(_ context.Context, acc *model.Account) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if _, ok := m.Accounts[acc.Login]; ok {
+		return model.ErrAlreadyExists
+	}
+	for _, record := range m.Accounts {
+		if record.UserID == acc.UserID {
+			return model.ErrAlreadyExists
+		}
+	}
+	m.Accounts[acc.Login] = db.NewAccountRecord(acc)
+	return nil
+}
+
+// GetAccount retrieves user account by login.
+func (m *Memory) GetAccount(_ context.Context, login string) (*model.Account, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	record, ok := m.Accounts[login]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return record.Account(), nil
+}
+
+// DumpAccounts returns copy of in-memory accounts database.
+func (m *Memory) DumpAccounts() db.Accounts {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	dump := make(db.Accounts, len(m.Accounts))
+	maps.Copy(dump, m.Accounts)
+	return dump
+}
+
+// CreateIdentity stores link of external identity to user. Subjects are unique within issuer.
+func (m *Memory) CreateIdentity(_ context.Context, identity *model.Identity) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	key := db.IdentityKey(identity.Issuer, identity.Subject)
+	if _, ok := m.Identities[key]; ok {
+		return model.ErrAlreadyExists
+	}
+	m.Identities[key] = db.NewIdentityRecord(identity)
+	return nil
+}
+
+// GetIdentity retrieves external identity by issuer and subject.
+func (m *Memory) GetIdentity(_ context.Context, issuer, subject string) (*model.Identity, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	record, ok := m.Identities[db.IdentityKey(issuer, subject)]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return record.Identity(), nil
+}
+
+// DumpIdentities returns copy of in-memory identities database.
+func (m *Memory) DumpIdentities() db.Identities {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	dump := make(db.Identities, len(m.Identities))
+	maps.Copy(dump, m.Identities)
+	return dump
+}
+
+// CreateWorkspace stores new workspace with its owner. Workspace ids are unique.
+func (m *Memory) CreateWorkspace(_ context.Context, ws *model.Workspace, ownerID string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if _, ok := m.Workspaces[ws.ID]; ok {
+		return model.ErrAlreadyExists
+	}
+	record := db.NewWorkspaceRecord(ws)
+	record.SetMember(&model.Member{UserID: ownerID, Role: model.RoleOwner, Created: ws.Created})
+	m.Workspaces[ws.ID] = record
+	return nil
+}
+
+// GetWorkspace retrieves workspace by id.
+func (m *Memory) GetWorkspace(_ context.Context, id string) (*model.Workspace, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	record, ok := m.Workspaces[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return record.Workspace(), nil
+}
+
+// ListWorkspaces returns workspaces user is member of ordered by creation time.
+// Role of user is set in every workspace.
+func (m *Memory) ListWorkspaces(_ context.Context, userID string) ([]*model.Workspace, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	var workspaces []*model.Workspace
+	for _, record := range m.Workspaces {
+		if member := record.Member(userID); member != nil {
+			ws := record.Workspace()
+			ws.Role = member.Role
+			workspaces = append(workspaces, ws)
+		}
+	}
+	slices.SortFunc(workspaces, func(a, b *model.Workspace) int {
+		if c := a.Created.Compare(b.Created); c != 0 {
+			return c
 		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return workspaces, nil
+}
+
+// GetMember retrieves membership of user in workspace.
+func (m *Memory) GetMember(_ context.Context, workspaceID, userID string) (*model.Member, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	record, ok := m.Workspaces[workspaceID]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	member := record.Member(userID)
+	if member == nil {
+		return nil, model.ErrNotFound
+	}
+	return member, nil
+}
+
+// ListMembers returns members of workspace in order they were added.
+func (m *Memory) ListMembers(_ context.Context, workspaceID string) ([]*model.Member, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	record, ok := m.Workspaces[workspaceID]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	members := make([]*model.Member, 0, len(record.Members))
+	for i := range record.Members {
+		members = append(members, record.Members[i].Member(workspaceID))
+	}
+	return members, nil
+}
+
+// SetMember adds member to workspace or changes role of existing member.
+// Workspace must keep at least one owner, otherwise ErrConflict is returned.
+func (m *Memory) SetMember(_ context.Context, member *model.Member) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	record, ok := m.Workspaces[member.WorkspaceID]
+	if !ok {
+		return model.ErrNotFound
+	}
+	record.SetMember(member)
+	if record.Owners() == 0 {
+		return model.ErrConflict
+	}
+	m.Workspaces[member.WorkspaceID] = record
+	return nil
+}
+
+// DeleteMember removes member from workspace.
+// Workspace must keep at least one owner, otherwise ErrConflict is returned.
+func (m *Memory) DeleteMember(_ context.Context, workspaceID, userID string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	record, ok := m.Workspaces[workspaceID]
+	if !ok || !record.DeleteMember(userID) {
+		return model.ErrNotFound
+	}
+	if record.Owners() == 0 {
+		return model.ErrConflict
 	}
+	m.Workspaces[workspaceID] = record
 	return nil
 }
 
+// DumpWorkspaces returns copy of in-memory workspaces database.
+func (m *Memory) DumpWorkspaces() db.Workspaces {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	dump := make(db.Workspaces, len(m.Workspaces))
+	maps.Copy(dump, m.Workspaces)
+	return dump
+}
+
+// CreateTransfer stores new transfer offer. Transfer ids are unique.
+func (m *Memory) CreateTransfer(_ context.Context, transfer *model.Transfer) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if _, ok := m.Transfers[transfer.ID]; ok {
+		return model.ErrAlreadyExists
+	}
+	m.Transfers[transfer.ID] = db.NewTransferRecord(transfer)
+	return nil
+}
+
+// GetTransfer retrieves transfer offer by id.
+func (m *Memory) GetTransfer(_ context.Context, id string) (*model.Transfer, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	record, ok := m.Transfers[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return record.Transfer(), nil
+}
+
+// ListTransfers returns transfer offers sent or received by user ordered by creation time.
+func (m *Memory) ListTransfers(_ context.Context, userID string) ([]*model.Transfer, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	var transfers []*model.Transfer
+	for _, record := range m.Transfers {
+		if record.From == userID || record.To == userID {
+			transfers = append(transfers, record.Transfer())
+		}
+	}
+	slices.SortFunc(transfers, func(a, b *model.Transfer) int {
+		if c := a.Created.Compare(b.Created); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return transfers, nil
+}
+
+// DeleteTransfer deletes transfer offer.
+func (m *Memory) DeleteTransfer(_ context.Context, id string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if _, ok := m.Transfers[id]; !ok {
+		return model.ErrNotFound
+	}
+	delete(m.Transfers, id)
+	return nil
+}
+
+// TransferURLs atomically changes owner of personal URLs listed in audit event and records event.
+// Every URL must be active personal URL of previous owner, otherwise ErrConflict is returned
+// and nothing is changed. If event has transfer id, transfer offer is consumed.
+func (m *Memory) TransferURLs(_ context.Context, event *model.AuditEvent) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if _, ok := m.Audit[event.ID]; ok {
+		return model.ErrAlreadyExists
+	}
+	if _, ok := m.Transfers[event.TransferID]; event.TransferID != "" && !ok {
+		return model.ErrNotFound
+	}
+	for _, short := range event.Shorts {
+		record, ok := m.DB[short]
+		if !ok || record.Deleted || record.UserID != event.FromUserID || record.WorkspaceID != "" {
+			return model.ErrConflict
+		}
+	}
+	for _, short := range event.Shorts {
+		record := m.DB[short]
+		record.UserID = event.ToUserID
+		m.DB[short] = record
+	}
+	delete(m.Transfers, event.TransferID)
+	m.Audit[event.ID] = db.NewAuditEventRecord(event)
+	return nil
+}
+
+// ListAuditEvents returns up to limit latest audit events, newest first.
+func (m *Memory) ListAuditEvents(_ context.Context, limit int) ([]*model.AuditEvent, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	events := make([]*model.AuditEvent, 0, len(m.Audit))
+	for _, record := range m.Audit {
+		events = append(events, record.AuditEvent())
+	}
+	slices.SortFunc(events, func(a, b *model.AuditEvent) int {
+		if c := b.Created.Compare(a.Created); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// DumpTransfers returns copy of in-memory transfers database.
+func (m *Memory) DumpTransfers() db.Transfers {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	dump := make(db.Transfers, len(m.Transfers))
+	maps.Copy(dump, m.Transfers)
+	return dump
+}
+
+// DumpAuditLog returns copy of in-memory audit events database.
+func (m *Memory) DumpAuditLog() db.AuditLog {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	dump := make(db.AuditLog, len(m.Audit))
+	maps.Copy(dump, m.Audit)
+	return dump
+}
+
+// CountUserURLs returns number of active personal URLs of user.
+func (m *Memory) CountUserURLs(_ context.Context, userID string) (int, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	var count int
+	for _, record := range m.DB {
+		if record.IsOwnedBy(userID, "") && !record.Deleted {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetQuota retrieves quota assignment of user.
+func (m *Memory) GetQuota(_ context.Context, userID string) (*model.Quota, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	record, ok := m.Quotas[userID]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return record.Quota(), nil
+}
+
+// SetQuota creates or replaces quota assignment of user.
+func (m *Memory) SetQuota(_ context.Context, quota *model.Quota) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.Quotas[quota.UserID] = db.NewQuotaRecord(quota)
+	return nil
+}
+
+// DeleteQuota deletes quota assignment of user.
+func (m *Memory) DeleteQuota(_ context.Context, userID string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if _, ok := m.Quotas[userID]; !ok {
+		return model.ErrNotFound
+	}
+	delete(m.Quotas, userID)
+	return nil
+}
+
+// DumpQuotas returns copy of in-memory quotas database.
+func (m *Memory) DumpQuotas() db.Quotas {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	dump := make(db.Quotas, len(m.Quotas))
+	maps.Copy(dump, m.Quotas)
+	return dump
+}
+
+// CreateAPIKey stores new api key. Key ids are unique.
+func (m *Memory) CreateAPIKey(_ context.Context, key *model.APIKey) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if _, ok := m.APIKeys[key.ID]; ok {
+		return model.ErrAlreadyExists
+	}
+	m.APIKeys[key.ID] = db.NewAPIKeyRecord(key)
+	return nil
+}
+
+// GetAPIKey retrieves api key by id.
+func (m *Memory) GetAPIKey(_ context.Context, id string) (*model.APIKey, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	record, ok := m.APIKeys[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return record.APIKey(), nil
+}
+
+// ListAPIKeys returns api keys of user ordered by creation time.
+func (m *Memory) ListAPIKeys(_ context.Context, userID string) ([]*model.APIKey, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	var keys []*model.APIKey
+	for _, record := range m.APIKeys {
+		if record.UserID == userID {
+			keys = append(keys, record.APIKey())
+		}
+	}
+	slices.SortFunc(keys, func(a, b *model.APIKey) int {
+		if c := a.Created.Compare(b.Created); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return keys, nil
+}
+
+// UpdateAPIKey updates name and scopes of user api key.
+func (m *Memory) UpdateAPIKey(_ context.Context, key *model.APIKey) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	record, ok := m.APIKeys[key.ID]
+	if !ok || record.UserID != key.UserID {
+		return model.ErrNotFound
+	}
+	record.Name = key.Name
+	record.Scopes = slices.Clone(key.Scopes)
+	m.APIKeys[key.ID] = record
+	return nil
+}
+
+// DeleteAPIKey deletes user api key.
+func (m *Memory) DeleteAPIKey(_ context.Context, userID, id string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	record, ok := m.APIKeys[id]
+	if !ok || record.UserID != userID {
+		return model.ErrNotFound
+	}
+	delete(m.APIKeys, id)
+	return nil
+}
+
+// DumpAPIKeys returns copy of in-memory api keys database.
+func (m *Memory) DumpAPIKeys() db.APIKeys {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	dump := make(db.APIKeys, len(m.APIKeys))
+	maps.Copy(dump, m.APIKeys)
+	return dump
+}
+
+// RevokeToken adds session token id to denylist until token expires.
+// Expired records are removed.
+func (m *Memory) RevokeToken(_ context.Context, id string, expires time.Time) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.Revoked.Prune(time.Now().Unix())
+	if record, ok := m.Revoked[id]; ok && record.Expires > expires.Unix() {
+		return nil
+	}
+	m.Revoked[id] = db.RevokedTokenRecord{ID: id, Expires: expires.Unix()}
+	return nil
+}
+
+// IsTokenRevoked returns whether session token id is in denylist.
+func (m *Memory) IsTokenRevoked(_ context.Context, id string) (bool, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	record, ok := m.Revoked[id]
+	return ok && record.Expires >= time.Now().Unix(), nil
+}
+
+// DumpRevokedTokens returns copy of in-memory revoked tokens database without expired records.
+func (m *Memory) DumpRevokedTokens() db.RevokedTokens {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.Revoked.Prune(time.Now().Unix())
+	dump := make(db.RevokedTokens, len(m.Revoked))
+	maps.Copy(dump, m.Revoked)
+	return dump
+}
+
 // Dump returns copy of in-memory URL database.
 func (m *Memory) Dump() db.DB {
 	m.mux.Lock()
@@ -145,3 +739,35 @@ func (m *Memory) Ping(_ context.Context) error {
 func (m *Memory) Stats(_ context.Context) (*model.Stats, error) {
 	return m.DB.Stats(), nil
 }
+
+// AddVariantClicks increments click counters of URL variants.
+// Counters are copied on write, so records returned by Dump are not changed.
+func (m *Memory) AddVariantClicks(_ context.Context, clicks []model.Click) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	for _, click := range clicks {
+		record, ok := m.DB[click.Short]
+		if !ok || click.Variant < 0 || click.Variant >= len(record.Variants) {
+			continue
+		}
+		counters := make([]int64, len(record.Variants))
+		copy(counters, record.Clicks)
+		counters[click.Variant]++
+		record.Clicks = counters
+		m.DB[click.Short] = record
+	}
+	return nil
+}
+
+// GetVariantClicks returns click counters of URL variants.
+func (m *Memory) GetVariantClicks(_ context.Context, short string) ([]int64, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	record, ok := m.DB[short]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	clicks := make([]int64, len(record.Variants))
+	copy(clicks, record.Clicks)
+	return clicks, nil
+}
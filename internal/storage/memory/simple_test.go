@@ -2,8 +2,10 @@ package memory
 
 import (
 	"context"
+	"io"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/adwski/shorty/internal/model"
 	"github.com/adwski/shorty/internal/storage/memory/db"
@@ -39,7 +41,7 @@ func TestNewSimple(t *testing.T) {
 
 			url, err2 := tt.si.Get(ctx, tt.args.Short)
 			require.NoError(t, err2)
-			assert.Equal(t, tt.args.Orig, url)
+			assert.Equal(t, tt.args.Orig, url.Orig)
 		})
 	}
 }
@@ -91,7 +93,7 @@ func TestNewSimple_Get(t *testing.T) {
 			url, err := si.Get(context.Background(), tt.args.url.Short)
 			if tt.err == nil {
 				require.NoError(t, err)
-				assert.Equal(t, tt.args.url.Orig, url)
+				assert.Equal(t, tt.args.url.Orig, url.Orig)
 			} else {
 				assert.Equal(t, tt.err, err)
 			}
@@ -170,3 +172,336 @@ func TestNewSimple_Store(t *testing.T) {
 		})
 	}
 }
+
+func TestMemory_VariantClicks(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	_, err := m.Store(ctx, &model.URL{
+		Short: "aaa",
+		Orig:  "https://bbb.ccc",
+		Variants: []model.Variant{
+			{URL: "https://ddd.eee", Weight: 1},
+			{URL: "https://fff.ggg", Weight: 2},
+		},
+	}, false)
+	require.NoError(t, err)
+
+	clicks, err := m.GetVariantClicks(ctx, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0, 0}, clicks)
+
+	err = m.AddVariantClicks(ctx, []model.Click{
+		{Short: "aaa", Variant: 1},
+		{Short: "aaa", Variant: 1},
+		{Short: "aaa", Variant: 0},
+		{Short: "aaa", Variant: 5},
+		{Short: "zzz", Variant: 0},
+	})
+	require.NoError(t, err)
+
+	clicks, err = m.GetVariantClicks(ctx, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, clicks)
+
+	_, err = m.GetVariantClicks(ctx, "zzz")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestMemory_Meta(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	for _, u := range []*model.URL{
+		{Short: "aaa", Orig: "https://bbb.ccc", UserID: "user", Tags: []string{"promo", "spring"}},
+		{Short: "ddd", Orig: "https://eee.fff", UserID: "user"},
+		{Short: "ggg", Orig: "https://hhh.iii", UserID: "other", Tags: []string{"promo"}},
+	} {
+		_, err := m.Store(ctx, u, false)
+		require.NoError(t, err)
+	}
+
+	urls, err := m.ListUserURLs(ctx, "user", "promo")
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "aaa", urls[0].Short)
+
+	urls, err = m.ListUserURLs(ctx, "user", "")
+	require.NoError(t, err)
+	assert.Len(t, urls, 2)
+
+	err = m.UpdateMeta(ctx, &model.URL{Short: "ddd", UserID: "user", Title: "Docs", Tags: []string{"promo"}, Notes: "n"})
+	require.NoError(t, err)
+	u, err := m.Get(ctx, "ddd")
+	require.NoError(t, err)
+	assert.Equal(t, "Docs", u.Title)
+	assert.Equal(t, []string{"promo"}, u.Tags)
+	assert.Equal(t, "n", u.Notes)
+
+	urls, err = m.ListUserURLs(ctx, "user", "promo")
+	require.NoError(t, err)
+	assert.Len(t, urls, 2)
+
+	err = m.UpdateMeta(ctx, &model.URL{Short: "ggg", UserID: "user", Title: "Docs"})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	err = m.UpdateMeta(ctx, &model.URL{Short: "zzz", UserID: "user"})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestMemory_StoreDeletedShort(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	_, err := m.Store(ctx, &model.URL{Short: "aaa", Orig: "https://bbb.ccc", UserID: "owner"}, false)
+	require.NoError(t, err)
+	_, err = m.DeleteUserURLs(ctx, []model.URL{{Short: "aaa", UserID: "owner"}})
+	require.NoError(t, err)
+
+	// deleted url keeps its short path
+	_, err = m.Store(ctx, &model.URL{Short: "aaa", Orig: "https://eee.fff", UserID: "other"}, false)
+	assert.ErrorIs(t, err, model.ErrAlreadyExists)
+	err = m.StoreBatch(ctx, []model.URL{{Short: "aaa", Orig: "https://eee.fff", UserID: "other"}})
+	assert.ErrorIs(t, err, model.ErrAlreadyExists)
+	_, err = m.Get(ctx, "aaa")
+	assert.ErrorIs(t, err, model.ErrDeleted)
+
+	// purged short path is free again
+	_, err = m.PurgeDeleted(ctx)
+	require.NoError(t, err)
+	_, err = m.Store(ctx, &model.URL{Short: "aaa", Orig: "https://eee.fff", UserID: "other"}, false)
+	require.NoError(t, err)
+}
+
+func TestMemory_ClaimUserURLs(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	require.NoError(t, m.StoreBatch(ctx, []model.URL{
+		{Short: "aaa", Orig: "https://aaa", UserID: "anon"},
+		{Short: "bbb", Orig: "https://bbb", UserID: "anon", WorkspaceID: "ws"},
+		{Short: "ccc", Orig: "https://ccc", UserID: "anon"},
+	}))
+	_, err := m.DeleteUserURLs(ctx, []model.URL{{Short: "ccc", UserID: "anon"}})
+	require.NoError(t, err)
+
+	num, err := m.ClaimUserURLs(ctx, "anon", "account")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), num)
+	dump := m.Dump()
+	assert.Equal(t, "account", dump["aaa"].UserID)
+	assert.Equal(t, "anon", dump["bbb"].UserID)
+	assert.Equal(t, "anon", dump["ccc"].UserID)
+}
+
+func TestMemory_ScanAndPurge(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	require.NoError(t, m.StoreBatch(ctx, []model.URL{
+		{Short: "aaa", Orig: "https://bbb.ccc", UserID: "user"},
+		{Short: "ddd", Orig: "https://eee.fff", UserID: "user"},
+	}))
+	_, err := m.DeleteUserURLs(ctx, []model.URL{{Short: "ddd", UserID: "user"}})
+	require.NoError(t, err)
+
+	var shorts []string
+	require.NoError(t, m.ScanURLs(ctx, "", func(url *model.URL) error {
+		shorts = append(shorts, url.Short)
+		return nil
+	}))
+	assert.Equal(t, []string{"aaa", "ddd"}, shorts)
+
+	num, err := m.PurgeDeleted(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), num)
+	_, err = m.Get(ctx, "ddd")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestMemory_ScanAndLoad(t *testing.T) {
+	ctx := context.Background()
+	created := time.Unix(1700000000, 0)
+	variants := []model.Variant{{URL: "https://v1", Weight: 1}, {URL: "https://v2", Weight: 1}}
+	m := New()
+	require.NoError(t, m.LoadURLs(ctx, reader([]model.URL{
+		{Short: "ccc", Orig: "https://ccc", UserID: "user", UUID: "6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+		{Short: "aaa", Orig: "https://aaa", UserID: "user", Deleted: true, Created: created},
+		{Short: "bbb", Orig: "https://bbb", UserID: "other", Variants: variants, Clicks: []int64{3, 5}},
+	}), false))
+	err := m.LoadURLs(ctx, reader([]model.URL{{Short: "ddd", Orig: "https://ddd", UUID: "bad"}}), false)
+	assert.Error(t, err)
+
+	scan := func(after string) []*model.URL {
+		var urls []*model.URL
+		require.NoError(t, m.ScanURLs(ctx, after, func(url *model.URL) error {
+			urls = append(urls, url)
+			return nil
+		}))
+		return urls
+	}
+	urls := scan("")
+	require.Len(t, urls, 3)
+	assert.Equal(t, "aaa", urls[0].Short)
+	assert.True(t, urls[0].Deleted)
+	assert.Equal(t, created, urls[0].Created)
+	assert.NotEmpty(t, urls[0].UUID)
+	assert.Equal(t, "bbb", urls[1].Short)
+	assert.True(t, urls[1].Created.IsZero())
+	assert.Equal(t, []int64{3, 5}, urls[1].Clicks)
+
+	urls = scan("bbb")
+	require.Len(t, urls, 1)
+	assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", urls[0].UUID)
+	assert.Equal(t, "user", urls[0].UserID)
+
+	_, err = m.Get(ctx, "aaa")
+	assert.ErrorIs(t, err, model.ErrDeleted)
+	clicks, err := m.GetVariantClicks(ctx, "bbb")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 5}, clicks)
+
+	// replace drops other urls
+	require.NoError(t, m.LoadURLs(ctx, reader([]model.URL{{Short: "bbb", Orig: "https://bbb", Variants: variants}}), true))
+	urls = scan("")
+	require.Len(t, urls, 1)
+	assert.Empty(t, urls[0].Clicks)
+}
+
+func reader(urls []model.URL) func() (*model.URL, error) {
+	var i int
+	return func() (*model.URL, error) {
+		if i == len(urls) {
+			return nil, io.EOF
+		}
+		i++
+		return &urls[i-1], nil
+	}
+}
+
+func TestMemory_Workspaces(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	require.NoError(t, m.CreateWorkspace(ctx, &model.Workspace{ID: "ws", Name: "Marketing"}, "owner"))
+	assert.ErrorIs(t, m.CreateWorkspace(ctx, &model.Workspace{ID: "ws"}, "other"), model.ErrAlreadyExists)
+	require.NoError(t, m.SetMember(ctx, &model.Member{WorkspaceID: "ws", UserID: "editor", Role: model.RoleEditor}))
+	assert.ErrorIs(t, m.SetMember(ctx, &model.Member{WorkspaceID: "zz", UserID: "editor"}), model.ErrNotFound)
+
+	workspaces, err := m.ListWorkspaces(ctx, "editor")
+	require.NoError(t, err)
+	require.Len(t, workspaces, 1)
+	assert.Equal(t, "Marketing", workspaces[0].Name)
+	assert.Equal(t, model.RoleEditor, workspaces[0].Role)
+	members, err := m.ListMembers(ctx, "ws")
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, "owner", members[0].UserID)
+
+	// last owner cannot be demoted or removed
+	assert.ErrorIs(t, m.SetMember(ctx, &model.Member{WorkspaceID: "ws", UserID: "owner", Role: model.RoleViewer}),
+		model.ErrConflict)
+	assert.ErrorIs(t, m.DeleteMember(ctx, "ws", "owner"), model.ErrConflict)
+	member, err := m.GetMember(ctx, "ws", "owner")
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleOwner, member.Role)
+	require.NoError(t, m.DeleteMember(ctx, "ws", "editor"))
+	_, err = m.GetMember(ctx, "ws", "editor")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	// workspace links are managed within workspace, not by creator
+	for _, u := range []*model.URL{
+		{Short: "aaa", Orig: "https://bbb.ccc", UserID: "owner", WorkspaceID: "ws"},
+		{Short: "ddd", Orig: "https://eee.fff", UserID: "owner"},
+	} {
+		_, err = m.Store(ctx, u, false)
+		require.NoError(t, err)
+	}
+	urls, err := m.ListUserURLs(ctx, "owner", "")
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "ddd", urls[0].Short)
+	urls, err = m.ListWorkspaceURLs(ctx, "ws", "")
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "aaa", urls[0].Short)
+
+	err = m.UpdateMeta(ctx, &model.URL{Short: "aaa", UserID: "owner", Title: "Docs"})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	require.NoError(t, m.UpdateMeta(ctx, &model.URL{Short: "aaa", UserID: "other", WorkspaceID: "ws", Title: "Docs"}))
+	num, err := m.DeleteUserURLs(ctx, []model.URL{{Short: "aaa", UserID: "owner"}})
+	require.NoError(t, err)
+	assert.Zero(t, num)
+	num, err = m.DeleteUserURLs(ctx, []model.URL{{Short: "aaa", UserID: "other", WorkspaceID: "ws"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), num)
+}
+
+func TestMemory_Transfers(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	for _, u := range []*model.URL{
+		{Short: "aaa", Orig: "https://bbb.ccc", UserID: "from"},
+		{Short: "ddd", Orig: "https://eee.fff", UserID: "from", WorkspaceID: "ws"},
+	} {
+		_, err := m.Store(ctx, u, false)
+		require.NoError(t, err)
+	}
+	transfer := &model.Transfer{ID: "t1", FromUserID: "from", ToUserID: "to", Shorts: []string{"aaa"}}
+	require.NoError(t, m.CreateTransfer(ctx, transfer))
+	assert.ErrorIs(t, m.CreateTransfer(ctx, transfer), model.ErrAlreadyExists)
+	for _, userID := range []string{"from", "to"} {
+		transfers, err := m.ListTransfers(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, transfers, 1)
+		assert.Equal(t, "t1", transfers[0].ID)
+	}
+
+	// workspace links cannot be transferred, nothing is changed
+	event := &model.AuditEvent{ID: "e1", Action: model.AuditTransferAccepted, TransferID: "t1",
+		FromUserID: "from", ToUserID: "to", Shorts: []string{"aaa", "ddd"}}
+	assert.ErrorIs(t, m.TransferURLs(ctx, event), model.ErrConflict)
+	_, err := m.GetTransfer(ctx, "t1")
+	require.NoError(t, err)
+
+	event.Shorts = []string{"aaa"}
+	require.NoError(t, m.TransferURLs(ctx, event))
+	url, err := m.Get(ctx, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, "to", url.UserID)
+	_, err = m.GetTransfer(ctx, "t1")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	event.ID = "e2"
+	assert.ErrorIs(t, m.TransferURLs(ctx, event), model.ErrNotFound)
+
+	events, err := m.ListAuditEvents(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "e1", events[0].ID)
+	assert.Equal(t, "t1", events[0].TransferID)
+}
+
+func TestMemory_Quotas(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	for _, u := range []*model.URL{
+		{Short: "aaa", Orig: "https://bbb.ccc", UserID: "user"},
+		{Short: "ddd", Orig: "https://eee.fff", UserID: "user", WorkspaceID: "ws"},
+		{Short: "ggg", Orig: "https://hhh.iii", UserID: "user"},
+		{Short: "jjj", Orig: "https://kkk.lll", UserID: "other"},
+	} {
+		_, err := m.Store(ctx, u, false)
+		require.NoError(t, err)
+	}
+	_, err := m.DeleteUserURLs(ctx, []model.URL{{Short: "ggg", UserID: "user"}})
+	require.NoError(t, err)
+
+	// only active personal links are counted
+	count, err := m.CountUserURLs(ctx, "user")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	_, err = m.GetQuota(ctx, "user")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	require.NoError(t, m.SetQuota(ctx, &model.Quota{UserID: "user", Tier: "pro"}))
+	quota, err := m.GetQuota(ctx, "user")
+	require.NoError(t, err)
+	assert.Equal(t, "pro", quota.Tier)
+	assert.False(t, quota.Updated.IsZero())
+
+	require.NoError(t, m.DeleteQuota(ctx, "user"))
+	assert.ErrorIs(t, m.DeleteQuota(ctx, "user"), model.ErrNotFound)
+}
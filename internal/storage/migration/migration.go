@@ -0,0 +1,346 @@
+// Package migration moves URLs between storages keeping their identity.
+//
+// Records are read in short path order from consistent snapshot of source storage,
+// so migration can run while server is serving requests. Records are stored page by page,
+// progress is saved to checkpoint file after each page, interrupted migration
+// is resumed from it. Records are overwritten in destination, so migration can be
+// repeated to catch up with changes made in source during previous run.
+// Variant click counters are migrated along with records.
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultPageSize is number of records read and stored at once.
+	DefaultPageSize = 1000
+
+	checkpointPermission = 0600
+)
+
+// ErrMismatch is returned when storage summaries differ.
+var ErrMismatch = errors.New("storages do not match")
+
+// Source is a storage that can be scanned in short path order starting after cursor.
+type Source interface {
+	ScanURLs(ctx context.Context, after string, fn func(url *model.URL) error) error
+}
+
+// Destination is a storage that can store URLs as is.
+type Destination interface {
+	LoadURLs(ctx context.Context, next func() (*model.URL, error), replace bool) error
+}
+
+// Config is migration configuration.
+type Config struct {
+	Logger      *zap.Logger
+	Source      Source
+	Destination Destination
+	// CheckpointPath is a path of file where progress is saved.
+	// If it's empty, progress is not saved and migration always starts from the beginning.
+	CheckpointPath string
+	PageSize       int
+}
+
+// Migrator copies all URLs including deleted ones from source to destination storage.
+type Migrator struct {
+	log        *zap.Logger
+	src        Source
+	dst        Destination
+	checkpoint string
+	pageSize   int
+}
+
+// Result is migration progress. It's also saved as checkpoint.
+type Result struct {
+	// Cursor is short path of last migrated record.
+	Cursor   string `json:"cursor"`
+	Migrated int64  `json:"migrated"`
+	Deleted  int64  `json:"deleted"`
+	Failed   int64  `json:"failed"`
+}
+
+// New creates migrator.
+func New(cfg *Config) (*Migrator, error) {
+	if cfg.Logger == nil {
+		return nil, errors.New("nil logger")
+	}
+	if cfg.Source == nil || cfg.Destination == nil {
+		return nil, errors.New("source and destination must be set")
+	}
+	pageSize := cfg.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return &Migrator{
+		log:        cfg.Logger.With(zap.String("component", "migration")),
+		src:        cfg.Source,
+		dst:        cfg.Destination,
+		checkpoint: cfg.CheckpointPath,
+		pageSize:   pageSize,
+	}, nil
+}
+
+// Run migrates records starting after checkpoint if there's one.
+// Records that cannot be stored are skipped and counted as failed.
+// Checkpoint is removed after successful migration, so next run starts from the beginning.
+func (m *Migrator) Run(ctx context.Context) (*Result, error) {
+	result, err := m.loadCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	if result.Cursor != "" {
+		m.log.Info("resuming migration", zap.String("cursor", result.Cursor))
+	}
+	page := make([]model.URL, 0, m.pageSize)
+	flush := func() error {
+		if errR := m.restorePage(ctx, page, result); errR != nil {
+			return errR
+		}
+		result.Cursor = page[len(page)-1].Short
+		page = page[:0]
+		if errS := m.saveCheckpoint(result); errS != nil {
+			return errS
+		}
+		m.log.Debug("page migrated",
+			zap.String("cursor", result.Cursor),
+			zap.Int64("migrated", result.Migrated))
+		return nil
+	}
+	var errFlush error
+	err = m.src.ScanURLs(ctx, result.Cursor, func(url *model.URL) error {
+		page = append(page, *url)
+		if len(page) < m.pageSize {
+			return nil
+		}
+		errFlush = flush()
+		return errFlush
+	})
+	if errFlush != nil {
+		return result, errFlush
+	}
+	if err != nil {
+		return result, fmt.Errorf("cannot read source: %w", err)
+	}
+	if len(page) > 0 {
+		if err = flush(); err != nil {
+			return result, err
+		}
+	}
+	if m.checkpoint != "" {
+		if err = os.Remove(m.checkpoint); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return result, fmt.Errorf("cannot remove checkpoint: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// restorePage stores page of records. If page cannot be stored as a whole,
+// records are stored one by one to skip only failing ones.
+func (m *Migrator) restorePage(ctx context.Context, page []model.URL, result *Result) error {
+	err := m.dst.LoadURLs(ctx, pageReader(page), false)
+	if err == nil {
+		for i := range page {
+			result.count(&page[i])
+		}
+		return nil
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("cannot store records: %w", err)
+	}
+	for i := range page {
+		if err = m.dst.LoadURLs(ctx, pageReader(page[i:i+1]), false); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("cannot store record: %w", err)
+			}
+			m.log.Warn("cannot migrate url", zap.String("short", page[i].Short), zap.Error(err))
+			result.Failed++
+			continue
+		}
+		result.count(&page[i])
+	}
+	return nil
+}
+
+// pageReader returns function that reads page records one by one.
+func pageReader(page []model.URL) func() (*model.URL, error) {
+	var i int
+	return func() (*model.URL, error) {
+		if i == len(page) {
+			return nil, io.EOF
+		}
+		i++
+		return &page[i-1], nil
+	}
+}
+
+func (res *Result) count(url *model.URL) {
+	res.Migrated++
+	if url.Deleted {
+		res.Deleted++
+	}
+}
+
+func (m *Migrator) loadCheckpoint() (*Result, error) {
+	result := &Result{}
+	if m.checkpoint == "" {
+		return result, nil
+	}
+	data, err := os.ReadFile(m.checkpoint)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return result, nil
+		}
+		return nil, fmt.Errorf("cannot read checkpoint: %w", err)
+	}
+	if err = json.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("malformed checkpoint: %w", err)
+	}
+	return result, nil
+}
+
+func (m *Migrator) saveCheckpoint(result *Result) error {
+	if m.checkpoint == "" {
+		return nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("cannot marshal checkpoint: %w", err)
+	}
+	// write and rename, so checkpoint is never partially written
+	tmp := m.checkpoint + ".tmp"
+	if err = os.WriteFile(tmp, data, checkpointPermission); err != nil {
+		return fmt.Errorf("cannot write checkpoint: %w", err)
+	}
+	if err = os.Rename(tmp, m.checkpoint); err != nil {
+		return fmt.Errorf("cannot save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Summary holds record counters and checksum of storage contents.
+type Summary struct {
+	Records  int64  `json:"records"`
+	Deleted  int64  `json:"deleted"`
+	Checksum string `json:"checksum"`
+}
+
+// Summarize scans whole storage and calculates its summary.
+// Checksum covers every stored field of every record including deleted ones
+// and variant click counters, so storages with equal summaries hold the same data.
+func Summarize(ctx context.Context, src Source) (*Summary, error) {
+	var (
+		summary Summary
+		h       = sha256.New()
+	)
+	err := src.ScanURLs(ctx, "", func(url *model.URL) error {
+		if err := writeRecord(h, url); err != nil {
+			return err
+		}
+		summary.Records++
+		if url.Deleted {
+			summary.Deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read storage: %w", err)
+	}
+	summary.Checksum = hex.EncodeToString(h.Sum(nil))
+	return &summary, nil
+}
+
+// Verify compares summaries of source and destination storages.
+// ErrMismatch is returned along with both summaries if they differ.
+func Verify(ctx context.Context, src, dst Source) (*Summary, *Summary, error) {
+	srcSummary, err := Summarize(ctx, src)
+	if err != nil {
+		return nil, nil, fmt.Errorf("source: %w", err)
+	}
+	dstSummary, err := Summarize(ctx, dst)
+	if err != nil {
+		return nil, nil, fmt.Errorf("destination: %w", err)
+	}
+	if *srcSummary != *dstSummary {
+		return srcSummary, dstSummary, ErrMismatch
+	}
+	return srcSummary, dstSummary, nil
+}
+
+// checksumRecord is storage independent representation of URL used for checksum.
+// Creation time has second precision since memory storage doesn't keep fractions.
+type checksumRecord struct {
+	Short        string          `json:"short"`
+	UUID         string          `json:"uuid"`
+	Orig         string          `json:"orig"`
+	UserID       string          `json:"user"`
+	Deleted      bool            `json:"deleted"`
+	Created      int64           `json:"created"`
+	Redirect     int             `json:"redirect"`
+	Passthrough  bool            `json:"passthrough"`
+	Targets      []model.Target  `json:"targets,omitempty"`
+	Variants     []model.Variant `json:"variants,omitempty"`
+	Schedule     *model.Schedule `json:"schedule,omitempty"`
+	PasswordHash string          `json:"password_hash"`
+	Title        string          `json:"title"`
+	Tags         []string        `json:"tags,omitempty"`
+	Notes        string          `json:"notes"`
+	Preview      bool            `json:"preview"`
+	Clicks       []int64         `json:"clicks,omitempty"`
+}
+
+func writeRecord(h hash.Hash, url *model.URL) error {
+	rec := checksumRecord{
+		Short:        url.Short,
+		UUID:         url.UUID,
+		Orig:         url.Orig,
+		UserID:       url.UserID,
+		Deleted:      url.Deleted,
+		Created:      createdUnix(url.Created),
+		Redirect:     url.Redirect,
+		Passthrough:  url.Passthrough,
+		Targets:      url.Targets,
+		Variants:     url.Variants,
+		Schedule:     url.Schedule,
+		PasswordHash: url.PasswordHash,
+		Title:        url.Title,
+		Tags:         url.Tags,
+		Notes:        url.Notes,
+		Preview:      url.Preview,
+		Clicks:       trimClicks(url.Clicks),
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("cannot marshal record %s: %w", url.Short, err)
+	}
+	_, _ = h.Write(append(data, '\n'))
+	return nil
+}
+
+// trimClicks removes trailing zero counters, storages may keep them or not.
+func trimClicks(clicks []int64) []int64 {
+	for len(clicks) > 0 && clicks[len(clicks)-1] == 0 {
+		clicks = clicks[:len(clicks)-1]
+	}
+	return clicks
+}
+
+func createdUnix(created time.Time) int64 {
+	if created.IsZero() {
+		return 0
+	}
+	return created.Unix()
+}
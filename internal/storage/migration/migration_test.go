@@ -0,0 +1,119 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adwski/shorty/internal/model"
+	"github.com/adwski/shorty/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// failingDst fails to restore specified urls and cancels context after limit of successful calls.
+type failingDst struct {
+	*memory.Memory
+	fail   map[string]bool
+	cancel context.CancelFunc
+	calls  int
+	limit  int
+}
+
+func (d *failingDst) LoadURLs(ctx context.Context, next func() (*model.URL, error), replace bool) error {
+	if d.limit > 0 && d.calls >= d.limit {
+		d.cancel()
+		return ctx.Err() //nolint:wrapcheck // test storage
+	}
+	d.calls++
+	return d.Memory.LoadURLs(ctx, func() (*model.URL, error) { //nolint:wrapcheck // test storage
+		url, err := next()
+		if err == nil && d.fail[url.Short] {
+			return nil, errors.New("restore error")
+		}
+		return url, err
+	}, replace)
+}
+
+func TestMigrator_Run(t *testing.T) {
+	ctx := context.Background()
+	src := memory.New()
+	require.NoError(t, src.StoreBatch(ctx, []model.URL{
+		{Short: "aaa", Orig: "https://aaa", UserID: "user1", Created: time.Unix(1700000000, 0)},
+		{Short: "bbb", Orig: "https://bbb", UserID: "user1", Tags: []string{"x"}, Variants: []model.Variant{
+			{URL: "https://v1", Weight: 1}, {URL: "https://v2", Weight: 1},
+		}},
+		{Short: "ccc", Orig: "https://ccc", UserID: "user2", Redirect: 301},
+		{Short: "ddd", Orig: "https://ddd", UserID: "user2"},
+		{Short: "eee", Orig: "https://eee", UserID: "user3"},
+	}))
+	require.NoError(t, src.AddVariantClicks(ctx, []model.Click{{Short: "bbb", Variant: 1}}))
+	_, err := src.DeleteUserURLs(ctx, []model.URL{{Short: "ddd", UserID: "user2"}})
+	require.NoError(t, err)
+
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.json")
+	runCtx, cancel := context.WithCancel(ctx)
+	dst := &failingDst{Memory: memory.New(), limit: 1, cancel: cancel}
+	m, err := New(&Config{
+		Logger:         zap.NewNop(),
+		Source:         src,
+		Destination:    dst,
+		CheckpointPath: checkpoint,
+		PageSize:       2,
+	})
+	require.NoError(t, err)
+
+	// first page is stored, second one is interrupted
+	result, err := m.Run(runCtx)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, &Result{Cursor: "bbb", Migrated: 2}, result)
+	assert.FileExists(t, checkpoint)
+
+	// resumed run skips already migrated page, failing record is skipped
+	dst.limit = 0
+	dst.fail = map[string]bool{"eee": true}
+	result, err = m.Run(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &Result{Cursor: "eee", Migrated: 4, Deleted: 1, Failed: 1}, result)
+	assert.NoFileExists(t, checkpoint)
+
+	_, _, err = Verify(ctx, src, dst)
+	assert.ErrorIs(t, err, ErrMismatch)
+
+	// repeated run starts from the beginning and overwrites records
+	dst.fail = nil
+	result, err = m.Run(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &Result{Cursor: "eee", Migrated: 5, Deleted: 1}, result)
+
+	srcSummary, dstSummary, err := Verify(ctx, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, srcSummary, dstSummary)
+	assert.Equal(t, int64(5), srcSummary.Records)
+	assert.Equal(t, int64(1), srcSummary.Deleted)
+
+	url, err := dst.Get(ctx, "ccc")
+	require.NoError(t, err)
+	assert.Equal(t, 301, url.Redirect)
+	_, err = dst.Get(ctx, "ddd")
+	assert.ErrorIs(t, err, model.ErrDeleted)
+	clicks, err := dst.GetVariantClicks(ctx, "bbb")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0, 1}, clicks)
+
+	// click counters change checksum
+	require.NoError(t, dst.AddVariantClicks(ctx, []model.Click{{Short: "bbb", Variant: 0}}))
+	_, _, err = Verify(ctx, src, dst)
+	assert.ErrorIs(t, err, ErrMismatch)
+	require.NoError(t, src.AddVariantClicks(ctx, []model.Click{{Short: "bbb", Variant: 0}}))
+	_, _, err = Verify(ctx, src, dst)
+	require.NoError(t, err)
+
+	// any changed field changes checksum
+	require.NoError(t, dst.UpdateMeta(ctx, &model.URL{Short: "bbb", UserID: "user1", Title: "changed"}))
+	_, _, err = Verify(ctx, src, dst)
+	assert.ErrorIs(t, err, ErrMismatch)
+}
@@ -4,14 +4,27 @@ package user
 import (
 	"encoding/base64"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 )
 
 // User represents single user.
 type User struct {
-	ID  string
-	new bool
+	ID string
+	// Login is account login of registered user, it's empty for anonymous users.
+	Login string
+	// KeyID is id of api key user is authenticated with, it's empty for session users.
+	KeyID string
+	// Scopes limits access of user authenticated with api key.
+	Scopes []string
+	// SessionID is id (jti) of session token user is authenticated with.
+	// It's kept when token is re-issued, so revocation invalidates all tokens of session.
+	SessionID string
+	// SessionExpires is expiration time of session token.
+	SessionExpires time.Time
+	new            bool
 }
 
 // IsNew returns whether user was created (generated) during this request (is new) or
@@ -20,6 +33,22 @@ func (u *User) IsNew() bool {
 	return u.new
 }
 
+// IsRegistered returns whether user has account.
+func (u *User) IsRegistered() bool {
+	return u.Login != ""
+}
+
+// IsAPIKey returns whether user is authenticated with api key.
+func (u *User) IsAPIKey() bool {
+	return u.KeyID != ""
+}
+
+// HasScope returns whether user is allowed to act within scope.
+// Session users have all scopes, api key users have only scopes of key.
+func (u *User) HasScope(scope string) bool {
+	return !u.IsAPIKey() || slices.Contains(u.Scopes, scope)
+}
+
 // NewWithID create user object with particular UD. It should be used to instantiate user
 // after successful cookie parse.
 func NewWithID(id string) *User {